@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultBlockedCIDRs 默认禁止访问的地址段：本机、内网、链路本地（含云厂商metadata地址）
+var defaultBlockedCIDRs = []string{
+	"0.0.0.0/8",
+	"127.0.0.0/8",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"169.254.0.0/16",
+	"100.64.0.0/10",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// ErrAddressBlocked 拨号阶段目标IP在受限地址段内，调用方可通过errors.Is识别
+var ErrAddressBlocked = errors.New("禁止访问受限地址")
+
+// AddressGuard 机器人地址SSRF防护
+type AddressGuard struct {
+	enable  bool
+	blocked []*net.IPNet
+	allowed []*net.IPNet
+}
+
+// NewAddressGuard 创建地址防护，blocked_cidrs为空时使用默认地址段
+func NewAddressGuard(cfg SecurityConfig) (*AddressGuard, error) {
+	guard := &AddressGuard{enable: cfg.RobotAddressCheck}
+
+	blockedCIDRs := cfg.BlockedCIDRs
+	if len(blockedCIDRs) == 0 {
+		blockedCIDRs = defaultBlockedCIDRs
+	}
+
+	var err error
+	if guard.blocked, err = parseCIDRs(blockedCIDRs); err != nil {
+		return nil, err
+	}
+	if guard.allowed, err = parseCIDRs(cfg.AllowedCIDRs); err != nil {
+		return nil, err
+	}
+
+	return guard, nil
+}
+
+// parseCIDRs 解析地址段列表，单个IP按/32或/128处理
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的地址段 %s: %w", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// isBlocked 判断IP是否被禁止访问，允许列表优先
+func (g *AddressGuard) isBlocked(ip net.IP) bool {
+	for _, n := range g.allowed {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	for _, n := range g.blocked {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check 校验机器人地址，解析出的任一IP命中禁止地址段即拒绝
+func (g *AddressGuard) Check(address string) error {
+	if !g.enable {
+		return nil
+	}
+
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + address
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("机器人地址格式错误: %w", err)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("机器人地址缺少主机名")
+	}
+
+	return g.checkHost("机器人地址", host)
+}
+
+// CheckHost 校验调用方传入地址（如回调地址）的主机名，解析出的任一IP命中禁止地址段即拒绝
+func (g *AddressGuard) CheckHost(kind, host string) error {
+	if !g.enable {
+		return nil
+	}
+	return g.checkHost(kind, host)
+}
+
+// checkHost 解析主机名并逐个校验IP，kind用于错误提示
+func (g *AddressGuard) checkHost(kind, host string) error {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return fmt.Errorf("%s解析失败: %w", kind, err)
+	}
+
+	for _, ip := range ips {
+		if g.isBlocked(ip) {
+			return fmt.Errorf("%s %s 指向受限地址 %s", kind, host, ip.String())
+		}
+	}
+	return nil
+}
+
+// dialControl 在建立连接前校验目标IP，防止DNS重绑定绕过Check
+func (g *AddressGuard) dialControl(network, address string, _ syscall.RawConn) error {
+	if !g.enable {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip != nil && g.isBlocked(ip) {
+		return fmt.Errorf("%w %s", ErrAddressBlocked, ip.String())
+	}
+	return nil
+}
+
+// Transport 返回在拨号阶段做地址校验的HTTP Transport
+func (g *AddressGuard) Transport() http.RoundTripper {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   g.dialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return transport
+}
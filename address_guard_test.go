@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAddressGuardCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		enable  bool
+		allowed []string
+		address string
+		wantErr string
+	}{
+		{name: "public ip", enable: true, address: "http://8.8.8.8:2531"},
+		{name: "without scheme", enable: true, address: "8.8.8.8:2531"},
+		{name: "loopback", enable: true, address: "http://127.0.0.1:2531", wantErr: "受限地址"},
+		{name: "private network", enable: true, address: "10.1.2.3:2531", wantErr: "受限地址"},
+		{name: "carrier grade nat", enable: true, address: "http://100.64.0.1", wantErr: "受限地址"},
+		{name: "metadata address", enable: true, address: "http://169.254.169.254", wantErr: "受限地址"},
+		{name: "ipv6 unique local", enable: true, address: "http://[fd00::1]:2531", wantErr: "受限地址"},
+		{name: "allowed cidr", enable: true, allowed: []string{"10.1.0.0/16"}, address: "http://10.1.2.3:2531"},
+		{name: "allowed single ip", enable: true, allowed: []string{"127.0.0.1"}, address: "http://127.0.0.1:2531"},
+		{name: "missing host", enable: true, address: "http://:2531", wantErr: "缺少主机名"},
+		{name: "check disabled", enable: false, address: "http://127.0.0.1:2531"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := newTestAddressGuard(t, tt.enable, tt.allowed...)
+			err := guard.Check(tt.address)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Check(%q) = %v, want nil", tt.address, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Check(%q) = %v, want error containing %q", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAddressGuardDialControl(t *testing.T) {
+	guard := newTestAddressGuard(t, true)
+
+	tests := []struct {
+		address     string
+		wantBlocked bool
+	}{
+		{address: "8.8.8.8:80"},
+		{address: "127.0.0.1:80", wantBlocked: true},
+		{address: "[::1]:443", wantBlocked: true},
+		{address: "192.168.0.10:2531", wantBlocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := guard.dialControl("tcp", tt.address, nil)
+			if got := errors.Is(err, ErrAddressBlocked); got != tt.wantBlocked {
+				t.Fatalf("dialControl(%q) = %v, want blocked %v", tt.address, err, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestNewAddressGuardInvalidCIDR(t *testing.T) {
+	if _, err := NewAddressGuard(SecurityConfig{RobotAddressCheck: true, BlockedCIDRs: []string{"10.0.0.0/33"}}); err == nil {
+		t.Fatal("invalid blocked cidr accepted")
+	}
+	if _, err := NewAddressGuard(SecurityConfig{RobotAddressCheck: true, AllowedCIDRs: []string{"not-an-ip"}}); err == nil {
+		t.Fatal("invalid allowed cidr accepted")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AdminTokenHeader 管理接口鉴权请求头
+const AdminTokenHeader = "X-Admin-Token"
+
+// requireAdmin 管理接口鉴权，请求头中的令牌需与 security.admin_token 一致
+// 未配置admin_token时管理接口一律拒绝访问
+func (rm *RouterManager) requireAdmin() gin.HandlerFunc {
+	adminToken := rm.cfg.Security.AdminToken
+	if adminToken == "" {
+		rm.logger.Warn("未配置security.admin_token，需要鉴权的管理接口不可用")
+	}
+
+	return func(c *gin.Context) {
+		if adminToken == "" {
+			rm.errorResponse(c, http.StatusForbidden, "未配置管理员令牌，接口不可用")
+			c.Abort()
+			return
+		}
+
+		token := c.GetHeader(AdminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			rm.logger.Warn("管理接口鉴权失败",
+				zap.String("path", c.FullPath()),
+				zap.String("client_ip", c.ClientIP()))
+			rm.errorResponse(c, http.StatusUnauthorized, "鉴权失败")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 金额格式化方式
+const (
+	AmountFormatPlain     = "plain"     // 原样输出，如 1234567.50
+	AmountFormatThousands = "thousands" // 千分位，如 1,234,567.50
+	AmountFormatCurrency  = "currency"  // 货币符号加千分位，如 ¥1,234,567.50
+)
+
+// formatAmount 按格式化方式输出金额，无法解析为数字的金额原样返回
+func formatAmount(amount, format, symbol string) string {
+	if format == "" || format == AmountFormatPlain {
+		return amount
+	}
+
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return amount
+	}
+
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	text := strconv.FormatFloat(value, 'f', 2, 64)
+	intPart, fracPart, _ := strings.Cut(text, ".")
+
+	var b strings.Builder
+	for i, ch := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(ch)
+	}
+	text = b.String() + "." + fracPart
+
+	if format == AmountFormatCurrency {
+		return sign + symbol + text
+	}
+	return sign + text
+}
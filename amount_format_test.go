@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount string
+		format string
+		want   string
+	}{
+		{name: "default plain", amount: "1234567.5", want: "1234567.5"},
+		{name: "plain", amount: "1234567.5", format: AmountFormatPlain, want: "1234567.5"},
+		{name: "thousands", amount: "1234567.5", format: AmountFormatThousands, want: "1,234,567.50"},
+		{name: "currency", amount: "1234567.5", format: AmountFormatCurrency, want: "¥1,234,567.50"},
+		{name: "small amount", amount: "12", format: AmountFormatThousands, want: "12.00"},
+		{name: "exact thousand", amount: "1000", format: AmountFormatCurrency, want: "¥1,000.00"},
+		{name: "six digits", amount: "123456.789", format: AmountFormatThousands, want: "123,456.79"},
+		{name: "negative", amount: "-98765.4", format: AmountFormatCurrency, want: "-¥98,765.40"},
+		{name: "not a number", amount: "abc", format: AmountFormatCurrency, want: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatAmount(tt.amount, tt.format, "¥"); got != tt.want {
+				t.Fatalf("formatAmount(%q, %q) = %q, want %q", tt.amount, tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBillListAmountFormat(t *testing.T) {
+	cfg := &Config{}
+	cfg.BillFormat.CurrencySymbol = "$"
+	router, _, db := newTestRouter(t, cfg)
+	createTestBills(t, db, WxBillInfo{OwnerID: 1, GroupID: "g@chatroom", Amount: "1234.5", MsgTime: 1})
+
+	tests := []struct {
+		format     string
+		wantStatus int
+		want       string
+	}{
+		{format: "thousands", wantStatus: http.StatusOK, want: "1,234.50"},
+		{format: "currency", wantStatus: http.StatusOK, want: "$1,234.50"},
+		{format: "yuan", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			w := doRequest(router, http.MethodGet, "/bills/list?owner_id=1&format="+tt.format, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.want == "" {
+				return
+			}
+			var resp struct {
+				Data BillQueryPaginatedResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(resp.Data.List) != 1 || resp.Data.List[0].Amount != tt.want {
+				t.Fatalf("list = %+v, want amount %q", resp.Data.List, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 外部接口并发超限时的处理方式
+const (
+	WxAPIConcurrencyModeWait = "wait" // 排队等待空闲名额，超过等待时长或请求超时后失败
+	WxAPIConcurrencyModeFail = "fail" // 立即失败
+)
+
+// ErrWxAPIBusy 外部接口并发已达上限，调用方可通过errors.Is识别
+var ErrWxAPIBusy = errors.New("外部接口调用并发已达上限")
+
+// concurrencyLimitTransport 限制外部接口同时进行中的请求数（全局信号量）
+// 名额在响应体关闭时释放，请求失败时立即释放
+type concurrencyLimitTransport struct {
+	next        http.RoundTripper
+	slots       chan struct{}
+	failFast    bool
+	waitTimeout time.Duration
+	logger      *zap.Logger
+}
+
+// newConcurrencyLimitTransport 创建并发限制transport，limit<=0时不限制直接返回next
+func newConcurrencyLimitTransport(cfg WxAPIConcurrencyConfig, next http.RoundTripper, logger *zap.Logger) http.RoundTripper {
+	if cfg.Limit <= 0 {
+		return next
+	}
+
+	logger.Info("外部接口并发限制已开启",
+		zap.Int("limit", cfg.Limit),
+		zap.String("mode", cfg.Mode),
+		zap.Duration("wait_timeout", cfg.WaitTimeout))
+	return &concurrencyLimitTransport{
+		next:        next,
+		slots:       make(chan struct{}, cfg.Limit),
+		failFast:    cfg.Mode == WxAPIConcurrencyModeFail,
+		waitTimeout: cfg.WaitTimeout,
+		logger:      logger,
+	}
+}
+
+// RoundTrip 获取名额后转发请求
+func (t *concurrencyLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.acquire(req); err != nil {
+		t.logger.Warn("外部接口并发已达上限，请求未发出",
+			zap.String("path", req.URL.Path),
+			zap.Int("limit", cap(t.slots)),
+			zap.Error(err))
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.release()
+		return nil, err
+	}
+	resp.Body = &releaseOnCloseBody{ReadCloser: resp.Body, release: t.release}
+	return resp, nil
+}
+
+// acquire 获取一个并发名额：快速失败模式下无空闲名额立即返回ErrWxAPIBusy；
+// 等待模式下排队直到有空闲名额、超过等待时长或请求被取消
+func (t *concurrencyLimitTransport) acquire(req *http.Request) error {
+	select {
+	case t.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if t.failFast {
+		return ErrWxAPIBusy
+	}
+
+	var timeout <-chan time.Time
+	if t.waitTimeout > 0 {
+		timer := time.NewTimer(t.waitTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case t.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrWxAPIBusy
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// release 释放一个并发名额
+func (t *concurrencyLimitTransport) release() {
+	<-t.slots
+}
+
+// releaseOnCloseBody 响应体关闭时释放并发名额，多次关闭只释放一次
+type releaseOnCloseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// roundTripFunc 测试用的RoundTripper
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// closeFuncBody 关闭时执行回调的响应体
+type closeFuncBody struct {
+	io.Reader
+	onClose func()
+}
+
+func (b *closeFuncBody) Close() error {
+	b.onClose()
+	return nil
+}
+
+func TestConcurrencyLimitTransport(t *testing.T) {
+	const requests = 5
+	const hold = 50 * time.Millisecond
+
+	tests := []struct {
+		name         string
+		cfg          WxAPIConcurrencyConfig
+		ctxTimeout   time.Duration
+		wantMax      int64
+		wantErrCount int
+		wantErr      error
+	}{
+		{name: "disabled", cfg: WxAPIConcurrencyConfig{}, wantMax: requests},
+		{name: "wait for slot", cfg: WxAPIConcurrencyConfig{Limit: 2, Mode: WxAPIConcurrencyModeWait, WaitTimeout: time.Second}, wantMax: 2},
+		{name: "fail fast", cfg: WxAPIConcurrencyConfig{Limit: 2, Mode: WxAPIConcurrencyModeFail}, wantMax: 2, wantErrCount: requests - 2, wantErr: ErrWxAPIBusy},
+		{name: "wait timeout", cfg: WxAPIConcurrencyConfig{Limit: 2, Mode: WxAPIConcurrencyModeWait, WaitTimeout: 10 * time.Millisecond}, wantMax: 2, wantErrCount: requests - 2, wantErr: ErrWxAPIBusy},
+		{name: "request cancelled while waiting", cfg: WxAPIConcurrencyConfig{Limit: 2, Mode: WxAPIConcurrencyModeWait}, ctxTimeout: 10 * time.Millisecond, wantMax: 2, wantErrCount: requests - 2, wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inFlight, maxInFlight int64
+			next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				n := atomic.AddInt64(&inFlight, 1)
+				for {
+					cur := atomic.LoadInt64(&maxInFlight)
+					if n <= cur || atomic.CompareAndSwapInt64(&maxInFlight, cur, n) {
+						break
+					}
+				}
+				time.Sleep(hold)
+				body := &closeFuncBody{Reader: strings.NewReader("ok"), onClose: func() { atomic.AddInt64(&inFlight, -1) }}
+				return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+			})
+			transport := newConcurrencyLimitTransport(tt.cfg, next, zap.NewNop())
+
+			var wg sync.WaitGroup
+			errs := make(chan error, requests)
+			for i := 0; i < requests; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ctx := context.Background()
+					if tt.ctxTimeout > 0 {
+						var cancel context.CancelFunc
+						ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+						defer cancel()
+					}
+					req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://robot.invalid/message/SendTextMessage", nil)
+					resp, err := transport.RoundTrip(req)
+					if err != nil {
+						errs <- err
+						return
+					}
+					resp.Body.Close()
+					resp.Body.Close() // 重复关闭只释放一次名额
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			var errCount int
+			for err := range errs {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				errCount++
+			}
+			if errCount != tt.wantErrCount {
+				t.Fatalf("errors = %d, want %d", errCount, tt.wantErrCount)
+			}
+			if maxInFlight != tt.wantMax {
+				t.Fatalf("max in flight = %d, want %d", maxInFlight, tt.wantMax)
+			}
+			if limited, ok := transport.(*concurrencyLimitTransport); ok && len(limited.slots) != 0 {
+				t.Fatalf("slots in use after all bodies closed = %d", len(limited.slots))
+			}
+		})
+	}
+}
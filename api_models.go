@@ -1,5 +1,7 @@
 package main
 
+import "time"
+
 // API响应结构
 type APIResponse struct {
 	Code    int         `json:"code"`
@@ -8,19 +10,34 @@ type APIResponse struct {
 }
 
 type QRCodeResponse struct {
-	QRCode        string `json:"qr_code"`
-	Token         string `json:"token"`
-	ExpireTime    int64  `json:"expire_time"`
-	QrCodeBase64  string `json:"qrCodeBase64"`
+	QRCode       string `json:"qr_code"`
+	Token        string `json:"token"`
+	ExpireTime   int64  `json:"expire_time"`
+	QrCodeBase64 string `json:"qrCodeBase64"`
+	LoginMode    string `json:"login_mode"` // qrcode 扫码登录，relogin 二次登录（无需扫码）
 }
 
+// 登录方式
+const (
+	LoginModeQRCode  = "qrcode"
+	LoginModeRelogin = "relogin"
+)
+
 type LoginStatusResponse struct {
-	Status   int    `json:"status"` // 0未扫码 1已扫码 2登录成功 3登录失败
+	Status   int    `json:"status"` // 0未扫码 1已扫码待确认 2登录成功 3登录失败
 	WxID     string `json:"wx_id"`
 	NickName string `json:"nick_name"`
 	Message  string `json:"message"`
 }
 
+// 扫码登录状态（LoginStatusResponse.Status）
+const (
+	LoginStatusWaitScan  = 0 // 未扫码
+	LoginStatusScanned   = 1 // 已扫码待确认
+	LoginStatusConfirmed = 2 // 登录成功
+	LoginStatusFailed    = 3 // 登录失败
+)
+
 // DTO 对象用于保存操作
 type SaveUserRequest struct {
 	RobotID         uint   `json:"robot_id" binding:"required"`
@@ -33,37 +50,128 @@ type SaveUserRequest struct {
 
 // 创建机器人配置请求
 type CreateRobotRequest struct {
-	Address     string   `json:"address" binding:"required"`
-	AdminKey    string   `json:"admin_key" binding:"required"`
-	OwnerID     uint     `json:"owner_id" binding:"required"`
-	Description string   `json:"description"`
-	AdminUsers  []string `json:"admin_users"`
+	Address        string   `json:"address" binding:"required"`
+	AdminKey       string   `json:"admin_key" binding:"required"`
+	OwnerID        uint     `json:"owner_id" binding:"required"`
+	Description    string   `json:"description"`
+	AdminUsers     []string `json:"admin_users"`
+	TimeoutSeconds int      `json:"timeout_seconds" binding:"min=0,max=300"`      // 外部接口调用超时(秒)，0表示使用全局默认，最大300
+	HealthPath     string   `json:"health_path" binding:"omitempty,startswith=/"` // 健康检查路径，默认 /
+	Tags           []string `json:"tags"`                                         // 标签，用于分组统计
 }
 
 // 更新机器人配置请求
 type UpdateRobotRequest struct {
-	Address     string   `json:"address" binding:"required"`
-	AdminKey    string   `json:"admin_key" binding:"required"`
-	OwnerID     uint     `json:"owner_id" binding:"required"`
-	Description string   `json:"description"`
-	AdminUsers  []string `json:"admin_users"`
+	Address        string   `json:"address" binding:"required"`
+	AdminKey       string   `json:"admin_key" binding:"required"`
+	OwnerID        uint     `json:"owner_id" binding:"required"`
+	Description    string   `json:"description"`
+	AdminUsers     []string `json:"admin_users"`
+	TimeoutSeconds int      `json:"timeout_seconds" binding:"min=0,max=300"`      // 外部接口调用超时(秒)，0表示使用全局默认，最大300
+	HealthPath     string   `json:"health_path" binding:"omitempty,startswith=/"` // 健康检查路径，默认 /
+	Tags           []string `json:"tags"`                                         // 标签，用于分组统计
+}
+
+// 部分更新机器人配置请求，未传的字段保持不变
+type PatchRobotRequest struct {
+	Address        *string   `json:"address"`
+	AdminKey       *string   `json:"admin_key"`
+	OwnerID        *uint     `json:"owner_id"`
+	Description    *string   `json:"description"`
+	AdminUsers     *[]string `json:"admin_users"`
+	TimeoutSeconds *int      `json:"timeout_seconds" binding:"omitempty,min=0,max=300"` // 外部接口调用超时(秒)，0表示使用全局默认，最大300
+	HealthPath     *string   `json:"health_path" binding:"omitempty,startswith=/"`      // 健康检查路径，传空字符串恢复默认 /
+	Enabled        *int      `json:"enabled" binding:"omitempty,oneof=0 1"`             // 是否启用 0禁用 1启用
+	Tags           *[]string `json:"tags"`                                              // 标签，传空数组清空
+}
+
+// 批量启用/禁用机器人请求，owner_id 与 ids 至少提供一个，同时提供时取交集
+type BatchRobotStatusRequest struct {
+	OwnerID uint   `json:"owner_id"`                             // 按所属公司操作
+	IDs     []uint `json:"ids"`                                  // 按机器人ID列表操作
+	Enabled *int   `json:"enabled" binding:"required,oneof=0 1"` // 0禁用 1启用
+}
+
+// 批量启用/禁用机器人响应
+type BatchRobotStatusResponse struct {
+	Enabled  int   `json:"enabled"`
+	Affected int64 `json:"affected"` // 状态实际发生变化的机器人数
+}
+
+// 闲置机器人查询请求
+type IdleRobotQuery struct {
+	OwnerID uint `form:"owner_id"`                                 // 所属公司ID，不传查询全部
+	Days    int  `form:"days,default=30" binding:"min=1,max=3650"` // 超过多少天无活动视为闲置，默认30
+}
+
+// 闲置机器人：没有在线账号且超过指定天数无活动
+type IdleRobot struct {
+	ID             uint   `json:"id"`
+	Address        string `json:"address"`
+	OwnerID        uint   `json:"owner_id"`
+	Description    string `json:"description"`
+	Enabled        int    `json:"enabled"`
+	UserCount      int    `json:"user_count"`       // 账号数（均不在线）
+	LastActiveTime string `json:"last_active_time"` // 最近活动时间：配置修改、账号状态变化或账号发送消息
+	IdleDays       int    `json:"idle_days"`        // 已闲置天数
+}
+
+// 闲置机器人清理请求，confirm为false时只预览将被清理的机器人
+type CleanupIdleRobotsRequest struct {
+	OwnerID uint   `json:"owner_id"`                                       // 所属公司ID，不传清理全部
+	Days    int    `json:"days" binding:"required,min=1,max=3650"`         // 超过多少天无活动视为闲置
+	IDs     []uint `json:"ids"`                                            // 只清理其中仍闲置的机器人，不传时清理全部闲置机器人
+	Action  string `json:"action" binding:"required,oneof=disable delete"` // disable 禁用，delete 删除机器人及其账号
+	Confirm bool   `json:"confirm"`                                        // 确认执行，为false时只返回将被清理的机器人
+}
+
+// 闲置机器人清理响应
+type CleanupIdleRobotsResponse struct {
+	Action    string      `json:"action"`
+	Confirmed bool        `json:"confirmed"` // 是否已执行清理
+	Robots    []IdleRobot `json:"robots"`    // 将被（或已被）清理的机器人
+	Affected  int64       `json:"affected"`  // 实际禁用或删除的机器人数
 }
 
 // 账单统计请求
 type BillStatsRequest struct {
-	GroupID   string `form:"group_id"`
-	GroupNick string `form:"group_nick"`
-	PageNo    int    `form:"page_no,default=1" binding:"min=1"`
-	PageSize  int    `form:"page_size,default=10" binding:"min=1,max=100"`
-	OwnerID   uint   `form:"owner_id" binding:"required"`
+	GroupID         string `form:"group_id"`
+	GroupNick       string `form:"group_nick"`
+	PageNo          int    `form:"page_no,default=1" binding:"min=1"`
+	PageSize        int    `form:"page_size,default=10" binding:"min=1,max=100"`
+	OwnerID         uint   `form:"owner_id" binding:"required"`
+	GroupBy         string `form:"group_by" binding:"omitempty,oneof=group operator"`         // 分组维度：group 按群（默认）、operator 按操作人
+	Format          string `form:"format" binding:"omitempty,oneof=plain currency thousands"` // 金额格式：plain 原样（默认）、currency 货币符号加千分位、thousands 千分位
+	CreateTimeStart string `form:"create_time_start"`                                         // 账单时间开始，格式：yyyy-mm-dd hh:mi:ss
+	CreateTimeEnd   string `form:"create_time_end"`                                           // 账单时间结束，格式：yyyy-mm-dd hh:mi:ss
+	Compare         bool   `form:"compare"`                                                   // 是否返回与上一等长时间段的环比，需同时传开始和结束时间
 }
 
+// 账单统计分组维度
+const (
+	BillStatsGroupByGroup    = "group"
+	BillStatsGroupByOperator = "operator"
+)
+
 // 账单统计响应
 type BillStatsResponse struct {
-	GroupID     string `json:"group_id"`
-	GroupNick   string `json:"group_nick"`
-	TotalAmount string `json:"total_amount"`
-	Count       int64  `json:"count"`
+	GroupID      string           `json:"group_id"`
+	GroupNick    string           `json:"group_nick"`
+	Operator     string           `json:"operator,omitempty"` // 按操作人分组时返回
+	TotalAmount  string           `json:"total_amount"`
+	Count        int64            `json:"count"`
+	DetailFilter BillDetailFilter `json:"detail_filter"` // 下钻查询明细的过滤条件
+}
+
+// 账单统计下钻过滤条件，字段与 /bills/list 的查询参数同名，可直接拼接查询该分组项的账单明细
+type BillDetailFilter struct {
+	OwnerID   uint   `json:"owner_id"`
+	GroupID   string `json:"group_id,omitempty"`
+	GroupName string `json:"group_name,omitempty"`
+	Operator  string `json:"operator,omitempty"`
+
+	CreateTimeStart string `json:"create_time_start,omitempty"`
+	CreateTimeEnd   string `json:"create_time_end,omitempty"`
 }
 
 // 分页信息
@@ -78,8 +186,24 @@ type PaginationInfo struct {
 
 // 账单统计分页响应
 type BillStatsPaginatedResponse struct {
-	List       []BillStatsResponse `json:"list"`
-	Pagination PaginationInfo      `json:"pagination"`
+	List       []BillStatsResponse  `json:"list"`
+	Pagination PaginationInfo       `json:"pagination"`
+	Comparison *BillStatsComparison `json:"comparison,omitempty"` // compare=true时返回
+}
+
+// 账单统计环比：本期为查询的时间段，上期为紧邻其前的等长时间段，金额和笔数为所有分组项的合计
+type BillStatsComparison struct {
+	CurrentStart   string `json:"current_start"`
+	CurrentEnd     string `json:"current_end"`
+	PreviousStart  string `json:"previous_start"`
+	PreviousEnd    string `json:"previous_end"`
+	CurrentAmount  string `json:"current_amount"`
+	PreviousAmount string `json:"previous_amount"`
+	CurrentCount   int64  `json:"current_count"`
+	PreviousCount  int64  `json:"previous_count"`
+	// 环比增减百分比，保留两位小数，如12.5表示增长12.5%；上期为0时为null
+	AmountChangeRate *float64 `json:"amount_change_rate"`
+	CountChangeRate  *float64 `json:"count_change_rate"`
 }
 
 // 账单查询请求
@@ -89,9 +213,11 @@ type BillQueryRequest struct {
 	GroupName       string `form:"group_name"`        // 群名称
 	GroupID         string `form:"group_id"`          // 群ID
 	Status          string `form:"status"`            // 账单状态
+	Operator        string `form:"operator"`          // 操作人
 	PageNum         int    `form:"page_num,default=1" binding:"min=1"`
 	PageSize        int    `form:"page_size,default=10" binding:"min=1,max=100"`
 	OwnerID         uint   `form:"owner_id" binding:"required"`
+	Format          string `form:"format" binding:"omitempty,oneof=plain currency thousands"` // 金额格式：plain 原样（默认）、currency 货币符号加千分位、thousands 千分位
 }
 
 // 账单信息响应
@@ -105,14 +231,607 @@ type BillInfoResponse struct {
 	Remark      string `json:"remark"`
 	Operator    string `json:"operator"`
 	MsgTime     int64  `json:"msg_time"`
+	MsgTimeText string `json:"msg_time_text"` // 账单时间，按配置时区格式化
 	Status      string `json:"status"`
 	OwnerID     uint   `json:"owner_id"`
 	CreateTime  string `json:"create_time"`
 	UpdateTime  string `json:"update_time"`
 }
 
+// 群最近账单查询请求
+type RecentBillsRequest struct {
+	OwnerID uint `form:"owner_id" binding:"required"`
+	Limit   int  `form:"limit,default=10" binding:"min=1,max=100"`
+}
+
 // 账单查询分页响应
 type BillQueryPaginatedResponse struct {
 	List       []BillInfoResponse `json:"list"`
 	Pagination PaginationInfo     `json:"pagination"`
-}
\ No newline at end of file
+}
+
+// 用户授权概览响应
+type UserAuthInfoResponse struct {
+	UserID           uint                 `json:"user_id"`
+	RobotID          uint                 `json:"robot_id"`
+	WxID             string               `json:"wx_id"`
+	NickName         string               `json:"nick_name"`
+	Remark           string               `json:"remark"`             // 运营备注
+	Token            string               `json:"token"`              // 脱敏后的授权token
+	AuthCreateTime   string               `json:"auth_create_time"`   // 授权生成（首次保存）时间
+	ExtensionTime    string               `json:"extension_time"`     // 最近延期时间
+	ExpirationTime   string               `json:"expiration_time"`    // 过期时间
+	RemainingDays    int                  `json:"remaining_days"`     // 剩余天数，已过期为负数
+	Status           int                  `json:"status"`             // 1正常 2风控 3需要重新登录
+	HasSecurityRisk  int                  `json:"has_security_risk"`  // 0否 1是
+	IsInitialized    int                  `json:"is_initialized"`     // 0未初始化 1初始化完成
+	LoginStatus      *UserLoginStatusInfo `json:"login_status"`       // 实时登录状态，查询失败时为空
+	LoginStatusError string               `json:"login_status_error"` // 实时登录状态查询失败原因
+	Extensions       []WxUserExtensionLog `json:"extensions"`         // 延期记录，按时间倒序
+}
+
+// 实时登录状态信息
+type UserLoginStatusInfo struct {
+	LoginState  int    `json:"login_state"`
+	LoginTime   string `json:"login_time"`
+	OnlineTime  string `json:"online_time"`
+	OnlineDays  int    `json:"online_days"`
+	ExpiryTime  string `json:"expiry_time"`
+	LoginErrMsg string `json:"login_err_msg"`
+}
+
+// 群发消息请求
+type BroadcastRequest struct {
+	ToUserNames  []string `json:"to_user_names" binding:"required,min=1"`
+	TextContent  string   `json:"text_content"`
+	ImageContent string   `json:"image_content"`
+	CallbackURL  string   `json:"callback_url"`                                   // 可选，全部发送完成后回调汇总结果
+	Priority     string   `json:"priority" binding:"omitempty,oneof=high normal"` // 发送优先级，默认normal
+	// 是否确认向超大群发送，未确认时成员数超过阈值的群会发送失败
+	ConfirmLargeGroup bool `json:"confirm_large_group"`
+	// 灰度发送：按比例或数量从有效群中随机抽取一部分先发，二者只能传一个；未抽中的群在任务的remaining中返回
+	SampleRate  float64 `json:"sample_rate" binding:"omitempty,gt=0,lte=1"` // 抽样比例，如0.1表示10%，至少抽1个群
+	SampleCount int     `json:"sample_count" binding:"omitempty,min=1"`     // 抽样数量，超过有效群数时全部发送
+	// 发起群发的API Key绑定的公司ID，由鉴权中间件填充，只使用该公司的消息机器人发送
+	OwnerID uint `json:"-"`
+}
+
+// 同步群发请求，发送完成后在响应中返回每个群的结果；单次最多50个群，更多群请使用异步群发任务
+type GroupBroadcastRequest struct {
+	ToUserNames  []string `json:"to_user_names" binding:"required,min=1,max=50"`
+	TextContent  string   `json:"text_content"`
+	ImageContent string   `json:"image_content"`
+	Priority     string   `json:"priority" binding:"omitempty,oneof=high normal"` // 发送优先级，默认normal
+	// 是否确认向超大群发送，未确认时成员数超过阈值的群会发送失败
+	ConfirmLargeGroup bool `json:"confirm_large_group"`
+}
+
+// 同步群发响应，部分群失败时同样返回，失败原因见各群结果
+type GroupBroadcastResponse struct {
+	TraceID string                 `json:"trace_id"` // 发送批次追踪ID，可查询发送记录
+	Total   int                    `json:"total"`
+	Success int                    `json:"success"`
+	Failed  int                    `json:"failed"`
+	Results []BroadcastGroupResult `json:"results"` // 按请求顺序返回，发送前被剔除的群也在其中
+}
+
+// 群发单个群的发送结果
+type BroadcastGroupResult struct {
+	ToUserName string `json:"to_user_name"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	WxID       string `json:"wx_id,omitempty"` // 实际发送的消息机器人
+}
+
+// 群发前被剔除的群及原因
+type BroadcastSkippedGroup struct {
+	ToUserName string `json:"to_user_name"`
+	Reason     string `json:"reason"`
+}
+
+// 群发任务进度
+type BroadcastTaskProgress struct {
+	TaskID     string                  `json:"task_id"`
+	TraceID    string                  `json:"trace_id"` // 发送批次追踪ID，可查询发送记录
+	Status     string                  `json:"status"`   // pending running completed
+	Total      int                     `json:"total"`    // 实际发送的有效群数量
+	Sent       int                     `json:"sent"`
+	Success    int                     `json:"success"`
+	Failed     int                     `json:"failed"`
+	Results    []BroadcastGroupResult  `json:"results"`
+	Skipped    []BroadcastSkippedGroup `json:"skipped"`             // 发送前剔除的重复/无效群
+	Remaining  []string                `json:"remaining,omitempty"` // 灰度发送时未抽中的群，确认无误后可用其提交全量
+	CreateTime string                  `json:"create_time"`
+	FinishTime string                  `json:"finish_time"`
+}
+
+// 按trace_id查询的发送批次结果
+type SendTraceResponse struct {
+	TraceID string         `json:"trace_id"`
+	Total   int            `json:"total"`
+	Success int            `json:"success"`
+	Failed  int            `json:"failed"`
+	Records []WxSendRecord `json:"records"`
+}
+
+// 发送审计记录过滤条件
+type SendAuditFilter struct {
+	StartTime  string `form:"start_time"`   // 发送时间开始，格式：yyyy-mm-dd hh:mi:ss
+	EndTime    string `form:"end_time"`     // 发送时间结束，格式：yyyy-mm-dd hh:mi:ss
+	ToUserName string `form:"to_user_name"` // 目标群ID
+	SenderWxID string `form:"sender_wx_id"` // 发送账号微信ID
+	OwnerID    uint   `form:"owner_id"`     // 发送账号所属公司ID
+}
+
+// 发送审计记录分页查询请求
+type SendAuditQueryRequest struct {
+	SendAuditFilter
+	PageNo   int `form:"page_no,default=1" binding:"min=1"`
+	PageSize int `form:"page_size,default=10" binding:"min=1,max=100"`
+}
+
+// 按机器人标签对比发送量请求
+type RobotTagStatsRequest struct {
+	StartTime string `form:"start_time"` // 发送时间开始，格式：yyyy-mm-dd hh:mi:ss
+	EndTime   string `form:"end_time"`   // 发送时间结束，格式：yyyy-mm-dd hh:mi:ss
+	OwnerID   uint   `form:"owner_id"`   // 机器人所属公司ID
+	Tags      string `form:"tags"`       // 只对比这些标签，逗号分隔，为空时全部标签
+}
+
+// 单个标签分组的发送统计
+type RobotTagSendStats struct {
+	Tag         string  `json:"tag"`          // 标签，空表示未打标签的机器人
+	RobotCount  int     `json:"robot_count"`  // 该标签下的机器人数
+	Total       int64   `json:"total"`        // 发送总数
+	Success     int64   `json:"success"`      // 发送成功数
+	Failed      int64   `json:"failed"`       // 发送失败数
+	SuccessRate float64 `json:"success_rate"` // 成功率(%)，保留两位小数
+}
+
+// 发送审计记录分页响应
+type SendAuditPaginatedResponse struct {
+	List       []WxSendAudit  `json:"list"`
+	Pagination PaginationInfo `json:"pagination"`
+}
+
+// 群消息搜索请求
+type GroupMessageSearchRequest struct {
+	Keyword   string `form:"keyword" binding:"required"` // 消息内容关键词
+	GroupID   string `form:"group_id"`                   // 群组ID
+	OwnerID   uint   `form:"owner_id"`                   // 所属公司ID
+	StartTime string `form:"start_time"`                 // 消息时间开始，格式：yyyy-mm-dd hh:mi:ss
+	EndTime   string `form:"end_time"`                   // 消息时间结束，格式：yyyy-mm-dd hh:mi:ss
+	PageNo    int    `form:"page_no,default=1" binding:"min=1"`
+	PageSize  int    `form:"page_size,default=10" binding:"min=1,max=100"`
+}
+
+// 群消息搜索分页响应
+type GroupMessageSearchResponse struct {
+	List       []WxGroupMessage `json:"list"`
+	Pagination PaginationInfo   `json:"pagination"`
+}
+
+// 群消息回调请求
+type MessageCallbackRequest struct {
+	WxID       string `json:"wx_id" binding:"required"`    // 接收消息的微信账号
+	GroupID    string `json:"group_id" binding:"required"` // 群组ID
+	WxNickName string `json:"wx_nick_name"`                // 发送者昵称
+	Content    string `json:"content"`                     // 消息内容
+	MsgType    int    `json:"msg_type"`                    // 消息类型 1文本 3图片 10000系统消息 10002撤回通知
+	MsgTime    int64  `json:"msg_time"`                    // 消息时间戳
+}
+
+// 批量延期授权请求，owner_id 与 robot_id 至少提供一个
+type BatchExtendAuthRequest struct {
+	OwnerID    uint `json:"owner_id"`                      // 按所属公司筛选
+	RobotID    uint `json:"robot_id"`                      // 按机器人筛选
+	Days       int  `json:"days" binding:"required,min=1"` // 延期天数
+	WithinDays int  `json:"within_days" binding:"min=0"`   // 只处理N天内到期（含已过期）的用户，0表示全部
+}
+
+// 单个用户的延期结果
+type BatchExtendAuthResult struct {
+	UserID     uint   `json:"user_id"`
+	RobotID    uint   `json:"robot_id"`
+	WxID       string `json:"wx_id"`
+	NickName   string `json:"nick_name"`
+	Success    bool   `json:"success"`
+	ExpiryDate string `json:"expiry_date,omitempty"` // 延期后的到期日期
+	Error      string `json:"error,omitempty"`
+}
+
+// 批量延期授权响应
+type BatchExtendAuthResponse struct {
+	Total   int                     `json:"total"`
+	Success int                     `json:"success"`
+	Failed  int                     `json:"failed"`
+	Results []BatchExtendAuthResult `json:"results"`
+}
+
+// 群组变化查询请求
+type GroupChangesRequest struct {
+	WxID      string `form:"wx_id"`                         // 机器人微信ID，不传查询全部
+	OwnerID   uint   `form:"owner_id"`                      // 所属公司ID，不传查询全部
+	StartTime string `form:"start_time" binding:"required"` // 开始时间，格式：yyyy-mm-dd hh:mi:ss
+	EndTime   string `form:"end_time" binding:"required"`   // 结束时间，格式：yyyy-mm-dd hh:mi:ss
+}
+
+// 群组变化查询响应
+type GroupChangesResponse struct {
+	Added []WxGroup         `json:"added"` // 时间段内新增的群
+	Lost  []WxGroupLeaveLog `json:"lost"`  // 时间段内流失的群
+}
+
+// 机器人健康检查结果
+type RobotHealthResult struct {
+	RobotID        uint   `json:"robot_id"`
+	Address        string `json:"address"`
+	Status         string `json:"status"` // healthy / unhealthy
+	ResponseTime   string `json:"response_time"`
+	ResponseTimeMs int64  `json:"response_time_ms"`
+	Error          string `json:"error,omitempty"`
+}
+
+// 批量健康检查响应
+type RobotHealthSummary struct {
+	Total     int                 `json:"total"`
+	Healthy   int                 `json:"healthy"`
+	Unhealthy int                 `json:"unhealthy"`
+	Results   []RobotHealthResult `json:"results"`
+}
+
+// 发送者信息，标识实际发送消息的机器人与账号，token已脱敏
+type SenderInfo struct {
+	RobotID  uint   `json:"robot_id"`
+	UserID   uint   `json:"user_id"`
+	WxID     string `json:"wx_id"`
+	NickName string `json:"nick_name"`
+	Token    string `json:"token"`
+}
+
+// 文本消息发送响应，外部接口返回字段保持不变，附带发送者信息
+type SendTextMessageResponse struct {
+	*SendTextResponse
+	Sender SenderInfo `json:"sender"`
+}
+
+// 图片消息发送响应，外部接口返回字段保持不变，附带发送者信息
+type SendImageMessageResponse struct {
+	*SendImageResponse
+	Sender SenderInfo `json:"sender"`
+}
+
+// 语音消息发送响应，外部接口返回字段保持不变，附带发送者信息
+type SendVoiceMessageResponse struct {
+	*SendVoiceResponse
+	Sender SenderInfo `json:"sender"`
+}
+
+// 视频消息发送响应，外部接口返回字段保持不变，附带发送者信息
+type SendVideoMessageResponse struct {
+	*SendVideoResponse
+	Sender SenderInfo `json:"sender"`
+}
+
+// 链接卡片消息发送响应，外部接口返回字段保持不变，附带发送者信息
+type SendAppMsgMessageResponse struct {
+	*SendAppMsgResponse
+	Sender SenderInfo `json:"sender"`
+}
+
+// 授权码生成数量与有效天数的默认值，上限由请求参数校验保证
+const (
+	DefaultAuthKeyCount = 1
+	DefaultAuthKeyDays  = 365
+)
+
+// 获取授权信息请求，count、days不传时使用默认值
+type AuthorizeUserRequest struct {
+	RobotID uint `json:"robot_id" binding:"required"`
+	Count   int  `json:"count" binding:"omitempty,min=1,max=20"` // 生成授权码数量，默认1，最大20
+	Days    int  `json:"days" binding:"omitempty,min=1,max=365"` // 授权有效天数，默认365，最大365
+}
+
+// 授权码分配状态
+const (
+	AuthKeyStatusAssigned   = "assigned"   // 已有账号使用
+	AuthKeyStatusUnassigned = "unassigned" // 未分配，可复用
+)
+
+// 授权码生成来源
+const (
+	AuthKeySourceManual      = "manual"       // 调用生成授权码接口
+	AuthKeySourceRotateProbe = "rotate_probe" // 轮换管理密钥时为验证新密钥生成
+)
+
+// 机器人授权码查询请求
+type AuthKeyListRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=assigned unassigned"` // 分配状态，不传查询全部
+}
+
+// 授权码及其分配状态
+type AuthKeyInfo struct {
+	ID         uint   `json:"id"`
+	AuthKey    string `json:"auth_key"`
+	Days       int    `json:"days"`
+	ExpireTime string `json:"expire_time"`
+	CreateTime string `json:"create_time"`
+	Status     string `json:"status"`
+	Source     string `json:"source"`            // 生成来源 manual/rotate_probe
+	UserID     uint   `json:"user_id,omitempty"` // 使用该授权码的账号
+	WxID       string `json:"wx_id,omitempty"`
+	NickName   string `json:"nick_name,omitempty"`
+}
+
+// 机器人授权码查询响应
+type AuthKeyListResponse struct {
+	RobotID    uint          `json:"robot_id"`
+	Total      int           `json:"total"`
+	Assigned   int           `json:"assigned"`
+	Unassigned int           `json:"unassigned"`
+	Keys       []AuthKeyInfo `json:"keys"`
+}
+
+// 轮换机器人管理密钥请求
+type RotateAdminKeyRequest struct {
+	NewAdminKey string `json:"new_admin_key" binding:"required"` // 已在底层服务配置好的新管理密钥
+}
+
+// 轮换机器人管理密钥响应，密钥已脱敏
+type RotateAdminKeyResponse struct {
+	RobotID   uint   `json:"robot_id"`
+	AdminKey  string `json:"admin_key"`
+	RotatedAt string `json:"rotated_at"`
+}
+
+// 机器人列表查询请求
+type RobotListRequest struct {
+	CreateTimeStart string `form:"create_time_start"`                                            // 创建时间开始，格式：yyyy-mm-dd hh:mi:ss
+	CreateTimeEnd   string `form:"create_time_end"`                                              // 创建时间结束，格式：yyyy-mm-dd hh:mi:ss
+	SortBy          string `form:"sort_by" binding:"omitempty,oneof=id create_time update_time"` // 排序字段，默认id
+	Order           string `form:"order" binding:"omitempty,oneof=asc desc"`                     // 排序方向，默认asc
+	OwnerID         uint   `form:"owner_id"`                                                     // 所属公司ID，不传查询全部
+}
+
+// 机器人概览：挂载用户的状态分布与能力
+type RobotSummaryResponse struct {
+	RobotID       uint   `json:"robot_id"`
+	Address       string `json:"address"`
+	Description   string `json:"description"`
+	TotalUsers    int64  `json:"total_users"`     // 挂载的用户总数
+	NormalUsers   int64  `json:"normal_users"`    // 状态正常
+	RiskUsers     int64  `json:"risk_users"`      // 风控
+	ReloginUsers  int64  `json:"relogin_users"`   // 需要重新登录
+	SecurityRisk  int64  `json:"security_risk"`   // 有安全风险标记
+	Initialized   int64  `json:"initialized"`     // 已完成初始化
+	MessageBots   int64  `json:"message_bots"`    // 消息机器人数量
+	HasMessageBot bool   `json:"has_message_bot"` // 是否有正常状态的消息机器人
+}
+
+// 按token反查用户请求，token也可通过查询参数传入
+type UserByTokenRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// 按token反查用户响应，token已脱敏
+type UserByTokenResponse struct {
+	UserID           uint   `json:"user_id"`
+	WxID             string `json:"wx_id"`
+	NickName         string `json:"nick_name"`
+	Token            string `json:"token"`
+	Status           int    `json:"status"`
+	IsMessageBot     int    `json:"is_message_bot"`
+	ExpirationTime   string `json:"expiration_time"`
+	RobotID          uint   `json:"robot_id"`
+	RobotAddress     string `json:"robot_address"`
+	RobotDescription string `json:"robot_description"`
+	OwnerID          uint   `json:"owner_id"`
+}
+
+// 更新用户备注请求
+type UpdateUserRemarkRequest struct {
+	Remark string `json:"remark" binding:"max=200"` // 运营备注，传空字符串清除备注
+}
+
+// 更新用户自动续期请求
+type UpdateUserAutoRenewRequest struct {
+	AutoRenew *int `json:"auto_renew" binding:"required,oneof=0 1"` // 1到期前自动续期 0关闭
+}
+
+// 更新用户签名请求
+type UpdateUserSignatureRequest struct {
+	Signature string `json:"signature" binding:"max=100"`                      // 签名内容，传空字符串清除签名
+	Position  string `json:"position" binding:"omitempty,oneof=prefix suffix"` // 签名位置，默认suffix
+}
+
+// 群候选消息机器人
+type GroupBotCandidate struct {
+	UserID       uint   `json:"user_id"`
+	WxID         string `json:"wx_id"`
+	NickName     string `json:"nick_name"`
+	RobotID      uint   `json:"robot_id"`
+	RobotAddress string `json:"robot_address"`
+}
+
+// 可用消息机器人查询请求
+type AvailableMessageBotsRequest struct {
+	OwnerID uint `form:"owner_id"` // 所属公司ID，不传查询全部
+}
+
+// 可用消息机器人
+type AvailableMessageBot struct {
+	UserID       uint   `json:"user_id"`
+	WxID         string `json:"wx_id"`
+	NickName     string `json:"nick_name"`
+	RobotID      uint   `json:"robot_id"`
+	RobotAddress string `json:"robot_address"`
+	OwnerID      uint   `json:"owner_id"`
+	GroupCount   int64  `json:"group_count"` // 覆盖群数
+}
+
+// 可用消息机器人列表响应
+type AvailableMessageBotsResponse struct {
+	Total int                   `json:"total"`
+	Bots  []AvailableMessageBot `json:"bots"`
+}
+
+// 消息机器人分配建议动作
+const (
+	CoverageActionEnableBot = "enable_message_bot" // 账号已在群内，设为消息机器人即可
+	CoverageActionJoinGroup = "join_group"         // 已有的消息机器人加入该群
+)
+
+// 消息机器人覆盖缺口查询请求
+type BotCoverageRequest struct {
+	OwnerID uint `form:"owner_id"`                               // 所属公司ID，不传查询全部
+	Limit   int  `form:"limit" binding:"omitempty,min=1,max=10"` // 每个群的加群建议数量，默认3
+}
+
+// 分配建议的候选账号
+type BotCoverageCandidate struct {
+	UserID     uint   `json:"user_id"`
+	WxID       string `json:"wx_id"`
+	NickName   string `json:"nick_name"`
+	RobotID    uint   `json:"robot_id"`
+	GroupCount int64  `json:"group_count,omitempty"` // 消息机器人当前覆盖群数，加群建议按此从少到多排序
+	Action     string `json:"action"`                // enable_message_bot / join_group
+}
+
+// 缺少消息机器人覆盖的群及分配建议
+type BotCoverageGap struct {
+	GroupID       string                 `json:"group_id"`
+	GroupNickName string                 `json:"group_nick_name"`
+	Suggestions   []BotCoverageCandidate `json:"suggestions"` // 为空表示同公司没有可用账号
+}
+
+// 消息机器人覆盖缺口响应
+type BotCoverageResponse struct {
+	UncoveredGroups int              `json:"uncovered_groups"`
+	Gaps            []BotCoverageGap `json:"gaps"`
+}
+
+// 用户搜索请求
+type UserSearchRequest struct {
+	Keyword  string `form:"keyword" binding:"required"` // 按 wx_id 或 nick_name 模糊匹配
+	OwnerID  uint   `form:"owner_id"`                   // 所属公司ID
+	Status   int    `form:"status" binding:"omitempty,oneof=1 2 3"`
+	PageNo   int    `form:"page_no,default=1" binding:"min=1"`
+	PageSize int    `form:"page_size,default=10" binding:"min=1,max=100"`
+}
+
+// 用户搜索分页响应
+type UserSearchPaginatedResponse struct {
+	List       []WxUserLogin  `json:"list"`
+	Pagination PaginationInfo `json:"pagination"`
+}
+
+// 需重新登录用户查询请求
+type NeedReloginRequest struct {
+	OwnerID  uint `form:"owner_id"` // 所属公司ID
+	RobotID  uint `form:"robot_id"` // 机器人ID
+	PageNo   int  `form:"page_no,default=1" binding:"min=1"`
+	PageSize int  `form:"page_size,default=10" binding:"min=1,max=100"`
+}
+
+// reloginQRCodeURL 按用户ID批量获取重登二维码的接口地址
+const reloginQRCodeURL = "/api/wx/v1/users/need-relogin/qrcodes"
+
+// 需重新登录的用户，token 已脱敏，登录二维码以 user_id 调用 qrcode_url（POST，body为{"user_ids":[user_id]}）获取
+type NeedReloginUser struct {
+	UserID     uint      `json:"user_id"`
+	RobotID    uint      `json:"robot_id"`
+	OwnerID    uint      `json:"owner_id"`
+	WxID       string    `json:"wx_id"`
+	NickName   string    `json:"nick_name"`
+	Remark     string    `json:"remark"`
+	Token      string    `json:"token"`       // 脱敏后的token
+	UpdateTime time.Time `json:"update_time"` // 最近一次状态更新时间
+	QRCodeURL  string    `json:"qrcode_url"`  // 批量获取重登二维码接口
+}
+
+// 需重新登录用户分页响应
+type NeedReloginPaginatedResponse struct {
+	List       []NeedReloginUser `json:"list"`
+	Pagination PaginationInfo    `json:"pagination"`
+}
+
+// 批量获取重登二维码请求
+type ReloginQRCodeBatchRequest struct {
+	UserIDs []uint `json:"user_ids" binding:"required,min=1,max=20"`
+	Check   bool   `json:"check"` // 是否先尝试二次登录（无需扫码）
+}
+
+// 单个用户的重登二维码结果
+type ReloginQRCodeResult struct {
+	UserID   uint            `json:"user_id"`
+	WxID     string          `json:"wx_id"`
+	NickName string          `json:"nick_name"`
+	Success  bool            `json:"success"`
+	Message  string          `json:"message"`
+	QRCode   *QRCodeResponse `json:"qr_code,omitempty"`
+}
+
+// 全部群组分页查询请求
+type GroupListRequest struct {
+	GroupNickName string `form:"group_nick_name"` // 群名称模糊匹配
+	OwnerID       uint   `form:"owner_id"`        // 所属公司ID，只返回该公司账号所在的群
+	PageNo        int    `form:"page_no,default=1" binding:"min=1"`
+	PageSize      int    `form:"page_size,default=10" binding:"min=1,max=100"`
+}
+
+// 群组导出请求
+type GroupExportRequest struct {
+	WxID    string `form:"wx_id"`    // 只导出该账号所在的群
+	OwnerID uint   `form:"owner_id"` // 只导出该公司账号所在的群
+}
+
+// 群同步变更的群
+type GroupSyncItem struct {
+	GroupID       string `json:"group_id"`
+	GroupNickName string `json:"group_nick_name"`
+}
+
+// 群同步时改名的群
+type GroupRenameItem struct {
+	GroupID     string `json:"group_id"`
+	OldNickName string `json:"old_nick_name"`
+	NewNickName string `json:"new_nick_name"`
+}
+
+// 群同步变更摘要
+type GroupSyncSummary struct {
+	WxID    string            `json:"wx_id"`
+	Total   int               `json:"total"`   // 同步后的群数量
+	Added   []GroupSyncItem   `json:"added"`   // 新增的群
+	Removed []GroupSyncItem   `json:"removed"` // 已退出或解散而删除的群
+	Renamed []GroupRenameItem `json:"renamed"` // 改名的群
+}
+
+// 添加群黑名单请求
+type AddGroupBlacklistRequest struct {
+	GroupID string `json:"group_id" binding:"required"`
+	Reason  string `json:"reason" binding:"max=200"` // 加入黑名单原因
+}
+
+// 群组概览，同一个群被多个账号加入时合并为一条
+type GroupOverview struct {
+	GroupID       string   `json:"group_id"`
+	GroupNickName string   `json:"group_nick_name"`
+	WxIDs         []string `json:"wx_ids"`      // 覆盖该群的账号
+	CreateTime    string   `json:"create_time"` // 最早入群时间
+}
+
+// 全部群组分页响应
+type GroupListPaginatedResponse struct {
+	List       []GroupOverview `json:"list"`
+	Pagination PaginationInfo  `json:"pagination"`
+}
+
+// 一条文本发送到多个群的请求
+type SendTextMultiRequest struct {
+	TextContent string   `json:"text_content" binding:"required"`
+	ToUserNames []string `json:"to_user_names" binding:"required,min=1"`
+	Priority    string   `json:"priority" binding:"omitempty,oneof=high normal"` // 发送优先级，默认normal
+	// 是否确认向超大群发送，未确认时成员数超过阈值的群会发送失败
+	ConfirmLargeGroup bool `json:"confirm_large_group"`
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 自动回复关键词匹配方式
+const (
+	AutoReplyMatchContains = "contains" // 消息包含关键词
+	AutoReplyMatchExact    = "exact"    // 消息去掉首尾空白后与关键词完全一致
+)
+
+// autoReplyRule 加载后的自动回复规则
+type autoReplyRule struct {
+	name     string
+	keywords []string
+	exact    bool
+	reply    string
+	groups   map[string]bool // 启用的群，为空时全部群启用
+}
+
+// AutoReplier 关键词自动回复规则引擎
+// 防循环：同一群同一规则在冷却时间内只回复一次；冷却时间内收到的消息包含本群刚自动回复过的内容时视为回显，不再匹配
+type AutoReplier struct {
+	rules    []autoReplyRule
+	cooldown *windowLimiter
+	window   time.Duration
+
+	mu      sync.Mutex
+	replies map[string]map[string]time.Time // 群ID -> 自动回复内容 -> 回复时间
+
+	logger *zap.Logger
+}
+
+// NewAutoReplier 创建自动回复规则引擎，关键词或回复内容为空的规则会被跳过
+func NewAutoReplier(cfg AutoReplyConfig, logger *zap.Logger) *AutoReplier {
+	window := cfg.Cooldown
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	replier := &AutoReplier{
+		cooldown: newWindowLimiter(1, window),
+		window:   window,
+		replies:  make(map[string]map[string]time.Time),
+		logger:   logger,
+	}
+	for _, rc := range cfg.Rules {
+		rule := autoReplyRule{
+			name:  rc.Name,
+			exact: rc.Match == AutoReplyMatchExact,
+			reply: strings.TrimSpace(rc.Reply),
+		}
+		for _, keyword := range rc.Keywords {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				rule.keywords = append(rule.keywords, keyword)
+			}
+		}
+		if len(rule.keywords) == 0 || rule.reply == "" {
+			logger.Error("自动回复规则缺少关键词或回复内容，已跳过", zap.String("name", rc.Name))
+			continue
+		}
+		// 未命名的规则以第一个关键词命名，规则名用于区分冷却
+		if rule.name == "" {
+			rule.name = rule.keywords[0]
+		}
+		if len(rc.Groups) > 0 {
+			rule.groups = make(map[string]bool, len(rc.Groups))
+			for _, groupID := range rc.Groups {
+				rule.groups[groupID] = true
+			}
+		}
+		replier.rules = append(replier.rules, rule)
+	}
+
+	logger.Info("自动回复规则加载完成", zap.Int("rule_count", len(replier.rules)))
+	return replier
+}
+
+// Match 按顺序匹配规则，返回第一个命中且不在冷却中的规则生成的回复内容
+func (r *AutoReplier) Match(msg *WxGroupMessage) (string, bool) {
+	content := strings.TrimSpace(msg.Content)
+	if msg.MsgType != MsgTypeText || content == "" {
+		return "", false
+	}
+	if r.isEcho(msg.GroupID, content) {
+		r.logger.Debug("消息为自动回复回显，不再匹配", zap.String("group_id", msg.GroupID))
+		return "", false
+	}
+
+	for _, rule := range r.rules {
+		if rule.groups != nil && !rule.groups[msg.GroupID] {
+			continue
+		}
+		if !rule.match(content) {
+			continue
+		}
+		if ok, _ := r.cooldown.Allow(msg.GroupID + "\x00" + rule.name); !ok {
+			r.logger.Debug("自动回复规则冷却中，跳过",
+				zap.String("group_id", msg.GroupID),
+				zap.String("rule", rule.name))
+			return "", false
+		}
+
+		reply := strings.ReplaceAll(rule.reply, "{nick_name}", msg.WxNickName)
+		r.remember(msg.GroupID, reply)
+		r.logger.Info("命中自动回复规则",
+			zap.String("group_id", msg.GroupID),
+			zap.String("rule", rule.name))
+		return reply, true
+	}
+	return "", false
+}
+
+// match 判断消息是否命中规则的任一关键词
+func (rule autoReplyRule) match(content string) bool {
+	for _, keyword := range rule.keywords {
+		if rule.exact && content == keyword {
+			return true
+		}
+		if !rule.exact && strings.Contains(content, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// remember 记录本群自动回复的内容，用于识别回显
+func (r *AutoReplier) remember(groupID, reply string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.replies[groupID] == nil {
+		r.replies[groupID] = make(map[string]time.Time)
+	}
+	r.replies[groupID][reply] = time.Now()
+}
+
+// isEcho 消息是否包含本群冷却时间内自动回复过的内容（回复可能被拼接签名）
+func (r *AutoReplier) isEcho(groupID, content string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	echo := false
+	for reply, at := range r.replies[groupID] {
+		if now.Sub(at) > r.window {
+			delete(r.replies[groupID], reply)
+			continue
+		}
+		if strings.Contains(content, reply) {
+			echo = true
+		}
+	}
+	if len(r.replies[groupID]) == 0 {
+		delete(r.replies, groupID)
+	}
+	return echo
+}
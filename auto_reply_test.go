@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAutoReplierMatch(t *testing.T) {
+	type message struct {
+		group     string
+		content   string
+		msgType   int // 0表示文本
+		wait      time.Duration
+		wantReply string // 为空表示不回复
+	}
+	text := func(group, content, wantReply string) message {
+		return message{group: group, content: content, wantReply: wantReply}
+	}
+	greeting := AutoReplyRuleConfig{Name: "greeting", Keywords: []string{"在吗", "有人吗"}, Reply: "您好{nick_name}，请问有什么可以帮您？"}
+
+	tests := []struct {
+		name     string
+		cfg      AutoReplyConfig
+		messages []message
+	}{
+		{
+			name: "keyword contained",
+			cfg:  AutoReplyConfig{Rules: []AutoReplyRuleConfig{greeting}},
+			messages: []message{
+				text("g1", "老板在吗？", "您好小王，请问有什么可以帮您？"),
+				text("g2", "有人吗", "您好小王，请问有什么可以帮您？"),
+				text("g3", "今天下雨", ""),
+			},
+		},
+		{
+			name: "non text ignored",
+			cfg:  AutoReplyConfig{Rules: []AutoReplyRuleConfig{greeting}},
+			messages: []message{
+				{group: "g1", content: "在吗", msgType: MsgTypeImage},
+			},
+		},
+		{
+			name: "exact match",
+			cfg:  AutoReplyConfig{Rules: []AutoReplyRuleConfig{{Keywords: []string{"价格"}, Match: AutoReplyMatchExact, Reply: "请查看价目表"}}},
+			messages: []message{
+				text("g1", "请问价格多少", ""),
+				text("g1", " 价格 ", "请查看价目表"),
+			},
+		},
+		{
+			name: "cooldown per group and rule",
+			cfg:  AutoReplyConfig{Cooldown: 50 * time.Millisecond, Rules: []AutoReplyRuleConfig{greeting}},
+			messages: []message{
+				text("g1", "在吗", "您好小王，请问有什么可以帮您？"),
+				text("g1", "在吗", ""),
+				text("g2", "在吗", "您好小王，请问有什么可以帮您？"),
+				{group: "g1", content: "在吗", wait: 60 * time.Millisecond, wantReply: "您好小王，请问有什么可以帮您？"},
+			},
+		},
+		{
+			name: "echo of own reply ignored",
+			cfg:  AutoReplyConfig{Rules: []AutoReplyRuleConfig{{Name: "loop", Keywords: []string{"帮您"}, Reply: "很高兴帮您"}, {Name: "thanks", Keywords: []string{"谢谢"}, Reply: "不客气"}}},
+			messages: []message{
+				text("g1", "能帮您吗", "很高兴帮您"),
+				text("g1", "很高兴帮您——来自客服 谢谢", ""),
+			},
+		},
+		{
+			name: "enabled groups only",
+			cfg:  AutoReplyConfig{Rules: []AutoReplyRuleConfig{{Keywords: []string{"在吗"}, Reply: "在的", Groups: []string{"g1"}}}},
+			messages: []message{
+				text("g2", "在吗", ""),
+				text("g1", "在吗", "在的"),
+			},
+		},
+		{
+			name: "first matching rule wins",
+			cfg:  AutoReplyConfig{Rules: []AutoReplyRuleConfig{{Keywords: []string{"发票"}, Reply: "请联系财务"}, {Keywords: []string{"在吗"}, Reply: "在的"}}},
+			messages: []message{
+				text("g1", "在吗，要开发票", "请联系财务"),
+			},
+		},
+		{
+			name: "invalid rules skipped",
+			cfg:  AutoReplyConfig{Rules: []AutoReplyRuleConfig{{Keywords: []string{" "}, Reply: "空关键词"}, {Keywords: []string{"在吗"}}}},
+			messages: []message{
+				text("g1", "在吗", ""),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replier := NewAutoReplier(tt.cfg, zap.NewNop())
+			for i, m := range tt.messages {
+				time.Sleep(m.wait)
+				msgType := m.msgType
+				if msgType == 0 {
+					msgType = MsgTypeText
+				}
+				reply, ok := replier.Match(&WxGroupMessage{GroupID: m.group, Content: m.content, MsgType: msgType, WxNickName: "小王"})
+				if ok != (m.wantReply != "") || reply != m.wantReply {
+					t.Fatalf("message %d Match(%q) = %q, %v, want %q", i, m.content, reply, ok, m.wantReply)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessAutoReply(t *testing.T) {
+	const groupID = "reply@chatroom"
+
+	tests := []struct {
+		name        string
+		content     string
+		blacklisted bool
+		wantReply   string
+	}{
+		{name: "keyword replied", content: "客服在吗", wantReply: "您好小王"},
+		{name: "no keyword", content: "收到"},
+		{name: "blacklisted group", content: "客服在吗", blacklisted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := make(chan SendTextMsgItem, 1)
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+					var req SendTextMessageRequest
+					if err := json.NewDecoder(r.Body).Decode(&req); err == nil && len(req.MsgItem) == 1 {
+						sent <- req.MsgItem[0]
+					}
+					jsonHandler(sendSuccessResponse(1))(w, r)
+				},
+			})
+			cfg := &Config{}
+			cfg.AutoReply = AutoReplyConfig{Enable: true, Rules: []AutoReplyRuleConfig{{Keywords: []string{"在吗"}, Reply: "您好{nick_name}"}}}
+			svc, db := newTestService(t, cfg)
+			bot := &WxUserLogin{WxID: "wxid_reply", Token: "token-reply", IsMessageBot: 1}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+			db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+			if tt.blacklisted {
+				db.Create(&WxGroupBlacklist{GroupID: groupID})
+			}
+
+			svc.msgRouter.Dispatch(&WxGroupMessage{GroupID: groupID, Content: tt.content, MsgType: MsgTypeText, WxNickName: "小王"})
+
+			wait := time.Second
+			if tt.wantReply == "" {
+				wait = 100 * time.Millisecond
+			}
+			select {
+			case item := <-sent:
+				if item.TextContent != tt.wantReply || item.ToUserName != groupID {
+					t.Fatalf("sent %q to %s, want %q to %s", item.TextContent, item.ToUserName, tt.wantReply, groupID)
+				}
+			case <-time.After(wait):
+				if tt.wantReply != "" {
+					t.Fatal("auto reply not sent")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// defaultBillParseRules 内置账单识别规则，配置文件未配置规则时使用
+// 命名分组: dollar 外币金额、rate 汇率、amount 人民币金额、operator 操作人
+var defaultBillParseRules = []BillParseRuleConfig{
+	{
+		Name:    "下单外币带汇率",
+		Pattern: `下单\s*(?P<dollar>\d+(?:\.\d+)?)\s*(?:USD|usd|U|u|美金|美元)?\s*汇率\s*(?P<rate>\d+(?:\.\d+)?)`,
+	},
+	{
+		Name:    "下单人民币",
+		Pattern: `下单\s*(?P<amount>\d+(?:\.\d+)?)\s*(?:RMB|rmb|元)`,
+	},
+}
+
+// BillParser 账单解析器接口，从群消息中识别账单
+type BillParser interface {
+	// Parse 解析消息，未命中任何规则时返回false
+	Parse(msg *WxGroupMessage) (*WxBillInfo, bool)
+}
+
+// billParseRule 编译后的账单识别规则
+type billParseRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// RegexBillParser 基于正则规则的账单解析器
+type RegexBillParser struct {
+	rules  []billParseRule
+	logger *zap.Logger
+}
+
+// NewRegexBillParser 创建正则账单解析器，无效规则会被跳过
+func NewRegexBillParser(ruleConfigs []BillParseRuleConfig, logger *zap.Logger) *RegexBillParser {
+	if len(ruleConfigs) == 0 {
+		ruleConfigs = defaultBillParseRules
+	}
+
+	parser := &RegexBillParser{logger: logger}
+	for _, rc := range ruleConfigs {
+		re, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			logger.Error("账单识别规则无效，已跳过", zap.String("name", rc.Name), zap.Error(err))
+			continue
+		}
+		parser.rules = append(parser.rules, billParseRule{name: rc.Name, re: re})
+	}
+
+	logger.Info("账单识别规则加载完成", zap.Int("rule_count", len(parser.rules)))
+	return parser
+}
+
+// Parse 按顺序匹配规则，第一个命中且金额合法的规则生效
+func (p *RegexBillParser) Parse(msg *WxGroupMessage) (*WxBillInfo, bool) {
+	if msg.MsgType != MsgTypeText || strings.TrimSpace(msg.Content) == "" {
+		return nil, false
+	}
+
+	for _, rule := range p.rules {
+		match := rule.re.FindStringSubmatch(msg.Content)
+		if match == nil {
+			continue
+		}
+
+		fields := make(map[string]string)
+		for i, name := range rule.re.SubexpNames() {
+			if name != "" && i < len(match) {
+				fields[name] = strings.TrimSpace(match[i])
+			}
+		}
+
+		bill, err := buildParsedBill(msg, fields)
+		if err != nil {
+			p.logger.Debug("账单识别规则命中但解析失败",
+				zap.String("rule", rule.name),
+				zap.String("content", msg.Content),
+				zap.Error(err))
+			continue
+		}
+
+		p.logger.Info("群消息识别为账单",
+			zap.String("rule", rule.name),
+			zap.String("group_id", msg.GroupID),
+			zap.String("amount", bill.Amount))
+		return bill, true
+	}
+
+	return nil, false
+}
+
+// buildParsedBill 根据规则提取的字段构建账单，未提供人民币金额时按 外币金额*汇率 计算
+func buildParsedBill(msg *WxGroupMessage, fields map[string]string) (*WxBillInfo, error) {
+	bill := &WxBillInfo{
+		GroupID:  msg.GroupID,
+		Dollar:   fields["dollar"],
+		Rate:     fields["rate"],
+		Remark:   msg.Content,
+		Operator: fields["operator"],
+		MsgTime:  msg.MsgTime,
+		Status:   "0",
+		OwnerID:  msg.OwnerID,
+	}
+	if bill.Operator == "" {
+		bill.Operator = msg.WxNickName
+	}
+
+	if amountStr := fields["amount"]; amountStr != "" {
+		amount, err := strconv.ParseFloat(amountStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("金额格式错误: %s", amountStr)
+		}
+		bill.Amount = fmt.Sprintf("%.2f", amount)
+		return bill, nil
+	}
+
+	if bill.Dollar == "" || bill.Rate == "" {
+		return nil, fmt.Errorf("缺少金额或汇率")
+	}
+
+	dollar, err := strconv.ParseFloat(bill.Dollar, 64)
+	if err != nil {
+		return nil, fmt.Errorf("外币金额格式错误: %s", bill.Dollar)
+	}
+	rate, err := strconv.ParseFloat(bill.Rate, 64)
+	if err != nil {
+		return nil, fmt.Errorf("汇率格式错误: %s", bill.Rate)
+	}
+
+	bill.Amount = fmt.Sprintf("%.2f", dollar*rate)
+	return bill, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRegexBillParser(t *testing.T) {
+	parser := NewRegexBillParser(nil, zap.NewNop())
+
+	tests := []struct {
+		name       string
+		msg        WxGroupMessage
+		wantOK     bool
+		wantAmount string
+		wantDollar string
+		wantRate   string
+	}{
+		{name: "dollar with rate", msg: WxGroupMessage{MsgType: MsgTypeText, Content: "下单 100U 汇率 7.2"}, wantOK: true, wantAmount: "720.00", wantDollar: "100", wantRate: "7.2"},
+		{name: "rmb amount", msg: WxGroupMessage{MsgType: MsgTypeText, Content: "下单500元"}, wantOK: true, wantAmount: "500.00"},
+		{name: "plain chat", msg: WxGroupMessage{MsgType: MsgTypeText, Content: "今天下午开会"}},
+		{name: "non text message", msg: WxGroupMessage{MsgType: MsgTypeText + 2, Content: "下单500元"}},
+		{name: "blank content", msg: WxGroupMessage{MsgType: MsgTypeText, Content: "  "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.GroupID = "123@chatroom"
+			tt.msg.WxNickName = "张三"
+			tt.msg.OwnerID = 7
+			bill, ok := parser.Parse(&tt.msg)
+			if ok != tt.wantOK {
+				t.Fatalf("Parse(%q) ok = %v, want %v", tt.msg.Content, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if bill.Amount != tt.wantAmount || bill.Dollar != tt.wantDollar || bill.Rate != tt.wantRate {
+				t.Fatalf("bill = amount %s dollar %s rate %s, want %s %s %s", bill.Amount, bill.Dollar, bill.Rate, tt.wantAmount, tt.wantDollar, tt.wantRate)
+			}
+			if bill.GroupID != "123@chatroom" || bill.Operator != "张三" || bill.OwnerID != 7 || bill.Remark != tt.msg.Content {
+				t.Fatalf("bill fields not copied from message: %+v", bill)
+			}
+		})
+	}
+}
+
+func TestRegexBillParserCustomRules(t *testing.T) {
+	parser := NewRegexBillParser([]BillParseRuleConfig{
+		{Name: "无效规则", Pattern: `(`},
+		{Name: "入账", Pattern: `(?P<operator>\S+)入账(?P<amount>\d+)`},
+	}, zap.NewNop())
+
+	bill, ok := parser.Parse(&WxGroupMessage{MsgType: MsgTypeText, Content: "李四入账300", WxNickName: "张三"})
+	if !ok {
+		t.Fatal("custom rule not matched")
+	}
+	if bill.Amount != "300.00" || bill.Operator != "李四" {
+		t.Fatalf("bill = %+v, want amount 300.00 operator 李四", bill)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 账单金额合法范围
+const (
+	maxBillAmount = 100000000.0 // 人民币金额上限
+	maxBillDollar = 100000000.0 // 外币金额上限
+	maxBillRate   = 1000.0      // 汇率上限
+)
+
+// ErrInvalidBill 账单数据不合法
+var ErrInvalidBill = errors.New("账单数据不合法")
+
+// ErrBillOwnerMismatch 账单所属公司与群所属公司不一致，属于不合法账单
+var ErrBillOwnerMismatch = fmt.Errorf("%w: 账单所属公司与群所属公司不一致", ErrInvalidBill)
+
+// validateBill 校验账单金额、外币金额和汇率为合法的非负数值且在合理范围内
+// 目前不支持负数金额（如退款），此类账单需走单独的状态处理，直接拒绝
+func validateBill(bill *WxBillInfo) error {
+	if strings.TrimSpace(bill.Amount) == "" {
+		return fmt.Errorf("%w: 金额不能为空", ErrInvalidBill)
+	}
+	if err := validateBillNumber("金额", bill.Amount, maxBillAmount, true); err != nil {
+		return err
+	}
+	if err := validateBillNumber("外币金额", bill.Dollar, maxBillDollar, true); err != nil {
+		return err
+	}
+	if err := validateBillNumber("汇率", bill.Rate, maxBillRate, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateBillNumber 校验单个数值字段，空值视为未填写
+func validateBillNumber(field, value string, max float64, allowZero bool) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	num, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(num) || math.IsInf(num, 0) {
+		return fmt.Errorf("%w: %s格式错误: %s", ErrInvalidBill, field, value)
+	}
+	if num < 0 {
+		return fmt.Errorf("%w: %s不能为负数: %s", ErrInvalidBill, field, value)
+	}
+	if num == 0 && !allowZero {
+		return fmt.Errorf("%w: %s必须大于0", ErrInvalidBill, field)
+	}
+	if num > max {
+		return fmt.Errorf("%w: %s超出合理范围: %s", ErrInvalidBill, field, value)
+	}
+	return nil
+}
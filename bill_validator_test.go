@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidateBill(t *testing.T) {
+	tests := []struct {
+		name    string
+		bill    WxBillInfo
+		wantErr bool
+	}{
+		{name: "amount only", bill: WxBillInfo{Amount: "100.50"}},
+		{name: "with dollar and rate", bill: WxBillInfo{Amount: "710", Dollar: "100", Rate: "7.1"}},
+		{name: "zero amount", bill: WxBillInfo{Amount: "0"}},
+		{name: "amount upper bound", bill: WxBillInfo{Amount: "100000000"}},
+		{name: "empty amount", bill: WxBillInfo{Amount: "  "}, wantErr: true},
+		{name: "negative amount", bill: WxBillInfo{Amount: "-1"}, wantErr: true},
+		{name: "amount too large", bill: WxBillInfo{Amount: "100000000.01"}, wantErr: true},
+		{name: "amount not a number", bill: WxBillInfo{Amount: "abc"}, wantErr: true},
+		{name: "amount NaN", bill: WxBillInfo{Amount: "NaN"}, wantErr: true},
+		{name: "amount Inf", bill: WxBillInfo{Amount: "Inf"}, wantErr: true},
+		{name: "negative dollar", bill: WxBillInfo{Amount: "1", Dollar: "-5"}, wantErr: true},
+		{name: "zero rate", bill: WxBillInfo{Amount: "1", Rate: "0"}, wantErr: true},
+		{name: "rate too large", bill: WxBillInfo{Amount: "1", Rate: "1000.1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBill(&tt.bill)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateBill = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidBill) {
+				t.Fatalf("err = %v, want ErrInvalidBill", err)
+			}
+		})
+	}
+}
+
+func TestCreateBillRejectsInvalidAmount(t *testing.T) {
+	svc, db := newTestService(t, nil)
+
+	tests := []struct {
+		name    string
+		bill    WxBillInfo
+		wantErr bool
+	}{
+		{name: "valid", bill: WxBillInfo{GroupID: "g@chatroom", MsgTime: 1, Amount: "100"}},
+		{name: "negative amount", bill: WxBillInfo{GroupID: "g@chatroom", MsgTime: 2, Amount: "-100"}, wantErr: true},
+		{name: "abnormal rate", bill: WxBillInfo{GroupID: "g@chatroom", MsgTime: 3, Amount: "100", Rate: "99999"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.CreateBill(&tt.bill)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateBill = %v, wantErr %v", err, tt.wantErr)
+			}
+			var count int64
+			db.Model(&WxBillInfo{}).Where("msg_time = ?", tt.bill.MsgTime).Count(&count)
+			if stored := count > 0; stored == tt.wantErr {
+				t.Fatalf("stored = %v, wantErr %v", stored, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateBillChecksGroupOwner(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	// shared@chatroom 被公司1和公司2的账号同时加入
+	for _, owner := range []uint{1, 2} {
+		wxID := fmt.Sprintf("wxid_owner%d", owner)
+		createTestRobot(t, db, &WxRobotConfig{Address: fmt.Sprintf("http://robot%d.invalid", owner), OwnerID: owner}, &WxUserLogin{WxID: wxID})
+		groups := []WxGroup{{WxID: wxID, GroupID: fmt.Sprintf("owner%d@chatroom", owner)}, {WxID: wxID, GroupID: "shared@chatroom"}}
+		if err := db.Create(&groups).Error; err != nil {
+			t.Fatalf("create groups: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		bill    WxBillInfo
+		wantErr error
+	}{
+		{name: "same owner", bill: WxBillInfo{OwnerID: 1, GroupID: "owner1@chatroom", MsgTime: 1, Amount: "10"}},
+		{name: "owner mismatch", bill: WxBillInfo{OwnerID: 2, GroupID: "owner1@chatroom", MsgTime: 2, Amount: "10"}, wantErr: ErrBillOwnerMismatch},
+		{name: "shared group either owner", bill: WxBillInfo{OwnerID: 2, GroupID: "shared@chatroom", MsgTime: 3, Amount: "10"}},
+		{name: "shared group unrelated owner", bill: WxBillInfo{OwnerID: 3, GroupID: "shared@chatroom", MsgTime: 4, Amount: "10"}, wantErr: ErrBillOwnerMismatch},
+		{name: "unknown group allowed", bill: WxBillInfo{OwnerID: 3, GroupID: "unknown@chatroom", MsgTime: 5, Amount: "10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.CreateBill(&tt.bill)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateBill = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidBill) {
+				t.Fatalf("CreateBill = %v, want ErrInvalidBill", err)
+			}
+			var count int64
+			db.Model(&WxBillInfo{}).Where("msg_time = ?", tt.bill.MsgTime).Count(&count)
+			if stored := count > 0; stored != (tt.wantErr == nil) {
+				t.Fatalf("stored = %v, wantErr %v", stored, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// bodyLimit 请求体大小限制(MB)，<=0表示不限制；bodyLimits中配置了的路由使用单独的上限
+// 声明的长度超限时直接返回413，未声明长度的请求读取超限时报错
+func (rm *RouterManager) bodyLimit(defaultMB int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		maxMB := defaultMB
+		if routeMB, ok := rm.bodyLimits[c.FullPath()]; ok {
+			maxMB = routeMB
+		}
+		limit := maxMB << 20
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			rm.logger.Warn("请求体超过大小限制",
+				zap.String("path", c.FullPath()),
+				zap.Int64("content_length", c.Request.ContentLength),
+				zap.Int64("limit", limit),
+				zap.String("client_ip", c.ClientIP()))
+			rm.errorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("请求体过大，最大%dMB", maxMB))
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
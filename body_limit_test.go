@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	cfg := &Config{}
+	cfg.Server.MaxBodyMB = 1
+	cfg.Server.ImageMaxBodyMB = 3
+	router, _, _ := newTestRouter(t, cfg)
+
+	body := func(mb float64) string {
+		return `{"to_user_name":"g@chatroom","text_content":"` + strings.Repeat("a", int(mb*(1<<20))) + `"}`
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		chunked      bool
+		wantTooLarge bool
+		wantStatus   int // 非0时校验具体状态码
+	}{
+		{name: "small body", path: "/messages/group/send-text", body: body(0.1)},
+		{name: "over default limit", path: "/messages/group/send-text", body: body(1.5), wantTooLarge: true},
+		{name: "image route allows larger", path: "/messages/group/send-image", body: body(1.5)},
+		{name: "over image limit", path: "/messages/group/send-image", body: body(3.5), wantTooLarge: true},
+		{name: "chunked over limit rejected on read", path: "/messages/group/send-text", body: body(1.5), chunked: true, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/wx/v1"+tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if tooLarge := w.Code == http.StatusRequestEntityTooLarge; tooLarge != tt.wantTooLarge {
+				t.Fatalf("status = %d, want too large %v", w.Code, tt.wantTooLarge)
+			}
+			if tt.wantStatus != 0 && w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	mathrand "math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 群发任务状态
+const (
+	BroadcastTaskPending   = "pending"
+	BroadcastTaskRunning   = "running"
+	BroadcastTaskCompleted = "completed"
+)
+
+// broadcastTaskRetention 已完成任务在内存中的保留时长
+const broadcastTaskRetention = 24 * time.Hour
+
+// broadcastTaskTimeout 单个群发任务的最长执行时间，超时后未发送的群按失败记录
+const broadcastTaskTimeout = time.Hour
+
+// BroadcastTask 群发任务
+type BroadcastTask struct {
+	mu         sync.Mutex
+	id         string
+	traceID    string // 贯穿整个发送批次的追踪ID
+	ownerID    uint   // 提交任务的API Key绑定的公司ID，0表示未使用API Key
+	status     string
+	total      int
+	results    []BroadcastGroupResult
+	skipped    []BroadcastSkippedGroup
+	remaining  []string // 灰度发送时未抽中的群
+	success    int
+	failed     int
+	createTime time.Time
+	finishTime time.Time
+}
+
+// BroadcastTaskManager 群发任务管理器（内存存储）
+type BroadcastTaskManager struct {
+	mu     sync.RWMutex
+	tasks  map[string]*BroadcastTask
+	logger *zap.Logger
+}
+
+// NewBroadcastTaskManager 创建群发任务管理器
+func NewBroadcastTaskManager(logger *zap.Logger) *BroadcastTaskManager {
+	return &BroadcastTaskManager{
+		tasks:  make(map[string]*BroadcastTask),
+		logger: logger,
+	}
+}
+
+// Create 创建群发任务，skipped为发送前剔除的群，ownerID为提交任务的公司
+func (m *BroadcastTaskManager) Create(ownerID uint, total int, skipped []BroadcastSkippedGroup) *BroadcastTask {
+	if skipped == nil {
+		skipped = []BroadcastSkippedGroup{}
+	}
+	task := &BroadcastTask{
+		id:         newTaskID(),
+		traceID:    newTaskID(),
+		ownerID:    ownerID,
+		status:     BroadcastTaskPending,
+		total:      total,
+		results:    make([]BroadcastGroupResult, 0, total),
+		skipped:    skipped,
+		createTime: time.Now(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneLocked()
+	m.tasks[task.id] = task
+	return task
+}
+
+// Get 获取任务进度快照，ownerID不为0时只能查到该公司提交的任务
+func (m *BroadcastTaskManager) Get(taskID string, ownerID uint) (*BroadcastTaskProgress, bool) {
+	m.mu.RLock()
+	task, ok := m.tasks[taskID]
+	m.mu.RUnlock()
+
+	if !ok || (ownerID > 0 && task.ownerID != ownerID) {
+		return nil, false
+	}
+	return task.Progress(), true
+}
+
+// pruneLocked 清理超过保留时长的已完成任务，调用方需持有写锁
+func (m *BroadcastTaskManager) pruneLocked() {
+	for id, task := range m.tasks {
+		task.mu.Lock()
+		expired := task.status == BroadcastTaskCompleted && time.Since(task.finishTime) > broadcastTaskRetention
+		task.mu.Unlock()
+
+		if expired {
+			delete(m.tasks, id)
+		}
+	}
+}
+
+// ID 任务ID
+func (t *BroadcastTask) ID() string {
+	return t.id
+}
+
+// TraceID 发送批次追踪ID
+func (t *BroadcastTask) TraceID() string {
+	return t.traceID
+}
+
+// Start 标记任务开始执行
+func (t *BroadcastTask) Start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status = BroadcastTaskRunning
+}
+
+// Record 记录单个群的发送结果
+func (t *BroadcastTask) Record(result BroadcastGroupResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.results = append(t.results, result)
+	if result.Success {
+		t.success++
+	} else {
+		t.failed++
+	}
+}
+
+// Finish 标记任务完成
+func (t *BroadcastTask) Finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status = BroadcastTaskCompleted
+	t.finishTime = time.Now()
+}
+
+// SetRemaining 记录灰度发送时未抽中的群
+func (t *BroadcastTask) SetRemaining(groups []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remaining = groups
+}
+
+// Progress 生成任务进度快照
+func (t *BroadcastTask) Progress() *BroadcastTaskProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	progress := &BroadcastTaskProgress{
+		TaskID:     t.id,
+		TraceID:    t.traceID,
+		Status:     t.status,
+		Total:      t.total,
+		Sent:       len(t.results),
+		Success:    t.success,
+		Failed:     t.failed,
+		Results:    append([]BroadcastGroupResult(nil), t.results...),
+		Skipped:    t.skipped,
+		Remaining:  t.remaining,
+		CreateTime: FormatTime(t.createTime),
+	}
+	if !t.finishTime.IsZero() {
+		progress.FinishTime = FormatTime(t.finishTime)
+	}
+	return progress
+}
+
+// newTaskID 生成随机任务ID
+func newTaskID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+// sampleBroadcastGroups 灰度发送时随机抽取部分群，返回抽中的群和未抽中的群，均保持原顺序
+// rate和count都未指定时全部发送；按比例抽取时向上取整，因此至少抽1个
+func sampleBroadcastGroups(groups []string, rate float64, count int) ([]string, []string) {
+	n := len(groups)
+	switch {
+	case count > 0:
+		n = count
+	case rate > 0:
+		n = int(math.Ceil(float64(len(groups)) * rate))
+	}
+	if n >= len(groups) {
+		return groups, nil
+	}
+
+	picked := make(map[int]bool, n)
+	for _, i := range mathrand.Perm(len(groups))[:n] {
+		picked[i] = true
+	}
+	sampled := make([]string, 0, n)
+	remaining := make([]string, 0, len(groups)-n)
+	for i, group := range groups {
+		if picked[i] {
+			sampled = append(sampled, group)
+		} else {
+			remaining = append(remaining, group)
+		}
+	}
+	return sampled, remaining
+}
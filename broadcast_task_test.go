@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestBroadcastTaskProgress(t *testing.T) {
+	m := NewBroadcastTaskManager(zap.NewNop())
+	task := m.Create(0, 3, nil)
+
+	steps := []struct {
+		name        string
+		apply       func()
+		wantStatus  string
+		wantSent    int
+		wantSuccess int
+		wantFailed  int
+	}{
+		{name: "created", apply: func() {}, wantStatus: BroadcastTaskPending},
+		{name: "started", apply: task.Start, wantStatus: BroadcastTaskRunning},
+		{name: "first success", apply: func() { task.Record(BroadcastGroupResult{ToUserName: "1@chatroom", Success: true}) }, wantStatus: BroadcastTaskRunning, wantSent: 1, wantSuccess: 1},
+		{name: "second failed", apply: func() { task.Record(BroadcastGroupResult{ToUserName: "2@chatroom", Error: "发送失败"}) }, wantStatus: BroadcastTaskRunning, wantSent: 2, wantSuccess: 1, wantFailed: 1},
+		{name: "finished", apply: task.Finish, wantStatus: BroadcastTaskCompleted, wantSent: 2, wantSuccess: 1, wantFailed: 1},
+	}
+
+	for _, step := range steps {
+		step.apply()
+		progress, ok := m.Get(task.ID(), 0)
+		if !ok {
+			t.Fatalf("%s: task not found", step.name)
+		}
+		if progress.Status != step.wantStatus || progress.Total != 3 || progress.Sent != step.wantSent ||
+			progress.Success != step.wantSuccess || progress.Failed != step.wantFailed {
+			t.Fatalf("%s: progress = %+v", step.name, progress)
+		}
+		if (progress.FinishTime != "") != (step.wantStatus == BroadcastTaskCompleted) {
+			t.Fatalf("%s: finish_time = %q", step.name, progress.FinishTime)
+		}
+	}
+
+	if _, ok := m.Get("missing", 0); ok {
+		t.Fatal("unknown task id found")
+	}
+}
+
+func TestSampleBroadcastGroups(t *testing.T) {
+	groups := make([]string, 10)
+	position := make(map[string]int, len(groups))
+	for i := range groups {
+		groups[i] = fmt.Sprintf("%d@chatroom", i)
+		position[groups[i]] = i
+	}
+
+	tests := []struct {
+		name        string
+		rate        float64
+		count       int
+		wantSampled int
+	}{
+		{name: "no sampling", wantSampled: 10},
+		{name: "rate", rate: 0.3, wantSampled: 3},
+		{name: "rate rounds up", rate: 0.25, wantSampled: 3},
+		{name: "tiny rate picks one", rate: 0.01, wantSampled: 1},
+		{name: "full rate", rate: 1, wantSampled: 10},
+		{name: "count", count: 4, wantSampled: 4},
+		{name: "count over total", count: 20, wantSampled: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampled, remaining := sampleBroadcastGroups(groups, tt.rate, tt.count)
+			if len(sampled) != tt.wantSampled || len(sampled)+len(remaining) != len(groups) {
+				t.Fatalf("sampled %d remaining %d, want %d sampled of %d", len(sampled), len(remaining), tt.wantSampled, len(groups))
+			}
+			// 抽中与未抽中的群不重复、不遗漏，且都保持原顺序
+			merged := append(append([]string{}, sampled...), remaining...)
+			sort.Strings(merged)
+			want := append([]string{}, groups...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(merged, want) {
+				t.Fatalf("sampled %v + remaining %v != groups", sampled, remaining)
+			}
+			for _, part := range [][]string{sampled, remaining} {
+				if !sort.SliceIsSorted(part, func(i, j int) bool { return position[part[i]] < position[part[j]] }) {
+					t.Fatalf("order not kept: %v", part)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SendCallbackPayload 发送结果回调内容
+type SendCallbackPayload struct {
+	Event      string      `json:"event"`        // 回调事件，如 send_text、send_image
+	ToUserName string      `json:"to_user_name"` // 目标群组
+	Success    bool        `json:"success"`      // 是否发送成功
+	Message    string      `json:"message"`      // 失败原因或成功消息
+	Data       interface{} `json:"data"`         // 发送结果明细
+	Timestamp  int64       `json:"timestamp"`    // 回调生成时间
+}
+
+// 系统事件
+const (
+	EventTokenExpired = "token_expired" // 用户token过期，已标记为需要重新登录
+	EventInitTimeout  = "init_timeout"  // 用户初始化超时
+
+	EventRobotUnhealthy = "robot_unhealthy" // 机器人连续多次健康检查失败
+	EventRobotRecovered = "robot_recovered" // 告警后的机器人恢复健康
+
+	EventAuthExpiring    = "auth_expiring"     // 用户授权即将到期
+	EventAuthRenewed     = "auth_renewed"      // 用户授权已自动续期
+	EventAuthRenewFailed = "auth_renew_failed" // 用户授权自动续期失败
+
+	EventDuplicateSend = "duplicate_send" // 去重窗口内向同一群重复发送相同内容
+
+	EventSendRetryFailed = "send_retry_failed" // 失败消息重试次数用尽或遇到不可重试的失败
+
+	EventAtAllDowngraded = "at_all_downgraded" // 发送账号不是群主或管理员，@所有人已降级为普通消息
+)
+
+// EventCallbackPayload 系统事件通知内容，POST到配置的事件通知地址
+type EventCallbackPayload struct {
+	Event     string      `json:"event"`     // 事件类型
+	Message   string      `json:"message"`   // 事件描述
+	Data      interface{} `json:"data"`      // 事件明细
+	Timestamp int64       `json:"timestamp"` // 事件发生时间
+}
+
+// CallbackNotifier 发送结果回调通知接口
+type CallbackNotifier interface {
+	// Notify 异步将payload POST到callbackURL，失败按配置重试
+	Notify(callbackURL string, payload interface{})
+}
+
+// DefaultCallbackNotifier 默认的HTTP回调通知实现
+type DefaultCallbackNotifier struct {
+	httpClient    *http.Client
+	logger        *zap.Logger
+	maxRetries    int
+	retryInterval time.Duration
+}
+
+// NewCallbackNotifier 创建回调通知器，guard不为nil时在拨号阶段拒绝受限地址（回调地址由调用方传入时必须传guard）
+func NewCallbackNotifier(cfg CallbackConfig, guard *AddressGuard, logger *zap.Logger) CallbackNotifier {
+	httpClient := &http.Client{
+		Timeout: cfg.Timeout,
+	}
+	if guard != nil {
+		httpClient.Transport = guard.Transport()
+	}
+
+	return &DefaultCallbackNotifier{
+		httpClient:    httpClient,
+		logger:        logger,
+		maxRetries:    cfg.MaxRetries,
+		retryInterval: cfg.RetryInterval,
+	}
+}
+
+// validateCallbackURL 校验回调地址，只允许http/https，且不能指向本机/内网/metadata等受限地址
+func validateCallbackURL(callbackURL string, guard *AddressGuard) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("回调地址格式错误: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("回调地址只支持http或https")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("回调地址缺少主机名")
+	}
+	return guard.CheckHost("回调地址", u.Hostname())
+}
+
+// Notify 异步发送回调
+func (n *DefaultCallbackNotifier) Notify(callbackURL string, payload interface{}) {
+	if callbackURL == "" {
+		return
+	}
+
+	body, err := marshalJSON(payload)
+	if err != nil {
+		n.logger.Error("序列化回调内容失败", zap.String("callback_url", callbackURL), zap.Error(err))
+		return
+	}
+
+	go n.deliver(callbackURL, body)
+}
+
+// deliver 投递回调，失败时按递增间隔重试
+func (n *DefaultCallbackNotifier) deliver(callbackURL string, body []byte) {
+	for attempt := 0; attempt <= n.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(n.retryInterval * time.Duration(attempt))
+		}
+
+		err := n.post(callbackURL, body)
+		if err == nil {
+			n.logger.Info("发送结果回调成功",
+				zap.String("callback_url", callbackURL),
+				zap.Int("attempt", attempt+1))
+			return
+		}
+
+		// 受限地址重试也不会成功，直接放弃
+		if errors.Is(err, ErrAddressBlocked) {
+			n.logger.Error("回调地址指向受限地址，放弃回调",
+				zap.String("callback_url", callbackURL),
+				zap.Error(err))
+			return
+		}
+
+		n.logger.Warn("发送结果回调失败",
+			zap.String("callback_url", callbackURL),
+			zap.Int("attempt", attempt+1),
+			zap.Int("max_retries", n.maxRetries),
+			zap.Error(err))
+	}
+
+	n.logger.Error("发送结果回调最终失败，放弃重试", zap.String("callback_url", callbackURL))
+}
+
+// post 执行单次回调请求，2xx视为成功
+func (n *DefaultCallbackNotifier) post(callbackURL string, body []byte) error {
+	req, err := http.NewRequest("POST", callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
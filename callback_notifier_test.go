@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestAddressGuard 创建测试用地址防护，allowed为放行的地址段
+func newTestAddressGuard(t *testing.T, enable bool, allowed ...string) *AddressGuard {
+	t.Helper()
+	guard, err := NewAddressGuard(SecurityConfig{RobotAddressCheck: enable, AllowedCIDRs: allowed})
+	if err != nil {
+		t.Fatalf("NewAddressGuard: %v", err)
+	}
+	return guard
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	guard := newTestAddressGuard(t, true)
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr string
+	}{
+		{name: "public ip", url: "http://8.8.8.8/callback"},
+		{name: "https with port", url: "https://8.8.8.8:8443/callback"},
+		{name: "unsupported scheme", url: "ftp://8.8.8.8/callback", wantErr: "只支持http或https"},
+		{name: "missing host", url: "http:///callback", wantErr: "缺少主机名"},
+		{name: "loopback", url: "http://127.0.0.1:8080/callback", wantErr: "受限地址"},
+		{name: "private network", url: "http://192.168.1.10/callback", wantErr: "受限地址"},
+		{name: "metadata address", url: "http://169.254.169.254/latest/meta-data", wantErr: "受限地址"},
+		{name: "ipv6 loopback", url: "http://[::1]/callback", wantErr: "受限地址"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCallbackURL(tt.url, guard)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateCallbackURL(%q) = %v, want nil", tt.url, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateCallbackURL(%q) = %v, want error containing %q", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCallbackURLAllowedCIDR(t *testing.T) {
+	guard := newTestAddressGuard(t, true, "127.0.0.1")
+	if err := validateCallbackURL("http://127.0.0.1:9000/callback", guard); err != nil {
+		t.Fatalf("allowed address rejected: %v", err)
+	}
+
+	disabled := newTestAddressGuard(t, false)
+	if err := validateCallbackURL("http://10.0.0.1/callback", disabled); err != nil {
+		t.Fatalf("address check disabled but rejected: %v", err)
+	}
+}
+
+func TestCallbackNotifierDelivers(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32 // 前几次请求返回500
+		retries   int
+		wantCalls int32
+	}{
+		{name: "first attempt succeeds", failures: 0, retries: 2, wantCalls: 1},
+		{name: "succeeds after retry", failures: 2, retries: 2, wantCalls: 3},
+		{name: "gives up after max retries", failures: 10, retries: 1, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			received := make(chan SendCallbackPayload, 10)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("unexpected request: %s %s", r.Method, r.Header.Get("Content-Type"))
+				}
+				var payload SendCallbackPayload
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("decode payload: %v", err)
+				}
+				if n <= tt.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				received <- payload
+			}))
+			defer server.Close()
+
+			guard := newTestAddressGuard(t, true, "127.0.0.1")
+			notifier := NewCallbackNotifier(CallbackConfig{
+				Timeout:       time.Second,
+				MaxRetries:    tt.retries,
+				RetryInterval: time.Millisecond,
+			}, guard, zap.NewNop())
+
+			notifier.Notify(server.URL, SendCallbackPayload{
+				Event:      "send_text",
+				ToUserName: "123@chatroom",
+				Success:    true,
+				Message:    "发送成功",
+			})
+
+			deadline := time.After(2 * time.Second)
+			if tt.wantCalls > tt.failures {
+				select {
+				case payload := <-received:
+					if payload.Event != "send_text" || payload.ToUserName != "123@chatroom" || !payload.Success {
+						t.Fatalf("unexpected payload: %+v", payload)
+					}
+				case <-deadline:
+					t.Fatal("callback not delivered")
+				}
+			} else {
+				for atomic.LoadInt32(&calls) < tt.wantCalls {
+					select {
+					case <-deadline:
+						t.Fatalf("calls = %d, want %d", atomic.LoadInt32(&calls), tt.wantCalls)
+					case <-time.After(5 * time.Millisecond):
+					}
+				}
+			}
+
+			// 留出时间确认没有多余的重试
+			time.Sleep(20 * time.Millisecond)
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCallbackNotifierRejectsBlockedAddressAtDial(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	notifier := NewCallbackNotifier(CallbackConfig{
+		Timeout:       time.Second,
+		MaxRetries:    2,
+		RetryInterval: time.Millisecond,
+	}, newTestAddressGuard(t, true), zap.NewNop()).(*DefaultCallbackNotifier)
+
+	err := notifier.post(server.URL, []byte(`{}`))
+	if !errors.Is(err, ErrAddressBlocked) {
+		t.Fatalf("post to loopback = %v, want blocked error", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Fatalf("blocked callback reached server %d times", got)
+	}
+}
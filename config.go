@@ -1,6 +1,8 @@
 package main
 
 import (
+	_ "embed"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -13,11 +15,27 @@ import (
 
 // Config 配置结构体
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Server   ServerConfig   `mapstructure:"server"`
-	Log      LogConfig      `mapstructure:"log"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Swagger  SwaggerConfig  `mapstructure:"swagger"`
+	App         AppConfig             `mapstructure:"app"`
+	Server      ServerConfig          `mapstructure:"server"`
+	Log         LogConfig             `mapstructure:"log"`
+	Database    DatabaseConfig        `mapstructure:"database"`
+	Swagger     SwaggerConfig         `mapstructure:"swagger"`
+	Callback    CallbackConfig        `mapstructure:"callback"`
+	BillParser  BillParserConfig      `mapstructure:"bill_parser"`
+	MsgCallback MessageCallbackConfig `mapstructure:"message_callback"`
+	AutoReply   AutoReplyConfig       `mapstructure:"auto_reply"`
+	Security    SecurityConfig        `mapstructure:"security"`
+	WxAPI       WxAPIConfig           `mapstructure:"wx_api"`
+	SendQueue   SendQueueConfig       `mapstructure:"send_queue"`
+	TextSplit   TextSplitConfig       `mapstructure:"text_split"`
+	Init        InitializationConfig  `mapstructure:"initialization"`
+	Audit       AuditConfig           `mapstructure:"audit"`
+	MsgSearch   MessageSearchConfig   `mapstructure:"message_search"`
+	BillFormat  BillFormatConfig      `mapstructure:"bill_format"`
+	LoginStream LoginStreamConfig     `mapstructure:"login_stream"`
+	RobotHealth RobotHealthConfig     `mapstructure:"robot_health"`
+	AuthExpiry  AuthExpiryConfig      `mapstructure:"auth_expiry"`
+	GroupSync   GroupSyncConfig       `mapstructure:"group_sync"`
 }
 
 type AppConfig struct {
@@ -26,6 +44,8 @@ type AppConfig struct {
 	Env     string `mapstructure:"env"`
 	Port    string `mapstructure:"port"`
 	Debug   bool   `mapstructure:"debug"`
+	// 响应展示和时间参数解析使用的时区，数据库统一按UTC存储
+	Timezone string `mapstructure:"timezone"`
 }
 
 type ServerConfig struct {
@@ -34,6 +54,9 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	// 请求体大小上限(MB)，0表示不限制；发图等请求体包含base64图片的接口使用 image_max_body_mb
+	MaxBodyMB      int64 `mapstructure:"max_body_mb"`
+	ImageMaxBodyMB int64 `mapstructure:"image_max_body_mb"`
 }
 
 type LogConfig struct {
@@ -45,6 +68,17 @@ type LogConfig struct {
 	MaxAge     int    `mapstructure:"max_age"`
 	MaxBackups int    `mapstructure:"max_backups"`
 	Compress   bool   `mapstructure:"compress"`
+
+	// 外部日志收集器，未启用时只输出到控制台/文件
+	Sink LogSinkConfig `mapstructure:"sink"`
+}
+
+type LogSinkConfig struct {
+	Enable     bool          `mapstructure:"enable"`
+	Type       string        `mapstructure:"type"`        // http 或 tcp
+	Address    string        `mapstructure:"address"`     // http为完整URL，tcp为host:port
+	Timeout    time.Duration `mapstructure:"timeout"`     // 单条发送超时
+	BufferSize int           `mapstructure:"buffer_size"` // 缓冲条数，满了丢弃
 }
 
 type DatabaseConfig struct {
@@ -63,6 +97,7 @@ type DatabaseConfig struct {
 	AllowMultiQueries        bool          `mapstructure:"allow_multi_queries"`
 	UseCursorFetch           bool          `mapstructure:"use_cursor_fetch"`
 	RewriteBatchedStatements bool          `mapstructure:"rewrite_batched_statements"`
+	AutoMigrate              bool          `mapstructure:"auto_migrate"` // 启动时按版本执行未执行的数据库迁移
 }
 
 type SwaggerConfig struct {
@@ -71,15 +106,307 @@ type SwaggerConfig struct {
 	Port   int    `mapstructure:"port"`
 }
 
+// CallbackConfig 发送结果回调配置
+type CallbackConfig struct {
+	Timeout       time.Duration `mapstructure:"timeout"`        // 单次回调请求超时
+	MaxRetries    int           `mapstructure:"max_retries"`    // 失败后最大重试次数
+	RetryInterval time.Duration `mapstructure:"retry_interval"` // 重试间隔（按次数递增）
+	EventURL      string        `mapstructure:"event_url"`      // 系统事件通知地址（如token过期），为空时不通知
+}
+
+// MessageCallbackConfig 群消息回调处理配置
+type MessageCallbackConfig struct {
+	AcceptTypes []int `mapstructure:"accept_types"` // 只处理这些消息类型，为空时处理全部
+	SaveIgnored bool  `mapstructure:"save_ignored"` // 不处理的类型是否仍然入库（仅记录），false时直接丢弃
+}
+
+// MessageSearchConfig 群消息搜索配置
+type MessageSearchConfig struct {
+	// 是否使用全文索引（MATCH AGAINST）搜索，需先为content建立ngram全文索引，未开启时使用LIKE模糊匹配
+	Fulltext bool `mapstructure:"fulltext"`
+}
+
+// BillParserConfig 群消息账单自动识别配置
+type BillParserConfig struct {
+	Enable bool                  `mapstructure:"enable"`
+	Rules  []BillParseRuleConfig `mapstructure:"rules"` // 为空时使用内置规则
+}
+
+// AutoReplyConfig 群消息关键词自动回复配置
+type AutoReplyConfig struct {
+	Enable   bool                  `mapstructure:"enable"`
+	Cooldown time.Duration         `mapstructure:"cooldown"` // 同一群同一规则的最短回复间隔，防止自动回复循环
+	Rules    []AutoReplyRuleConfig `mapstructure:"rules"`
+}
+
+// AutoReplyRuleConfig 自动回复规则
+type AutoReplyRuleConfig struct {
+	Name     string   `mapstructure:"name"`
+	Keywords []string `mapstructure:"keywords"` // 命中任一关键词即回复
+	Match    string   `mapstructure:"match"`    // 匹配方式 contains包含（默认）、exact完全一致
+	Reply    string   `mapstructure:"reply"`    // 回复模板，{nick_name} 替换为发送者昵称
+	Groups   []string `mapstructure:"groups"`   // 启用的群ID，为空时全部群启用
+}
+
+// LoginStreamConfig 登录状态实时推送配置
+type LoginStreamConfig struct {
+	Interval time.Duration `mapstructure:"interval"` // 服务端轮询登录状态的间隔
+	Timeout  time.Duration `mapstructure:"timeout"`  // 推送最长持续时间，超时后关闭连接
+}
+
+// BillFormatConfig 账单金额格式化配置
+type BillFormatConfig struct {
+	CurrencySymbol string `mapstructure:"currency_symbol"` // format=currency 时金额前拼接的货币符号
+}
+
+// BillParseRuleConfig 账单识别规则，Pattern 使用命名分组 dollar/rate/amount/operator 提取字段
+type BillParseRuleConfig struct {
+	Name    string `mapstructure:"name"`
+	Pattern string `mapstructure:"pattern"`
+}
+
+// SecurityConfig 安全相关配置
+type SecurityConfig struct {
+	RobotAddressCheck bool     `mapstructure:"robot_address_check"` // 是否校验机器人地址（SSRF防护）
+	BlockedCIDRs      []string `mapstructure:"blocked_cidrs"`       // 禁止访问的地址段，为空时使用默认的本机/内网/metadata地址段
+	AllowedCIDRs      []string `mapstructure:"allowed_cidrs"`       // 允许访问的地址段，优先于禁止列表（用于放行内网部署的机器人）
+	// 超大群保护：目标群成员数超过该值时需确认后才发送，0表示不校验
+	LargeGroupThreshold int `mapstructure:"large_group_threshold"`
+	// 管理接口令牌，请求头 X-Admin-Token 需与之一致，为空时管理接口不可用
+	AdminToken string `mapstructure:"admin_token"`
+	// 租户API Key，请求头 X-API-Key 命中后只能访问绑定公司的数据；配置后不带Key的请求需携带管理员令牌
+	APIKeys []APIKeyConfig `mapstructure:"api_keys"`
+	// 登录二维码防刷：同一机器人+token在窗口时间内最多获取的次数，0表示不限制
+	QRCodeLimit  int           `mapstructure:"qrcode_limit"`
+	QRCodeWindow time.Duration `mapstructure:"qrcode_window"`
+	// 发送内容中的链接安全检查
+	URLCheck URLCheckConfig `mapstructure:"url_check"`
+}
+
+// URLCheckConfig 发送内容链接安全检查配置
+type URLCheckConfig struct {
+	Enable         bool          `mapstructure:"enable"`
+	AllowDomains   []string      `mapstructure:"allow_domains"`   // 白名单域名（含子域名），直接放行
+	BlockDomains   []string      `mapstructure:"block_domains"`   // 黑名单域名（含子域名），拒绝发送
+	CheckerURL     string        `mapstructure:"checker_url"`     // 可选的外部安全检测接口，为空时只按黑名单检查
+	CheckerTimeout time.Duration `mapstructure:"checker_timeout"` // 外部安全检测接口超时
+}
+
+// WxAPIConfig 外部微信机器人API调用配置
+type WxAPIConfig struct {
+	Timeout   time.Duration  `mapstructure:"timeout"`   // 全局默认请求超时，机器人未配置独立超时时使用
+	Endpoints WxAPIEndpoints `mapstructure:"endpoints"` // 外部接口路径，未配置的使用默认路径
+
+	InitStatusCacheTTL time.Duration `mapstructure:"init_status_cache_ttl"` // 未初始化结果的缓存时长，0表示不缓存
+
+	Record WxAPIRecordConfig `mapstructure:"record"` // 录制模式，联调时记录完整请求响应
+
+	Concurrency WxAPIConcurrencyConfig `mapstructure:"concurrency"` // 外部接口调用并发总量限制
+
+	Retry WxAPIRetryConfig `mapstructure:"retry"` // 外部接口失败重试
+}
+
+// WxAPIRetryConfig 外部接口失败重试配置，只重试GET和标记为可安全重复调用的POST，发送消息不重试
+type WxAPIRetryConfig struct {
+	MaxAttempts    int           `mapstructure:"max_attempts"`    // 最多尝试次数（含第一次），1表示不重试
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"` // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`     // 重试等待时间上限
+}
+
+// WxAPIConcurrencyConfig 外部接口调用并发总量限制配置，所有机器人共享
+type WxAPIConcurrencyConfig struct {
+	Limit       int           `mapstructure:"limit"`        // 同时进行中的请求上限，0表示不限制
+	Mode        string        `mapstructure:"mode"`         // 达到上限时的处理方式：wait 排队等待，fail 立即失败
+	WaitTimeout time.Duration `mapstructure:"wait_timeout"` // wait模式下的最长等待时间，0表示等到请求超时
+}
+
+// WxAPIRecordConfig 外部接口录制模式配置
+type WxAPIRecordConfig struct {
+	Enable    bool     `mapstructure:"enable"`
+	FilePath  string   `mapstructure:"file_path"` // 录制文件路径，与业务日志分开
+	Endpoints []string `mapstructure:"endpoints"` // 只录制这些接口路径，为空时录制全部
+}
+
+// WxAPIEndpoints 外部微信机器人API路径
+type WxAPIEndpoints struct {
+	GenAuthKey          string `mapstructure:"gen_auth_key"`
+	DelayAuthKey        string `mapstructure:"delay_auth_key"`
+	GetLoginQrCode      string `mapstructure:"get_login_qr_code"`
+	CheckCanSetAlias    string `mapstructure:"check_can_set_alias"`
+	CheckLoginStatus    string `mapstructure:"check_login_status"`
+	GetLoginStatus      string `mapstructure:"get_login_status"`
+	GetInitStatus       string `mapstructure:"get_init_status"`
+	GetChatRoomInfo     string `mapstructure:"get_chat_room_info"`
+	GroupList           string `mapstructure:"group_list"`
+	SendTextMessage     string `mapstructure:"send_text_message"`
+	SendImageNewMessage string `mapstructure:"send_image_new_message"`
+	SendVoiceMessage    string `mapstructure:"send_voice_message"`
+	SendVideoMessage    string `mapstructure:"send_video_message"`
+	SendAppMessage      string `mapstructure:"send_app_message"`
+}
+
+// SendQueueConfig 消息发送队列配置
+type SendQueueConfig struct {
+	Workers   int     `mapstructure:"workers"`    // 发送worker数量
+	QueueSize int     `mapstructure:"queue_size"` // 每个优先级队列的容量，队列满时拒绝发送
+	RateLimit float64 `mapstructure:"rate_limit"` // 全局发送速率（条/秒），0表示不限流
+
+	Backoff      SendBackoffConfig   `mapstructure:"backoff"`       // 按账号的自适应退避
+	AccountLimit SendRateLimitConfig `mapstructure:"account_limit"` // 按账号的发送限流
+	Dedup        SendDedupConfig     `mapstructure:"dedup"`         // 相同群相同内容的去重窗口
+	Retry        SendRetryConfig     `mapstructure:"retry"`         // 临时故障失败消息的后台重试
+}
+
+// SendRateLimitConfig 按发送账号的令牌桶限流配置，所有发送接口和群发共用
+type SendRateLimitConfig struct {
+	Rate    float64       `mapstructure:"rate"`     // 每个账号的发送速率（条/秒），0表示不限流
+	Burst   int           `mapstructure:"burst"`    // 允许的突发条数
+	Mode    string        `mapstructure:"mode"`     // 超限时的处理方式：wait 等待令牌，fail 立即失败
+	MaxWait time.Duration `mapstructure:"max_wait"` // wait模式下预计等待超过该时长时失败，0表示不限制
+}
+
+// SendRetryConfig 失败消息重试队列配置，因网络错误等临时故障发送失败的消息落库后由后台按退避重发
+type SendRetryConfig struct {
+	Enable         bool          `mapstructure:"enable"`          // 是否启用
+	Interval       time.Duration `mapstructure:"interval"`        // 扫描到期重试消息的间隔
+	BatchSize      int           `mapstructure:"batch_size"`      // 每次扫描最多处理的消息数
+	MaxAttempts    int           `mapstructure:"max_attempts"`    // 最多重试次数，用尽后标记为最终失败并告警
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"` // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`     // 重试等待时间上限
+}
+
+// SendDedupConfig 发送去重配置，同一群在窗口时间内重复发送相同内容时拒绝或告警
+type SendDedupConfig struct {
+	Window time.Duration `mapstructure:"window"` // 去重窗口，0表示不检查
+	Action string        `mapstructure:"action"` // 命中时的处理：reject 拒绝发送、warn 发送并告警
+}
+
+// SendBackoffConfig 按发送账号的自适应退避配置，出现风控迹象时自动降低该账号的发送频率
+type SendBackoffConfig struct {
+	Enable      bool          `mapstructure:"enable"`       // 是否启用
+	Window      int           `mapstructure:"window"`       // 统计失败率的最近发送次数
+	FailureRate float64       `mapstructure:"failure_rate"` // 失败率达到该值时加大发送间隔
+	MinInterval time.Duration `mapstructure:"min_interval"` // 退避时的初始发送间隔，恢复到低于该值时取消限制
+	MaxInterval time.Duration `mapstructure:"max_interval"` // 发送间隔上限
+}
+
+// TextSplitConfig 长文本分段发送配置
+type TextSplitConfig struct {
+	Enable    bool          `mapstructure:"enable"`     // 是否自动分段
+	MaxLength int           `mapstructure:"max_length"` // 单条消息最大字符数，超过时分段
+	Strategy  string        `mapstructure:"strategy"`   // 分段策略：paragraph 按段落、fixed 按固定长度
+	Interval  time.Duration `mapstructure:"interval"`   // 分段之间的发送间隔
+}
+
+// RobotHealthConfig 机器人健康巡检配置
+type RobotHealthConfig struct {
+	Interval      time.Duration `mapstructure:"interval"`       // 巡检间隔，0表示不巡检
+	FailThreshold int           `mapstructure:"fail_threshold"` // 连续失败达到该次数才告警，避免偶发失败造成告警风暴
+}
+
+// AuthExpiryConfig 授权到期预警与自动续期配置
+type AuthExpiryConfig struct {
+	Interval        time.Duration `mapstructure:"interval"`          // 检查间隔，0表示不检查
+	WarnDays        int           `mapstructure:"warn_days"`         // 未开启自动续期的用户在到期前N天内发送预警，0表示不预警
+	RenewBeforeDays int           `mapstructure:"renew_before_days"` // 开启自动续期的用户在到期前N天内自动延期
+	RenewDays       int           `mapstructure:"renew_days"`        // 自动续期的延期天数，0表示不自动续期
+}
+
+// GroupSyncConfig 群组定时同步配置，同步任务每3分钟执行一轮，外部接口不支持增量拉取，通过错峰降低全量拉取的开销
+type GroupSyncConfig struct {
+	MinInterval      time.Duration `mapstructure:"min_interval"`        // 同一用户两次成功同步的最小间隔，未到间隔的用户本轮跳过
+	MaxUsersPerRound int           `mapstructure:"max_users_per_round"` // 每轮最多同步的用户数，优先同步最久未同步的用户，0表示不限制
+}
+
+// InitializationConfig 用户初始化检查配置
+type InitializationConfig struct {
+	Timeout          time.Duration `mapstructure:"timeout"`            // 登录后超过该时长仍未初始化完成视为超时，0表示不检测
+	ReloginOnTimeout bool          `mapstructure:"relogin_on_timeout"` // 超时后是否将用户标记为需要重新登录
+}
+
+// AuditConfig 发送内容审计配置，所有对外发送都会记录审计
+type AuditConfig struct {
+	StoreContent bool `mapstructure:"store_content"` // 是否保存完整文本，false时只保存内容哈希
+}
+
+// setDefaults 设置配置默认值，配置文件未填写时生效
+func setDefaults() {
+	viper.SetDefault("app.timezone", "Local")
+	viper.SetDefault("database.auto_migrate", true)
+	viper.SetDefault("server.max_body_mb", 2)
+	viper.SetDefault("server.image_max_body_mb", 20)
+	viper.SetDefault("log.sink.enable", false)
+	viper.SetDefault("log.sink.type", LogSinkTypeHTTP)
+	viper.SetDefault("log.sink.timeout", "3s")
+	viper.SetDefault("log.sink.buffer_size", 1000)
+	viper.SetDefault("callback.timeout", "10s")
+	viper.SetDefault("callback.max_retries", 3)
+	viper.SetDefault("callback.retry_interval", "2s")
+	viper.SetDefault("bill_parser.enable", true)
+	viper.SetDefault("auto_reply.enable", false)
+	viper.SetDefault("auto_reply.cooldown", "1m")
+	viper.SetDefault("security.robot_address_check", true)
+	viper.SetDefault("security.large_group_threshold", 500)
+	viper.SetDefault("security.qrcode_limit", 5)
+	viper.SetDefault("security.qrcode_window", "5m")
+	viper.SetDefault("wx_api.timeout", "30s")
+	viper.SetDefault("wx_api.init_status_cache_ttl", "1m")
+	viper.SetDefault("wx_api.record.enable", false)
+	viper.SetDefault("wx_api.record.file_path", "./logs/wx_api_record.log")
+	viper.SetDefault("wx_api.concurrency.limit", 0)
+	viper.SetDefault("wx_api.concurrency.mode", WxAPIConcurrencyModeWait)
+	viper.SetDefault("wx_api.concurrency.wait_timeout", "10s")
+	viper.SetDefault("wx_api.retry.max_attempts", 3)
+	viper.SetDefault("wx_api.retry.initial_backoff", "200ms")
+	viper.SetDefault("wx_api.retry.max_backoff", "2s")
+	viper.SetDefault("send_queue.workers", 4)
+	viper.SetDefault("send_queue.queue_size", 1000)
+	viper.SetDefault("send_queue.rate_limit", 0)
+	viper.SetDefault("send_queue.backoff.enable", true)
+	viper.SetDefault("send_queue.backoff.window", 20)
+	viper.SetDefault("send_queue.backoff.failure_rate", 0.5)
+	viper.SetDefault("send_queue.backoff.min_interval", "2s")
+	viper.SetDefault("send_queue.backoff.max_interval", "60s")
+	viper.SetDefault("send_queue.account_limit.rate", 0)
+	viper.SetDefault("send_queue.account_limit.burst", 5)
+	viper.SetDefault("send_queue.account_limit.mode", SendRateLimitModeWait)
+	viper.SetDefault("send_queue.account_limit.max_wait", "30s")
+	viper.SetDefault("text_split.enable", true)
+	viper.SetDefault("text_split.max_length", 2000)
+	viper.SetDefault("text_split.strategy", TextSplitStrategyParagraph)
+	viper.SetDefault("text_split.interval", "500ms")
+	viper.SetDefault("initialization.timeout", "30m")
+	viper.SetDefault("initialization.relogin_on_timeout", false)
+	viper.SetDefault("audit.store_content", true)
+	viper.SetDefault("message_search.fulltext", false)
+	viper.SetDefault("bill_format.currency_symbol", "¥")
+	viper.SetDefault("security.url_check.enable", false)
+	viper.SetDefault("security.url_check.checker_timeout", "3s")
+	viper.SetDefault("login_stream.interval", "2s")
+	viper.SetDefault("login_stream.timeout", "5m")
+	viper.SetDefault("send_queue.dedup.window", "0s")
+	viper.SetDefault("send_queue.dedup.action", SendDedupActionReject)
+	viper.SetDefault("send_queue.retry.enable", false)
+	viper.SetDefault("send_queue.retry.interval", "30s")
+	viper.SetDefault("send_queue.retry.batch_size", 50)
+	viper.SetDefault("send_queue.retry.max_attempts", 5)
+	viper.SetDefault("send_queue.retry.initial_backoff", "1m")
+	viper.SetDefault("send_queue.retry.max_backoff", "30m")
+	viper.SetDefault("robot_health.interval", "1m")
+	viper.SetDefault("robot_health.fail_threshold", 3)
+	viper.SetDefault("auth_expiry.interval", "1h")
+	viper.SetDefault("auth_expiry.warn_days", 7)
+	viper.SetDefault("auth_expiry.renew_before_days", 3)
+	viper.SetDefault("auth_expiry.renew_days", 30)
+
+	viper.SetDefault("group_sync.min_interval", "3m")
+	viper.SetDefault("group_sync.max_users_per_round", 0)
+}
+
 // InitConfig 初始化配置
 func InitConfig() (*Config, error) {
-	// 获取环境变量，默认为开发环境
-	env := os.Getenv("APP_ENV")
-	if env == "" {
-		env = "dev"
-	}
-
 	// 根据环境选择配置文件
+	env := currentEnv()
 	configName := "config-" + env
 	viper.SetConfigName(configName)
 	viper.SetConfigType("toml")
@@ -89,7 +416,14 @@ func InitConfig() (*Config, error) {
 	// 环境变量支持
 	viper.AutomaticEnv()
 
+	// 默认值
+	setDefaults()
+
 	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, configNotFoundError(env)
+		}
 		return nil, fmt.Errorf("读取配置文件失败 (%s): %w", configName, err)
 	}
 
@@ -112,6 +446,41 @@ func InitConfig() (*Config, error) {
 	return cfg, nil
 }
 
+// currentEnv 获取运行环境，APP_ENV未设置时默认为开发环境
+func currentEnv() string {
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "dev"
+	}
+	return env
+}
+
+// sampleConfig 带注释的样例配置，--init-config 时写出
+//
+//go:embed config.sample.toml
+var sampleConfig []byte
+
+// configNotFoundError 配置文件缺失时的提示，说明需要创建的文件和生成方式
+func configNotFoundError(env string) error {
+	return fmt.Errorf("未找到配置文件 config-%s.toml（查找目录: ./ 和 ./config）\n"+
+		"  - 执行 `%s --init-config` 生成带注释的样例配置 config-%s.toml，修改数据库等配置后重新启动\n"+
+		"  - 或设置环境变量 APP_ENV 选择其他环境（当前 APP_ENV=%s），对应文件 config-<APP_ENV>.toml",
+		env, os.Args[0], env, env)
+}
+
+// WriteSampleConfig 在当前目录生成当前环境的样例配置文件，文件已存在时不覆盖
+func WriteSampleConfig() (string, error) {
+	path := "config-" + currentEnv() + ".toml"
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("配置文件 %s 已存在，未覆盖", path)
+	}
+
+	if err := os.WriteFile(path, sampleConfig, 0644); err != nil {
+		return "", fmt.Errorf("写入样例配置失败: %w", err)
+	}
+	return path, nil
+}
+
 // InitLogger 初始化日志
 func InitLogger(cfg *Config) (*zap.Logger, error) {
 	var level zapcore.Level
@@ -180,6 +549,17 @@ func InitLogger(cfg *Config) (*zap.Logger, error) {
 		cores = append(cores, fileCore)
 	}
 
+	// 外部日志收集器，创建失败不影响本地日志
+	var sinkErr error
+	if cfg.Log.Sink.Enable {
+		sinkCore, err := newRemoteLogCore(cfg.Log.Sink, encoderConfig, level)
+		if err != nil {
+			sinkErr = err
+		} else {
+			cores = append(cores, sinkCore)
+		}
+	}
+
 	// 创建核心
 	core := zapcore.NewTee(cores...)
 
@@ -194,7 +574,14 @@ func InitLogger(cfg *Config) (*zap.Logger, error) {
 		zap.Int("max_age_days", cfg.Log.MaxAge),
 		zap.Int("max_size_mb", cfg.Log.MaxSize),
 		zap.Int("max_backups", cfg.Log.MaxBackups),
-		zap.Bool("compress", cfg.Log.Compress))
+		zap.Bool("compress", cfg.Log.Compress),
+		zap.Bool("sink_enable", cfg.Log.Sink.Enable),
+		zap.String("sink_type", cfg.Log.Sink.Type),
+		zap.String("sink_address", cfg.Log.Sink.Address))
+
+	if sinkErr != nil {
+		logger.Error("外部日志收集器初始化失败，仅使用本地日志", zap.Error(sinkErr))
+	}
 
 	return logger, nil
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestInitConfigMissingAndSample(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         string
+		writeSample bool
+		wantErr     []string
+	}{
+		{name: "missing dev config", env: "", wantErr: []string{"config-dev.toml", "--init-config", "APP_ENV"}},
+		{name: "missing prod config", env: "prod", wantErr: []string{"config-prod.toml", "--init-config"}},
+		{name: "sample config loads", env: "test", writeSample: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			t.Setenv("APP_ENV", tt.env)
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			if tt.writeSample {
+				path, err := WriteSampleConfig()
+				if err != nil || path != "config-"+tt.env+".toml" {
+					t.Fatalf("WriteSampleConfig = %q, %v", path, err)
+				}
+				if _, err := WriteSampleConfig(); err == nil {
+					t.Fatal("WriteSampleConfig overwrote existing file")
+				}
+				if data, _ := os.ReadFile(path); string(data) != string(sampleConfig) {
+					t.Fatal("sample config content mismatch")
+				}
+			}
+
+			cfg, err := InitConfig()
+			if len(tt.wantErr) == 0 {
+				if err != nil || cfg == nil {
+					t.Fatalf("InitConfig: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("InitConfig succeeded without config file")
+			}
+			for _, s := range tt.wantErr {
+				if !strings.Contains(err.Error(), s) {
+					t.Errorf("error %q missing %q", err, s)
+				}
+			}
+		})
+	}
+}
@@ -46,7 +46,8 @@ func (dm *DatabaseManager) Close() error {
 // connectDatabase 初始化数据库连接
 func connectDatabase(cfg *Config, logger *zap.Logger) (*gorm.DB, error) {
 	// 构建简化的DSN - 先用最基本的参数测试
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=true&loc=Local",
+	// 时间统一按UTC读写，会话时区也设为UTC，展示时再按 app.timezone 转换
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=true&loc=UTC&time_zone=%%27%%2B00%%3A00%%27",
 		cfg.Database.Username,
 		cfg.Database.Password,
 		cfg.Database.Host,
@@ -106,6 +107,11 @@ func connectDatabase(cfg *Config, logger *zap.Logger) (*gorm.DB, error) {
 }
 
 
+// Migrate 按版本顺序执行未执行过的数据库迁移
+func (dm *DatabaseManager) Migrate() error {
+	return applyMigrations(dm.db, dm.logger, schemaMigrations)
+}
+
 // CheckDatabaseHealth 检查数据库健康状态
 func (dm *DatabaseManager) CheckDatabaseHealth() error {
 	if dm.db == nil {
@@ -119,3 +125,29 @@ func (dm *DatabaseManager) CheckDatabaseHealth() error {
 	}
 }
 
+// WithTransaction 在事务中执行fn，fn返回错误或发生panic时回滚，否则提交
+func WithTransaction(db *gorm.DB, fn func(tx *gorm.DB) error) (err error) {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("开启事务失败: %w", tx.Error)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			err = fmt.Errorf("事务执行异常已回滚: %v", r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback().Error; rbErr != nil {
+			return fmt.Errorf("%w (回滚失败: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
@@ -8,6 +8,10 @@ CREATE TABLE `wx_robot_configs` (
     `owner_id` bigint(20) unsigned NOT NULL COMMENT '所属公司ID',
     `description` varchar(500) COMMENT '文本描述',
     `admin_users` text COMMENT '管理员用户列表，用逗号分隔',
+    `timeout_seconds` int NOT NULL DEFAULT 0 COMMENT '外部接口调用超时(秒)，0表示使用全局默认',
+    `health_path` varchar(255) NOT NULL DEFAULT '/' COMMENT '健康检查路径',
+    `enabled` tinyint(1) NOT NULL DEFAULT '1' COMMENT '是否启用 0禁用 1启用，禁用后其账号不再作为消息机器人发送',
+    `tags` varchar(500) DEFAULT NULL COMMENT '标签，用逗号分隔，用于分组统计',
     `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
     `update_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '修改时间',
     PRIMARY KEY (`id`),
@@ -25,14 +29,19 @@ CREATE TABLE `wx_user_logins` (
     `extension_time` datetime(3) DEFAULT NULL COMMENT '延期时间',
     `has_security_risk` tinyint(1) DEFAULT '0' COMMENT '是否有安全风险 0否 1是',
     `expiration_time` datetime(3) DEFAULT NULL COMMENT '过期时间',
+    `auto_renew` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否到期前自动续期 0否 1是',
     `status` int(11) DEFAULT '1' COMMENT '状态 1正常 2风控 3过期',
     `is_initialized` int(11) DEFAULT '0' COMMENT '是否初始化完成 0未初始化 1初始化完成',
+    `init_start_time` datetime(3) DEFAULT NULL COMMENT '进入未初始化状态的时间',
     `is_message_bot` int(11) DEFAULT '0' COMMENT '是否是消息机器人 0不是 1是',
+    `remark` varchar(200) DEFAULT NULL COMMENT '运营备注',
+    `signature` varchar(100) DEFAULT NULL COMMENT '发送文本时自动拼接的签名',
+    `signature_position` varchar(10) NOT NULL DEFAULT 'suffix' COMMENT '签名位置 prefix前缀 suffix后缀',
     `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
     `update_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '修改时间',
     PRIMARY KEY (`id`),
     -- 复合索引：优先级高的查询组合
-    INDEX `idx_robot_wx` (`robot_id`, `wx_id`),
+    UNIQUE KEY `uk_robot_wx` (`robot_id`, `wx_id`),
     INDEX `idx_robot_token` (`robot_id`, `token`),
     INDEX `idx_init_status` (`is_initialized`, `status`),
     INDEX `idx_wx_status_msgbot_risk` (`wx_id`, `status`, `is_message_bot`, `has_security_risk`),
@@ -41,12 +50,26 @@ CREATE TABLE `wx_user_logins` (
     INDEX `idx_wx_id` (`wx_id`),
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='微信用户登录信息表';
 
+-- 用户状态变更日志表
+CREATE TABLE `wx_user_status_logs` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `user_id` bigint(20) unsigned NOT NULL COMMENT '用户ID',
+    `old_status` int(11) NOT NULL COMMENT '变更前状态',
+    `new_status` int(11) NOT NULL COMMENT '变更后状态',
+    `reason` varchar(255) DEFAULT NULL COMMENT '变更原因',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '变更时间',
+    PRIMARY KEY (`id`),
+    INDEX `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户状态变更日志表';
+
 -- 微信群列表表
 CREATE TABLE `wx_groups` (
     `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
     `wx_id` varchar(100) NOT NULL COMMENT '微信ID',
     `group_id` varchar(100) NOT NULL COMMENT '群组ID',
     `group_nick_name` varchar(200) DEFAULT NULL COMMENT '群组昵称',
+    `member_count` int(11) NOT NULL DEFAULT 0 COMMENT '群成员数',
+    `chat_room_owner` varchar(100) DEFAULT NULL COMMENT '群主微信ID',
     `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
     `update_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '修改时间',
     PRIMARY KEY (`id`),
@@ -59,6 +82,41 @@ CREATE TABLE `wx_groups` (
     UNIQUE KEY `uk_wx_group` (`wx_id`, `group_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='微信群列表表';
 
+-- 退群记录表
+CREATE TABLE `wx_group_leave_logs` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `wx_id` varchar(100) NOT NULL COMMENT '微信ID',
+    `group_id` varchar(100) NOT NULL COMMENT '群组ID',
+    `group_nick_name` varchar(200) DEFAULT NULL COMMENT '群组昵称',
+    `join_time` datetime(3) DEFAULT NULL COMMENT '入群时间（群记录创建时间）',
+    `leave_time` datetime(3) NOT NULL COMMENT '退群时间',
+    PRIMARY KEY (`id`),
+    INDEX `idx_wx_leave_time` (`wx_id`, `leave_time`),
+    INDEX `idx_leave_time` (`leave_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='退群记录表';
+
+-- 群黑名单表
+CREATE TABLE `wx_group_blacklist` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `group_id` varchar(100) NOT NULL COMMENT '群组ID',
+    `reason` varchar(200) DEFAULT NULL COMMENT '加入黑名单原因',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_group_id` (`group_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='群黑名单表';
+
+-- 微信群成员表
+CREATE TABLE `wx_group_members` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `group_id` varchar(100) NOT NULL COMMENT '群组ID',
+    `member_wx_id` varchar(100) NOT NULL COMMENT '成员微信ID',
+    `nick_name` varchar(200) DEFAULT NULL COMMENT '成员昵称',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
+    `update_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '修改时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_group_member` (`group_id`, `member_wx_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='微信群成员表';
+
 -- 微信账单信息表
 CREATE TABLE `wx_bill_info` (
     `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '账单ID',
@@ -79,11 +137,149 @@ CREATE TABLE `wx_bill_info` (
     INDEX `idx_owner_group` (`owner_id`, `group_id`),
     INDEX `idx_owner_status` (`owner_id`, `status`),
     INDEX `idx_owner_msgtime` (`owner_id`, `msg_time`),
+    INDEX `idx_bill_dedup` (`group_id`, `msg_time`, `operator`),
     -- 单列索引：GROUP BY和LIKE查询
     INDEX `idx_group_name` (`group_name`),
     INDEX `idx_msg_time` (`msg_time`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='wx账单源表';
 
+-- 群消息表
+CREATE TABLE `wx_group_messages` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `group_id` varchar(100) NOT NULL COMMENT '群组ID',
+    `wx_nick_name` varchar(100) NOT NULL COMMENT '微信昵称',
+    `content` text NOT NULL COMMENT '消息内容',
+    `msg_type` int(11) NOT NULL COMMENT '消息类型',
+    `msg_time` bigint(20) NOT NULL COMMENT '消息时间戳',
+    `owner_id` bigint(20) unsigned NOT NULL COMMENT '所属公司ID',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
+    `update_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '修改时间',
+    PRIMARY KEY (`id`),
+    INDEX `idx_group_msgtime` (`group_id`, `msg_time`),
+    INDEX `idx_msg_time` (`msg_time`),
+    -- 关键词搜索使用的全文索引，ngram分词支持中文
+    FULLTEXT INDEX `ft_content` (`content`) WITH PARSER ngram
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='群消息表';
+
+-- 消息发送记录表，owner_id列由迁移版本5添加
+CREATE TABLE `wx_send_records` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `trace_id` varchar(64) NOT NULL COMMENT '发送批次追踪ID',
+    `to_user_name` varchar(100) NOT NULL COMMENT '目标群ID',
+    `wx_id` varchar(100) DEFAULT NULL COMMENT '实际发送的消息机器人',
+    `success` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否发送成功',
+    `error` varchar(500) DEFAULT NULL COMMENT '失败原因',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    INDEX `idx_trace_id` (`trace_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='消息发送记录表';
+
+-- 发送内容审计表
+CREATE TABLE `wx_send_audits` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `sender_wx_id` varchar(100) DEFAULT NULL COMMENT '发送账号微信ID',
+    `owner_id` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '发送账号所属公司ID',
+    `to_user_name` varchar(100) NOT NULL COMMENT '目标群ID',
+    `msg_type` varchar(20) NOT NULL COMMENT '消息类型 text/image/text_image/voice/video/link',
+    `content` text COMMENT '发送的文本内容，配置为只存哈希时为空',
+    `content_hash` char(64) NOT NULL COMMENT '文本与图片内容的SHA-256',
+    `success` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否发送成功',
+    `error` varchar(500) DEFAULT NULL COMMENT '失败原因',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '发送时间',
+    PRIMARY KEY (`id`),
+    INDEX `idx_create_time` (`create_time`),
+    INDEX `idx_to_user_time` (`to_user_name`, `create_time`),
+    INDEX `idx_sender_time` (`sender_wx_id`, `create_time`),
+    INDEX `idx_owner_time` (`owner_id`, `create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='发送内容审计表';
+
+-- 授权码表，source列由迁移版本6添加
+CREATE TABLE `wx_auth_keys` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `robot_id` bigint(20) unsigned NOT NULL COMMENT '生成授权码的机器人ID',
+    `auth_key` varchar(500) NOT NULL COMMENT '授权码',
+    `days` int(11) NOT NULL COMMENT '授权有效天数',
+    `expire_time` datetime(3) DEFAULT NULL COMMENT '授权到期时间',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '生成时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_auth_key` (`auth_key`),
+    INDEX `idx_robot_id` (`robot_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='授权码表';
+
+-- 数据库迁移版本记录表，服务启动时按版本顺序执行未执行的迁移，已有数据库首次启动时自动创建
+CREATE TABLE `schema_migrations` (
+    `version` bigint(20) unsigned NOT NULL COMMENT '迁移版本',
+    `description` varchar(200) DEFAULT NULL COMMENT '迁移说明',
+    `applied_time` datetime(3) NOT NULL COMMENT '执行时间',
+    PRIMARY KEY (`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='数据库迁移版本记录表';
+
+-- 机器人配置变更记录表，已有数据库由迁移版本2自动创建
+CREATE TABLE `wx_robot_config_changes` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `robot_id` bigint(20) unsigned NOT NULL COMMENT '机器人ID',
+    `field` varchar(50) NOT NULL COMMENT '变更字段',
+    `old_value` text COMMENT '变更前的值，敏感字段不记录明文',
+    `new_value` text COMMENT '变更后的值，敏感字段不记录明文',
+    `source` varchar(30) NOT NULL COMMENT '变更来源 update/patch/rotate_admin_key/batch_status/idle_disable/idle_delete',
+    `operator_owner_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '操作者API Key绑定的公司ID，0表示未使用API Key',
+    `client_ip` varchar(64) DEFAULT NULL COMMENT '操作者IP',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '变更时间',
+    PRIMARY KEY (`id`),
+    INDEX `idx_robot_time` (`robot_id`, `create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='机器人配置变更记录表';
+
+-- 失败消息重试表，已有数据库由迁移版本3自动创建，owner_id列由迁移版本5添加
+CREATE TABLE `wx_send_retries` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `msg_type` varchar(20) NOT NULL COMMENT '消息类型 text/image/voice/video/link',
+    `to_user_name` varchar(100) NOT NULL COMMENT '目标群ID',
+    `payload` mediumtext NOT NULL COMMENT '原始发送请求JSON',
+    `status` varchar(20) NOT NULL DEFAULT 'pending' COMMENT '状态 pending/success/failed',
+    `attempts` int(11) NOT NULL DEFAULT 0 COMMENT '已重试次数',
+    `last_error` varchar(500) DEFAULT NULL COMMENT '最近一次失败原因',
+    `next_retry_time` datetime(3) NOT NULL COMMENT '下次重试时间',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
+    `update_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '修改时间',
+    PRIMARY KEY (`id`),
+    INDEX `idx_status_next_retry` (`status`, `next_retry_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='失败消息重试表';
+
+-- 用户授权延期记录表，已有数据库由迁移版本4自动创建
+CREATE TABLE `wx_user_extension_logs` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+    `user_id` bigint(20) unsigned NOT NULL COMMENT '用户ID',
+    `robot_id` bigint(20) unsigned NOT NULL COMMENT '机器人ID',
+    `days` int(11) NOT NULL COMMENT '延期天数',
+    `old_expiration_time` datetime(3) DEFAULT NULL COMMENT '延期前的过期时间',
+    `new_expiration_time` datetime(3) DEFAULT NULL COMMENT '延期后的过期时间',
+    `source` varchar(20) NOT NULL COMMENT '延期来源 manual/batch/auto_renew',
+    `create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '延期时间',
+    PRIMARY KEY (`id`),
+    INDEX `idx_user_time` (`user_id`, `create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户授权延期记录表';
+
+-- 已有数据库升级语句
+-- ALTER TABLE `wx_robot_configs` ADD COLUMN `timeout_seconds` int NOT NULL DEFAULT 0 COMMENT '外部接口调用超时(秒)，0表示使用全局默认' AFTER `admin_users`;
+-- ALTER TABLE `wx_user_logins` ADD COLUMN `remark` varchar(200) DEFAULT NULL COMMENT '运营备注' AFTER `is_message_bot`;
+-- ALTER TABLE `wx_bill_info` ADD INDEX `idx_bill_dedup` (`group_id`, `msg_time`, `operator`);
+-- ALTER TABLE `wx_user_logins` ADD COLUMN `signature` varchar(100) DEFAULT NULL COMMENT '发送文本时自动拼接的签名' AFTER `remark`;
+-- ALTER TABLE `wx_user_logins` ADD COLUMN `signature_position` varchar(10) NOT NULL DEFAULT 'suffix' COMMENT '签名位置 prefix前缀 suffix后缀' AFTER `signature`;
+-- ALTER TABLE `wx_groups` ADD COLUMN `member_count` int(11) NOT NULL DEFAULT 0 COMMENT '群成员数' AFTER `group_nick_name`;
+-- ALTER TABLE `wx_user_logins` ADD COLUMN `init_start_time` datetime(3) DEFAULT NULL COMMENT '进入未初始化状态的时间' AFTER `is_initialized`;
+-- ALTER TABLE `wx_groups` ADD COLUMN `chat_room_owner` varchar(100) DEFAULT NULL COMMENT '群主微信ID' AFTER `member_count`;
+-- ALTER TABLE `wx_robot_configs` ADD COLUMN `health_path` varchar(255) NOT NULL DEFAULT '/' COMMENT '健康检查路径' AFTER `timeout_seconds`;
+-- 升级唯一索引前需先清理重复记录，保留id最大的一条：
+-- DELETE a FROM `wx_user_logins` a JOIN `wx_user_logins` b ON a.`robot_id` = b.`robot_id` AND a.`wx_id` = b.`wx_id` AND a.`id` < b.`id`;
+-- ALTER TABLE `wx_user_logins` DROP INDEX `idx_robot_wx`, ADD UNIQUE KEY `uk_robot_wx` (`robot_id`, `wx_id`);
+-- ALTER TABLE `wx_group_messages` ADD INDEX `idx_group_msgtime` (`group_id`, `msg_time`);
+-- ALTER TABLE `wx_group_messages` ADD FULLTEXT INDEX `ft_content` (`content`) WITH PARSER ngram;
+-- ALTER TABLE `wx_send_audits` ADD COLUMN `owner_id` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '发送账号所属公司ID' AFTER `sender_wx_id`, ADD INDEX `idx_owner_time` (`owner_id`, `create_time`);
+-- ALTER TABLE `wx_robot_configs` ADD COLUMN `enabled` tinyint(1) NOT NULL DEFAULT '1' COMMENT '是否启用 0禁用 1启用，禁用后其账号不再作为消息机器人发送' AFTER `health_path`;
+-- ALTER TABLE `wx_user_logins` ADD COLUMN `auto_renew` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否到期前自动续期 0否 1是' AFTER `expiration_time`;
+-- ALTER TABLE `wx_robot_configs` ADD COLUMN `tags` varchar(500) DEFAULT NULL COMMENT '标签，用逗号分隔，用于分组统计' AFTER `enabled`;
+-- 时间字段改为按UTC存储，各表历史datetime字段需按原部署时区转换（以下以+08:00、wx_user_logins为例，其它表同理），msg_time为时间戳无需转换
+-- UPDATE `wx_user_logins` SET `create_time` = CONVERT_TZ(`create_time`, '+08:00', '+00:00'), `update_time` = CONVERT_TZ(`update_time`, '+08:00', '+00:00'), `extension_time` = CONVERT_TZ(`extension_time`, '+08:00', '+00:00'), `expiration_time` = CONVERT_TZ(`expiration_time`, '+08:00', '+00:00');
 
 -- 插入示例数据（可选）
 -- INSERT INTO `wx_robot_configs` (`address`, `admin_key`, `owner_id`) VALUES 
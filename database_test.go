@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestWithTransaction(t *testing.T) {
+	errStep := errors.New("第二步失败")
+
+	tests := []struct {
+		name      string
+		fn        func(tx *gorm.DB) error
+		wantErr   bool
+		wantCount int64
+	}{
+		{
+			name: "commit",
+			fn: func(tx *gorm.DB) error {
+				if err := tx.Create(&WxBillInfo{GroupID: "g", Amount: "1"}).Error; err != nil {
+					return err
+				}
+				return tx.Create(&WxBillInfo{GroupID: "g", Amount: "2"}).Error
+			},
+			wantCount: 2,
+		},
+		{
+			name: "rollback on error",
+			fn: func(tx *gorm.DB) error {
+				if err := tx.Create(&WxBillInfo{GroupID: "g", Amount: "1"}).Error; err != nil {
+					return err
+				}
+				return errStep
+			},
+			wantErr: true,
+		},
+		{
+			name: "rollback on panic",
+			fn: func(tx *gorm.DB) error {
+				if err := tx.Create(&WxBillInfo{GroupID: "g", Amount: "1"}).Error; err != nil {
+					return err
+				}
+				panic("boom")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			err := WithTransaction(db, tt.fn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithTransaction = %v, wantErr %v", err, tt.wantErr)
+			}
+			var count int64
+			db.Model(&WxBillInfo{}).Count(&count)
+			if count != tt.wantCount {
+				t.Fatalf("bill count = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+
+	t.Run("error is preserved", func(t *testing.T) {
+		err := WithTransaction(newTestDB(t), func(tx *gorm.DB) error { return errStep })
+		if !errors.Is(err, errStep) {
+			t.Fatalf("err = %v, want %v", err, errStep)
+		}
+	})
+}
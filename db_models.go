@@ -6,15 +6,19 @@ import (
 
 // 数据库模型
 type WxRobotConfig struct {
-	ID          uint          `json:"id" gorm:"primaryKey;autoIncrement"`
-	Address     string        `json:"address" gorm:"type:varchar(255);not null;comment:机器人地址"`
-	AdminKey    string        `json:"admin_key" gorm:"type:varchar(255);not null;comment:管理密钥"`
-	OwnerID     uint          `json:"owner_id" gorm:"not null;comment:所属公司ID"`
-	Description string        `json:"description" gorm:"type:varchar(500);comment:文本描述"`
-	AdminUsers  string        `json:"admin_users" gorm:"type:text;comment:管理员用户列表，用逗号分隔"`
-	CreateTime  time.Time     `json:"create_time" gorm:"autoCreateTime;comment:创建时间"`
-	UpdateTime  time.Time     `json:"update_time" gorm:"autoUpdateTime;comment:修改时间"`
-	UserLogins  []WxUserLogin `json:"user_logins" gorm:"foreignKey:RobotID"`
+	ID             uint          `json:"id" gorm:"primaryKey;autoIncrement"`
+	Address        string        `json:"address" gorm:"type:varchar(255);not null;comment:机器人地址"`
+	AdminKey       string        `json:"admin_key" gorm:"type:varchar(255);not null;comment:管理密钥"`
+	OwnerID        uint          `json:"owner_id" gorm:"not null;comment:所属公司ID"`
+	Description    string        `json:"description" gorm:"type:varchar(500);comment:文本描述"`
+	AdminUsers     string        `json:"admin_users" gorm:"type:text;comment:管理员用户列表，用逗号分隔"`
+	TimeoutSeconds int           `json:"timeout_seconds" gorm:"default:0;comment:外部接口调用超时(秒)，0表示使用全局默认"`
+	HealthPath     string        `json:"health_path" gorm:"type:varchar(255);not null;default:'/';comment:健康检查路径"`
+	Enabled        int           `json:"enabled" gorm:"default:1;comment:是否启用 0禁用 1启用，禁用后其账号不再作为消息机器人发送"`
+	Tags           string        `json:"tags" gorm:"type:varchar(500);comment:标签，用逗号分隔，用于分组统计"`
+	CreateTime     time.Time     `json:"create_time" gorm:"autoCreateTime;comment:创建时间"`
+	UpdateTime     time.Time     `json:"update_time" gorm:"autoUpdateTime;comment:修改时间"`
+	UserLogins     []WxUserLogin `json:"user_logins" gorm:"foreignKey:RobotID"`
 }
 
 func (WxRobotConfig) TableName() string {
@@ -22,30 +26,87 @@ func (WxRobotConfig) TableName() string {
 }
 
 type WxUserLogin struct {
-	ID              uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	RobotID         uint      `json:"robot_id" gorm:"not null;comment:关联的机器人ID"`
-	Token           string    `json:"token" gorm:"type:varchar(500);comment:登录令牌"`
-	WxID            string    `json:"wx_id" gorm:"type:varchar(100);comment:微信ID"`
-	NickName        string    `json:"nick_name" gorm:"type:varchar(100);comment:微信昵称"`
-	ExtensionTime   time.Time `json:"extension_time" gorm:"comment:延期时间"`
-	HasSecurityRisk int       `json:"has_security_risk" gorm:"default:0;comment:是否有安全风险 0否 1是"`
-	ExpirationTime  time.Time `json:"expiration_time" gorm:"comment:过期时间"`
-	Status          int       `json:"status" gorm:"default:1;comment:状态 1正常 2风控 3需要重新登录"`
-	IsInitialized   int       `json:"is_initialized" gorm:"default:0;comment:是否初始化完成 0未初始化 1初始化完成"`
-	IsMessageBot    int       `json:"is_message_bot" gorm:"default:0;comment:是否是消息机器人 0不是 1是"`
-	CreateTime      time.Time `json:"create_time" gorm:"autoCreateTime;comment:创建时间"`
-	UpdateTime      time.Time `json:"update_time" gorm:"autoUpdateTime;comment:修改时间"`
+	ID              uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	RobotID         uint       `json:"robot_id" gorm:"not null;uniqueIndex:uk_robot_wx;comment:关联的机器人ID"`
+	Token           string     `json:"token" gorm:"type:varchar(500);comment:登录令牌"`
+	WxID            string     `json:"wx_id" gorm:"type:varchar(100);uniqueIndex:uk_robot_wx;comment:微信ID"`
+	NickName        string     `json:"nick_name" gorm:"type:varchar(100);comment:微信昵称"`
+	ExtensionTime   time.Time  `json:"extension_time" gorm:"comment:延期时间"`
+	HasSecurityRisk int        `json:"has_security_risk" gorm:"default:0;comment:是否有安全风险 0否 1是"`
+	ExpirationTime  time.Time  `json:"expiration_time" gorm:"comment:过期时间"`
+	AutoRenew       int        `json:"auto_renew" gorm:"default:0;comment:是否到期前自动续期 0否 1是"`
+	Status          int        `json:"status" gorm:"default:1;comment:状态 1正常 2风控 3需要重新登录"`
+	IsInitialized   int        `json:"is_initialized" gorm:"default:0;comment:是否初始化完成 0未初始化 1初始化完成"`
+	InitStartTime   *time.Time `json:"init_start_time" gorm:"comment:进入未初始化状态的时间"`
+	IsMessageBot    int        `json:"is_message_bot" gorm:"default:0;comment:是否是消息机器人 0不是 1是"`
+	Remark          string     `json:"remark" gorm:"type:varchar(200);comment:运营备注"`
+	Signature       string     `json:"signature" gorm:"type:varchar(100);comment:发送文本时自动拼接的签名"`
+	SignaturePos    string     `json:"signature_position" gorm:"column:signature_position;type:varchar(10);default:suffix;comment:签名位置 prefix前缀 suffix后缀"`
+	CreateTime      time.Time  `json:"create_time" gorm:"autoCreateTime;comment:创建时间"`
+	UpdateTime      time.Time  `json:"update_time" gorm:"autoUpdateTime;comment:修改时间"`
 }
 
 func (WxUserLogin) TableName() string {
 	return "wx_user_logins"
 }
 
+// 用户状态
+const (
+	UserStatusNormal  = 1 // 正常
+	UserStatusRisk    = 2 // 风控
+	UserStatusRelogin = 3 // 需要重新登录
+)
+
+// 签名位置
+const (
+	SignaturePositionPrefix = "prefix" // 拼接在文本前
+	SignaturePositionSuffix = "suffix" // 拼接在文本后
+)
+
+// WxUserStatusLog 用户状态变更日志
+type WxUserStatusLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint      `json:"user_id" gorm:"not null;index;comment:用户ID"`
+	OldStatus  int       `json:"old_status" gorm:"not null;comment:变更前状态"`
+	NewStatus  int       `json:"new_status" gorm:"not null;comment:变更后状态"`
+	Reason     string    `json:"reason" gorm:"type:varchar(255);comment:变更原因"`
+	CreateTime time.Time `json:"create_time" gorm:"autoCreateTime;comment:变更时间"`
+}
+
+func (WxUserStatusLog) TableName() string {
+	return "wx_user_status_logs"
+}
+
+// 授权延期来源
+const (
+	ExtensionSourceManual    = "manual"     // 单个延期接口
+	ExtensionSourceBatch     = "batch"      // 批量延期接口
+	ExtensionSourceAutoRenew = "auto_renew" // 到期自动续期
+)
+
+// WxUserExtensionLog 用户授权延期记录，每次延期成功记录一条
+type WxUserExtensionLog struct {
+	ID                uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID            uint      `json:"user_id" gorm:"not null;index;comment:用户ID"`
+	RobotID           uint      `json:"robot_id" gorm:"not null;comment:机器人ID"`
+	Days              int       `json:"days" gorm:"not null;comment:延期天数"`
+	OldExpirationTime time.Time `json:"old_expiration_time" gorm:"comment:延期前的过期时间"`
+	NewExpirationTime time.Time `json:"new_expiration_time" gorm:"comment:延期后的过期时间"`
+	Source            string    `json:"source" gorm:"type:varchar(20);not null;comment:延期来源 manual/batch/auto_renew"`
+	CreateTime        time.Time `json:"create_time" gorm:"autoCreateTime;comment:延期时间"`
+}
+
+func (WxUserExtensionLog) TableName() string {
+	return "wx_user_extension_logs"
+}
+
 type WxGroup struct {
 	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	WxID          string    `json:"wx_id" gorm:"type:varchar(100);not null;comment:微信ID"`
 	GroupID       string    `json:"group_id" gorm:"type:varchar(100);not null;comment:群组ID"`
 	GroupNickName string    `json:"group_nick_name" gorm:"type:varchar(200);comment:群组昵称"`
+	MemberCount   int       `json:"member_count" gorm:"default:0;comment:群成员数"`
+	ChatRoomOwner string    `json:"chat_room_owner" gorm:"type:varchar(100);comment:群主微信ID"`
 	CreateTime    time.Time `json:"create_time" gorm:"autoCreateTime;comment:创建时间"`
 	UpdateTime    time.Time `json:"update_time" gorm:"autoUpdateTime;comment:修改时间"`
 }
@@ -54,6 +115,46 @@ func (WxGroup) TableName() string {
 	return "wx_groups"
 }
 
+// WxGroupLeaveLog 退群记录，群组同步发现机器人已不在群中时写入
+type WxGroupLeaveLog struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WxID          string    `json:"wx_id" gorm:"type:varchar(100);not null;comment:微信ID"`
+	GroupID       string    `json:"group_id" gorm:"type:varchar(100);not null;comment:群组ID"`
+	GroupNickName string    `json:"group_nick_name" gorm:"type:varchar(200);comment:群组昵称"`
+	JoinTime      time.Time `json:"join_time" gorm:"comment:入群时间（群记录创建时间）"`
+	LeaveTime     time.Time `json:"leave_time" gorm:"not null;comment:退群时间"`
+}
+
+func (WxGroupLeaveLog) TableName() string {
+	return "wx_group_leave_logs"
+}
+
+// WxGroupBlacklist 群黑名单，名单中的群禁止机器人发送消息
+type WxGroupBlacklist struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	GroupID    string    `json:"group_id" gorm:"type:varchar(100);not null;uniqueIndex;comment:群组ID"`
+	Reason     string    `json:"reason" gorm:"type:varchar(200);comment:加入黑名单原因"`
+	CreateTime time.Time `json:"create_time" gorm:"autoCreateTime;comment:创建时间"`
+}
+
+func (WxGroupBlacklist) TableName() string {
+	return "wx_group_blacklist"
+}
+
+// WxGroupMember 群成员，随群组同步更新
+type WxGroupMember struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	GroupID    string    `json:"group_id" gorm:"type:varchar(100);not null;comment:群组ID"`
+	MemberWxID string    `json:"member_wx_id" gorm:"type:varchar(100);not null;comment:成员微信ID"`
+	NickName   string    `json:"nick_name" gorm:"type:varchar(200);comment:成员昵称"`
+	CreateTime time.Time `json:"create_time" gorm:"autoCreateTime;comment:创建时间"`
+	UpdateTime time.Time `json:"update_time" gorm:"autoUpdateTime;comment:修改时间"`
+}
+
+func (WxGroupMember) TableName() string {
+	return "wx_group_members"
+}
+
 type WxBillInfo struct {
 	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	GroupName   string    `json:"group_name" gorm:"type:varchar(50);not null;comment:群组名称"`
@@ -89,4 +190,118 @@ type WxGroupMessage struct {
 
 func (WxGroupMessage) TableName() string {
 	return "wx_group_messages"
-}
\ No newline at end of file
+}
+
+// WxSendRecord 消息发送记录，同一发送批次（如一次群发）共享trace_id，便于排查时关联
+type WxSendRecord struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TraceID    string    `json:"trace_id" gorm:"type:varchar(64);not null;index;comment:发送批次追踪ID"`
+	OwnerID    uint      `json:"owner_id" gorm:"not null;default:0;comment:发起发送的API Key绑定的公司ID，0表示未使用API Key"`
+	ToUserName string    `json:"to_user_name" gorm:"type:varchar(100);not null;comment:目标群ID"`
+	WxID       string    `json:"wx_id" gorm:"type:varchar(100);comment:实际发送的消息机器人"`
+	Success    bool      `json:"success" gorm:"not null;default:false;comment:是否发送成功"`
+	Error      string    `json:"error" gorm:"type:varchar(500);comment:失败原因"`
+	CreateTime time.Time `json:"create_time" gorm:"autoCreateTime;comment:创建时间"`
+}
+
+func (WxSendRecord) TableName() string {
+	return "wx_send_records"
+}
+
+// 机器人生成的授权码，是否已分配按wx_user_logins中是否有账号使用该token判断
+type WxAuthKey struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	RobotID    uint      `json:"robot_id" gorm:"not null;index;comment:生成授权码的机器人ID"`
+	AuthKey    string    `json:"auth_key" gorm:"type:varchar(500);not null;uniqueIndex;comment:授权码"`
+	Days       int       `json:"days" gorm:"not null;comment:授权有效天数"`
+	ExpireTime time.Time `json:"expire_time" gorm:"comment:授权到期时间"`
+	Source     string    `json:"source" gorm:"type:varchar(20);not null;default:manual;comment:生成来源 manual/rotate_probe"`
+	CreateTime time.Time `json:"create_time" gorm:"autoCreateTime;comment:生成时间"`
+}
+
+func (WxAuthKey) TableName() string {
+	return "wx_auth_keys"
+}
+
+// 发送审计的消息类型
+const (
+	SendAuditTypeText      = "text"
+	SendAuditTypeImage     = "image"
+	SendAuditTypeTextImage = "text_image"
+	SendAuditTypeVoice     = "voice"
+	SendAuditTypeVideo     = "video"
+	SendAuditTypeLink      = "link"
+)
+
+// WxSendAudit 发送内容审计记录，每次对外发送一条，满足合规追溯要求
+type WxSendAudit struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	SenderWxID  string    `json:"sender_wx_id" gorm:"type:varchar(100);comment:发送账号微信ID"`
+	OwnerID     uint      `json:"owner_id" gorm:"not null;default:0;comment:发送账号所属公司ID"`
+	ToUserName  string    `json:"to_user_name" gorm:"type:varchar(100);not null;comment:目标群ID"`
+	MsgType     string    `json:"msg_type" gorm:"type:varchar(20);not null;comment:消息类型 text/image/text_image/voice/video/link"`
+	Content     string    `json:"content" gorm:"type:text;comment:发送的文本内容，配置为只存哈希时为空"`
+	ContentHash string    `json:"content_hash" gorm:"type:char(64);not null;comment:文本与图片内容的SHA-256"`
+	Success     bool      `json:"success" gorm:"not null;default:false;comment:是否发送成功"`
+	Error       string    `json:"error" gorm:"type:varchar(500);comment:失败原因"`
+	CreateTime  time.Time `json:"create_time" gorm:"autoCreateTime;comment:发送时间"`
+}
+
+func (WxSendAudit) TableName() string {
+	return "wx_send_audits"
+}
+
+// 失败消息重试状态
+const (
+	SendRetryStatusPending = "pending" // 等待重试
+	SendRetryStatusSuccess = "success" // 重试发送成功
+	SendRetryStatusFailed  = "failed"  // 重试次数用尽或遇到不可重试的失败，最终失败
+)
+
+// WxSendRetry 因临时故障发送失败、等待后台重发的消息，payload为原始发送请求
+type WxSendRetry struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	OwnerID       uint      `json:"owner_id" gorm:"not null;default:0;comment:发起发送的API Key绑定的公司ID，重发只使用该公司的消息机器人"`
+	MsgType       string    `json:"msg_type" gorm:"type:varchar(20);not null;comment:消息类型 text/image/voice/video/link"`
+	ToUserName    string    `json:"to_user_name" gorm:"type:varchar(100);not null;comment:目标群ID"`
+	Payload       string    `json:"-" gorm:"type:mediumtext;not null;comment:原始发送请求JSON"`
+	Status        string    `json:"status" gorm:"type:varchar(20);not null;default:pending;comment:状态 pending/success/failed"`
+	Attempts      int       `json:"attempts" gorm:"not null;default:0;comment:已重试次数"`
+	LastError     string    `json:"last_error" gorm:"type:varchar(500);comment:最近一次失败原因"`
+	NextRetryTime time.Time `json:"next_retry_time" gorm:"not null;comment:下次重试时间"`
+	CreateTime    time.Time `json:"create_time" gorm:"autoCreateTime;comment:创建时间"`
+	UpdateTime    time.Time `json:"update_time" gorm:"autoUpdateTime;comment:修改时间"`
+}
+
+func (WxSendRetry) TableName() string {
+	return "wx_send_retries"
+}
+
+// WxRobotConfigChange 机器人配置字段级变更记录，一次修改变更了几个字段就记录几条
+// 敏感字段（管理密钥）只记录变更事实，新旧值不记录明文
+type WxRobotConfigChange struct {
+	ID              uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	RobotID         uint      `json:"robot_id" gorm:"not null;index;comment:机器人ID"`
+	Field           string    `json:"field" gorm:"type:varchar(50);not null;comment:变更字段"`
+	OldValue        string    `json:"old_value" gorm:"type:text;comment:变更前的值，敏感字段不记录明文"`
+	NewValue        string    `json:"new_value" gorm:"type:text;comment:变更后的值，敏感字段不记录明文"`
+	Source          string    `json:"source" gorm:"type:varchar(30);not null;comment:变更来源 update/patch/rotate_admin_key/batch_status/idle_disable/idle_delete"`
+	OperatorOwnerID uint      `json:"operator_owner_id" gorm:"default:0;comment:操作者API Key绑定的公司ID，0表示未使用API Key"`
+	ClientIP        string    `json:"client_ip" gorm:"type:varchar(64);comment:操作者IP"`
+	CreateTime      time.Time `json:"create_time" gorm:"autoCreateTime;comment:变更时间"`
+}
+
+func (WxRobotConfigChange) TableName() string {
+	return "wx_robot_config_changes"
+}
+
+// SchemaMigration 已执行的数据库迁移记录，按版本号记录
+type SchemaMigration struct {
+	Version     uint      `json:"version" gorm:"primaryKey;autoIncrement:false;comment:迁移版本"`
+	Description string    `json:"description" gorm:"type:varchar(200);comment:迁移说明"`
+	AppliedTime time.Time `json:"applied_time" gorm:"not null;comment:执行时间"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
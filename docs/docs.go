@@ -24,73 +24,85 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/extend/{robotId}": {
-            "post": {
-                "description": "延长机器人授权有效期",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/migrations": {
+            "get": {
+                "description": "返回数据库当前迁移版本、程序登记的最新版本、已执行的迁移记录与待执行的迁移",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
-                ],
-                "summary": "延期授权",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "机器人ID",
-                        "name": "robotId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "延期天数",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "days": {
-                                    "type": "integer"
-                                }
-                            }
-                        }
-                    }
+                    "system"
                 ],
+                "summary": "数据库迁移版本",
                 "responses": {
                     "200": {
-                        "description": "延期成功",
+                        "description": "查询成功",
                         "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.MigrationStatus"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
-                    "400": {
-                        "description": "参数错误",
+                    "401": {
+                        "description": "鉴权失败",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
-                    "404": {
-                        "description": "机器人或用户不存在",
+                    "500": {
+                        "description": "查询失败",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
-                    },
-                    "500": {
-                        "description": "内部服务器错误",
+                    }
+                }
+            }
+        },
+        "/admin/stats": {
+            "get": {
+                "description": "返回服务启动时间、运行时长，以及启动以来累计处理的请求数、发送成功的消息数和错误数",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "运行统计",
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
                         "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.RuntimeStatsInfo"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
                 }
             }
         },
-        "/bills/list": {
+        "/audits": {
             "get": {
-                "description": "根据条件查询账单信息，支持分页",
+                "description": "按发送时间、目标群、发送账号分页查询对外发送内容的审计记录，按时间倒序；需在请求头 X-Admin-Token 中携带管理员令牌，或携带 X-API-Key 查看本公司记录",
                 "consumes": [
                     "application/json"
                 ],
@@ -98,63 +110,68 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "bills"
+                    "audits"
                 ],
-                "summary": "查询账单列表",
+                "summary": "查询发送审计记录",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "创建时间开始，格式：yyyy-mm-dd hh:mi:ss",
-                        "name": "create_time_start",
-                        "in": "query"
+                        "description": "管理员令牌，与X-API-Key二选一",
+                        "name": "X-Admin-Token",
+                        "in": "header"
                     },
                     {
                         "type": "string",
-                        "description": "创建时间结束，格式：yyyy-mm-dd hh:mi:ss",
-                        "name": "create_time_end",
+                        "description": "租户API Key，只能查看本公司记录",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "发送时间开始，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "start_time",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "群名称",
-                        "name": "group_name",
+                        "description": "发送时间结束，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "end_time",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "群ID",
-                        "name": "group_id",
+                        "description": "目标群ID",
+                        "name": "to_user_name",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "账单状态",
-                        "name": "status",
+                        "description": "发送账号微信ID",
+                        "name": "sender_wx_id",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "页码，默认1",
-                        "name": "page_num",
+                        "description": "发送账号所属公司ID，使用租户API Key时默认为Key绑定的公司",
+                        "name": "owner_id",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "每页大小，默认10，最大100",
-                        "name": "page_size",
+                        "description": "页码，默认1",
+                        "name": "page_no",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "所属公司ID",
-                        "name": "owner_id",
-                        "in": "query",
-                        "required": true
+                        "description": "每页数量，默认10，最大100",
+                        "name": "page_size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "查询成功",
                         "schema": {
                             "allOf": [
                                 {
@@ -164,7 +181,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/main.BillQueryPaginatedResponse"
+                                            "$ref": "#/definitions/main.SendAuditPaginatedResponse"
                                         }
                                     }
                                 }
@@ -172,13 +189,19 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "鉴权失败",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "内部服务器错误",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
@@ -186,74 +209,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/bills/stats": {
+        "/audits/export": {
             "get": {
-                "description": "根据群组ID和群组昵称获取账单统计信息，按group_id和group_name分组统计金额总数，支持分页",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "按发送时间、目标群、发送账号过滤，流式导出审计记录为CSV文件；需在请求头 X-Admin-Token 中携带管理员令牌，或携带 X-API-Key 查看本公司记录",
                 "produces": [
-                    "application/json"
+                    "text/csv"
                 ],
                 "tags": [
-                    "bills"
+                    "audits"
                 ],
-                "summary": "获取账单统计信息（分页）",
+                "summary": "导出发送审计记录",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "群组ID",
-                        "name": "group_id",
+                        "description": "管理员令牌，与X-API-Key二选一",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "租户API Key，只能查看本公司记录",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "发送时间开始，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "start_time",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "群组昵称",
-                        "name": "group_nick",
+                        "description": "发送时间结束，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "end_time",
                         "in": "query"
                     },
                     {
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 1,
-                        "description": "页码，默认1",
-                        "name": "page_no",
+                        "type": "string",
+                        "description": "目标群ID",
+                        "name": "to_user_name",
                         "in": "query"
                     },
                     {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 10,
-                        "description": "每页大小，默认10",
-                        "name": "page_size",
+                        "type": "string",
+                        "description": "发送账号微信ID",
+                        "name": "sender_wx_id",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "所属公司ID",
+                        "description": "发送账号所属公司ID，使用租户API Key时默认为Key绑定的公司",
                         "name": "owner_id",
-                        "in": "query",
-                        "required": true
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
+                        "description": "CSV文件",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/main.APIResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "data": {
-                                            "$ref": "#/definitions/main.BillStatsPaginatedResponse"
-                                        }
-                                    }
-                                }
-                            ]
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -262,8 +276,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
-                    "500": {
-                        "description": "内部服务器错误",
+                    "401": {
+                        "description": "鉴权失败",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
@@ -271,31 +285,57 @@ const docTemplate = `{
                 }
             }
         },
-        "/groups/search": {
+        "/audits/tag-stats": {
             "get": {
-                "description": "根据群名称进行模糊搜索",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "基于发送审计记录，按机器人标签汇总发送总数、成功数、失败数与成功率，用于对比不同分组机器人；有多个标签的机器人计入每个标签，未打标签的机器人汇总到空标签；需在请求头 X-Admin-Token 中携带管理员令牌，或携带 X-API-Key 查看本公司记录",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "audits"
                 ],
-                "summary": "搜索群组",
+                "summary": "按机器人标签对比发送统计",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "群名称",
-                        "name": "groupNickName",
-                        "in": "query",
-                        "required": true
+                        "description": "管理员令牌，与X-API-Key二选一",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "租户API Key，只能查看本公司记录",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "发送时间开始，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "start_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "发送时间结束，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "end_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "机器人所属公司ID，使用租户API Key时默认为Key绑定的公司",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "只对比这些标签，逗号分隔，为空时全部标签",
+                        "name": "tags",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "搜索成功",
+                        "description": "查询成功，按发送总数降序",
                         "schema": {
                             "allOf": [
                                 {
@@ -307,7 +347,7 @@ const docTemplate = `{
                                         "data": {
                                             "type": "array",
                                             "items": {
-                                                "$ref": "#/definitions/main.WxGroup"
+                                                "$ref": "#/definitions/main.RobotTagSendStats"
                                             }
                                         }
                                     }
@@ -321,6 +361,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
+                    "401": {
+                        "description": "鉴权失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -330,9 +376,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/groups/user/{wxId}": {
-            "get": {
-                "description": "获取指定微信用户的所有群组信息",
+        "/auth/extend-batch": {
+            "post": {
+                "description": "按所属公司或机器人批量延期用户授权，可只处理N天内到期的用户，返回每个用户的延期结果",
                 "consumes": [
                     "application/json"
                 ],
@@ -340,21 +386,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "auth"
                 ],
-                "summary": "获取用户群组列表",
+                "summary": "批量延期授权",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "微信ID",
-                        "name": "wxId",
-                        "in": "path",
-                        "required": true
+                        "description": "批量延期参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.BatchExtendAuthRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "查询成功",
+                        "description": "延期完成（可能部分失败）",
                         "schema": {
                             "allOf": [
                                 {
@@ -364,10 +412,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/main.WxGroup"
-                                            }
+                                            "$ref": "#/definitions/main.BatchExtendAuthResponse"
                                         }
                                     }
                                 }
@@ -389,9 +434,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/messages/group/send-image": {
+        "/auth/extend/{robotId}": {
             "post": {
-                "description": "向指定群组发送图片消息",
+                "description": "延长机器人授权有效期",
                 "consumes": [
                     "application/json"
                 ],
@@ -399,23 +444,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "auth"
                 ],
-                "summary": "发送图片消息",
+                "summary": "延期授权",
                 "parameters": [
                     {
-                        "description": "图片消息参数",
+                        "type": "string",
+                        "description": "机器人ID",
+                        "name": "robotId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "延期天数",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "type": "object",
                             "properties": {
-                                "image_content": {
-                                    "type": "string"
-                                },
-                                "to_user_name": {
-                                    "type": "string"
+                                "days": {
+                                    "type": "integer"
                                 }
                             }
                         }
@@ -423,7 +472,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "发送成功",
+                        "description": "延期成功",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
@@ -435,7 +484,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "未找到消息机器人",
+                        "description": "机器人或用户不存在",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
@@ -449,9 +498,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/messages/group/send-text": {
-            "post": {
-                "description": "向指定群组发送文本消息",
+        "/bills/group/{groupId}/recent": {
+            "get": {
+                "description": "按账单时间倒序返回指定群最近的limit笔账单，只返回属于owner_id所属公司的账单",
                 "consumes": [
                     "application/json"
                 ],
@@ -459,33 +508,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "bills"
                 ],
-                "summary": "发送文本消息",
+                "summary": "查询群最近账单",
                 "parameters": [
                     {
-                        "description": "文本消息参数",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "text_content": {
-                                    "type": "string"
-                                },
-                                "to_user_name": {
-                                    "type": "string"
-                                }
-                            }
-                        }
+                        "type": "string",
+                        "description": "群ID",
+                        "name": "groupId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID",
+                        "name": "owner_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "返回条数，默认10，最大100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "发送成功",
+                        "description": "查询成功",
                         "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.BillInfoResponse"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -494,12 +564,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
-                    "404": {
-                        "description": "未找到消息机器人",
-                        "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
-                        }
-                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -509,9 +573,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/messages/group/send-text-image": {
-            "post": {
-                "description": "向指定群组同时发送文本和图片消息",
+        "/bills/list": {
+            "get": {
+                "description": "根据条件查询账单信息，支持分页",
                 "consumes": [
                     "application/json"
                 ],
@@ -519,120 +583,80 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "bills"
                 ],
-                "summary": "发送文本和图片消息",
+                "summary": "查询账单列表",
                 "parameters": [
                     {
-                        "description": "混合消息参数",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "image_content": {
-                                    "type": "string"
-                                },
-                                "text_content": {
-                                    "type": "string"
-                                },
-                                "to_user_name": {
-                                    "type": "string"
-                                }
-                            }
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "发送成功",
-                        "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
-                        }
+                        "type": "string",
+                        "description": "创建时间开始，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "create_time_start",
+                        "in": "query"
                     },
-                    "400": {
-                        "description": "参数错误",
-                        "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
-                        }
+                    {
+                        "type": "string",
+                        "description": "创建时间结束，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "create_time_end",
+                        "in": "query"
                     },
-                    "404": {
-                        "description": "未找到消息机器人",
-                        "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
-                        }
+                    {
+                        "type": "string",
+                        "description": "群名称",
+                        "name": "group_name",
+                        "in": "query"
                     },
-                    "500": {
-                        "description": "内部服务器错误",
-                        "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/messages/group/set-strategy": {
-            "post": {
-                "description": "设置系统的消息发送策略（随机或轮询）",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "messages"
-                ],
-                "summary": "设置消息发送策略",
-                "parameters": [
                     {
-                        "description": "策略参数 (random/round_robin)",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "strategy": {
-                                    "type": "string"
-                                }
-                            }
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "设置成功",
-                        "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
-                        }
+                        "type": "string",
+                        "description": "群ID",
+                        "name": "group_id",
+                        "in": "query"
                     },
-                    "400": {
-                        "description": "参数错误",
-                        "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
-                        }
+                    {
+                        "type": "string",
+                        "description": "账单状态",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "操作人",
+                        "name": "operator",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码，默认1",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页大小，默认10，最大100",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID",
+                        "name": "owner_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "plain",
+                            "currency",
+                            "thousands"
+                        ],
+                        "type": "string",
+                        "description": "金额格式：plain 原样（默认）、currency 货币符号加千分位、thousands 千分位",
+                        "name": "format",
+                        "in": "query"
                     }
-                }
-            }
-        },
-        "/robots/": {
-            "get": {
-                "description": "获取所有机器人配置及其关联的用户信息",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "robots"
                 ],
-                "summary": "获取机器人列表",
                 "responses": {
                     "200": {
-                        "description": "查询成功",
+                        "description": "OK",
                         "schema": {
                             "allOf": [
                                 {
@@ -642,26 +666,31 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/main.WxRobotConfig"
-                                            }
+                                            "$ref": "#/definitions/main.BillQueryPaginatedResponse"
                                         }
                                     }
                                 }
                             ]
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
                     "500": {
-                        "description": "内部服务器错误",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "创建新的微信机器人配置",
+            }
+        },
+        "/bills/stats": {
+            "get": {
+                "description": "根据群组ID和群组昵称获取账单统计信息，默认按group_id和group_name分组统计金额总数，group_by=operator时按操作人分组，支持分页；每个分组项的detail_filter可直接作为 /bills/list 的查询参数下钻明细；compare=true时在comparison中返回与上一等长时间段的环比",
                 "consumes": [
                     "application/json"
                 ],
@@ -669,23 +698,89 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "robots"
+                    "bills"
                 ],
-                "summary": "创建机器人配置",
+                "summary": "获取账单统计信息（分页）",
                 "parameters": [
                     {
-                        "description": "机器人配置信息",
-                        "name": "robot",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.CreateRobotRequest"
-                        }
-                    }
+                        "type": "string",
+                        "description": "群组ID",
+                        "name": "group_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "群组昵称",
+                        "name": "group_nick",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码，默认1",
+                        "name": "page_no",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "每页大小，默认10",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID",
+                        "name": "owner_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "group",
+                            "operator"
+                        ],
+                        "type": "string",
+                        "description": "分组维度：group 按群（默认）、operator 按操作人",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "plain",
+                            "currency",
+                            "thousands"
+                        ],
+                        "type": "string",
+                        "description": "金额格式：plain 原样（默认）、currency 货币符号加千分位、thousands 千分位",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "账单时间开始，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "create_time_start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "账单时间结束，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "create_time_end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否返回与上一等长时间段的环比，需同时传开始和结束时间",
+                        "name": "compare",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "创建成功",
+                        "description": "获取成功",
                         "schema": {
                             "allOf": [
                                 {
@@ -695,7 +790,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/main.WxRobotConfig"
+                                            "$ref": "#/definitions/main.BillStatsPaginatedResponse"
                                         }
                                     }
                                 }
@@ -717,9 +812,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/robots/{id}": {
+        "/groups": {
             "get": {
-                "description": "根据ID获取机器人详细信息",
+                "description": "跨账号分页查询系统内全部群组，同一群被多个账号加入时合并为一条并返回覆盖的账号；支持按所属公司、群名称过滤",
                 "consumes": [
                     "application/json"
                 ],
@@ -727,16 +822,38 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "robots"
+                    "groups"
                 ],
-                "summary": "获取单个机器人信息",
+                "summary": "分页查询全部群组",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "群名称（模糊匹配）",
+                        "name": "group_nick_name",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "机器人ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "所属公司ID",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码，默认1",
+                        "name": "page_no",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "每页大小，默认10",
+                        "name": "page_size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -751,7 +868,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/main.WxRobotConfig"
+                                            "$ref": "#/definitions/main.GroupListPaginatedResponse"
                                         }
                                     }
                                 }
@@ -764,16 +881,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
-                    "404": {
-                        "description": "机器人不存在",
+                    "500": {
+                        "description": "内部服务器错误",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "更新机器人配置信息",
+            }
+        },
+        "/groups/blacklist": {
+            "get": {
+                "description": "查询禁止机器人发送消息的群",
                 "consumes": [
                     "application/json"
                 ],
@@ -781,30 +900,12 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "robots"
-                ],
-                "summary": "修改机器人配置",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "机器人ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "机器人配置信息",
-                        "name": "robot",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.UpdateRobotRequest"
-                        }
-                    }
+                    "groups"
                 ],
+                "summary": "查询群黑名单",
                 "responses": {
                     "200": {
-                        "description": "修改成功",
+                        "description": "查询成功",
                         "schema": {
                             "allOf": [
                                 {
@@ -814,25 +915,16 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/main.WxRobotConfig"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.WxGroupBlacklist"
+                                            }
                                         }
                                     }
                                 }
                             ]
                         }
                     },
-                    "400": {
-                        "description": "参数错误",
-                        "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "机器人不存在",
-                        "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
-                        }
-                    },
                     "500": {
                         "description": "内部服务器错误",
                         "schema": {
@@ -840,11 +932,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/users/authorize": {
+            },
             "post": {
-                "description": "为指定机器人生成授权token",
+                "description": "将群加入黑名单，之后所有发送接口向该群发送时都会被拒绝",
                 "consumes": [
                     "application/json"
                 ],
@@ -852,28 +942,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "groups"
                 ],
-                "summary": "获取授权信息",
+                "summary": "添加群黑名单",
                 "parameters": [
                     {
-                        "description": "请求参数",
+                        "description": "黑名单群",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "robot_id": {
-                                    "type": "integer"
-                                }
-                            }
+                            "$ref": "#/definitions/main.AddGroupBlacklistRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
+                        "description": "添加成功",
                         "schema": {
                             "allOf": [
                                 {
@@ -883,15 +968,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "object",
-                                            "properties": {
-                                                "robot_id": {
-                                                    "type": "integer"
-                                                },
-                                                "token": {
-                                                    "type": "string"
-                                                }
-                                            }
+                                            "$ref": "#/definitions/main.WxGroupBlacklist"
                                         }
                                     }
                                 }
@@ -899,13 +976,13 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "参数错误",
+                        "description": "参数错误或群已在黑名单中",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
-                    "404": {
-                        "description": "机器人不存在",
+                    "403": {
+                        "description": "无权访问其他公司的数据",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
@@ -919,9 +996,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/login-status/{id}": {
-            "get": {
-                "description": "获取用户当前的在线状态",
+        "/groups/blacklist/{groupId}": {
+            "delete": {
+                "description": "将群移出黑名单，恢复正常发送",
                 "consumes": [
                     "application/json"
                 ],
@@ -929,33 +1006,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "groups"
                 ],
-                "summary": "获取用户在线状态",
+                "summary": "移除群黑名单",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "用户ID",
-                        "name": "id",
+                        "description": "群组ID",
+                        "name": "groupId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "获取成功",
+                        "description": "移除成功",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
-                    "400": {
-                        "description": "参数错误",
+                    "403": {
+                        "description": "无权访问其他公司的数据",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
                     "404": {
-                        "description": "用户不存在",
+                        "description": "群不在黑名单中",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
@@ -969,9 +1046,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/message-bot-status/{id}": {
-            "post": {
-                "description": "设置用户是否为消息机器人",
+        "/groups/bot-coverage": {
+            "get": {
+                "description": "列出群内没有可用消息机器人的群并给出分配建议：群内已有正常账号时建议设为消息机器人（enable_message_bot），否则建议同公司覆盖群数最少的消息机器人加群（join_group）",
                 "consumes": [
                     "application/json"
                 ],
@@ -979,37 +1056,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "groups"
                 ],
-                "summary": "更新消息机器人状态",
+                "summary": "消息机器人覆盖缺口",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "用户ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "所属公司ID，不传查询全部",
+                        "name": "owner_id",
+                        "in": "query"
                     },
                     {
-                        "description": "消息机器人状态",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "is_message_bot": {
-                                    "type": "integer"
-                                }
-                            }
-                        }
+                        "type": "integer",
+                        "description": "每个群的加群建议数量，默认3，最大10",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "更新成功",
+                        "description": "查询成功",
                         "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.BotCoverageResponse"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -1027,9 +1107,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/qrcode": {
-            "post": {
-                "description": "生成微信登录二维码",
+        "/groups/changes": {
+            "get": {
+                "description": "查询时间段内机器人新进的群（按群记录创建时间）和退出的群（按退群记录）",
                 "consumes": [
                     "application/json"
                 ],
@@ -1037,32 +1117,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "groups"
                 ],
-                "summary": "获取登录二维码",
+                "summary": "查询群组变化",
                 "parameters": [
                     {
-                        "description": "请求参数",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "robot_id": {
-                                    "type": "integer"
-                                },
-                                "token": {
-                                    "type": "string"
-                                }
-                            }
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "获取成功",
-                        "schema": {
+                        "type": "string",
+                        "description": "机器人微信ID，不传查询全部",
+                        "name": "wx_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID，不传查询全部",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "start_time",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "end_time",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
                             "allOf": [
                                 {
                                     "$ref": "#/definitions/main.APIResponse"
@@ -1071,7 +1160,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/main.QRCodeResponse"
+                                            "$ref": "#/definitions/main.GroupChangesResponse"
                                         }
                                     }
                                 }
@@ -1084,8 +1173,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
-                    "404": {
-                        "description": "机器人不存在",
+                    "403": {
+                        "description": "无权访问其他公司的数据",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
@@ -1099,9 +1188,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/robot/{robotId}": {
+        "/groups/export": {
             "get": {
-                "description": "获取指定机器人的所有用户登录信息",
+                "description": "按账号或所属公司过滤，流式导出群组为CSV文件，每个账号所在的群一行，包含账号、群ID、群名、成员数、群主和创建时间",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "导出群组",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "微信ID，只导出该账号所在的群",
+                        "name": "wx_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID，只导出该公司账号所在的群",
+                        "name": "owner_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV文件",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/search": {
+            "get": {
+                "description": "根据群名称进行模糊搜索",
                 "consumes": [
                     "application/json"
                 ],
@@ -1109,21 +1238,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "groups"
                 ],
-                "summary": "获取机器人用户列表",
+                "summary": "搜索群组",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "机器人ID",
-                        "name": "robotId",
-                        "in": "path",
+                        "description": "群名称",
+                        "name": "groupNickName",
+                        "in": "query",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "查询成功",
+                        "description": "搜索成功",
                         "schema": {
                             "allOf": [
                                 {
@@ -1135,7 +1264,7 @@ const docTemplate = `{
                                         "data": {
                                             "type": "array",
                                             "items": {
-                                                "$ref": "#/definitions/main.WxUserLogin"
+                                                "$ref": "#/definitions/main.WxGroup"
                                             }
                                         }
                                     }
@@ -1158,9 +1287,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/save": {
-            "post": {
-                "description": "保存用户登录信息到数据库",
+        "/groups/user/{wxId}": {
+            "get": {
+                "description": "获取指定微信用户的所有群组信息",
                 "consumes": [
                     "application/json"
                 ],
@@ -1168,23 +1297,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "groups"
                 ],
-                "summary": "保存用户数据",
+                "summary": "获取用户群组列表",
                 "parameters": [
                     {
-                        "description": "用户数据",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.SaveUserRequest"
-                        }
+                        "type": "string",
+                        "description": "微信ID",
+                        "name": "wxId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "保存成功",
+                        "description": "查询成功",
                         "schema": {
                             "allOf": [
                                 {
@@ -1194,7 +1321,10 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/main.WxUserLogin"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.WxGroup"
+                                            }
                                         }
                                     }
                                 }
@@ -1207,8 +1337,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
-                    "404": {
-                        "description": "机器人不存在",
+                    "403": {
+                        "description": "无权访问其他公司的数据",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
@@ -1222,9 +1352,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/status/{robotId}/{token}": {
+        "/groups/{groupId}/bots": {
             "get": {
-                "description": "检查用户扫码登录状态",
+                "description": "返回该群内所有状态正常、已设为消息机器人且无风控的用户及其机器人信息，即发送策略的候选范围",
                 "consumes": [
                     "application/json"
                 ],
@@ -1232,28 +1362,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "groups"
                 ],
-                "summary": "检查登录状态",
+                "summary": "查询群候选消息机器人",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "机器人ID",
-                        "name": "robotId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "授权token",
-                        "name": "token",
+                        "description": "群组ID",
+                        "name": "groupId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "检查成功",
+                        "description": "查询成功",
                         "schema": {
                             "allOf": [
                                 {
@@ -1263,7 +1386,10 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/main.LoginStatusResponse"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.GroupBotCandidate"
+                                            }
                                         }
                                     }
                                 }
@@ -1276,8 +1402,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIResponse"
                         }
                     },
-                    "404": {
-                        "description": "机器人不存在",
+                    "403": {
+                        "description": "无权访问其他公司的数据",
                         "schema": {
                             "$ref": "#/definitions/main.APIResponse"
                         }
@@ -1291,9 +1417,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{id}": {
-            "delete": {
-                "description": "删除用户及其关联的群组数据",
+        "/message-bots": {
+            "get": {
+                "description": "列出所有状态正常、已设为消息机器人且无风控的用户及其覆盖群数，支持按所属公司过滤",
                 "consumes": [
                     "application/json"
                 ],
@@ -1301,23 +1427,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "messages"
                 ],
-                "summary": "删除用户",
+                "summary": "查询可用消息机器人",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "用户ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "所属公司ID，不传查询全部",
+                        "name": "owner_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "删除成功",
+                        "description": "查询成功",
                         "schema": {
-                            "$ref": "#/definitions/main.APIResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.AvailableMessageBotsResponse"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -1334,86 +1471,5571 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "main.APIResponse": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "integer"
-                },
-                "data": {},
-                "message": {
-                    "type": "string"
+        },
+        "/messages/broadcast": {
+            "post": {
+                "description": "向多个群组群发文本和/或图片，立即返回task_id，后台逐个群发送，可通过任务ID查询进度；重复、不存在或没有可用消息机器人的群在发送前剔除，原因见skipped；\n传 sample_rate 或 sample_count 时为灰度发送，只随机抽取部分有效群发送，未抽中的群见remaining",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "提交异步群发任务",
+                "parameters": [
+                    {
+                        "description": "群发参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.BroadcastRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "提交成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.BroadcastTaskProgress"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误或没有可发送的有效群",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.BroadcastSkippedGroup"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "内容包含禁止发送的链接",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
                 }
             }
         },
-        "main.BillInfoResponse": {
-            "type": "object",
-            "properties": {
-                "amount": {
-                    "type": "string"
-                },
-                "create_time": {
-                    "type": "string"
-                },
-                "dollar": {
-                    "type": "string"
-                },
-                "group_id": {
-                    "type": "string"
-                },
-                "group_name": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "msg_time": {
-                    "type": "integer"
-                },
-                "operator": {
-                    "type": "string"
-                },
+        "/messages/broadcast/{taskId}": {
+            "get": {
+                "description": "查询异步群发任务的进度（已发/总数/成功/失败）和每个群的发送结果",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "查询群发任务进度",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务ID",
+                        "name": "taskId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.BroadcastTaskProgress"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/callback": {
+            "post": {
+                "description": "接收底层推送的群消息，保存到群消息表，并按规则自动识别账单入库",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "接收群消息回调",
+                "parameters": [
+                    {
+                        "description": "群消息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.MessageCallbackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "处理成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.WxGroupMessage"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/broadcast": {
+            "post": {
+                "description": "向多个群组发送同一文本和/或图片，每个群按策略选择消息机器人，全部发送完成后返回每个群的结果；部分群失败时仍返回200，失败原因见results；\n重复、不存在、在黑名单中或没有可用消息机器人的群不发送，作为失败结果返回；单次最多50个群，更多群请使用异步群发任务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "同步群发到多个群",
+                "parameters": [
+                    {
+                        "description": "群发参数，text_content与image_content至少传一个",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.GroupBroadcastRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "发送完成，各群结果见results",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.GroupBroadcastResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "内容包含禁止发送的链接",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/search": {
+            "get": {
+                "description": "按消息内容关键词分页搜索入库的群消息，支持按群、所属公司和消息时间范围过滤，按消息时间倒序",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "搜索群消息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息内容关键词",
+                        "name": "keyword",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "群组ID",
+                        "name": "group_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "消息时间开始，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "start_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "消息时间结束，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "end_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码，默认1",
+                        "name": "page_no",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页数量，默认10，最大100",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.GroupMessageSearchResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/send-image": {
+            "post": {
+                "description": "向指定群组发送图片消息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "发送图片消息",
+                "parameters": [
+                    {
+                        "description": "图片消息参数，thumb_content可选缩略图，auto_thumb为true时未传缩略图自动从原图生成；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "auto_thumb": {
+                                    "type": "boolean"
+                                },
+                                "callback_url": {
+                                    "type": "string"
+                                },
+                                "confirm_large_group": {
+                                    "type": "boolean"
+                                },
+                                "image_content": {
+                                    "type": "string"
+                                },
+                                "priority": {
+                                    "type": "string"
+                                },
+                                "thumb_content": {
+                                    "type": "string"
+                                },
+                                "to_user_name": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "发送成功，sender为实际发送的机器人与账号",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendImageMessageResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "目标群没有本公司的账号或在黑名单中",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "未找到消息机器人",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "发送失败，data中error_type为失败分类，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendErrorInfo"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/send-link": {
+            "post": {
+                "description": "向指定群组发送文章/链接卡片消息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "发送链接卡片消息",
+                "parameters": [
+                    {
+                        "description": "卡片消息参数，title、url必填；description、thumb_url可选；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "callback_url": {
+                                    "type": "string"
+                                },
+                                "confirm_large_group": {
+                                    "type": "boolean"
+                                },
+                                "description": {
+                                    "type": "string"
+                                },
+                                "priority": {
+                                    "type": "string"
+                                },
+                                "thumb_url": {
+                                    "type": "string"
+                                },
+                                "title": {
+                                    "type": "string"
+                                },
+                                "to_user_name": {
+                                    "type": "string"
+                                },
+                                "url": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "发送成功，sender为实际发送的机器人与账号",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendAppMsgMessageResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "目标群没有本公司的账号、在黑名单中或链接禁止发送",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "未找到消息机器人",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "发送失败，data中error_type为失败分类，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendErrorInfo"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/send-text": {
+            "post": {
+                "description": "向指定群组发送文本消息，超长文本按配置自动分段顺序发送，响应Segments中返回每段的结果",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "发送文本消息",
+                "parameters": [
+                    {
+                        "description": "文本消息参数，at_wx_id_list可选，需要@的成员wxid，notify@all表示@所有人（需发送账号为群主或管理员，发送前会校验，无权限时降级为普通消息并发出 at_all_downgraded 事件通知）：列表原样交给平台触发@提醒，不会改写文本，需要显示的@昵称请自行写在text_content中；未传时按text_content中的@昵称自动匹配群成员；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "at_wx_id_list": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
+                                },
+                                "callback_url": {
+                                    "type": "string"
+                                },
+                                "confirm_large_group": {
+                                    "type": "boolean"
+                                },
+                                "priority": {
+                                    "type": "string"
+                                },
+                                "text_content": {
+                                    "type": "string"
+                                },
+                                "to_user_name": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "发送成功，sender为实际发送的机器人与账号",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendTextMessageResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "目标群没有本公司的账号、在黑名单中或内容包含禁止发送的链接",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "未找到消息机器人",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "去重窗口内已向该群发送过相同内容",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "发送失败，data中error_type为失败分类：network_error/risk_control/target_not_found/content_rejected/unknown，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendErrorInfo"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/send-text-image": {
+            "post": {
+                "description": "向指定群组同时发送文本和图片消息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "发送文本和图片消息",
+                "parameters": [
+                    {
+                        "description": "混合消息参数，callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "callback_url": {
+                                    "type": "string"
+                                },
+                                "confirm_large_group": {
+                                    "type": "boolean"
+                                },
+                                "image_content": {
+                                    "type": "string"
+                                },
+                                "priority": {
+                                    "type": "string"
+                                },
+                                "text_content": {
+                                    "type": "string"
+                                },
+                                "to_user_name": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "发送成功",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "目标群没有本公司的账号、在黑名单中或内容包含禁止发送的链接",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "未找到消息机器人",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "去重窗口内已向该群发送过相同内容",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/send-text-multi": {
+            "post": {
+                "description": "向多个群组发送同一条文本消息，同一消息机器人负责的群合并为一次请求，返回每个群的发送结果",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "批量发送文本消息",
+                "parameters": [
+                    {
+                        "description": "批量文本消息参数，priority可选 high/normal，默认normal",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SendTextMultiRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "发送完成，各群结果见data，失败的群ErrorType为失败分类",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.SendTextResult"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "内容包含禁止发送的链接",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/send-video": {
+            "post": {
+                "description": "向指定群组发送视频消息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "发送视频消息",
+                "parameters": [
+                    {
+                        "description": "视频消息参数，video_content为base64视频；thumb_content可选封面缩略图base64；play_length为视频时长(秒)；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "callback_url": {
+                                    "type": "string"
+                                },
+                                "confirm_large_group": {
+                                    "type": "boolean"
+                                },
+                                "play_length": {
+                                    "type": "integer"
+                                },
+                                "priority": {
+                                    "type": "string"
+                                },
+                                "thumb_content": {
+                                    "type": "string"
+                                },
+                                "to_user_name": {
+                                    "type": "string"
+                                },
+                                "video_content": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "发送成功，sender为实际发送的机器人与账号",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendVideoMessageResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误或缩略图不是有效的base64",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "目标群没有本公司的账号或在黑名单中",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "未找到消息机器人",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "发送失败，data中error_type为失败分类，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendErrorInfo"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/send-voice": {
+            "post": {
+                "description": "向指定群组发送语音消息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "发送语音消息",
+                "parameters": [
+                    {
+                        "description": "语音消息参数，voice_content为base64语音；voice_format可选 silk/amr，默认silk；voice_duration为语音时长(秒)，1-60；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "callback_url": {
+                                    "type": "string"
+                                },
+                                "confirm_large_group": {
+                                    "type": "boolean"
+                                },
+                                "priority": {
+                                    "type": "string"
+                                },
+                                "to_user_name": {
+                                    "type": "string"
+                                },
+                                "voice_content": {
+                                    "type": "string"
+                                },
+                                "voice_duration": {
+                                    "type": "integer"
+                                },
+                                "voice_format": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "发送成功，sender为实际发送的机器人与账号",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendVoiceMessageResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "目标群没有本公司的账号或在黑名单中",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "未找到消息机器人",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "发送失败，data中error_type为失败分类，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendErrorInfo"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/group/set-strategy": {
+            "post": {
+                "description": "设置系统的消息发送策略（随机或轮询）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "设置消息发送策略",
+                "parameters": [
+                    {
+                        "description": "策略参数 (random/round_robin)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "strategy": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "设置成功",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "租户API Key不能修改全局发送策略",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/trace/{traceId}": {
+            "get": {
+                "description": "查询同一发送批次（如一次群发，trace_id见群发任务进度）中所有消息的发送结果，发送记录持久化保存，不受群发任务内存过期影响",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "按追踪ID查询发送结果",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "发送批次追踪ID",
+                        "name": "traceId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SendTraceResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "没有该批次的发送记录",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/": {
+            "get": {
+                "description": "获取机器人配置及其关联的用户信息，支持按创建时间范围过滤和按id/创建时间/更新时间排序",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "获取机器人列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "创建时间开始，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "create_time_start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "创建时间结束，格式：yyyy-mm-dd hh:mi:ss",
+                        "name": "create_time_end",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "create_time",
+                            "update_time"
+                        ],
+                        "type": "string",
+                        "description": "排序字段，默认id",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "排序方向，默认asc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID，不传查询全部",
+                        "name": "owner_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.WxRobotConfig"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建新的微信机器人配置",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "创建机器人配置",
+                "parameters": [
+                    {
+                        "description": "机器人配置信息",
+                        "name": "robot",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateRobotRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "创建成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.WxRobotConfig"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/batch-status": {
+            "post": {
+                "description": "按所属公司或机器人ID列表批量启用/禁用机器人（如客户欠费时全部停用），禁用后其账号不再作为消息机器人发送；owner_id与ids同时提供时取交集，返回状态实际变化的数量，变化的机器人记录到配置变更历史；需在请求头携带 X-Admin-Token 或本公司的 X-API-Key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "批量启用/禁用机器人",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "管理员令牌，与X-API-Key二选一",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "租户API Key，只能操作本公司机器人",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "请求参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.BatchRobotStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "操作成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.BatchRobotStatusResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "鉴权失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/health": {
+            "get": {
+                "description": "并发检查多个机器人的健康状态，返回每个机器人的状态和响应时间；不传ids时检查全部机器人，带API Key时只检查本公司的机器人",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "批量检查机器人健康状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "机器人ID列表，逗号分隔",
+                        "name": "ids",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "检查完成",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.RobotHealthSummary"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/idle": {
+            "get": {
+                "description": "列出没有在线账号且超过指定天数无活动（配置修改、账号状态变化、账号发送消息）的机器人，按最近活动时间升序；需在请求头携带 X-Admin-Token 或本公司的 X-API-Key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "查询闲置机器人",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "管理员令牌，与X-API-Key二选一",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "租户API Key，只能查询本公司机器人",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID，不传查询全部",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "超过多少天无活动视为闲置，默认30",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.IdleRobot"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "鉴权失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/idle/cleanup": {
+            "post": {
+                "description": "批量禁用或删除闲置机器人，删除时同时删除其下账号、账号状态日志及授权码记录，禁用和删除均记录到配置变更历史；confirm为false时只返回将被清理的机器人，确认后传confirm=true执行；执行时重新判断是否闲置，ids中已恢复使用的机器人不会被清理；需在请求头携带 X-Admin-Token 或本公司的 X-API-Key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "批量禁用/删除闲置机器人",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "管理员令牌，与X-API-Key二选一",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "租户API Key，只能操作本公司机器人",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "请求参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CleanupIdleRobotsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "操作成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.CleanupIdleRobotsResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "鉴权失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/{id}": {
+            "get": {
+                "description": "根据ID获取机器人详细信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "获取单个机器人信息",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "机器人ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.WxRobotConfig"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新机器人配置信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "修改机器人配置",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "机器人ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "机器人配置信息",
+                        "name": "robot",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateRobotRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "修改成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.WxRobotConfig"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "只更新请求中传入的字段，未传的字段保持不变；传空字符串可清空描述等可选字段",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "部分更新机器人配置",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "机器人ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "需要更新的字段",
+                        "name": "robot",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.PatchRobotRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "修改成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.WxRobotConfig"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/{id}/auth-keys": {
+            "get": {
+                "description": "查询机器人通过授权接口生成的全部授权码及分配状态，有账号使用该授权码登录即为已分配，未分配的码可复用；需在请求头携带 X-Admin-Token 或本公司的 X-API-Key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "查询机器人授权码",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "管理员令牌，与X-API-Key二选一",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "租户API Key，只能查看本公司机器人",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "机器人ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "assigned",
+                            "unassigned"
+                        ],
+                        "type": "string",
+                        "description": "分配状态，不传查询全部",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.AuthKeyListResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "鉴权失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/{id}/changes": {
+            "get": {
+                "description": "查询通过修改、部分更新和轮换管理密钥接口产生的字段级变更记录，按时间倒序；管理密钥只记录变更事实，新旧值不返回明文",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "获取机器人配置变更历史",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "机器人ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.WxRobotConfigChange"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/{id}/health": {
+            "get": {
+                "description": "通过HTTP GET请求机器人地址拼接健康检查路径（health_path，默认 /），返回200视为健康",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "检查机器人健康状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "机器人ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "机器人健康",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object",
+                                            "properties": {
+                                                "address": {
+                                                    "type": "string"
+                                                },
+                                                "response_time": {
+                                                    "type": "string"
+                                                },
+                                                "status": {
+                                                    "type": "string"
+                                                }
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "机器人不健康",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/{id}/rotate-admin-key": {
+            "post": {
+                "description": "新管理密钥需先在底层服务配置好；先用新密钥调用底层接口验证可用，验证通过后才更新数据库，验证失败时保留原密钥。需在请求头 X-Admin-Token 中携带管理员令牌",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "轮换机器人管理密钥",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "管理员令牌",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "机器人ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "新管理密钥",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RotateAdminKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "轮换成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.RotateAdminKeyResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误或新密钥验证失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "鉴权失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/robots/{id}/summary": {
+            "get": {
+                "description": "汇总机器人下挂载的用户数、各状态（正常/风控/需重登）分布、初始化情况及是否有可用的消息机器人",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "robots"
+                ],
+                "summary": "获取机器人概览",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "机器人ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.RobotSummaryResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/authorize": {
+            "post": {
+                "description": "为指定机器人生成授权token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取授权信息",
+                "parameters": [
+                    {
+                        "description": "请求参数，count默认1最大20，days默认365最大365",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.AuthorizeUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object",
+                                            "properties": {
+                                                "robot_id": {
+                                                    "type": "integer"
+                                                },
+                                                "token": {
+                                                    "type": "string"
+                                                },
+                                                "tokens": {
+                                                    "type": "array",
+                                                    "items": {
+                                                        "type": "string"
+                                                    }
+                                                }
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/by-token": {
+            "get": {
+                "description": "根据授权token查询所属用户及机器人，需在请求头 X-Admin-Token 中携带管理员令牌；GET通过查询参数token传入，POST通过body传入（推荐，避免token出现在访问日志中）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "按token反查用户",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "管理员令牌",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "授权token（GET）",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "description": "授权token（POST）",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.UserByTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.UserByTokenResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "鉴权失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "根据授权token查询所属用户及机器人，需在请求头 X-Admin-Token 中携带管理员令牌；GET通过查询参数token传入，POST通过body传入（推荐，避免token出现在访问日志中）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "按token反查用户",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "管理员令牌",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "授权token（GET）",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "description": "授权token（POST）",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.UserByTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.UserByTokenResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "鉴权失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/login-status/{id}": {
+            "get": {
+                "description": "获取用户当前的在线状态",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取用户在线状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/message-bot-status/{id}": {
+            "post": {
+                "description": "设置用户是否为消息机器人",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "更新消息机器人状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "消息机器人状态",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "is_message_bot": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/need-relogin": {
+            "get": {
+                "description": "跨机器人分页查询状态为需要重新登录(status=3)的用户，按状态更新时间倒序；token 已脱敏，登录二维码以 user_id 调用 qrcode_url 批量获取",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "查询需重新登录的用户",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "机器人ID",
+                        "name": "robot_id",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码，默认1",
+                        "name": "page_no",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "每页大小，默认10",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.NeedReloginPaginatedResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/need-relogin/qrcodes": {
+            "post": {
+                "description": "为多个用户批量生成登录二维码，check=true 时先尝试二次登录（无需扫码）；单个用户失败不影响其他用户，受获取二维码频率限制",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "批量获取重登二维码",
+                "parameters": [
+                    {
+                        "description": "用户ID列表，最多20个",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ReloginQRCodeBatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "处理完成，各用户结果见data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.ReloginQRCodeResult"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/qrcode": {
+            "post": {
+                "description": "生成微信登录二维码；check=true 时先尝试已登录设备的二次登录（无需扫码），失败回退为扫码登录",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取登录二维码",
+                "parameters": [
+                    {
+                        "description": "请求参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "check": {
+                                    "type": "boolean"
+                                },
+                                "robot_id": {
+                                    "type": "integer"
+                                },
+                                "token": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.QRCodeResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "获取过于频繁",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/qrcode/image": {
+            "get": {
+                "description": "生成微信登录二维码并直接返回图片二进制，便于\u003cimg src\u003e直接引用",
+                "produces": [
+                    "image/png"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取登录二维码图片",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "授权token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "机器人ID",
+                        "name": "robot_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "二维码图片",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "获取过于频繁",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/robot/{robotId}": {
+            "get": {
+                "description": "获取指定机器人的所有用户登录信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取机器人用户列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "机器人ID",
+                        "name": "robotId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.WxUserLogin"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/save": {
+            "post": {
+                "description": "保存用户登录信息到数据库",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "保存用户数据",
+                "parameters": [
+                    {
+                        "description": "用户数据",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SaveUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "保存成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.WxUserLogin"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/search": {
+            "get": {
+                "description": "按 wx_id 或昵称模糊搜索用户，支持所属公司、状态过滤和分页",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "搜索用户",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "关键字（wx_id 或昵称片段）",
+                        "name": "keyword",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "所属公司ID",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "状态 1正常 2风控 3需要重新登录",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码，默认1",
+                        "name": "page_no",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "每页大小，默认10",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.UserSearchPaginatedResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/status/{robotId}/{token}": {
+            "get": {
+                "description": "检查用户扫码登录状态，status：0未扫码 1已扫码待确认 2登录成功 3登录失败",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "检查登录状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "机器人ID",
+                        "name": "robotId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "授权token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "检查成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.LoginStatusResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/status/{robotId}/{token}/stream": {
+            "get": {
+                "description": "服务端轮询扫码登录状态，状态变化时以SSE事件 status 推送 LoginStatusResponse；登录成功或失败后关闭连接，超时推送 timeout 事件后关闭",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "实时推送登录状态(SSE)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "机器人ID",
+                        "name": "robotId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "授权token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "status事件的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.LoginStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "机器人不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "delete": {
+                "description": "删除用户（不删除关联的群组数据）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "删除用户",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/auth-info": {
+            "get": {
+                "description": "聚合用户的授权token（脱敏）、生成时间、延期与过期时间、历次延期记录以及实时登录状态（token过期时同样标记用户需要重新登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取用户授权概览",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.UserAuthInfoResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/auto-renew": {
+            "put": {
+                "description": "开启后授权到期检查任务会在到期前按配置自动延期，未开启的用户只发送到期预警",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "设置用户到期自动续期",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "是否自动续期",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateUserAutoRenewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/remark": {
+            "put": {
+                "description": "设置运营自定义的用户备注/别名，用于区分多个账号",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "更新用户备注",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "备注内容",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateUserRemarkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/signature": {
+            "put": {
+                "description": "设置账号发送文本时自动拼接的签名（如\"【客服】\"），可选拼接在前缀或后缀，群发同样生效",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "更新用户签名",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "签名内容",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateUserSignatureRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/status-history": {
+            "get": {
+                "description": "查询用户状态（1正常 2风控 3需要重新登录）的变更记录，按时间倒序",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取用户状态变更历史",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/main.WxUserStatusLog"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "无权访问其他公司的数据",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/sync-groups": {
+            "post": {
+                "description": "立即从外部接口拉取用户的群列表并同步到本地，返回本次新增、删除和改名的群",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "手动同步用户群组",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "同步成功",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.GroupSyncSummary"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "用户状态不可同步",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "内部服务器错误",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "获取群列表失败",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "main.APIResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "data": {},
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.AddGroupBlacklistRequest": {
+            "type": "object",
+            "required": [
+                "group_id"
+            ],
+            "properties": {
+                "group_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "加入黑名单原因",
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "main.AuthKeyInfo": {
+            "type": "object",
+            "properties": {
+                "auth_key": {
+                    "type": "string"
+                },
+                "create_time": {
+                    "type": "string"
+                },
+                "days": {
+                    "type": "integer"
+                },
+                "expire_time": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "nick_name": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "生成来源 manual/rotate_probe",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "使用该授权码的账号",
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.AuthKeyListResponse": {
+            "type": "object",
+            "properties": {
+                "assigned": {
+                    "type": "integer"
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.AuthKeyInfo"
+                    }
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "unassigned": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.AuthorizeUserRequest": {
+            "type": "object",
+            "required": [
+                "robot_id"
+            ],
+            "properties": {
+                "count": {
+                    "description": "生成授权码数量，默认1，最大20",
+                    "type": "integer",
+                    "maximum": 20,
+                    "minimum": 1
+                },
+                "days": {
+                    "description": "授权有效天数，默认365，最大365",
+                    "type": "integer",
+                    "maximum": 365,
+                    "minimum": 1
+                },
+                "robot_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.AvailableMessageBot": {
+            "type": "object",
+            "properties": {
+                "group_count": {
+                    "description": "覆盖群数",
+                    "type": "integer"
+                },
+                "nick_name": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "robot_address": {
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.AvailableMessageBotsResponse": {
+            "type": "object",
+            "properties": {
+                "bots": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.AvailableMessageBot"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.BatchExtendAuthRequest": {
+            "type": "object",
+            "required": [
+                "days"
+            ],
+            "properties": {
+                "days": {
+                    "description": "延期天数",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "owner_id": {
+                    "description": "按所属公司筛选",
+                    "type": "integer"
+                },
+                "robot_id": {
+                    "description": "按机器人筛选",
+                    "type": "integer"
+                },
+                "within_days": {
+                    "description": "只处理N天内到期（含已过期）的用户，0表示全部",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "main.BatchExtendAuthResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BatchExtendAuthResult"
+                    }
+                },
+                "success": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.BatchExtendAuthResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "expiry_date": {
+                    "description": "延期后的到期日期",
+                    "type": "string"
+                },
+                "nick_name": {
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BatchRobotStatusRequest": {
+            "type": "object",
+            "required": [
+                "enabled"
+            ],
+            "properties": {
+                "enabled": {
+                    "description": "0禁用 1启用",
+                    "type": "integer",
+                    "enum": [
+                        0,
+                        1
+                    ]
+                },
+                "ids": {
+                    "description": "按机器人ID列表操作",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "owner_id": {
+                    "description": "按所属公司操作",
+                    "type": "integer"
+                }
+            }
+        },
+        "main.BatchRobotStatusResponse": {
+            "type": "object",
+            "properties": {
+                "affected": {
+                    "description": "状态实际发生变化的机器人数",
+                    "type": "integer"
+                },
+                "enabled": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.BillDetailFilter": {
+            "type": "object",
+            "properties": {
+                "create_time_end": {
+                    "type": "string"
+                },
+                "create_time_start": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "group_name": {
+                    "type": "string"
+                },
+                "operator": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.BillInfoResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "create_time": {
+                    "type": "string"
+                },
+                "dollar": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "group_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "msg_time": {
+                    "type": "integer"
+                },
+                "msg_time_text": {
+                    "description": "账单时间，按配置时区格式化",
+                    "type": "string"
+                },
+                "operator": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "rate": {
+                    "type": "string"
+                },
+                "remark": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "update_time": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BillQueryPaginatedResponse": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BillInfoResponse"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationInfo"
+                }
+            }
+        },
+        "main.BillStatsComparison": {
+            "type": "object",
+            "properties": {
+                "amount_change_rate": {
+                    "description": "环比增减百分比，保留两位小数，如12.5表示增长12.5%；上期为0时为null",
+                    "type": "number"
+                },
+                "count_change_rate": {
+                    "type": "number"
+                },
+                "current_amount": {
+                    "type": "string"
+                },
+                "current_count": {
+                    "type": "integer"
+                },
+                "current_end": {
+                    "type": "string"
+                },
+                "current_start": {
+                    "type": "string"
+                },
+                "previous_amount": {
+                    "type": "string"
+                },
+                "previous_count": {
+                    "type": "integer"
+                },
+                "previous_end": {
+                    "type": "string"
+                },
+                "previous_start": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BillStatsPaginatedResponse": {
+            "type": "object",
+            "properties": {
+                "comparison": {
+                    "description": "compare=true时返回",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.BillStatsComparison"
+                        }
+                    ]
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BillStatsResponse"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationInfo"
+                }
+            }
+        },
+        "main.BillStatsResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "detail_filter": {
+                    "description": "下钻查询明细的过滤条件",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.BillDetailFilter"
+                        }
+                    ]
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "group_nick": {
+                    "type": "string"
+                },
+                "operator": {
+                    "description": "按操作人分组时返回",
+                    "type": "string"
+                },
+                "total_amount": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BotCoverageCandidate": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "enable_message_bot / join_group",
+                    "type": "string"
+                },
+                "group_count": {
+                    "description": "消息机器人当前覆盖群数，加群建议按此从少到多排序",
+                    "type": "integer"
+                },
+                "nick_name": {
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BotCoverageGap": {
+            "type": "object",
+            "properties": {
+                "group_id": {
+                    "type": "string"
+                },
+                "group_nick_name": {
+                    "type": "string"
+                },
+                "suggestions": {
+                    "description": "为空表示同公司没有可用账号",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BotCoverageCandidate"
+                    }
+                }
+            }
+        },
+        "main.BotCoverageResponse": {
+            "type": "object",
+            "properties": {
+                "gaps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BotCoverageGap"
+                    }
+                },
+                "uncovered_groups": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.BroadcastGroupResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "to_user_name": {
+                    "type": "string"
+                },
+                "wx_id": {
+                    "description": "实际发送的消息机器人",
+                    "type": "string"
+                }
+            }
+        },
+        "main.BroadcastRequest": {
+            "type": "object",
+            "required": [
+                "to_user_names"
+            ],
+            "properties": {
+                "callback_url": {
+                    "description": "可选，全部发送完成后回调汇总结果",
+                    "type": "string"
+                },
+                "confirm_large_group": {
+                    "description": "是否确认向超大群发送，未确认时成员数超过阈值的群会发送失败",
+                    "type": "boolean"
+                },
+                "image_content": {
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "发送优先级，默认normal",
+                    "type": "string",
+                    "enum": [
+                        "high",
+                        "normal"
+                    ]
+                },
+                "sample_count": {
+                    "description": "抽样数量，超过有效群数时全部发送",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "sample_rate": {
+                    "description": "灰度发送：按比例或数量从有效群中随机抽取一部分先发，二者只能传一个；未抽中的群在任务的remaining中返回",
+                    "type": "number",
+                    "maximum": 1
+                },
+                "text_content": {
+                    "type": "string"
+                },
+                "to_user_names": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.BroadcastSkippedGroup": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                },
+                "to_user_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BroadcastTaskProgress": {
+            "type": "object",
+            "properties": {
+                "create_time": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "finish_time": {
+                    "type": "string"
+                },
+                "remaining": {
+                    "description": "灰度发送时未抽中的群，确认无误后可用其提交全量",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BroadcastGroupResult"
+                    }
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "description": "发送前剔除的重复/无效群",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BroadcastSkippedGroup"
+                    }
+                },
+                "status": {
+                    "description": "pending running completed",
+                    "type": "string"
+                },
+                "success": {
+                    "type": "integer"
+                },
+                "task_id": {
+                    "type": "string"
+                },
+                "total": {
+                    "description": "实际发送的有效群数量",
+                    "type": "integer"
+                },
+                "trace_id": {
+                    "description": "发送批次追踪ID，可查询发送记录",
+                    "type": "string"
+                }
+            }
+        },
+        "main.CleanupIdleRobotsRequest": {
+            "type": "object",
+            "required": [
+                "action",
+                "days"
+            ],
+            "properties": {
+                "action": {
+                    "description": "disable 禁用，delete 删除机器人及其账号",
+                    "type": "string",
+                    "enum": [
+                        "disable",
+                        "delete"
+                    ]
+                },
+                "confirm": {
+                    "description": "确认执行，为false时只返回将被清理的机器人",
+                    "type": "boolean"
+                },
+                "days": {
+                    "description": "超过多少天无活动视为闲置",
+                    "type": "integer",
+                    "maximum": 3650,
+                    "minimum": 1
+                },
+                "ids": {
+                    "description": "只清理其中仍闲置的机器人，不传时清理全部闲置机器人",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "owner_id": {
+                    "description": "所属公司ID，不传清理全部",
+                    "type": "integer"
+                }
+            }
+        },
+        "main.CleanupIdleRobotsResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "affected": {
+                    "description": "实际禁用或删除的机器人数",
+                    "type": "integer"
+                },
+                "confirmed": {
+                    "description": "是否已执行清理",
+                    "type": "boolean"
+                },
+                "robots": {
+                    "description": "将被（或已被）清理的机器人",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.IdleRobot"
+                    }
+                }
+            }
+        },
+        "main.CreateRobotRequest": {
+            "type": "object",
+            "required": [
+                "address",
+                "admin_key",
+                "owner_id"
+            ],
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "admin_key": {
+                    "type": "string"
+                },
+                "admin_users": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "health_path": {
+                    "description": "健康检查路径，默认 /",
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "tags": {
+                    "description": "标签，用于分组统计",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timeout_seconds": {
+                    "description": "外部接口调用超时(秒)，0表示使用全局默认，最大300",
+                    "type": "integer",
+                    "maximum": 300,
+                    "minimum": 0
+                }
+            }
+        },
+        "main.GroupBotCandidate": {
+            "type": "object",
+            "properties": {
+                "nick_name": {
+                    "type": "string"
+                },
+                "robot_address": {
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.GroupBroadcastRequest": {
+            "type": "object",
+            "required": [
+                "to_user_names"
+            ],
+            "properties": {
+                "confirm_large_group": {
+                    "description": "是否确认向超大群发送，未确认时成员数超过阈值的群会发送失败",
+                    "type": "boolean"
+                },
+                "image_content": {
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "发送优先级，默认normal",
+                    "type": "string",
+                    "enum": [
+                        "high",
+                        "normal"
+                    ]
+                },
+                "text_content": {
+                    "type": "string"
+                },
+                "to_user_names": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.GroupBroadcastResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "description": "按请求顺序返回，发送前被剔除的群也在其中",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BroadcastGroupResult"
+                    }
+                },
+                "success": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "trace_id": {
+                    "description": "发送批次追踪ID，可查询发送记录",
+                    "type": "string"
+                }
+            }
+        },
+        "main.GroupChangesResponse": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "description": "时间段内新增的群",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.WxGroup"
+                    }
+                },
+                "lost": {
+                    "description": "时间段内流失的群",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.WxGroupLeaveLog"
+                    }
+                }
+            }
+        },
+        "main.GroupListPaginatedResponse": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.GroupOverview"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationInfo"
+                }
+            }
+        },
+        "main.GroupMessageSearchResponse": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.WxGroupMessage"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationInfo"
+                }
+            }
+        },
+        "main.GroupOverview": {
+            "type": "object",
+            "properties": {
+                "create_time": {
+                    "description": "最早入群时间",
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "group_nick_name": {
+                    "type": "string"
+                },
+                "wx_ids": {
+                    "description": "覆盖该群的账号",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.GroupRenameItem": {
+            "type": "object",
+            "properties": {
+                "group_id": {
+                    "type": "string"
+                },
+                "new_nick_name": {
+                    "type": "string"
+                },
+                "old_nick_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.GroupSyncItem": {
+            "type": "object",
+            "properties": {
+                "group_id": {
+                    "type": "string"
+                },
+                "group_nick_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.GroupSyncSummary": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "description": "新增的群",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.GroupSyncItem"
+                    }
+                },
+                "removed": {
+                    "description": "已退出或解散而删除的群",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.GroupSyncItem"
+                    }
+                },
+                "renamed": {
+                    "description": "改名的群",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.GroupRenameItem"
+                    }
+                },
+                "total": {
+                    "description": "同步后的群数量",
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.IdleRobot": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "idle_days": {
+                    "description": "已闲置天数",
+                    "type": "integer"
+                },
+                "last_active_time": {
+                    "description": "最近活动时间：配置修改、账号状态变化或账号发送消息",
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "user_count": {
+                    "description": "账号数（均不在线）",
+                    "type": "integer"
+                }
+            }
+        },
+        "main.LoginStatusResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "nick_name": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "0未扫码 1已扫码待确认 2登录成功 3登录失败",
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.MessageCallbackRequest": {
+            "type": "object",
+            "required": [
+                "group_id",
+                "wx_id"
+            ],
+            "properties": {
+                "content": {
+                    "description": "消息内容",
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "群组ID",
+                    "type": "string"
+                },
+                "msg_time": {
+                    "description": "消息时间戳",
+                    "type": "integer"
+                },
+                "msg_type": {
+                    "description": "消息类型 1文本 3图片 10000系统消息 10002撤回通知",
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "description": "接收消息的微信账号",
+                    "type": "string"
+                },
+                "wx_nick_name": {
+                    "description": "发送者昵称",
+                    "type": "string"
+                }
+            }
+        },
+        "main.MigrationInfo": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.MigrationStatus": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.SchemaMigration"
+                    }
+                },
+                "current_version": {
+                    "description": "已执行的最大版本，0表示未执行过迁移",
+                    "type": "integer"
+                },
+                "latest_version": {
+                    "description": "当前程序登记的最新版本",
+                    "type": "integer"
+                },
+                "pending": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.MigrationInfo"
+                    }
+                }
+            }
+        },
+        "main.NeedReloginPaginatedResponse": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.NeedReloginUser"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationInfo"
+                }
+            }
+        },
+        "main.NeedReloginUser": {
+            "type": "object",
+            "properties": {
+                "nick_name": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "qrcode_url": {
+                    "description": "批量获取重登二维码接口",
+                    "type": "string"
+                },
+                "remark": {
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "脱敏后的token",
+                    "type": "string"
+                },
+                "update_time": {
+                    "description": "最近一次状态更新时间",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PaginationInfo": {
+            "type": "object",
+            "properties": {
+                "has_next": {
+                    "type": "boolean"
+                },
+                "has_prev": {
+                    "type": "boolean"
+                },
+                "page_no": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "total_count": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PatchRobotRequest": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "admin_key": {
+                    "type": "string"
+                },
+                "admin_users": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "description": "是否启用 0禁用 1启用",
+                    "type": "integer",
+                    "enum": [
+                        0,
+                        1
+                    ]
+                },
+                "health_path": {
+                    "description": "健康检查路径，传空字符串恢复默认 /",
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "tags": {
+                    "description": "标签，传空数组清空",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timeout_seconds": {
+                    "description": "外部接口调用超时(秒)，0表示使用全局默认，最大300",
+                    "type": "integer",
+                    "maximum": 300,
+                    "minimum": 0
+                }
+            }
+        },
+        "main.QRCodeResponse": {
+            "type": "object",
+            "properties": {
+                "expire_time": {
+                    "type": "integer"
+                },
+                "login_mode": {
+                    "description": "qrcode 扫码登录，relogin 二次登录（无需扫码）",
+                    "type": "string"
+                },
+                "qrCodeBase64": {
+                    "type": "string"
+                },
+                "qr_code": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ReloginQRCodeBatchRequest": {
+            "type": "object",
+            "required": [
+                "user_ids"
+            ],
+            "properties": {
+                "check": {
+                    "description": "是否先尝试二次登录（无需扫码）",
+                    "type": "boolean"
+                },
+                "user_ids": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "main.ReloginQRCodeResult": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "nick_name": {
+                    "type": "string"
+                },
+                "qr_code": {
+                    "$ref": "#/definitions/main.QRCodeResponse"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.RobotHealthResult": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "response_time": {
+                    "type": "string"
+                },
+                "response_time_ms": {
+                    "type": "integer"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "healthy / unhealthy",
+                    "type": "string"
+                }
+            }
+        },
+        "main.RobotHealthSummary": {
+            "type": "object",
+            "properties": {
+                "healthy": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RobotHealthResult"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "unhealthy": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.RobotSummaryResponse": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "has_message_bot": {
+                    "description": "是否有正常状态的消息机器人",
+                    "type": "boolean"
+                },
+                "initialized": {
+                    "description": "已完成初始化",
+                    "type": "integer"
+                },
+                "message_bots": {
+                    "description": "消息机器人数量",
+                    "type": "integer"
+                },
+                "normal_users": {
+                    "description": "状态正常",
+                    "type": "integer"
+                },
+                "relogin_users": {
+                    "description": "需要重新登录",
+                    "type": "integer"
+                },
+                "risk_users": {
+                    "description": "风控",
+                    "type": "integer"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "security_risk": {
+                    "description": "有安全风险标记",
+                    "type": "integer"
+                },
+                "total_users": {
+                    "description": "挂载的用户总数",
+                    "type": "integer"
+                }
+            }
+        },
+        "main.RobotTagSendStats": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "description": "发送失败数",
+                    "type": "integer"
+                },
+                "robot_count": {
+                    "description": "该标签下的机器人数",
+                    "type": "integer"
+                },
+                "success": {
+                    "description": "发送成功数",
+                    "type": "integer"
+                },
+                "success_rate": {
+                    "description": "成功率(%)，保留两位小数",
+                    "type": "number"
+                },
+                "tag": {
+                    "description": "标签，空表示未打标签的机器人",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "发送总数",
+                    "type": "integer"
+                }
+            }
+        },
+        "main.RotateAdminKeyRequest": {
+            "type": "object",
+            "required": [
+                "new_admin_key"
+            ],
+            "properties": {
+                "new_admin_key": {
+                    "description": "已在底层服务配置好的新管理密钥",
+                    "type": "string"
+                }
+            }
+        },
+        "main.RotateAdminKeyResponse": {
+            "type": "object",
+            "properties": {
+                "admin_key": {
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "rotated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.RuntimeStatsInfo": {
+            "type": "object",
+            "properties": {
+                "start_time": {
+                    "type": "string"
+                },
+                "total_errors": {
+                    "type": "integer"
+                },
+                "total_requests": {
+                    "type": "integer"
+                },
+                "total_sent": {
+                    "type": "integer"
+                },
+                "uptime": {
+                    "type": "string"
+                },
+                "uptime_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.SaveUserRequest": {
+            "type": "object",
+            "required": [
+                "robot_id",
+                "token",
+                "wx_id"
+            ],
+            "properties": {
+                "has_security_risk": {
+                    "type": "integer"
+                },
+                "is_message_bot": {
+                    "type": "integer"
+                },
+                "nick_name": {
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.SchemaMigration": {
+            "type": "object",
+            "properties": {
+                "applied_time": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.SendAppMsgMessageResponse": {
+            "type": "object",
+            "properties": {
+                "ClientMsgId": {
+                    "type": "string"
+                },
+                "CreateTime": {
+                    "type": "integer"
+                },
+                "MsgId": {
+                    "type": "integer"
+                },
+                "NewMsgId": {
+                    "type": "integer"
+                },
+                "ToUserName": {
+                    "type": "string"
+                },
+                "sender": {
+                    "$ref": "#/definitions/main.SenderInfo"
+                }
+            }
+        },
+        "main.SendAuditPaginatedResponse": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.WxSendAudit"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationInfo"
+                }
+            }
+        },
+        "main.SendErrorInfo": {
+            "type": "object",
+            "properties": {
+                "error_type": {
+                    "$ref": "#/definitions/main.SendErrorType"
+                },
+                "retry_id": {
+                    "description": "已加入后台重试队列时的重试记录ID",
+                    "type": "integer"
+                },
+                "retryable": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.SendErrorType": {
+            "type": "string",
+            "enum": [
+                "network_error",
+                "risk_control",
+                "target_not_found",
+                "content_rejected",
+                "unknown"
+            ],
+            "x-enum-comments": {
+                "SendErrorContentRejected": "内容违规被拒绝，修改内容前不应重试",
+                "SendErrorNetwork": "网络错误或超时，可重试",
+                "SendErrorRiskControl": "账号被风控或发送频率受限，应暂停发送",
+                "SendErrorTargetNotFound": "目标群不存在或已不在群内，不应重试",
+                "SendErrorUnknown": "无法识别的失败"
+            },
+            "x-enum-descriptions": [
+                "网络错误或超时，可重试",
+                "账号被风控或发送频率受限，应暂停发送",
+                "目标群不存在或已不在群内，不应重试",
+                "内容违规被拒绝，修改内容前不应重试",
+                "无法识别的失败"
+            ],
+            "x-enum-varnames": [
+                "SendErrorNetwork",
+                "SendErrorRiskControl",
+                "SendErrorTargetNotFound",
+                "SendErrorContentRejected",
+                "SendErrorUnknown"
+            ]
+        },
+        "main.SendImageMessageResponse": {
+            "type": "object",
+            "properties": {
+                "CreateTime": {
+                    "type": "integer"
+                },
+                "FromUserName": {
+                    "type": "string"
+                },
+                "MsgId": {
+                    "type": "integer"
+                },
+                "NewMsgId": {
+                    "type": "integer"
+                },
+                "ToUserName": {
+                    "type": "string"
+                },
+                "sender": {
+                    "$ref": "#/definitions/main.SenderInfo"
+                }
+            }
+        },
+        "main.SendTextMessageResponse": {
+            "type": "object",
+            "properties": {
+                "ClientMsgId": {
+                    "type": "integer"
+                },
+                "CreateTime": {
+                    "type": "integer"
+                },
+                "NewMsgId": {
+                    "type": "integer"
+                },
+                "Segments": {
+                    "description": "长文本分段发送时每一段的结果，未分段时为空",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.SendTextResponse"
+                    }
+                },
+                "ToUserName": {
+                    "type": "string"
+                },
+                "sender": {
+                    "$ref": "#/definitions/main.SenderInfo"
+                }
+            }
+        },
+        "main.SendTextMultiRequest": {
+            "type": "object",
+            "required": [
+                "text_content",
+                "to_user_names"
+            ],
+            "properties": {
+                "confirm_large_group": {
+                    "description": "是否确认向超大群发送，未确认时成员数超过阈值的群会发送失败",
+                    "type": "boolean"
+                },
+                "priority": {
+                    "description": "发送优先级，默认normal",
+                    "type": "string",
+                    "enum": [
+                        "high",
+                        "normal"
+                    ]
+                },
+                "text_content": {
+                    "type": "string"
+                },
+                "to_user_names": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.SendTextResponse": {
+            "type": "object",
+            "properties": {
+                "ClientMsgId": {
+                    "type": "integer"
+                },
+                "CreateTime": {
+                    "type": "integer"
+                },
+                "NewMsgId": {
+                    "type": "integer"
+                },
+                "Segments": {
+                    "description": "长文本分段发送时每一段的结果，未分段时为空",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.SendTextResponse"
+                    }
+                },
+                "ToUserName": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.SendTextResult": {
+            "type": "object",
+            "properties": {
+                "ClientMsgId": {
+                    "type": "integer"
+                },
+                "CreateTime": {
+                    "type": "integer"
+                },
+                "Error": {
+                    "type": "string"
+                },
+                "ErrorType": {
+                    "description": "失败分类",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.SendErrorType"
+                        }
+                    ]
+                },
+                "NewMsgId": {
+                    "type": "integer"
+                },
+                "Success": {
+                    "type": "boolean"
+                },
+                "ToUserName": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.SendTraceResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.WxSendRecord"
+                    }
+                },
+                "success": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "trace_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.SendVideoMessageResponse": {
+            "type": "object",
+            "properties": {
+                "ClientMsgId": {
+                    "type": "string"
+                },
+                "CreateTime": {
+                    "type": "integer"
+                },
+                "MsgId": {
+                    "type": "integer"
+                },
+                "NewMsgId": {
+                    "type": "integer"
+                },
+                "ToUserName": {
+                    "type": "string"
+                },
+                "sender": {
+                    "$ref": "#/definitions/main.SenderInfo"
+                }
+            }
+        },
+        "main.SendVoiceMessageResponse": {
+            "type": "object",
+            "properties": {
+                "ClientMsgId": {
+                    "type": "string"
+                },
+                "CreateTime": {
+                    "type": "integer"
+                },
+                "MsgId": {
+                    "type": "integer"
+                },
+                "NewMsgId": {
+                    "type": "integer"
+                },
+                "ToUserName": {
+                    "type": "string"
+                },
+                "sender": {
+                    "$ref": "#/definitions/main.SenderInfo"
+                }
+            }
+        },
+        "main.SenderInfo": {
+            "type": "object",
+            "properties": {
+                "nick_name": {
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.UpdateRobotRequest": {
+            "type": "object",
+            "required": [
+                "address",
+                "admin_key",
+                "owner_id"
+            ],
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "admin_key": {
+                    "type": "string"
+                },
+                "admin_users": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "health_path": {
+                    "description": "健康检查路径，默认 /",
+                    "type": "string"
+                },
                 "owner_id": {
                     "type": "integer"
                 },
-                "rate": {
+                "tags": {
+                    "description": "标签，用于分组统计",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timeout_seconds": {
+                    "description": "外部接口调用超时(秒)，0表示使用全局默认，最大300",
+                    "type": "integer",
+                    "maximum": 300,
+                    "minimum": 0
+                }
+            }
+        },
+        "main.UpdateUserAutoRenewRequest": {
+            "type": "object",
+            "required": [
+                "auto_renew"
+            ],
+            "properties": {
+                "auto_renew": {
+                    "description": "1到期前自动续期 0关闭",
+                    "type": "integer",
+                    "enum": [
+                        0,
+                        1
+                    ]
+                }
+            }
+        },
+        "main.UpdateUserRemarkRequest": {
+            "type": "object",
+            "properties": {
+                "remark": {
+                    "description": "运营备注，传空字符串清除备注",
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "main.UpdateUserSignatureRequest": {
+            "type": "object",
+            "properties": {
+                "position": {
+                    "description": "签名位置，默认suffix",
+                    "type": "string",
+                    "enum": [
+                        "prefix",
+                        "suffix"
+                    ]
+                },
+                "signature": {
+                    "description": "签名内容，传空字符串清除签名",
+                    "type": "string",
+                    "maxLength": 100
+                }
+            }
+        },
+        "main.UserAuthInfoResponse": {
+            "type": "object",
+            "properties": {
+                "auth_create_time": {
+                    "description": "授权生成（首次保存）时间",
+                    "type": "string"
+                },
+                "expiration_time": {
+                    "description": "过期时间",
+                    "type": "string"
+                },
+                "extension_time": {
+                    "description": "最近延期时间",
+                    "type": "string"
+                },
+                "extensions": {
+                    "description": "延期记录，按时间倒序",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.WxUserExtensionLog"
+                    }
+                },
+                "has_security_risk": {
+                    "description": "0否 1是",
+                    "type": "integer"
+                },
+                "is_initialized": {
+                    "description": "0未初始化 1初始化完成",
+                    "type": "integer"
+                },
+                "login_status": {
+                    "description": "实时登录状态，查询失败时为空",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.UserLoginStatusInfo"
+                        }
+                    ]
+                },
+                "login_status_error": {
+                    "description": "实时登录状态查询失败原因",
+                    "type": "string"
+                },
+                "nick_name": {
                     "type": "string"
                 },
+                "remaining_days": {
+                    "description": "剩余天数，已过期为负数",
+                    "type": "integer"
+                },
                 "remark": {
+                    "description": "运营备注",
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "1正常 2风控 3需要重新登录",
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "脱敏后的授权token",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wx_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.UserByTokenRequest": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.UserByTokenResponse": {
+            "type": "object",
+            "properties": {
+                "expiration_time": {
+                    "type": "string"
+                },
+                "is_message_bot": {
+                    "type": "integer"
+                },
+                "nick_name": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "robot_address": {
                     "type": "string"
                 },
-                "status": {
+                "robot_description": {
+                    "type": "string"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "token": {
                     "type": "string"
                 },
-                "update_time": {
+                "user_id": {
+                    "type": "integer"
+                },
+                "wx_id": {
                     "type": "string"
                 }
             }
         },
-        "main.BillQueryPaginatedResponse": {
+        "main.UserLoginStatusInfo": {
             "type": "object",
             "properties": {
-                "list": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.BillInfoResponse"
-                    }
+                "expiry_time": {
+                    "type": "string"
                 },
-                "pagination": {
-                    "$ref": "#/definitions/main.PaginationInfo"
+                "login_err_msg": {
+                    "type": "string"
+                },
+                "login_state": {
+                    "type": "integer"
+                },
+                "login_time": {
+                    "type": "string"
+                },
+                "online_days": {
+                    "type": "integer"
+                },
+                "online_time": {
+                    "type": "string"
                 }
             }
         },
-        "main.BillStatsPaginatedResponse": {
+        "main.UserSearchPaginatedResponse": {
             "type": "object",
             "properties": {
                 "list": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.BillStatsResponse"
+                        "$ref": "#/definitions/main.WxUserLogin"
                     }
                 },
                 "pagination": {
@@ -1421,181 +7043,243 @@ const docTemplate = `{
                 }
             }
         },
-        "main.BillStatsResponse": {
+        "main.WxGroup": {
             "type": "object",
             "properties": {
-                "count": {
-                    "type": "integer"
+                "chat_room_owner": {
+                    "type": "string"
+                },
+                "create_time": {
+                    "type": "string"
                 },
                 "group_id": {
                     "type": "string"
                 },
-                "group_nick": {
+                "group_nick_name": {
                     "type": "string"
                 },
-                "total_amount": {
+                "id": {
+                    "type": "integer"
+                },
+                "member_count": {
+                    "type": "integer"
+                },
+                "update_time": {
+                    "type": "string"
+                },
+                "wx_id": {
                     "type": "string"
                 }
             }
         },
-        "main.CreateRobotRequest": {
+        "main.WxGroupBlacklist": {
             "type": "object",
-            "required": [
-                "address",
-                "admin_key",
-                "owner_id"
-            ],
             "properties": {
-                "address": {
+                "create_time": {
                     "type": "string"
                 },
-                "admin_key": {
+                "group_id": {
                     "type": "string"
                 },
-                "admin_users": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "id": {
+                    "type": "integer"
                 },
-                "description": {
+                "reason": {
                     "type": "string"
-                },
-                "owner_id": {
-                    "type": "integer"
                 }
             }
         },
-        "main.LoginStatusResponse": {
+        "main.WxGroupLeaveLog": {
             "type": "object",
             "properties": {
-                "message": {
+                "group_id": {
                     "type": "string"
                 },
-                "nick_name": {
+                "group_nick_name": {
                     "type": "string"
                 },
-                "status": {
-                    "description": "0未扫码 1已扫码 2登录成功 3登录失败",
+                "id": {
                     "type": "integer"
                 },
+                "join_time": {
+                    "type": "string"
+                },
+                "leave_time": {
+                    "type": "string"
+                },
                 "wx_id": {
                     "type": "string"
                 }
             }
         },
-        "main.PaginationInfo": {
+        "main.WxGroupMessage": {
             "type": "object",
             "properties": {
-                "has_next": {
-                    "type": "boolean"
+                "content": {
+                    "type": "string"
                 },
-                "has_prev": {
-                    "type": "boolean"
+                "create_time": {
+                    "type": "string"
                 },
-                "page_no": {
+                "group_id": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "page_size": {
+                "msg_time": {
                     "type": "integer"
                 },
-                "total_count": {
+                "msg_type": {
                     "type": "integer"
                 },
-                "total_pages": {
+                "owner_id": {
                     "type": "integer"
+                },
+                "update_time": {
+                    "type": "string"
+                },
+                "wx_nick_name": {
+                    "type": "string"
                 }
             }
         },
-        "main.QRCodeResponse": {
+        "main.WxRobotConfig": {
             "type": "object",
             "properties": {
-                "expire_time": {
+                "address": {
+                    "type": "string"
+                },
+                "admin_key": {
+                    "type": "string"
+                },
+                "admin_users": {
+                    "type": "string"
+                },
+                "create_time": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
                     "type": "integer"
                 },
-                "qr_code": {
+                "health_path": {
                     "type": "string"
                 },
-                "token": {
+                "id": {
+                    "type": "integer"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "tags": {
                     "type": "string"
+                },
+                "timeout_seconds": {
+                    "type": "integer"
+                },
+                "update_time": {
+                    "type": "string"
+                },
+                "user_logins": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.WxUserLogin"
+                    }
                 }
             }
         },
-        "main.SaveUserRequest": {
+        "main.WxRobotConfigChange": {
             "type": "object",
-            "required": [
-                "robot_id",
-                "token",
-                "wx_id"
-            ],
             "properties": {
-                "has_security_risk": {
-                    "type": "integer"
+                "client_ip": {
+                    "type": "string"
                 },
-                "is_message_bot": {
-                    "type": "integer"
+                "create_time": {
+                    "type": "string"
                 },
-                "nick_name": {
+                "field": {
                     "type": "string"
                 },
-                "robot_id": {
+                "id": {
                     "type": "integer"
                 },
-                "token": {
+                "new_value": {
                     "type": "string"
                 },
-                "wx_id": {
+                "old_value": {
+                    "type": "string"
+                },
+                "operator_owner_id": {
+                    "type": "integer"
+                },
+                "robot_id": {
+                    "type": "integer"
+                },
+                "source": {
                     "type": "string"
                 }
             }
         },
-        "main.UpdateRobotRequest": {
+        "main.WxSendAudit": {
             "type": "object",
-            "required": [
-                "address",
-                "admin_key",
-                "owner_id"
-            ],
             "properties": {
-                "address": {
+                "content": {
                     "type": "string"
                 },
-                "admin_key": {
+                "content_hash": {
                     "type": "string"
                 },
-                "admin_users": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "create_time": {
+                    "type": "string"
                 },
-                "description": {
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "msg_type": {
                     "type": "string"
                 },
                 "owner_id": {
                     "type": "integer"
+                },
+                "sender_wx_id": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "to_user_name": {
+                    "type": "string"
                 }
             }
         },
-        "main.WxGroup": {
+        "main.WxSendRecord": {
             "type": "object",
             "properties": {
                 "create_time": {
                     "type": "string"
                 },
-                "group_id": {
-                    "type": "string"
-                },
-                "group_nick_name": {
+                "error": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "msg_time": {
+                "owner_id": {
                     "type": "integer"
                 },
-                "update_time": {
+                "success": {
+                    "type": "boolean"
+                },
+                "to_user_name": {
+                    "type": "string"
+                },
+                "trace_id": {
                     "type": "string"
                 },
                 "wx_id": {
@@ -1603,44 +7287,41 @@ const docTemplate = `{
                 }
             }
         },
-        "main.WxRobotConfig": {
+        "main.WxUserExtensionLog": {
             "type": "object",
             "properties": {
-                "address": {
+                "create_time": {
                     "type": "string"
                 },
-                "admin_key": {
-                    "type": "string"
+                "days": {
+                    "type": "integer"
                 },
-                "admin_users": {
-                    "type": "string"
+                "id": {
+                    "type": "integer"
                 },
-                "create_time": {
+                "new_expiration_time": {
                     "type": "string"
                 },
-                "description": {
+                "old_expiration_time": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "owner_id": {
+                "robot_id": {
                     "type": "integer"
                 },
-                "update_time": {
+                "source": {
                     "type": "string"
                 },
-                "user_logins": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.WxUserLogin"
-                    }
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
         "main.WxUserLogin": {
             "type": "object",
             "properties": {
+                "auto_renew": {
+                    "type": "integer"
+                },
                 "create_time": {
                     "type": "string"
                 },
@@ -1656,6 +7337,9 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "init_start_time": {
+                    "type": "string"
+                },
                 "is_initialized": {
                     "type": "integer"
                 },
@@ -1665,9 +7349,18 @@ const docTemplate = `{
                 "nick_name": {
                     "type": "string"
                 },
+                "remark": {
+                    "type": "string"
+                },
                 "robot_id": {
                     "type": "integer"
                 },
+                "signature": {
+                    "type": "string"
+                },
+                "signature_position": {
+                    "type": "string"
+                },
                 "status": {
                     "type": "integer"
                 },
@@ -1681,6 +7374,29 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "main.WxUserStatusLog": {
+            "type": "object",
+            "properties": {
+                "create_time": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "new_status": {
+                    "type": "integer"
+                },
+                "old_status": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // 注册gif解码
+	"image/jpeg"
+	_ "image/png" // 注册png解码
+	"strings"
+)
+
+// 自动生成缩略图的参数
+const (
+	thumbnailMaxSize = 240 // 缩略图最长边像素
+	thumbnailQuality = 80  // 缩略图JPEG质量
+)
+
+// normalizeBase64Image 规范化图片base64内容
+// 前端常传入 "data:image/png;base64,xxxx" 形式的data URI，或按76字符换行的base64，
+// 底层接口只接受纯base64，这里剥离data URI前缀并去掉所有空白字符
+func normalizeBase64Image(content string) string {
+	content = strings.TrimSpace(content)
+
+	if strings.HasPrefix(content, "data:") {
+		if idx := strings.Index(content, ","); idx >= 0 {
+			content = content[idx+1:]
+		}
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\r', '\n', '\t', ' ':
+			return -1
+		}
+		return r
+	}, content)
+}
+
+// decodeBase64Image 解码图片base64内容（兼容data URI前缀和换行）
+func decodeBase64Image(content string) ([]byte, error) {
+	normalized := normalizeBase64Image(content)
+	if normalized == "" {
+		return nil, fmt.Errorf("图片内容为空")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("图片base64解码失败: %w", err)
+	}
+	return data, nil
+}
+
+// generateThumbnail 根据原图base64生成JPEG缩略图base64，最长边缩放到maxSize，原图更小时不放大
+func generateThumbnail(content string, maxSize int) (string, error) {
+	data, err := decodeBase64Image(content)
+	if err != nil {
+		return "", err
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("图片解码失败: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return "", fmt.Errorf("图片尺寸无效")
+	}
+
+	dstWidth, dstHeight := width, height
+	if width > maxSize || height > maxSize {
+		if width >= height {
+			dstWidth = maxSize
+			dstHeight = max(1, height*maxSize/width)
+		} else {
+			dstHeight = maxSize
+			dstWidth = max(1, width*maxSize/height)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleImage(src, dstWidth, dstHeight), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return "", fmt.Errorf("缩略图编码失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// scaleImage 按区域平均缩放图片，缩小时比最近邻采样更平滑
+func scaleImage(src image.Image, dstWidth, dstHeight int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/dstHeight)
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/dstWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeBase64Image(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "plain base64", content: "iVBORw0KGgo=", want: "iVBORw0KGgo="},
+		{name: "png data uri", content: "data:image/png;base64,iVBORw0KGgo=", want: "iVBORw0KGgo="},
+		{name: "jpeg data uri", content: "data:image/jpeg;base64,/9j/4AAQ", want: "/9j/4AAQ"},
+		{name: "line breaks", content: "iVBORw0K\r\nGgo=\n", want: "iVBORw0KGgo="},
+		{name: "surrounding spaces", content: "  data:image/gif;base64, R0lG ODlh \t", want: "R0lGODlh"},
+		{name: "empty", content: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeBase64Image(tt.content); got != tt.want {
+				t.Fatalf("normalizeBase64Image(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBase64Image(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{name: "data uri", content: "data:text/plain;base64,aGVsbG8=", want: "hello"},
+		{name: "wrapped lines", content: "aGVs\nbG8=", want: "hello"},
+		{name: "empty", content: "data:image/png;base64,", wantErr: "图片内容为空"},
+		{name: "invalid", content: "not*base64", wantErr: "base64解码失败"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeBase64Image(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeBase64Image(%q) error = %v, want %q", tt.content, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Fatalf("decodeBase64Image(%q) = %q, %v, want %q", tt.content, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// testPNGBase64 生成指定尺寸的PNG图片base64
+func testPNGBase64(t *testing.T, width, height int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestGenerateThumbnail(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantWidth  int
+		wantHeight int
+		wantErr    bool
+	}{
+		{name: "landscape", content: testPNGBase64(t, 800, 400), wantWidth: 240, wantHeight: 120},
+		{name: "portrait", content: testPNGBase64(t, 100, 500), wantWidth: 48, wantHeight: 240},
+		{name: "small image not enlarged", content: testPNGBase64(t, 50, 30), wantWidth: 50, wantHeight: 30},
+		{name: "data uri", content: "data:image/png;base64," + testPNGBase64(t, 480, 480), wantWidth: 240, wantHeight: 240},
+		{name: "not an image", content: base64.StdEncoding.EncodeToString([]byte("hello")), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumb, err := generateThumbnail(tt.content, thumbnailMaxSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generateThumbnail error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			data, _ := base64.StdEncoding.DecodeString(thumb)
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil || format != "jpeg" {
+				t.Fatalf("thumbnail is not jpeg: format %q, err %v", format, err)
+			}
+			if cfg.Width != tt.wantWidth || cfg.Height != tt.wantHeight {
+				t.Fatalf("thumbnail size = %dx%d, want %dx%d", cfg.Width, cfg.Height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
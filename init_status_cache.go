@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// initStatusCache 未初始化结果的短时缓存（按token），避免定时任务反复调用GetInitStatus
+// 只缓存"未初始化完成"的结果，初始化完成的用户不会再被定时任务轮询
+type initStatusCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]initStatusCacheEntry
+}
+
+type initStatusCacheEntry struct {
+	resp     *GetInitStatusResponse
+	expireAt time.Time
+}
+
+// newInitStatusCache 创建缓存，ttl<=0 时不缓存
+func newInitStatusCache(ttl time.Duration) *initStatusCache {
+	return &initStatusCache{
+		ttl:     ttl,
+		entries: make(map[string]initStatusCacheEntry),
+	}
+}
+
+// Get 获取未过期的缓存结果
+func (c *initStatusCache) Get(token string) (*GetInitStatusResponse, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[token]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expireAt) {
+		delete(c.entries, token)
+		return nil, false
+	}
+	return entry.resp, true
+}
+
+// Set 缓存未初始化完成的结果，已完成的结果会清除缓存
+func (c *initStatusCache) Set(token string, resp *GetInitStatusResponse) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if resp.Code == 200 && resp.Data {
+		delete(c.entries, token)
+		return
+	}
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expireAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[token] = initStatusCacheEntry{resp: resp, expireAt: now.Add(c.ttl)}
+}
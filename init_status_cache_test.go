@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInitStatusCache(t *testing.T) {
+	notReady := &GetInitStatusResponse{Code: 200, Data: false}
+	ready := &GetInitStatusResponse{Code: 200, Data: true}
+	failed := &GetInitStatusResponse{Code: 500}
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		set     []*GetInitStatusResponse
+		wait    time.Duration
+		wantHit bool
+	}{
+		{name: "not initialized cached", ttl: time.Minute, set: []*GetInitStatusResponse{notReady}, wantHit: true},
+		{name: "error response cached", ttl: time.Minute, set: []*GetInitStatusResponse{failed}, wantHit: true},
+		{name: "initialized not cached", ttl: time.Minute, set: []*GetInitStatusResponse{ready}},
+		{name: "initialized clears cache", ttl: time.Minute, set: []*GetInitStatusResponse{notReady, ready}},
+		{name: "expired", ttl: 10 * time.Millisecond, set: []*GetInitStatusResponse{notReady}, wait: 20 * time.Millisecond},
+		{name: "disabled", ttl: 0, set: []*GetInitStatusResponse{notReady}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newInitStatusCache(tt.ttl)
+			for _, resp := range tt.set {
+				cache.Set("token", resp)
+			}
+			time.Sleep(tt.wait)
+
+			resp, ok := cache.Get("token")
+			if ok != tt.wantHit {
+				t.Fatalf("Get hit = %v, want %v", ok, tt.wantHit)
+			}
+			if ok && resp != tt.set[len(tt.set)-1] {
+				t.Fatalf("Get = %+v, want last cached response", resp)
+			}
+			if _, ok := cache.Get("other"); ok {
+				t.Fatal("cache hit for unrelated token")
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 外部日志收集器类型
+const (
+	LogSinkTypeHTTP = "http" // 逐条POST JSON日志
+	LogSinkTypeTCP  = "tcp"  // 按行写入JSON日志
+)
+
+// remoteLogSink 将日志异步发送到外部收集器（ELK/Loki等）
+// 写入只投递到缓冲队列，队列满或发送失败时丢弃，不阻塞主流程
+type remoteLogSink struct {
+	cfg        LogSinkConfig
+	queue      chan []byte
+	httpClient *http.Client
+	conn       net.Conn
+	dropped    int64
+}
+
+// newRemoteLogSink 创建外部日志sink并启动发送协程
+func newRemoteLogSink(cfg LogSinkConfig) (*remoteLogSink, error) {
+	if cfg.Type != LogSinkTypeHTTP && cfg.Type != LogSinkTypeTCP {
+		return nil, fmt.Errorf("不支持的日志sink类型: %s", cfg.Type)
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("日志sink地址不能为空")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 3 * time.Second
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+
+	s := &remoteLogSink{
+		cfg:        cfg,
+		queue:      make(chan []byte, cfg.BufferSize),
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write 实现zapcore.WriteSyncer，复制日志内容后投递到队列
+func (s *remoteLogSink) Write(p []byte) (int, error) {
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	select {
+	case s.queue <- entry:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+	return len(p), nil
+}
+
+// Sync 异步发送无需同步
+func (s *remoteLogSink) Sync() error {
+	return nil
+}
+
+// run 逐条发送队列中的日志
+func (s *remoteLogSink) run() {
+	for entry := range s.queue {
+		var err error
+		if s.cfg.Type == LogSinkTypeHTTP {
+			err = s.sendHTTP(entry)
+		} else {
+			err = s.sendTCP(entry)
+		}
+
+		// 日志发送失败不能再走zap，否则会递归写入sink
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "发送日志到外部收集器失败: %v (已丢弃 %d 条)\n", err, atomic.LoadInt64(&s.dropped))
+		}
+	}
+}
+
+// sendHTTP 以POST方式发送单条JSON日志
+func (s *remoteLogSink) sendHTTP(entry []byte) error {
+	resp, err := s.httpClient.Post(s.cfg.Address, "application/json; charset=utf-8", bytes.NewReader(entry))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("收集器返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendTCP 通过长连接写入单条日志，失败时断开并在下一条重连
+func (s *remoteLogSink) sendTCP(entry []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("tcp", s.cfg.Address, s.cfg.Timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout))
+	if _, err := s.conn.Write(entry); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// newRemoteLogCore 创建外部日志输出core，始终使用JSON编码
+func newRemoteLogCore(cfg LogSinkConfig, encoderConfig zapcore.EncoderConfig, level zapcore.Level) (zapcore.Core, error) {
+	sink, err := newRemoteLogSink(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), sink, level), nil
+}
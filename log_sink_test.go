@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewRemoteLogSinkValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LogSinkConfig
+		wantErr bool
+	}{
+		{name: "http", cfg: LogSinkConfig{Type: LogSinkTypeHTTP, Address: "http://collector.invalid"}},
+		{name: "tcp", cfg: LogSinkConfig{Type: LogSinkTypeTCP, Address: "127.0.0.1:1"}},
+		{name: "unsupported type", cfg: LogSinkConfig{Type: "udp", Address: "127.0.0.1:1"}, wantErr: true},
+		{name: "missing address", cfg: LogSinkConfig{Type: LogSinkTypeHTTP}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRemoteLogSink(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRemoteLogSink = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRemoteLogCore(t *testing.T) {
+	tests := []struct {
+		name string
+		sink func(t *testing.T, received chan<- []byte) LogSinkConfig
+	}{
+		{
+			name: "http",
+			sink: func(t *testing.T, received chan<- []byte) LogSinkConfig {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					body, _ := io.ReadAll(r.Body)
+					received <- body
+				}))
+				t.Cleanup(server.Close)
+				return LogSinkConfig{Type: LogSinkTypeHTTP, Address: server.URL}
+			},
+		},
+		{
+			name: "tcp",
+			sink: func(t *testing.T, received chan<- []byte) LogSinkConfig {
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatalf("listen: %v", err)
+				}
+				t.Cleanup(func() { ln.Close() })
+				go func() {
+					conn, err := ln.Accept()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+					line, _ := bufio.NewReader(conn).ReadBytes('\n')
+					received <- line
+				}()
+				return LogSinkConfig{Type: LogSinkTypeTCP, Address: ln.Addr().String()}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []byte, 1)
+			core, err := newRemoteLogCore(tt.sink(t, received), zap.NewProductionEncoderConfig(), zapcore.InfoLevel)
+			if err != nil {
+				t.Fatalf("newRemoteLogCore: %v", err)
+			}
+			logger := zap.New(core)
+			logger.Debug("低于级别不发送")
+			logger.Info("机器人上线", zap.String("wx_id", "wxid_a"))
+
+			select {
+			case body := <-received:
+				var entry map[string]interface{}
+				if err := json.Unmarshal(body, &entry); err != nil {
+					t.Fatalf("log entry is not JSON: %q", body)
+				}
+				if entry["msg"] != "机器人上线" || entry["wx_id"] != "wxid_a" {
+					t.Fatalf("entry = %v", entry)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("log not delivered to sink")
+			}
+		})
+	}
+}
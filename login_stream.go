@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// streamLoginStatus 实时推送登录状态
+// @Summary 实时推送登录状态(SSE)
+// @Description 服务端轮询扫码登录状态，状态变化时以SSE事件 status 推送 LoginStatusResponse；登录成功或失败后关闭连接，超时推送 timeout 事件后关闭
+// @Tags users
+// @Produce text/event-stream
+// @Param robotId path string true "机器人ID"
+// @Param token path string true "授权token"
+// @Success 200 {object} LoginStatusResponse "status事件的数据"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 404 {object} APIResponse "机器人不存在"
+// @Router /users/status/{robotId}/{token}/stream [get]
+func (rm *RouterManager) streamLoginStatus(c *gin.Context) {
+	robotIdStr := c.Param("robotId")
+	token := c.Param("token")
+
+	robotId, err := strconv.ParseUint(robotIdStr, 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "robotId参数错误")
+		return
+	}
+
+	robot, err := rm.service.GetRobotByID(uint(robotId))
+	if err != nil {
+		rm.notFoundResponse(c, "机器人不存在")
+		return
+	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
+
+	// 推送持续时间可能超过服务端写超时，取消该连接的写超时
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		rm.logger.Debug("取消SSE连接写超时失败", zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	rm.pushLoginStatus(c, robot, token, rm.cfg.LoginStream.Interval, rm.cfg.LoginStream.Timeout)
+}
+
+// pushLoginStatus 按间隔轮询登录状态，状态变化时推送，登录成功、失败、超时或客户端断开时返回
+func (rm *RouterManager) pushLoginStatus(c *gin.Context, robot *WxRobotConfig, token string, interval, timeout time.Duration) {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	var last *LoginStatusResponse
+	for {
+		loginResp, err := rm.service.CheckLoginStatus(withRobotTimeout(c.Request.Context(), robot), robot.Address, token)
+		if err != nil {
+			// 单次查询失败不中断推送，下次轮询重试
+			rm.logger.Warn("实时推送查询登录状态失败", zap.String("token", maskToken(token)), zap.Error(err))
+		} else {
+			status := loginStatusFromResponse(loginResp)
+			if last == nil || *last != status {
+				c.SSEvent("status", status)
+				c.Writer.Flush()
+				last = &status
+			}
+			if status.Status == LoginStatusConfirmed || status.Status == LoginStatusFailed {
+				return
+			}
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-deadline.C:
+			c.SSEvent("timeout", LoginStatusResponse{Status: LoginStatusWaitScan, Message: "等待登录超时"})
+			c.Writer.Flush()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStreamLoginStatus(t *testing.T) {
+	state := func(state int) map[string]interface{} {
+		return map[string]interface{}{"Code": 200, "Data": map[string]interface{}{"state": state, "wxid": "wxid_login", "nick_name": "小助手"}}
+	}
+
+	tests := []struct {
+		name      string
+		responses []map[string]interface{} // 依次返回，最后一个重复返回
+		timeout   time.Duration
+		want      []string // 事件名:状态
+	}{
+		{
+			name:      "pushes changes until confirmed",
+			responses: []map[string]interface{}{state(QrCodeStateWaitScan), state(QrCodeStateWaitScan), state(QrCodeStateScanned), state(QrCodeStateScanned), state(QrCodeStateConfirmed)},
+			timeout:   time.Second,
+			want:      []string{"status:0", "status:1", "status:2"},
+		},
+		{
+			name:      "cancelled on phone",
+			responses: []map[string]interface{}{state(QrCodeStateScanned), state(QrCodeStateCancelled)},
+			timeout:   time.Second,
+			want:      []string{"status:1", fmt.Sprintf("status:%d", LoginStatusFailed)},
+		},
+		{
+			name:      "timeout",
+			responses: []map[string]interface{}{state(QrCodeStateWaitScan)},
+			timeout:   50 * time.Millisecond,
+			want:      []string{"status:0", "timeout:0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.CheckLoginStatus: func(w http.ResponseWriter, r *http.Request) {
+					i := int(atomic.AddInt32(&calls, 1)) - 1
+					if i >= len(tt.responses) {
+						i = len(tt.responses) - 1
+					}
+					jsonHandler(tt.responses[i])(w, r)
+				},
+			})
+			cfg := &Config{}
+			cfg.LoginStream.Interval = 5 * time.Millisecond
+			cfg.LoginStream.Timeout = tt.timeout
+			router, _, db := newTestRouter(t, cfg)
+			robot := &WxRobotConfig{Address: server.URL}
+			createTestRobot(t, db, robot)
+
+			w := doRequest(router, http.MethodGet, fmt.Sprintf("/users/status/%d/token-login/stream", robot.ID), "")
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+				t.Fatalf("status = %d, content type = %q", w.Code, w.Header().Get("Content-Type"))
+			}
+
+			var got []string
+			for _, block := range strings.Split(strings.TrimSpace(w.Body.String()), "\n\n") {
+				var event, data string
+				for _, line := range strings.Split(block, "\n") {
+					if v, ok := strings.CutPrefix(line, "event:"); ok {
+						event = v
+					} else if v, ok := strings.CutPrefix(line, "data:"); ok {
+						data = v
+					}
+				}
+				var status LoginStatusResponse
+				if err := json.Unmarshal([]byte(data), &status); err != nil {
+					t.Fatalf("decode event %q: %v", block, err)
+				}
+				got = append(got, fmt.Sprintf("%s:%d", event, status.Status))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("events = %v, want %v\n%s", got, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -33,12 +34,28 @@ import (
 )
 
 func main() {
+	initConfig := flag.Bool("init-config", false, "生成当前环境（APP_ENV）的带注释样例配置文件后退出")
+	flag.Parse()
+
+	if *initConfig {
+		path, err := WriteSampleConfig()
+		if err != nil {
+			log.Fatalf("生成样例配置失败: %v", err)
+		}
+		fmt.Printf("已生成样例配置文件: %s，请按实际环境修改后启动服务\n", path)
+		return
+	}
+
 	// 初始化配置
 	cfg, err := InitConfig()
 	if err != nil {
 		log.Fatalf("初始化配置失败: %v", err)
 	}
 
+	if err := SetDisplayTimezone(cfg.App.Timezone); err != nil {
+		log.Fatalf("初始化时区失败: %v", err)
+	}
+
 	// 初始化日志
 	logger, err := InitLogger(cfg)
 	if err != nil {
@@ -46,7 +63,12 @@ func main() {
 	}
 	defer logger.Sync()
 
-	logger.Info("应用启动", zap.String("name", cfg.App.Name), zap.String("version", cfg.App.Version))
+	versionInfo := getVersionInfo(cfg.App)
+	logger.Info("应用启动",
+		zap.String("name", versionInfo.Name),
+		zap.String("version", versionInfo.Version),
+		zap.String("build_time", versionInfo.BuildTime),
+		zap.String("git_commit", versionInfo.GitCommit))
 
 	// 初始化数据库
 	dbManager, err := NewDatabaseManager(cfg, logger)
@@ -54,25 +76,47 @@ func main() {
 		logger.Fatal("初始化数据库失败", zap.Error(err))
 	}
 
+	// 执行数据库迁移
+	if cfg.Database.AutoMigrate {
+		if err := dbManager.Migrate(); err != nil {
+			logger.Fatal("数据库迁移失败", zap.Error(err))
+		}
+	}
+
 
 	// 初始化微信机器人服务
-	wxRobotSvc := NewWxRobotService(dbManager.GetDB(), logger)
+	wxRobotSvc, err := NewWxRobotService(cfg, dbManager.GetDB(), logger)
+	if err != nil {
+		logger.Fatal("初始化微信机器人服务失败", zap.Error(err))
+	}
+
+	// 初始化群组同步定时任务
+	groupSyncScheduler := NewGroupSyncScheduler(logger, wxRobotSvc, cfg.GroupSync)
 
 	// 初始化路由管理器
-	routerMgr := NewRouterManager(logger, wxRobotSvc)
+	routerMgr, err := NewRouterManager(cfg, logger, wxRobotSvc, groupSyncScheduler)
+	if err != nil {
+		logger.Fatal("初始化路由管理器失败", zap.Error(err))
+	}
 
 	// 初始化路由
 	router := routerMgr.InitRoutes(cfg)
 
 	// 初始化定时任务
-	scheduler := NewInitializationScheduler(logger, wxRobotSvc)
-
-	// 初始化群组同步定时任务
-	groupSyncScheduler := NewGroupSyncScheduler(logger, wxRobotSvc)
+	scheduler := NewInitializationScheduler(logger, wxRobotSvc, cfg.Init)
 
 	// 初始化登录状态检查定时任务
 	loginStatusScheduler := NewLoginStatusScheduler(logger, wxRobotSvc)
 
+	// 初始化机器人健康巡检定时任务
+	robotHealthScheduler := NewRobotHealthScheduler(logger, wxRobotSvc, cfg.RobotHealth)
+
+	// 初始化授权到期检查定时任务
+	authExpiryScheduler := NewAuthExpiryScheduler(logger, wxRobotSvc, cfg.AuthExpiry)
+
+	// 初始化失败消息重试定时任务
+	sendRetryScheduler := NewSendRetryScheduler(logger, wxRobotSvc, cfg.SendQueue.Retry)
+
 
 	// 创建HTTP服务器
 	server := &http.Server{
@@ -98,6 +142,21 @@ func main() {
 		logger.Error("启动登录状态检查定时任务失败", zap.Error(err))
 	}
 
+	// 启动机器人健康巡检定时任务
+	if err := robotHealthScheduler.Start(); err != nil {
+		logger.Error("启动机器人健康巡检定时任务失败", zap.Error(err))
+	}
+
+	// 启动授权到期检查定时任务
+	if err := authExpiryScheduler.Start(); err != nil {
+		logger.Error("启动授权到期检查定时任务失败", zap.Error(err))
+	}
+
+	// 启动失败消息重试定时任务
+	if err := sendRetryScheduler.Start(); err != nil {
+		logger.Error("启动失败消息重试定时任务失败", zap.Error(err))
+	}
+
 
 	// 启动服务器
 	go func() {
@@ -108,11 +167,11 @@ func main() {
 	}()
 
 	// 优雅关闭
-	gracefulShutdown(server, logger, scheduler, groupSyncScheduler, loginStatusScheduler, dbManager)
+	gracefulShutdown(server, logger, scheduler, groupSyncScheduler, loginStatusScheduler, robotHealthScheduler, authExpiryScheduler, sendRetryScheduler, dbManager)
 }
 
 // gracefulShutdown 优雅关闭
-func gracefulShutdown(server *http.Server, logger *zap.Logger, scheduler InitializationScheduler, groupSyncScheduler GroupSyncScheduler, loginStatusScheduler LoginStatusScheduler, dbManager *DatabaseManager) {
+func gracefulShutdown(server *http.Server, logger *zap.Logger, scheduler InitializationScheduler, groupSyncScheduler GroupSyncScheduler, loginStatusScheduler LoginStatusScheduler, robotHealthScheduler RobotHealthScheduler, authExpiryScheduler AuthExpiryScheduler, sendRetryScheduler SendRetryScheduler, dbManager *DatabaseManager) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -140,6 +199,27 @@ func gracefulShutdown(server *http.Server, logger *zap.Logger, scheduler Initial
 		}
 	}
 
+	// 停止机器人健康巡检定时任务
+	if robotHealthScheduler != nil {
+		if err := robotHealthScheduler.Stop(); err != nil {
+			logger.Error("停止机器人健康巡检定时任务失败", zap.Error(err))
+		}
+	}
+
+	// 停止授权到期检查定时任务
+	if authExpiryScheduler != nil {
+		if err := authExpiryScheduler.Stop(); err != nil {
+			logger.Error("停止授权到期检查定时任务失败", zap.Error(err))
+		}
+	}
+
+	// 停止失败消息重试定时任务
+	if sendRetryScheduler != nil {
+		if err := sendRetryScheduler.Stop(); err != nil {
+			logger.Error("停止失败消息重试定时任务失败", zap.Error(err))
+		}
+	}
+
 
 	ctx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// mentionSeparator 微信@昵称后使用的分隔符（U+2005）
+const mentionSeparator = '\u2005'
+
+// AtAllWxID @所有人时AtWxIDList中使用的特殊值，需发送账号为群主或群管理员
+const AtAllWxID = "notify@all"
+
+// chatroomMemberFlagAdmin 群成员信息中chatroom_member_flag表示群管理员的标志位
+const chatroomMemberFlagAdmin = 2048
+
+// containsAtAll 判断@列表中是否包含@所有人
+func containsAtAll(wxIDs []string) bool {
+	for _, wxID := range wxIDs {
+		if wxID == AtAllWxID {
+			return true
+		}
+	}
+	return false
+}
+
+// removeAtAll 去掉@列表中的@所有人，降级为普通消息
+func removeAtAll(wxIDs []string) []string {
+	result := make([]string, 0, len(wxIDs))
+	for _, wxID := range wxIDs {
+		if wxID != AtAllWxID {
+			result = append(result, wxID)
+		}
+	}
+	return result
+}
+
+// chatRoomAtAllAllowed 根据群详情判断账号能否@所有人：群主或群管理员可以；found表示群详情中是否有该群
+func chatRoomAtAllAllowed(info *GetChatRoomInfoResponse, groupID, wxID string) (allowed, found bool) {
+	for _, contact := range info.Data.ContactList {
+		if contact.UserName.Str != groupID {
+			continue
+		}
+		if contact.ChatRoomOwner == wxID {
+			return true, true
+		}
+		for _, member := range contact.NewChatroomData.ChatroomMemberList {
+			if member.UserName == wxID {
+				return member.ChatroomMemberFlag&chatroomMemberFlagAdmin != 0, true
+			}
+		}
+		return false, true
+	}
+	return false, false
+}
+
+// normalizeAtWxIDList 去掉空白与重复的wxid；包含 notify@all 时只保留它，已覆盖全部成员
+func normalizeAtWxIDList(wxIDs []string) []string {
+	seen := make(map[string]bool, len(wxIDs))
+	result := make([]string, 0, len(wxIDs))
+	for _, wxID := range wxIDs {
+		wxID = strings.TrimSpace(wxID)
+		if wxID == "" || seen[wxID] {
+			continue
+		}
+		if wxID == AtAllWxID {
+			return []string{AtAllWxID}
+		}
+		seen[wxID] = true
+		result = append(result, wxID)
+	}
+	return result
+}
+
+// resolveMentions 从文本中解析 @昵称，按群成员昵称匹配出wxid
+// 昵称可能包含空格，因此每个@之后优先匹配最长的成员昵称；匹配不到的@原样保留并返回给调用方告警
+func resolveMentions(content string, members []WxGroupMember) (wxIDs []string, unresolved []string) {
+	if !strings.Contains(content, "@") {
+		return nil, nil
+	}
+
+	// 按昵称长度倒序，保证最长匹配优先
+	candidates := make([]WxGroupMember, 0, len(members))
+	for _, m := range members {
+		if m.NickName != "" && m.MemberWxID != "" {
+			candidates = append(candidates, m)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].NickName) > len(candidates[j].NickName)
+	})
+
+	seen := make(map[string]bool)
+	for rest := content; ; {
+		idx := strings.Index(rest, "@")
+		if idx < 0 {
+			break
+		}
+		rest = rest[idx+1:]
+
+		matched := false
+		for _, m := range candidates {
+			if !strings.HasPrefix(rest, m.NickName) || !isMentionBoundary(rest[len(m.NickName):]) {
+				continue
+			}
+			if !seen[m.MemberWxID] {
+				seen[m.MemberWxID] = true
+				wxIDs = append(wxIDs, m.MemberWxID)
+			}
+			rest = rest[len(m.NickName):]
+			matched = true
+			break
+		}
+
+		if !matched {
+			if name := mentionToken(rest); name != "" {
+				unresolved = append(unresolved, name)
+			}
+		}
+	}
+
+	return wxIDs, unresolved
+}
+
+// isMentionBoundary 判断昵称之后是否为结束位置（文本结尾、空白或@）
+func isMentionBoundary(s string) bool {
+	if s == "" {
+		return true
+	}
+	r := []rune(s)[0]
+	return r == mentionSeparator || r == '@' || unicode.IsSpace(r)
+}
+
+// mentionToken 提取@之后到空白或下一个@之前的内容，用于告警
+func mentionToken(s string) string {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return r == mentionSeparator || r == '@' || unicode.IsSpace(r)
+	})
+	if end < 0 {
+		return s
+	}
+	return s[:end]
+}
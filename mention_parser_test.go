@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveMentions(t *testing.T) {
+	members := []WxGroupMember{
+		{MemberWxID: "wxid_zhang", NickName: "张三"},
+		{MemberWxID: "wxid_zhangsan2", NickName: "张三 丰"},
+		{MemberWxID: "wxid_li", NickName: "李四"},
+		{MemberWxID: "wxid_empty", NickName: ""},
+	}
+
+	tests := []struct {
+		name           string
+		content        string
+		wantWxIDs      []string
+		wantUnresolved []string
+	}{
+		{name: "no mention", content: "大家好"},
+		{name: "single mention", content: "@张三 请查收", wantWxIDs: []string{"wxid_zhang"}},
+		{name: "mention at end", content: "请查收 @李四", wantWxIDs: []string{"wxid_li"}},
+		{name: "wechat separator", content: "@李四\u2005收到请回复", wantWxIDs: []string{"wxid_li"}},
+		{name: "longest nickname wins", content: "@张三 丰 你好", wantWxIDs: []string{"wxid_zhangsan2"}},
+		{name: "multiple and duplicate", content: "@张三 @李四 @张三", wantWxIDs: []string{"wxid_zhang", "wxid_li"}},
+		{name: "adjacent mentions", content: "@张三@李四", wantWxIDs: []string{"wxid_zhang", "wxid_li"}},
+		{name: "unknown nickname", content: "@王五 你好 @李四", wantWxIDs: []string{"wxid_li"}, wantUnresolved: []string{"王五"}},
+		{name: "prefix is not a match", content: "@张三丰收", wantUnresolved: []string{"张三丰收"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wxIDs, unresolved := resolveMentions(tt.content, members)
+			if !reflect.DeepEqual(wxIDs, tt.wantWxIDs) {
+				t.Errorf("wxIDs = %v, want %v", wxIDs, tt.wantWxIDs)
+			}
+			if !reflect.DeepEqual(unresolved, tt.wantUnresolved) {
+				t.Errorf("unresolved = %v, want %v", unresolved, tt.wantUnresolved)
+			}
+		})
+	}
+}
+
+func TestNormalizeAtWxIDList(t *testing.T) {
+	tests := []struct {
+		name  string
+		wxIDs []string
+		want  []string
+	}{
+		{name: "nil", want: []string{}},
+		{name: "trimmed and deduplicated", wxIDs: []string{" wxid_a", "wxid_b", "", "wxid_a "}, want: []string{"wxid_a", "wxid_b"}},
+		{name: "at all only", wxIDs: []string{AtAllWxID}, want: []string{AtAllWxID}},
+		{name: "at all covers members", wxIDs: []string{"wxid_a", " notify@all ", "wxid_b"}, want: []string{AtAllWxID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeAtWxIDList(tt.wxIDs); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("normalizeAtWxIDList(%v) = %v, want %v", tt.wxIDs, got, tt.want)
+			}
+		})
+	}
+}
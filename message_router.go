@@ -0,0 +1,73 @@
+package main
+
+import (
+	"go.uber.org/zap"
+)
+
+// 消息类型
+const (
+	MsgTypeText   = 1     // 文本消息
+	MsgTypeImage  = 3     // 图片消息
+	MsgTypeSystem = 10000 // 系统消息
+	MsgTypeRevoke = 10002 // 撤回通知
+)
+
+// MessageHandler 群消息处理器，消息入库后调用
+type MessageHandler func(msg *WxGroupMessage)
+
+// MessageRouter 按消息类型将回调消息路由到对应处理器
+type MessageRouter struct {
+	handlers    map[int][]MessageHandler
+	acceptTypes map[int]bool // 只处理这些类型，为空时处理全部
+	saveIgnored bool         // 不处理的类型是否仍然入库
+	logger      *zap.Logger
+}
+
+// NewMessageRouter 创建消息路由
+func NewMessageRouter(cfg MessageCallbackConfig, logger *zap.Logger) *MessageRouter {
+	acceptTypes := make(map[int]bool, len(cfg.AcceptTypes))
+	for _, msgType := range cfg.AcceptTypes {
+		acceptTypes[msgType] = true
+	}
+
+	return &MessageRouter{
+		handlers:    make(map[int][]MessageHandler),
+		acceptTypes: acceptTypes,
+		saveIgnored: cfg.SaveIgnored,
+		logger:      logger,
+	}
+}
+
+// Register 注册某类型消息的处理器，同一类型可注册多个，按注册顺序执行
+func (r *MessageRouter) Register(msgType int, handler MessageHandler) {
+	r.handlers[msgType] = append(r.handlers[msgType], handler)
+}
+
+// Accept 判断该类型消息是否需要处理
+func (r *MessageRouter) Accept(msgType int) bool {
+	return len(r.acceptTypes) == 0 || r.acceptTypes[msgType]
+}
+
+// ShouldSave 判断该类型消息是否入库：需要处理的都入库，不处理的按配置决定
+func (r *MessageRouter) ShouldSave(msgType int) bool {
+	return r.Accept(msgType) || r.saveIgnored
+}
+
+// Dispatch 将消息分发给对应类型的处理器，未注册处理器的类型只入库
+func (r *MessageRouter) Dispatch(msg *WxGroupMessage) {
+	if !r.Accept(msg.MsgType) {
+		return
+	}
+
+	handlers := r.handlers[msg.MsgType]
+	if len(handlers) == 0 {
+		r.logger.Debug("消息类型未注册处理器，仅入库",
+			zap.String("group_id", msg.GroupID),
+			zap.Int("msg_type", msg.MsgType))
+		return
+	}
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
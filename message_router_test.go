@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMessageRouter(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         MessageCallbackConfig
+		msgType     int
+		wantHandled []string
+		wantSave    bool
+	}{
+		{name: "text routed", msgType: MsgTypeText, wantHandled: []string{"text", "text-audit"}, wantSave: true},
+		{name: "revoke routed", msgType: MsgTypeRevoke, wantHandled: []string{"revoke"}, wantSave: true},
+		{name: "no handler only saved", msgType: MsgTypeImage, wantSave: true},
+		{name: "accepted type", cfg: MessageCallbackConfig{AcceptTypes: []int{MsgTypeText}}, msgType: MsgTypeText, wantHandled: []string{"text", "text-audit"}, wantSave: true},
+		{name: "filtered type dropped", cfg: MessageCallbackConfig{AcceptTypes: []int{MsgTypeText}}, msgType: MsgTypeSystem},
+		{name: "filtered type saved", cfg: MessageCallbackConfig{AcceptTypes: []int{MsgTypeText}, SaveIgnored: true}, msgType: MsgTypeRevoke, wantSave: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewMessageRouter(tt.cfg, zap.NewNop())
+			var handled []string
+			record := func(name string) MessageHandler {
+				return func(msg *WxGroupMessage) { handled = append(handled, name) }
+			}
+			router.Register(MsgTypeText, record("text"))
+			router.Register(MsgTypeText, record("text-audit"))
+			router.Register(MsgTypeRevoke, record("revoke"))
+			router.Register(MsgTypeSystem, record("system"))
+
+			router.Dispatch(&WxGroupMessage{GroupID: "g@chatroom", MsgType: tt.msgType})
+			if !reflect.DeepEqual(handled, tt.wantHandled) {
+				t.Fatalf("handled = %v, want %v", handled, tt.wantHandled)
+			}
+			if got := router.ShouldSave(tt.msgType); got != tt.wantSave {
+				t.Fatalf("ShouldSave = %v, want %v", got, tt.wantSave)
+			}
+		})
+	}
+}
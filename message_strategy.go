@@ -1,7 +1,7 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"math/rand"
 	"time"
 
@@ -24,8 +24,12 @@ type messageBotQueryResult struct {
 	RobotID       uint   `json:"robot_id"`
 	RobotAddress  string `json:"robot_address"`
 	RobotAdminKey string `json:"robot_admin_key"`
+	RobotTimeout  int    `json:"robot_timeout"`
 }
 
+// ErrNoMessageBot 群内没有可用的消息机器人
+var ErrNoMessageBot = errors.New("未找到可用的消息机器人")
+
 // MessageSendStrategy 消息发送策略接口
 type MessageSendStrategy interface {
 	GetMessageBot(db *gorm.DB, groupId string, logger *zap.Logger) (*MessageBotInfo, error)
@@ -55,16 +59,43 @@ func NewRandomMessageSendStrategy() MessageSendStrategy {
 	}
 }
 
+// ownerScopedStrategy 只在指定公司机器人下的消息机器人中按原策略选择
+type ownerScopedStrategy struct {
+	strategy MessageSendStrategy
+	ownerID  uint
+}
+
+// withOwnerScope 限定策略只选择指定公司的消息机器人，ownerID为0时不限定
+func withOwnerScope(strategy MessageSendStrategy, ownerID uint) MessageSendStrategy {
+	if ownerID == 0 {
+		return strategy
+	}
+	return &ownerScopedStrategy{strategy: strategy, ownerID: ownerID}
+}
+
+// GetMessageBot 按公司过滤候选后交给原策略选择
+func (s *ownerScopedStrategy) GetMessageBot(db *gorm.DB, groupId string, logger *zap.Logger) (*MessageBotInfo, error) {
+	return s.strategy.GetMessageBot(scopeBotOwner(db, s.ownerID), groupId, logger)
+}
+
+// scopeBotOwner 限定queryMessageBots只查询指定公司机器人下的账号，ownerID为0时不限定
+func scopeBotOwner(db *gorm.DB, ownerID uint) *gorm.DB {
+	if ownerID == 0 {
+		return db
+	}
+	return db.Where("r.owner_id = ?", ownerID)
+}
+
 // queryMessageBots 查询所有可用的消息机器人
 func queryMessageBots(db *gorm.DB, groupId string, logger *zap.Logger) ([]messageBotQueryResult, error) {
 	var results []messageBotQueryResult
 
 	err := db.Table("wx_groups g").
 		Select(`u.id as user_id, u.token as user_token, u.wx_id as user_wx_id, u.nick_name as user_nick_name,
-			r.id as robot_id, r.address as robot_address, r.admin_key as robot_admin_key`).
+			r.id as robot_id, r.address as robot_address, r.admin_key as robot_admin_key, r.timeout_seconds as robot_timeout`).
 		Joins("JOIN wx_user_logins u ON g.wx_id = u.wx_id").
 		Joins("JOIN wx_robot_configs r ON u.robot_id = r.id").
-		Where("g.group_id = ? AND u.status = 1 AND u.is_message_bot = 1 AND u.has_security_risk = 0", groupId).
+		Where("g.group_id = ? AND u.status = 1 AND u.is_message_bot = 1 AND u.has_security_risk = 0 AND r.enabled = 1", groupId).
 		Find(&results).Error
 
 	if err != nil {
@@ -75,7 +106,7 @@ func queryMessageBots(db *gorm.DB, groupId string, logger *zap.Logger) ([]messag
 	}
 
 	if len(results) == 0 {
-		return nil, fmt.Errorf("未找到可用的消息机器人")
+		return nil, ErrNoMessageBot
 	}
 
 	return results, nil
@@ -92,9 +123,10 @@ func buildMessageBotInfo(result messageBotQueryResult) *MessageBotInfo {
 	}
 
 	robot := &WxRobotConfig{
-		ID:       result.RobotID,
-		Address:  result.RobotAddress,
-		AdminKey: result.RobotAdminKey,
+		ID:             result.RobotID,
+		Address:        result.RobotAddress,
+		AdminKey:       result.RobotAdminKey,
+		TimeoutSeconds: result.RobotTimeout,
 	}
 
 	return &MessageBotInfo{
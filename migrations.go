@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Migration 数据库结构迁移，按版本号从小到大依次执行，已执行的版本记录在 schema_migrations 表中不再重复执行
+type Migration struct {
+	Version     uint
+	Description string
+	Statements  []string // 迁移执行的SQL，按顺序执行
+}
+
+// schemaMigrations 已登记的迁移，新增表结构变更时在末尾追加新版本，不要修改已发布的版本
+// 版本1为 database.sql 的初始表结构（含“已有数据库升级语句”），已按该文件建库或升级的数据库视为已执行
+var schemaMigrations = []Migration{
+	{Version: 1, Description: "初始表结构（database.sql）"},
+	{Version: 2, Description: "新增机器人配置变更记录表", Statements: []string{createRobotConfigChangesTable}},
+	{Version: 3, Description: "新增失败消息重试表", Statements: []string{createSendRetriesTable}},
+	{Version: 4, Description: "新增用户授权延期记录表", Statements: []string{createUserExtensionLogsTable}},
+	{Version: 5, Description: "发送记录和失败重试记录增加所属公司", Statements: []string{addSendRecordOwnerID, addSendRetryOwnerID}},
+	{Version: 6, Description: "授权码记录增加生成来源", Statements: []string{addAuthKeySource}},
+}
+
+// 迁移记录表，已有数据库首次启动时自动创建
+const createSchemaMigrationsTable = "CREATE TABLE IF NOT EXISTS `schema_migrations` (" +
+	"`version` bigint(20) unsigned NOT NULL COMMENT '迁移版本', " +
+	"`description` varchar(200) DEFAULT NULL COMMENT '迁移说明', " +
+	"`applied_time` datetime(3) NOT NULL COMMENT '执行时间', " +
+	"PRIMARY KEY (`version`)" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='数据库迁移版本记录表'"
+
+// 迁移版本2：机器人配置变更记录表，与 database.sql 中的建表语句保持一致
+const createRobotConfigChangesTable = "CREATE TABLE IF NOT EXISTS `wx_robot_config_changes` (" +
+	"`id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID', " +
+	"`robot_id` bigint(20) unsigned NOT NULL COMMENT '机器人ID', " +
+	"`field` varchar(50) NOT NULL COMMENT '变更字段', " +
+	"`old_value` text COMMENT '变更前的值，敏感字段不记录明文', " +
+	"`new_value` text COMMENT '变更后的值，敏感字段不记录明文', " +
+	"`source` varchar(30) NOT NULL COMMENT '变更来源 update/patch/rotate_admin_key', " +
+	"`operator_owner_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '操作者API Key绑定的公司ID，0表示未使用API Key', " +
+	"`client_ip` varchar(64) DEFAULT NULL COMMENT '操作者IP', " +
+	"`create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '变更时间', " +
+	"PRIMARY KEY (`id`), " +
+	"INDEX `idx_robot_time` (`robot_id`, `create_time`)" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='机器人配置变更记录表'"
+
+// 迁移版本3：失败消息重试表，与 database.sql 中的建表语句保持一致
+const createSendRetriesTable = "CREATE TABLE IF NOT EXISTS `wx_send_retries` (" +
+	"`id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID', " +
+	"`msg_type` varchar(20) NOT NULL COMMENT '消息类型 text/image/voice/video/link', " +
+	"`to_user_name` varchar(100) NOT NULL COMMENT '目标群ID', " +
+	"`payload` mediumtext NOT NULL COMMENT '原始发送请求JSON', " +
+	"`status` varchar(20) NOT NULL DEFAULT 'pending' COMMENT '状态 pending/success/failed', " +
+	"`attempts` int(11) NOT NULL DEFAULT 0 COMMENT '已重试次数', " +
+	"`last_error` varchar(500) DEFAULT NULL COMMENT '最近一次失败原因', " +
+	"`next_retry_time` datetime(3) NOT NULL COMMENT '下次重试时间', " +
+	"`create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间', " +
+	"`update_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '修改时间', " +
+	"PRIMARY KEY (`id`), " +
+	"INDEX `idx_status_next_retry` (`status`, `next_retry_time`)" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='失败消息重试表'"
+
+// 迁移版本4：用户授权延期记录表，与 database.sql 中的建表语句保持一致
+const createUserExtensionLogsTable = "CREATE TABLE IF NOT EXISTS `wx_user_extension_logs` (" +
+	"`id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID', " +
+	"`user_id` bigint(20) unsigned NOT NULL COMMENT '用户ID', " +
+	"`robot_id` bigint(20) unsigned NOT NULL COMMENT '机器人ID', " +
+	"`days` int(11) NOT NULL COMMENT '延期天数', " +
+	"`old_expiration_time` datetime(3) DEFAULT NULL COMMENT '延期前的过期时间', " +
+	"`new_expiration_time` datetime(3) DEFAULT NULL COMMENT '延期后的过期时间', " +
+	"`source` varchar(20) NOT NULL COMMENT '延期来源 manual/batch/auto_renew', " +
+	"`create_time` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) COMMENT '延期时间', " +
+	"PRIMARY KEY (`id`), " +
+	"INDEX `idx_user_time` (`user_id`, `create_time`)" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户授权延期记录表'"
+
+// 迁移版本5：发送记录和失败重试记录增加发起方公司ID，用于租户隔离
+const addSendRecordOwnerID = "ALTER TABLE `wx_send_records` " +
+	"ADD COLUMN `owner_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '发起发送的API Key绑定的公司ID，0表示未使用API Key' AFTER `trace_id`"
+
+const addSendRetryOwnerID = "ALTER TABLE `wx_send_retries` " +
+	"ADD COLUMN `owner_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '发起发送的API Key绑定的公司ID，重发只使用该公司的消息机器人' AFTER `id`"
+
+// 迁移版本6：授权码记录增加生成来源，区分手动生成与轮换管理密钥时的验证
+const addAuthKeySource = "ALTER TABLE `wx_auth_keys` " +
+	"ADD COLUMN `source` varchar(20) NOT NULL DEFAULT 'manual' COMMENT '生成来源 manual/rotate_probe' AFTER `expire_time`"
+
+// MigrationStatus 数据库迁移版本状态
+type MigrationStatus struct {
+	CurrentVersion uint              `json:"current_version"` // 已执行的最大版本，0表示未执行过迁移
+	LatestVersion  uint              `json:"latest_version"`  // 当前程序登记的最新版本
+	Applied        []SchemaMigration `json:"applied"`
+	Pending        []MigrationInfo   `json:"pending"`
+}
+
+// MigrationInfo 待执行的迁移
+type MigrationInfo struct {
+	Version     uint   `json:"version"`
+	Description string `json:"description"`
+}
+
+// sortedMigrations 按版本排序并校验版本号不重复
+func sortedMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version == 0 {
+			return nil, fmt.Errorf("迁移版本号必须大于0: %s", m.Description)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("迁移版本号重复: %d", m.Version)
+		}
+	}
+	return sorted, nil
+}
+
+// appliedMigrations 查询已执行的迁移，按版本升序
+func appliedMigrations(db *gorm.DB) ([]SchemaMigration, error) {
+	var applied []SchemaMigration
+	if err := db.Order("version ASC").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("查询已执行的迁移失败: %w", err)
+	}
+	return applied, nil
+}
+
+// applyMigrations 按版本顺序执行未执行过的迁移，每个版本执行成功后立即记录，失败时停止并返回错误
+func applyMigrations(db *gorm.DB, logger *zap.Logger, migrations []Migration) error {
+	sorted, err := sortedMigrations(migrations)
+	if err != nil {
+		return err
+	}
+
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		if err := db.Exec(createSchemaMigrationsTable).Error; err != nil {
+			return fmt.Errorf("创建迁移记录表失败: %w", err)
+		}
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	done := make(map[uint]bool, len(applied))
+	for _, m := range applied {
+		done[m.Version] = true
+	}
+
+	for _, m := range sorted {
+		if done[m.Version] {
+			continue
+		}
+
+		logger.Info("执行数据库迁移", zap.Uint("version", m.Version), zap.String("description", m.Description))
+		// MySQL的DDL会隐式提交，事务只能保证DML与版本记录一致，DDL失败需按日志手工处理
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, stmt := range m.Statements {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return fmt.Errorf("执行语句失败: %w", err)
+				}
+			}
+			return tx.Create(&SchemaMigration{
+				Version:     m.Version,
+				Description: m.Description,
+				AppliedTime: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			logger.Error("数据库迁移失败", zap.Uint("version", m.Version), zap.Error(err))
+			return fmt.Errorf("数据库迁移版本%d失败: %w", m.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// loadMigrationStatus 查询当前迁移版本以及待执行的迁移
+func loadMigrationStatus(db *gorm.DB, migrations []Migration) (*MigrationStatus, error) {
+	sorted, err := sortedMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{
+		Applied: []SchemaMigration{},
+		Pending: []MigrationInfo{},
+	}
+	if len(sorted) > 0 {
+		status.LatestVersion = sorted[len(sorted)-1].Version
+	}
+
+	// 迁移记录表不存在时视为未执行过迁移
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		applied, err := appliedMigrations(db)
+		if err != nil {
+			return nil, err
+		}
+		status.Applied = applied
+	}
+
+	done := make(map[uint]bool, len(status.Applied))
+	for _, m := range status.Applied {
+		done[m.Version] = true
+		if m.Version > status.CurrentVersion {
+			status.CurrentVersion = m.Version
+		}
+	}
+	for _, m := range sorted {
+		if !done[m.Version] {
+			status.Pending = append(status.Pending, MigrationInfo{Version: m.Version, Description: m.Description})
+		}
+	}
+
+	return status, nil
+}
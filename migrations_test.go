@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestApplyMigrations(t *testing.T) {
+	// 每个版本向记录表写入自己的版本号，用于检查执行顺序和次数
+	migration := func(version uint) Migration {
+		return Migration{
+			Version:     version,
+			Description: fmt.Sprintf("版本%d", version),
+			Statements:  []string{fmt.Sprintf("INSERT INTO migration_log (version) VALUES (%d)", version)},
+		}
+	}
+	broken := Migration{Version: 2, Description: "错误语句", Statements: []string{"ALTER TABLE not_exists ADD COLUMN x int"}}
+
+	tests := []struct {
+		name        string
+		preApplied  []uint
+		migrations  []Migration
+		wantErr     bool
+		wantLog     []uint // 迁移语句的执行顺序，两次执行的合计
+		wantApplied []uint
+	}{
+		{name: "applied in version order", migrations: []Migration{migration(3), migration(1), migration(2)}, wantLog: []uint{1, 2, 3}, wantApplied: []uint{1, 2, 3}},
+		{name: "already applied skipped", preApplied: []uint{1}, migrations: []Migration{migration(1), migration(2)}, wantLog: []uint{2}, wantApplied: []uint{1, 2}},
+		{name: "failure stops later versions", migrations: []Migration{migration(1), broken, migration(3)}, wantErr: true, wantLog: []uint{1}, wantApplied: []uint{1}},
+		{name: "duplicate version", migrations: []Migration{migration(1), migration(1)}, wantErr: true},
+		{name: "zero version", migrations: []Migration{migration(0)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			if err := db.Exec("CREATE TABLE migration_log (id integer PRIMARY KEY AUTOINCREMENT, version integer)").Error; err != nil {
+				t.Fatalf("create log table: %v", err)
+			}
+			for _, v := range tt.preApplied {
+				db.Create(&SchemaMigration{Version: v})
+			}
+
+			// 第二次执行不应重复执行已记录的版本
+			for i := 0; i < 2; i++ {
+				if err := applyMigrations(db, zap.NewNop(), tt.migrations); (err != nil) != tt.wantErr {
+					t.Fatalf("run %d applyMigrations err = %v, wantErr %v", i, err, tt.wantErr)
+				}
+			}
+
+			var log []uint
+			db.Table("migration_log").Order("id").Pluck("version", &log)
+			var applied []uint
+			db.Model(&SchemaMigration{}).Order("version").Pluck("version", &applied)
+			if len(log) != len(tt.wantLog) || (len(log) > 0 && !reflect.DeepEqual(log, tt.wantLog)) {
+				t.Fatalf("executed = %v, want %v", log, tt.wantLog)
+			}
+			if len(applied) != len(tt.wantApplied) || (len(applied) > 0 && !reflect.DeepEqual(applied, tt.wantApplied)) {
+				t.Fatalf("applied = %v, want %v", applied, tt.wantApplied)
+			}
+		})
+	}
+}
+
+func TestLoadMigrationStatus(t *testing.T) {
+	migrations := []Migration{{Version: 1, Description: "一"}, {Version: 3, Description: "三"}, {Version: 2, Description: "二"}}
+
+	tests := []struct {
+		name        string
+		preApplied  []uint
+		noTable     bool
+		wantCurrent uint
+		wantPending []MigrationInfo
+	}{
+		{name: "none applied", wantPending: []MigrationInfo{{1, "一"}, {2, "二"}, {3, "三"}}},
+		{name: "no migration table", noTable: true, wantPending: []MigrationInfo{{1, "一"}, {2, "二"}, {3, "三"}}},
+		{name: "partially applied", preApplied: []uint{1, 2}, wantCurrent: 2, wantPending: []MigrationInfo{{3, "三"}}},
+		{name: "up to date", preApplied: []uint{1, 2, 3}, wantCurrent: 3, wantPending: []MigrationInfo{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			if tt.noTable {
+				db.Migrator().DropTable(&SchemaMigration{})
+			}
+			for _, v := range tt.preApplied {
+				db.Create(&SchemaMigration{Version: v})
+			}
+
+			status, err := loadMigrationStatus(db, migrations)
+			if err != nil {
+				t.Fatalf("loadMigrationStatus: %v", err)
+			}
+			if status.CurrentVersion != tt.wantCurrent || status.LatestVersion != 3 {
+				t.Fatalf("current = %d latest = %d, want %d and 3", status.CurrentVersion, status.LatestVersion, tt.wantCurrent)
+			}
+			if len(status.Applied) != len(tt.preApplied) {
+				t.Fatalf("applied = %+v, want %d versions", status.Applied, len(tt.preApplied))
+			}
+			if !reflect.DeepEqual(status.Pending, tt.wantPending) {
+				t.Fatalf("pending = %+v, want %+v", status.Pending, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestSchemaMigrationsSorted(t *testing.T) {
+	if _, err := sortedMigrations(schemaMigrations); err != nil {
+		t.Fatalf("schemaMigrations invalid: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const testAdminToken = "test-admin-token"
+
+// tenantFixture 两个公司各一个机器人、一个消息机器人账号和一个群
+type tenantFixture struct {
+	router *gin.Engine
+	db     *gorm.DB
+	robots [2]*WxRobotConfig
+	users  [2]*WxUserLogin
+	groups [2]string
+}
+
+// newTenantFixture 创建配置了两个租户API Key的路由，公司1的Key为key-1，公司2的Key为key-2
+func newTenantFixture(t *testing.T) *tenantFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &Config{}
+	cfg.Security.AdminToken = testAdminToken
+	cfg.Security.APIKeys = []APIKeyConfig{{Key: "key-1", OwnerID: 1}, {Key: "key-2", OwnerID: 2}}
+	svc, db := newTestService(t, cfg)
+
+	f := &tenantFixture{db: db}
+	for i := 0; i < 2; i++ {
+		owner := uint(i + 1)
+		f.robots[i] = &WxRobotConfig{Address: fmt.Sprintf("http://robot%d.invalid", owner), OwnerID: owner}
+		f.users[i] = &WxUserLogin{WxID: fmt.Sprintf("wxid_owner%d", owner), Token: fmt.Sprintf("token-%d", owner), Status: 1, IsMessageBot: 1}
+		createTestRobot(t, db, f.robots[i], f.users[i])
+
+		f.groups[i] = fmt.Sprintf("owner%d@chatroom", owner)
+		group := &WxGroup{WxID: f.users[i].WxID, GroupID: f.groups[i], GroupNickName: fmt.Sprintf("测试群%d", owner)}
+		if err := db.Create(group).Error; err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+
+	rm, err := NewRouterManager(cfg, zap.NewNop(), svc, nil)
+	if err != nil {
+		t.Fatalf("NewRouterManager: %v", err)
+	}
+	f.router = rm.InitRoutes(cfg)
+	return f
+}
+
+// do 发送请求，headers为请求头键值对
+func (f *tenantFixture) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/wx/v1"+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOwnerScopeAuthentication(t *testing.T) {
+	f := newTenantFixture(t)
+	path := fmt.Sprintf("/users/%d/status-history", f.users[0].ID)
+
+	tests := []struct {
+		name    string
+		headers []string
+		want    int
+	}{
+		{name: "no key and no admin token", want: http.StatusUnauthorized},
+		{name: "wrong admin token", headers: []string{AdminTokenHeader, "wrong"}, want: http.StatusUnauthorized},
+		{name: "unknown api key", headers: []string{APIKeyHeader, "key-x"}, want: http.StatusUnauthorized},
+		{name: "admin token", headers: []string{AdminTokenHeader, testAdminToken}, want: http.StatusOK},
+		{name: "own api key", headers: []string{APIKeyHeader, "key-1"}, want: http.StatusOK},
+		{name: "other owner api key", headers: []string{APIKeyHeader, "key-2"}, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.do(http.MethodGet, path, "", tt.headers...)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOwnerScopeDeniesOtherOwnerResources(t *testing.T) {
+	f := newTenantFixture(t)
+	// 公司1的Key访问公司2的资源
+	otherUser, otherRobot, otherGroup := f.users[1], f.robots[1], f.groups[1]
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "delete user", method: http.MethodDelete, path: fmt.Sprintf("/users/%d", otherUser.ID)},
+		{name: "user auth info", method: http.MethodGet, path: fmt.Sprintf("/users/%d/auth-info", otherUser.ID)},
+		{name: "user status history", method: http.MethodGet, path: fmt.Sprintf("/users/%d/status-history", otherUser.ID)},
+		{name: "update remark", method: http.MethodPut, path: fmt.Sprintf("/users/%d/remark", otherUser.ID), body: `{"remark":"x"}`},
+		{name: "update signature", method: http.MethodPut, path: fmt.Sprintf("/users/%d/signature", otherUser.ID), body: `{"signature":"x"}`},
+		{name: "update auto renew", method: http.MethodPut, path: fmt.Sprintf("/users/%d/auto-renew", otherUser.ID), body: `{"auto_renew":1}`},
+		{name: "message bot status", method: http.MethodPost, path: fmt.Sprintf("/users/message-bot-status/%d", otherUser.ID), body: `{"is_message_bot":0}`},
+		{name: "users by robot", method: http.MethodGet, path: fmt.Sprintf("/users/robot/%d", otherRobot.ID)},
+		{name: "robot summary", method: http.MethodGet, path: fmt.Sprintf("/robots/%d/summary", otherRobot.ID)},
+		{name: "patch robot", method: http.MethodPatch, path: fmt.Sprintf("/robots/%d", otherRobot.ID), body: `{"description":"x"}`},
+		{name: "move robot to other owner", method: http.MethodPatch, path: fmt.Sprintf("/robots/%d", f.robots[0].ID), body: `{"owner_id":2}`},
+		{name: "groups by wx id", method: http.MethodGet, path: "/groups/user/" + otherUser.WxID},
+		{name: "group bots", method: http.MethodGet, path: "/groups/" + otherGroup + "/bots"},
+		{name: "group changes", method: http.MethodGet, path: "/groups/changes?owner_id=2&start_time=2020-01-01+00:00:00&end_time=2099-01-01+00:00:00"},
+		{name: "add blacklist", method: http.MethodPost, path: "/groups/blacklist", body: `{"group_id":"` + otherGroup + `"}`},
+		{name: "remove blacklist", method: http.MethodDelete, path: "/groups/blacklist/" + otherGroup},
+		{name: "send text", method: http.MethodPost, path: "/messages/group/send-text", body: `{"text_content":"hi","to_user_name":"` + otherGroup + `"}`},
+		{name: "send image", method: http.MethodPost, path: "/messages/group/send-image", body: `{"image_content":"aGk=","to_user_name":"` + otherGroup + `"}`},
+		{name: "send text and image", method: http.MethodPost, path: "/messages/group/send-text-image", body: `{"text_content":"hi","to_user_name":"` + otherGroup + `"}`},
+		{name: "message callback", method: http.MethodPost, path: "/messages/callback", body: `{"wx_id":"` + otherUser.WxID + `","group_id":"` + otherGroup + `","msg_type":1,"content":"hi"}`},
+		{name: "set strategy", method: http.MethodPost, path: "/messages/group/set-strategy", body: `{"strategy":"random"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.do(tt.method, tt.path, tt.body, APIKeyHeader, "key-1")
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want 403, body = %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// 被拒绝的写操作不能生效
+	var user WxUserLogin
+	if err := f.db.First(&user, otherUser.ID).Error; err != nil {
+		t.Fatalf("other owner's user deleted: %v", err)
+	}
+	if user.IsMessageBot != 1 {
+		t.Fatalf("other owner's user was modified: is_message_bot = %d", user.IsMessageBot)
+	}
+	var robot WxRobotConfig
+	if err := f.db.First(&robot, f.robots[0].ID).Error; err != nil || robot.OwnerID != 1 {
+		t.Fatalf("robot owner = %d, err = %v, want 1", robot.OwnerID, err)
+	}
+}
+
+func TestOwnerScopeFiltersLists(t *testing.T) {
+	f := newTenantFixture(t)
+	for _, groupID := range f.groups {
+		if err := f.db.Create(&WxGroupBlacklist{GroupID: groupID}).Error; err != nil {
+			t.Fatalf("create blacklist: %v", err)
+		}
+	}
+	for i, groupID := range f.groups {
+		if err := f.db.Create(&WxSendRecord{TraceID: "trace-1", OwnerID: uint(i + 1), ToUserName: groupID}).Error; err != nil {
+			t.Fatalf("create send record: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		headers []string
+		want    []string // 响应中应出现的群，另一公司的群不应出现
+	}{
+		{name: "search groups", path: "/groups/search?groupNickName=" + "测试群", headers: []string{APIKeyHeader, "key-1"}, want: []string{f.groups[0]}},
+		{name: "blacklist", path: "/groups/blacklist", headers: []string{APIKeyHeader, "key-2"}, want: []string{f.groups[1]}},
+		{name: "send trace", path: "/messages/trace/trace-1", headers: []string{APIKeyHeader, "key-1"}, want: []string{f.groups[0]}},
+		{name: "admin sees all", path: "/groups/blacklist", headers: []string{AdminTokenHeader, testAdminToken}, want: f.groups[:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.do(http.MethodGet, tt.path, "", tt.headers...)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+			body := w.Body.String()
+			for _, groupID := range f.groups {
+				wanted := false
+				for _, want := range tt.want {
+					wanted = wanted || want == groupID
+				}
+				if got := strings.Contains(body, groupID); got != wanted {
+					t.Fatalf("group %s in response = %v, want %v, body = %s", groupID, got, wanted, body)
+				}
+			}
+		})
+	}
+}
+
+func TestOwnerScopeBroadcastTask(t *testing.T) {
+	f := newTenantFixture(t)
+
+	// 公司1的群发只使用公司1的消息机器人，公司2的群没有可用消息机器人被剔除
+	w := f.do(http.MethodPost, "/messages/broadcast",
+		`{"to_user_names":["`+f.groups[1]+`"],"text_content":"hi"}`, APIKeyHeader, "key-1")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("broadcast to other owner's group: status = %d, want 400, body = %s", w.Code, w.Body.String())
+	}
+
+	w = f.do(http.MethodPost, "/messages/broadcast",
+		`{"to_user_names":["`+f.groups[0]+`"],"text_content":"hi"}`, APIKeyHeader, "key-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("broadcast to own group: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data BroadcastTaskProgress `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	path := "/messages/broadcast/" + resp.Data.TaskID
+	if w := f.do(http.MethodGet, path, "", APIKeyHeader, "key-2"); w.Code != http.StatusNotFound {
+		t.Fatalf("other owner progress: status = %d, want 404", w.Code)
+	}
+	if w := f.do(http.MethodGet, path, "", APIKeyHeader, "key-1"); w.Code != http.StatusOK {
+		t.Fatalf("own progress: status = %d, want 200", w.Code)
+	}
+
+	// 等待后台任务结束，发送记录带上发起方公司
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var record WxSendRecord
+		err := f.db.Where("trace_id = ?", resp.Data.TraceID).First(&record).Error
+		if err == nil {
+			if record.OwnerID != 1 {
+				t.Fatalf("send record owner_id = %d, want 1", record.OwnerID)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("send record not saved: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOwnerScopedStrategy(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	for owner := uint(1); owner <= 2; owner++ {
+		user := &WxUserLogin{WxID: fmt.Sprintf("wxid_%d", owner), Token: fmt.Sprintf("token-%d", owner), Status: 1, IsMessageBot: 1}
+		createTestRobot(t, db, &WxRobotConfig{Address: "http://robot.invalid", OwnerID: owner}, user)
+		if err := db.Create(&WxGroup{WxID: user.WxID, GroupID: "shared@chatroom"}).Error; err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		ownerID  uint
+		wantWxID string
+	}{
+		{name: "owner 1", ownerID: 1, wantWxID: "wxid_1"},
+		{name: "owner 2", ownerID: 2, wantWxID: "wxid_2"},
+		{name: "owner without bots", ownerID: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := withOwnerScope(NewRoundRobinMessageSendStrategy(), tt.ownerID)
+			// 多次选择都只能选到本公司的账号
+			for i := 0; i < 4; i++ {
+				bot, err := svc.GetMessageBotByStrategy("shared@chatroom", strategy)
+				if tt.wantWxID == "" {
+					if err == nil {
+						t.Fatalf("got bot %s, want error", bot.User.WxID)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("GetMessageBotByStrategy: %v", err)
+				}
+				if bot.User.WxID != tt.wantWxID {
+					t.Fatalf("selected %s, want %s", bot.User.WxID, tt.wantWxID)
+				}
+			}
+		})
+	}
+}
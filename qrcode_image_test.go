@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestGetQRCodeImage(t *testing.T) {
+	// 最小的PNG文件头，足以被识别为image/png
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	encoded := base64.StdEncoding.EncodeToString(png)
+
+	tests := []struct {
+		name        string
+		qrCode      string
+		query       func(robotID uint) string
+		wantStatus  int
+		wantPNGBody bool
+	}{
+		{name: "plain base64", qrCode: encoded, query: func(id uint) string { return fmt.Sprintf("token=abc&robot_id=%d", id) }, wantStatus: http.StatusOK, wantPNGBody: true},
+		{name: "data uri", qrCode: "data:image/png;base64," + encoded, query: func(id uint) string { return fmt.Sprintf("token=abc&robot_id=%d", id) }, wantStatus: http.StatusOK, wantPNGBody: true},
+		{name: "empty qrcode", qrCode: "", query: func(id uint) string { return fmt.Sprintf("token=abc&robot_id=%d", id) }, wantStatus: http.StatusInternalServerError},
+		{name: "missing token", qrCode: encoded, query: func(id uint) string { return fmt.Sprintf("robot_id=%d", id) }, wantStatus: http.StatusBadRequest},
+		{name: "invalid robot id", qrCode: encoded, query: func(id uint) string { return "token=abc&robot_id=x" }, wantStatus: http.StatusBadRequest},
+		{name: "unknown robot", qrCode: encoded, query: func(id uint) string { return fmt.Sprintf("token=abc&robot_id=%d", id+100) }, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, db := newTestRouter(t, nil)
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.GetLoginQrCode: jsonHandler(map[string]interface{}{
+					"Code": 200,
+					"Data": map[string]interface{}{"qrCodeBase64": tt.qrCode},
+				}),
+			})
+			robot := &WxRobotConfig{Address: server.URL, OwnerID: 1}
+			createTestRobot(t, db, robot)
+
+			w := doRequest(router, http.MethodGet, "/users/qrcode/image?"+tt.query(robot.ID), "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !tt.wantPNGBody {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+				t.Fatalf("Content-Type = %q, want image/png", ct)
+			}
+			if w.Header().Get("Cache-Control") != "no-store" {
+				t.Fatalf("Cache-Control = %q, want no-store", w.Header().Get("Cache-Control"))
+			}
+			if !bytes.Equal(w.Body.Bytes(), png) {
+				t.Fatalf("body = %q, want decoded png", w.Body.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// windowLimiter 滑动窗口计数限流，同一key在window内最多允许limit次，limit<=0表示不限流
+type windowLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+func newWindowLimiter(limit int, window time.Duration) *windowLimiter {
+	return &windowLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow 判断key本次是否允许通过，超限时返回需等待的时长
+func (l *windowLimiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 || l.window <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	hits := l.prune(l.hits[key], now)
+	if len(hits) >= l.limit {
+		l.hits[key] = hits
+		return false, hits[0].Add(l.window).Sub(now)
+	}
+	l.hits[key] = append(hits, now)
+	return true, 0
+}
+
+// Undo 撤销key最近一次通过的记录，用于通过后操作失败的情况
+func (l *windowLimiter) Undo(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if hits := l.hits[key]; len(hits) > 0 {
+		l.hits[key] = hits[:len(hits)-1]
+	}
+}
+
+// prune 去掉窗口外的记录
+func (l *windowLimiter) prune(hits []time.Time, now time.Time) []time.Time {
+	start := now.Add(-l.window)
+	i := 0
+	for i < len(hits) && !hits[i].After(start) {
+		i++
+	}
+	return hits[i:]
+}
+
+// sweep 每个窗口周期清理一次已无记录的key，避免map无限增长
+func (l *windowLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, hits := range l.hits {
+		if hits = l.prune(hits, now); len(hits) == 0 {
+			delete(l.hits, key)
+		} else {
+			l.hits[key] = hits
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWindowLimiter(t *testing.T) {
+	type call struct {
+		key       string
+		wait      time.Duration // 调用前等待
+		undo      bool          // 调用后撤销
+		wantAllow bool
+	}
+
+	tests := []struct {
+		name   string
+		limit  int
+		window time.Duration
+		calls  []call
+	}{
+		{name: "within limit", limit: 2, window: time.Minute, calls: []call{{key: "a", wantAllow: true}, {key: "a", wantAllow: true}}},
+		{name: "over limit", limit: 2, window: time.Minute, calls: []call{{key: "a", wantAllow: true}, {key: "a", wantAllow: true}, {key: "a"}}},
+		{name: "keys independent", limit: 1, window: time.Minute, calls: []call{{key: "a", wantAllow: true}, {key: "b", wantAllow: true}, {key: "a"}}},
+		{name: "window slides", limit: 1, window: 30 * time.Millisecond, calls: []call{{key: "a", wantAllow: true}, {key: "a"}, {key: "a", wait: 40 * time.Millisecond, wantAllow: true}}},
+		{name: "undo releases", limit: 1, window: time.Minute, calls: []call{{key: "a", undo: true, wantAllow: true}, {key: "a", wantAllow: true}, {key: "a"}}},
+		{name: "disabled", limit: 0, window: time.Minute, calls: []call{{key: "a", wantAllow: true}, {key: "a", wantAllow: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newWindowLimiter(tt.limit, tt.window)
+			for i, c := range tt.calls {
+				time.Sleep(c.wait)
+				allowed, retryAfter := l.Allow(c.key)
+				if allowed != c.wantAllow {
+					t.Fatalf("call %d Allow(%s) = %v, want %v", i, c.key, allowed, c.wantAllow)
+				}
+				if !allowed && (retryAfter <= 0 || retryAfter > tt.window) {
+					t.Fatalf("call %d retryAfter = %v, want within (0, %v]", i, retryAfter, tt.window)
+				}
+				if c.undo {
+					l.Undo(c.key)
+				}
+			}
+		})
+	}
+}
+
+func TestGetQRCodeRateLimited(t *testing.T) {
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.GetLoginQrCode: jsonHandler(map[string]interface{}{"Code": 200}),
+	})
+	cfg := &Config{}
+	cfg.Security.QRCodeLimit = 2
+	cfg.Security.QRCodeWindow = time.Minute
+	router, _, db := newTestRouter(t, cfg)
+	robot := &WxRobotConfig{Address: server.URL}
+	createTestRobot(t, db, robot)
+
+	tests := []struct {
+		name    string
+		token   string
+		limited bool
+	}{
+		{name: "first", token: "token-a"},
+		{name: "second", token: "token-a"},
+		{name: "third limited", token: "token-a", limited: true},
+		{name: "other token", token: "token-b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(router, http.MethodPost, "/users/qrcode", fmt.Sprintf(`{"token":%q,"robot_id":%d}`, tt.token, robot.ID))
+			if limited := w.Code == http.StatusTooManyRequests; limited != tt.limited {
+				t.Fatalf("status = %d, limited want %v, body = %s", w.Code, tt.limited, w.Body.String())
+			}
+			if tt.limited && w.Header().Get("Retry-After") == "" {
+				t.Fatal("limited response missing Retry-After")
+			}
+		})
+	}
+}
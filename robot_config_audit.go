@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 机器人配置变更来源
+const (
+	RobotChangeSourceUpdate      = "update"           // PUT 覆盖式更新
+	RobotChangeSourcePatch       = "patch"            // PATCH 部分更新
+	RobotChangeSourceRotateKey   = "rotate_admin_key" // 轮换管理密钥
+	RobotChangeSourceBatchStatus = "batch_status"     // 批量启用/禁用
+	RobotChangeSourceIdleDisable = "idle_disable"     // 清理闲置机器人时禁用
+	RobotChangeSourceIdleDelete  = "idle_delete"      // 清理闲置机器人时删除
+	robotChangeMaskedValue       = "******"           // 敏感字段新旧值的占位内容
+)
+
+// RobotChangeOperator 机器人配置变更的操作者信息
+type RobotChangeOperator struct {
+	OwnerID  uint   // 请求API Key绑定的公司ID，未使用API Key时为0
+	ClientIP string // 请求来源IP
+}
+
+// robotChangeOperator 从请求中获取操作者信息
+func robotChangeOperator(c *gin.Context) RobotChangeOperator {
+	ownerID, _ := scopedOwner(c)
+	return RobotChangeOperator{OwnerID: ownerID, ClientIP: c.ClientIP()}
+}
+
+// robotAuditField 参与变更审计的机器人配置字段
+type robotAuditField struct {
+	name      string
+	value     func(*WxRobotConfig) string
+	sensitive bool // 敏感字段只记录变更事实
+}
+
+// robotAuditFields 按字段顺序对比，字段名与数据库列名一致
+var robotAuditFields = []robotAuditField{
+	{name: "address", value: func(r *WxRobotConfig) string { return r.Address }},
+	{name: "admin_key", value: func(r *WxRobotConfig) string { return r.AdminKey }, sensitive: true},
+	{name: "owner_id", value: func(r *WxRobotConfig) string { return strconv.FormatUint(uint64(r.OwnerID), 10) }},
+	{name: "description", value: func(r *WxRobotConfig) string { return r.Description }},
+	{name: "admin_users", value: func(r *WxRobotConfig) string { return r.AdminUsers }},
+	{name: "timeout_seconds", value: func(r *WxRobotConfig) string { return strconv.Itoa(r.TimeoutSeconds) }},
+	{name: "health_path", value: func(r *WxRobotConfig) string { return r.HealthPath }},
+	{name: "enabled", value: func(r *WxRobotConfig) string { return strconv.Itoa(r.Enabled) }},
+	{name: "tags", value: func(r *WxRobotConfig) string { return r.Tags }},
+}
+
+// diffRobotConfig 对比修改前后的机器人配置，返回发生变化的字段记录，没有变化时返回空
+func diffRobotConfig(before, after *WxRobotConfig, source string, operator RobotChangeOperator) []WxRobotConfigChange {
+	var changes []WxRobotConfigChange
+	for _, field := range robotAuditFields {
+		oldValue, newValue := field.value(before), field.value(after)
+		if oldValue == newValue {
+			continue
+		}
+		if field.sensitive {
+			oldValue, newValue = robotChangeMaskedValue, robotChangeMaskedValue
+		}
+		changes = append(changes, WxRobotConfigChange{
+			RobotID:         before.ID,
+			Field:           field.name,
+			OldValue:        oldValue,
+			NewValue:        newValue,
+			Source:          source,
+			OperatorOwnerID: operator.OwnerID,
+			ClientIP:        operator.ClientIP,
+		})
+	}
+	return changes
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDiffRobotConfig(t *testing.T) {
+	before := WxRobotConfig{ID: 7, Address: "http://a.invalid", AdminKey: "key-1", OwnerID: 1, Description: "旧", Enabled: 1}
+	operator := RobotChangeOperator{OwnerID: 2, ClientIP: "10.0.0.1"}
+
+	tests := []struct {
+		name   string
+		modify func(r *WxRobotConfig)
+		want   []string // 字段:旧值->新值
+	}{
+		{name: "unchanged", modify: func(r *WxRobotConfig) {}},
+		{name: "address", modify: func(r *WxRobotConfig) { r.Address = "http://b.invalid" }, want: []string{"address:http://a.invalid->http://b.invalid"}},
+		{name: "admin key masked", modify: func(r *WxRobotConfig) { r.AdminKey = "key-2" }, want: []string{"admin_key:******->******"}},
+		{
+			name: "multiple fields in order",
+			modify: func(r *WxRobotConfig) {
+				r.Tags, r.Enabled, r.OwnerID, r.TimeoutSeconds = "华东", 0, 3, 60
+			},
+			want: []string{"owner_id:1->3", "timeout_seconds:0->60", "enabled:1->0", "tags:->华东"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := before
+			tt.modify(&after)
+			changes := diffRobotConfig(&before, &after, RobotChangeSourcePatch, operator)
+
+			var got []string
+			for _, c := range changes {
+				if c.RobotID != before.ID || c.Source != RobotChangeSourcePatch || c.OperatorOwnerID != 2 || c.ClientIP != "10.0.0.1" {
+					t.Fatalf("change = %+v", c)
+				}
+				got = append(got, fmt.Sprintf("%s:%s->%s", c.Field, c.OldValue, c.NewValue))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotConfigChangeHistory(t *testing.T) {
+	router, _, db := newTestRouter(t, nil)
+	robot := &WxRobotConfig{Address: "http://robot.invalid", AdminKey: "admin-key", OwnerID: 1, Description: "旧描述", AdminUsers: "wxid_admin"}
+	createTestRobot(t, db, robot)
+	path := fmt.Sprintf("/robots/%d", robot.ID)
+
+	steps := []struct {
+		name   string
+		method string
+		body   string
+		want   []string // 变更历史，按时间倒序：字段:旧值->新值(来源)
+	}{
+		{
+			name: "patch description and admin key", method: http.MethodPatch, body: `{"description":"新描述","admin_key":"new-key"}`,
+			want: []string{"description:旧描述->新描述(patch)", "admin_key:******->******(patch)"},
+		},
+		{
+			name: "patch without change", method: http.MethodPatch, body: `{"description":"新描述"}`,
+			want: []string{"description:旧描述->新描述(patch)", "admin_key:******->******(patch)"},
+		},
+		{
+			name: "update address and tags", method: http.MethodPut,
+			body: `{"address":"http://robot2.invalid","admin_key":"new-key","owner_id":1,"description":"新描述","admin_users":["wxid_admin"],"tags":["华东"]}`,
+			want: []string{
+				"tags:->华东(update)", "address:http://robot.invalid->http://robot2.invalid(update)",
+				"description:旧描述->新描述(patch)", "admin_key:******->******(patch)",
+			},
+		},
+	}
+
+	for _, s := range steps {
+		t.Run(s.name, func(t *testing.T) {
+			if w := doRequest(router, s.method, path, s.body); w.Code != http.StatusOK {
+				t.Fatalf("%s status = %d, body = %s", s.method, w.Code, w.Body.String())
+			}
+
+			w := doRequest(router, http.MethodGet, path+"/changes", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("changes status = %d, body = %s", w.Code, w.Body.String())
+			}
+			var resp struct {
+				Data []WxRobotConfigChange `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			got := make([]string, 0, len(resp.Data))
+			for _, c := range resp.Data {
+				got = append(got, fmt.Sprintf("%s:%s->%s(%s)", c.Field, c.OldValue, c.NewValue, c.Source))
+			}
+			if !reflect.DeepEqual(got, s.want) {
+				t.Fatalf("changes = %v, want %v", got, s.want)
+			}
+		})
+	}
+
+	if w := doRequest(router, http.MethodGet, fmt.Sprintf("/robots/%d/changes", robot.ID+100), ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown robot status = %d, want 404", w.Code)
+	}
+}
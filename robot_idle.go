@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// 闲置机器人清理方式
+const (
+	IdleRobotActionDisable = "disable" // 禁用，保留配置和账号
+	IdleRobotActionDelete  = "delete"  // 删除机器人配置及其账号
+)
+
+// robotLastActiveTime 机器人最近活动时间：取机器人配置、其下账号记录的最近修改时间与账号最近发送时间中的最大值
+func robotLastActiveTime(robot *WxRobotConfig, lastSend map[string]time.Time) time.Time {
+	last := robot.UpdateTime
+	for _, user := range robot.UserLogins {
+		if user.UpdateTime.After(last) {
+			last = user.UpdateTime
+		}
+		if sendTime, ok := lastSend[user.WxID]; ok && sendTime.After(last) {
+			last = sendTime
+		}
+	}
+	return last
+}
+
+// hasOnlineUser 机器人下是否有在线（状态正常）的账号
+func hasOnlineUser(robot *WxRobotConfig) bool {
+	for _, user := range robot.UserLogins {
+		if user.Status == UserStatusNormal {
+			return true
+		}
+	}
+	return false
+}
+
+// filterIdleRobots 筛选没有在线账号且最近活动早于cutoff的机器人，按最近活动时间升序返回
+// robots需预加载UserLogins，lastSend为账号微信ID到最近发送时间的映射
+func filterIdleRobots(robots []WxRobotConfig, lastSend map[string]time.Time, cutoff time.Time) []IdleRobot {
+	type candidate struct {
+		robot      *WxRobotConfig
+		lastActive time.Time
+	}
+
+	var candidates []candidate
+	for i := range robots {
+		robot := &robots[i]
+		if hasOnlineUser(robot) {
+			continue
+		}
+		lastActive := robotLastActiveTime(robot, lastSend)
+		if !lastActive.Before(cutoff) {
+			continue
+		}
+		candidates = append(candidates, candidate{robot: robot, lastActive: lastActive})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].lastActive.Before(candidates[j].lastActive)
+	})
+
+	idle := make([]IdleRobot, 0, len(candidates))
+	for _, item := range candidates {
+		idle = append(idle, IdleRobot{
+			ID:             item.robot.ID,
+			Address:        item.robot.Address,
+			OwnerID:        item.robot.OwnerID,
+			Description:    item.robot.Description,
+			Enabled:        item.robot.Enabled,
+			UserCount:      len(item.robot.UserLogins),
+			LastActiveTime: FormatTime(item.lastActive),
+			IdleDays:       int(time.Since(item.lastActive).Hours() / 24),
+		})
+	}
+	return idle
+}
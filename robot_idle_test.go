@@ -0,0 +1,203 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterIdleRobots(t *testing.T) {
+	now := time.Now()
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+	cutoff := daysAgo(30)
+
+	tests := []struct {
+		name     string
+		robots   []WxRobotConfig
+		lastSend map[string]time.Time
+		want     []uint
+	}{
+		{
+			name:   "no users and old config",
+			robots: []WxRobotConfig{{ID: 1, UpdateTime: daysAgo(40)}},
+			want:   []uint{1},
+		},
+		{
+			name:   "recently updated",
+			robots: []WxRobotConfig{{ID: 1, UpdateTime: daysAgo(10)}},
+		},
+		{
+			name: "online user",
+			robots: []WxRobotConfig{{ID: 1, UpdateTime: daysAgo(40), UserLogins: []WxUserLogin{
+				{WxID: "wxid_a", Status: UserStatusNormal, UpdateTime: daysAgo(40)},
+			}}},
+		},
+		{
+			name: "offline user updated recently",
+			robots: []WxRobotConfig{{ID: 1, UpdateTime: daysAgo(40), UserLogins: []WxUserLogin{
+				{WxID: "wxid_a", Status: UserStatusRelogin, UpdateTime: daysAgo(5)},
+			}}},
+		},
+		{
+			name: "offline user sent recently",
+			robots: []WxRobotConfig{{ID: 1, UpdateTime: daysAgo(40), UserLogins: []WxUserLogin{
+				{WxID: "wxid_a", Status: UserStatusRisk, UpdateTime: daysAgo(40)},
+			}}},
+			lastSend: map[string]time.Time{"wxid_a": daysAgo(1)},
+		},
+		{
+			name: "ordered by last active",
+			robots: []WxRobotConfig{
+				{ID: 1, UpdateTime: daysAgo(35)},
+				{ID: 2, UpdateTime: daysAgo(90)},
+				{ID: 3, UpdateTime: daysAgo(60), UserLogins: []WxUserLogin{
+					{WxID: "wxid_c", Status: UserStatusRelogin, UpdateTime: daysAgo(60)},
+				}},
+			},
+			lastSend: map[string]time.Time{"wxid_c": daysAgo(50)},
+			want:     []uint{2, 3, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idle := filterIdleRobots(tt.robots, tt.lastSend, cutoff)
+			var got []uint
+			for _, robot := range idle {
+				got = append(got, robot.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("idle robots = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanupIdleRobots(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          CleanupIdleRobotsRequest
+		wantIdle     []uint // 按最近活动时间升序
+		wantAffected int64
+		wantEnabled  map[string]int  // 清理后各机器人的启用状态，不存在表示已删除
+		wantChanges  map[uint]string // 记录了enabled 1->0变更的机器人ID及变更来源
+	}{
+		{
+			name:        "preview",
+			req:         CleanupIdleRobotsRequest{Days: 30, Action: IdleRobotActionDelete},
+			wantIdle:    []uint{2, 1},
+			wantEnabled: map[string]int{"idle": 1, "idle-old": 1, "sent": 1, "online": 1},
+		},
+		{
+			name:         "disable",
+			req:          CleanupIdleRobotsRequest{Days: 30, Action: IdleRobotActionDisable, Confirm: true},
+			wantIdle:     []uint{2, 1},
+			wantAffected: 2,
+			wantEnabled:  map[string]int{"idle": 0, "idle-old": 0, "sent": 1, "online": 1},
+			wantChanges:  map[uint]string{1: RobotChangeSourceIdleDisable, 2: RobotChangeSourceIdleDisable},
+		},
+		{
+			name:         "delete selected",
+			req:          CleanupIdleRobotsRequest{Days: 30, Action: IdleRobotActionDelete, IDs: []uint{1, 3}, Confirm: true},
+			wantIdle:     []uint{1},
+			wantAffected: 1,
+			wantEnabled:  map[string]int{"idle-old": 1, "sent": 1, "online": 1},
+			wantChanges:  map[uint]string{1: RobotChangeSourceIdleDelete},
+		},
+		{
+			name:        "longer idle days",
+			req:         CleanupIdleRobotsRequest{Days: 60, Action: IdleRobotActionDisable},
+			wantIdle:    []uint{2},
+			wantEnabled: map[string]int{"idle": 1, "idle-old": 1, "sent": 1, "online": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, db := newTestService(t, nil)
+			now := time.Now()
+			// 机器人及其账号的最近修改时间（天前）、账号状态与最近发送时间（天前，0表示未发送）
+			fixtures := []struct {
+				desc      string
+				idleDays  int
+				status    int
+				sentDays  int
+				withUsers bool
+			}{
+				{desc: "idle", idleDays: 40, status: UserStatusRelogin, withUsers: true},
+				{desc: "idle-old", idleDays: 90},
+				{desc: "sent", idleDays: 40, status: UserStatusRelogin, sentDays: 2, withUsers: true},
+				{desc: "online", idleDays: 40, status: UserStatusNormal, withUsers: true},
+			}
+			for _, f := range fixtures {
+				robot := &WxRobotConfig{Address: "http://" + f.desc + ".invalid", Description: f.desc}
+				var users []*WxUserLogin
+				if f.withUsers {
+					users = append(users, &WxUserLogin{WxID: "wxid_" + f.desc})
+				}
+				createTestRobot(t, db, robot, users...)
+				db.Create(&WxAuthKey{RobotID: robot.ID, AuthKey: "auth_" + f.desc, Days: 30})
+				for _, user := range users {
+					db.Create(&WxUserStatusLog{UserID: user.ID, OldStatus: UserStatusNormal, NewStatus: f.status})
+				}
+
+				old := now.AddDate(0, 0, -f.idleDays)
+				db.Model(&WxRobotConfig{}).Where("id = ?", robot.ID).UpdateColumn("update_time", old)
+				for _, user := range users {
+					db.Model(&WxUserLogin{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{"status": f.status, "update_time": old})
+				}
+				if f.sentDays > 0 {
+					db.Create(&WxSendAudit{SenderWxID: "wxid_" + f.desc, ToUserName: "g@chatroom", MsgType: SendAuditTypeText,
+						ContentHash: "hash", CreateTime: now.AddDate(0, 0, -f.sentDays)})
+				}
+			}
+
+			resp, err := svc.CleanupIdleRobots(&tt.req, RobotChangeOperator{})
+			if err != nil {
+				t.Fatalf("CleanupIdleRobots: %v", err)
+			}
+			var gotIdle []uint
+			for _, robot := range resp.Robots {
+				gotIdle = append(gotIdle, robot.ID)
+			}
+			if !reflect.DeepEqual(gotIdle, tt.wantIdle) || resp.Affected != tt.wantAffected || resp.Confirmed != tt.req.Confirm {
+				t.Fatalf("resp = %+v, want idle %v affected %d", resp, tt.wantIdle, tt.wantAffected)
+			}
+
+			var robots []WxRobotConfig
+			db.Find(&robots)
+			gotEnabled := make(map[string]int, len(robots))
+			for _, robot := range robots {
+				gotEnabled[robot.Description] = robot.Enabled
+			}
+			if !reflect.DeepEqual(gotEnabled, tt.wantEnabled) {
+				t.Fatalf("robots enabled = %v, want %v", gotEnabled, tt.wantEnabled)
+			}
+			if _, deleted := tt.wantEnabled["idle"]; !deleted {
+				var users, logs, keys int64
+				db.Model(&WxUserLogin{}).Where("wx_id = ?", "wxid_idle").Count(&users)
+				db.Model(&WxUserStatusLog{}).Count(&logs)
+				db.Model(&WxAuthKey{}).Where("auth_key = ?", "auth_idle").Count(&keys)
+				if users != 0 || logs != 2 || keys != 0 {
+					t.Fatalf("after delete users = %d, status logs = %d, auth keys = %d, want 0, 2, 0", users, logs, keys)
+				}
+			}
+
+			var changes []WxRobotConfigChange
+			db.Where("field = ?", "enabled").Find(&changes)
+			gotChanges := make(map[uint]string, len(changes))
+			for _, change := range changes {
+				if change.OldValue != "1" || change.NewValue != "0" {
+					t.Errorf("robot %d enabled change %s -> %s, want 1 -> 0", change.RobotID, change.OldValue, change.NewValue)
+				}
+				gotChanges[change.RobotID] = change.Source
+			}
+			if len(gotChanges) == 0 {
+				gotChanges = nil
+			}
+			if !reflect.DeepEqual(gotChanges, tt.wantChanges) {
+				t.Fatalf("config changes = %v, want %v", gotChanges, tt.wantChanges)
+			}
+		})
+	}
+}
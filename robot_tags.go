@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// joinRobotTags 去掉空白与重复标签后拼接为逗号分隔字符串存储
+func joinRobotTags(tags []string) string {
+	seen := make(map[string]bool, len(tags))
+	cleaned := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		cleaned = append(cleaned, tag)
+	}
+	return strings.Join(cleaned, ",")
+}
+
+// splitRobotTags 解析逗号分隔的标签
+func splitRobotTags(tags string) []string {
+	var result []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// robotSendCount 单个机器人的发送量
+type robotSendCount struct {
+	Total   int64
+	Success int64
+}
+
+// aggregateRobotTagStats 将各机器人的发送量按标签汇总，有多个标签的机器人计入每个标签，
+// 没有标签的机器人汇总到空标签；onlyTags 不为空时只统计这些标签，结果按发送总数降序
+func aggregateRobotTagStats(robotTags map[uint][]string, sends map[uint]robotSendCount, onlyTags []string) []RobotTagSendStats {
+	var only map[string]bool
+	if len(onlyTags) > 0 {
+		only = make(map[string]bool, len(onlyTags))
+		for _, tag := range onlyTags {
+			only[tag] = true
+		}
+	}
+
+	byTag := make(map[string]*RobotTagSendStats)
+	for robotID, tags := range robotTags {
+		if len(tags) == 0 {
+			tags = []string{""}
+		}
+		count := sends[robotID]
+		for _, tag := range tags {
+			if only != nil && !only[tag] {
+				continue
+			}
+			stats, ok := byTag[tag]
+			if !ok {
+				stats = &RobotTagSendStats{Tag: tag}
+				byTag[tag] = stats
+			}
+			stats.RobotCount++
+			stats.Total += count.Total
+			stats.Success += count.Success
+		}
+	}
+
+	result := make([]RobotTagSendStats, 0, len(byTag))
+	for _, stats := range byTag {
+		stats.Failed = stats.Total - stats.Success
+		if stats.Total > 0 {
+			stats.SuccessRate = math.Round(float64(stats.Success)/float64(stats.Total)*10000) / 100
+		}
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Tag < result[j].Tag
+	})
+	return result
+}
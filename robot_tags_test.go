@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJoinRobotTags(t *testing.T) {
+	tests := []struct {
+		name      string
+		tags      []string
+		want      string
+		wantSplit []string
+	}{
+		{name: "empty", tags: nil, want: ""},
+		{name: "trimmed", tags: []string{" 华东 ", "大客户"}, want: "华东,大客户", wantSplit: []string{"华东", "大客户"}},
+		{name: "duplicates and blanks dropped", tags: []string{"华东", "", "华东", "  "}, want: "华东", wantSplit: []string{"华东"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := joinRobotTags(tt.tags)
+			if got != tt.want {
+				t.Fatalf("joinRobotTags = %q, want %q", got, tt.want)
+			}
+			if split := splitRobotTags(got); !reflect.DeepEqual(split, tt.wantSplit) {
+				t.Fatalf("splitRobotTags = %v, want %v", split, tt.wantSplit)
+			}
+		})
+	}
+}
+
+func TestGetRobotTagSendStats(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	robots := []struct {
+		robot *WxRobotConfig
+		wxID  string
+	}{
+		{&WxRobotConfig{Address: "http://r1.invalid", OwnerID: 1, Tags: "华东,大客户"}, "wxid_a"},
+		{&WxRobotConfig{Address: "http://r2.invalid", OwnerID: 1, Tags: "华东"}, "wxid_b"},
+		{&WxRobotConfig{Address: "http://r3.invalid", OwnerID: 1}, "wxid_c"},
+		{&WxRobotConfig{Address: "http://r4.invalid", OwnerID: 2, Tags: "华南"}, "wxid_d"},
+	}
+	for _, r := range robots {
+		createTestRobot(t, db, r.robot, &WxUserLogin{WxID: r.wxID, Token: "token-" + r.wxID})
+	}
+	audits := []struct {
+		wxID    string
+		ownerID uint
+		success []bool
+	}{
+		{"wxid_a", 1, []bool{true, true, false}},
+		{"wxid_b", 1, []bool{true}},
+		{"wxid_c", 1, []bool{false, false}},
+		{"wxid_d", 2, []bool{true, true, true, true}},
+		{"wxid_unknown", 1, []bool{true}}, // 找不到所属机器人的发送账号不计入
+	}
+	for _, a := range audits {
+		for _, success := range a.success {
+			db.Create(&WxSendAudit{SenderWxID: a.wxID, OwnerID: a.ownerID, ToUserName: "g@chatroom", MsgType: SendAuditTypeText, Success: success})
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  RobotTagStatsRequest
+		want []RobotTagSendStats
+	}{
+		{
+			name: "by owner",
+			req:  RobotTagStatsRequest{OwnerID: 1},
+			want: []RobotTagSendStats{
+				{Tag: "华东", RobotCount: 2, Total: 4, Success: 3, Failed: 1, SuccessRate: 75},
+				{Tag: "大客户", RobotCount: 1, Total: 3, Success: 2, Failed: 1, SuccessRate: 66.67},
+				{Tag: "", RobotCount: 1, Total: 2, Success: 0, Failed: 2, SuccessRate: 0},
+			},
+		},
+		{
+			name: "all owners",
+			req:  RobotTagStatsRequest{},
+			want: []RobotTagSendStats{
+				{Tag: "华东", RobotCount: 2, Total: 4, Success: 3, Failed: 1, SuccessRate: 75},
+				{Tag: "华南", RobotCount: 1, Total: 4, Success: 4, Failed: 0, SuccessRate: 100},
+				{Tag: "大客户", RobotCount: 1, Total: 3, Success: 2, Failed: 1, SuccessRate: 66.67},
+				{Tag: "", RobotCount: 1, Total: 2, Success: 0, Failed: 2, SuccessRate: 0},
+			},
+		},
+		{
+			name: "selected tags",
+			req:  RobotTagStatsRequest{OwnerID: 1, Tags: "大客户, 华南"},
+			want: []RobotTagSendStats{
+				{Tag: "大客户", RobotCount: 1, Total: 3, Success: 2, Failed: 1, SuccessRate: 66.67},
+			},
+		},
+		{
+			name: "no sends in range",
+			req:  RobotTagStatsRequest{OwnerID: 1, StartTime: FormatTime(time.Now().Add(time.Hour))},
+			want: []RobotTagSendStats{
+				{Tag: "", RobotCount: 1},
+				{Tag: "华东", RobotCount: 2},
+				{Tag: "大客户", RobotCount: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.GetRobotTagSendStats(tt.req)
+			if err != nil {
+				t.Fatalf("GetRobotTagSendStats: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("stats = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+)
+
+func TestRobotTimeoutValidation(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name    string
+		timeout int
+		wantErr bool
+	}{
+		{name: "global default", timeout: 0},
+		{name: "custom", timeout: 60},
+		{name: "upper bound", timeout: 300},
+		{name: "negative", timeout: -1, wantErr: true},
+		{name: "too large", timeout: 301, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs := map[string]interface{}{
+				"create": &CreateRobotRequest{Address: "http://robot.invalid", AdminKey: "key", OwnerID: 1, TimeoutSeconds: tt.timeout},
+				"update": &UpdateRobotRequest{Address: "http://robot.invalid", AdminKey: "key", OwnerID: 1, TimeoutSeconds: tt.timeout},
+				"patch":  &PatchRobotRequest{TimeoutSeconds: intPtr(tt.timeout)},
+			}
+			for kind, req := range reqs {
+				err := binding.Validator.ValidateStruct(req)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("%s timeout_seconds=%d: err = %v, wantErr %v", kind, tt.timeout, err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestWxAPIClientRobotTimeout(t *testing.T) {
+	client := NewWxAPIClient(WxAPIConfig{Timeout: 30 * time.Second}, zap.NewNop(), newTestAddressGuard(t, false))
+
+	tests := []struct {
+		name  string
+		robot *WxRobotConfig
+		want  time.Duration
+	}{
+		{name: "robot timeout", robot: &WxRobotConfig{TimeoutSeconds: 5}, want: 5 * time.Second},
+		{name: "max timeout", robot: &WxRobotConfig{TimeoutSeconds: 300}, want: 300 * time.Second},
+		{name: "zero falls back to default", robot: &WxRobotConfig{}, want: 30 * time.Second},
+		{name: "no robot", want: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := client.timeoutFor(withRobotTimeout(context.Background(), tt.robot)); got != tt.want {
+				t.Fatalf("timeoutFor = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWxAPIClientRobotTimeoutApplied(t *testing.T) {
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.GetLoginStatus: func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			jsonHandler(map[string]interface{}{"Code": 200})(w, r)
+		},
+	})
+
+	client := NewWxAPIClient(WxAPIConfig{
+		Timeout: 50 * time.Millisecond,
+		Retry:   WxAPIRetryConfig{MaxAttempts: 1},
+	}, zap.NewNop(), newTestAddressGuard(t, false))
+
+	// 同一地址的两个机器人配置，超时互不影响
+	tests := []struct {
+		name    string
+		robot   *WxRobotConfig
+		wantErr bool
+	}{
+		{name: "robot timeout", robot: &WxRobotConfig{ID: 1, Address: server.URL, TimeoutSeconds: 1}},
+		{name: "global default timeout", robot: &WxRobotConfig{ID: 2, Address: server.URL}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetLoginStatus(withRobotTimeout(context.Background(), tt.robot), tt.robot.Address, "token")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetLoginStatus(robot %d) = %v, wantErr %v", tt.robot.ID, err, tt.wantErr)
+			}
+		})
+	}
+}
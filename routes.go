@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,6 +14,7 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // 响应工具函数
@@ -42,20 +46,87 @@ func (rm *RouterManager) internalErrorResponse(c *gin.Context, message string) {
 	rm.errorResponse(c, http.StatusInternalServerError, message)
 }
 
+// sendFailedResponse 消息发送失败响应，data中返回失败分类
+func (rm *RouterManager) sendFailedResponse(c *gin.Context, message string, err error) {
+	c.JSON(http.StatusInternalServerError, APIResponse{
+		Code:    -1,
+		Message: message + ": " + err.Error(),
+		Data:    newSendErrorInfo(err),
+	})
+}
+
+// sendFailedWithRetry 消息发送失败响应，可重试的失败先加入后台重试队列，data中返回重试记录ID
+func (rm *RouterManager) sendFailedWithRetry(c *gin.Context, message, msgType, toUserName string, payload interface{}, err error) {
+	// 入队失败时服务层已记录日志，仍按普通失败响应
+	scoped, _ := scopedOwner(c)
+	retryID, _ := rm.service.EnqueueSendRetry(scoped, msgType, toUserName, payload, err)
+	info := newSendErrorInfo(err)
+	info.RetryID = retryID
+	if retryID > 0 {
+		message += "（已加入重试队列）"
+	}
+
+	c.JSON(http.StatusInternalServerError, APIResponse{
+		Code:    -1,
+		Message: message + ": " + err.Error(),
+		Data:    info,
+	})
+}
+
+// notifySendResult 发送结果回调，未指定callback_url时忽略
+func (rm *RouterManager) notifySendResult(callbackURL, event, toUserName string, data interface{}, sendErr error) {
+	if callbackURL == "" {
+		return
+	}
+
+	payload := SendCallbackPayload{
+		Event:      event,
+		ToUserName: toUserName,
+		Success:    sendErr == nil,
+		Message:    "发送成功",
+		Data:       data,
+		Timestamp:  time.Now().Unix(),
+	}
+	if sendErr != nil {
+		payload.Message = sendErr.Error()
+	}
+
+	rm.callbackNotifier.Notify(callbackURL, payload)
+}
+
 // RouterManager 路由管理器
 type RouterManager struct {
+	cfg                 *Config
 	logger              *zap.Logger
 	service             WxRobotService
 	messageSendStrategy MessageSendStrategy
+	callbackNotifier    CallbackNotifier
+	addressGuard        *AddressGuard // 调用方传入的回调地址的SSRF防护
+	broadcastTasks      *BroadcastTaskManager
+	qrCodeLimiter       *windowLimiter
+	bodyLimits          map[string]int64 // 单独设置请求体上限(MB)的路由
+	groupSync           GroupSyncScheduler
 }
 
 // NewRouterManager 创建路由管理器
-func NewRouterManager(logger *zap.Logger, service WxRobotService) *RouterManager {
+func NewRouterManager(cfg *Config, logger *zap.Logger, service WxRobotService, groupSync GroupSyncScheduler) (*RouterManager, error) {
+	addressGuard, err := NewAddressGuard(cfg.Security)
+	if err != nil {
+		return nil, fmt.Errorf("初始化地址防护失败: %w", err)
+	}
+
 	return &RouterManager{
+		cfg:                 cfg,
 		logger:              logger,
 		service:             service,
 		messageSendStrategy: NewRandomMessageSendStrategy(), // 默认使用随机策略
-	}
+		callbackNotifier:    NewCallbackNotifier(cfg.Callback, addressGuard, logger),
+		addressGuard:        addressGuard,
+		broadcastTasks:      NewBroadcastTaskManager(logger),
+		qrCodeLimiter:       newWindowLimiter(cfg.Security.QRCodeLimit, cfg.Security.QRCodeWindow),
+		bodyLimits:          make(map[string]int64),
+		groupSync:           groupSync,
+	}, nil
 }
 
 // InitRoutes 初始化路由
@@ -70,10 +141,18 @@ func (rm *RouterManager) InitRoutes(cfg *Config) *gin.Engine {
 	// 中间件
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
+	router.Use(runtimeStats.Middleware())
+	router.Use(rm.bodyLimit(cfg.Server.MaxBodyMB))
 
 	// 健康检查
 	router.GET("/health", rm.healthCheck)
 
+	// 版本信息
+	router.GET("/version", rm.version)
+
+	// 运行时长与累计计数
+	router.GET("/admin/stats", rm.getRuntimeStats)
+
 	// Swagger文档路由 - 根据配置决定是否启用
 	if cfg.Swagger.Enable {
 		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -83,65 +162,182 @@ func (rm *RouterManager) InitRoutes(cfg *Config) *gin.Engine {
 		rm.logger.Info("Swagger文档已禁用")
 	}
 
-	// API路由组
-	apiV1 := router.Group("/api/wx/v1")
+	// 管理接口鉴权
+	adminAuth := rm.requireAdmin()
+	// 管理员或租户鉴权，租户只能访问本公司数据
+	ownerAuth := rm.requireAdminOrOwner()
+
+	// 数据库迁移版本（需鉴权）
+	router.GET("/admin/migrations", adminAuth, rm.getMigrationStatus)
+
+	// API路由组，带API Key的请求限定只能访问Key绑定公司的数据
+	apiV1 := router.Group("/api/wx/v1", rm.ownerScope())
 	{
 		// 微信机器人配置相关接口
 		robots := apiV1.Group("/robots")
 		{
-			robots.GET("/", rm.getRobotList)               // 获取机器人列表
-			robots.POST("/", rm.createRobot)               // 创建机器人配置
-			robots.GET("/:id", rm.getRobotById)            // 获取单个机器人信息
-			robots.PUT("/:id", rm.updateRobot)             // 修改机器人配置
-			robots.GET("/:id/health", rm.checkRobotHealth) // 检查机器人健康状态
+			robots.GET("/", rm.getRobotList)                                   // 获取机器人列表
+			robots.POST("/", rm.createRobot)                                   // 创建机器人配置
+			robots.GET("/:id", rm.getRobotById)                                // 获取单个机器人信息
+			robots.PUT("/:id", rm.updateRobot)                                 // 修改机器人配置
+			robots.PATCH("/:id", rm.patchRobot)                                // 部分更新机器人配置
+			robots.GET("/:id/health", rm.checkRobotHealth)                     // 检查机器人健康状态
+			robots.GET("/:id/summary", rm.getRobotSummary)                     // 机器人用户状态概览
+			robots.GET("/:id/changes", rm.getRobotConfigChanges)               // 配置变更历史
+			robots.GET("/:id/auth-keys", ownerAuth, rm.getRobotAuthKeys)       // 已生成的授权码及分配状态（需鉴权）
+			robots.POST("/:id/rotate-admin-key", adminAuth, rm.rotateAdminKey) // 轮换管理密钥（需鉴权）
+			robots.GET("/health", rm.checkRobotsHealth)                        // 批量检查机器人健康状态
+			robots.POST("/batch-status", ownerAuth, rm.batchSetRobotStatus)    // 批量启用/禁用机器人（需鉴权）
+			robots.GET("/idle", ownerAuth, rm.getIdleRobots)                   // 查询闲置机器人（需鉴权）
+			robots.POST("/idle/cleanup", ownerAuth, rm.cleanupIdleRobots)      // 批量禁用/删除闲置机器人（需鉴权）
 		}
 
 		// 微信用户登录相关接口
 		users := apiV1.Group("/users")
 		{
-			users.GET("/robot/:robotId", rm.getUsersByRobot)                 // 获取指定机器人的用户列表
-			users.POST("/authorize", rm.authorizeUser)                       // 获取授权信息
-			users.POST("/qrcode", rm.getQRCode)                              // 获取二维码
-			users.GET("/status/:robotId/:token", rm.checkLoginStatus)        // 检查登录状态
-			users.POST("/save", rm.saveUser)                                 // 保存用户数据
-			users.DELETE("/:id", rm.deleteUser)                              // 删除用户
-			users.GET("/login-status/:id", rm.getLoginStatus)                // 获取在线状态
-			users.POST("/message-bot-status/:id", rm.updateMessageBotStatus) // 更新消息机器人状态
+			users.GET("/robot/:robotId", rm.getUsersByRobot)                  // 获取指定机器人的用户列表
+			users.GET("/search", rm.searchUsers)                              // 按关键字搜索用户
+			users.GET("/need-relogin", rm.listNeedReloginUsers)               // 需重新登录的用户
+			users.POST("/need-relogin/qrcodes", rm.batchReloginQRCodes)       // 批量获取重登二维码
+			users.GET("/by-token", adminAuth, rm.getUserByToken)              // 按token反查用户（需鉴权）
+			users.POST("/by-token", adminAuth, rm.getUserByToken)             // 按token反查用户，token放在body中（需鉴权）
+			users.POST("/authorize", rm.authorizeUser)                        // 获取授权信息
+			users.POST("/qrcode", rm.getQRCode)                               // 获取二维码
+			users.GET("/qrcode/image", rm.getQRCodeImage)                     // 获取二维码图片
+			users.GET("/status/:robotId/:token", rm.checkLoginStatus)         // 检查登录状态
+			users.GET("/status/:robotId/:token/stream", rm.streamLoginStatus) // 实时推送登录状态(SSE)
+			users.POST("/save", rm.saveUser)                                  // 保存用户数据
+			users.DELETE("/:id", rm.deleteUser)                               // 删除用户
+			users.GET("/login-status/:id", rm.getLoginStatus)                 // 获取在线状态
+			users.GET("/:id/auth-info", rm.getUserAuthInfo)                   // 获取授权概览
+			users.GET("/:id/status-history", rm.getUserStatusHistory)         // 获取状态变更历史
+			users.POST("/:id/sync-groups", rm.syncUserGroups)                 // 手动同步群组
+			users.PUT("/:id/remark", rm.updateUserRemark)                     // 更新用户备注
+			users.PUT("/:id/auto-renew", rm.updateUserAutoRenew)              // 设置到期自动续期
+			users.PUT("/:id/signature", rm.updateUserSignature)               // 更新用户签名
+			users.POST("/message-bot-status/:id", rm.updateMessageBotStatus)  // 更新消息机器人状态
 		}
 
 		// 授权管理相关接口
 		auth := apiV1.Group("/auth")
 		{
-			auth.POST("/extend/:robotId", rm.extendAuth) // 延期授权
+			auth.POST("/extend/:robotId", rm.extendAuth)   // 延期授权
+			auth.POST("/extend-batch", rm.batchExtendAuth) // 批量延期授权
 		}
 
 		// 消息发送相关接口
 		messages := apiV1.Group("/messages/group")
 		{
 			messages.POST("/send-text", rm.sendText)               // 发送文本消息
+			messages.POST("/send-text-multi", rm.sendTextMulti)    // 同一文本批量发送到多个群
 			messages.POST("/send-image", rm.sendImage)             // 发送图片消息
+			messages.POST("/send-voice", rm.sendVoice)             // 发送语音消息
+			messages.POST("/send-video", rm.sendVideo)             // 发送视频消息
+			messages.POST("/send-link", rm.sendLink)               // 发送链接卡片消息
+			messages.POST("/broadcast", rm.broadcastToGroups)      // 同步群发到多个群
 			messages.POST("/send-text-image", rm.sendTextAndImage) // 发送文字和图片
 			messages.POST("/set-strategy", rm.setMessageStrategy)  // 设置消息发送策略
+			messages.GET("/search", rm.searchGroupMessages)        // 按关键词搜索群消息
+		}
+
+		// 消息回调相关接口
+		apiV1.POST("/messages/callback", rm.messageCallback) // 接收群消息回调
+
+		// 群发任务相关接口
+		broadcast := apiV1.Group("/messages/broadcast")
+		{
+			broadcast.POST("", rm.submitBroadcastTask)             // 提交异步群发任务
+			broadcast.GET("/:taskId", rm.getBroadcastTaskProgress) // 查询群发任务进度
+		}
+
+		// 请求体包含base64图片、语音、视频的接口放宽请求体大小限制
+		for _, path := range []string{
+			messages.BasePath() + "/send-image",
+			messages.BasePath() + "/send-voice",
+			messages.BasePath() + "/send-video",
+			messages.BasePath() + "/broadcast",
+			messages.BasePath() + "/send-text-image",
+			broadcast.BasePath(),
+		} {
+			rm.bodyLimits[path] = cfg.Server.ImageMaxBodyMB
+		}
+
+		// 按追踪ID查询发送批次结果
+		apiV1.GET("/messages/trace/:traceId", rm.getSendTrace)
+
+		// 发送内容审计接口（需管理员令牌，或租户API Key只查看本公司记录）
+		audits := apiV1.Group("/audits", ownerAuth)
+		{
+			audits.GET("", rm.listSendAudits)                 // 分页查询审计记录
+			audits.GET("/export", rm.exportSendAudits)        // 导出审计记录为CSV
+			audits.GET("/tag-stats", rm.getRobotTagSendStats) // 按机器人标签对比发送量
 		}
 
 		// 群组管理相关接口
 		groups := apiV1.Group("/groups")
 		{
-			groups.GET("/user/:wxId", rm.getGroupsByWxID) // 获取指定用户的群组列表
-			groups.GET("/search", rm.searchGroupsByName)  // 按群名称模糊搜索群组
+			groups.GET("", rm.listGroups)                                 // 分页查询全部群组
+			groups.GET("/user/:wxId", rm.getGroupsByWxID)                 // 获取指定用户的群组列表
+			groups.GET("/search", rm.searchGroupsByName)                  // 按群名称模糊搜索群组
+			groups.GET("/export", rm.exportGroups)                        // 导出群组为CSV
+			groups.GET("/blacklist", rm.listGroupBlacklist)               // 查询群黑名单
+			groups.POST("/blacklist", rm.addGroupBlacklist)               // 添加群黑名单
+			groups.DELETE("/blacklist/:groupId", rm.removeGroupBlacklist) // 移除群黑名单
+			groups.GET("/changes", rm.getGroupChanges)                    // 查询时间段内新增/流失的群
+			groups.GET("/bot-coverage", rm.getBotCoverageGaps)            // 缺少消息机器人覆盖的群及分配建议
+			groups.GET("/:groupId/bots", rm.getGroupBots)                 // 查询可服务该群的消息机器人
 		}
 
+		// 可用消息机器人
+		apiV1.GET("/message-bots", rm.getAvailableMessageBots)
+
 		// 账单统计相关接口
 		bills := apiV1.Group("/bills")
 		{
-			bills.GET("/stats", rm.getBillStatistics) // 获取账单统计信息
-			bills.GET("/list", rm.getBillList)        // 查询账单列表
+			bills.GET("/stats", rm.getBillStatistics)                     // 获取账单统计信息
+			bills.GET("/list", rm.getBillList)                            // 查询账单列表
+			bills.GET("/group/:groupId/recent", rm.getRecentBillsByGroup) // 查询群最近账单
 		}
 	}
 
 	return router
 }
 
+// version 返回应用版本与构建信息
+func (rm *RouterManager) version(c *gin.Context) {
+	rm.successResponse(c, "查询成功", getVersionInfo(rm.cfg.App))
+}
+
+// getRuntimeStats 返回服务运行时长与累计计数
+// @Summary 运行统计
+// @Description 返回服务启动时间、运行时长，以及启动以来累计处理的请求数、发送成功的消息数和错误数
+// @Tags system
+// @Produce json
+// @Success 200 {object} APIResponse{data=RuntimeStatsInfo} "查询成功"
+// @Router /admin/stats [get]
+func (rm *RouterManager) getRuntimeStats(c *gin.Context) {
+	rm.successResponse(c, "查询成功", runtimeStats.Snapshot())
+}
+
+// getMigrationStatus 查询数据库迁移版本
+// @Summary 数据库迁移版本
+// @Description 返回数据库当前迁移版本、程序登记的最新版本、已执行的迁移记录与待执行的迁移
+// @Tags system
+// @Produce json
+// @Success 200 {object} APIResponse{data=MigrationStatus} "查询成功"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Failure 500 {object} APIResponse "查询失败"
+// @Router /admin/migrations [get]
+func (rm *RouterManager) getMigrationStatus(c *gin.Context) {
+	status, err := rm.service.GetMigrationStatus()
+	if err != nil {
+		rm.logger.Error("查询数据库迁移版本失败", zap.Error(err))
+		rm.internalErrorResponse(c, "查询数据库迁移版本失败")
+		return
+	}
+	rm.successResponse(c, "查询成功", status)
+}
+
 // healthCheck 健康检查
 func (rm *RouterManager) healthCheck(c *gin.Context) {
 	// 检查各个组件的健康状态
@@ -181,15 +377,39 @@ func (rm *RouterManager) healthCheck(c *gin.Context) {
 
 // getRobotList 获取机器人列表
 // @Summary 获取机器人列表
-// @Description 获取所有机器人配置及其关联的用户信息
+// @Description 获取机器人配置及其关联的用户信息，支持按创建时间范围过滤和按id/创建时间/更新时间排序
 // @Tags robots
 // @Accept json
 // @Produce json
+// @Param create_time_start query string false "创建时间开始，格式：yyyy-mm-dd hh:mi:ss"
+// @Param create_time_end query string false "创建时间结束，格式：yyyy-mm-dd hh:mi:ss"
+// @Param sort_by query string false "排序字段，默认id" Enums(id, create_time, update_time)
+// @Param order query string false "排序方向，默认asc" Enums(asc, desc)
+// @Param owner_id query uint false "所属公司ID，不传查询全部"
 // @Success 200 {object} APIResponse{data=[]WxRobotConfig} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
 // @Failure 500 {object} APIResponse "内部服务器错误"
 // @Router /robots/ [get]
 func (rm *RouterManager) getRobotList(c *gin.Context) {
-	robots, err := rm.service.GetRobotList()
+	var req RobotListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+	for _, value := range []string{req.CreateTimeStart, req.CreateTimeEnd} {
+		if value == "" {
+			continue
+		}
+		if _, err := ParseTime(value); err != nil {
+			rm.badRequestResponse(c, "时间格式错误，应为 yyyy-mm-dd hh:mi:ss")
+			return
+		}
+	}
+
+	robots, err := rm.service.GetRobotList(req)
 	if err != nil {
 		rm.internalErrorResponse(c, "查询机器人列表失败")
 		return
@@ -198,6 +418,115 @@ func (rm *RouterManager) getRobotList(c *gin.Context) {
 	rm.successResponse(c, "查询成功", robots)
 }
 
+// batchSetRobotStatus 批量启用/禁用机器人
+// @Summary 批量启用/禁用机器人
+// @Description 按所属公司或机器人ID列表批量启用/禁用机器人（如客户欠费时全部停用），禁用后其账号不再作为消息机器人发送；owner_id与ids同时提供时取交集，返回状态实际变化的数量，变化的机器人记录到配置变更历史；需在请求头携带 X-Admin-Token 或本公司的 X-API-Key
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string false "管理员令牌，与X-API-Key二选一"
+// @Param X-API-Key header string false "租户API Key，只能操作本公司机器人"
+// @Param request body BatchRobotStatusRequest true "请求参数"
+// @Success 200 {object} APIResponse{data=BatchRobotStatusResponse} "操作成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /robots/batch-status [post]
+func (rm *RouterManager) batchSetRobotStatus(c *gin.Context) {
+	var req BatchRobotStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.OwnerID == 0 && len(req.IDs) == 0 {
+		rm.badRequestResponse(c, "owner_id和ids至少提供一个")
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+
+	resp, err := rm.service.BatchSetRobotStatus(&req, robotChangeOperator(c))
+	if err != nil {
+		rm.internalErrorResponse(c, "批量启用/禁用机器人失败")
+		return
+	}
+
+	rm.successResponse(c, "操作成功", resp)
+}
+
+// getIdleRobots 查询闲置机器人
+// @Summary 查询闲置机器人
+// @Description 列出没有在线账号且超过指定天数无活动（配置修改、账号状态变化、账号发送消息）的机器人，按最近活动时间升序；需在请求头携带 X-Admin-Token 或本公司的 X-API-Key
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string false "管理员令牌，与X-API-Key二选一"
+// @Param X-API-Key header string false "租户API Key，只能查询本公司机器人"
+// @Param owner_id query uint false "所属公司ID，不传查询全部"
+// @Param days query int false "超过多少天无活动视为闲置，默认30"
+// @Success 200 {object} APIResponse{data=[]IdleRobot} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /robots/idle [get]
+func (rm *RouterManager) getIdleRobots(c *gin.Context) {
+	var query IdleRobotQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &query.OwnerID) {
+		return
+	}
+
+	robots, err := rm.service.GetIdleRobots(query.OwnerID, query.Days)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询闲置机器人失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", robots)
+}
+
+// cleanupIdleRobots 批量禁用/删除闲置机器人
+// @Summary 批量禁用/删除闲置机器人
+// @Description 批量禁用或删除闲置机器人，删除时同时删除其下账号、账号状态日志及授权码记录，禁用和删除均记录到配置变更历史；confirm为false时只返回将被清理的机器人，确认后传confirm=true执行；执行时重新判断是否闲置，ids中已恢复使用的机器人不会被清理；需在请求头携带 X-Admin-Token 或本公司的 X-API-Key
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string false "管理员令牌，与X-API-Key二选一"
+// @Param X-API-Key header string false "租户API Key，只能操作本公司机器人"
+// @Param request body CleanupIdleRobotsRequest true "请求参数"
+// @Success 200 {object} APIResponse{data=CleanupIdleRobotsResponse} "操作成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /robots/idle/cleanup [post]
+func (rm *RouterManager) cleanupIdleRobots(c *gin.Context) {
+	var req CleanupIdleRobotsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+
+	resp, err := rm.service.CleanupIdleRobots(&req, robotChangeOperator(c))
+	if err != nil {
+		rm.internalErrorResponse(c, "清理闲置机器人失败")
+		return
+	}
+
+	if !resp.Confirmed {
+		rm.successResponse(c, "预览成功，确认后传confirm=true执行", resp)
+		return
+	}
+	rm.successResponse(c, "清理完成", resp)
+}
+
 // createRobot 创建机器人配置
 // @Summary 创建机器人配置
 // @Description 创建新的微信机器人配置
@@ -221,14 +550,26 @@ func (rm *RouterManager) createRobot(c *gin.Context) {
 		rm.badRequestResponse(c, "机器人地址、管理密钥和所属公司ID为必填项")
 		return
 	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+
+	// 校验机器人地址，拒绝内网/本机等受限地址
+	if err := rm.service.ValidateRobotAddress(req.Address); err != nil {
+		rm.badRequestResponse(c, "机器人地址不可用: "+err.Error())
+		return
+	}
 
 	// 构建 WxRobotConfig 对象
 	robot := WxRobotConfig{
-		Address:     req.Address,
-		AdminKey:    req.AdminKey,
-		OwnerID:     req.OwnerID,
-		Description: req.Description,
-		AdminUsers:  strings.Join(req.AdminUsers, ","), // 将数组转为逗号分隔字符串
+		Address:        req.Address,
+		AdminKey:       req.AdminKey,
+		OwnerID:        req.OwnerID,
+		Description:    req.Description,
+		AdminUsers:     strings.Join(req.AdminUsers, ","), // 将数组转为逗号分隔字符串
+		TimeoutSeconds: req.TimeoutSeconds,
+		HealthPath:     normalizeHealthPath(req.HealthPath),
+		Tags:           joinRobotTags(req.Tags),
 	}
 
 	if err := rm.service.CreateRobot(&robot); err != nil {
@@ -269,6 +610,9 @@ func (rm *RouterManager) getRobotById(c *gin.Context) {
 		rm.notFoundResponse(c, "机器人不存在")
 		return
 	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
 
 	rm.successResponse(c, "查询成功", robot)
 }
@@ -311,6 +655,15 @@ func (rm *RouterManager) updateRobot(c *gin.Context) {
 		rm.badRequestResponse(c, "机器人地址、管理密钥和所属公司ID为必填项")
 		return
 	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+
+	// 校验机器人地址，拒绝内网/本机等受限地址
+	if err := rm.service.ValidateRobotAddress(req.Address); err != nil {
+		rm.badRequestResponse(c, "机器人地址不可用: "+err.Error())
+		return
+	}
 
 	// 检查机器人是否存在
 	existingRobot, err := rm.service.GetRobotByID(uint(robotId))
@@ -318,19 +671,94 @@ func (rm *RouterManager) updateRobot(c *gin.Context) {
 		rm.notFoundResponse(c, "机器人不存在")
 		return
 	}
+	if !rm.checkRobotOwner(c, existingRobot) {
+		return
+	}
 
 	// 构建更新的机器人配置对象
 	robot := WxRobotConfig{
-		ID:          uint(robotId),
-		Address:     req.Address,
-		AdminKey:    req.AdminKey,
-		OwnerID:     req.OwnerID,
-		Description: req.Description,
-		AdminUsers:  strings.Join(req.AdminUsers, ","), // 将数组转为逗号分隔字符串
-		CreateTime:  existingRobot.CreateTime,          // 保留创建时间
+		ID:             uint(robotId),
+		Address:        req.Address,
+		AdminKey:       req.AdminKey,
+		OwnerID:        req.OwnerID,
+		Description:    req.Description,
+		AdminUsers:     strings.Join(req.AdminUsers, ","), // 将数组转为逗号分隔字符串
+		TimeoutSeconds: req.TimeoutSeconds,
+		HealthPath:     normalizeHealthPath(req.HealthPath),
+		Tags:           joinRobotTags(req.Tags),
+		Enabled:        existingRobot.Enabled,    // 启用状态不在本接口修改
+		CreateTime:     existingRobot.CreateTime, // 保留创建时间
+	}
+
+	if err := rm.service.UpdateRobot(&robot, robotChangeOperator(c)); err != nil {
+		rm.internalErrorResponse(c, "修改机器人配置失败")
+		return
+	}
+
+	rm.successResponse(c, "修改成功", robot)
+}
+
+// patchRobot 部分更新机器人配置
+// @Summary 部分更新机器人配置
+// @Description 只更新请求中传入的字段，未传的字段保持不变；传空字符串可清空描述等可选字段
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param id path uint true "机器人ID"
+// @Param robot body PatchRobotRequest true "需要更新的字段"
+// @Success 200 {object} APIResponse{data=WxRobotConfig} "修改成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "机器人不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /robots/{id} [patch]
+func (rm *RouterManager) patchRobot(c *gin.Context) {
+	robotId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "机器人ID格式错误")
+		return
+	}
+
+	var req PatchRobotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.Address == nil && req.AdminKey == nil && req.OwnerID == nil &&
+		req.Description == nil && req.AdminUsers == nil && req.TimeoutSeconds == nil && req.HealthPath == nil &&
+		req.Enabled == nil && req.Tags == nil {
+		rm.badRequestResponse(c, "未提供需要更新的字段")
+		return
+	}
+
+	// 必填字段允许不传，但传了不能为空
+	if (req.Address != nil && *req.Address == "") || (req.AdminKey != nil && *req.AdminKey == "") ||
+		(req.OwnerID != nil && *req.OwnerID == 0) {
+		rm.badRequestResponse(c, "机器人地址、管理密钥和所属公司ID不能为空")
+		return
+	}
+	if !rm.checkRobotIDOwner(c, uint(robotId)) {
+		return
+	}
+	// 租户不能把机器人转给其他公司
+	if req.OwnerID != nil && !rm.checkOwnerScope(c, req.OwnerID) {
+		return
+	}
+
+	if req.Address != nil {
+		if err := rm.service.ValidateRobotAddress(*req.Address); err != nil {
+			rm.badRequestResponse(c, "机器人地址不可用: "+err.Error())
+			return
+		}
 	}
 
-	if err := rm.service.UpdateRobot(&robot); err != nil {
+	robot, err := rm.service.PatchRobot(uint(robotId), &req, robotChangeOperator(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rm.notFoundResponse(c, "机器人不存在")
+			return
+		}
 		rm.internalErrorResponse(c, "修改机器人配置失败")
 		return
 	}
@@ -338,6 +766,44 @@ func (rm *RouterManager) updateRobot(c *gin.Context) {
 	rm.successResponse(c, "修改成功", robot)
 }
 
+// getRobotConfigChanges 获取机器人配置变更历史
+// @Summary 获取机器人配置变更历史
+// @Description 查询通过修改、部分更新和轮换管理密钥接口产生的字段级变更记录，按时间倒序；管理密钥只记录变更事实，新旧值不返回明文
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param id path uint true "机器人ID"
+// @Success 200 {object} APIResponse{data=[]WxRobotConfigChange} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "机器人不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /robots/{id}/changes [get]
+func (rm *RouterManager) getRobotConfigChanges(c *gin.Context) {
+	robotId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "机器人ID格式错误")
+		return
+	}
+
+	robot, err := rm.service.GetRobotByID(uint(robotId))
+	if err != nil {
+		rm.notFoundResponse(c, "机器人不存在")
+		return
+	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
+
+	changes, err := rm.service.GetRobotConfigChanges(uint(robotId))
+	if err != nil {
+		rm.internalErrorResponse(c, "查询配置变更历史失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", changes)
+}
+
 // getUsersByRobot 获取指定机器人的用户列表
 // @Summary 获取机器人用户列表
 // @Description 获取指定机器人的所有用户登录信息
@@ -347,6 +813,8 @@ func (rm *RouterManager) updateRobot(c *gin.Context) {
 // @Param robotId path string true "机器人ID"
 // @Success 200 {object} APIResponse{data=[]WxUserLogin} "查询成功"
 // @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "机器人不存在"
 // @Failure 500 {object} APIResponse "内部服务器错误"
 // @Router /users/robot/{robotId} [get]
 func (rm *RouterManager) getUsersByRobot(c *gin.Context) {
@@ -360,6 +828,15 @@ func (rm *RouterManager) getUsersByRobot(c *gin.Context) {
 		return
 	}
 
+	parsedRobotId, err := strconv.ParseUint(robotId, 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "机器人ID格式错误")
+		return
+	}
+	if !rm.checkRobotIDOwner(c, uint(parsedRobotId)) {
+		return
+	}
+
 	users, err := rm.service.GetUsersByRobot(robotId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, APIResponse{
@@ -383,16 +860,14 @@ func (rm *RouterManager) getUsersByRobot(c *gin.Context) {
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param request body object{robot_id=uint} true "请求参数"
-// @Success 200 {object} APIResponse{data=object{token=string,robot_id=uint}} "获取成功"
+// @Param request body AuthorizeUserRequest true "请求参数，count默认1最大20，days默认365最大365"
+// @Success 200 {object} APIResponse{data=object{token=string,tokens=[]string,robot_id=uint}} "获取成功"
 // @Failure 400 {object} APIResponse "参数错误"
 // @Failure 404 {object} APIResponse "机器人不存在"
 // @Failure 500 {object} APIResponse "内部服务器错误"
 // @Router /users/authorize [post]
 func (rm *RouterManager) authorizeUser(c *gin.Context) {
-	var req struct {
-		RobotID uint `json:"robot_id" binding:"required"`
-	}
+	var req AuthorizeUserRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, APIResponse{
@@ -402,6 +877,12 @@ func (rm *RouterManager) authorizeUser(c *gin.Context) {
 		})
 		return
 	}
+	if req.Count == 0 {
+		req.Count = DefaultAuthKeyCount
+	}
+	if req.Days == 0 {
+		req.Days = DefaultAuthKeyDays
+	}
 
 	// 检查机器人是否存在
 	robot, err := rm.service.GetRobotByID(req.RobotID)
@@ -413,9 +894,12 @@ func (rm *RouterManager) authorizeUser(c *gin.Context) {
 		})
 		return
 	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
 
 	// 调用微信机器人API获取授权token
-	authResp, err := rm.service.GenAuthKey(robot.Address, robot.AdminKey, 1, 365)
+	authResp, err := rm.service.GenAuthKey(c.Request.Context(), robot, req.Count, req.Days)
 	if err != nil {
 		rm.logger.Error("调用GenAuthKey失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, APIResponse{
@@ -437,32 +921,57 @@ func (rm *RouterManager) authorizeUser(c *gin.Context) {
 
 	authKey := authResp.Data[0]
 
+	rm.logger.Info("生成授权码成功",
+		zap.Uint("robot_id", req.RobotID),
+		zap.Int("count", len(authResp.Data)),
+		zap.Int("days", req.Days))
+
 	c.JSON(http.StatusOK, APIResponse{
 		Code:    0,
 		Message: "获取授权信息成功",
 		Data: map[string]interface{}{
 			"token":    authKey,
+			"tokens":   authResp.Data,
 			"robot_id": req.RobotID,
 		},
 	})
 }
 
+// checkQRCodeLimit 同一机器人+token获取二维码超过频率限制时返回429
+func (rm *RouterManager) checkQRCodeLimit(c *gin.Context, robotID uint, token string) bool {
+	allowed, retryAfter := rm.qrCodeLimiter.Allow(fmt.Sprintf("%d:%s", robotID, token))
+	if allowed {
+		return true
+	}
+
+	rm.logger.Warn("获取二维码过于频繁，已限流",
+		zap.Uint("robot_id", robotID),
+		zap.String("token", maskToken(token)),
+		zap.String("client_ip", c.ClientIP()))
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	rm.errorResponse(c, http.StatusTooManyRequests, fmt.Sprintf("获取二维码过于频繁，请%d秒后重试", seconds))
+	return false
+}
+
 // getQRCode 获取二维码
 // @Summary 获取登录二维码
-// @Description 生成微信登录二维码
+// @Description 生成微信登录二维码；check=true 时先尝试已登录设备的二次登录（无需扫码），失败回退为扫码登录
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param request body object{token=string,robot_id=uint} true "请求参数"
+// @Param request body object{token=string,robot_id=uint,check=bool} true "请求参数"
 // @Success 200 {object} APIResponse{data=QRCodeResponse} "获取成功"
 // @Failure 400 {object} APIResponse "参数错误"
 // @Failure 404 {object} APIResponse "机器人不存在"
+// @Failure 429 {object} APIResponse "获取过于频繁"
 // @Failure 500 {object} APIResponse "内部服务器错误"
 // @Router /users/qrcode [post]
 func (rm *RouterManager) getQRCode(c *gin.Context) {
 	var req struct {
 		Token   string `json:"token" binding:"required"`
 		RobotID uint   `json:"robot_id" binding:"required"`
+		Check   bool   `json:"check"` // 是否尝试二次登录
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -484,11 +993,15 @@ func (rm *RouterManager) getQRCode(c *gin.Context) {
 		})
 		return
 	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
+	if !rm.checkQRCodeLimit(c, robot.ID, req.Token) {
+		return
+	}
 
-	// 调用微信机器人API获取二维码
-	qrResp, err := rm.service.GetLoginQrCode(robot.Address, req.Token, false, "")
+	qrResponse, message, err := rm.loginQRCode(c.Request.Context(), robot, req.Token, req.Check)
 	if err != nil {
-		rm.logger.Error("调用GetLoginQrCode失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, APIResponse{
 			Code:    -1,
 			Message: "获取二维码失败: " + err.Error(),
@@ -497,46 +1010,145 @@ func (rm *RouterManager) getQRCode(c *gin.Context) {
 		return
 	}
 
-	qrResponse := QRCodeResponse{
-		QRCode:       qrResp.Data.QrCodeUrl,
-		Token:        req.Token,
-		ExpireTime:   time.Now().Add(5 * time.Minute).Unix(),
-		QrCodeBase64: qrResp.Data.QrCodeBase64,
-	}
-
 	c.JSON(http.StatusOK, APIResponse{
 		Code:    0,
-		Message: "获取二维码成功",
+		Message: message,
 		Data:    qrResponse,
 	})
 }
 
-// checkLoginStatus 检查登录状态（仅检查，不保存）
-// @Summary 检查登录状态
-// @Description 检查用户扫码登录状态
-// @Tags users
-// @Accept json
-// @Produce json
-// @Param robotId path string true "机器人ID"
-// @Param token path string true "授权token"
-// @Success 200 {object} APIResponse{data=LoginStatusResponse} "检查成功"
-// @Failure 400 {object} APIResponse "参数错误"
-// @Failure 404 {object} APIResponse "机器人不存在"
-// @Failure 500 {object} APIResponse "内部服务器错误"
-// @Router /users/status/{robotId}/{token} [get]
-func (rm *RouterManager) checkLoginStatus(c *gin.Context) {
-	robotIdStr := c.Param("robotId")
-	token := c.Param("token")
+// loginQRCode 获取登录二维码；check为true时先尝试已登录设备的二次登录（无需扫码），失败回退为扫码登录
+func (rm *RouterManager) loginQRCode(ctx context.Context, robot *WxRobotConfig, token string, check bool) (*QRCodeResponse, string, error) {
+	ctx = withRobotTimeout(ctx, robot)
+	// 二次登录：已登录过的设备无需扫码直接恢复，失败时回退扫码登录
+	if check {
+		checkResp, err := rm.service.GetLoginQrCode(ctx, robot.Address, token, true, "")
+		if err == nil {
+			qrResponse := &QRCodeResponse{
+				QRCode:       checkResp.Data.QrCodeUrl,
+				Token:        token,
+				ExpireTime:   time.Now().Add(5 * time.Minute).Unix(),
+				QrCodeBase64: checkResp.Data.QrCodeBase64,
+				LoginMode:    LoginModeQRCode,
+			}
+			message := "获取二维码成功"
+			if qrResponse.QRCode == "" && qrResponse.QrCodeBase64 == "" {
+				qrResponse.LoginMode = LoginModeRelogin
+				message = "二次登录请求成功，请查询登录状态"
+			}
+			return qrResponse, message, nil
+		}
 
-	if robotIdStr == "" || token == "" {
-		rm.badRequestResponse(c, "robotId和token不能为空")
-		return
+		rm.logger.Warn("二次登录失败，回退为扫码登录",
+			zap.Uint("robot_id", robot.ID),
+			zap.Error(err))
 	}
 
-	robotId, err := strconv.ParseUint(robotIdStr, 10, 32)
+	// 调用微信机器人API获取二维码
+	qrResp, err := rm.service.GetLoginQrCode(ctx, robot.Address, token, false, "")
 	if err != nil {
-		rm.badRequestResponse(c, "robotId参数错误")
-		return
+		rm.logger.Error("调用GetLoginQrCode失败", zap.Error(err))
+		return nil, "", err
+	}
+
+	return &QRCodeResponse{
+		QRCode:       qrResp.Data.QrCodeUrl,
+		Token:        token,
+		ExpireTime:   time.Now().Add(5 * time.Minute).Unix(),
+		QrCodeBase64: qrResp.Data.QrCodeBase64,
+		LoginMode:    LoginModeQRCode,
+	}, "获取二维码成功", nil
+}
+
+// getQRCodeImage 获取登录二维码图片
+// @Summary 获取登录二维码图片
+// @Description 生成微信登录二维码并直接返回图片二进制，便于<img src>直接引用
+// @Tags users
+// @Produce png
+// @Param token query string true "授权token"
+// @Param robot_id query uint true "机器人ID"
+// @Success 200 {file} binary "二维码图片"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 404 {object} APIResponse "机器人不存在"
+// @Failure 429 {object} APIResponse "获取过于频繁"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /users/qrcode/image [get]
+func (rm *RouterManager) getQRCodeImage(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		rm.badRequestResponse(c, "token不能为空")
+		return
+	}
+
+	robotId, err := strconv.ParseUint(c.Query("robot_id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "robot_id参数错误")
+		return
+	}
+
+	// 获取机器人信息
+	robot, err := rm.service.GetRobotByID(uint(robotId))
+	if err != nil {
+		rm.notFoundResponse(c, "机器人不存在")
+		return
+	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
+	if !rm.checkQRCodeLimit(c, robot.ID, token) {
+		return
+	}
+
+	// 调用微信机器人API获取二维码
+	qrResp, err := rm.service.GetLoginQrCode(withRobotTimeout(c.Request.Context(), robot), robot.Address, token, false, "")
+	if err != nil {
+		rm.logger.Error("调用GetLoginQrCode失败", zap.Error(err))
+		rm.internalErrorResponse(c, "获取二维码失败: "+err.Error())
+		return
+	}
+
+	imageData, err := decodeBase64Image(qrResp.Data.QrCodeBase64)
+	if err != nil {
+		rm.logger.Error("解码二维码图片失败", zap.Error(err))
+		rm.internalErrorResponse(c, "解码二维码图片失败: "+err.Error())
+		return
+	}
+
+	contentType := http.DetectContentType(imageData)
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = "image/png"
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, contentType, imageData)
+}
+
+// checkLoginStatus 检查登录状态（仅检查，不保存）
+// @Summary 检查登录状态
+// @Description 检查用户扫码登录状态，status：0未扫码 1已扫码待确认 2登录成功 3登录失败
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param robotId path string true "机器人ID"
+// @Param token path string true "授权token"
+// @Success 200 {object} APIResponse{data=LoginStatusResponse} "检查成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 404 {object} APIResponse "机器人不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /users/status/{robotId}/{token} [get]
+func (rm *RouterManager) checkLoginStatus(c *gin.Context) {
+	robotIdStr := c.Param("robotId")
+	token := c.Param("token")
+
+	if robotIdStr == "" || token == "" {
+		rm.badRequestResponse(c, "robotId和token不能为空")
+		return
+	}
+
+	robotId, err := strconv.ParseUint(robotIdStr, 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "robotId参数错误")
+		return
 	}
 
 	// 获取机器人信息
@@ -545,50 +1157,70 @@ func (rm *RouterManager) checkLoginStatus(c *gin.Context) {
 		rm.notFoundResponse(c, "机器人不存在")
 		return
 	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
 
 	// 调用微信机器人API检查登录状态
-	loginResp, err := rm.service.CheckLoginStatus(robot.Address, token)
+	loginResp, err := rm.service.CheckLoginStatus(withRobotTimeout(c.Request.Context(), robot), robot.Address, token)
 	if err != nil {
 		rm.logger.Error("调用CheckLoginStatus失败", zap.Error(err))
 		rm.internalErrorResponse(c, "检查登录状态失败: "+err.Error())
 		return
 	}
 
-	// 根据外部API响应的Code判断状态
-	var status LoginStatusResponse
+	rm.successResponse(c, "检查成功", loginStatusFromResponse(loginResp))
+}
+
+// loginStatusFromResponse 根据外部API响应的Code判断登录状态
+func loginStatusFromResponse(loginResp *CheckLoginStatusResponse) LoginStatusResponse {
 	switch loginResp.Code {
 	case 200:
-		// Code 200时还需要检查state字段，只有state为2才是真正的登录成功
-		if loginResp.Data.State == 2 {
-			// 登录成功，包含完整用户信息
-			status = LoginStatusResponse{
-				Status:   2,
-				WxID:     loginResp.Data.WxID,
-				NickName: loginResp.Data.NickName,
-				Message:  "登录成功",
-			}
-		} else {
-			// Code 200但state不为2，视为二维码已过期或不存在
-			status = LoginStatusResponse{
-				Status:  0,
-				Message: "二维码已过期或不存在",
-			}
-		}
+		// Code 200时按state区分扫码进度，只有state为2才是真正的登录成功
+		return loginStatusFromState(loginResp)
 	case 300:
 		// 不存在状态（二维码过期或其他原因）
-		status = LoginStatusResponse{
-			Status:  0,
+		return LoginStatusResponse{
+			Status:  LoginStatusWaitScan,
 			Message: "二维码已过期或不存在",
 		}
 	default:
 		// 其他错误状态
-		status = LoginStatusResponse{
-			Status:  3,
+		return LoginStatusResponse{
+			Status:  LoginStatusFailed,
 			Message: "检查登录状态失败",
 		}
 	}
+}
 
-	rm.successResponse(c, "检查成功", status)
+// loginStatusFromState 将外部接口的扫码状态映射为登录状态
+func loginStatusFromState(loginResp *CheckLoginStatusResponse) LoginStatusResponse {
+	switch loginResp.Data.State {
+	case QrCodeStateWaitScan:
+		return LoginStatusResponse{Status: LoginStatusWaitScan, Message: "等待扫码"}
+	case QrCodeStateScanned:
+		return LoginStatusResponse{
+			Status:   LoginStatusScanned,
+			NickName: loginResp.Data.NickName,
+			Message:  "已扫码，等待手机确认",
+		}
+	case QrCodeStateConfirmed:
+		// 登录成功，包含完整用户信息
+		return LoginStatusResponse{
+			Status:   LoginStatusConfirmed,
+			WxID:     loginResp.Data.WxID,
+			NickName: loginResp.Data.NickName,
+			Message:  "登录成功",
+		}
+	case QrCodeStateCancelled:
+		return LoginStatusResponse{Status: LoginStatusFailed, Message: "已在手机端取消登录"}
+	default:
+		message := fmt.Sprintf("未知的扫码状态(state=%d)", loginResp.Data.State)
+		if loginResp.Data.Msg != "" {
+			message += ": " + loginResp.Data.Msg
+		}
+		return LoginStatusResponse{Status: LoginStatusFailed, Message: message}
+	}
 }
 
 // saveUser 保存用户数据
@@ -616,11 +1248,14 @@ func (rm *RouterManager) saveUser(c *gin.Context) {
 		rm.notFoundResponse(c, "关联的机器人不存在")
 		return
 	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
 
 	// 检查是否有安全风险
 	hasRisk := req.HasSecurityRisk
 	if hasRisk == 0 {
-		riskResp, err := rm.service.CheckCanSetAlias(robot.Address, req.Token)
+		riskResp, err := rm.service.CheckCanSetAlias(withRobotTimeout(c.Request.Context(), robot), robot.Address, req.Token)
 		if err == nil {
 			for _, result := range riskResp.Data.Results {
 				if !result.IsPass {
@@ -661,6 +1296,8 @@ func (rm *RouterManager) saveUser(c *gin.Context) {
 // @Param id path string true "用户ID"
 // @Success 200 {object} APIResponse "删除成功"
 // @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "用户不存在"
 // @Failure 500 {object} APIResponse "内部服务器错误"
 // @Router /users/{id} [delete]
 func (rm *RouterManager) deleteUser(c *gin.Context) {
@@ -669,6 +1306,14 @@ func (rm *RouterManager) deleteUser(c *gin.Context) {
 		rm.badRequestResponse(c, "用户ID不能为空")
 		return
 	}
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "用户ID格式错误")
+		return
+	}
+	if !rm.checkUserOwner(c, uint(userID)) {
+		return
+	}
 
 	if err := rm.service.DeleteUser(id); err != nil {
 		rm.internalErrorResponse(c, "删除用户失败")
@@ -733,9 +1378,12 @@ func (rm *RouterManager) getLoginStatus(c *gin.Context) {
 		})
 		return
 	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
 
 	// 调用微信机器人API获取登录状态
-	statusResp, err := rm.service.GetLoginStatus(robot.Address, user.Token)
+	statusResp, err := rm.service.GetLoginStatus(withRobotTimeout(c.Request.Context(), robot), robot.Address, user.Token)
 	if err != nil {
 		rm.logger.Error("调用GetLoginStatus失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, APIResponse{
@@ -753,355 +1401,2034 @@ func (rm *RouterManager) getLoginStatus(c *gin.Context) {
 	})
 }
 
-// extendAuth 延期授权
-// @Summary 延期授权
-// @Description 延长机器人授权有效期
-// @Tags auth
+// getUserAuthInfo 获取用户授权概览
+// @Summary 获取用户授权概览
+// @Description 聚合用户的授权token（脱敏）、生成时间、延期与过期时间、历次延期记录以及实时登录状态（token过期时同样标记用户需要重新登录）
+// @Tags users
 // @Accept json
 // @Produce json
-// @Param robotId path string true "机器人ID"
-// @Param request body object{days=int} true "延期天数"
-// @Success 200 {object} APIResponse "延期成功"
+// @Param id path string true "用户ID"
+// @Success 200 {object} APIResponse{data=UserAuthInfoResponse} "查询成功"
 // @Failure 400 {object} APIResponse "参数错误"
-// @Failure 404 {object} APIResponse "机器人或用户不存在"
-// @Failure 500 {object} APIResponse "内部服务器错误"
-// @Router /auth/extend/{robotId} [post]
-func (rm *RouterManager) extendAuth(c *gin.Context) {
-	robotIdStr := c.Param("robotId")
-
-	var req struct {
-		Days int `json:"days" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Code:    -1,
-			Message: "参数错误: " + err.Error(),
-			Data:    nil,
-		})
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "用户不存在"
+// @Router /users/{id}/auth-info [get]
+func (rm *RouterManager) getUserAuthInfo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "用户ID格式错误")
 		return
 	}
 
-	if robotIdStr == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Code:    -1,
-			Message: "robotId不能为空",
-			Data:    nil,
-		})
+	if !rm.checkUserOwner(c, uint(id)) {
 		return
 	}
 
-	robotId, err := strconv.ParseUint(robotIdStr, 10, 32)
+	info, err := rm.service.GetUserAuthInfo(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Code:    -1,
-			Message: "robotId参数错误",
-			Data:    nil,
-		})
+		rm.notFoundResponse(c, "用户不存在")
 		return
 	}
 
-	// 获取机器人信息
-	robot, err := rm.service.GetRobotByID(uint(robotId))
-	if err != nil {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Code:    -1,
-			Message: "机器人不存在",
-			Data:    nil,
-		})
-		return
-	}
+	rm.successResponse(c, "查询成功", info)
+}
 
-	// 从robot关联的用户中获取token（假设取第一个有效用户的token）
-	users, err := rm.service.GetUsersByRobot(robotIdStr)
-	if err != nil || len(users) == 0 {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Code:    -1,
-			Message: "未找到该机器人关联的用户",
-			Data:    nil,
-		})
+// getUserByToken 按token反查用户
+// @Summary 按token反查用户
+// @Description 根据授权token查询所属用户及机器人，需在请求头 X-Admin-Token 中携带管理员令牌；GET通过查询参数token传入，POST通过body传入（推荐，避免token出现在访问日志中）
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "管理员令牌"
+// @Param token query string false "授权token（GET）"
+// @Param request body UserByTokenRequest false "授权token（POST）"
+// @Success 200 {object} APIResponse{data=UserByTokenResponse} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Failure 404 {object} APIResponse "用户不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /users/by-token [get]
+// @Router /users/by-token [post]
+func (rm *RouterManager) getUserByToken(c *gin.Context) {
+	var req UserByTokenRequest
+	var err error
+	if c.Request.Method == http.MethodPost {
+		err = c.ShouldBindJSON(&req)
+	} else {
+		err = c.ShouldBindQuery(&req)
+	}
+	if err != nil {
+		rm.badRequestResponse(c, "参数错误: token不能为空")
 		return
 	}
 
-	// 取第一个有效用户的token
-	var token string
-	for _, user := range users {
-		if user.Status == 1 && user.Token != "" { // 状态正常且有token
-			token = user.Token
-			break
+	user, err := rm.service.GetUserByToken(req.Token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rm.notFoundResponse(c, "用户不存在")
+			return
 		}
-	}
-
-	if token == "" {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Code:    -1,
-			Message: "未找到有效的用户token",
-			Data:    nil,
-		})
+		rm.internalErrorResponse(c, "查询用户失败")
 		return
 	}
-
-	// 调用微信机器人API延期授权
-	extendResp, err := rm.service.DelayAuthKey(robot.Address, robot.AdminKey, token, req.Days)
-	if err != nil {
-		rm.logger.Error("调用DelayAuthKey失败", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Code:    -1,
-			Message: "延期授权失败: " + err.Error(),
-			Data:    nil,
-		})
+	if scoped, ok := scopedOwner(c); ok && user.OwnerID != scoped {
+		rm.denyOwnerAccess(c, scoped, user.OwnerID)
 		return
 	}
 
-	// 更新数据库中的用户延期时间
-	newExpiry, _ := time.Parse("2006-01-02", extendResp.Data.ExpiryDate)
-	rm.service.UpdateUserExtension(uint(robotId), token, newExpiry)
-
-	c.JSON(http.StatusOK, APIResponse{
-		Code:    0,
-		Message: "延期成功",
-		Data:    extendResp,
-	})
+	rm.successResponse(c, "查询成功", user)
 }
 
-// sendText 发送文本消息
-// @Summary 发送文本消息
-// @Description 向指定群组发送文本消息
-// @Tags messages
+// searchUsers 按关键字搜索用户
+// @Summary 搜索用户
+// @Description 按 wx_id 或昵称模糊搜索用户，支持所属公司、状态过滤和分页
+// @Tags users
 // @Accept json
 // @Produce json
-// @Param request body object{text_content=string,to_user_name=string} true "文本消息参数"
-// @Success 200 {object} APIResponse "发送成功"
+// @Param keyword query string true "关键字（wx_id 或昵称片段）"
+// @Param owner_id query uint false "所属公司ID"
+// @Param status query int false "状态 1正常 2风控 3需要重新登录"
+// @Param page_no query int false "页码，默认1" default(1) minimum(1)
+// @Param page_size query int false "每页大小，默认10" default(10) minimum(1) maximum(100)
+// @Success 200 {object} APIResponse{data=UserSearchPaginatedResponse} "查询成功"
 // @Failure 400 {object} APIResponse "参数错误"
-// @Failure 404 {object} APIResponse "未找到消息机器人"
 // @Failure 500 {object} APIResponse "内部服务器错误"
-// @Router /messages/group/send-text [post]
-func (rm *RouterManager) sendText(c *gin.Context) {
-	var req struct {
-		TextContent string `json:"text_content" binding:"required"`
-		ToUserName  string `json:"to_user_name" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
+// @Router /users/search [get]
+func (rm *RouterManager) searchUsers(c *gin.Context) {
+	var req UserSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
 		rm.badRequestResponse(c, "参数错误: "+err.Error())
 		return
 	}
-
-	// 通过策略获取消息机器人信息
-	botInfo, err := rm.service.GetMessageBotByStrategy(req.ToUserName, rm.messageSendStrategy)
-	if err != nil {
-		rm.notFoundResponse(c, "未找到对应的消息机器人")
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
 		return
 	}
 
-	// 构建发送请求
-	sendReq := &SendTextRequest{
-		TextContent: req.TextContent,
-		ToUserName:  req.ToUserName,
+	req.Keyword = strings.TrimSpace(req.Keyword)
+	if req.Keyword == "" {
+		rm.badRequestResponse(c, "关键字不能为空")
+		return
 	}
 
-	// 调用服务发送文本消息
-	resp, err := rm.service.SendText(botInfo.Robot.Address, botInfo.User.Token, sendReq)
+	result, err := rm.service.SearchUsers(req)
 	if err != nil {
-		rm.logger.Error("发送文本消息失败", zap.Error(err))
-		rm.internalErrorResponse(c, "发送文本消息失败: "+err.Error())
+		rm.internalErrorResponse(c, "搜索用户失败")
 		return
 	}
 
-	rm.successResponse(c, "文本消息发送成功", resp)
+	rm.successResponse(c, "查询成功", result)
 }
 
-// sendImage 发送图片消息
-// @Summary 发送图片消息
-// @Description 向指定群组发送图片消息
-// @Tags messages
-// @Accept json
+// listNeedReloginUsers 查询需重新登录的用户
+// @Summary 查询需重新登录的用户
+// @Description 跨机器人分页查询状态为需要重新登录(status=3)的用户，按状态更新时间倒序；token 已脱敏，登录二维码以 user_id 调用 qrcode_url 批量获取
+// @Tags users
 // @Produce json
-// @Param request body object{image_content=string,to_user_name=string} true "图片消息参数"
-// @Success 200 {object} APIResponse "发送成功"
+// @Param owner_id query uint false "所属公司ID"
+// @Param robot_id query uint false "机器人ID"
+// @Param page_no query int false "页码，默认1" default(1) minimum(1)
+// @Param page_size query int false "每页大小，默认10" default(10) minimum(1) maximum(100)
+// @Success 200 {object} APIResponse{data=NeedReloginPaginatedResponse} "查询成功"
 // @Failure 400 {object} APIResponse "参数错误"
-// @Failure 404 {object} APIResponse "未找到消息机器人"
 // @Failure 500 {object} APIResponse "内部服务器错误"
-// @Router /messages/group/send-image [post]
-func (rm *RouterManager) sendImage(c *gin.Context) {
-	var req struct {
-		ImageContent string `json:"image_content" binding:"required"`
-		ToUserName   string `json:"to_user_name" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
+// @Router /users/need-relogin [get]
+func (rm *RouterManager) listNeedReloginUsers(c *gin.Context) {
+	var req NeedReloginRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
 		rm.badRequestResponse(c, "参数错误: "+err.Error())
 		return
 	}
-
-	// 通过策略获取消息机器人信息
-	botInfo, err := rm.service.GetMessageBotByStrategy(req.ToUserName, rm.messageSendStrategy)
-	if err != nil {
-		rm.notFoundResponse(c, "未找到对应的消息机器人")
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
 		return
 	}
 
-	// 构建发送请求
-	sendReq := &SendImageRequest{
-		ImageContent: req.ImageContent,
-		ToUserName:   req.ToUserName,
-	}
-
-	// 调用服务发送图片消息
-	resp, err := rm.service.SendImage(botInfo.Robot.Address, botInfo.User.Token, sendReq)
+	result, err := rm.service.ListNeedReloginUsers(req)
 	if err != nil {
-		rm.logger.Error("发送图片消息失败", zap.Error(err))
-		rm.internalErrorResponse(c, "发送图片消息失败: "+err.Error())
+		rm.internalErrorResponse(c, "查询需重新登录用户失败")
 		return
 	}
 
-	rm.successResponse(c, "图片消息发送成功", resp)
+	rm.successResponse(c, "查询成功", result)
 }
 
-// sendTextAndImage 同时发送文字和图片
-// @Summary 发送文本和图片消息
-// @Description 向指定群组同时发送文本和图片消息
-// @Tags messages
+// batchReloginQRCodes 批量获取重登二维码
+// @Summary 批量获取重登二维码
+// @Description 为多个用户批量生成登录二维码，check=true 时先尝试二次登录（无需扫码）；单个用户失败不影响其他用户，受获取二维码频率限制
+// @Tags users
 // @Accept json
 // @Produce json
-// @Param request body object{text_content=string,image_content=string,to_user_name=string} true "混合消息参数"
-// @Success 200 {object} APIResponse "发送成功"
+// @Param request body ReloginQRCodeBatchRequest true "用户ID列表，最多20个"
+// @Success 200 {object} APIResponse{data=[]ReloginQRCodeResult} "处理完成，各用户结果见data"
 // @Failure 400 {object} APIResponse "参数错误"
-// @Failure 404 {object} APIResponse "未找到消息机器人"
-// @Failure 500 {object} APIResponse "内部服务器错误"
-// @Router /messages/group/send-text-image [post]
-func (rm *RouterManager) sendTextAndImage(c *gin.Context) {
+// @Router /users/need-relogin/qrcodes [post]
+func (rm *RouterManager) batchReloginQRCodes(c *gin.Context) {
+	var req ReloginQRCodeBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	scoped, hasScope := scopedOwner(c)
+	results := make([]ReloginQRCodeResult, 0, len(req.UserIDs))
+	for _, userID := range req.UserIDs {
+		result := ReloginQRCodeResult{UserID: userID}
+
+		user, err := rm.service.GetUserByID(userID)
+		if err != nil {
+			result.Message = "用户不存在"
+			results = append(results, result)
+			continue
+		}
+		result.WxID = user.WxID
+		result.NickName = user.NickName
+
+		robot, err := rm.service.GetRobotByID(user.RobotID)
+		if err != nil {
+			result.Message = "机器人不存在"
+			results = append(results, result)
+			continue
+		}
+		if hasScope && robot.OwnerID != scoped {
+			result.Message = "无权访问其他公司的数据"
+			results = append(results, result)
+			continue
+		}
+		if allowed, _ := rm.qrCodeLimiter.Allow(fmt.Sprintf("%d:%s", robot.ID, user.Token)); !allowed {
+			result.Message = "获取二维码过于频繁，请稍后重试"
+			results = append(results, result)
+			continue
+		}
+
+		qrResponse, message, err := rm.loginQRCode(c.Request.Context(), robot, user.Token, req.Check)
+		if err != nil {
+			result.Message = "获取二维码失败: " + err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.Success = true
+		result.Message = message
+		result.QRCode = qrResponse
+		results = append(results, result)
+	}
+
+	rm.successResponse(c, "批量获取二维码完成", results)
+}
+
+// updateUserRemark 更新用户备注
+// @Summary 更新用户备注
+// @Description 设置运营自定义的用户备注/别名，用于区分多个账号
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body UpdateUserRemarkRequest true "备注内容"
+// @Success 200 {object} APIResponse "更新成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "用户不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /users/{id}/remark [put]
+func (rm *RouterManager) updateUserRemark(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	var req UpdateUserRemarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if !rm.checkUserOwner(c, uint(id)) {
+		return
+	}
+
+	if err := rm.service.UpdateUserRemark(uint(id), strings.TrimSpace(req.Remark)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rm.notFoundResponse(c, "用户不存在")
+			return
+		}
+		rm.internalErrorResponse(c, "更新用户备注失败")
+		return
+	}
+
+	rm.successResponse(c, "更新成功", nil)
+}
+
+// updateUserAutoRenew 设置用户到期自动续期
+// @Summary 设置用户到期自动续期
+// @Description 开启后授权到期检查任务会在到期前按配置自动延期，未开启的用户只发送到期预警
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body UpdateUserAutoRenewRequest true "是否自动续期"
+// @Success 200 {object} APIResponse "更新成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "用户不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /users/{id}/auto-renew [put]
+func (rm *RouterManager) updateUserAutoRenew(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	var req UpdateUserAutoRenewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if !rm.checkUserOwner(c, uint(id)) {
+		return
+	}
+
+	if err := rm.service.UpdateUserAutoRenew(uint(id), *req.AutoRenew); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rm.notFoundResponse(c, "用户不存在")
+			return
+		}
+		rm.internalErrorResponse(c, "更新用户自动续期失败")
+		return
+	}
+
+	rm.successResponse(c, "更新成功", nil)
+}
+
+// updateUserSignature 更新用户签名
+// @Summary 更新用户签名
+// @Description 设置账号发送文本时自动拼接的签名（如"【客服】"），可选拼接在前缀或后缀，群发同样生效
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body UpdateUserSignatureRequest true "签名内容"
+// @Success 200 {object} APIResponse "更新成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "用户不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /users/{id}/signature [put]
+func (rm *RouterManager) updateUserSignature(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	var req UpdateUserSignatureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if !rm.checkUserOwner(c, uint(id)) {
+		return
+	}
+
+	if err := rm.service.UpdateUserSignature(uint(id), req.Signature, req.Position); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rm.notFoundResponse(c, "用户不存在")
+			return
+		}
+		rm.internalErrorResponse(c, "更新用户签名失败")
+		return
+	}
+
+	rm.successResponse(c, "更新成功", nil)
+}
+
+// getUserStatusHistory 获取用户状态变更历史
+// @Summary 获取用户状态变更历史
+// @Description 查询用户状态（1正常 2风控 3需要重新登录）的变更记录，按时间倒序
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Success 200 {object} APIResponse{data=[]WxUserStatusLog} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "用户不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /users/{id}/status-history [get]
+func (rm *RouterManager) getUserStatusHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	if _, err := rm.service.GetUserByID(uint(id)); err != nil {
+		rm.notFoundResponse(c, "用户不存在")
+		return
+	}
+	if !rm.checkUserOwner(c, uint(id)) {
+		return
+	}
+
+	logs, err := rm.service.GetUserStatusHistory(uint(id))
+	if err != nil {
+		rm.internalErrorResponse(c, "查询状态变更历史失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", logs)
+}
+
+// syncUserGroups 手动同步用户群组
+// @Summary 手动同步用户群组
+// @Description 立即从外部接口拉取用户的群列表并同步到本地，返回本次新增、删除和改名的群
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Success 200 {object} APIResponse{data=GroupSyncSummary} "同步成功"
+// @Failure 400 {object} APIResponse "用户状态不可同步"
+// @Failure 404 {object} APIResponse "用户不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Failure 502 {object} APIResponse "获取群列表失败"
+// @Router /users/{id}/sync-groups [post]
+func (rm *RouterManager) syncUserGroups(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	user, err := rm.service.GetUserByID(uint(id))
+	if err != nil {
+		rm.notFoundResponse(c, "用户不存在")
+		return
+	}
+	robot, err := rm.service.GetRobotByID(user.RobotID)
+	if err != nil {
+		rm.notFoundResponse(c, "关联的机器人不存在")
+		return
+	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
+
+	summary, err := rm.groupSync.SyncGroupsForUser(c.Request.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotCallable):
+			rm.badRequestResponse(c, err.Error())
+		case errors.Is(err, ErrGroupListUnavailable):
+			rm.errorResponse(c, http.StatusBadGateway, err.Error())
+		default:
+			rm.internalErrorResponse(c, "同步群组失败: "+err.Error())
+		}
+		return
+	}
+
+	rm.successResponse(c, "同步成功", summary)
+}
+
+// extendAuth 延期授权
+// @Summary 延期授权
+// @Description 延长机器人授权有效期
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param robotId path string true "机器人ID"
+// @Param request body object{days=int} true "延期天数"
+// @Success 200 {object} APIResponse "延期成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 404 {object} APIResponse "机器人或用户不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /auth/extend/{robotId} [post]
+func (rm *RouterManager) extendAuth(c *gin.Context) {
+	robotIdStr := c.Param("robotId")
+
 	var req struct {
-		TextContent  string `json:"text_content"`
-		ImageContent string `json:"image_content"`
-		ToUserName   string `json:"to_user_name" binding:"required"`
+		Days int `json:"days" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    -1,
+			Message: "参数错误: " + err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	if robotIdStr == "" {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    -1,
+			Message: "robotId不能为空",
+			Data:    nil,
+		})
+		return
+	}
+
+	robotId, err := strconv.ParseUint(robotIdStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    -1,
+			Message: "robotId参数错误",
+			Data:    nil,
+		})
+		return
+	}
+
+	// 获取机器人信息
+	robot, err := rm.service.GetRobotByID(uint(robotId))
+	if err != nil {
+		c.JSON(http.StatusNotFound, APIResponse{
+			Code:    -1,
+			Message: "机器人不存在",
+			Data:    nil,
+		})
+		return
+	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
+
+	// 从robot关联的用户中获取token（假设取第一个有效用户的token）
+	users, err := rm.service.GetUsersByRobot(robotIdStr)
+	if err != nil || len(users) == 0 {
+		c.JSON(http.StatusNotFound, APIResponse{
+			Code:    -1,
+			Message: "未找到该机器人关联的用户",
+			Data:    nil,
+		})
+		return
+	}
+
+	// 取第一个有效用户的token
+	var token string
+	for _, user := range users {
+		if user.Status == 1 && user.Token != "" { // 状态正常且有token
+			token = user.Token
+			break
+		}
+	}
+
+	if token == "" {
+		c.JSON(http.StatusNotFound, APIResponse{
+			Code:    -1,
+			Message: "未找到有效的用户token",
+			Data:    nil,
+		})
+		return
+	}
+
+	// 调用微信机器人API延期授权
+	extendResp, err := rm.service.DelayAuthKey(withRobotTimeout(c.Request.Context(), robot), robot.Address, robot.AdminKey, token, req.Days)
+	if err != nil {
+		rm.logger.Error("调用DelayAuthKey失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    -1,
+			Message: "延期授权失败: " + err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// 更新数据库中的用户延期时间
+	newExpiry, _ := time.Parse("2006-01-02", extendResp.Data.ExpiryDate)
+	rm.service.UpdateUserExtension(uint(robotId), token, req.Days, newExpiry, ExtensionSourceManual)
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "延期成功",
+		Data:    extendResp,
+	})
+}
+
+// batchExtendAuth 批量延期授权
+// @Summary 批量延期授权
+// @Description 按所属公司或机器人批量延期用户授权，可只处理N天内到期的用户，返回每个用户的延期结果
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body BatchExtendAuthRequest true "批量延期参数"
+// @Success 200 {object} APIResponse{data=BatchExtendAuthResponse} "延期完成（可能部分失败）"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /auth/extend-batch [post]
+func (rm *RouterManager) batchExtendAuth(c *gin.Context) {
+	var req BatchExtendAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+
+	if req.OwnerID == 0 && req.RobotID == 0 {
+		rm.badRequestResponse(c, "owner_id和robot_id至少提供一个")
+		return
+	}
+
+	resp, err := rm.service.BatchExtendAuth(c.Request.Context(), &req)
+	if err != nil {
+		rm.internalErrorResponse(c, "批量延期授权失败")
+		return
+	}
+
+	rm.successResponse(c, "批量延期完成", resp)
+}
+
+// sendText 发送文本消息
+// @Summary 发送文本消息
+// @Description 向指定群组发送文本消息，超长文本按配置自动分段顺序发送，响应Segments中返回每段的结果
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body object{text_content=string,to_user_name=string,at_wx_id_list=[]string,callback_url=string,priority=string,confirm_large_group=bool} true "文本消息参数，at_wx_id_list可选，需要@的成员wxid，notify@all表示@所有人（需发送账号为群主或管理员，发送前会校验，无权限时降级为普通消息并发出 at_all_downgraded 事件通知）：列表原样交给平台触发@提醒，不会改写文本，需要显示的@昵称请自行写在text_content中；未传时按text_content中的@昵称自动匹配群成员；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true"
+// @Success 200 {object} APIResponse{data=SendTextMessageResponse} "发送成功，sender为实际发送的机器人与账号"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "目标群没有本公司的账号、在黑名单中或内容包含禁止发送的链接"
+// @Failure 404 {object} APIResponse "未找到消息机器人"
+// @Failure 409 {object} APIResponse "去重窗口内已向该群发送过相同内容"
+// @Failure 500 {object} APIResponse{data=SendErrorInfo} "发送失败，data中error_type为失败分类：network_error/risk_control/target_not_found/content_rejected/unknown，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID"
+// @Router /messages/group/send-text [post]
+func (rm *RouterManager) sendText(c *gin.Context) {
+	var req struct {
+		TextContent string   `json:"text_content" binding:"required"`
+		ToUserName  string   `json:"to_user_name" binding:"required"`
+		AtWxIDList  []string `json:"at_wx_id_list"` // 需要@的成员wxid，notify@all表示@所有人
+		CallbackURL string   `json:"callback_url"`
+		Priority    string   `json:"priority" binding:"omitempty,oneof=high normal"`
+		// 目标群成员数超过阈值时需显式确认
+		ConfirmLargeGroup bool `json:"confirm_large_group"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL, rm.addressGuard); err != nil {
+			rm.badRequestResponse(c, err.Error())
+			return
+		}
+	}
+
+	if !rm.checkSendContent(c, req.TextContent) {
+		return
+	}
+	if !rm.checkSendTarget(c, req.ToUserName, req.ConfirmLargeGroup) {
+		return
+	}
+
+	// 通过策略获取消息机器人信息
+	botInfo, err := rm.service.GetMessageBotByStrategy(req.ToUserName, rm.sendStrategy(c))
+	if err != nil {
+		rm.notFoundResponse(c, "未找到对应的消息机器人")
+		return
+	}
+
+	// 构建发送请求
+	sendReq := &SendTextRequest{
+		TextContent: req.TextContent,
+		ToUserName:  req.ToUserName,
+		AtWxIDList:  normalizeAtWxIDList(req.AtWxIDList),
+		Priority:    req.Priority,
+	}
+
+	if !rm.checkDuplicateSend(c, req.ToUserName, req.TextContent) {
+		return
+	}
+
+	// 发送时会追加签名，重试队列保存原始请求
+	retryReq := *sendReq
+
+	// 调用服务发送文本消息
+	resp, err := rm.service.SendText(withRobotTimeout(c.Request.Context(), botInfo.Robot), botInfo.Robot.Address, botInfo.User.Token, sendReq)
+	rm.notifySendResult(req.CallbackURL, "send_text", req.ToUserName, resp, err)
+	if err != nil {
+		rm.service.ReleaseDuplicateSend(req.ToUserName, req.TextContent)
+		rm.logger.Error("发送文本消息失败", zap.Error(err), zap.String("error_type", string(classifySendError(err))))
+		// 分段发送已有部分成功时整体重发会重复，不加入重试队列
+		if resp != nil {
+			rm.sendFailedResponse(c, "发送文本消息失败", err)
+			return
+		}
+		rm.sendFailedWithRetry(c, "发送文本消息失败", SendAuditTypeText, req.ToUserName, &retryReq, err)
+		return
+	}
+
+	rm.successResponse(c, "文本消息发送成功", SendTextMessageResponse{
+		SendTextResponse: resp,
+		Sender:           newSenderInfo(botInfo),
+	})
+}
+
+// newSenderInfo 根据选中的消息机器人生成发送者信息
+func newSenderInfo(botInfo *MessageBotInfo) SenderInfo {
+	return SenderInfo{
+		RobotID:  botInfo.Robot.ID,
+		UserID:   botInfo.User.ID,
+		WxID:     botInfo.User.WxID,
+		NickName: botInfo.User.NickName,
+		Token:    maskToken(botInfo.User.Token),
+	}
+}
+
+// checkSendTarget 发送前校验目标群：带API Key时群内需有本公司账号，不能在黑名单中，成员数超过阈值时需确认；未通过时直接写入响应并返回false
+func (rm *RouterManager) checkSendTarget(c *gin.Context, groupID string, confirmed bool) bool {
+	if !rm.checkGroupOwner(c, groupID) {
+		return false
+	}
+	if err := rm.service.CheckGroupBlacklist(groupID); err != nil {
+		if errors.Is(err, ErrGroupBlacklisted) {
+			rm.errorResponse(c, http.StatusForbidden, err.Error())
+			return false
+		}
+		rm.internalErrorResponse(c, "校验群黑名单失败")
+		return false
+	}
+
+	if err := rm.service.CheckLargeGroup(groupID, confirmed); err != nil {
+		if errors.Is(err, ErrLargeGroupNotConfirmed) {
+			rm.badRequestResponse(c, err.Error())
+			return false
+		}
+		rm.internalErrorResponse(c, "校验群成员数失败")
+		return false
+	}
+	return true
+}
+
+// checkDuplicateSend 发送前检查去重窗口内是否已向该群发送过相同内容，配置为拒绝时直接写入响应并返回false
+func (rm *RouterManager) checkDuplicateSend(c *gin.Context, groupID, content string) bool {
+	if err := rm.service.CheckDuplicateSend(groupID, content); err != nil {
+		rm.errorResponse(c, http.StatusConflict, err.Error())
+		return false
+	}
+	return true
+}
+
+// checkSendContent 发送前检查文本中的链接，命中黑名单或安全检测未通过时直接写入响应并返回false
+func (rm *RouterManager) checkSendContent(c *gin.Context, text string) bool {
+	if err := rm.service.CheckContentURLs(text); err != nil {
+		rm.errorResponse(c, http.StatusForbidden, err.Error())
+		return false
+	}
+	return true
+}
+
+// sendTextMulti 同一文本批量发送到多个群
+// @Summary 批量发送文本消息
+// @Description 向多个群组发送同一条文本消息，同一消息机器人负责的群合并为一次请求，返回每个群的发送结果
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body SendTextMultiRequest true "批量文本消息参数，priority可选 high/normal，默认normal"
+// @Success 200 {object} APIResponse{data=[]SendTextResult} "发送完成，各群结果见data，失败的群ErrorType为失败分类"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "内容包含禁止发送的链接"
+// @Router /messages/group/send-text-multi [post]
+func (rm *RouterManager) sendTextMulti(c *gin.Context) {
+	var req SendTextMultiRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkSendContent(c, req.TextContent) {
+		return
+	}
+
+	results := rm.service.SendTextToGroups(c.Request.Context(), &req, rm.sendStrategy(c))
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	if failed > 0 {
+		rm.logger.Warn("批量发送文本消息部分失败",
+			zap.Int("total", len(results)),
+			zap.Int("failed", failed))
+	}
+
+	rm.successResponse(c, "批量文本消息发送完成", results)
+}
+
+// sendImage 发送图片消息
+// @Summary 发送图片消息
+// @Description 向指定群组发送图片消息
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body object{image_content=string,thumb_content=string,auto_thumb=bool,to_user_name=string,callback_url=string,priority=string,confirm_large_group=bool} true "图片消息参数，thumb_content可选缩略图，auto_thumb为true时未传缩略图自动从原图生成；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true"
+// @Success 200 {object} APIResponse{data=SendImageMessageResponse} "发送成功，sender为实际发送的机器人与账号"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "目标群没有本公司的账号或在黑名单中"
+// @Failure 404 {object} APIResponse "未找到消息机器人"
+// @Failure 500 {object} APIResponse{data=SendErrorInfo} "发送失败，data中error_type为失败分类，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID"
+// @Router /messages/group/send-image [post]
+func (rm *RouterManager) sendImage(c *gin.Context) {
+	var req struct {
+		ImageContent string `json:"image_content" binding:"required"`
+		ThumbContent string `json:"thumb_content"` // 缩略图base64，可选
+		AutoThumb    bool   `json:"auto_thumb"`    // 未传缩略图时从原图自动生成
+		ToUserName   string `json:"to_user_name" binding:"required"`
+		CallbackURL  string `json:"callback_url"`
+		Priority     string `json:"priority" binding:"omitempty,oneof=high normal"`
+		// 目标群成员数超过阈值时需显式确认
+		ConfirmLargeGroup bool `json:"confirm_large_group"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL, rm.addressGuard); err != nil {
+			rm.badRequestResponse(c, err.Error())
+			return
+		}
+	}
+
+	if !rm.checkSendTarget(c, req.ToUserName, req.ConfirmLargeGroup) {
+		return
+	}
+
+	// 通过策略获取消息机器人信息
+	botInfo, err := rm.service.GetMessageBotByStrategy(req.ToUserName, rm.sendStrategy(c))
+	if err != nil {
+		rm.notFoundResponse(c, "未找到对应的消息机器人")
+		return
+	}
+
+	// 构建发送请求
+	sendReq := &SendImageRequest{
+		ImageContent: req.ImageContent,
+		ThumbContent: req.ThumbContent,
+		AutoThumb:    req.AutoThumb,
+		ToUserName:   req.ToUserName,
+		Priority:     req.Priority,
+	}
+
+	// 调用服务发送图片消息
+	resp, err := rm.service.SendImage(withRobotTimeout(c.Request.Context(), botInfo.Robot), botInfo.Robot.Address, botInfo.User.Token, sendReq)
+	rm.notifySendResult(req.CallbackURL, "send_image", req.ToUserName, resp, err)
+	if err != nil {
+		rm.logger.Error("发送图片消息失败", zap.Error(err), zap.String("error_type", string(classifySendError(err))))
+		rm.sendFailedWithRetry(c, "发送图片消息失败", SendAuditTypeImage, req.ToUserName, sendReq, err)
+		return
+	}
+
+	rm.successResponse(c, "图片消息发送成功", SendImageMessageResponse{
+		SendImageResponse: resp,
+		Sender:            newSenderInfo(botInfo),
+	})
+}
+
+// sendVoice 发送语音消息
+// @Summary 发送语音消息
+// @Description 向指定群组发送语音消息
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body object{voice_content=string,voice_format=string,voice_duration=int,to_user_name=string,callback_url=string,priority=string,confirm_large_group=bool} true "语音消息参数，voice_content为base64语音；voice_format可选 silk/amr，默认silk；voice_duration为语音时长(秒)，1-60；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true"
+// @Success 200 {object} APIResponse{data=SendVoiceMessageResponse} "发送成功，sender为实际发送的机器人与账号"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "目标群没有本公司的账号或在黑名单中"
+// @Failure 404 {object} APIResponse "未找到消息机器人"
+// @Failure 500 {object} APIResponse{data=SendErrorInfo} "发送失败，data中error_type为失败分类，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID"
+// @Router /messages/group/send-voice [post]
+func (rm *RouterManager) sendVoice(c *gin.Context) {
+	var req struct {
+		VoiceContent  string `json:"voice_content" binding:"required"`
+		VoiceFormat   string `json:"voice_format" binding:"omitempty,oneof=silk amr"`
+		VoiceDuration int    `json:"voice_duration" binding:"required,min=1,max=60"`
+		ToUserName    string `json:"to_user_name" binding:"required"`
+		CallbackURL   string `json:"callback_url"`
+		Priority      string `json:"priority" binding:"omitempty,oneof=high normal"`
+		// 目标群成员数超过阈值时需显式确认
+		ConfirmLargeGroup bool `json:"confirm_large_group"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL, rm.addressGuard); err != nil {
+			rm.badRequestResponse(c, err.Error())
+			return
+		}
+	}
+
+	if !rm.checkSendTarget(c, req.ToUserName, req.ConfirmLargeGroup) {
+		return
+	}
+
+	// 通过策略获取消息机器人信息
+	botInfo, err := rm.service.GetMessageBotByStrategy(req.ToUserName, rm.sendStrategy(c))
+	if err != nil {
+		rm.notFoundResponse(c, "未找到对应的消息机器人")
+		return
+	}
+
+	voiceFormat := VoiceFormatSilk
+	if req.VoiceFormat == "amr" {
+		voiceFormat = VoiceFormatAMR
+	}
+
+	// 构建发送请求
+	sendReq := &SendVoiceRequest{
+		VoiceContent:  req.VoiceContent,
+		VoiceFormat:   voiceFormat,
+		VoiceDuration: req.VoiceDuration,
+		ToUserName:    req.ToUserName,
+		Priority:      req.Priority,
+	}
+
+	// 调用服务发送语音消息
+	resp, err := rm.service.SendVoice(withRobotTimeout(c.Request.Context(), botInfo.Robot), botInfo.Robot.Address, botInfo.User.Token, sendReq)
+	rm.notifySendResult(req.CallbackURL, "send_voice", req.ToUserName, resp, err)
+	if err != nil {
+		rm.logger.Error("发送语音消息失败", zap.Error(err), zap.String("error_type", string(classifySendError(err))))
+		rm.sendFailedWithRetry(c, "发送语音消息失败", SendAuditTypeVoice, req.ToUserName, sendReq, err)
+		return
+	}
+
+	rm.successResponse(c, "语音消息发送成功", SendVoiceMessageResponse{
+		SendVoiceResponse: resp,
+		Sender:            newSenderInfo(botInfo),
+	})
+}
+
+// sendVideo 发送视频消息
+// @Summary 发送视频消息
+// @Description 向指定群组发送视频消息
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body object{video_content=string,thumb_content=string,play_length=int,to_user_name=string,callback_url=string,priority=string,confirm_large_group=bool} true "视频消息参数，video_content为base64视频；thumb_content可选封面缩略图base64；play_length为视频时长(秒)；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true"
+// @Success 200 {object} APIResponse{data=SendVideoMessageResponse} "发送成功，sender为实际发送的机器人与账号"
+// @Failure 400 {object} APIResponse "参数错误或缩略图不是有效的base64"
+// @Failure 403 {object} APIResponse "目标群没有本公司的账号或在黑名单中"
+// @Failure 404 {object} APIResponse "未找到消息机器人"
+// @Failure 500 {object} APIResponse{data=SendErrorInfo} "发送失败，data中error_type为失败分类，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID"
+// @Router /messages/group/send-video [post]
+func (rm *RouterManager) sendVideo(c *gin.Context) {
+	var req struct {
+		VideoContent string `json:"video_content" binding:"required"`
+		ThumbContent string `json:"thumb_content"` // 封面缩略图base64，可选
+		PlayLength   int    `json:"play_length" binding:"required,min=1"`
+		ToUserName   string `json:"to_user_name" binding:"required"`
+		CallbackURL  string `json:"callback_url"`
+		Priority     string `json:"priority" binding:"omitempty,oneof=high normal"`
+		// 目标群成员数超过阈值时需显式确认
+		ConfirmLargeGroup bool `json:"confirm_large_group"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.ThumbContent != "" {
+		if _, err := decodeBase64Image(req.ThumbContent); err != nil {
+			rm.badRequestResponse(c, "缩略图不是有效的base64: "+err.Error())
+			return
+		}
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL, rm.addressGuard); err != nil {
+			rm.badRequestResponse(c, err.Error())
+			return
+		}
+	}
+
+	if !rm.checkSendTarget(c, req.ToUserName, req.ConfirmLargeGroup) {
+		return
+	}
+
+	// 通过策略获取消息机器人信息
+	botInfo, err := rm.service.GetMessageBotByStrategy(req.ToUserName, rm.sendStrategy(c))
+	if err != nil {
+		rm.notFoundResponse(c, "未找到对应的消息机器人")
+		return
+	}
+
+	// 构建发送请求
+	sendReq := &SendVideoRequest{
+		VideoContent: req.VideoContent,
+		ThumbContent: req.ThumbContent,
+		PlayLength:   req.PlayLength,
+		ToUserName:   req.ToUserName,
+		Priority:     req.Priority,
+	}
+
+	// 调用服务发送视频消息
+	resp, err := rm.service.SendVideo(withRobotTimeout(c.Request.Context(), botInfo.Robot), botInfo.Robot.Address, botInfo.User.Token, sendReq)
+	rm.notifySendResult(req.CallbackURL, "send_video", req.ToUserName, resp, err)
+	if err != nil {
+		rm.logger.Error("发送视频消息失败", zap.Error(err), zap.String("error_type", string(classifySendError(err))))
+		rm.sendFailedWithRetry(c, "发送视频消息失败", SendAuditTypeVideo, req.ToUserName, sendReq, err)
+		return
+	}
+
+	rm.successResponse(c, "视频消息发送成功", SendVideoMessageResponse{
+		SendVideoResponse: resp,
+		Sender:            newSenderInfo(botInfo),
+	})
+}
+
+// sendLink 发送链接卡片消息
+// @Summary 发送链接卡片消息
+// @Description 向指定群组发送文章/链接卡片消息
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body object{title=string,description=string,url=string,thumb_url=string,to_user_name=string,callback_url=string,priority=string,confirm_large_group=bool} true "卡片消息参数，title、url必填；description、thumb_url可选；callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true"
+// @Success 200 {object} APIResponse{data=SendAppMsgMessageResponse} "发送成功，sender为实际发送的机器人与账号"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "目标群没有本公司的账号、在黑名单中或链接禁止发送"
+// @Failure 404 {object} APIResponse "未找到消息机器人"
+// @Failure 500 {object} APIResponse{data=SendErrorInfo} "发送失败，data中error_type为失败分类，retryable表示可否直接重试；开启失败重试队列时可重试的失败由后台自动重发，retry_id为重试记录ID"
+// @Router /messages/group/send-link [post]
+func (rm *RouterManager) sendLink(c *gin.Context) {
+	var req struct {
+		Title       string `json:"title" binding:"required"`
+		Description string `json:"description"`
+		Url         string `json:"url" binding:"required,url"`
+		ThumbUrl    string `json:"thumb_url" binding:"omitempty,url"`
+		ToUserName  string `json:"to_user_name" binding:"required"`
+		CallbackURL string `json:"callback_url"`
+		Priority    string `json:"priority" binding:"omitempty,oneof=high normal"`
+		// 目标群成员数超过阈值时需显式确认
+		ConfirmLargeGroup bool `json:"confirm_large_group"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL, rm.addressGuard); err != nil {
+			rm.badRequestResponse(c, err.Error())
+			return
+		}
+	}
+
+	if !rm.checkSendContent(c, req.Url) {
+		return
+	}
+	if !rm.checkSendTarget(c, req.ToUserName, req.ConfirmLargeGroup) {
+		return
+	}
+
+	// 通过策略获取消息机器人信息
+	botInfo, err := rm.service.GetMessageBotByStrategy(req.ToUserName, rm.sendStrategy(c))
+	if err != nil {
+		rm.notFoundResponse(c, "未找到对应的消息机器人")
+		return
+	}
+
+	// 构建发送请求
+	sendReq := &SendAppMsgRequest{
+		Title:       req.Title,
+		Description: req.Description,
+		Url:         req.Url,
+		ThumbUrl:    req.ThumbUrl,
+		ToUserName:  req.ToUserName,
+		Priority:    req.Priority,
+	}
+
+	// 调用服务发送卡片消息
+	resp, err := rm.service.SendAppMsg(withRobotTimeout(c.Request.Context(), botInfo.Robot), botInfo.Robot.Address, botInfo.User.Token, sendReq)
+	rm.notifySendResult(req.CallbackURL, "send_link", req.ToUserName, resp, err)
+	if err != nil {
+		rm.logger.Error("发送卡片消息失败", zap.Error(err), zap.String("error_type", string(classifySendError(err))))
+		rm.sendFailedWithRetry(c, "发送卡片消息失败", SendAuditTypeLink, req.ToUserName, sendReq, err)
+		return
+	}
+
+	rm.successResponse(c, "卡片消息发送成功", SendAppMsgMessageResponse{
+		SendAppMsgResponse: resp,
+		Sender:             newSenderInfo(botInfo),
+	})
+}
+
+// sendTextAndImage 同时发送文字和图片
+// @Summary 发送文本和图片消息
+// @Description 向指定群组同时发送文本和图片消息
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body object{text_content=string,image_content=string,to_user_name=string,callback_url=string,priority=string,confirm_large_group=bool} true "混合消息参数，callback_url可选，发送完成后回调结果；priority可选 high/normal，默认normal；目标群成员数超过阈值时需confirm_large_group=true"
+// @Success 200 {object} APIResponse "发送成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "目标群没有本公司的账号、在黑名单中或内容包含禁止发送的链接"
+// @Failure 404 {object} APIResponse "未找到消息机器人"
+// @Failure 409 {object} APIResponse "去重窗口内已向该群发送过相同内容"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /messages/group/send-text-image [post]
+func (rm *RouterManager) sendTextAndImage(c *gin.Context) {
+	var req struct {
+		TextContent  string `json:"text_content"`
+		ImageContent string `json:"image_content"`
+		ToUserName   string `json:"to_user_name" binding:"required"`
+		CallbackURL  string `json:"callback_url"`
+		Priority     string `json:"priority" binding:"omitempty,oneof=high normal"`
+		// 目标群成员数超过阈值时需显式确认
+		ConfirmLargeGroup bool `json:"confirm_large_group"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL, rm.addressGuard); err != nil {
+			rm.badRequestResponse(c, err.Error())
+			return
+		}
+	}
+
+	// 检查至少有一个内容不为空
+	if req.TextContent == "" && req.ImageContent == "" {
+		rm.badRequestResponse(c, "文本内容和图片内容不能都为空")
+		return
+	}
+
+	if !rm.checkSendContent(c, req.TextContent) {
+		return
+	}
+	if !rm.checkSendTarget(c, req.ToUserName, req.ConfirmLargeGroup) {
+		return
+	}
+
+	// 通过策略获取消息机器人信息
+	botInfo, err := rm.service.GetMessageBotByStrategy(req.ToUserName, rm.sendStrategy(c))
+	if err != nil {
+		rm.notFoundResponse(c, "未找到对应的消息机器人")
+		return
+	}
+
+	// 构建发送请求
+	sendReq := &SendTextAndImageRequest{
+		TextContent:  req.TextContent,
+		ImageContent: req.ImageContent,
+		ToUserName:   req.ToUserName,
+		Priority:     req.Priority,
+	}
+
+	dedupContent := req.TextContent + req.ImageContent
+	if !rm.checkDuplicateSend(c, req.ToUserName, dedupContent) {
+		return
+	}
+
+	// 调用服务发送文字和图片
+	resp, err := rm.service.SendTextAndImage(withRobotTimeout(c.Request.Context(), botInfo.Robot), botInfo.Robot.Address, botInfo.User.Token, sendReq)
+	callbackErr := err
+	if err == nil && !resp.Success {
+		callbackErr = errors.New(resp.Message)
+	}
+	if callbackErr != nil {
+		rm.service.ReleaseDuplicateSend(req.ToUserName, dedupContent)
+	}
+	rm.notifySendResult(req.CallbackURL, "send_text_image", req.ToUserName, resp, callbackErr)
+	if err != nil {
+		rm.logger.Error("发送文字和图片失败", zap.Error(err))
+		rm.internalErrorResponse(c, "发送文字和图片失败: "+err.Error())
+		return
+	}
+
+	rm.successResponse(c, "消息发送完成", resp)
+}
+
+// setMessageStrategy 设置消息发送策略
+// @Summary 设置消息发送策略
+// @Description 设置系统的消息发送策略（随机或轮询）
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body object{strategy=string} true "策略参数 (random/round_robin)"
+// @Success 200 {object} APIResponse "设置成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "租户API Key不能修改全局发送策略"
+// @Router /messages/group/set-strategy [post]
+func (rm *RouterManager) setMessageStrategy(c *gin.Context) {
+	// 策略对所有公司生效，租户不能修改
+	if _, ok := scopedOwner(c); ok {
+		rm.errorResponse(c, http.StatusForbidden, "租户API Key不能修改全局发送策略")
+		return
+	}
+
+	var req struct {
+		Strategy string `json:"strategy" binding:"required"` // round_robin, random
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	switch req.Strategy {
+	case "round_robin":
+		rm.messageSendStrategy = NewRoundRobinMessageSendStrategy()
+		rm.logger.Info("消息发送策略已切换为: 轮询")
+	case "random":
+		rm.messageSendStrategy = NewRandomMessageSendStrategy()
+		rm.logger.Info("消息发送策略已切换为: 随机")
+	default:
+		rm.badRequestResponse(c, "无效的策略类型，支持: round_robin, random")
+		return
+	}
+
+	rm.successResponse(c, "策略设置成功", map[string]string{
+		"strategy": req.Strategy,
+	})
+}
+
+// broadcastToGroups 同步群发到多个群
+// @Summary 同步群发到多个群
+// @Description 向多个群组发送同一文本和/或图片，每个群按策略选择消息机器人，全部发送完成后返回每个群的结果；部分群失败时仍返回200，失败原因见results；
+// @Description 重复、不存在、在黑名单中或没有可用消息机器人的群不发送，作为失败结果返回；单次最多50个群，更多群请使用异步群发任务
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body GroupBroadcastRequest true "群发参数，text_content与image_content至少传一个"
+// @Success 200 {object} APIResponse{data=GroupBroadcastResponse} "发送完成，各群结果见results"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "内容包含禁止发送的链接"
+// @Router /messages/group/broadcast [post]
+func (rm *RouterManager) broadcastToGroups(c *gin.Context) {
+	var req GroupBroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.TextContent == "" && req.ImageContent == "" {
+		rm.badRequestResponse(c, "文本内容和图片内容不能都为空")
+		return
+	}
+	if !rm.checkSendContent(c, req.TextContent) {
+		return
+	}
+
+	traceID := newTaskID()
+	scoped, _ := scopedOwner(c)
+	result := rm.service.BroadcastToGroups(c.Request.Context(), &BroadcastRequest{
+		OwnerID:           scoped,
+		ToUserNames:       req.ToUserNames,
+		TextContent:       req.TextContent,
+		ImageContent:      req.ImageContent,
+		Priority:          req.Priority,
+		ConfirmLargeGroup: req.ConfirmLargeGroup,
+	}, traceID, rm.sendStrategy(c))
+
+	rm.logger.Info("同步群发完成",
+		zap.String("trace_id", traceID),
+		zap.Int("total", result.Total),
+		zap.Int("success", result.Success),
+		zap.Int("failed", result.Failed))
+
+	rm.successResponse(c, "群发完成", result)
+}
+
+// submitBroadcastTask 提交异步群发任务
+// @Summary 提交异步群发任务
+// @Description 向多个群组群发文本和/或图片，立即返回task_id，后台逐个群发送，可通过任务ID查询进度；重复、不存在或没有可用消息机器人的群在发送前剔除，原因见skipped；
+// @Description 传 sample_rate 或 sample_count 时为灰度发送，只随机抽取部分有效群发送，未抽中的群见remaining
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body BroadcastRequest true "群发参数"
+// @Success 200 {object} APIResponse{data=BroadcastTaskProgress} "提交成功"
+// @Failure 400 {object} APIResponse{data=[]BroadcastSkippedGroup} "参数错误或没有可发送的有效群"
+// @Failure 403 {object} APIResponse "内容包含禁止发送的链接"
+// @Router /messages/broadcast [post]
+func (rm *RouterManager) submitBroadcastTask(c *gin.Context) {
+	var req BroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.TextContent == "" && req.ImageContent == "" {
+		rm.badRequestResponse(c, "文本内容和图片内容不能都为空")
+		return
+	}
+	if req.SampleRate > 0 && req.SampleCount > 0 {
+		rm.badRequestResponse(c, "sample_rate和sample_count只能传一个")
+		return
+	}
+	if !rm.checkSendContent(c, req.TextContent) {
+		return
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL, rm.addressGuard); err != nil {
+			rm.badRequestResponse(c, err.Error())
+			return
+		}
+	}
+
+	// 去重并剔除不存在/无可用机器人的群，只对有效群发送；带API Key时只使用本公司的消息机器人
+	req.OwnerID, _ = scopedOwner(c)
+	validGroups, skipped := rm.service.FilterBroadcastGroups(req.ToUserNames, req.OwnerID)
+	if len(validGroups) == 0 {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    -1,
+			Message: "没有可发送的有效群",
+			Data:    skipped,
+		})
+		return
+	}
+	// 灰度发送只发抽中的群，其余群返回给调用方确认后再全量
+	sampled, remaining := sampleBroadcastGroups(validGroups, req.SampleRate, req.SampleCount)
+	req.ToUserNames = sampled
+
+	task := rm.broadcastTasks.Create(req.OwnerID, len(req.ToUserNames), skipped)
+	task.SetRemaining(remaining)
+	go rm.runBroadcastTask(task, &req)
+
+	rm.logger.Info("群发任务已提交",
+		zap.String("task_id", task.ID()),
+		zap.String("trace_id", task.TraceID()),
+		zap.Int("group_count", len(req.ToUserNames)),
+		zap.Int("skipped_count", len(skipped)),
+		zap.Int("remaining_count", len(remaining)))
+
+	rm.successResponse(c, "群发任务已提交", task.Progress())
+}
+
+// runBroadcastTask 后台执行群发任务并更新进度
+func (rm *RouterManager) runBroadcastTask(task *BroadcastTask, req *BroadcastRequest) {
+	task.Start()
+	// 任务在请求返回后继续执行，不能使用请求的context；单独限定最长执行时间，避免外部接口无响应时任务一直挂起
+	ctx, cancel := context.WithTimeout(context.Background(), broadcastTaskTimeout)
+	defer cancel()
+	rm.service.BroadcastMessage(ctx, req, task.TraceID(), withOwnerScope(rm.messageSendStrategy, req.OwnerID), task.Record)
+	task.Finish()
+
+	progress := task.Progress()
+	rm.logger.Info("群发任务执行完成",
+		zap.String("task_id", progress.TaskID),
+		zap.String("trace_id", progress.TraceID),
+		zap.Int("total", progress.Total),
+		zap.Int("success", progress.Success),
+		zap.Int("failed", progress.Failed))
+
+	if req.CallbackURL != "" {
+		rm.callbackNotifier.Notify(req.CallbackURL, SendCallbackPayload{
+			Event:     "broadcast",
+			Success:   progress.Failed == 0,
+			Message:   fmt.Sprintf("群发完成: 成功%d, 失败%d", progress.Success, progress.Failed),
+			Data:      progress,
+			Timestamp: time.Now().Unix(),
+		})
+	}
+}
+
+// getBroadcastTaskProgress 查询群发任务进度
+// @Summary 查询群发任务进度
+// @Description 查询异步群发任务的进度（已发/总数/成功/失败）和每个群的发送结果
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param taskId path string true "任务ID"
+// @Success 200 {object} APIResponse{data=BroadcastTaskProgress} "查询成功"
+// @Failure 404 {object} APIResponse "任务不存在"
+// @Router /messages/broadcast/{taskId} [get]
+func (rm *RouterManager) getBroadcastTaskProgress(c *gin.Context) {
+	// 带API Key时只能查到本公司提交的任务
+	scoped, _ := scopedOwner(c)
+	progress, ok := rm.broadcastTasks.Get(c.Param("taskId"), scoped)
+	if !ok {
+		rm.notFoundResponse(c, "群发任务不存在或已过期")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", progress)
+}
+
+// getSendTrace 按trace_id查询发送批次结果
+// @Summary 按追踪ID查询发送结果
+// @Description 查询同一发送批次（如一次群发，trace_id见群发任务进度）中所有消息的发送结果，发送记录持久化保存，不受群发任务内存过期影响
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param traceId path string true "发送批次追踪ID"
+// @Success 200 {object} APIResponse{data=SendTraceResponse} "查询成功"
+// @Failure 404 {object} APIResponse "没有该批次的发送记录"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /messages/trace/{traceId} [get]
+func (rm *RouterManager) getSendTrace(c *gin.Context) {
+	traceID := c.Param("traceId")
+
+	// 带API Key时只返回本公司发起的发送记录
+	scoped, _ := scopedOwner(c)
+	resp, err := rm.service.GetSendRecordsByTrace(traceID, scoped)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询发送记录失败")
+		return
+	}
+	if resp.Total == 0 {
+		rm.notFoundResponse(c, "没有该批次的发送记录")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", resp)
+}
+
+// checkTimeParams 校验可选的时间参数格式，不合法时直接写入响应并返回false
+func (rm *RouterManager) checkTimeParams(c *gin.Context, values ...string) bool {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if _, err := ParseTime(value); err != nil {
+			rm.badRequestResponse(c, "时间格式错误，应为 yyyy-mm-dd hh:mi:ss")
+			return false
+		}
+	}
+	return true
+}
+
+// listSendAudits 分页查询发送审计记录
+// @Summary 查询发送审计记录
+// @Description 按发送时间、目标群、发送账号分页查询对外发送内容的审计记录，按时间倒序；需在请求头 X-Admin-Token 中携带管理员令牌，或携带 X-API-Key 查看本公司记录
+// @Tags audits
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string false "管理员令牌，与X-API-Key二选一"
+// @Param X-API-Key header string false "租户API Key，只能查看本公司记录"
+// @Param start_time query string false "发送时间开始，格式：yyyy-mm-dd hh:mi:ss"
+// @Param end_time query string false "发送时间结束，格式：yyyy-mm-dd hh:mi:ss"
+// @Param to_user_name query string false "目标群ID"
+// @Param sender_wx_id query string false "发送账号微信ID"
+// @Param owner_id query uint false "发送账号所属公司ID，使用租户API Key时默认为Key绑定的公司"
+// @Param page_no query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认10，最大100"
+// @Success 200 {object} APIResponse{data=SendAuditPaginatedResponse} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /audits [get]
+func (rm *RouterManager) listSendAudits(c *gin.Context) {
+	var req SendAuditQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+	if !rm.checkTimeParams(c, req.StartTime, req.EndTime) {
+		return
+	}
+
+	result, err := rm.service.ListSendAudits(req)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询审计记录失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", result)
+}
+
+// getRobotTagSendStats 按机器人标签对比发送量与成功率
+// @Summary 按机器人标签对比发送统计
+// @Description 基于发送审计记录，按机器人标签汇总发送总数、成功数、失败数与成功率，用于对比不同分组机器人；有多个标签的机器人计入每个标签，未打标签的机器人汇总到空标签；需在请求头 X-Admin-Token 中携带管理员令牌，或携带 X-API-Key 查看本公司记录
+// @Tags audits
+// @Produce json
+// @Param X-Admin-Token header string false "管理员令牌，与X-API-Key二选一"
+// @Param X-API-Key header string false "租户API Key，只能查看本公司记录"
+// @Param start_time query string false "发送时间开始，格式：yyyy-mm-dd hh:mi:ss"
+// @Param end_time query string false "发送时间结束，格式：yyyy-mm-dd hh:mi:ss"
+// @Param owner_id query uint false "机器人所属公司ID，使用租户API Key时默认为Key绑定的公司"
+// @Param tags query string false "只对比这些标签，逗号分隔，为空时全部标签"
+// @Success 200 {object} APIResponse{data=[]RobotTagSendStats} "查询成功，按发送总数降序"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /audits/tag-stats [get]
+func (rm *RouterManager) getRobotTagSendStats(c *gin.Context) {
+	var req RobotTagStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+	if !rm.checkTimeParams(c, req.StartTime, req.EndTime) {
+		return
+	}
+
+	result, err := rm.service.GetRobotTagSendStats(req)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询标签发送统计失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", result)
+}
+
+// exportSendAudits 导出发送审计记录为CSV
+// @Summary 导出发送审计记录
+// @Description 按发送时间、目标群、发送账号过滤，流式导出审计记录为CSV文件；需在请求头 X-Admin-Token 中携带管理员令牌，或携带 X-API-Key 查看本公司记录
+// @Tags audits
+// @Produce text/csv
+// @Param X-Admin-Token header string false "管理员令牌，与X-API-Key二选一"
+// @Param X-API-Key header string false "租户API Key，只能查看本公司记录"
+// @Param start_time query string false "发送时间开始，格式：yyyy-mm-dd hh:mi:ss"
+// @Param end_time query string false "发送时间结束，格式：yyyy-mm-dd hh:mi:ss"
+// @Param to_user_name query string false "目标群ID"
+// @Param sender_wx_id query string false "发送账号微信ID"
+// @Param owner_id query uint false "发送账号所属公司ID，使用租户API Key时默认为Key绑定的公司"
+// @Success 200 {file} file "CSV文件"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Router /audits/export [get]
+func (rm *RouterManager) exportSendAudits(c *gin.Context) {
+	var filter SendAuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &filter.OwnerID) {
+		return
+	}
+	if !rm.checkTimeParams(c, filter.StartTime, filter.EndTime) {
+		return
+	}
+
+	filename := fmt.Sprintf("send_audits_%s.csv", time.Now().In(displayLocation).Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	// 写入UTF-8 BOM，避免Excel打开中文乱码
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"发送时间", "发送账号", "目标群", "消息类型", "内容", "内容哈希", "是否成功", "失败原因"})
+
+	count := 0
+	err := rm.service.ExportSendAudits(filter, func(audit *WxSendAudit) error {
+		count++
+		if err := writer.Write([]string{
+			FormatTime(audit.CreateTime),
+			audit.SenderWxID,
+			audit.ToUserName,
+			audit.MsgType,
+			audit.Content,
+			audit.ContentHash,
+			strconv.FormatBool(audit.Success),
+			audit.Error,
+		}); err != nil {
+			return err
+		}
+		// 每500行刷新一次，边查边写
+		if count%500 == 0 {
+			writer.Flush()
+			return writer.Error()
+		}
+		return nil
+	})
+	writer.Flush()
+
+	// 已开始输出文件内容，出错时只能记录日志
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		rm.logger.Error("导出审计记录失败", zap.Int("exported", count), zap.Error(err))
+		return
+	}
+	rm.logger.Info("导出审计记录完成",
+		zap.String("to_user_name", filter.ToUserName),
+		zap.String("sender_wx_id", filter.SenderWxID),
+		zap.Int("count", count))
+}
+
+// searchGroupMessages 按关键词搜索群消息
+// @Summary 搜索群消息
+// @Description 按消息内容关键词分页搜索入库的群消息，支持按群、所属公司和消息时间范围过滤，按消息时间倒序
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param keyword query string true "消息内容关键词"
+// @Param group_id query string false "群组ID"
+// @Param owner_id query uint false "所属公司ID"
+// @Param start_time query string false "消息时间开始，格式：yyyy-mm-dd hh:mi:ss"
+// @Param end_time query string false "消息时间结束，格式：yyyy-mm-dd hh:mi:ss"
+// @Param page_no query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认10，最大100"
+// @Success 200 {object} APIResponse{data=GroupMessageSearchResponse} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /messages/group/search [get]
+func (rm *RouterManager) searchGroupMessages(c *gin.Context) {
+	var req GroupMessageSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+	req.Keyword = strings.TrimSpace(req.Keyword)
+	if req.Keyword == "" {
+		rm.badRequestResponse(c, "关键词不能为空")
+		return
+	}
+	if !rm.checkTimeParams(c, req.StartTime, req.EndTime) {
+		return
+	}
+
+	result, err := rm.service.SearchGroupMessages(req)
+	if err != nil {
+		rm.internalErrorResponse(c, "搜索群消息失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", result)
+}
+
+// messageCallback 接收群消息回调
+// @Summary 接收群消息回调
+// @Description 接收底层推送的群消息，保存到群消息表，并按规则自动识别账单入库
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body MessageCallbackRequest true "群消息"
+// @Success 200 {object} APIResponse{data=WxGroupMessage} "处理成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /messages/callback [post]
+func (rm *RouterManager) messageCallback(c *gin.Context) {
+	var req MessageCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if !rm.checkWxIDOwner(c, req.WxID) {
+		return
+	}
+
+	msg, err := rm.service.HandleGroupMessage(&req)
+	if err != nil {
+		rm.internalErrorResponse(c, "处理群消息失败")
+		return
+	}
+	if msg == nil {
+		rm.successResponse(c, "消息类型不处理，已忽略", nil)
+		return
+	}
+
+	rm.successResponse(c, "处理成功", msg)
+}
+
+// listGroups 分页查询全部群组
+// @Summary 分页查询全部群组
+// @Description 跨账号分页查询系统内全部群组，同一群被多个账号加入时合并为一条并返回覆盖的账号；支持按所属公司、群名称过滤
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param group_nick_name query string false "群名称（模糊匹配）"
+// @Param owner_id query uint false "所属公司ID"
+// @Param page_no query int false "页码，默认1" default(1) minimum(1)
+// @Param page_size query int false "每页大小，默认10" default(10) minimum(1) maximum(100)
+// @Success 200 {object} APIResponse{data=GroupListPaginatedResponse} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /groups [get]
+func (rm *RouterManager) listGroups(c *gin.Context) {
+	var req GroupListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+	req.GroupNickName = strings.TrimSpace(req.GroupNickName)
+
+	result, err := rm.service.ListGroups(req)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询群组失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", result)
+}
+
+// getGroupsByWxID 获取指定用户的群组列表
+// @Summary 获取用户群组列表
+// @Description 获取指定微信用户的所有群组信息
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param wxId path string true "微信ID"
+// @Success 200 {object} APIResponse{data=[]WxGroup} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /groups/user/{wxId} [get]
+func (rm *RouterManager) getGroupsByWxID(c *gin.Context) {
+	wxId := c.Param("wxId")
+	if wxId == "" {
+		rm.badRequestResponse(c, "微信ID不能为空")
+		return
+	}
+
+	if !rm.checkWxIDOwner(c, wxId) {
+		return
+	}
+
+	groups, err := rm.service.GetGroupsByWxID(wxId)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询用户群组列表失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", groups)
+}
+
+// exportGroups 导出群组为CSV
+// @Summary 导出群组
+// @Description 按账号或所属公司过滤，流式导出群组为CSV文件，每个账号所在的群一行，包含账号、群ID、群名、成员数、群主和创建时间
+// @Tags groups
+// @Produce text/csv
+// @Param wx_id query string false "微信ID，只导出该账号所在的群"
+// @Param owner_id query uint false "所属公司ID，只导出该公司账号所在的群"
+// @Success 200 {file} file "CSV文件"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Router /groups/export [get]
+func (rm *RouterManager) exportGroups(c *gin.Context) {
+	var req GroupExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+
+	filename := fmt.Sprintf("groups_%s.csv", time.Now().In(displayLocation).Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	// 写入UTF-8 BOM，避免Excel打开中文乱码
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"微信ID", "群ID", "群名称", "成员数", "群主", "创建时间"})
+
+	count := 0
+	err := rm.service.ExportGroups(req, func(group *WxGroup) error {
+		count++
+		if err := writer.Write([]string{
+			group.WxID,
+			group.GroupID,
+			group.GroupNickName,
+			strconv.Itoa(group.MemberCount),
+			group.ChatRoomOwner,
+			FormatTime(group.CreateTime),
+		}); err != nil {
+			return err
+		}
+		// 每500行刷新一次，边查边写
+		if count%500 == 0 {
+			writer.Flush()
+			return writer.Error()
+		}
+		return nil
+	})
+	writer.Flush()
+
+	// 已开始输出文件内容，出错时只能记录日志
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		rm.logger.Error("导出群组失败", zap.Int("exported", count), zap.Error(err))
+		return
+	}
+	rm.logger.Info("导出群组完成",
+		zap.String("wx_id", req.WxID),
+		zap.Uint("owner_id", req.OwnerID),
+		zap.Int("count", count))
+}
+
+// searchGroupsByName 按群名称模糊搜索群组
+// @Summary 搜索群组
+// @Description 根据群名称进行模糊搜索
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param groupNickName query string true "群名称"
+// @Success 200 {object} APIResponse{data=[]WxGroup} "搜索成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /groups/search [get]
+func (rm *RouterManager) searchGroupsByName(c *gin.Context) {
+	groupNickName := c.Query("groupNickName")
+	if groupNickName == "" {
+		rm.badRequestResponse(c, "群名称参数不能为空")
+		return
+	}
+
+	// 带API Key时只搜索本公司账号所在的群
+	scoped, _ := scopedOwner(c)
+	groups, err := rm.service.SearchGroupsByName(groupNickName, scoped)
+	if err != nil {
+		rm.internalErrorResponse(c, "搜索群组失败")
+		return
+	}
+
+	rm.successResponse(c, "搜索成功", groups)
+}
+
+// getGroupBots 查询可服务该群的消息机器人
+// @Summary 查询群候选消息机器人
+// @Description 返回该群内所有状态正常、已设为消息机器人且无风控的用户及其机器人信息，即发送策略的候选范围
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param groupId path string true "群组ID"
+// @Success 200 {object} APIResponse{data=[]GroupBotCandidate} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /groups/{groupId}/bots [get]
+func (rm *RouterManager) getGroupBots(c *gin.Context) {
+	groupID := c.Param("groupId")
+	if groupID == "" {
+		rm.badRequestResponse(c, "群组ID不能为空")
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		rm.badRequestResponse(c, "参数错误: "+err.Error())
+	if !rm.checkGroupOwner(c, groupID) {
 		return
 	}
 
-	// 检查至少有一个内容不为空
-	if req.TextContent == "" && req.ImageContent == "" {
-		rm.badRequestResponse(c, "文本内容和图片内容不能都为空")
+	// 带API Key时只返回本公司的消息机器人
+	scoped, _ := scopedOwner(c)
+	bots, err := rm.service.GetGroupMessageBots(groupID, scoped)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询消息机器人失败")
 		return
 	}
 
-	// 通过策略获取消息机器人信息
-	botInfo, err := rm.service.GetMessageBotByStrategy(req.ToUserName, rm.messageSendStrategy)
+	rm.successResponse(c, "查询成功", bots)
+}
+
+// getAvailableMessageBots 查询当前所有可用的消息机器人
+// @Summary 查询可用消息机器人
+// @Description 列出所有状态正常、已设为消息机器人且无风控的用户及其覆盖群数，支持按所属公司过滤
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param owner_id query uint false "所属公司ID，不传查询全部"
+// @Success 200 {object} APIResponse{data=AvailableMessageBotsResponse} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /message-bots [get]
+func (rm *RouterManager) getAvailableMessageBots(c *gin.Context) {
+	var req AvailableMessageBotsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+
+	resp, err := rm.service.GetAvailableMessageBots(req.OwnerID)
 	if err != nil {
-		rm.notFoundResponse(c, "未找到对应的消息机器人")
+		rm.internalErrorResponse(c, "查询可用消息机器人失败")
 		return
 	}
 
-	// 构建发送请求
-	sendReq := &SendTextAndImageRequest{
-		TextContent:  req.TextContent,
-		ImageContent: req.ImageContent,
-		ToUserName:   req.ToUserName,
+	rm.successResponse(c, "查询成功", resp)
+}
+
+// getBotCoverageGaps 分析缺少消息机器人覆盖的群
+// @Summary 消息机器人覆盖缺口
+// @Description 列出群内没有可用消息机器人的群并给出分配建议：群内已有正常账号时建议设为消息机器人（enable_message_bot），否则建议同公司覆盖群数最少的消息机器人加群（join_group）
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param owner_id query uint false "所属公司ID，不传查询全部"
+// @Param limit query int false "每个群的加群建议数量，默认3，最大10"
+// @Success 200 {object} APIResponse{data=BotCoverageResponse} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /groups/bot-coverage [get]
+func (rm *RouterManager) getBotCoverageGaps(c *gin.Context) {
+	var req BotCoverageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
 	}
 
-	// 调用服务发送文字和图片
-	resp, err := rm.service.SendTextAndImage(botInfo.Robot.Address, botInfo.User.Token, sendReq)
+	resp, err := rm.service.GetBotCoverageGaps(req)
 	if err != nil {
-		rm.logger.Error("发送文字和图片失败", zap.Error(err))
-		rm.internalErrorResponse(c, "发送文字和图片失败: "+err.Error())
+		rm.internalErrorResponse(c, "查询消息机器人覆盖缺口失败")
 		return
 	}
 
-	rm.successResponse(c, "消息发送完成", resp)
+	rm.successResponse(c, "查询成功", resp)
 }
 
-// setMessageStrategy 设置消息发送策略
-// @Summary 设置消息发送策略
-// @Description 设置系统的消息发送策略（随机或轮询）
-// @Tags messages
+// listGroupBlacklist 查询群黑名单
+// @Summary 查询群黑名单
+// @Description 查询禁止机器人发送消息的群
+// @Tags groups
 // @Accept json
 // @Produce json
-// @Param request body object{strategy=string} true "策略参数 (random/round_robin)"
-// @Success 200 {object} APIResponse "设置成功"
-// @Failure 400 {object} APIResponse "参数错误"
-// @Router /messages/group/set-strategy [post]
-func (rm *RouterManager) setMessageStrategy(c *gin.Context) {
-	var req struct {
-		Strategy string `json:"strategy" binding:"required"` // round_robin, random
+// @Success 200 {object} APIResponse{data=[]WxGroupBlacklist} "查询成功"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /groups/blacklist [get]
+func (rm *RouterManager) listGroupBlacklist(c *gin.Context) {
+	// 带API Key时只返回本公司账号所在的群
+	scoped, _ := scopedOwner(c)
+	list, err := rm.service.ListGroupBlacklist(scoped)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询群黑名单失败")
+		return
 	}
 
+	rm.successResponse(c, "查询成功", list)
+}
+
+// addGroupBlacklist 添加群黑名单
+// @Summary 添加群黑名单
+// @Description 将群加入黑名单，之后所有发送接口向该群发送时都会被拒绝
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param request body AddGroupBlacklistRequest true "黑名单群"
+// @Success 200 {object} APIResponse{data=WxGroupBlacklist} "添加成功"
+// @Failure 400 {object} APIResponse "参数错误或群已在黑名单中"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /groups/blacklist [post]
+func (rm *RouterManager) addGroupBlacklist(c *gin.Context) {
+	var req AddGroupBlacklistRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		rm.badRequestResponse(c, "参数错误: "+err.Error())
 		return
 	}
 
-	switch req.Strategy {
-	case "round_robin":
-		rm.messageSendStrategy = NewRoundRobinMessageSendStrategy()
-		rm.logger.Info("消息发送策略已切换为: 轮询")
-	case "random":
-		rm.messageSendStrategy = NewRandomMessageSendStrategy()
-		rm.logger.Info("消息发送策略已切换为: 随机")
-	default:
-		rm.badRequestResponse(c, "无效的策略类型，支持: round_robin, random")
+	if !rm.checkGroupOwner(c, req.GroupID) {
 		return
 	}
 
-	rm.successResponse(c, "策略设置成功", map[string]string{
-		"strategy": req.Strategy,
-	})
+	item, err := rm.service.AddGroupBlacklist(&req)
+	if err != nil {
+		if errors.Is(err, ErrGroupAlreadyBlacklisted) {
+			rm.badRequestResponse(c, err.Error())
+			return
+		}
+		rm.internalErrorResponse(c, "添加群黑名单失败")
+		return
+	}
+
+	rm.successResponse(c, "添加成功", item)
 }
 
-// getGroupsByWxID 获取指定用户的群组列表
-// @Summary 获取用户群组列表
-// @Description 获取指定微信用户的所有群组信息
+// removeGroupBlacklist 移除群黑名单
+// @Summary 移除群黑名单
+// @Description 将群移出黑名单，恢复正常发送
 // @Tags groups
 // @Accept json
 // @Produce json
-// @Param wxId path string true "微信ID"
-// @Success 200 {object} APIResponse{data=[]WxGroup} "查询成功"
-// @Failure 400 {object} APIResponse "参数错误"
+// @Param groupId path string true "群组ID"
+// @Success 200 {object} APIResponse "移除成功"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "群不在黑名单中"
 // @Failure 500 {object} APIResponse "内部服务器错误"
-// @Router /groups/user/{wxId} [get]
-func (rm *RouterManager) getGroupsByWxID(c *gin.Context) {
-	wxId := c.Param("wxId")
-	if wxId == "" {
-		rm.badRequestResponse(c, "微信ID不能为空")
+// @Router /groups/blacklist/{groupId} [delete]
+func (rm *RouterManager) removeGroupBlacklist(c *gin.Context) {
+	if !rm.checkGroupOwner(c, c.Param("groupId")) {
 		return
 	}
 
-	groups, err := rm.service.GetGroupsByWxID(wxId)
-	if err != nil {
-		rm.internalErrorResponse(c, "查询用户群组列表失败")
+	if err := rm.service.RemoveGroupBlacklist(c.Param("groupId")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rm.notFoundResponse(c, "群不在黑名单中")
+			return
+		}
+		rm.internalErrorResponse(c, "移除群黑名单失败")
 		return
 	}
 
-	rm.successResponse(c, "查询成功", groups)
+	rm.successResponse(c, "移除成功", nil)
 }
 
-// searchGroupsByName 按群名称模糊搜索群组
-// @Summary 搜索群组
-// @Description 根据群名称进行模糊搜索
+// getGroupChanges 查询时间段内新增/流失的群
+// @Summary 查询群组变化
+// @Description 查询时间段内机器人新进的群（按群记录创建时间）和退出的群（按退群记录）
 // @Tags groups
 // @Accept json
 // @Produce json
-// @Param groupNickName query string true "群名称"
-// @Success 200 {object} APIResponse{data=[]WxGroup} "搜索成功"
+// @Param wx_id query string false "机器人微信ID，不传查询全部"
+// @Param owner_id query uint false "所属公司ID，不传查询全部"
+// @Param start_time query string true "开始时间，格式：yyyy-mm-dd hh:mi:ss"
+// @Param end_time query string true "结束时间，格式：yyyy-mm-dd hh:mi:ss"
+// @Success 200 {object} APIResponse{data=GroupChangesResponse} "查询成功"
 // @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
 // @Failure 500 {object} APIResponse "内部服务器错误"
-// @Router /groups/search [get]
-func (rm *RouterManager) searchGroupsByName(c *gin.Context) {
-	groupNickName := c.Query("groupNickName")
-	if groupNickName == "" {
-		rm.badRequestResponse(c, "群名称参数不能为空")
+// @Router /groups/changes [get]
+func (rm *RouterManager) getGroupChanges(c *gin.Context) {
+	var req GroupChangesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
 		return
 	}
 
-	groups, err := rm.service.SearchGroupsByName(groupNickName)
+	start, err := ParseTime(req.StartTime)
 	if err != nil {
-		rm.internalErrorResponse(c, "搜索群组失败")
+		rm.badRequestResponse(c, "开始时间格式错误")
+		return
+	}
+	end, err := ParseTime(req.EndTime)
+	if err != nil {
+		rm.badRequestResponse(c, "结束时间格式错误")
+		return
+	}
+	if end.Before(start) {
+		rm.badRequestResponse(c, "结束时间不能早于开始时间")
 		return
 	}
 
-	rm.successResponse(c, "搜索成功", groups)
+	changes, err := rm.service.GetGroupChanges(req.WxID, req.OwnerID, start, end)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询群组变化失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", changes)
 }
 
 // updateMessageBotStatus 更新消息机器人状态
@@ -1114,6 +3441,8 @@ func (rm *RouterManager) searchGroupsByName(c *gin.Context) {
 // @Param request body object{is_message_bot=int} true "消息机器人状态"
 // @Success 200 {object} APIResponse "更新成功"
 // @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "用户不存在"
 // @Failure 500 {object} APIResponse "内部服务器错误"
 // @Router /users/message-bot-status/{id} [post]
 func (rm *RouterManager) updateMessageBotStatus(c *gin.Context) {
@@ -1145,6 +3474,10 @@ func (rm *RouterManager) updateMessageBotStatus(c *gin.Context) {
 		return
 	}
 
+	if !rm.checkUserOwner(c, uint(parsedId)) {
+		return
+	}
+
 	// 调用服务更新消息机器人状态
 	if err := rm.service.UpdateMessageBotStatus(uint(parsedId), req.IsMessageBot); err != nil {
 		rm.internalErrorResponse(c, "更新消息机器人状态失败")
@@ -1159,7 +3492,7 @@ func (rm *RouterManager) updateMessageBotStatus(c *gin.Context) {
 
 // getBillStatistics 获取账单统计信息（分页）
 // @Summary 获取账单统计信息（分页）
-// @Description 根据群组ID和群组昵称获取账单统计信息，按group_id和group_name分组统计金额总数，支持分页
+// @Description 根据群组ID和群组昵称获取账单统计信息，默认按group_id和group_name分组统计金额总数，group_by=operator时按操作人分组，支持分页；每个分组项的detail_filter可直接作为 /bills/list 的查询参数下钻明细；compare=true时在comparison中返回与上一等长时间段的环比
 // @Tags bills
 // @Accept json
 // @Produce json
@@ -1168,6 +3501,11 @@ func (rm *RouterManager) updateMessageBotStatus(c *gin.Context) {
 // @Param page_no query int false "页码，默认1" default(1) minimum(1)
 // @Param page_size query int false "每页大小，默认10" default(10) minimum(1) maximum(100)
 // @Param owner_id query uint true "所属公司ID"
+// @Param group_by query string false "分组维度：group 按群（默认）、operator 按操作人" Enums(group, operator)
+// @Param format query string false "金额格式：plain 原样（默认）、currency 货币符号加千分位、thousands 千分位" Enums(plain, currency, thousands)
+// @Param create_time_start query string false "账单时间开始，格式：yyyy-mm-dd hh:mi:ss"
+// @Param create_time_end query string false "账单时间结束，格式：yyyy-mm-dd hh:mi:ss"
+// @Param compare query bool false "是否返回与上一等长时间段的环比，需同时传开始和结束时间"
 // @Success 200 {object} APIResponse{data=BillStatsPaginatedResponse} "获取成功"
 // @Failure 400 {object} APIResponse "参数错误"
 // @Failure 500 {object} APIResponse "内部服务器错误"
@@ -1178,6 +3516,18 @@ func (rm *RouterManager) getBillStatistics(c *gin.Context) {
 		rm.badRequestResponse(c, "参数错误: "+err.Error())
 		return
 	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+	if !rm.checkTimeParams(c, req.CreateTimeStart, req.CreateTimeEnd) {
+		return
+	}
+	if req.Compare {
+		if _, _, ok := billStatsRange(req); !ok {
+			rm.badRequestResponse(c, ErrBillCompareRange.Error())
+			return
+		}
+	}
 
 	// 设置默认值
 	if req.PageNo <= 0 {
@@ -1195,6 +3545,13 @@ func (rm *RouterManager) getBillStatistics(c *gin.Context) {
 		rm.internalErrorResponse(c, "获取账单统计失败")
 		return
 	}
+	for i := range stats.List {
+		stats.List[i].TotalAmount = formatAmount(stats.List[i].TotalAmount, req.Format, rm.cfg.BillFormat.CurrencySymbol)
+	}
+	if stats.Comparison != nil {
+		stats.Comparison.CurrentAmount = formatAmount(stats.Comparison.CurrentAmount, req.Format, rm.cfg.BillFormat.CurrencySymbol)
+		stats.Comparison.PreviousAmount = formatAmount(stats.Comparison.PreviousAmount, req.Format, rm.cfg.BillFormat.CurrencySymbol)
+	}
 
 	rm.successResponse(c, "获取成功", stats)
 }
@@ -1209,9 +3566,11 @@ func (rm *RouterManager) getBillStatistics(c *gin.Context) {
 // @Param group_name query string false "群名称"
 // @Param group_id query string false "群ID"
 // @Param status query string false "账单状态"
+// @Param operator query string false "操作人"
 // @Param page_num query int false "页码，默认1"
 // @Param page_size query int false "每页大小，默认10，最大100"
 // @Param owner_id query uint true "所属公司ID"
+// @Param format query string false "金额格式：plain 原样（默认）、currency 货币符号加千分位、thousands 千分位" Enums(plain, currency, thousands)
 // @Success 200 {object} APIResponse{data=BillQueryPaginatedResponse}
 // @Failure 400 {object} APIResponse
 // @Failure 500 {object} APIResponse
@@ -1222,6 +3581,9 @@ func (rm *RouterManager) getBillList(c *gin.Context) {
 		rm.badRequestResponse(c, "参数错误: "+err.Error())
 		return
 	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
 
 	// 设置默认值
 	if req.PageNum <= 0 {
@@ -1239,13 +3601,214 @@ func (rm *RouterManager) getBillList(c *gin.Context) {
 		rm.internalErrorResponse(c, "查询账单列表失败")
 		return
 	}
+	for i := range billList.List {
+		billList.List[i].Amount = formatAmount(billList.List[i].Amount, req.Format, rm.cfg.BillFormat.CurrencySymbol)
+	}
 
 	rm.successResponse(c, "查询成功", billList)
 }
 
+// getRecentBillsByGroup 查询群最近账单
+// @Summary 查询群最近账单
+// @Description 按账单时间倒序返回指定群最近的limit笔账单，只返回属于owner_id所属公司的账单
+// @Tags bills
+// @Accept json
+// @Produce json
+// @Param groupId path string true "群ID"
+// @Param owner_id query uint true "所属公司ID"
+// @Param limit query int false "返回条数，默认10，最大100" default(10) minimum(1) maximum(100)
+// @Success 200 {object} APIResponse{data=[]BillInfoResponse} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /bills/group/{groupId}/recent [get]
+func (rm *RouterManager) getRecentBillsByGroup(c *gin.Context) {
+	var req RecentBillsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+	if !rm.checkOwnerScope(c, &req.OwnerID) {
+		return
+	}
+
+	bills, err := rm.service.GetRecentBillsByGroup(c.Param("groupId"), req.OwnerID, req.Limit)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询群最近账单失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", bills)
+}
+
+// checkRobotsHealth 批量检查机器人健康状态
+// @Summary 批量检查机器人健康状态
+// @Description 并发检查多个机器人的健康状态，返回每个机器人的状态和响应时间；不传ids时检查全部机器人，带API Key时只检查本公司的机器人
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param ids query string false "机器人ID列表，逗号分隔"
+// @Success 200 {object} APIResponse{data=RobotHealthSummary} "检查完成"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /robots/health [get]
+func (rm *RouterManager) checkRobotsHealth(c *gin.Context) {
+	var robotIDs []uint
+	if idsStr := strings.TrimSpace(c.Query("ids")); idsStr != "" {
+		for _, idStr := range strings.Split(idsStr, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(idStr), 10, 32)
+			if err != nil {
+				rm.badRequestResponse(c, "机器人ID格式错误: "+idStr)
+				return
+			}
+			robotIDs = append(robotIDs, uint(id))
+		}
+	}
+
+	scoped, _ := scopedOwner(c)
+	summary, err := rm.service.CheckRobotsHealth(c.Request.Context(), robotIDs, scoped)
+	if err != nil {
+		rm.internalErrorResponse(c, "批量检查机器人健康状态失败")
+		return
+	}
+
+	rm.successResponse(c, "检查完成", summary)
+}
+
+// rotateAdminKey 轮换机器人管理密钥
+// @Summary 轮换机器人管理密钥
+// @Description 新管理密钥需先在底层服务配置好；先用新密钥调用底层接口验证可用，验证通过后才更新数据库，验证失败时保留原密钥。需在请求头 X-Admin-Token 中携带管理员令牌
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "管理员令牌"
+// @Param id path uint true "机器人ID"
+// @Param request body RotateAdminKeyRequest true "新管理密钥"
+// @Success 200 {object} APIResponse{data=RotateAdminKeyResponse} "轮换成功"
+// @Failure 400 {object} APIResponse "参数错误或新密钥验证失败"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Failure 404 {object} APIResponse "机器人不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /robots/{id}/rotate-admin-key [post]
+func (rm *RouterManager) rotateAdminKey(c *gin.Context) {
+	robotId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "机器人ID格式错误")
+		return
+	}
+
+	var req RotateAdminKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if !rm.checkRobotIDOwner(c, uint(robotId)) {
+		return
+	}
+
+	resp, err := rm.service.RotateAdminKey(c.Request.Context(), uint(robotId), req.NewAdminKey, robotChangeOperator(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			rm.notFoundResponse(c, "机器人不存在")
+		case errors.Is(err, ErrAdminKeyUnchanged), errors.Is(err, ErrAdminKeyVerifyFailed):
+			rm.badRequestResponse(c, err.Error())
+		default:
+			rm.internalErrorResponse(c, "轮换管理密钥失败: "+err.Error())
+		}
+		return
+	}
+
+	rm.successResponse(c, "轮换成功", resp)
+}
+
+// getRobotAuthKeys 查询机器人已生成的授权码
+// @Summary 查询机器人授权码
+// @Description 查询机器人通过授权接口生成的全部授权码及分配状态，有账号使用该授权码登录即为已分配，未分配的码可复用；需在请求头携带 X-Admin-Token 或本公司的 X-API-Key
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string false "管理员令牌，与X-API-Key二选一"
+// @Param X-API-Key header string false "租户API Key，只能查看本公司机器人"
+// @Param id path uint true "机器人ID"
+// @Param status query string false "分配状态，不传查询全部" Enums(assigned, unassigned)
+// @Success 200 {object} APIResponse{data=AuthKeyListResponse} "查询成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 401 {object} APIResponse "鉴权失败"
+// @Failure 404 {object} APIResponse "机器人不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /robots/{id}/auth-keys [get]
+func (rm *RouterManager) getRobotAuthKeys(c *gin.Context) {
+	robotId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "机器人ID格式错误")
+		return
+	}
+
+	var req AuthKeyListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		rm.badRequestResponse(c, "参数错误: "+err.Error())
+		return
+	}
+
+	robot, err := rm.service.GetRobotByID(uint(robotId))
+	if err != nil {
+		rm.notFoundResponse(c, "机器人不存在")
+		return
+	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
+
+	resp, err := rm.service.ListAuthKeys(robot.ID, req.Status)
+	if err != nil {
+		rm.internalErrorResponse(c, "查询授权码失败")
+		return
+	}
+
+	rm.successResponse(c, "查询成功", resp)
+}
+
+// getRobotSummary 获取机器人概览
+// @Summary 获取机器人概览
+// @Description 汇总机器人下挂载的用户数、各状态（正常/风控/需重登）分布、初始化情况及是否有可用的消息机器人
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param id path uint true "机器人ID"
+// @Success 200 {object} APIResponse{data=RobotSummaryResponse} "获取成功"
+// @Failure 400 {object} APIResponse "参数错误"
+// @Failure 403 {object} APIResponse "无权访问其他公司的数据"
+// @Failure 404 {object} APIResponse "机器人不存在"
+// @Failure 500 {object} APIResponse "内部服务器错误"
+// @Router /robots/{id}/summary [get]
+func (rm *RouterManager) getRobotSummary(c *gin.Context) {
+	robotId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		rm.badRequestResponse(c, "机器人ID格式错误")
+		return
+	}
+
+	if !rm.checkRobotIDOwner(c, uint(robotId)) {
+		return
+	}
+
+	summary, err := rm.service.GetRobotSummary(uint(robotId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rm.notFoundResponse(c, "机器人不存在")
+			return
+		}
+		rm.internalErrorResponse(c, "获取机器人概览失败")
+		return
+	}
+
+	rm.successResponse(c, "获取成功", summary)
+}
+
 // checkRobotHealth 检查机器人健康状态
 // @Summary 检查机器人健康状态
-// @Description 通过HTTP请求检查指定机器人的健康状态
+// @Description 通过HTTP GET请求机器人地址拼接健康检查路径（health_path，默认 /），返回200视为健康
 // @Tags robots
 // @Accept json
 // @Produce json
@@ -1275,10 +3838,13 @@ func (rm *RouterManager) checkRobotHealth(c *gin.Context) {
 		rm.notFoundResponse(c, "机器人不存在")
 		return
 	}
+	if !rm.checkRobotOwner(c, robot) {
+		return
+	}
 
 	// 检查机器人健康状态
 	startTime := time.Now()
-	isHealthy, err := rm.service.CheckRobotHealth(robot.Address)
+	isHealthy, err := rm.service.CheckRobotHealth(withRobotTimeout(c.Request.Context(), robot), robot.Address, robot.HealthPath)
 	responseTime := time.Since(startTime)
 
 	if err != nil {
@@ -0,0 +1,1249 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateUserRemark(t *testing.T) {
+	router, _, db := newTestRouter(t, nil)
+	user := &WxUserLogin{WxID: "wxid_remark", Token: "token-remark", Remark: "旧备注"}
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot.invalid", OwnerID: 1}, user)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+		wantRemark string
+	}{
+		{name: "set remark", id: fmt.Sprint(user.ID), body: `{"remark":"  客服一号  "}`, wantStatus: http.StatusOK, wantRemark: "客服一号"},
+		{name: "clear remark", id: fmt.Sprint(user.ID), body: `{"remark":""}`, wantStatus: http.StatusOK, wantRemark: ""},
+		{name: "too long", id: fmt.Sprint(user.ID), body: `{"remark":"` + strings.Repeat("备", 201) + `"}`, wantStatus: http.StatusBadRequest, wantRemark: ""},
+		{name: "invalid id", id: "abc", body: `{"remark":"x"}`, wantStatus: http.StatusBadRequest, wantRemark: ""},
+		{name: "unknown user", id: fmt.Sprint(user.ID + 100), body: `{"remark":"x"}`, wantStatus: http.StatusNotFound, wantRemark: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(router, http.MethodPut, "/users/"+tt.id+"/remark", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var saved WxUserLogin
+			db.First(&saved, user.ID)
+			if saved.Remark != tt.wantRemark {
+				t.Fatalf("remark = %q, want %q", saved.Remark, tt.wantRemark)
+			}
+		})
+	}
+}
+
+func TestGetQRCodeCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		check         bool
+		reloginOK     bool
+		wantMode      string
+		wantChecks    []bool
+		wantQRCodeURL string
+	}{
+		{name: "scan login", check: false, wantMode: LoginModeQRCode, wantChecks: []bool{false}, wantQRCodeURL: "http://qr"},
+		{name: "relogin succeeds", check: true, reloginOK: true, wantMode: LoginModeRelogin, wantChecks: []bool{true}},
+		{name: "relogin falls back to scan", check: true, wantMode: LoginModeQRCode, wantChecks: []bool{true, false}, wantQRCodeURL: "http://qr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checks []bool
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.GetLoginQrCode: func(w http.ResponseWriter, r *http.Request) {
+					var req GetLoginQrCodeRequest
+					json.NewDecoder(r.Body).Decode(&req)
+					checks = append(checks, req.Check)
+					switch {
+					case !req.Check:
+						jsonHandler(map[string]interface{}{"Code": 200, "Data": map[string]interface{}{"QrCodeUrl": "http://qr"}})(w, r)
+					case tt.reloginOK:
+						jsonHandler(map[string]interface{}{"Code": 200})(w, r)
+					default:
+						jsonHandler(map[string]interface{}{"Code": -1, "Text": "设备未登录过"})(w, r)
+					}
+				},
+			})
+			router, _, db := newTestRouter(t, nil)
+			robot := &WxRobotConfig{Address: server.URL, OwnerID: 1}
+			createTestRobot(t, db, robot)
+
+			body := fmt.Sprintf(`{"token":"abc","robot_id":%d,"check":%v}`, robot.ID, tt.check)
+			w := doRequest(router, http.MethodPost, "/users/qrcode", body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+			var resp struct {
+				Data QRCodeResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Data.LoginMode != tt.wantMode || resp.Data.QRCode != tt.wantQRCodeURL {
+				t.Fatalf("response = %+v, want mode %q qr %q", resp.Data, tt.wantMode, tt.wantQRCodeURL)
+			}
+			if !reflect.DeepEqual(checks, tt.wantChecks) {
+				t.Fatalf("upstream check flags = %v, want %v", checks, tt.wantChecks)
+			}
+		})
+	}
+}
+
+func TestPatchRobot(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         func(robot *WxRobotConfig) string
+		body       string
+		wantStatus int
+		check      func(t *testing.T, robot WxRobotConfig)
+	}{
+		{
+			name: "description only", body: `{"description":"新描述"}`, wantStatus: http.StatusOK,
+			check: func(t *testing.T, robot WxRobotConfig) {
+				if robot.Description != "新描述" || robot.Address != "http://robot.invalid" || robot.AdminKey != "admin-key" || robot.OwnerID != 1 || robot.AdminUsers != "wxid_admin" {
+					t.Fatalf("robot = %+v", robot)
+				}
+			},
+		},
+		{
+			name: "clear description", body: `{"description":""}`, wantStatus: http.StatusOK,
+			check: func(t *testing.T, robot WxRobotConfig) {
+				if robot.Description != "" || robot.AdminUsers != "wxid_admin" {
+					t.Fatalf("robot = %+v", robot)
+				}
+			},
+		},
+		{
+			name: "disable", body: `{"enabled":0}`, wantStatus: http.StatusOK,
+			check: func(t *testing.T, robot WxRobotConfig) {
+				if robot.Enabled != 0 || robot.Description != "旧描述" {
+					t.Fatalf("robot = %+v", robot)
+				}
+			},
+		},
+		{name: "no fields", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "empty required field", body: `{"admin_key":""}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid timeout", body: `{"timeout_seconds":301}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown robot", id: func(robot *WxRobotConfig) string { return fmt.Sprint(robot.ID + 100) }, body: `{"description":"x"}`, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, db := newTestRouter(t, nil)
+			robot := &WxRobotConfig{Address: "http://robot.invalid", AdminKey: "admin-key", OwnerID: 1, Description: "旧描述", AdminUsers: "wxid_admin"}
+			createTestRobot(t, db, robot)
+
+			id := fmt.Sprint(robot.ID)
+			if tt.id != nil {
+				id = tt.id(robot)
+			}
+			w := doRequest(router, http.MethodPatch, "/robots/"+id, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.check != nil {
+				var saved WxRobotConfig
+				db.First(&saved, robot.ID)
+				tt.check(t, saved)
+			}
+		})
+	}
+}
+
+func TestSendResponseIncludesSender(t *testing.T) {
+	const groupID = "sender@chatroom"
+	sendOK := jsonHandler(sendSuccessResponse(1))
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "send text", path: "/messages/group/send-text", body: `{"text_content":"你好","to_user_name":"` + groupID + `"}`},
+		{name: "send image", path: "/messages/group/send-image", body: `{"image_content":"aGVsbG8=","to_user_name":"` + groupID + `"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendTextMessage:     sendOK,
+				defaultWxAPIEndpoints.SendImageNewMessage: sendOK,
+			})
+			router, _, db := newTestRouter(t, nil)
+			robot := &WxRobotConfig{Address: server.URL, OwnerID: 1}
+			bot := &WxUserLogin{WxID: "wxid_bot", NickName: "客服", Token: "token-sender-123456", IsMessageBot: 1}
+			createTestRobot(t, db, robot, bot)
+			db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+
+			w := doRequest(router, http.MethodPost, tt.path, tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+			var resp struct {
+				Data struct {
+					Sender SenderInfo `json:"sender"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			want := SenderInfo{RobotID: robot.ID, UserID: bot.ID, WxID: bot.WxID, NickName: bot.NickName, Token: maskToken(bot.Token)}
+			if resp.Data.Sender != want {
+				t.Fatalf("sender = %+v, want %+v", resp.Data.Sender, want)
+			}
+			if strings.Contains(w.Body.String(), bot.Token) {
+				t.Fatal("response leaks full token")
+			}
+		})
+	}
+}
+
+func TestGetUserByToken(t *testing.T) {
+	f := newTenantFixture(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		headers    []string
+		wantStatus int
+		wantWxID   string
+	}{
+		{name: "query token", method: http.MethodGet, path: "/users/by-token?token=token-2", headers: []string{AdminTokenHeader, testAdminToken}, wantStatus: http.StatusOK, wantWxID: "wxid_owner2"},
+		{name: "body token", method: http.MethodPost, path: "/users/by-token", body: `{"token":"token-1"}`, headers: []string{AdminTokenHeader, testAdminToken}, wantStatus: http.StatusOK, wantWxID: "wxid_owner1"},
+		{name: "unknown token", method: http.MethodGet, path: "/users/by-token?token=nope", headers: []string{AdminTokenHeader, testAdminToken}, wantStatus: http.StatusNotFound},
+		{name: "missing token", method: http.MethodPost, path: "/users/by-token", body: `{}`, headers: []string{AdminTokenHeader, testAdminToken}, wantStatus: http.StatusBadRequest},
+		{name: "no admin token", method: http.MethodGet, path: "/users/by-token?token=token-1", wantStatus: http.StatusUnauthorized},
+		{name: "tenant key rejected", method: http.MethodGet, path: "/users/by-token?token=token-1", headers: []string{"X-API-Key", "key-1"}, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.do(tt.method, tt.path, tt.body, tt.headers...)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantWxID == "" {
+				return
+			}
+			var resp struct {
+				Data UserByTokenResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Data.WxID != tt.wantWxID || resp.Data.RobotID == 0 || resp.Data.OwnerID == 0 {
+				t.Fatalf("user = %+v, want wx_id %s", resp.Data, tt.wantWxID)
+			}
+			if strings.Contains(w.Body.String(), `"token-`) {
+				t.Fatalf("response leaks token: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExportGroups(t *testing.T) {
+	router, _, db := newTestRouter(t, nil)
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot1.invalid", OwnerID: 1},
+		&WxUserLogin{WxID: "wxid_a", Token: "t1"})
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot2.invalid", OwnerID: 2},
+		&WxUserLogin{WxID: "wxid_b", Token: "t2"})
+	created := time.Date(2024, 5, 1, 2, 30, 0, 0, time.UTC)
+	for _, g := range []WxGroup{
+		{WxID: "wxid_a", GroupID: "1@chatroom", GroupNickName: "销售群, 一部", MemberCount: 120, ChatRoomOwner: "wxid_owner", CreateTime: created},
+		{WxID: "wxid_a", GroupID: "2@chatroom", GroupNickName: "客服群", MemberCount: 30, CreateTime: created},
+		{WxID: "wxid_b", GroupID: "1@chatroom", GroupNickName: "销售群, 一部", MemberCount: 120, ChatRoomOwner: "wxid_owner", CreateTime: created},
+	} {
+		if err := db.Create(&g).Error; err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+
+	header := []string{"微信ID", "群ID", "群名称", "成员数", "群主", "创建时间"}
+	row1 := []string{"wxid_a", "1@chatroom", "销售群, 一部", "120", "wxid_owner", FormatTime(created)}
+	row2 := []string{"wxid_a", "2@chatroom", "客服群", "30", "", FormatTime(created)}
+	row3 := []string{"wxid_b", "1@chatroom", "销售群, 一部", "120", "wxid_owner", FormatTime(created)}
+
+	tests := []struct {
+		name  string
+		query string
+		want  [][]string
+	}{
+		{name: "all", want: [][]string{header, row1, row2, row3}},
+		{name: "by wx_id", query: "?wx_id=wxid_b", want: [][]string{header, row3}},
+		{name: "by owner", query: "?owner_id=1", want: [][]string{header, row1, row2}},
+		{name: "empty", query: "?wx_id=nobody", want: [][]string{header}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(router, http.MethodGet, "/groups/export"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+			if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+				t.Fatalf("Content-Type = %q", w.Header().Get("Content-Type"))
+			}
+			body := strings.TrimPrefix(w.Body.String(), "\xEF\xBB\xBF")
+			records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+			if err != nil {
+				t.Fatalf("parse csv: %v", err)
+			}
+			if !reflect.DeepEqual(records, tt.want) {
+				t.Fatalf("csv = %q, want %q", records, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupBlacklist(t *testing.T) {
+	const groupID = "black@chatroom"
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.SendTextMessage: jsonHandler(sendSuccessResponse(1)),
+	})
+	router, _, db := newTestRouter(t, nil)
+	bot := &WxUserLogin{WxID: "wxid_bot", Token: "token-bot", IsMessageBot: 1}
+	createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+	db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+
+	sendBody := `{"text_content":"你好","to_user_name":"` + groupID + `"}`
+	// 按顺序执行，后一步依赖前一步的黑名单状态
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "send before blacklisted", method: http.MethodPost, path: "/messages/group/send-text", body: sendBody, wantStatus: http.StatusOK},
+		{name: "add", method: http.MethodPost, path: "/groups/blacklist", body: `{"group_id":"` + groupID + `","reason":"客户内部群"}`, wantStatus: http.StatusOK},
+		{name: "add again", method: http.MethodPost, path: "/groups/blacklist", body: `{"group_id":"` + groupID + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "send rejected", method: http.MethodPost, path: "/messages/group/send-text", body: `{"text_content":"再次通知","to_user_name":"` + groupID + `"}`, wantStatus: http.StatusForbidden},
+		{name: "remove", method: http.MethodDelete, path: "/groups/blacklist/" + groupID, wantStatus: http.StatusOK},
+		{name: "remove again", method: http.MethodDelete, path: "/groups/blacklist/" + groupID, wantStatus: http.StatusNotFound},
+		{name: "send after removed", method: http.MethodPost, path: "/messages/group/send-text", body: `{"text_content":"第三次通知","to_user_name":"` + groupID + `"}`, wantStatus: http.StatusOK},
+	}
+
+	for _, step := range steps {
+		w := doRequest(router, step.method, step.path, step.body)
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d, body = %s", step.name, w.Code, step.wantStatus, w.Body.String())
+		}
+	}
+
+	w := doRequest(router, http.MethodGet, "/groups/blacklist", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), groupID) {
+		t.Fatalf("list after remove: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLoginStatusFromResponse(t *testing.T) {
+	newResp := func(code, state int, msg string) *CheckLoginStatusResponse {
+		resp := &CheckLoginStatusResponse{Code: code}
+		resp.Data.State = state
+		resp.Data.WxID = "wxid_login"
+		resp.Data.NickName = "小助手"
+		resp.Data.Msg = msg
+		return resp
+	}
+
+	tests := []struct {
+		name        string
+		resp        *CheckLoginStatusResponse
+		wantStatus  int
+		wantWxID    string
+		wantNick    string
+		wantMessage string
+	}{
+		{name: "wait scan", resp: newResp(200, QrCodeStateWaitScan, ""), wantStatus: LoginStatusWaitScan, wantMessage: "等待扫码"},
+		{name: "scanned", resp: newResp(200, QrCodeStateScanned, ""), wantStatus: LoginStatusScanned, wantNick: "小助手", wantMessage: "已扫码，等待手机确认"},
+		{name: "confirmed", resp: newResp(200, QrCodeStateConfirmed, ""), wantStatus: LoginStatusConfirmed, wantWxID: "wxid_login", wantNick: "小助手", wantMessage: "登录成功"},
+		{name: "cancelled", resp: newResp(200, QrCodeStateCancelled, ""), wantStatus: LoginStatusFailed, wantMessage: "已在手机端取消登录"},
+		{name: "unknown state", resp: newResp(200, 3, ""), wantStatus: LoginStatusFailed, wantMessage: "未知的扫码状态(state=3)"},
+		{name: "unknown state with msg", resp: newResp(200, 9, "风控拦截"), wantStatus: LoginStatusFailed, wantMessage: "未知的扫码状态(state=9): 风控拦截"},
+		{name: "qrcode expired", resp: newResp(300, QrCodeStateConfirmed, ""), wantStatus: LoginStatusWaitScan, wantMessage: "二维码已过期或不存在"},
+		{name: "upstream error", resp: newResp(500, QrCodeStateConfirmed, ""), wantStatus: LoginStatusFailed, wantMessage: "检查登录状态失败"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := loginStatusFromResponse(tt.resp)
+			if got.Status != tt.wantStatus || got.WxID != tt.wantWxID || got.NickName != tt.wantNick || got.Message != tt.wantMessage {
+				t.Fatalf("status = %+v, want status=%d wxid=%q nick=%q message=%q", got, tt.wantStatus, tt.wantWxID, tt.wantNick, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestGetRecentBillsByGroup(t *testing.T) {
+	f := newTenantFixture(t)
+	createTestBills(t, f.db,
+		WxBillInfo{OwnerID: 1, GroupID: "a@chatroom", Amount: "1.00", MsgTime: 100},
+		WxBillInfo{OwnerID: 1, GroupID: "a@chatroom", Amount: "3.00", MsgTime: 300},
+		WxBillInfo{OwnerID: 1, GroupID: "a@chatroom", Amount: "2.00", MsgTime: 200},
+		WxBillInfo{OwnerID: 1, GroupID: "b@chatroom", Amount: "9.00", MsgTime: 900},
+		WxBillInfo{OwnerID: 2, GroupID: "a@chatroom", Amount: "8.00", MsgTime: 800},
+	)
+
+	tests := []struct {
+		name       string
+		path       string
+		headers    []string
+		wantStatus int
+		wantTimes  []int64
+	}{
+		{name: "latest first", path: "/bills/group/a@chatroom/recent?owner_id=1", headers: []string{APIKeyHeader, "key-1"}, wantStatus: http.StatusOK, wantTimes: []int64{300, 200, 100}},
+		{name: "limit", path: "/bills/group/a@chatroom/recent?owner_id=1&limit=2", headers: []string{APIKeyHeader, "key-1"}, wantStatus: http.StatusOK, wantTimes: []int64{300, 200}},
+		{name: "other owner bills excluded", path: "/bills/group/a@chatroom/recent?owner_id=2", headers: []string{APIKeyHeader, "key-2"}, wantStatus: http.StatusOK, wantTimes: []int64{800}},
+		{name: "unknown group", path: "/bills/group/x@chatroom/recent?owner_id=1", headers: []string{AdminTokenHeader, testAdminToken}, wantStatus: http.StatusOK, wantTimes: []int64{}},
+		{name: "other owner rejected", path: "/bills/group/a@chatroom/recent?owner_id=1", headers: []string{APIKeyHeader, "key-2"}, wantStatus: http.StatusForbidden},
+		{name: "limit too large", path: "/bills/group/a@chatroom/recent?owner_id=1&limit=101", headers: []string{APIKeyHeader, "key-1"}, wantStatus: http.StatusBadRequest},
+		{name: "missing owner", path: "/bills/group/a@chatroom/recent", headers: []string{AdminTokenHeader, testAdminToken}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.do(http.MethodGet, tt.path, "", tt.headers...)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantTimes == nil {
+				return
+			}
+			var resp struct {
+				Data []BillInfoResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			got := make([]int64, 0, len(resp.Data))
+			for _, bill := range resp.Data {
+				got = append(got, bill.MsgTime)
+			}
+			if !reflect.DeepEqual(got, tt.wantTimes) {
+				t.Fatalf("msg_time = %v, want %v", got, tt.wantTimes)
+			}
+		})
+	}
+}
+
+func TestGetAvailableMessageBots(t *testing.T) {
+	f := newTenantFixture(t)
+	// 公司1再加一个群，并补充各类不可用的账号
+	if err := f.db.Create(&WxGroup{WxID: "wxid_owner1", GroupID: "owner1-b@chatroom"}).Error; err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	for _, user := range []*WxUserLogin{
+		{WxID: "wxid_not_bot", Status: 1},
+		{WxID: "wxid_risk", Status: 1, IsMessageBot: 1, HasSecurityRisk: 1},
+		{WxID: "wxid_relogin", Status: UserStatusRelogin, IsMessageBot: 1},
+	} {
+		user.RobotID = f.robots[0].ID
+		if err := f.db.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	disabled := &WxRobotConfig{Address: "http://disabled.invalid", OwnerID: 1}
+	createTestRobot(t, f.db, disabled, &WxUserLogin{WxID: "wxid_disabled", Status: 1, IsMessageBot: 1})
+	f.db.Model(disabled).Update("enabled", 0)
+
+	tests := []struct {
+		name       string
+		path       string
+		headers    []string
+		wantStatus int
+		want       map[string]int64 // wx_id -> 覆盖群数
+	}{
+		{name: "all owners", path: "/message-bots", headers: []string{AdminTokenHeader, testAdminToken}, wantStatus: http.StatusOK, want: map[string]int64{"wxid_owner1": 2, "wxid_owner2": 1}},
+		{name: "owner filter", path: "/message-bots?owner_id=2", headers: []string{AdminTokenHeader, testAdminToken}, wantStatus: http.StatusOK, want: map[string]int64{"wxid_owner2": 1}},
+		{name: "scoped by api key", path: "/message-bots?owner_id=1", headers: []string{APIKeyHeader, "key-1"}, wantStatus: http.StatusOK, want: map[string]int64{"wxid_owner1": 2}},
+		{name: "other owner rejected", path: "/message-bots?owner_id=1", headers: []string{APIKeyHeader, "key-2"}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.do(http.MethodGet, tt.path, "", tt.headers...)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.want == nil {
+				return
+			}
+			var resp struct {
+				Data AvailableMessageBotsResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			got := make(map[string]int64, len(resp.Data.Bots))
+			for _, bot := range resp.Data.Bots {
+				got[bot.WxID] = bot.GroupCount
+			}
+			if resp.Data.Total != len(tt.want) || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("bots = %v (total %d), want %v", got, resp.Data.Total, tt.want)
+			}
+		})
+	}
+}
+
+func TestBroadcastSharesTraceID(t *testing.T) {
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(body), "fail@chatroom") {
+				jsonHandler(map[string]interface{}{"Code": 200, "Data": []interface{}{map[string]interface{}{"isSendSuccess": false}}})(w, r)
+				return
+			}
+			jsonHandler(sendSuccessResponse(1))(w, r)
+		},
+	})
+	router, _, db := newTestRouter(t, nil)
+	bot := &WxUserLogin{WxID: "wxid_bot", Token: "token-trace", IsMessageBot: 1}
+	createTestRobot(t, db, &WxRobotConfig{Address: server.URL}, bot)
+	for _, groupID := range []string{"ok1@chatroom", "ok2@chatroom", "fail@chatroom"} {
+		db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+	}
+
+	broadcast := func(groups string) string {
+		w := doRequest(router, http.MethodPost, "/messages/group/broadcast", `{"text_content":"通知","to_user_names":[`+groups+`]}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("broadcast status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data GroupBroadcastResponse `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Data.TraceID == "" {
+			t.Fatalf("broadcast response missing trace_id: %s", w.Body.String())
+		}
+		return resp.Data.TraceID
+	}
+	first := broadcast(`"ok1@chatroom","ok2@chatroom","fail@chatroom"`)
+	second := broadcast(`"ok1@chatroom"`)
+	if first == second {
+		t.Fatalf("two batches share trace_id %s", first)
+	}
+
+	tests := []struct {
+		name        string
+		traceID     string
+		wantStatus  int
+		wantGroups  []string
+		wantSuccess int
+		wantFailed  int
+	}{
+		{name: "first batch", traceID: first, wantStatus: http.StatusOK, wantGroups: []string{"ok1@chatroom", "ok2@chatroom", "fail@chatroom"}, wantSuccess: 2, wantFailed: 1},
+		{name: "second batch", traceID: second, wantStatus: http.StatusOK, wantGroups: []string{"ok1@chatroom"}, wantSuccess: 1},
+		{name: "unknown trace", traceID: "missing", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(router, http.MethodGet, "/messages/trace/"+tt.traceID, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantGroups == nil {
+				return
+			}
+			var resp struct {
+				Data SendTraceResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			groups := make([]string, 0, len(resp.Data.Records))
+			for _, record := range resp.Data.Records {
+				if record.TraceID != tt.traceID {
+					t.Fatalf("record trace_id = %s, want %s", record.TraceID, tt.traceID)
+				}
+				groups = append(groups, record.ToUserName)
+			}
+			if !reflect.DeepEqual(groups, tt.wantGroups) || resp.Data.Success != tt.wantSuccess || resp.Data.Failed != tt.wantFailed {
+				t.Fatalf("trace = %+v, want groups %v success %d failed %d", resp.Data, tt.wantGroups, tt.wantSuccess, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestSendAuditTrail(t *testing.T) {
+	const groupID = "audit@chatroom"
+	failed := jsonHandler(map[string]interface{}{"Code": 200, "Data": []interface{}{map[string]interface{}{"isSendSuccess": false}}})
+
+	tests := []struct {
+		name         string
+		storeContent bool
+		handler      http.HandlerFunc
+		wantSuccess  bool
+	}{
+		{name: "success with content", storeContent: true, handler: jsonHandler(sendSuccessResponse(1)), wantSuccess: true},
+		{name: "hash only", handler: jsonHandler(sendSuccessResponse(1)), wantSuccess: true},
+		{name: "failed send audited", storeContent: true, handler: failed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{defaultWxAPIEndpoints.SendTextMessage: tt.handler})
+			cfg := &Config{}
+			cfg.Audit.StoreContent = tt.storeContent
+			cfg.Security.AdminToken = testAdminToken
+			router, _, db := newTestRouter(t, cfg)
+			bot := &WxUserLogin{WxID: "wxid_audit", Token: "token-audit", IsMessageBot: 1}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 7}, bot)
+			db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+
+			doRequest(router, http.MethodPost, "/messages/group/send-text", `{"text_content":"合规通知","to_user_name":"`+groupID+`"}`, AdminTokenHeader, testAdminToken)
+
+			w := doRequest(router, http.MethodGet, "/audits?to_user_name="+groupID+"&sender_wx_id=wxid_audit", "", AdminTokenHeader, testAdminToken)
+			if w.Code != http.StatusOK {
+				t.Fatalf("list status = %d, body = %s", w.Code, w.Body.String())
+			}
+			var resp struct {
+				Data SendAuditPaginatedResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(resp.Data.List) != 1 {
+				t.Fatalf("got %d audits, want 1: %s", len(resp.Data.List), w.Body.String())
+			}
+			audit := resp.Data.List[0]
+			wantContent := ""
+			if tt.storeContent {
+				wantContent = "合规通知"
+			}
+			if audit.SenderWxID != "wxid_audit" || audit.OwnerID != 7 || audit.MsgType != SendAuditTypeText ||
+				audit.Content != wantContent || audit.ContentHash != contentHash("合规通知") ||
+				audit.Success != tt.wantSuccess || (audit.Error == "") != tt.wantSuccess {
+				t.Fatalf("audit = %+v", audit)
+			}
+
+			w = doRequest(router, http.MethodGet, "/audits?sender_wx_id=other", "", AdminTokenHeader, testAdminToken)
+			if !strings.Contains(w.Body.String(), `"total_count":0`) {
+				t.Fatalf("filter by other sender: %s", w.Body.String())
+			}
+
+			w = doRequest(router, http.MethodGet, "/audits/export?to_user_name="+groupID, "", AdminTokenHeader, testAdminToken)
+			records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), "\xEF\xBB\xBF"))).ReadAll()
+			if err != nil || len(records) != 2 {
+				t.Fatalf("export = %q, err %v", w.Body.String(), err)
+			}
+			if records[1][1] != "wxid_audit" || records[1][2] != groupID || records[1][5] != audit.ContentHash {
+				t.Fatalf("export row = %v", records[1])
+			}
+		})
+	}
+}
+
+func TestAuthorizeUserLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCount  int
+		wantDays   int
+	}{
+		{name: "defaults", body: `{"robot_id":%d}`, wantStatus: http.StatusOK, wantCount: DefaultAuthKeyCount, wantDays: DefaultAuthKeyDays},
+		{name: "custom", body: `{"robot_id":%d,"count":5,"days":30}`, wantStatus: http.StatusOK, wantCount: 5, wantDays: 30},
+		{name: "upper bounds", body: `{"robot_id":%d,"count":20,"days":365}`, wantStatus: http.StatusOK, wantCount: 20, wantDays: 365},
+		{name: "count too large", body: `{"robot_id":%d,"count":21}`, wantStatus: http.StatusBadRequest},
+		{name: "days too large", body: `{"robot_id":%d,"days":3650}`, wantStatus: http.StatusBadRequest},
+		{name: "negative count", body: `{"robot_id":%d,"count":-1}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got GenAuthKeyRequest
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.GenAuthKey: func(w http.ResponseWriter, r *http.Request) {
+					json.NewDecoder(r.Body).Decode(&got)
+					keys := make([]string, got.Count)
+					for i := range keys {
+						keys[i] = fmt.Sprintf("auth-%d", i)
+					}
+					jsonHandler(map[string]interface{}{"Code": 200, "Data": keys})(w, r)
+				},
+			})
+			router, _, db := newTestRouter(t, nil)
+			robot := &WxRobotConfig{Address: server.URL}
+			createTestRobot(t, db, robot)
+
+			w := doRequest(router, http.MethodPost, "/users/authorize", fmt.Sprintf(tt.body, robot.ID))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if got.Count != 0 {
+					t.Fatal("GenAuthKey called for invalid request")
+				}
+				return
+			}
+			if got.Count != tt.wantCount || got.Days != tt.wantDays {
+				t.Fatalf("GenAuthKey count=%d days=%d, want %d/%d", got.Count, got.Days, tt.wantCount, tt.wantDays)
+			}
+			var resp struct {
+				Data struct {
+					Token  string   `json:"token"`
+					Tokens []string `json:"tokens"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Data.Token != "auth-0" || len(resp.Data.Tokens) != tt.wantCount {
+				t.Fatalf("data = %+v, want %d tokens", resp.Data, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestBatchSetRobotStatus(t *testing.T) {
+	f := newTenantFixture(t)
+	extra := &WxRobotConfig{Address: "http://robot1b.invalid", OwnerID: 1}
+	createTestRobot(t, f.db, extra)
+	r1, r2 := f.robots[0].ID, f.robots[1].ID
+	admin := []string{AdminTokenHeader, testAdminToken}
+
+	// 按顺序执行，后一步依赖前一步的启用状态
+	steps := []struct {
+		name         string
+		body         string
+		headers      []string
+		wantStatus   int
+		wantAffected int64
+	}{
+		{name: "disable owner", body: `{"owner_id":1,"enabled":0}`, headers: admin, wantStatus: http.StatusOK, wantAffected: 2},
+		{name: "disable owner again", body: `{"owner_id":1,"enabled":0}`, headers: admin, wantStatus: http.StatusOK},
+		{name: "enable by ids", body: fmt.Sprintf(`{"ids":[%d],"enabled":1}`, r1), headers: []string{APIKeyHeader, "key-1"}, wantStatus: http.StatusOK, wantAffected: 1},
+		{name: "api key limited to own robots", body: fmt.Sprintf(`{"ids":[%d],"enabled":0}`, r2), headers: []string{APIKeyHeader, "key-1"}, wantStatus: http.StatusOK},
+		{name: "other owner rejected", body: `{"owner_id":1,"enabled":1}`, headers: []string{APIKeyHeader, "key-2"}, wantStatus: http.StatusForbidden},
+		{name: "no target", body: `{"enabled":1}`, headers: admin, wantStatus: http.StatusBadRequest},
+		{name: "missing enabled", body: `{"owner_id":1}`, headers: admin, wantStatus: http.StatusBadRequest},
+		{name: "invalid enabled", body: `{"owner_id":1,"enabled":2}`, headers: admin, wantStatus: http.StatusBadRequest},
+		{name: "no auth", body: `{"owner_id":1,"enabled":1}`, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, step := range steps {
+		w := f.do(http.MethodPost, "/robots/batch-status", step.body, step.headers...)
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d, body = %s", step.name, w.Code, step.wantStatus, w.Body.String())
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var resp struct {
+			Data BatchRobotStatusResponse `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: decode: %v", step.name, err)
+		}
+		if resp.Data.Affected != step.wantAffected {
+			t.Fatalf("%s: affected = %d, want %d", step.name, resp.Data.Affected, step.wantAffected)
+		}
+	}
+
+	want := map[uint]int{r1: 1, extra.ID: 0, r2: 1}
+	for id, enabled := range want {
+		var robot WxRobotConfig
+		f.db.First(&robot, id)
+		if robot.Enabled != enabled {
+			t.Fatalf("robot %d enabled = %d, want %d", id, robot.Enabled, enabled)
+		}
+	}
+
+	// 每个状态实际变化的机器人记录一条变更，未变化的不记录
+	var changes []WxRobotConfigChange
+	f.db.Order("id").Find(&changes)
+	var gotChanges []string
+	for _, change := range changes {
+		gotChanges = append(gotChanges, fmt.Sprintf("%d:%s:%s->%s:%s:%d", change.RobotID, change.Field,
+			change.OldValue, change.NewValue, change.Source, change.OperatorOwnerID))
+	}
+	wantChanges := []string{
+		fmt.Sprintf("%d:enabled:1->0:batch_status:0", r1),
+		fmt.Sprintf("%d:enabled:1->0:batch_status:0", extra.ID),
+		fmt.Sprintf("%d:enabled:0->1:batch_status:1", r1),
+	}
+	if !reflect.DeepEqual(gotChanges, wantChanges) {
+		t.Fatalf("config changes = %v, want %v", gotChanges, wantChanges)
+	}
+}
+
+func TestSendTextDedup(t *testing.T) {
+	type step struct {
+		path       string
+		body       string
+		failSend   bool // 机器人返回发送失败
+		wantStatus int
+		wantErrors int // send-text-multi 中失败的群数
+	}
+	text := func(group, content string) string {
+		return `{"text_content":"` + content + `","to_user_name":"` + group + `"}`
+	}
+	const sendText, sendMulti = "/messages/group/send-text", "/messages/group/send-text-multi"
+
+	tests := []struct {
+		name   string
+		action string
+		steps  []step
+	}{
+		{
+			name:   "reject",
+			action: SendDedupActionReject,
+			steps: []step{
+				{path: sendText, body: text("g1@chatroom", "通知"), wantStatus: http.StatusOK},
+				{path: sendText, body: text("g1@chatroom", "通知"), wantStatus: http.StatusConflict},
+				{path: sendText, body: text("g1@chatroom", "另一条通知"), wantStatus: http.StatusOK},
+				{path: sendText, body: text("g2@chatroom", "通知"), wantStatus: http.StatusOK},
+				{path: sendMulti, body: `{"text_content":"通知","to_user_names":["g1@chatroom","g3@chatroom"]}`, wantStatus: http.StatusOK, wantErrors: 1},
+				// 发送失败后撤销记录，可立即重试
+				{path: sendText, body: text("g1@chatroom", "重试"), failSend: true, wantStatus: http.StatusInternalServerError},
+				{path: sendText, body: text("g1@chatroom", "重试"), wantStatus: http.StatusOK},
+				{path: sendText, body: text("g1@chatroom", "重试"), wantStatus: http.StatusConflict},
+			},
+		},
+		{
+			name:   "warn",
+			action: SendDedupActionWarn,
+			steps: []step{
+				{path: sendText, body: text("g1@chatroom", "通知"), wantStatus: http.StatusOK},
+				{path: sendText, body: text("g1@chatroom", "通知"), wantStatus: http.StatusOK},
+				{path: sendMulti, body: `{"text_content":"通知","to_user_names":["g1@chatroom"]}`, wantStatus: http.StatusOK},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failSend bool
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+					if !failSend {
+						jsonHandler(sendSuccessResponse(1))(w, r)
+						return
+					}
+					jsonHandler(map[string]interface{}{
+						"Code": 200,
+						"Data": []interface{}{map[string]interface{}{
+							"isSendSuccess": true,
+							"resp":          map[string]interface{}{"base_response": map[string]interface{}{"ret": -1}},
+						}},
+					})(w, r)
+				},
+			})
+			cfg := &Config{}
+			cfg.SendQueue.Dedup = SendDedupConfig{Window: time.Minute, Action: tt.action}
+			router, _, db := newTestRouter(t, cfg)
+			bot := &WxUserLogin{WxID: "wxid_dedup", Token: "token-dedup", IsMessageBot: 1}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+			for _, group := range []string{"g1@chatroom", "g2@chatroom", "g3@chatroom"} {
+				db.Create(&WxGroup{WxID: bot.WxID, GroupID: group})
+			}
+
+			for i, s := range tt.steps {
+				failSend = s.failSend
+				w := doRequest(router, http.MethodPost, s.path, s.body)
+				if w.Code != s.wantStatus {
+					t.Fatalf("step %d status = %d, want %d, body = %s", i, w.Code, s.wantStatus, w.Body.String())
+				}
+				if s.path != sendMulti {
+					continue
+				}
+				var resp struct {
+					Data []SendTextResult `json:"data"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("step %d decode: %v", i, err)
+				}
+				var failed int
+				for _, r := range resp.Data {
+					if !r.Success {
+						failed++
+					}
+				}
+				if failed != s.wantErrors {
+					t.Fatalf("step %d failed groups = %d, want %d: %+v", i, failed, s.wantErrors, resp.Data)
+				}
+			}
+		})
+	}
+}
+
+func TestSendVoice(t *testing.T) {
+	const groupID = "voice@chatroom"
+	body := func(fields string) string {
+		return `{"to_user_name":"` + groupID + `"` + fields + `}`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		response   map[string]interface{}
+		wantStatus int
+		wantSent   *SendVoiceMessageRequest
+		wantType   SendErrorType
+	}{
+		{
+			name:       "default silk",
+			body:       body(`,"voice_content":"aGVsbG8=","voice_duration":3`),
+			response:   sendRawResponse(0, "", 99),
+			wantStatus: http.StatusOK,
+			wantSent:   &SendVoiceMessageRequest{ToUserName: groupID, VoiceData: "aGVsbG8=", VoiceFormat: VoiceFormatSilk, VoiceSecond: 3},
+		},
+		{
+			name:       "amr with data uri",
+			body:       body(`,"voice_content":"data:audio/amr;base64,aGVs\nbG8=","voice_format":"amr","voice_duration":60`),
+			response:   sendRawResponse(0, "", 99),
+			wantStatus: http.StatusOK,
+			wantSent:   &SendVoiceMessageRequest{ToUserName: groupID, VoiceData: "aGVsbG8=", VoiceFormat: VoiceFormatAMR, VoiceSecond: 60},
+		},
+		{name: "missing content", body: body(`,"voice_duration":3`), wantStatus: http.StatusBadRequest},
+		{name: "duration too long", body: body(`,"voice_content":"aGVsbG8=","voice_duration":61`), wantStatus: http.StatusBadRequest},
+		{name: "unknown format", body: body(`,"voice_content":"aGVsbG8=","voice_format":"mp3","voice_duration":3`), wantStatus: http.StatusBadRequest},
+		{
+			name:       "send failed",
+			body:       body(`,"voice_content":"aGVsbG8=","voice_duration":3`),
+			response:   sendRawResponse(-1, "操作过于频繁", 0),
+			wantStatus: http.StatusInternalServerError,
+			wantSent:   &SendVoiceMessageRequest{ToUserName: groupID, VoiceData: "aGVsbG8=", VoiceFormat: VoiceFormatSilk, VoiceSecond: 3},
+			wantType:   SendErrorRiskControl,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent *SendVoiceMessageRequest
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendVoiceMessage: func(w http.ResponseWriter, r *http.Request) {
+					sent = &SendVoiceMessageRequest{}
+					json.NewDecoder(r.Body).Decode(sent)
+					jsonHandler(tt.response)(w, r)
+				},
+			})
+			router, _, db := newTestRouter(t, nil)
+			bot := &WxUserLogin{WxID: "wxid_voice", Token: "token-voice", IsMessageBot: 1}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+			db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+
+			w := doRequest(router, http.MethodPost, "/messages/group/send-voice", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !reflect.DeepEqual(sent, tt.wantSent) {
+				t.Fatalf("sent = %+v, want %+v", sent, tt.wantSent)
+			}
+
+			var resp struct {
+				Data struct {
+					NewMsgId  int64         `json:"NewMsgId"`
+					ErrorType SendErrorType `json:"error_type"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if tt.wantStatus == http.StatusOK && resp.Data.NewMsgId != 99 {
+				t.Fatalf("NewMsgId = %d, want 99", resp.Data.NewMsgId)
+			}
+			if resp.Data.ErrorType != tt.wantType {
+				t.Fatalf("error_type = %q, want %q", resp.Data.ErrorType, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestSendVideo(t *testing.T) {
+	const groupID = "video@chatroom"
+	body := func(fields string) string {
+		return `{"to_user_name":"` + groupID + `"` + fields + `}`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		response   map[string]interface{}
+		wantStatus int
+		wantSent   *SendVideoMessageRequest
+	}{
+		{
+			name:       "without thumb",
+			body:       body(`,"video_content":"dmlkZW8=","play_length":10`),
+			response:   sendRawResponse(0, "", 77),
+			wantStatus: http.StatusOK,
+			wantSent:   &SendVideoMessageRequest{ToUserName: groupID, VideoData: "dmlkZW8=", PlayLength: 10},
+		},
+		{
+			name:       "with data uri thumb",
+			body:       body(`,"video_content":"dmlkZW8=","thumb_content":"data:image/jpeg;base64,dGh1bWI=","play_length":10`),
+			response:   sendRawResponse(0, "", 77),
+			wantStatus: http.StatusOK,
+			wantSent:   &SendVideoMessageRequest{ToUserName: groupID, VideoData: "dmlkZW8=", ThumbData: "dGh1bWI=", PlayLength: 10},
+		},
+		{name: "invalid thumb", body: body(`,"video_content":"dmlkZW8=","thumb_content":"not base64!","play_length":10`), wantStatus: http.StatusBadRequest},
+		{name: "missing play length", body: body(`,"video_content":"dmlkZW8="`), wantStatus: http.StatusBadRequest},
+		{name: "missing content", body: body(`,"play_length":10`), wantStatus: http.StatusBadRequest},
+		{
+			name:       "send failed",
+			body:       body(`,"video_content":"dmlkZW8=","play_length":10`),
+			response:   sendRawResponse(-1, "群聊不存在", 0),
+			wantStatus: http.StatusInternalServerError,
+			wantSent:   &SendVideoMessageRequest{ToUserName: groupID, VideoData: "dmlkZW8=", PlayLength: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent *SendVideoMessageRequest
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendVideoMessage: func(w http.ResponseWriter, r *http.Request) {
+					sent = &SendVideoMessageRequest{}
+					json.NewDecoder(r.Body).Decode(sent)
+					jsonHandler(tt.response)(w, r)
+				},
+			})
+			router, _, db := newTestRouter(t, nil)
+			bot := &WxUserLogin{WxID: "wxid_video", Token: "token-video", IsMessageBot: 1}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+			db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+
+			w := doRequest(router, http.MethodPost, "/messages/group/send-video", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !reflect.DeepEqual(sent, tt.wantSent) {
+				t.Fatalf("sent = %+v, want %+v", sent, tt.wantSent)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data SendVideoMessageResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Data.SendVideoResponse == nil || resp.Data.NewMsgId != 77 || resp.Data.MsgId != 76 || resp.Data.ToUserName != groupID {
+				t.Fatalf("response = %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSendLink(t *testing.T) {
+	const groupID = "link@chatroom"
+	body := func(fields string) string {
+		return `{"to_user_name":"` + groupID + `"` + fields + `}`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantXML    string // 发送的appmsg XML需包含的内容，为空表示不应发送
+	}{
+		{name: "sent", body: body(`,"title":"周报","description":"本周进展","url":"https://example.com/a"`), wantStatus: http.StatusOK, wantXML: "<title>周报</title><des>本周进展</des>"},
+		{name: "missing title", body: body(`,"url":"https://example.com/a"`), wantStatus: http.StatusBadRequest},
+		{name: "missing url", body: body(`,"title":"周报"`), wantStatus: http.StatusBadRequest},
+		{name: "invalid url", body: body(`,"title":"周报","url":"not a url"`), wantStatus: http.StatusBadRequest},
+		{name: "invalid thumb url", body: body(`,"title":"周报","url":"https://example.com/a","thumb_url":"thumb"`), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent *SendAppMessageRequest
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendAppMessage: func(w http.ResponseWriter, r *http.Request) {
+					sent = &SendAppMessageRequest{}
+					json.NewDecoder(r.Body).Decode(sent)
+					jsonHandler(sendRawResponse(0, "", 55))(w, r)
+				},
+			})
+			router, _, db := newTestRouter(t, nil)
+			bot := &WxUserLogin{WxID: "wxid_link", Token: "token-link", IsMessageBot: 1}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+			db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+
+			w := doRequest(router, http.MethodPost, "/messages/group/send-link", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantXML == "" {
+				if sent != nil {
+					t.Fatalf("sent = %+v, want not sent", sent)
+				}
+				return
+			}
+			if sent == nil || len(sent.AppList) != 1 {
+				t.Fatalf("sent = %+v, want one card", sent)
+			}
+			item := sent.AppList[0]
+			if item.ContentType != appMsgTypeLink || item.ToUserName != groupID || !strings.Contains(item.ContentXML, tt.wantXML) {
+				t.Fatalf("card = %+v, want xml containing %q", item, tt.wantXML)
+			}
+			if !strings.Contains(w.Body.String(), `"NewMsgId":55`) {
+				t.Fatalf("response = %s, want NewMsgId 55", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSendTextAtWxIDList(t *testing.T) {
+	const groupID = "at@chatroom"
+	const botWxID = "wxid_at_bot"
+
+	tests := []struct {
+		name     string
+		body     string
+		wantAtWx []string
+	}{
+		{name: "no mention", body: `{"text_content":"大家好","to_user_name":"` + groupID + `"}`},
+		{name: "empty list resolves nickname", body: `{"text_content":"@小王 请查收","to_user_name":"` + groupID + `","at_wx_id_list":[]}`, wantAtWx: []string{"wxid_wang"}},
+		{name: "explicit list", body: `{"text_content":"请查收","to_user_name":"` + groupID + `","at_wx_id_list":["wxid_wang"," wxid_li","wxid_wang"]}`, wantAtWx: []string{"wxid_wang", "wxid_li"}},
+		{name: "at all", body: `{"text_content":"全体注意","to_user_name":"` + groupID + `","at_wx_id_list":["wxid_wang","notify@all"]}`, wantAtWx: []string{AtAllWxID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent *SendTextMsgItem
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				// 发送账号为群主，保留@所有人
+				defaultWxAPIEndpoints.GetChatRoomInfo: jsonHandler(map[string]interface{}{
+					"Code": 200,
+					"Data": map[string]interface{}{
+						"contactList": []map[string]interface{}{{
+							"userName":      map[string]string{"str": groupID},
+							"chatRoomOwner": botWxID,
+						}},
+					},
+				}),
+				defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+					var req SendTextMessageRequest
+					if err := json.NewDecoder(r.Body).Decode(&req); err == nil && len(req.MsgItem) == 1 {
+						sent = &req.MsgItem[0]
+					}
+					jsonHandler(sendSuccessResponse(1))(w, r)
+				},
+			})
+			router, _, db := newTestRouter(t, nil)
+			bot := &WxUserLogin{WxID: botWxID, Token: "token-at", IsMessageBot: 1}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+			db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+			db.Create(&WxGroupMember{GroupID: groupID, MemberWxID: "wxid_wang", NickName: "小王"})
+
+			w := doRequest(router, http.MethodPost, "/messages/group/send-text", tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+			if sent == nil {
+				t.Fatal("text message not sent")
+			}
+			if len(sent.AtWxIDList) != len(tt.wantAtWx) || (len(tt.wantAtWx) > 0 && !reflect.DeepEqual(sent.AtWxIDList, tt.wantAtWx)) {
+				t.Fatalf("AtWxIDList = %v, want %v", sent.AtWxIDList, tt.wantAtWx)
+			}
+			// @列表由平台触发提醒，不改写文本
+			var req struct {
+				TextContent string `json:"text_content"`
+			}
+			json.Unmarshal([]byte(tt.body), &req)
+			if sent.TextContent != req.TextContent {
+				t.Fatalf("TextContent = %q, want %q", sent.TextContent, req.TextContent)
+			}
+		})
+	}
+}
+
+func TestGroupBroadcastResults(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantResults []BroadcastGroupResult // 只比较群、是否成功、发送账号
+	}{
+		{
+			name:       "partial failure",
+			body:       `{"text_content":"通知","to_user_names":["ok@chatroom","fail@chatroom","missing@chatroom","ok@chatroom"]}`,
+			wantStatus: http.StatusOK,
+			wantResults: []BroadcastGroupResult{
+				{ToUserName: "ok@chatroom", Success: true, WxID: "wxid_bc"},
+				{ToUserName: "fail@chatroom", WxID: "wxid_bc"},
+				{ToUserName: "missing@chatroom"},
+			},
+		},
+		{
+			name:        "all failed",
+			body:        `{"text_content":"通知","to_user_names":["fail@chatroom"]}`,
+			wantStatus:  http.StatusOK,
+			wantResults: []BroadcastGroupResult{{ToUserName: "fail@chatroom", WxID: "wxid_bc"}},
+		},
+		{name: "no content", body: `{"to_user_names":["ok@chatroom"]}`, wantStatus: http.StatusBadRequest},
+		{name: "no groups", body: `{"text_content":"通知","to_user_names":[]}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+					body, _ := io.ReadAll(r.Body)
+					if strings.Contains(string(body), "fail@chatroom") {
+						jsonHandler(map[string]interface{}{"Code": 200, "Data": []interface{}{map[string]interface{}{"isSendSuccess": false}}})(w, r)
+						return
+					}
+					jsonHandler(sendSuccessResponse(1))(w, r)
+				},
+			})
+			router, _, db := newTestRouter(t, nil)
+			bot := &WxUserLogin{WxID: "wxid_bc", Token: "token-bc", IsMessageBot: 1}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL}, bot)
+			for _, groupID := range []string{"ok@chatroom", "fail@chatroom"} {
+				db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+			}
+
+			w := doRequest(router, http.MethodPost, "/messages/group/broadcast", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantResults == nil {
+				return
+			}
+			var resp struct {
+				Data GroupBroadcastResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			var success int
+			got := make([]BroadcastGroupResult, 0, len(resp.Data.Results))
+			for _, r := range resp.Data.Results {
+				if r.Success {
+					success++
+				} else if r.Error == "" {
+					t.Errorf("%s failed without error", r.ToUserName)
+				}
+				got = append(got, BroadcastGroupResult{ToUserName: r.ToUserName, Success: r.Success, WxID: r.WxID})
+			}
+			if !reflect.DeepEqual(got, tt.wantResults) {
+				t.Fatalf("results = %+v, want %+v", got, tt.wantResults)
+			}
+			if resp.Data.Total != len(tt.wantResults) || resp.Data.Success != success || resp.Data.Failed != len(tt.wantResults)-success {
+				t.Fatalf("total/success/failed = %d/%d/%d", resp.Data.Total, resp.Data.Success, resp.Data.Failed)
+			}
+		})
+	}
+}
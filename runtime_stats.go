@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RuntimeStats 服务启动以来的运行计数，计数器均为原子操作
+type RuntimeStats struct {
+	startTime time.Time
+	requests  int64 // 处理的HTTP请求数
+	sent      int64 // 发送成功的消息数
+	errors    int64 // 请求处理错误（5xx）与消息发送失败数
+}
+
+// runtimeStats 全局运行计数
+var runtimeStats = NewRuntimeStats()
+
+// NewRuntimeStats 创建运行计数，以当前时间为启动时间
+func NewRuntimeStats() *RuntimeStats {
+	return &RuntimeStats{startTime: time.Now()}
+}
+
+// IncRequests 请求数加1
+func (s *RuntimeStats) IncRequests() {
+	atomic.AddInt64(&s.requests, 1)
+}
+
+// AddSent 发送成功数增加n
+func (s *RuntimeStats) AddSent(n int) {
+	atomic.AddInt64(&s.sent, int64(n))
+}
+
+// AddErrors 错误数增加n
+func (s *RuntimeStats) AddErrors(n int) {
+	atomic.AddInt64(&s.errors, int64(n))
+}
+
+// RecordSend 按发送结果计数
+func (s *RuntimeStats) RecordSend(err error) {
+	if err != nil {
+		s.AddErrors(1)
+		return
+	}
+	s.AddSent(1)
+}
+
+// RuntimeStatsInfo 运行时长与计数
+type RuntimeStatsInfo struct {
+	StartTime     string `json:"start_time"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	Uptime        string `json:"uptime"`
+	TotalRequests int64  `json:"total_requests"`
+	TotalSent     int64  `json:"total_sent"`
+	TotalErrors   int64  `json:"total_errors"`
+}
+
+// Snapshot 获取当前运行时长与计数
+func (s *RuntimeStats) Snapshot() RuntimeStatsInfo {
+	uptime := time.Since(s.startTime)
+	return RuntimeStatsInfo{
+		StartTime:     FormatTime(s.startTime),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Uptime:        uptime.Truncate(time.Second).String(),
+		TotalRequests: atomic.LoadInt64(&s.requests),
+		TotalSent:     atomic.LoadInt64(&s.sent),
+		TotalErrors:   atomic.LoadInt64(&s.errors),
+	}
+}
+
+// Middleware 统计请求数与5xx错误数
+func (s *RuntimeStats) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		s.IncRequests()
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			s.AddErrors(1)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		record    func(s *RuntimeStats)
+		want      RuntimeStatsInfo
+		minUptime int64
+	}{
+		{name: "empty", record: func(s *RuntimeStats) {}},
+		{
+			name: "send results",
+			record: func(s *RuntimeStats) {
+				s.RecordSend(nil)
+				s.RecordSend(nil)
+				s.RecordSend(errors.New("发送失败"))
+				s.AddSent(3)
+			},
+			want: RuntimeStatsInfo{TotalSent: 5, TotalErrors: 1},
+		},
+		{
+			name: "uptime",
+			record: func(s *RuntimeStats) {
+				s.startTime = s.startTime.Add(-90 * time.Second)
+			},
+			minUptime: 90,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := NewRuntimeStats()
+			tt.record(stats)
+
+			got := stats.Snapshot()
+			if got.TotalRequests != tt.want.TotalRequests || got.TotalSent != tt.want.TotalSent || got.TotalErrors != tt.want.TotalErrors {
+				t.Fatalf("snapshot = %+v, want %+v", got, tt.want)
+			}
+			if got.UptimeSeconds < tt.minUptime || got.UptimeSeconds > tt.minUptime+1 {
+				t.Fatalf("uptime = %ds, want about %ds", got.UptimeSeconds, tt.minUptime)
+			}
+			if got.StartTime != FormatTime(stats.startTime) {
+				t.Fatalf("start time = %q", got.StartTime)
+			}
+		})
+	}
+}
+
+func TestRuntimeStatsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stats := NewRuntimeStats()
+	router := gin.New()
+	router.Use(stats.Middleware())
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/bad", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	for _, path := range []string{"/ok", "/ok", "/bad", "/fail"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	got := stats.Snapshot()
+	if got.TotalRequests != 4 || got.TotalErrors != 1 {
+		t.Fatalf("requests = %d errors = %d, want 4 and 1", got.TotalRequests, got.TotalErrors)
+	}
+}
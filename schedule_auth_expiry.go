@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// AuthExpiryScheduler 授权到期预警与自动续期定时任务接口
+type AuthExpiryScheduler interface {
+	Start() error
+	Stop() error
+	CheckAuthExpiry(ctx context.Context) error
+}
+
+// DefaultAuthExpiryScheduler 默认的授权到期检查实现
+type DefaultAuthExpiryScheduler struct {
+	logger     *zap.Logger
+	wxRobotSvc WxRobotService
+	cron       *cron.Cron
+	cfg        AuthExpiryConfig
+
+	// 已预警的用户，key为用户ID，value为预警时的到期时间，续期后到期时间变化会再次预警
+	warnedMu sync.Mutex
+	warned   map[uint]time.Time
+}
+
+// NewAuthExpiryScheduler 创建新的授权到期检查定时任务
+func NewAuthExpiryScheduler(
+	logger *zap.Logger,
+	wxRobotSvc WxRobotService,
+	cfg AuthExpiryConfig,
+) AuthExpiryScheduler {
+	c := cron.New(cron.WithSeconds())
+	return &DefaultAuthExpiryScheduler{
+		logger:     logger,
+		wxRobotSvc: wxRobotSvc,
+		cron:       c,
+		cfg:        cfg,
+		warned:     make(map[uint]time.Time),
+	}
+}
+
+// Start 启动授权到期检查定时任务，间隔为0时不启动
+func (s *DefaultAuthExpiryScheduler) Start() error {
+	if s.cfg.Interval <= 0 {
+		s.logger.Info("授权到期检查未开启")
+		return nil
+	}
+
+	s.logger.Info("启动授权到期检查定时任务",
+		zap.Duration("interval", s.cfg.Interval),
+		zap.Int("warn_days", s.cfg.WarnDays),
+		zap.Int("renew_before_days", s.cfg.RenewBeforeDays),
+		zap.Int("renew_days", s.cfg.RenewDays))
+
+	_, err := s.cron.AddFunc(fmt.Sprintf("@every %s", s.cfg.Interval), func() {
+		s.logger.Debug("开始执行授权到期检查任务")
+		// 单次执行不超过调度间隔，避免慢机器人导致任务堆积
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Interval)
+		defer cancel()
+		if err := s.CheckAuthExpiry(ctx); err != nil {
+			s.logger.Error("授权到期检查任务执行失败", zap.Error(err))
+		}
+	})
+
+	if err != nil {
+		s.logger.Error("添加授权到期检查定时任务失败", zap.Error(err))
+		return err
+	}
+
+	s.cron.Start()
+	s.logger.Info("授权到期检查定时任务启动完成")
+	return nil
+}
+
+// Stop 停止授权到期检查定时任务
+func (s *DefaultAuthExpiryScheduler) Stop() error {
+	s.logger.Info("停止授权到期检查定时任务")
+	ctx := s.cron.Stop()
+	<-ctx.Done()
+	s.logger.Info("授权到期检查定时任务停止完成")
+	return nil
+}
+
+// CheckAuthExpiry 对开启自动续期的用户自动延期，对未开启的用户发送到期预警
+func (s *DefaultAuthExpiryScheduler) CheckAuthExpiry(ctx context.Context) error {
+	if s.cfg.RenewDays > 0 {
+		resp, err := s.wxRobotSvc.RenewExpiringUsers(ctx, s.cfg.RenewBeforeDays, s.cfg.RenewDays)
+		if err != nil {
+			return err
+		}
+		if resp.Total > 0 {
+			s.logger.Info("授权自动续期完成",
+				zap.Int("total", resp.Total),
+				zap.Int("success", resp.Success),
+				zap.Int("failed", resp.Failed))
+		}
+	}
+
+	if s.cfg.WarnDays > 0 {
+		return s.warnExpiringUsers()
+	}
+	return nil
+}
+
+// warnExpiringUsers 对即将到期且未开启自动续期的用户发送预警，同一到期时间只预警一次
+func (s *DefaultAuthExpiryScheduler) warnExpiringUsers() error {
+	users, err := s.wxRobotSvc.GetExpiringUsers(s.cfg.WarnDays, 0)
+	if err != nil {
+		return err
+	}
+
+	s.warnedMu.Lock()
+	defer s.warnedMu.Unlock()
+
+	expiring := make(map[uint]bool, len(users))
+	for _, user := range users {
+		expiring[user.ID] = true
+		if warnedAt, ok := s.warned[user.ID]; ok && warnedAt.Equal(user.ExpirationTime) {
+			continue
+		}
+
+		s.logger.Warn("用户授权即将到期",
+			zap.Uint("user_id", user.ID),
+			zap.String("wx_id", user.WxID),
+			zap.String("expiration_time", FormatTime(user.ExpirationTime)))
+		s.wxRobotSvc.NotifyAuthExpiring(user)
+		s.warned[user.ID] = user.ExpirationTime
+	}
+
+	// 已续期或已删除的用户不再保留预警记录
+	for userID := range s.warned {
+		if !expiring[userID] {
+			delete(s.warned, userID)
+		}
+	}
+	return nil
+}
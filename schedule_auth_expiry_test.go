@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// countingExpiryService 记录到期预警通知的用户
+type countingExpiryService struct {
+	WxRobotService
+	warned []string
+}
+
+func (s *countingExpiryService) NotifyAuthExpiring(user WxUserLogin) {
+	s.warned = append(s.warned, user.WxID)
+	s.WxRobotService.NotifyAuthExpiring(user)
+}
+
+func TestCheckAuthExpiry(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         AuthExpiryConfig
+		wantRenewed []string
+		wantWarned  []string
+	}{
+		{name: "renew and warn", cfg: AuthExpiryConfig{WarnDays: 7, RenewBeforeDays: 3, RenewDays: 30}, wantRenewed: []string{"wxid_auto_soon"}, wantWarned: []string{"wxid_manual_soon"}},
+		{name: "renew disabled", cfg: AuthExpiryConfig{WarnDays: 7, RenewBeforeDays: 3}, wantWarned: []string{"wxid_manual_soon"}},
+		{name: "wider renew window", cfg: AuthExpiryConfig{RenewBeforeDays: 30, RenewDays: 30}, wantRenewed: []string{"wxid_auto_later", "wxid_auto_soon"}},
+		{name: "warn disabled", cfg: AuthExpiryConfig{RenewBeforeDays: 3, RenewDays: 30}, wantRenewed: []string{"wxid_auto_soon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var renewedKeys []string
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.DelayAuthKey: func(w http.ResponseWriter, r *http.Request) {
+					var req DelayAuthKeyRequest
+					json.NewDecoder(r.Body).Decode(&req)
+					mu.Lock()
+					renewedKeys = append(renewedKeys, req.Key)
+					mu.Unlock()
+					jsonHandler(map[string]interface{}{"Code": 200, "Data": map[string]interface{}{"expiryDate": "2030-01-01"}})(w, r)
+				},
+			})
+			svc, db := newTestService(t, nil)
+			now := time.Now()
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1},
+				&WxUserLogin{WxID: "wxid_auto_soon", Token: "wxid_auto_soon", AutoRenew: 1, ExpirationTime: now.AddDate(0, 0, 2)},
+				&WxUserLogin{WxID: "wxid_auto_later", Token: "wxid_auto_later", AutoRenew: 1, ExpirationTime: now.AddDate(0, 0, 20)},
+				&WxUserLogin{WxID: "wxid_manual_soon", Token: "wxid_manual_soon", ExpirationTime: now.AddDate(0, 0, 5)},
+				&WxUserLogin{WxID: "wxid_manual_later", Token: "wxid_manual_later", ExpirationTime: now.AddDate(0, 0, 60)})
+
+			counting := &countingExpiryService{WxRobotService: svc}
+			scheduler := NewAuthExpiryScheduler(zap.NewNop(), counting, tt.cfg)
+			// 连续执行两次：已续期的用户不再续期，同一到期时间只预警一次
+			for i := 0; i < 2; i++ {
+				if err := scheduler.CheckAuthExpiry(context.Background()); err != nil {
+					t.Fatalf("CheckAuthExpiry: %v", err)
+				}
+			}
+
+			sort.Strings(renewedKeys)
+			if !reflect.DeepEqual(renewedKeys, tt.wantRenewed) {
+				t.Fatalf("renewed = %v, want %v", renewedKeys, tt.wantRenewed)
+			}
+			if !reflect.DeepEqual(counting.warned, tt.wantWarned) {
+				t.Fatalf("warned = %v, want %v", counting.warned, tt.wantWarned)
+			}
+			for _, wxID := range tt.wantRenewed {
+				var user WxUserLogin
+				db.Where("wx_id = ?", wxID).First(&user)
+				if user.ExpirationTime.Format("2006-01-02") != "2030-01-01" {
+					t.Fatalf("%s expiration_time = %s, want 2030-01-01", wxID, user.ExpirationTime)
+				}
+			}
+		})
+	}
+}
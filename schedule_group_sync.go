@@ -1,6 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 )
@@ -9,7 +17,28 @@ import (
 type GroupSyncScheduler interface {
 	Start() error
 	Stop() error
-	SyncGroupsForAllUsers() error
+	SyncGroupsForAllUsers(ctx context.Context) error
+	SyncGroupsForUser(ctx context.Context, userID uint) (*GroupSyncSummary, error)
+}
+
+// ErrGroupListUnavailable 外部接口返回群列表失败，可能是临时问题，定时同步不计为失败
+var ErrGroupListUnavailable = errors.New("获取群列表返回错误")
+
+// 连续同步失败退避参数
+const (
+	groupSyncFailureThreshold = 3  // 连续失败达到该次数后开始跳过
+	groupSyncMaxSkipRounds    = 20 // 最多连续跳过的轮数（每轮3分钟）
+
+	groupSyncTaskTimeout = 3 * time.Minute // 单轮同步的最长耗时，与调度间隔一致
+
+	// 判断是否到达同步间隔时允许的误差，避免调度时间的微小偏差让用户多等一轮
+	groupSyncIntervalSlack = 10 * time.Second
+)
+
+// groupSyncFailure 单个用户的连续同步失败状态
+type groupSyncFailure struct {
+	failures   int // 连续失败次数
+	skipRounds int // 剩余需跳过的轮数
 }
 
 // DefaultGroupSyncScheduler 默认的群组同步定时任务实现
@@ -17,29 +46,45 @@ type DefaultGroupSyncScheduler struct {
 	logger     *zap.Logger
 	wxRobotSvc WxRobotService
 	cron       *cron.Cron
+	cfg        GroupSyncConfig
+
+	failureMu sync.Mutex
+	failures  map[uint]*groupSyncFailure // 按用户ID记录连续失败
+
+	syncedMu   sync.Mutex
+	lastSynced map[uint]time.Time // 按用户ID记录上次成功同步的时间
 }
 
 // NewGroupSyncScheduler 创建新的群组同步定时任务
 func NewGroupSyncScheduler(
 	logger *zap.Logger,
 	wxRobotSvc WxRobotService,
+	cfg GroupSyncConfig,
 ) GroupSyncScheduler {
 	c := cron.New(cron.WithSeconds())
 	return &DefaultGroupSyncScheduler{
 		logger:     logger,
 		wxRobotSvc: wxRobotSvc,
 		cron:       c,
+		cfg:        cfg,
+		failures:   make(map[uint]*groupSyncFailure),
+		lastSynced: make(map[uint]time.Time),
 	}
 }
 
 // Start 启动群组同步定时任务 - 每3分钟执行一次
 func (s *DefaultGroupSyncScheduler) Start() error {
-	s.logger.Info("启动群组同步定时任务", zap.String("schedule", "每3分钟执行一次"))
+	s.logger.Info("启动群组同步定时任务",
+		zap.String("schedule", "每3分钟执行一次"),
+		zap.Duration("min_interval", s.cfg.MinInterval),
+		zap.Int("max_users_per_round", s.cfg.MaxUsersPerRound))
 
 	// 添加定时任务：每3分钟执行一次
 	_, err := s.cron.AddFunc("0 */3 * * * *", func() {
 		s.logger.Debug("开始执行群组同步任务")
-		if err := s.SyncGroupsForAllUsers(); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), groupSyncTaskTimeout)
+		defer cancel()
+		if err := s.SyncGroupsForAllUsers(ctx); err != nil {
 			s.logger.Error("群组同步任务执行失败", zap.Error(err))
 		}
 	})
@@ -64,7 +109,7 @@ func (s *DefaultGroupSyncScheduler) Stop() error {
 }
 
 // SyncGroupsForAllUsers 为所有已初始化用户同步群组数据
-func (s *DefaultGroupSyncScheduler) SyncGroupsForAllUsers() error {
+func (s *DefaultGroupSyncScheduler) SyncGroupsForAllUsers(ctx context.Context) error {
 	s.logger.Debug("开始为所有已初始化用户同步群组数据")
 
 	// 1. 获取所有已初始化的用户
@@ -80,33 +125,226 @@ func (s *DefaultGroupSyncScheduler) SyncGroupsForAllUsers() error {
 	}
 
 	s.logger.Info("找到已初始化用户", zap.Int("count", len(users)))
+	s.pruneSynced(users)
+
+	// 2. 排除处于退避期和不可调用的用户
+	skippedCount := 0
+	candidates := make([]WxUserLogin, 0, len(users))
+	for _, user := range users {
+		if s.shouldSkipUser(user) {
+			skippedCount++
+			continue
+		}
 
-	// 2. 逐个用户同步群组数据
+		// token为空或状态不正常的用户调用必然失败，直接跳过，不计入同步失败
+		if err := checkUserCallable(&user); err != nil {
+			s.logger.Info("用户不可调用外部接口，跳过群组同步",
+				zap.Uint("user_id", user.ID),
+				zap.String("wx_id", user.WxID),
+				zap.Error(err))
+			skippedCount++
+			continue
+		}
+		candidates = append(candidates, user)
+	}
+
+	// 3. 错峰：只同步到达同步间隔的用户，最久未同步的优先
+	roundStart := time.Now()
+	dueUsers := s.selectDueUsers(candidates, roundStart)
+	deferredCount := len(candidates) - len(dueUsers)
+
+	// 4. 逐个用户同步群组数据
 	successCount := 0
 	errorCount := 0
 
-	for _, user := range users {
-		if err := s.syncGroupsForUser(user); err != nil {
+	for _, user := range dueUsers {
+		// 超时后剩余用户留到下一轮，不计入同步失败
+		if ctx.Err() != nil {
+			s.logger.Warn("群组同步超时，剩余用户下一轮同步", zap.Error(ctx.Err()))
+			break
+		}
+
+		_, err := s.syncGroupsForUser(ctx, user)
+		if err != nil && !errors.Is(err, ErrGroupListUnavailable) && ctx.Err() == nil {
 			s.logger.Error("同步用户群组数据失败",
 				zap.Uint("user_id", user.ID),
 				zap.String("wx_id", user.WxID),
 				zap.Error(err))
+			s.recordSyncFailure(user)
 			errorCount++
 			continue
 		}
+		// 按本轮开始时间记录，下一轮判断间隔时不受本轮内同步先后的影响
+		if err == nil {
+			s.markSynced(user, roundStart)
+		}
+		s.resetSyncFailure(user)
 		successCount++
 	}
 
 	s.logger.Info("群组同步任务完成",
 		zap.Int("total", len(users)),
 		zap.Int("success", successCount),
-		zap.Int("error", errorCount))
+		zap.Int("error", errorCount),
+		zap.Int("skipped", skippedCount),
+		zap.Int("deferred", deferredCount))
 
 	return nil
 }
 
+// SyncGroupsForUser 手动同步单个用户的群组数据，返回新增、删除和改名的群
+func (s *DefaultGroupSyncScheduler) SyncGroupsForUser(ctx context.Context, userID uint) (*GroupSyncSummary, error) {
+	user, err := s.wxRobotSvc.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUserCallable(user); err != nil {
+		return nil, err
+	}
+
+	summary, err := s.syncGroupsForUser(ctx, *user)
+	if err != nil {
+		if !errors.Is(err, ErrGroupListUnavailable) {
+			s.recordSyncFailure(*user)
+		}
+		return nil, err
+	}
+	s.resetSyncFailure(*user)
+	s.markSynced(*user, time.Now())
+
+	s.logger.Info("手动同步用户群组完成",
+		zap.Uint("user_id", user.ID),
+		zap.String("wx_id", user.WxID),
+		zap.Int("total", summary.Total),
+		zap.Int("added", len(summary.Added)),
+		zap.Int("removed", len(summary.Removed)),
+		zap.Int("renamed", len(summary.Renamed)))
+	return summary, nil
+}
+
+// pruneSynced 清理已不在已初始化用户列表中的同步时间记录
+func (s *DefaultGroupSyncScheduler) pruneSynced(users []WxUserLogin) {
+	s.syncedMu.Lock()
+	defer s.syncedMu.Unlock()
+
+	current := make(map[uint]bool, len(users))
+	for _, user := range users {
+		current[user.ID] = true
+	}
+	for userID := range s.lastSynced {
+		if !current[userID] {
+			delete(s.lastSynced, userID)
+		}
+	}
+}
+
+// selectDueUsers 选出本轮需要同步的用户
+func (s *DefaultGroupSyncScheduler) selectDueUsers(users []WxUserLogin, now time.Time) []WxUserLogin {
+	s.syncedMu.Lock()
+	defer s.syncedMu.Unlock()
+	return dueGroupSyncUsers(users, s.lastSynced, now, s.cfg.MinInterval, s.cfg.MaxUsersPerRound)
+}
+
+// dueGroupSyncUsers 筛选距上次成功同步已达到minInterval的用户，从未同步过的优先，其余按上次同步时间升序，
+// maxUsers>0时最多返回maxUsers个，未选中的用户留到后续轮次，使同步请求分散到各轮
+func dueGroupSyncUsers(users []WxUserLogin, lastSynced map[uint]time.Time, now time.Time, minInterval time.Duration, maxUsers int) []WxUserLogin {
+	due := make([]WxUserLogin, 0, len(users))
+	for _, user := range users {
+		last, ok := lastSynced[user.ID]
+		if ok && now.Sub(last)+groupSyncIntervalSlack < minInterval {
+			continue
+		}
+		due = append(due, user)
+	}
+
+	// 未同步过的用户时间为零值，自然排在最前
+	sort.SliceStable(due, func(i, j int) bool {
+		return lastSynced[due[i].ID].Before(lastSynced[due[j].ID])
+	})
+
+	if maxUsers > 0 && len(due) > maxUsers {
+		due = due[:maxUsers]
+	}
+	return due
+}
+
+// markSynced 记录用户成功同步的时间
+func (s *DefaultGroupSyncScheduler) markSynced(user WxUserLogin, syncTime time.Time) {
+	s.syncedMu.Lock()
+	defer s.syncedMu.Unlock()
+	s.lastSynced[user.ID] = syncTime
+}
+
+// shouldSkipUser 判断用户是否处于退避期，处于退避期时消耗一轮
+func (s *DefaultGroupSyncScheduler) shouldSkipUser(user WxUserLogin) bool {
+	s.failureMu.Lock()
+	defer s.failureMu.Unlock()
+
+	state, ok := s.failures[user.ID]
+	if !ok || state.skipRounds <= 0 {
+		return false
+	}
+
+	state.skipRounds--
+	s.logger.Debug("用户处于群同步退避期，跳过本轮",
+		zap.Uint("user_id", user.ID),
+		zap.String("wx_id", user.WxID),
+		zap.Int("consecutive_failures", state.failures),
+		zap.Int("remaining_skip_rounds", state.skipRounds))
+	return true
+}
+
+// recordSyncFailure 记录一次同步失败，达到阈值后按指数退避跳过后续轮次并告警
+func (s *DefaultGroupSyncScheduler) recordSyncFailure(user WxUserLogin) {
+	s.failureMu.Lock()
+	defer s.failureMu.Unlock()
+
+	state, ok := s.failures[user.ID]
+	if !ok {
+		state = &groupSyncFailure{}
+		s.failures[user.ID] = state
+	}
+	state.failures++
+
+	if state.failures < groupSyncFailureThreshold {
+		return
+	}
+
+	// 第一次达到阈值跳过1轮，之后每次失败翻倍，直到上限
+	skipRounds := 1 << uint(state.failures-groupSyncFailureThreshold)
+	if skipRounds > groupSyncMaxSkipRounds || skipRounds <= 0 {
+		skipRounds = groupSyncMaxSkipRounds
+	}
+	state.skipRounds = skipRounds
+
+	s.logger.Error("用户群同步连续失败，暂停同步，可能已掉线",
+		zap.Uint("user_id", user.ID),
+		zap.String("wx_id", user.WxID),
+		zap.Int("consecutive_failures", state.failures),
+		zap.Int("skip_rounds", skipRounds))
+}
+
+// resetSyncFailure 同步成功后清除失败记录
+func (s *DefaultGroupSyncScheduler) resetSyncFailure(user WxUserLogin) {
+	s.failureMu.Lock()
+	defer s.failureMu.Unlock()
+
+	state, ok := s.failures[user.ID]
+	if !ok {
+		return
+	}
+	delete(s.failures, user.ID)
+
+	if state.failures >= groupSyncFailureThreshold {
+		s.logger.Info("用户群同步已恢复",
+			zap.Uint("user_id", user.ID),
+			zap.String("wx_id", user.WxID),
+			zap.Int("previous_failures", state.failures))
+	}
+}
+
 // syncGroupsForUser 同步单个用户的群组数据
-func (s *DefaultGroupSyncScheduler) syncGroupsForUser(user WxUserLogin) error {
+func (s *DefaultGroupSyncScheduler) syncGroupsForUser(ctx context.Context, user WxUserLogin) (*GroupSyncSummary, error) {
 	s.logger.Debug("开始同步用户群组数据",
 		zap.Uint("user_id", user.ID),
 		zap.String("wx_id", user.WxID))
@@ -117,17 +355,17 @@ func (s *DefaultGroupSyncScheduler) syncGroupsForUser(user WxUserLogin) error {
 		s.logger.Error("获取机器人配置失败",
 			zap.Uint("robot_id", user.RobotID),
 			zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	// 调用微信接口获取群列表
-	groupResp, err := s.wxRobotSvc.GetGroupList(robot.Address, user.Token)
+	groupResp, err := s.wxRobotSvc.GetGroupList(withRobotTimeout(ctx, robot), robot.Address, user.Token)
 	if err != nil {
 		s.logger.Error("获取群列表失败",
 			zap.String("address", robot.Address),
 			zap.String("token", user.Token),
 			zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	if groupResp.Code != 200 {
@@ -135,30 +373,65 @@ func (s *DefaultGroupSyncScheduler) syncGroupsForUser(user WxUserLogin) error {
 			zap.String("wx_id", user.WxID),
 			zap.Int("code", groupResp.Code),
 			zap.String("text", groupResp.Text))
-		return nil // 不算作错误，可能是临时问题
+		// 可能是临时问题，定时同步不算作错误
+		return nil, fmt.Errorf("%w: code=%d, %s", ErrGroupListUnavailable, groupResp.Code, groupResp.Text)
 	}
 
 	// 处理群组数据同步
 	return s.processGroupSync(user.WxID, groupResp)
 }
 
-// processGroupSync 处理群组数据同步逻辑
-func (s *DefaultGroupSyncScheduler) processGroupSync(wxID string, groupResp *GroupListResponse) error {
+// processGroupSync 处理群组数据同步逻辑，返回本次同步的变更明细
+func (s *DefaultGroupSyncScheduler) processGroupSync(wxID string, groupResp *GroupListResponse) (*GroupSyncSummary, error) {
+	// 同步前的群，用于对比新增、删除和改名
+	existingGroups, err := s.wxRobotSvc.GetGroupsByWxID(wxID)
+	if err != nil {
+		s.logger.Error("查询用户已有群组失败", zap.String("wx_id", wxID), zap.Error(err))
+		return nil, err
+	}
+	existing := make(map[string]string, len(existingGroups))
+	for _, g := range existingGroups {
+		existing[g.GroupID] = g.GroupNickName
+	}
+
+	summary := &GroupSyncSummary{
+		WxID:    wxID,
+		Added:   []GroupSyncItem{},
+		Removed: []GroupSyncItem{},
+		Renamed: []GroupRenameItem{},
+	}
+
 	// 提取当前API返回的群ID列表
 	currentGroupIDs := make([]string, 0, len(groupResp.Data.GroupList))
+	current := make(map[string]bool, len(groupResp.Data.GroupList))
 
 	// 保存或更新群组信息
 	for _, group := range groupResp.Data.GroupList {
 		groupID := group.UserName.Str
 		groupNickName := group.NickName.Str
 
+		// 过滤混入群列表的非群聊联系人
+		if !isChatRoomID(groupID) {
+			s.logger.Debug("跳过非群聊联系人", zap.String("wx_id", wxID), zap.String("user_name", groupID))
+			continue
+		}
+
 		currentGroupIDs = append(currentGroupIDs, groupID)
+		current[groupID] = true
+
+		if oldNickName, ok := existing[groupID]; !ok {
+			summary.Added = append(summary.Added, GroupSyncItem{GroupID: groupID, GroupNickName: groupNickName})
+		} else if oldNickName != groupNickName {
+			summary.Renamed = append(summary.Renamed, GroupRenameItem{GroupID: groupID, OldNickName: oldNickName, NewNickName: groupNickName})
+		}
 
 		// 保存或更新群组
 		wxGroup := &WxGroup{
 			WxID:          wxID,
 			GroupID:       groupID,
 			GroupNickName: groupNickName,
+			MemberCount:   group.NewChatroomData.MemberCount,
+			ChatRoomOwner: group.ChatRoomOwner,
 		}
 
 		if err := s.wxRobotSvc.SaveOrUpdateGroup(wxGroup); err != nil {
@@ -166,7 +439,22 @@ func (s *DefaultGroupSyncScheduler) processGroupSync(wxID string, groupResp *Gro
 				zap.String("wx_id", wxID),
 				zap.String("group_id", groupID),
 				zap.Error(err))
-			return err
+			return nil, err
+		}
+
+		// 同步群成员，用于发送消息时解析@昵称；群成员保存失败不影响群组同步
+		if memberList := group.NewChatroomData.ChatroomMemberList; len(memberList) > 0 {
+			members := make([]WxGroupMember, 0, len(memberList))
+			for _, m := range memberList {
+				members = append(members, WxGroupMember{
+					GroupID:    groupID,
+					MemberWxID: m.UserName,
+					NickName:   m.NickName,
+				})
+			}
+			if err := s.wxRobotSvc.SaveGroupMembers(groupID, members); err != nil {
+				s.logger.Warn("保存群成员失败", zap.String("group_id", groupID), zap.Error(err))
+			}
 		}
 	}
 
@@ -175,12 +463,26 @@ func (s *DefaultGroupSyncScheduler) processGroupSync(wxID string, groupResp *Gro
 		s.logger.Error("删除过期群组失败",
 			zap.String("wx_id", wxID),
 			zap.Error(err))
-		return err
+		return nil, err
 	}
+	for _, g := range existingGroups {
+		if !current[g.GroupID] {
+			summary.Removed = append(summary.Removed, GroupSyncItem{GroupID: g.GroupID, GroupNickName: g.GroupNickName})
+		}
+	}
+	summary.Total = len(currentGroupIDs)
 
 	s.logger.Debug("用户群组数据同步完成",
 		zap.String("wx_id", wxID),
-		zap.Int("group_count", len(currentGroupIDs)))
+		zap.Int("group_count", len(currentGroupIDs)),
+		zap.Int("added", len(summary.Added)),
+		zap.Int("removed", len(summary.Removed)),
+		zap.Int("renamed", len(summary.Renamed)))
 
-	return nil
-}
\ No newline at end of file
+	return summary, nil
+}
+
+// isChatRoomID 判断是否为群聊ID，群聊ID以@chatroom结尾
+func isChatRoomID(userName string) bool {
+	return strings.HasSuffix(userName, "@chatroom")
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestGroupList 按 群ID -> 群名 构造群列表响应
+func newTestGroupList(t *testing.T, groups [][2]string) *GroupListResponse {
+	t.Helper()
+	list := make([]map[string]interface{}, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, map[string]interface{}{
+			"userName": map[string]string{"str": g[0]},
+			"nickName": map[string]string{"str": g[1]},
+		})
+	}
+	data, _ := json.Marshal(map[string]interface{}{"Code": 200, "Data": map[string]interface{}{"GroupList": list}})
+
+	var resp GroupListResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("build group list: %v", err)
+	}
+	return &resp
+}
+
+func TestProcessGroupSyncSkipsNonChatRoom(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups [][2]string
+		want   []string
+	}{
+		{name: "only chatrooms", groups: [][2]string{{"1@chatroom", "群1"}, {"2@chatroom", "群2"}}, want: []string{"1@chatroom", "2@chatroom"}},
+		{name: "mixed contacts", groups: [][2]string{{"1@chatroom", "群1"}, {"wxid_friend", "好友"}, {"gh_official", "公众号"}}, want: []string{"1@chatroom"}},
+		{name: "no chatrooms", groups: [][2]string{{"wxid_friend", "好友"}}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, db := newTestService(t, nil)
+			scheduler := NewGroupSyncScheduler(zap.NewNop(), svc, GroupSyncConfig{}).(*DefaultGroupSyncScheduler)
+
+			summary, err := scheduler.processGroupSync("wxid_bot", newTestGroupList(t, tt.groups))
+			if err != nil {
+				t.Fatalf("processGroupSync: %v", err)
+			}
+			if summary.Total != len(tt.want) || len(summary.Added) != len(tt.want) {
+				t.Fatalf("summary total = %d added = %d, want %d", summary.Total, len(summary.Added), len(tt.want))
+			}
+
+			var saved []string
+			db.Model(&WxGroup{}).Where("wx_id = ?", "wxid_bot").Pluck("group_id", &saved)
+			sort.Strings(saved)
+			if len(saved) != len(tt.want) {
+				t.Fatalf("saved groups = %v, want %v", saved, tt.want)
+			}
+			for i := range saved {
+				if saved[i] != tt.want[i] {
+					t.Fatalf("saved groups = %v, want %v", saved, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupSyncFailureBackoff(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantSkipped  int
+		resetBetween bool
+	}{
+		{name: "below threshold", failures: 2, wantSkipped: 0},
+		{name: "reach threshold", failures: 3, wantSkipped: 1},
+		{name: "doubles", failures: 4, wantSkipped: 2},
+		{name: "doubles again", failures: 5, wantSkipped: 4},
+		{name: "capped", failures: 12, wantSkipped: groupSyncMaxSkipRounds},
+		{name: "reset on success", failures: 5, resetBetween: true, wantSkipped: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := NewGroupSyncScheduler(zap.NewNop(), nil, GroupSyncConfig{}).(*DefaultGroupSyncScheduler)
+			user := WxUserLogin{ID: 1, WxID: "wxid_offline"}
+			for i := 0; i < tt.failures; i++ {
+				scheduler.recordSyncFailure(user)
+			}
+			if tt.resetBetween {
+				scheduler.resetSyncFailure(user)
+			}
+
+			skipped := 0
+			for scheduler.shouldSkipUser(user) {
+				skipped++
+			}
+			if skipped != tt.wantSkipped {
+				t.Fatalf("skipped %d rounds, want %d", skipped, tt.wantSkipped)
+			}
+			if other := (WxUserLogin{ID: 2}); scheduler.shouldSkipUser(other) {
+				t.Fatal("unrelated user skipped")
+			}
+		})
+	}
+}
+
+func TestCheckUserCallable(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    WxUserLogin
+		wantErr bool
+	}{
+		{name: "callable", user: WxUserLogin{Token: "token", Status: UserStatusNormal}},
+		{name: "empty token", user: WxUserLogin{Status: UserStatusNormal}, wantErr: true},
+		{name: "blank token", user: WxUserLogin{Token: "  ", Status: UserStatusNormal}, wantErr: true},
+		{name: "need relogin", user: WxUserLogin{Token: "token", Status: UserStatusRelogin}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkUserCallable(&tt.user)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrUserNotCallable)) {
+				t.Fatalf("checkUserCallable = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGroupSyncSkipsUncallableUsers(t *testing.T) {
+	var mu sync.Mutex
+	calledKeys := make(map[string]int)
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.GroupList: func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			calledKeys[r.URL.Query().Get("key")]++
+			mu.Unlock()
+			jsonHandler(map[string]interface{}{"Code": 200})(w, r)
+		},
+	})
+	svc, db := newTestService(t, nil)
+	good := &WxUserLogin{WxID: "wxid_good", Token: "good-token", IsInitialized: 1}
+	empty := &WxUserLogin{WxID: "wxid_empty", IsInitialized: 1}
+	relogin := &WxUserLogin{WxID: "wxid_relogin", Token: "relogin-token", IsInitialized: 1}
+	createTestRobot(t, db, &WxRobotConfig{Address: server.URL}, good, empty, relogin)
+	db.Model(relogin).Update("status", UserStatusRelogin)
+
+	scheduler := NewGroupSyncScheduler(zap.NewNop(), svc, GroupSyncConfig{}).(*DefaultGroupSyncScheduler)
+	if err := scheduler.SyncGroupsForAllUsers(context.Background()); err != nil {
+		t.Fatalf("SyncGroupsForAllUsers: %v", err)
+	}
+	if want := map[string]int{"good-token": 1}; !reflect.DeepEqual(calledKeys, want) {
+		t.Fatalf("GroupList calls = %v, want %v", calledKeys, want)
+	}
+
+	tests := []struct {
+		name string
+		user *WxUserLogin
+	}{
+		{name: "empty token", user: empty},
+		{name: "need relogin", user: relogin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := scheduler.SyncGroupsForUser(context.Background(), tt.user.ID); !errors.Is(err, ErrUserNotCallable) {
+				t.Fatalf("SyncGroupsForUser = %v, want ErrUserNotCallable", err)
+			}
+			if scheduler.shouldSkipUser(*tt.user) {
+				t.Fatal("uncallable user counted as sync failure")
+			}
+		})
+	}
+	if len(calledKeys) != 1 {
+		t.Fatalf("GroupList called for uncallable users: %v", calledKeys)
+	}
+}
+
+func TestProcessGroupSyncSummary(t *testing.T) {
+	initial := [][2]string{{"1@chatroom", "群1"}, {"2@chatroom", "群2"}, {"3@chatroom", "群3"}}
+
+	tests := []struct {
+		name        string
+		groups      [][2]string
+		wantAdded   []GroupSyncItem
+		wantRemoved []GroupSyncItem
+		wantRenamed []GroupRenameItem
+	}{
+		{name: "unchanged", groups: initial},
+		{
+			name:      "added",
+			groups:    append(append([][2]string{}, initial...), [2]string{"4@chatroom", "群4"}),
+			wantAdded: []GroupSyncItem{{GroupID: "4@chatroom", GroupNickName: "群4"}},
+		},
+		{
+			name:        "removed",
+			groups:      [][2]string{{"1@chatroom", "群1"}, {"3@chatroom", "群3"}},
+			wantRemoved: []GroupSyncItem{{GroupID: "2@chatroom", GroupNickName: "群2"}},
+		},
+		{
+			name:        "renamed",
+			groups:      [][2]string{{"1@chatroom", "群1"}, {"2@chatroom", "新群2"}, {"3@chatroom", "群3"}},
+			wantRenamed: []GroupRenameItem{{GroupID: "2@chatroom", OldNickName: "群2", NewNickName: "新群2"}},
+		},
+		{
+			name:        "mixed",
+			groups:      [][2]string{{"1@chatroom", "财务群"}, {"5@chatroom", "群5"}},
+			wantAdded:   []GroupSyncItem{{GroupID: "5@chatroom", GroupNickName: "群5"}},
+			wantRemoved: []GroupSyncItem{{GroupID: "2@chatroom", GroupNickName: "群2"}, {GroupID: "3@chatroom", GroupNickName: "群3"}},
+			wantRenamed: []GroupRenameItem{{GroupID: "1@chatroom", OldNickName: "群1", NewNickName: "财务群"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(t, nil)
+			scheduler := NewGroupSyncScheduler(zap.NewNop(), svc, GroupSyncConfig{}).(*DefaultGroupSyncScheduler)
+			if _, err := scheduler.processGroupSync("wxid_bot", newTestGroupList(t, initial)); err != nil {
+				t.Fatalf("initial sync: %v", err)
+			}
+
+			summary, err := scheduler.processGroupSync("wxid_bot", newTestGroupList(t, tt.groups))
+			if err != nil {
+				t.Fatalf("processGroupSync: %v", err)
+			}
+			sort.Slice(summary.Removed, func(i, j int) bool { return summary.Removed[i].GroupID < summary.Removed[j].GroupID })
+			if tt.wantAdded == nil {
+				tt.wantAdded = []GroupSyncItem{}
+			}
+			if tt.wantRemoved == nil {
+				tt.wantRemoved = []GroupSyncItem{}
+			}
+			if tt.wantRenamed == nil {
+				tt.wantRenamed = []GroupRenameItem{}
+			}
+			if summary.Total != len(tt.groups) || !reflect.DeepEqual(summary.Added, tt.wantAdded) ||
+				!reflect.DeepEqual(summary.Removed, tt.wantRemoved) || !reflect.DeepEqual(summary.Renamed, tt.wantRenamed) {
+				t.Fatalf("summary = %+v", summary)
+			}
+		})
+	}
+}
+
+func TestDueGroupSyncUsers(t *testing.T) {
+	now := time.Now()
+	users := []WxUserLogin{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+
+	tests := []struct {
+		name        string
+		lastSynced  map[uint]time.Time
+		minInterval time.Duration
+		maxUsers    int
+		want        []uint
+	}{
+		{name: "never synced", want: []uint{1, 2, 3, 4}},
+		{
+			name:        "recently synced deferred",
+			lastSynced:  map[uint]time.Time{1: now.Add(-time.Minute), 3: now.Add(-2 * time.Hour)},
+			minInterval: time.Hour,
+			want:        []uint{2, 4, 3},
+		},
+		{
+			name:        "within slack",
+			lastSynced:  map[uint]time.Time{1: now.Add(-time.Hour + 5*time.Second)},
+			minInterval: time.Hour,
+			want:        []uint{2, 3, 4, 1},
+		},
+		{
+			name:       "oldest first with limit",
+			lastSynced: map[uint]time.Time{1: now.Add(-time.Minute), 2: now.Add(-3 * time.Minute), 3: now.Add(-2 * time.Minute)},
+			maxUsers:   2,
+			want:       []uint{4, 2},
+		},
+		{
+			name:        "all recently synced",
+			lastSynced:  map[uint]time.Time{1: now, 2: now, 3: now, 4: now},
+			minInterval: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []uint
+			for _, user := range dueGroupSyncUsers(users, tt.lastSynced, now, tt.minInterval, tt.maxUsers) {
+				got = append(got, user.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("due users = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupSyncStaggering(t *testing.T) {
+	var mu sync.Mutex
+	var calledKeys []string
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.GroupList: func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			calledKeys = append(calledKeys, r.URL.Query().Get("key"))
+			mu.Unlock()
+			jsonHandler(map[string]interface{}{"Code": 200, "Data": map[string]interface{}{"GroupList": []interface{}{}}})(w, r)
+		},
+	})
+	svc, db := newTestService(t, nil)
+	users := []*WxUserLogin{
+		{WxID: "wxid_a", Token: "token-a", IsInitialized: 1},
+		{WxID: "wxid_b", Token: "token-b", IsInitialized: 1},
+		{WxID: "wxid_c", Token: "token-c", IsInitialized: 1},
+	}
+	createTestRobot(t, db, &WxRobotConfig{Address: server.URL}, users...)
+	scheduler := NewGroupSyncScheduler(zap.NewNop(), svc, GroupSyncConfig{MinInterval: time.Hour, MaxUsersPerRound: 2}).(*DefaultGroupSyncScheduler)
+
+	steps := []struct {
+		name   string
+		before func() // 本轮开始前调整状态
+		want   []string
+	}{
+		{name: "first round limited", want: []string{"token-a", "token-b"}},
+		{name: "remaining user", want: []string{"token-c"}},
+		{name: "all recently synced"},
+		{
+			name:   "interval reached",
+			before: func() { scheduler.markSynced(*users[1], time.Now().Add(-2*time.Hour)) },
+			want:   []string{"token-b"},
+		},
+		{
+			name: "manual sync resets interval",
+			before: func() {
+				scheduler.markSynced(*users[0], time.Now().Add(-2*time.Hour))
+				if _, err := scheduler.SyncGroupsForUser(context.Background(), users[0].ID); err != nil {
+					t.Fatalf("SyncGroupsForUser: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, s := range steps {
+		t.Run(s.name, func(t *testing.T) {
+			if s.before != nil {
+				s.before()
+			}
+			calledKeys = nil
+			if err := scheduler.SyncGroupsForAllUsers(context.Background()); err != nil {
+				t.Fatalf("SyncGroupsForAllUsers: %v", err)
+			}
+			sort.Strings(calledKeys)
+			if !reflect.DeepEqual(calledKeys, s.want) {
+				t.Fatalf("GroupList calls = %v, want %v", calledKeys, s.want)
+			}
+		})
+	}
+}
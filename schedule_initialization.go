@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 )
@@ -9,26 +13,37 @@ import (
 type InitializationScheduler interface {
 	Start() error
 	Stop() error
-	CheckInitializationStatus() error
+	CheckInitializationStatus(ctx context.Context) error
 }
 
+// initializationTaskTimeout 单次检查的最长耗时，与调度间隔一致，避免慢机器人导致任务堆积
+const initializationTaskTimeout = 30 * time.Second
+
 // DefaultInitializationScheduler 默认的初始化状态检查实现
 type DefaultInitializationScheduler struct {
 	logger     *zap.Logger
 	wxRobotSvc WxRobotService
 	cron       *cron.Cron
+	cfg        InitializationConfig
+
+	// 已告警的初始化超时，key为用户ID，value为告警时的初始化开始时间，重新登录后会再次告警
+	timeoutMu      sync.Mutex
+	timeoutAlerted map[uint]time.Time
 }
 
 // NewInitializationScheduler 创建新的初始化状态检查定时任务
 func NewInitializationScheduler(
 	logger *zap.Logger,
 	wxRobotSvc WxRobotService,
+	cfg InitializationConfig,
 ) InitializationScheduler {
 	c := cron.New(cron.WithSeconds())
 	return &DefaultInitializationScheduler{
-		logger:     logger,
-		wxRobotSvc: wxRobotSvc,
-		cron:       c,
+		logger:         logger,
+		wxRobotSvc:     wxRobotSvc,
+		cron:           c,
+		cfg:            cfg,
+		timeoutAlerted: make(map[uint]time.Time),
 	}
 }
 
@@ -42,7 +57,9 @@ func (s *DefaultInitializationScheduler) Start() error {
 	// 添加定时任务
 	_, err := s.cron.AddFunc(cronExpr, func() {
 		s.logger.Debug("开始执行初始化状态检查任务")
-		if err := s.CheckInitializationStatus(); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), initializationTaskTimeout)
+		defer cancel()
+		if err := s.CheckInitializationStatus(ctx); err != nil {
 			s.logger.Error("初始化状态检查任务执行失败", zap.Error(err))
 		}
 	})
@@ -67,7 +84,7 @@ func (s *DefaultInitializationScheduler) Stop() error {
 }
 
 // CheckInitializationStatus 检查初始化状态的核心逻辑
-func (s *DefaultInitializationScheduler) CheckInitializationStatus() error {
+func (s *DefaultInitializationScheduler) CheckInitializationStatus(ctx context.Context) error {
 	s.logger.Debug("开始检查初始化状态")
 
 	// 1. 查询未初始化的用户
@@ -85,7 +102,11 @@ func (s *DefaultInitializationScheduler) CheckInitializationStatus() error {
 
 	// 2. 逐个检查用户的初始化状态
 	for _, user := range users {
-		if err := s.processUser(user); err != nil {
+		if ctx.Err() != nil {
+			s.logger.Warn("初始化状态检查超时，剩余用户下次检查", zap.Error(ctx.Err()))
+			break
+		}
+		if err := s.processUser(ctx, user); err != nil {
 			s.logger.Error("处理用户失败",
 				zap.Uint("user_id", user.ID),
 				zap.String("wx_id", user.WxID),
@@ -103,11 +124,20 @@ func (s *DefaultInitializationScheduler) getUninitializedUsers() ([]WxUserLogin,
 }
 
 // processUser 处理单个用户的初始化检查
-func (s *DefaultInitializationScheduler) processUser(user WxUserLogin) error {
+func (s *DefaultInitializationScheduler) processUser(ctx context.Context, user WxUserLogin) error {
 	s.logger.Debug("开始处理用户",
 		zap.Uint("user_id", user.ID),
 		zap.String("wx_id", user.WxID))
 
+	// token为空或状态不正常的用户调用必然失败，直接跳过
+	if err := checkUserCallable(&user); err != nil {
+		s.logger.Info("用户不可调用外部接口，跳过初始化检查",
+			zap.Uint("user_id", user.ID),
+			zap.String("wx_id", user.WxID),
+			zap.Error(err))
+		return nil
+	}
+
 	// 获取机器人配置
 	robot, err := s.wxRobotSvc.GetRobotByID(user.RobotID)
 	if err != nil {
@@ -118,7 +148,7 @@ func (s *DefaultInitializationScheduler) processUser(user WxUserLogin) error {
 	}
 
 	// 1. 检查初始化状态
-	initResp, err := s.wxRobotSvc.GetInitStatus(robot.Address, user.Token)
+	initResp, err := s.wxRobotSvc.GetInitStatus(withRobotTimeout(ctx, robot), robot.Address, user.Token)
 	if err != nil {
 		s.logger.Error("调用GetInitStatus失败",
 			zap.String("address", robot.Address),
@@ -135,6 +165,11 @@ func (s *DefaultInitializationScheduler) processUser(user WxUserLogin) error {
 			zap.Uint("user_id", user.ID),
 			zap.String("wx_id", user.WxID))
 
+		// 超时后已标记为需要重新登录的用户不再继续处理
+		if s.checkInitTimeout(user) {
+			return nil
+		}
+
 		// 检查是否存在该用户的群组数据
 		groups, err := s.wxRobotSvc.GetGroupsByWxID(user.WxID)
 		if err != nil {
@@ -162,7 +197,7 @@ func (s *DefaultInitializationScheduler) processUser(user WxUserLogin) error {
 		zap.String("wx_id", user.WxID))
 
 	// 2. 获取群列表
-	groupResp, err := s.wxRobotSvc.GetGroupList(robot.Address, user.Token)
+	groupResp, err := s.wxRobotSvc.GetGroupList(withRobotTimeout(ctx, robot), robot.Address, user.Token)
 	if err != nil {
 		s.logger.Error("获取群列表失败",
 			zap.String("address", robot.Address),
@@ -190,6 +225,36 @@ func (s *DefaultInitializationScheduler) processUser(user WxUserLogin) error {
 	return nil
 }
 
+// checkInitTimeout 检查用户是否初始化超时，超时时告警，同一次登录只告警一次
+// 返回true表示用户已因超时被标记为需要重新登录
+func (s *DefaultInitializationScheduler) checkInitTimeout(user WxUserLogin) bool {
+	if s.cfg.Timeout <= 0 || user.InitStartTime == nil {
+		return false
+	}
+
+	elapsed := time.Since(*user.InitStartTime)
+	if elapsed < s.cfg.Timeout {
+		return false
+	}
+
+	s.timeoutMu.Lock()
+	alertedStart, alerted := s.timeoutAlerted[user.ID]
+	s.timeoutMu.Unlock()
+	if alerted && alertedStart.Equal(*user.InitStartTime) {
+		return false
+	}
+
+	if err := s.wxRobotSvc.HandleInitTimeout(user, elapsed, s.cfg.ReloginOnTimeout); err != nil {
+		s.logger.Error("处理用户初始化超时失败", zap.Uint("user_id", user.ID), zap.Error(err))
+		return false
+	}
+
+	s.timeoutMu.Lock()
+	s.timeoutAlerted[user.ID] = *user.InitStartTime
+	s.timeoutMu.Unlock()
+	return s.cfg.ReloginOnTimeout
+}
+
 // saveGroupInfo 保存群信息到数据库
 func (s *DefaultInitializationScheduler) saveGroupInfo(wxID string, groupResp *GroupListResponse) error {
 	if groupResp.Code != 200 || len(groupResp.Data.GroupList) == 0 {
@@ -201,11 +266,19 @@ func (s *DefaultInitializationScheduler) saveGroupInfo(wxID string, groupResp *G
 		groupID := group.UserName.Str
 		groupNickName := group.NickName.Str
 
+		// 过滤混入群列表的非群聊联系人
+		if !isChatRoomID(groupID) {
+			s.logger.Debug("跳过非群聊联系人", zap.String("wx_id", wxID), zap.String("user_name", groupID))
+			continue
+		}
+
 		// 保存或更新群组
 		wxGroup := &WxGroup{
 			WxID:          wxID,
 			GroupID:       groupID,
 			GroupNickName: groupNickName,
+			MemberCount:   group.NewChatroomData.MemberCount,
+			ChatRoomOwner: group.ChatRoomOwner,
 		}
 
 		if err := s.wxRobotSvc.SaveOrUpdateGroup(wxGroup); err != nil {
@@ -215,6 +288,21 @@ func (s *DefaultInitializationScheduler) saveGroupInfo(wxID string, groupResp *G
 				zap.Error(err))
 			return err
 		}
+
+		// 同步群成员，用于发送消息时解析@昵称；群成员保存失败不影响群组同步
+		if memberList := group.NewChatroomData.ChatroomMemberList; len(memberList) > 0 {
+			members := make([]WxGroupMember, 0, len(memberList))
+			for _, m := range memberList {
+				members = append(members, WxGroupMember{
+					GroupID:    groupID,
+					MemberWxID: m.UserName,
+					NickName:   m.NickName,
+				})
+			}
+			if err := s.wxRobotSvc.SaveGroupMembers(groupID, members); err != nil {
+				s.logger.Warn("保存群成员失败", zap.String("group_id", groupID), zap.Error(err))
+			}
+		}
 	}
 
 	return nil
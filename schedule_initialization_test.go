@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// countingInitTimeoutService 统计HandleInitTimeout调用次数
+type countingInitTimeoutService struct {
+	WxRobotService
+	calls int
+}
+
+func (s *countingInitTimeoutService) HandleInitTimeout(user WxUserLogin, elapsed time.Duration, relogin bool) error {
+	s.calls++
+	return s.WxRobotService.HandleInitTimeout(user, elapsed, relogin)
+}
+
+func TestCheckInitTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         InitializationConfig
+		startedAgo  time.Duration
+		noStartTime bool
+		wantRelogin bool
+		wantAlerts  int
+		wantStatus  int
+	}{
+		{name: "timeout disabled", cfg: InitializationConfig{}, startedAgo: time.Hour, wantStatus: UserStatusNormal},
+		{name: "within timeout", cfg: InitializationConfig{Timeout: 10 * time.Minute}, startedAgo: time.Minute, wantStatus: UserStatusNormal},
+		{name: "no start time", cfg: InitializationConfig{Timeout: 10 * time.Minute}, noStartTime: true, wantStatus: UserStatusNormal},
+		{name: "timeout alert only once", cfg: InitializationConfig{Timeout: 10 * time.Minute}, startedAgo: time.Hour, wantAlerts: 1, wantStatus: UserStatusNormal},
+		{name: "timeout relogin", cfg: InitializationConfig{Timeout: 10 * time.Minute, ReloginOnTimeout: true}, startedAgo: time.Hour, wantRelogin: true, wantAlerts: 1, wantStatus: UserStatusRelogin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, db := newTestService(t, nil)
+			user := &WxUserLogin{WxID: "wxid_init", Token: "token-init"}
+			if !tt.noStartTime {
+				started := time.Now().Add(-tt.startedAgo)
+				user.InitStartTime = &started
+			}
+			createTestRobot(t, db, &WxRobotConfig{Address: "http://robot.invalid", OwnerID: 1}, user)
+
+			counting := &countingInitTimeoutService{WxRobotService: svc}
+			scheduler := NewInitializationScheduler(zap.NewNop(), counting, tt.cfg).(*DefaultInitializationScheduler)
+
+			if got := scheduler.checkInitTimeout(*user); got != tt.wantRelogin {
+				t.Fatalf("checkInitTimeout = %v, want %v", got, tt.wantRelogin)
+			}
+			// 同一次登录再次检查不重复告警
+			scheduler.checkInitTimeout(*user)
+			if counting.calls != tt.wantAlerts {
+				t.Fatalf("alerts = %d, want %d", counting.calls, tt.wantAlerts)
+			}
+
+			var saved WxUserLogin
+			db.First(&saved, user.ID)
+			if saved.Status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", saved.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 )
@@ -9,9 +13,12 @@ import (
 type LoginStatusScheduler interface {
 	Start() error
 	Stop() error
-	CheckLoginStatus() error
+	CheckLoginStatus(ctx context.Context) error
 }
 
+// loginStatusTaskTimeout 单次检查的最长耗时，与调度间隔一致，避免慢机器人导致任务堆积
+const loginStatusTaskTimeout = 30 * time.Second
+
 // DefaultLoginStatusScheduler 默认的登录状态检查实现
 type DefaultLoginStatusScheduler struct {
 	logger     *zap.Logger
@@ -42,7 +49,9 @@ func (s *DefaultLoginStatusScheduler) Start() error {
 	// 添加定时任务
 	_, err := s.cron.AddFunc(cronExpr, func() {
 		s.logger.Debug("开始执行登录状态检查任务")
-		if err := s.CheckLoginStatus(); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), loginStatusTaskTimeout)
+		defer cancel()
+		if err := s.CheckLoginStatus(ctx); err != nil {
 			s.logger.Error("登录状态检查任务执行失败", zap.Error(err))
 		}
 	})
@@ -67,7 +76,7 @@ func (s *DefaultLoginStatusScheduler) Stop() error {
 }
 
 // CheckLoginStatus 检查登录状态的核心逻辑
-func (s *DefaultLoginStatusScheduler) CheckLoginStatus() error {
+func (s *DefaultLoginStatusScheduler) CheckLoginStatus(ctx context.Context) error {
 	s.logger.Debug("开始检查用户登录状态")
 
 	// 1. 查询状态为1的活跃用户
@@ -90,6 +99,11 @@ func (s *DefaultLoginStatusScheduler) CheckLoginStatus() error {
 	reloginCount := 0
 
 	for _, user := range users {
+		if ctx.Err() != nil {
+			s.logger.Warn("登录状态检查超时，剩余用户下次检查", zap.Error(ctx.Err()))
+			break
+		}
+
 		// 检查用户是否需要重新登录
 		robot, err := s.wxRobotSvc.GetRobotByID(user.RobotID)
 		if err != nil {
@@ -98,7 +112,17 @@ func (s *DefaultLoginStatusScheduler) CheckLoginStatus() error {
 			continue
 		}
 
-		resp, err := s.wxRobotSvc.CheckCanSetAlias(robot.Address, user.Token)
+		// 返回token过期时，服务层已将用户标记为需要重新登录并发送通知
+		_, err = s.wxRobotSvc.CheckCanSetAlias(withRobotTimeout(ctx, robot), robot.Address, user.Token)
+		if errors.Is(err, ErrTokenExpired) {
+			s.logger.Info("用户需要重新登录，状态已更新",
+				zap.Uint("user_id", user.ID),
+				zap.String("wx_id", user.WxID),
+				zap.Int("new_status", UserStatusRelogin),
+				zap.String("status_desc", "需要重新登录"))
+			reloginCount++
+			continue
+		}
 		if err != nil {
 			s.logger.Error("调用CheckCanSetAlias失败",
 				zap.String("address", robot.Address),
@@ -107,25 +131,7 @@ func (s *DefaultLoginStatusScheduler) CheckLoginStatus() error {
 			errorCount++
 			continue
 		}
-
-		// 如果返回代码是300，表示需要重新登录
-		if resp.Code == 300 {
-			if err := s.wxRobotSvc.UpdateUserStatus(user.ID, 3); err != nil {
-				s.logger.Error("更新用户状态为需要重新登录失败",
-					zap.Uint("user_id", user.ID),
-					zap.Error(err))
-				errorCount++
-				continue
-			}
-			s.logger.Info("用户需要重新登录，状态已更新",
-				zap.Uint("user_id", user.ID),
-				zap.String("wx_id", user.WxID),
-				zap.Int("new_status", 3),
-				zap.String("status_desc", "需要重新登录"))
-			reloginCount++
-		} else {
-			successCount++
-		}
+		successCount++
 	}
 
 	s.logger.Info("登录状态检查任务完成",
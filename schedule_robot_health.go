@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// RobotHealthScheduler 机器人健康巡检定时任务接口
+type RobotHealthScheduler interface {
+	Start() error
+	Stop() error
+	CheckRobotsHealth(ctx context.Context) error
+}
+
+// DefaultRobotHealthScheduler 默认的机器人健康巡检实现，连续失败达到阈值才告警
+type DefaultRobotHealthScheduler struct {
+	logger     *zap.Logger
+	wxRobotSvc WxRobotService
+	cron       *cron.Cron
+	cfg        RobotHealthConfig
+	debouncer  *healthDebouncer
+}
+
+// NewRobotHealthScheduler 创建新的机器人健康巡检定时任务
+func NewRobotHealthScheduler(
+	logger *zap.Logger,
+	wxRobotSvc WxRobotService,
+	cfg RobotHealthConfig,
+) RobotHealthScheduler {
+	c := cron.New(cron.WithSeconds())
+	return &DefaultRobotHealthScheduler{
+		logger:     logger,
+		wxRobotSvc: wxRobotSvc,
+		cron:       c,
+		cfg:        cfg,
+		debouncer:  newHealthDebouncer(cfg.FailThreshold),
+	}
+}
+
+// Start 启动机器人健康巡检定时任务，间隔为0时不启动
+func (s *DefaultRobotHealthScheduler) Start() error {
+	if s.cfg.Interval <= 0 {
+		s.logger.Info("机器人健康巡检未开启")
+		return nil
+	}
+
+	s.logger.Info("启动机器人健康巡检定时任务",
+		zap.Duration("interval", s.cfg.Interval),
+		zap.Int("fail_threshold", s.debouncer.threshold))
+
+	_, err := s.cron.AddFunc(fmt.Sprintf("@every %s", s.cfg.Interval), func() {
+		s.logger.Debug("开始执行机器人健康巡检任务")
+		// 单次执行不超过调度间隔，避免慢机器人导致任务堆积
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Interval)
+		defer cancel()
+		if err := s.CheckRobotsHealth(ctx); err != nil {
+			s.logger.Error("机器人健康巡检任务执行失败", zap.Error(err))
+		}
+	})
+
+	if err != nil {
+		s.logger.Error("添加机器人健康巡检定时任务失败", zap.Error(err))
+		return err
+	}
+
+	s.cron.Start()
+	s.logger.Info("机器人健康巡检定时任务启动完成")
+	return nil
+}
+
+// Stop 停止机器人健康巡检定时任务
+func (s *DefaultRobotHealthScheduler) Stop() error {
+	s.logger.Info("停止机器人健康巡检定时任务")
+	ctx := s.cron.Stop()
+	<-ctx.Done()
+	s.logger.Info("机器人健康巡检定时任务停止完成")
+	return nil
+}
+
+// CheckRobotsHealth 检查全部机器人，连续失败达到阈值时告警，告警后恢复时发送恢复通知
+func (s *DefaultRobotHealthScheduler) CheckRobotsHealth(ctx context.Context) error {
+	summary, err := s.wxRobotSvc.CheckRobotsHealth(ctx, nil, 0)
+	if err != nil {
+		return err
+	}
+
+	robotIDs := make([]uint, 0, len(summary.Results))
+	for _, result := range summary.Results {
+		robotIDs = append(robotIDs, result.RobotID)
+
+		healthy := result.Status == "healthy"
+		failures, alert, recovered := s.debouncer.Observe(result.RobotID, healthy)
+		switch {
+		case alert:
+			s.logger.Warn("机器人连续健康检查失败，发送告警",
+				zap.Uint("robot_id", result.RobotID),
+				zap.String("address", result.Address),
+				zap.Int("failures", failures),
+				zap.String("error", result.Error))
+			s.wxRobotSvc.NotifyRobotHealth(result, failures, false)
+		case recovered:
+			s.logger.Info("机器人恢复健康，发送恢复通知",
+				zap.Uint("robot_id", result.RobotID),
+				zap.String("address", result.Address))
+			s.wxRobotSvc.NotifyRobotHealth(result, failures, true)
+		case !healthy:
+			s.logger.Debug("机器人健康检查失败，未达到告警阈值",
+				zap.Uint("robot_id", result.RobotID),
+				zap.Int("failures", failures),
+				zap.Int("fail_threshold", s.debouncer.threshold))
+		}
+	}
+	// 已删除的机器人不再保留失败计数
+	s.debouncer.Retain(robotIDs)
+
+	s.logger.Debug("机器人健康巡检完成",
+		zap.Int("total", summary.Total),
+		zap.Int("healthy", summary.Healthy),
+		zap.Int("unhealthy", summary.Unhealthy))
+	return nil
+}
+
+// healthDebouncer 健康检查告警去抖：按机器人记录连续失败次数，达到阈值告警一次，恢复时通知一次
+type healthDebouncer struct {
+	mu        sync.Mutex
+	threshold int
+	states    map[uint]*healthState
+}
+
+// healthState 单个机器人的连续失败次数与告警状态
+type healthState struct {
+	failures int
+	alerted  bool
+}
+
+// newHealthDebouncer 创建告警去抖，阈值小于1时按1处理（每次失败都告警）
+func newHealthDebouncer(threshold int) *healthDebouncer {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &healthDebouncer{threshold: threshold, states: make(map[uint]*healthState)}
+}
+
+// Observe 记录一次检查结果，返回当前连续失败次数、是否需要告警、是否需要发送恢复通知
+func (d *healthDebouncer) Observe(robotID uint, healthy bool) (failures int, alert, recovered bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.states[robotID]
+	if healthy {
+		if !ok {
+			return 0, false, false
+		}
+		delete(d.states, robotID)
+		return state.failures, false, state.alerted
+	}
+
+	if !ok {
+		state = &healthState{}
+		d.states[robotID] = state
+	}
+	state.failures++
+	if !state.alerted && state.failures >= d.threshold {
+		state.alerted = true
+		return state.failures, true, false
+	}
+	return state.failures, false, false
+}
+
+// Retain 只保留指定机器人的状态
+func (d *healthDebouncer) Retain(robotIDs []uint) {
+	keep := make(map[uint]bool, len(robotIDs))
+	for _, id := range robotIDs {
+		keep[id] = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for id := range d.states {
+		if !keep[id] {
+			delete(d.states, id)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// scriptedHealthService 按顺序返回健康检查结果并记录告警通知
+type scriptedHealthService struct {
+	WxRobotService
+	rounds  [][]bool // 每轮各机器人（ID从1开始）是否健康
+	round   int
+	notices []string
+}
+
+func (s *scriptedHealthService) CheckRobotsHealth(ctx context.Context, robotIDs []uint, ownerID uint) (*RobotHealthSummary, error) {
+	summary := &RobotHealthSummary{}
+	for i, healthy := range s.rounds[s.round] {
+		status := "healthy"
+		if !healthy {
+			status = "unhealthy"
+		}
+		summary.Results = append(summary.Results, RobotHealthResult{RobotID: uint(i + 1), Status: status})
+	}
+	s.round++
+	return summary, nil
+}
+
+func (s *scriptedHealthService) NotifyRobotHealth(result RobotHealthResult, failures int, recovered bool) {
+	if recovered {
+		s.notices = append(s.notices, fmt.Sprintf("%d:recovered", result.RobotID))
+		return
+	}
+	s.notices = append(s.notices, fmt.Sprintf("%d:alert:%d", result.RobotID, failures))
+}
+
+func TestCheckRobotsHealthDebounce(t *testing.T) {
+	const ok, fail = true, false
+
+	tests := []struct {
+		name      string
+		threshold int
+		rounds    [][]bool
+		want      []string
+	}{
+		{name: "occasional failure not alerted", threshold: 3, rounds: [][]bool{{fail}, {ok}, {fail}, {fail}, {ok}}},
+		{name: "consecutive failures alert once", threshold: 3, rounds: [][]bool{{fail}, {fail}, {fail}, {fail}, {fail}}, want: []string{"1:alert:3"}},
+		{name: "recovered after alert", threshold: 2, rounds: [][]bool{{fail}, {fail}, {ok}, {ok}}, want: []string{"1:alert:2", "1:recovered"}},
+		{name: "alert again after recovery", threshold: 2, rounds: [][]bool{{fail}, {fail}, {ok}, {fail}, {fail}}, want: []string{"1:alert:2", "1:recovered", "1:alert:2"}},
+		{name: "robots counted separately", threshold: 2, rounds: [][]bool{{fail, ok}, {ok, fail}, {fail, fail}, {fail, ok}}, want: []string{"2:alert:2", "1:alert:2", "2:recovered"}},
+		{name: "threshold below one alerts every failure", threshold: 0, rounds: [][]bool{{fail}, {ok}, {fail}}, want: []string{"1:alert:1", "1:recovered", "1:alert:1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &scriptedHealthService{rounds: tt.rounds}
+			scheduler := NewRobotHealthScheduler(zap.NewNop(), svc, RobotHealthConfig{FailThreshold: tt.threshold})
+			for range tt.rounds {
+				if err := scheduler.CheckRobotsHealth(context.Background()); err != nil {
+					t.Fatalf("CheckRobotsHealth: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(svc.notices, tt.want) {
+				t.Fatalf("notices = %v, want %v", svc.notices, tt.want)
+			}
+		})
+	}
+}
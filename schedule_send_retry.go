@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// SendRetryScheduler 失败消息重试定时任务接口
+type SendRetryScheduler interface {
+	Start() error
+	Stop() error
+	ProcessSendRetries(ctx context.Context) error
+}
+
+// DefaultSendRetryScheduler 默认的失败消息重试实现
+type DefaultSendRetryScheduler struct {
+	logger     *zap.Logger
+	wxRobotSvc WxRobotService
+	cron       *cron.Cron
+	cfg        SendRetryConfig
+	strategy   MessageSendStrategy
+}
+
+// NewSendRetryScheduler 创建新的失败消息重试定时任务
+func NewSendRetryScheduler(
+	logger *zap.Logger,
+	wxRobotSvc WxRobotService,
+	cfg SendRetryConfig,
+) SendRetryScheduler {
+	c := cron.New(cron.WithSeconds())
+	return &DefaultSendRetryScheduler{
+		logger:     logger,
+		wxRobotSvc: wxRobotSvc,
+		cron:       c,
+		cfg:        cfg,
+		strategy:   NewRandomMessageSendStrategy(),
+	}
+}
+
+// Start 启动失败消息重试定时任务，未开启重试队列时不启动
+func (s *DefaultSendRetryScheduler) Start() error {
+	if !s.cfg.Enable || s.cfg.Interval <= 0 {
+		s.logger.Info("失败消息重试队列未开启")
+		return nil
+	}
+
+	s.logger.Info("启动失败消息重试定时任务",
+		zap.Duration("interval", s.cfg.Interval),
+		zap.Int("batch_size", s.cfg.BatchSize),
+		zap.Int("max_attempts", s.cfg.MaxAttempts))
+
+	_, err := s.cron.AddFunc(fmt.Sprintf("@every %s", s.cfg.Interval), func() {
+		s.logger.Debug("开始执行失败消息重试任务")
+		// 单次执行不超过调度间隔，避免慢机器人导致任务堆积
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Interval)
+		defer cancel()
+		if err := s.ProcessSendRetries(ctx); err != nil {
+			s.logger.Error("失败消息重试任务执行失败", zap.Error(err))
+		}
+	})
+
+	if err != nil {
+		s.logger.Error("添加失败消息重试定时任务失败", zap.Error(err))
+		return err
+	}
+
+	s.cron.Start()
+	s.logger.Info("失败消息重试定时任务启动完成")
+	return nil
+}
+
+// Stop 停止失败消息重试定时任务
+func (s *DefaultSendRetryScheduler) Stop() error {
+	s.logger.Info("停止失败消息重试定时任务")
+	ctx := s.cron.Stop()
+	<-ctx.Done()
+	s.logger.Info("失败消息重试定时任务停止完成")
+	return nil
+}
+
+// ProcessSendRetries 重发到期的失败消息
+func (s *DefaultSendRetryScheduler) ProcessSendRetries(ctx context.Context) error {
+	summary, err := s.wxRobotSvc.ProcessSendRetries(ctx, s.strategy)
+	if err != nil {
+		return err
+	}
+	if summary.Total > 0 {
+		s.logger.Info("失败消息重试完成",
+			zap.Int("total", summary.Total),
+			zap.Int("success", summary.Success),
+			zap.Int("pending", summary.Pending),
+			zap.Int("failed", summary.Failed))
+	}
+	return nil
+}
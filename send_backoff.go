@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 失败率统计的最少样本数，样本不足时只按风控错误退避
+const backoffMinSamples = 5
+
+// SendBackoff 按发送账号自适应退避：出现风控错误或近期失败率过高时加大该账号的发送间隔，
+// 发送恢复正常后逐步缩小间隔直至不限制
+type SendBackoff struct {
+	mu          sync.Mutex
+	enable      bool
+	window      int
+	failureRate float64
+	minInterval time.Duration
+	maxInterval time.Duration
+	accounts    map[string]*accountBackoff
+	logger      *zap.Logger
+}
+
+// accountBackoff 单个账号的退避状态
+type accountBackoff struct {
+	results  []bool // 最近window次发送结果，true为失败
+	pos      int
+	interval time.Duration // 当前发送间隔，0表示不限制
+	next     time.Time     // 下次允许发送的时间
+}
+
+// NewSendBackoff 创建自适应退避
+func NewSendBackoff(cfg SendBackoffConfig, logger *zap.Logger) *SendBackoff {
+	window := cfg.Window
+	if window < backoffMinSamples {
+		window = backoffMinSamples
+	}
+	minInterval := cfg.MinInterval
+	if minInterval <= 0 {
+		minInterval = time.Second
+	}
+	maxInterval := cfg.MaxInterval
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+
+	return &SendBackoff{
+		enable:      cfg.Enable,
+		window:      window,
+		failureRate: cfg.FailureRate,
+		minInterval: minInterval,
+		maxInterval: maxInterval,
+		accounts:    make(map[string]*accountBackoff),
+		logger:      logger,
+	}
+}
+
+// Wait 按账号当前间隔阻塞，直到允许发送；ctx取消时立即返回ctx的错误
+func (b *SendBackoff) Wait(ctx context.Context, authKey string) error {
+	if !b.enable {
+		return nil
+	}
+
+	b.mu.Lock()
+	state, ok := b.accounts[authKey]
+	if !ok || state.interval <= 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if state.next.Before(now) {
+		state.next = now
+	}
+	wait := state.next.Sub(now)
+	state.next = state.next.Add(state.interval)
+	b.mu.Unlock()
+
+	return sleepContext(ctx, wait)
+}
+
+// Record 记录一次发送结果并调整账号的发送间隔：
+// 发送失败且为风控错误或失败率达到阈值时间隔翻倍（不超过上限）；
+// 发送成功且失败率低于阈值时间隔减半，低于下限时取消限制
+func (b *SendBackoff) Record(authKey string, sendErr error) {
+	// 被本地限流拒绝的发送未实际发出，不计入发送结果
+	if !b.enable || errors.Is(sendErr, ErrRateLimited) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.accounts[authKey]
+	if !ok {
+		if sendErr == nil {
+			return
+		}
+		state = &accountBackoff{results: make([]bool, 0, b.window)}
+		b.accounts[authKey] = state
+	}
+
+	failed := sendErr != nil
+	if len(state.results) < b.window {
+		state.results = append(state.results, failed)
+	} else {
+		state.results[state.pos] = failed
+		state.pos = (state.pos + 1) % b.window
+	}
+
+	rate := state.failureRate()
+	riskControl := classifySendError(sendErr) == SendErrorRiskControl
+	highRate := len(state.results) >= backoffMinSamples && rate >= b.failureRate
+	if failed && (riskControl || highRate) {
+		previous := state.interval
+		state.interval *= 2
+		if state.interval < b.minInterval {
+			state.interval = b.minInterval
+		}
+		if state.interval > b.maxInterval {
+			state.interval = b.maxInterval
+		}
+		if state.interval != previous {
+			b.logger.Warn("账号发送出现风控迹象，加大发送间隔",
+				zap.String("token", maskToken(authKey)),
+				zap.Bool("risk_control", riskControl),
+				zap.Float64("failure_rate", rate),
+				zap.Duration("interval", state.interval))
+		}
+		return
+	}
+
+	if failed || highRate || state.interval <= 0 {
+		return
+	}
+	state.interval /= 2
+	if state.interval < b.minInterval {
+		b.logger.Info("账号发送恢复正常，取消发送间隔限制", zap.String("token", maskToken(authKey)))
+		delete(b.accounts, authKey)
+	}
+}
+
+// failureRate 最近发送的失败率
+func (s *accountBackoff) failureRate() float64 {
+	if len(s.results) == 0 {
+		return 0
+	}
+	failures := 0
+	for _, failed := range s.results {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(s.results))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSendBackoffInterval(t *testing.T) {
+	risk := newSendError(SendErrorRiskControl, "操作过于频繁")
+	failed := errors.New("发送失败")
+	cfg := SendBackoffConfig{Enable: true, Window: 5, FailureRate: 0.5, MinInterval: time.Second, MaxInterval: 8 * time.Second}
+
+	tests := []struct {
+		name     string
+		disabled bool
+		results  []error
+		want     time.Duration
+	}{
+		{name: "success only", results: []error{nil, nil}},
+		{name: "few plain failures", results: []error{failed, failed}},
+		{name: "risk control starts at min", results: []error{risk}, want: time.Second},
+		{name: "risk control doubles", results: []error{risk, risk, risk}, want: 4 * time.Second},
+		{name: "capped at max", results: []error{risk, risk, risk, risk, risk, risk}, want: 8 * time.Second},
+		{name: "failure rate reached", results: []error{failed, nil, failed, nil, failed}, want: time.Second},
+		{name: "failure rate below threshold", results: []error{failed, nil, nil, nil, failed}},
+		{name: "success halves", results: []error{risk, risk, risk, nil}, want: 2 * time.Second},
+		{name: "recovered", results: []error{risk, risk, nil, nil}},
+		{name: "rate limited ignored", results: []error{ErrRateLimited, ErrRateLimited}},
+		{name: "disabled", disabled: true, results: []error{risk, risk}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			c.Enable = !tt.disabled
+			b := NewSendBackoff(c, zap.NewNop())
+			for _, err := range tt.results {
+				b.Record("token", err)
+			}
+
+			var got time.Duration
+			if state, ok := b.accounts["token"]; ok {
+				got = state.interval
+			}
+			if got != tt.want {
+				t.Fatalf("interval = %v, want %v", got, tt.want)
+			}
+			if _, ok := b.accounts["other"]; ok {
+				t.Fatal("unrelated account backed off")
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SendErrorType 消息发送失败分类，调用方据此决定重试还是放弃
+type SendErrorType string
+
+const (
+	SendErrorNetwork         SendErrorType = "network_error"    // 网络错误或超时，可重试
+	SendErrorRiskControl     SendErrorType = "risk_control"     // 账号被风控或发送频率受限，应暂停发送
+	SendErrorTargetNotFound  SendErrorType = "target_not_found" // 目标群不存在或已不在群内，不应重试
+	SendErrorContentRejected SendErrorType = "content_rejected" // 内容违规被拒绝，修改内容前不应重试
+	SendErrorUnknown         SendErrorType = "unknown"          // 无法识别的失败
+)
+
+// Retryable 该类失败是否可直接重试
+func (t SendErrorType) Retryable() bool {
+	return t == SendErrorNetwork
+}
+
+// SendError 带分类的发送失败错误，调用方可通过errors.As取出分类
+type SendError struct {
+	Type SendErrorType
+	Err  error
+}
+
+func (e *SendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// newSendError 创建带分类的发送失败错误
+func newSendError(errType SendErrorType, format string, args ...interface{}) *SendError {
+	return &SendError{Type: errType, Err: fmt.Errorf(format, args...)}
+}
+
+// 按外部接口返回的失败信息归类的关键字，按顺序匹配
+var sendFailureKeywords = []struct {
+	errType  SendErrorType
+	keywords []string
+}{
+	{SendErrorRiskControl, []string{"风控", "频繁", "限制", "封禁", "frequency", "limit"}},
+	{SendErrorTargetNotFound, []string{"不存在", "不在群", "已退出", "已解散", "移出", "not found", "not exist"}},
+	{SendErrorContentRejected, []string{"违规", "敏感", "违法", "拒绝", "rejected", "forbidden"}},
+}
+
+// classifySendFailure 根据外部接口返回的失败信息归类
+func classifySendFailure(msg string) SendErrorType {
+	lower := strings.ToLower(msg)
+	for _, item := range sendFailureKeywords {
+		for _, keyword := range item.keywords {
+			if strings.Contains(lower, keyword) {
+				return item.errType
+			}
+		}
+	}
+	return SendErrorUnknown
+}
+
+// classifySendError 获取发送错误的分类，nil返回空
+func classifySendError(err error) SendErrorType {
+	if err == nil {
+		return ""
+	}
+
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.Type
+	}
+
+	// 本地限流拒绝，说明账号发送过快，与风控同样应放慢发送
+	if errors.Is(err, ErrRateLimited) {
+		return SendErrorRiskControl
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return SendErrorNetwork
+	}
+	return SendErrorUnknown
+}
+
+// SendErrorInfo 发送失败时响应data中的错误分类
+type SendErrorInfo struct {
+	ErrorType SendErrorType `json:"error_type"`
+	Retryable bool          `json:"retryable"`
+	RetryID   uint          `json:"retry_id,omitempty"` // 已加入后台重试队列时的重试记录ID
+}
+
+// newSendErrorInfo 根据发送错误生成分类信息
+func newSendErrorInfo(err error) SendErrorInfo {
+	errType := classifySendError(err)
+	return SendErrorInfo{ErrorType: errType, Retryable: errType.Retryable()}
+}
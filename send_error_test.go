@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestClassifySendFailure(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want SendErrorType
+	}{
+		{msg: "操作过于频繁，请稍后再试", want: SendErrorRiskControl},
+		{msg: "账号已被风控", want: SendErrorRiskControl},
+		{msg: "Frequency Limit", want: SendErrorRiskControl},
+		{msg: "群聊不存在", want: SendErrorTargetNotFound},
+		{msg: "你已不在群聊中", want: SendErrorTargetNotFound},
+		{msg: "chatroom not found", want: SendErrorTargetNotFound},
+		{msg: "消息包含敏感内容", want: SendErrorContentRejected},
+		{msg: "Content Rejected", want: SendErrorContentRejected},
+		{msg: "发送状态为失败", want: SendErrorUnknown},
+		{msg: "", want: SendErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			if got := classifySendFailure(tt.msg); got != tt.want {
+				t.Fatalf("classifySendFailure(%q) = %s, want %s", tt.msg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifySendError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		want          SendErrorType
+		wantRetryable bool
+	}{
+		{name: "nil"},
+		{name: "send error", err: newSendError(SendErrorTargetNotFound, "群不存在"), want: SendErrorTargetNotFound},
+		{name: "wrapped send error", err: fmt.Errorf("批量发送: %w", newSendError(SendErrorNetwork, "超时")), want: SendErrorNetwork, wantRetryable: true},
+		{name: "rate limited", err: fmt.Errorf("token: %w", ErrRateLimited), want: SendErrorRiskControl},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, want: SendErrorNetwork, wantRetryable: true},
+		{name: "plain error", err: errors.New("boom"), want: SendErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifySendError(tt.err); got != tt.want {
+				t.Fatalf("classifySendError = %q, want %q", got, tt.want)
+			}
+			if info := newSendErrorInfo(tt.err); info.ErrorType != tt.want || info.Retryable != tt.wantRetryable {
+				t.Fatalf("newSendErrorInfo = %+v, want type %q retryable %v", info, tt.want, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestWxAPIClientSendTextErrorType(t *testing.T) {
+	failed := func(errMsg string) map[string]interface{} {
+		return map[string]interface{}{
+			"Code": 200,
+			"Data": []interface{}{map[string]interface{}{
+				"isSendSuccess": true,
+				"resp": map[string]interface{}{
+					"base_response": map[string]interface{}{"ret": -1, "errMsg": map[string]interface{}{"str": errMsg}},
+				},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    SendErrorType
+	}{
+		{name: "risk control", handler: jsonHandler(failed("发送过于频繁")), want: SendErrorRiskControl},
+		{name: "target not found", handler: jsonHandler(failed("群聊已解散")), want: SendErrorTargetNotFound},
+		{name: "content rejected", handler: jsonHandler(failed("内容违规")), want: SendErrorContentRejected},
+		{name: "unknown", handler: jsonHandler(failed("")), want: SendErrorUnknown},
+		{name: "network", handler: func(w http.ResponseWriter, r *http.Request) {
+			hj, _ := w.(http.Hijacker)
+			conn, _, _ := hj.Hijack()
+			conn.Close()
+		}, want: SendErrorNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{defaultWxAPIEndpoints.SendTextMessage: tt.handler})
+			client := NewWxAPIClient(WxAPIConfig{Retry: WxAPIRetryConfig{MaxAttempts: 1}}, zap.NewNop(), newTestAddressGuard(t, false))
+
+			_, err := client.SendText(context.Background(), server.URL, "token", &SendTextRequest{ToUserName: "g@chatroom", TextContent: "hi"})
+			if err == nil {
+				t.Fatal("SendText succeeded, want error")
+			}
+			if got := classifySendError(err); got != tt.want {
+				t.Fatalf("error type = %q, want %q (err: %v)", got, tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 消息发送优先级
+const (
+	SendPriorityHigh   = "high"   // 紧急通知，如告警
+	SendPriorityNormal = "normal" // 普通消息及群发
+)
+
+// ErrSendQueueFull 发送队列已满
+var ErrSendQueueFull = errors.New("消息发送队列已满，请稍后重试")
+
+// 发送任务状态，排队中的任务被调用方取消后worker不再执行
+const (
+	sendJobPending int32 = iota
+	sendJobRunning
+	sendJobCanceled
+)
+
+// sendJob 发送任务，执行完成后关闭done
+type sendJob struct {
+	ctx   context.Context
+	fn    func()
+	done  chan struct{}
+	state int32
+	err   error // 未执行fn时的原因，如等待限流时ctx被取消
+}
+
+// SendQueue 带优先级的消息发送队列，worker总是优先处理高优先级任务
+type SendQueue struct {
+	high    chan *sendJob
+	normal  chan *sendJob
+	limiter *rateLimiter
+	logger  *zap.Logger
+}
+
+// NewSendQueue 创建发送队列并启动worker
+func NewSendQueue(cfg SendQueueConfig, logger *zap.Logger) *SendQueue {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+
+	q := &SendQueue{
+		high:    make(chan *sendJob, queueSize),
+		normal:  make(chan *sendJob, queueSize),
+		limiter: newRateLimiter(cfg.RateLimit),
+		logger:  logger,
+	}
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+
+	logger.Info("消息发送队列已启动",
+		zap.Int("workers", workers),
+		zap.Int("queue_size", queueSize),
+		zap.Float64("rate_limit", cfg.RateLimit))
+	return q
+}
+
+// Do 按优先级排队执行fn并等待执行完成，未知优先级按普通处理
+// 排队期间ctx取消时撤回任务并返回ctx的错误；fn已开始执行时等待其结束，fn应自行响应ctx取消
+func (q *SendQueue) Do(ctx context.Context, priority string, fn func()) error {
+	job := &sendJob{ctx: ctx, fn: fn, done: make(chan struct{})}
+
+	queue := q.normal
+	if priority == SendPriorityHigh {
+		queue = q.high
+	}
+
+	select {
+	case queue <- job:
+	default:
+		q.logger.Warn("消息发送队列已满", zap.String("priority", priority))
+		return ErrSendQueueFull
+	}
+
+	select {
+	case <-job.done:
+		return job.err
+	case <-ctx.Done():
+		if atomic.CompareAndSwapInt32(&job.state, sendJobPending, sendJobCanceled) {
+			return ctx.Err()
+		}
+		<-job.done
+		return job.err
+	}
+}
+
+// worker 循环取出任务，跳过已取消的任务，限流后执行
+func (q *SendQueue) worker() {
+	for {
+		job := q.next()
+		if !atomic.CompareAndSwapInt32(&job.state, sendJobPending, sendJobRunning) {
+			continue
+		}
+		if err := q.limiter.Wait(job.ctx); err != nil {
+			job.err = err
+			close(job.done)
+			continue
+		}
+		q.run(job)
+	}
+}
+
+// next 取出下一个任务，高优先级队列非空时优先取高优先级
+func (q *SendQueue) next() *sendJob {
+	select {
+	case job := <-q.high:
+		return job
+	default:
+	}
+
+	select {
+	case job := <-q.high:
+		return job
+	case job := <-q.normal:
+		return job
+	}
+}
+
+// run 执行任务，fn panic时同样释放等待方
+func (q *SendQueue) run(job *sendJob) {
+	defer close(job.done)
+	defer func() {
+		if r := recover(); r != nil {
+			q.logger.Error("消息发送任务异常", zap.Any("panic", r))
+		}
+	}()
+	job.fn()
+}
+
+// rateLimiter 简单的匀速限流器，rate为每秒允许的次数，<=0表示不限流
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	limiter := &rateLimiter{}
+	if rate > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / rate)
+	}
+	return limiter
+}
+
+// Wait 阻塞直到获得执行许可，ctx取消时立即返回ctx的错误
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleepContext(ctx, wait)
+}
+
+// sleepContext 等待d，ctx先取消时返回ctx的错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSendQueueDo(t *testing.T) {
+	q := NewSendQueue(SendQueueConfig{Workers: 1, QueueSize: 10}, zap.NewNop())
+
+	var ran int32
+	if err := q.Do(context.Background(), SendPriorityNormal, func() { atomic.AddInt32(&ran, 1) }); err != nil {
+		t.Fatalf("Do = %v, want nil", err)
+	}
+	if err := q.Do(context.Background(), SendPriorityHigh, func() { panic("boom") }); err != nil {
+		t.Fatalf("Do with panicking fn = %v, want nil", err)
+	}
+	if got := atomic.LoadInt32(&ran); got != 1 {
+		t.Fatalf("fn ran %d times, want 1", got)
+	}
+}
+
+func TestSendQueueDoHonorsContext(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup 返回使任务无法立即执行的队列及释放函数
+		setup func(t *testing.T) (*SendQueue, func())
+	}{
+		{
+			name: "canceled while queued",
+			setup: func(t *testing.T) (*SendQueue, func()) {
+				q := NewSendQueue(SendQueueConfig{Workers: 1, QueueSize: 10}, zap.NewNop())
+				release := make(chan struct{})
+				started := make(chan struct{})
+				go q.Do(context.Background(), SendPriorityNormal, func() {
+					close(started)
+					<-release
+				})
+				<-started
+				return q, func() { close(release) }
+			},
+		},
+		{
+			name: "canceled while rate limited",
+			setup: func(t *testing.T) (*SendQueue, func()) {
+				q := NewSendQueue(SendQueueConfig{Workers: 1, QueueSize: 10, RateLimit: 0.01}, zap.NewNop())
+				// 第一次执行消耗许可，后续任务需等待约100秒
+				if err := q.Do(context.Background(), SendPriorityNormal, func() {}); err != nil {
+					t.Fatalf("first Do = %v", err)
+				}
+				return q, func() {}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, release := tt.setup(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			var ran int32
+			start := time.Now()
+			err := q.Do(ctx, SendPriorityNormal, func() { atomic.AddInt32(&ran, 1) })
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Do = %v, want deadline exceeded", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("Do returned after %v, want prompt return on ctx done", elapsed)
+			}
+
+			release()
+			time.Sleep(20 * time.Millisecond)
+			if got := atomic.LoadInt32(&ran); got != 0 {
+				t.Fatalf("canceled job ran %d times, want 0", got)
+			}
+		})
+	}
+}
+
+func TestSendQueueDoFull(t *testing.T) {
+	q := NewSendQueue(SendQueueConfig{Workers: 1, QueueSize: 1}, zap.NewNop())
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	go q.Do(context.Background(), SendPriorityNormal, func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	// 占满容量为1的普通队列
+	go q.Do(context.Background(), SendPriorityNormal, func() {})
+	deadline := time.Now().Add(time.Second)
+	for len(q.normal) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := q.Do(context.Background(), SendPriorityNormal, func() {}); !errors.Is(err, ErrSendQueueFull) {
+		t.Fatalf("Do on full queue = %v, want ErrSendQueueFull", err)
+	}
+}
+
+func TestSendBackoffWait(t *testing.T) {
+	riskErr := newSendError(SendErrorRiskControl, "发送过于频繁")
+
+	tests := []struct {
+		name     string
+		enable   bool
+		failures int
+		timeout  time.Duration
+		wantErr  error
+	}{
+		{name: "disabled", enable: false, failures: 3, timeout: 20 * time.Millisecond},
+		{name: "no backoff yet", enable: true, failures: 0, timeout: 20 * time.Millisecond},
+		{name: "canceled while backing off", enable: true, failures: 3, timeout: 20 * time.Millisecond, wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewSendBackoff(SendBackoffConfig{
+				Enable:      tt.enable,
+				FailureRate: 0.5,
+				MinInterval: time.Minute,
+				MaxInterval: time.Hour,
+			}, zap.NewNop())
+			for i := 0; i < tt.failures; i++ {
+				b.Record("token", riskErr)
+			}
+			// 消耗当前许可，下一次需等待完整间隔
+			if err := b.Wait(context.Background(), "token"); err != nil {
+				t.Fatalf("first Wait = %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			start := time.Now()
+			err := b.Wait(ctx, "token")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Wait = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("Wait returned after %v, want prompt return", elapsed)
+			}
+		})
+	}
+}
+
+func TestSendQueuePriority(t *testing.T) {
+	q := NewSendQueue(SendQueueConfig{Workers: 1, QueueSize: 10}, zap.NewNop())
+
+	// 阻塞唯一的worker，使后续任务全部排队
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go q.Do(context.Background(), SendPriorityNormal, func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	order := make(chan string, 4)
+	// submit 提交任务并等待其进入队列，保证同优先级内的入队顺序
+	submit := func(priority, name string, queue chan *sendJob) {
+		queued := len(queue)
+		go q.Do(context.Background(), priority, func() { order <- name })
+		for len(queue) == queued {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	submit(SendPriorityNormal, "normal-1", q.normal)
+	submit(SendPriorityNormal, "normal-2", q.normal)
+	submit(SendPriorityHigh, "high", q.high)
+	submit("unknown", "normal-3", q.normal)
+	close(release)
+
+	want := []string{"high", "normal-1", "normal-2", "normal-3"}
+	for i, name := range want {
+		select {
+		case got := <-order:
+			if got != name {
+				t.Fatalf("job %d = %s, want %s", i, got, name)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("job %d not executed", i)
+		}
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		calls   int
+		minTime time.Duration
+	}{
+		{name: "unlimited", rate: 0, calls: 5},
+		{name: "limited", rate: 50, calls: 3, minTime: 40 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(tt.rate)
+			start := time.Now()
+			for i := 0; i < tt.calls; i++ {
+				if err := limiter.Wait(context.Background()); err != nil {
+					t.Fatalf("Wait: %v", err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed < tt.minTime || elapsed > tt.minTime+time.Second {
+				t.Fatalf("%d calls took %v, want about %v", tt.calls, elapsed, tt.minTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 账号发送速率超限时的处理方式
+const (
+	SendRateLimitModeWait = "wait" // 等待令牌，预计等待超过max_wait或请求取消时失败
+	SendRateLimitModeFail = "fail" // 立即失败
+)
+
+// ErrRateLimited 账号发送速率超过限制，调用方可通过errors.Is识别
+var ErrRateLimited = errors.New("账号发送过于频繁，已被限流")
+
+// sendRateLimiterSweepInterval 清理空闲令牌桶的间隔
+const sendRateLimiterSweepInterval = 10 * time.Minute
+
+// SendRateLimiter 按发送账号（token）的令牌桶限流，所有发送接口和群发共用，避免单个账号发送过快触发风控
+type SendRateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // 每秒补充的令牌数，<=0表示不限流
+	burst     float64 // 令牌桶容量，允许的突发条数
+	failFast  bool
+	maxWait   time.Duration
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	logger    *zap.Logger
+}
+
+// tokenBucket 单个账号的令牌桶，等待中的请求会预占令牌，tokens可能为负
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewSendRateLimiter 创建按账号的发送限流
+func NewSendRateLimiter(cfg SendRateLimitConfig, logger *zap.Logger) *SendRateLimiter {
+	burst := float64(cfg.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	if cfg.Rate > 0 {
+		logger.Info("账号发送限流已开启",
+			zap.Float64("rate", cfg.Rate),
+			zap.Float64("burst", burst),
+			zap.String("mode", cfg.Mode),
+			zap.Duration("max_wait", cfg.MaxWait))
+	}
+
+	return &SendRateLimiter{
+		rate:      cfg.Rate,
+		burst:     burst,
+		failFast:  cfg.Mode == SendRateLimitModeFail,
+		maxWait:   cfg.MaxWait,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+		logger:    logger,
+	}
+}
+
+// Wait 获取账号的一个发送令牌
+func (l *SendRateLimiter) Wait(ctx context.Context, authKey string) error {
+	return l.WaitN(ctx, authKey, 1)
+}
+
+// WaitN 获取账号的n个发送令牌（批量发送按条数计）：令牌足够时立即返回；
+// 不足时快速失败模式返回ErrRateLimited，等待模式预占令牌后等待补充，预计等待超过max_wait时返回ErrRateLimited
+func (l *SendRateLimiter) WaitN(ctx context.Context, authKey string, n int) error {
+	if l.rate <= 0 || n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.sweep(now)
+	bucket := l.refill(authKey, now)
+	need := float64(n)
+	if bucket.tokens >= need {
+		bucket.tokens -= need
+		l.mu.Unlock()
+		return nil
+	}
+
+	wait := time.Duration((need - bucket.tokens) / l.rate * float64(time.Second))
+	if l.failFast || (l.maxWait > 0 && wait > l.maxWait) {
+		l.mu.Unlock()
+		l.logger.Debug("账号发送速率超限，拒绝发送",
+			zap.String("token", maskToken(authKey)),
+			zap.Int("count", n),
+			zap.Duration("wait", wait))
+		return ErrRateLimited
+	}
+	bucket.tokens -= need
+	l.mu.Unlock()
+
+	l.logger.Debug("账号发送速率超限，等待令牌",
+		zap.String("token", maskToken(authKey)),
+		zap.Int("count", n),
+		zap.Duration("wait", wait))
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// 未发送，归还预占的令牌
+		l.mu.Lock()
+		l.refill(authKey, time.Now()).tokens += need
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// refill 按经过的时间补充令牌并返回账号的令牌桶，新账号的令牌桶是满的，调用方需持有锁
+func (l *SendRateLimiter) refill(authKey string, now time.Time) *tokenBucket {
+	bucket, ok := l.buckets[authKey]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[authKey] = bucket
+		return bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.rate
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.last = now
+	return bucket
+}
+
+// sweep 定期清理已补满的令牌桶，避免已下线账号的记录一直保留，调用方需持有锁
+func (l *SendRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sendRateLimiterSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for authKey, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, authKey)
+		}
+	}
+}
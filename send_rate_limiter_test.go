@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSendRateLimiterWaitN(t *testing.T) {
+	type call struct {
+		token    string
+		n        int
+		timeout  time.Duration // >0时带超时的ctx
+		wantErr  error
+		wantWait time.Duration // 预计至少等待的时长
+	}
+
+	tests := []struct {
+		name  string
+		cfg   SendRateLimitConfig
+		calls []call
+	}{
+		{
+			name:  "disabled",
+			cfg:   SendRateLimitConfig{Burst: 1, Mode: SendRateLimitModeFail},
+			calls: []call{{token: "a", n: 5}, {token: "a", n: 5}},
+		},
+		{
+			name:  "within burst",
+			cfg:   SendRateLimitConfig{Rate: 1, Burst: 3, Mode: SendRateLimitModeFail},
+			calls: []call{{token: "a", n: 2}, {token: "a", n: 1}},
+		},
+		{
+			name: "fail fast",
+			cfg:  SendRateLimitConfig{Rate: 1, Burst: 2, Mode: SendRateLimitModeFail},
+			calls: []call{
+				{token: "a", n: 2},
+				{token: "a", n: 1, wantErr: ErrRateLimited},
+				{token: "b", n: 2},
+			},
+		},
+		{
+			name: "wait for token",
+			cfg:  SendRateLimitConfig{Rate: 20, Burst: 1, Mode: SendRateLimitModeWait},
+			calls: []call{
+				{token: "a", n: 1},
+				{token: "a", n: 1, wantWait: 40 * time.Millisecond},
+			},
+		},
+		{
+			name: "max wait exceeded",
+			cfg:  SendRateLimitConfig{Rate: 1, Burst: 1, Mode: SendRateLimitModeWait, MaxWait: 100 * time.Millisecond},
+			calls: []call{
+				{token: "a", n: 1},
+				{token: "a", n: 1, wantErr: ErrRateLimited},
+			},
+		},
+		{
+			name: "cancelled wait returns tokens",
+			cfg:  SendRateLimitConfig{Rate: 10, Burst: 1, Mode: SendRateLimitModeWait, MaxWait: 250 * time.Millisecond},
+			calls: []call{
+				{token: "a", n: 1},
+				{token: "a", n: 2, timeout: 20 * time.Millisecond, wantErr: context.DeadlineExceeded},
+				// 归还后只需等待约一个令牌，而不是三个
+				{token: "a", n: 1, wantWait: 50 * time.Millisecond},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewSendRateLimiter(tt.cfg, zap.NewNop())
+			for i, c := range tt.calls {
+				ctx := context.Background()
+				if c.timeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, c.timeout)
+					defer cancel()
+				}
+
+				start := time.Now()
+				err := limiter.WaitN(ctx, c.token, c.n)
+				if !errors.Is(err, c.wantErr) {
+					t.Fatalf("call %d err = %v, want %v", i, err, c.wantErr)
+				}
+				if elapsed := time.Since(start); elapsed < c.wantWait {
+					t.Fatalf("call %d waited %v, want at least %v", i, elapsed, c.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func TestSendTextAccountRateLimit(t *testing.T) {
+	var calls int32
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			jsonHandler(sendSuccessResponse(1))(w, r)
+		},
+	})
+	cfg := &Config{}
+	cfg.SendQueue.AccountLimit = SendRateLimitConfig{Rate: 0.01, Burst: 1, Mode: SendRateLimitModeFail}
+	router, _, db := newTestRouter(t, cfg)
+	bot := &WxUserLogin{WxID: "wxid_limit", Token: "token-limit", IsMessageBot: 1}
+	createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+	db.Create(&WxGroup{WxID: bot.WxID, GroupID: "g1@chatroom"})
+
+	steps := []struct {
+		name       string
+		wantStatus int
+		wantType   SendErrorType
+	}{
+		{name: "first send", wantStatus: http.StatusOK},
+		{name: "limited", wantStatus: http.StatusInternalServerError, wantType: SendErrorRiskControl},
+	}
+
+	for _, s := range steps {
+		t.Run(s.name, func(t *testing.T) {
+			w := doRequest(router, http.MethodPost, "/messages/group/send-text", `{"text_content":"通知","to_user_name":"g1@chatroom"}`)
+			if w.Code != s.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, s.wantStatus, w.Body.String())
+			}
+			if s.wantType == "" {
+				return
+			}
+			var resp struct {
+				Data SendErrorInfo `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Data.ErrorType != s.wantType {
+				t.Fatalf("error type = %q, want %q", resp.Data.ErrorType, s.wantType)
+			}
+		})
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("robot send calls = %d, want 1", got)
+	}
+}
+
+func TestSendTextMultiRateLimitNotRecorded(t *testing.T) {
+	var calls int32
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			jsonHandler(sendSuccessResponse(1))(w, r)
+		},
+	})
+	cfg := &Config{}
+	cfg.SendQueue.AccountLimit = SendRateLimitConfig{Rate: 0.01, Burst: 1, Mode: SendRateLimitModeFail}
+	cfg.SendQueue.Backoff = SendBackoffConfig{Enable: true, Window: 5, FailureRate: 0.5, MinInterval: time.Second, MaxInterval: 8 * time.Second}
+	router, svc, db := newTestRouter(t, cfg)
+	bot := &WxUserLogin{WxID: "wxid_limit", Token: "token-limit", IsMessageBot: 1}
+	createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+	for _, group := range []string{"g1@chatroom", "g2@chatroom"} {
+		db.Create(&WxGroup{WxID: bot.WxID, GroupID: group})
+	}
+
+	// 两个群合并为一批，需要两个令牌，超过突发容量被本地拒绝
+	w := doRequest(router, http.MethodPost, "/messages/group/send-text-multi", `{"text_content":"通知","to_user_names":["g1@chatroom","g2@chatroom"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data []SendTextResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Data) != 2 {
+		t.Fatalf("results = %+v, want 2", resp.Data)
+	}
+	for _, r := range resp.Data {
+		if r.Success || r.ErrorType != SendErrorRiskControl {
+			t.Fatalf("result = %+v, want rate limited failure", r)
+		}
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Fatalf("robot send calls = %d, want 0", got)
+	}
+	if state, ok := svc.sendBackoff.accounts[bot.Token]; ok {
+		t.Fatalf("backoff interval = %v, want unchanged", state.interval)
+	}
+	var audits int64
+	db.Model(&WxSendAudit{}).Count(&audits)
+	if audits != 0 {
+		t.Fatalf("send audits = %d, want 0", audits)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "unicode/utf8"
+
+// SendRetryRunSummary 一次重试扫描的处理结果
+type SendRetryRunSummary struct {
+	Total   int // 到期的重试消息数
+	Success int // 重试发送成功
+	Pending int // 仍失败，已安排下次重试
+	Failed  int // 最终失败
+}
+
+// sendErrorMaxLength 失败原因的最大保存长度（字符），与表字段长度一致
+const sendErrorMaxLength = 500
+
+// truncateSendError 截断失败原因，避免超过表字段长度
+func truncateSendError(err error) string {
+	msg := err.Error()
+	if utf8.RuneCountInString(msg) > sendErrorMaxLength {
+		msg = string([]rune(msg)[:sendErrorMaxLength])
+	}
+	return msg
+}
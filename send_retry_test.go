@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnqueueSendRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		enable  bool
+		err     error
+		wantNew bool
+	}{
+		{name: "network failure", enable: true, err: newSendError(SendErrorNetwork, "连接超时"), wantNew: true},
+		{name: "target not found", enable: true, err: newSendError(SendErrorTargetNotFound, "群聊已解散")},
+		{name: "plain error", enable: true, err: errors.New("boom")},
+		{name: "disabled", err: newSendError(SendErrorNetwork, "连接超时")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			cfg.SendQueue.Retry = SendRetryConfig{Enable: tt.enable, InitialBackoff: time.Minute}
+			svc, db := newTestService(t, cfg)
+
+			id, err := svc.EnqueueSendRetry(1, SendAuditTypeText, "g1@chatroom", &SendTextRequest{ToUserName: "g1@chatroom", TextContent: "hi"}, tt.err)
+			if err != nil {
+				t.Fatalf("EnqueueSendRetry: %v", err)
+			}
+			if (id > 0) != tt.wantNew {
+				t.Fatalf("retry id = %d, want enqueued %v", id, tt.wantNew)
+			}
+			if !tt.wantNew {
+				return
+			}
+
+			var retry WxSendRetry
+			if err := db.First(&retry, id).Error; err != nil {
+				t.Fatalf("load retry: %v", err)
+			}
+			if retry.Status != SendRetryStatusPending || retry.OwnerID != 1 || retry.Attempts != 0 ||
+				!strings.Contains(retry.Payload, `"hi"`) || !retry.NextRetryTime.After(time.Now()) {
+				t.Fatalf("retry = %+v", retry)
+			}
+		})
+	}
+}
+
+func TestProcessSendRetries(t *testing.T) {
+	const (
+		ok       = "ok"
+		network  = "network"  // 连接被断开，可重试
+		notFound = "notfound" // 群已解散，不可重试
+	)
+	type step struct {
+		result       string
+		wantStatus   string
+		wantAttempts int
+		wantSummary  SendRetryRunSummary
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "succeeds on first retry",
+			steps: []step{
+				{result: ok, wantStatus: SendRetryStatusSuccess, wantAttempts: 1, wantSummary: SendRetryRunSummary{Total: 1, Success: 1}},
+				{result: ok, wantStatus: SendRetryStatusSuccess, wantAttempts: 1},
+			},
+		},
+		{
+			name: "succeeds after transient failure",
+			steps: []step{
+				{result: network, wantStatus: SendRetryStatusPending, wantAttempts: 1, wantSummary: SendRetryRunSummary{Total: 1, Pending: 1}},
+				{result: ok, wantStatus: SendRetryStatusSuccess, wantAttempts: 2, wantSummary: SendRetryRunSummary{Total: 1, Success: 1}},
+			},
+		},
+		{
+			name: "fails after max attempts",
+			steps: []step{
+				{result: network, wantStatus: SendRetryStatusPending, wantAttempts: 1, wantSummary: SendRetryRunSummary{Total: 1, Pending: 1}},
+				{result: network, wantStatus: SendRetryStatusFailed, wantAttempts: 2, wantSummary: SendRetryRunSummary{Total: 1, Failed: 1}},
+				{result: ok, wantStatus: SendRetryStatusFailed, wantAttempts: 2},
+			},
+		},
+		{
+			name: "non retryable failure",
+			steps: []step{
+				{result: notFound, wantStatus: SendRetryStatusFailed, wantAttempts: 1, wantSummary: SendRetryRunSummary{Total: 1, Failed: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result string
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+					switch result {
+					case network:
+						hj, _ := w.(http.Hijacker)
+						conn, _, _ := hj.Hijack()
+						conn.Close()
+					case notFound:
+						jsonHandler(map[string]interface{}{
+							"Code": 200,
+							"Data": []interface{}{map[string]interface{}{
+								"isSendSuccess": true,
+								"resp": map[string]interface{}{
+									"base_response": map[string]interface{}{"ret": -1, "errMsg": map[string]interface{}{"str": "群聊已解散"}},
+								},
+							}},
+						})(w, r)
+					default:
+						jsonHandler(sendSuccessResponse(1))(w, r)
+					}
+				},
+			})
+			cfg := &Config{}
+			cfg.WxAPI.Retry.MaxAttempts = 1
+			// 退避为0，失败后立即到期，便于逐步驱动
+			cfg.SendQueue.Retry = SendRetryConfig{Enable: true, BatchSize: 10, MaxAttempts: 2}
+			svc, db := newTestService(t, cfg)
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1},
+				&WxUserLogin{WxID: "wxid_retry", Token: "token-retry", IsMessageBot: 1})
+			db.Create(&WxGroup{WxID: "wxid_retry", GroupID: "g1@chatroom"})
+
+			id, err := svc.EnqueueSendRetry(1, SendAuditTypeText, "g1@chatroom",
+				&SendTextRequest{ToUserName: "g1@chatroom", TextContent: "hi"}, newSendError(SendErrorNetwork, "连接超时"))
+			if err != nil || id == 0 {
+				t.Fatalf("EnqueueSendRetry = %d, %v", id, err)
+			}
+
+			for i, s := range tt.steps {
+				result = s.result
+				summary, err := svc.ProcessSendRetries(context.Background(), NewRandomMessageSendStrategy())
+				if err != nil {
+					t.Fatalf("step %d ProcessSendRetries: %v", i, err)
+				}
+				if *summary != s.wantSummary {
+					t.Fatalf("step %d summary = %+v, want %+v", i, *summary, s.wantSummary)
+				}
+
+				var retry WxSendRetry
+				if err := db.First(&retry, id).Error; err != nil {
+					t.Fatalf("step %d load retry: %v", i, err)
+				}
+				if retry.Status != s.wantStatus || retry.Attempts != s.wantAttempts {
+					t.Fatalf("step %d retry status = %s attempts = %d, want %s %d", i, retry.Status, retry.Attempts, s.wantStatus, s.wantAttempts)
+				}
+				if (retry.LastError == "") != (retry.Status == SendRetryStatusSuccess) {
+					t.Fatalf("step %d last error = %q with status %s", i, retry.LastError, retry.Status)
+				}
+			}
+		})
+	}
+}
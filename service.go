@@ -1,154 +1,1738 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // 微信机器人服务接口
 type WxRobotService interface {
 	// 外部API调用
-	GenAuthKey(robotAddress, adminKey string, count, days int) (*GenAuthKeyResponse, error)
-	GetLoginQrCode(robotAddress, authKey string, check bool, proxy string) (*GetLoginQrCodeResponse, error)
-	CheckCanSetAlias(robotAddress, authKey string) (*CheckCanSetAliasResponse, error)
-	CheckLoginStatus(robotAddress, authKey string) (*CheckLoginStatusResponse, error)
-	GetLoginStatus(robotAddress, authKey string) (*GetLoginStatusResponse, error)
-	GetInitStatus(robotAddress, authKey string) (*GetInitStatusResponse, error)
-	DelayAuthKey(robotAddress, adminKey, authKey string, days int) (*DelayAuthKeyResponse, error)
-	GetChatRoomInfo(robotAddress, authKey string, chatRoomIds []string) (*GetChatRoomInfoResponse, error)
-	GetGroupList(robotAddress, authKey string) (*GroupListResponse, error)
+	GenAuthKey(ctx context.Context, robot *WxRobotConfig, count, days int) (*GenAuthKeyResponse, error)
+	ListAuthKeys(robotID uint, status string) (*AuthKeyListResponse, error)
+	GetLoginQrCode(ctx context.Context, robotAddress, authKey string, check bool, proxy string) (*GetLoginQrCodeResponse, error)
+	CheckCanSetAlias(ctx context.Context, robotAddress, authKey string) (*CheckCanSetAliasResponse, error)
+	CheckLoginStatus(ctx context.Context, robotAddress, authKey string) (*CheckLoginStatusResponse, error)
+	GetLoginStatus(ctx context.Context, robotAddress, authKey string) (*GetLoginStatusResponse, error)
+	GetInitStatus(ctx context.Context, robotAddress, authKey string) (*GetInitStatusResponse, error)
+	DelayAuthKey(ctx context.Context, robotAddress, adminKey, authKey string, days int) (*DelayAuthKeyResponse, error)
+	GetChatRoomInfo(ctx context.Context, robotAddress, authKey string, chatRoomIds []string) (*GetChatRoomInfoResponse, error)
+	GetGroupList(ctx context.Context, robotAddress, authKey string) (*GroupListResponse, error)
 
 	// 消息发送接口
-	SendText(robotAddress, authKey string, req *SendTextRequest) (*SendTextResponse, error)
-	SendImage(robotAddress, authKey string, req *SendImageRequest) (*SendImageResponse, error)
-	SendTextAndImage(robotAddress, authKey string, req *SendTextAndImageRequest) (*SendTextAndImageResponse, error)
+	SendText(ctx context.Context, robotAddress, authKey string, req *SendTextRequest) (*SendTextResponse, error)
+	SendTextToGroups(ctx context.Context, req *SendTextMultiRequest, strategy MessageSendStrategy) []SendTextResult
+	CheckLargeGroup(groupID string, confirmed bool) error
+	CheckGroupBlacklist(groupID string) error
+	CheckContentURLs(text string) error
+	CheckDuplicateSend(groupID, content string) error
+	ReleaseDuplicateSend(groupID, content string)
+	EnqueueSendRetry(ownerID uint, msgType, toUserName string, payload interface{}, sendErr error) (uint, error)
+	ProcessSendRetries(ctx context.Context, strategy MessageSendStrategy) (*SendRetryRunSummary, error)
+	SendImage(ctx context.Context, robotAddress, authKey string, req *SendImageRequest) (*SendImageResponse, error)
+	SendVoice(ctx context.Context, robotAddress, authKey string, req *SendVoiceRequest) (*SendVoiceResponse, error)
+	SendVideo(ctx context.Context, robotAddress, authKey string, req *SendVideoRequest) (*SendVideoResponse, error)
+	SendAppMsg(ctx context.Context, robotAddress, authKey string, req *SendAppMsgRequest) (*SendAppMsgResponse, error)
+	SendTextAndImage(ctx context.Context, robotAddress, authKey string, req *SendTextAndImageRequest) (*SendTextAndImageResponse, error)
+	BroadcastMessage(ctx context.Context, req *BroadcastRequest, traceID string, strategy MessageSendStrategy, onResult func(BroadcastGroupResult)) []BroadcastGroupResult
+	GetSendRecordsByTrace(traceID string, ownerID uint) (*SendTraceResponse, error)
+	ListSendAudits(req SendAuditQueryRequest) (*SendAuditPaginatedResponse, error)
+	GetRobotTagSendStats(req RobotTagStatsRequest) ([]RobotTagSendStats, error)
+	ExportSendAudits(filter SendAuditFilter, fn func(audit *WxSendAudit) error) error
+	FilterBroadcastGroups(toUserNames []string, ownerID uint) ([]string, []BroadcastSkippedGroup)
+	BroadcastToGroups(ctx context.Context, req *BroadcastRequest, traceID string, strategy MessageSendStrategy) *GroupBroadcastResponse
 
 	// 数据库操作
-	GetRobotList() ([]WxRobotConfig, error)
+	GetRobotList(req RobotListRequest) ([]WxRobotConfig, error)
+	BatchSetRobotStatus(req *BatchRobotStatusRequest, operator RobotChangeOperator) (*BatchRobotStatusResponse, error)
+	GetIdleRobots(ownerID uint, days int) ([]IdleRobot, error)
+	CleanupIdleRobots(req *CleanupIdleRobotsRequest, operator RobotChangeOperator) (*CleanupIdleRobotsResponse, error)
 	CreateRobot(robot *WxRobotConfig) error
-	UpdateRobot(robot *WxRobotConfig) error
+	UpdateRobot(robot *WxRobotConfig, operator RobotChangeOperator) error
+	PatchRobot(id uint, req *PatchRobotRequest, operator RobotChangeOperator) (*WxRobotConfig, error)
+	RotateAdminKey(ctx context.Context, id uint, newAdminKey string, operator RobotChangeOperator) (*RotateAdminKeyResponse, error)
+	GetRobotConfigChanges(robotID uint) ([]WxRobotConfigChange, error)
 	GetUsersByRobot(robotId string) ([]WxUserLogin, error)
 	GetRobotByID(id uint) (*WxRobotConfig, error)
+	GetRobotSummary(id uint) (*RobotSummaryResponse, error)
 	GetUserByID(id uint) (*WxUserLogin, error)
+	ListNeedReloginUsers(req NeedReloginRequest) (*NeedReloginPaginatedResponse, error)
+	GetUserAuthInfo(ctx context.Context, id uint) (*UserAuthInfoResponse, error)
+	GetUserByToken(token string) (*UserByTokenResponse, error)
 	SaveUser(user *WxUserLogin) error
 	DeleteUser(id string) error
-	UpdateUserExtension(robotId uint, token string, newExpiry time.Time) error
+	UpdateUserExtension(robotId uint, token string, days int, newExpiry time.Time, source string) error
+	BatchExtendAuth(ctx context.Context, req *BatchExtendAuthRequest) (*BatchExtendAuthResponse, error)
+	GetExpiringUsers(withinDays int, autoRenew int) ([]WxUserLogin, error)
+	RenewExpiringUsers(ctx context.Context, withinDays, days int) (*BatchExtendAuthResponse, error)
+	NotifyAuthExpiring(user WxUserLogin)
 	GetInitializedUsers() ([]WxUserLogin, error)
 	GetUninitializedUsers() ([]WxUserLogin, error)
 	GetActiveUsers() ([]WxUserLogin, error)
 	UpdateUserInitializationStatus(userID uint) error
-	UpdateUserStatus(userID uint, status int) error
+	UpdateUserStatus(userID uint, status int, reason string) error
+	HandleInitTimeout(user WxUserLogin, elapsed time.Duration, relogin bool) error
+	GetUserStatusHistory(userID uint) ([]WxUserStatusLog, error)
+	UpdateUserRemark(userID uint, remark string) error
+	UpdateUserAutoRenew(userID uint, autoRenew int) error
+	UpdateUserSignature(userID uint, signature, position string) error
+	SearchUsers(req UserSearchRequest) (*UserSearchPaginatedResponse, error)
 	UpdateMessageBotStatus(userID uint, isMessageBot int) error
 	SaveOrUpdateGroup(group *WxGroup) error
 	DeleteGroupsByWxIDNotInList(wxID string, groupIDs []string) error
+	SaveGroupMembers(groupID string, members []WxGroupMember) error
+	GetGroupMembers(groupID string) ([]WxGroupMember, error)
+	GetGroupChanges(wxID string, ownerID uint, start, end time.Time) (*GroupChangesResponse, error)
+	GetGroupMessageBots(groupID string, ownerID uint) ([]GroupBotCandidate, error)
+	GetAvailableMessageBots(ownerID uint) (*AvailableMessageBotsResponse, error)
+	GetBotCoverageGaps(req BotCoverageRequest) (*BotCoverageResponse, error)
 	GetGroupsByWxID(wxID string) ([]WxGroup, error)
-	SearchGroupsByName(groupNickName string) ([]WxGroup, error)
+	SearchGroupsByName(groupNickName string, ownerID uint) ([]WxGroup, error)
+	ListGroups(req GroupListRequest) (*GroupListPaginatedResponse, error)
+	ExportGroups(req GroupExportRequest, fn func(group *WxGroup) error) error
+	ListGroupBlacklist(ownerID uint) ([]WxGroupBlacklist, error)
+	AddGroupBlacklist(req *AddGroupBlacklistRequest) (*WxGroupBlacklist, error)
+	RemoveGroupBlacklist(groupID string) error
+	OwnerHasWxID(ownerID uint, wxID string) (bool, error)
+	OwnerHasGroup(ownerID uint, groupID string) (bool, error)
 	GetMessageBotByStrategy(groupId string, strategy MessageSendStrategy) (*MessageBotInfo, error)
 	CheckDatabaseHealth() error
-	CheckRobotHealth(robotAddress string) (bool, error)
+	GetMigrationStatus() (*MigrationStatus, error)
+	CheckRobotHealth(ctx context.Context, robotAddress, healthPath string) (bool, error)
+	CheckRobotsHealth(ctx context.Context, robotIDs []uint, ownerID uint) (*RobotHealthSummary, error)
+	NotifyRobotHealth(result RobotHealthResult, failures int, recovered bool)
+	ValidateRobotAddress(robotAddress string) error
 
 	// 账单处理相关
 	GetMaxMsgTimeFromMessages() (int64, error)
 	GetGroupByGroupID(groupID string) (*WxGroup, error)
 	CreateBill(bill *WxBillInfo) error
+	HandleGroupMessage(req *MessageCallbackRequest) (*WxGroupMessage, error)
+	SearchGroupMessages(req GroupMessageSearchRequest) (*GroupMessageSearchResponse, error)
 	
 	// 账单统计相关
 	GetBillStatistics(req BillStatsRequest) (*BillStatsPaginatedResponse, error)
 	GetBillList(req BillQueryRequest) (*BillQueryPaginatedResponse, error)
+	GetRecentBillsByGroup(groupID string, ownerID uint, limit int) ([]BillInfoResponse, error)
 }
 
 // 微信机器人服务实现
 type wxRobotService struct {
-	apiClient *WxAPIClient
-	db        *gorm.DB
-	logger    *zap.Logger
+	apiClient    *WxAPIClient
+	db           *gorm.DB
+	logger       *zap.Logger
+	billParser   BillParser
+	addressGuard *AddressGuard
+	urlGuard     *URLGuard
+	initStatus   *initStatusCache
+	sendQueue    *SendQueue
+	sendBackoff  *SendBackoff
+	sendLimiter  *SendRateLimiter // 按账号的发送限流
+
+	sendDedup       *windowLimiter // 相同群相同内容的去重窗口
+	sendDedupAction string         // 去重命中时的处理：reject/warn
+
+	largeGroupThreshold int // 超大群成员数阈值，0表示不校验
+
+	notifier CallbackNotifier
+	eventURL string // 系统事件通知地址
+
+	msgRouter *MessageRouter
+
+	autoReplier       *AutoReplier        // 关键词自动回复，未开启时为nil
+	autoReplyStrategy MessageSendStrategy // 自动回复选择消息机器人的策略
+
+	textSplit TextSplitConfig // 长文本分段发送配置
+
+	sendRetry SendRetryConfig // 失败消息重试队列配置
+
+	auditStoreContent bool // 发送审计是否保存完整文本
+
+	msgSearchFulltext bool // 群消息搜索是否使用全文索引
+}
+
+// NewWxRobotService 创建微信机器人服务
+func NewWxRobotService(cfg *Config, db *gorm.DB, logger *zap.Logger) (WxRobotService, error) {
+	addressGuard, err := NewAddressGuard(cfg.Security)
+	if err != nil {
+		return nil, fmt.Errorf("初始化地址防护失败: %w", err)
+	}
+
+	svc := &wxRobotService{
+		apiClient:    NewWxAPIClient(cfg.WxAPI, logger, addressGuard),
+		db:           db,
+		logger:       logger,
+		addressGuard: addressGuard,
+		urlGuard:     NewURLGuard(cfg.Security.URLCheck, logger),
+		initStatus:   newInitStatusCache(cfg.WxAPI.InitStatusCacheTTL),
+		sendQueue:    NewSendQueue(cfg.SendQueue, logger),
+		sendBackoff:  NewSendBackoff(cfg.SendQueue.Backoff, logger),
+		sendLimiter:  NewSendRateLimiter(cfg.SendQueue.AccountLimit, logger),
+
+		sendDedup:       newWindowLimiter(1, cfg.SendQueue.Dedup.Window),
+		sendDedupAction: cfg.SendQueue.Dedup.Action,
+
+		largeGroupThreshold: cfg.Security.LargeGroupThreshold,
+
+		// 事件通知地址由运维配置，可能是内网告警服务，不做地址限制
+		notifier: NewCallbackNotifier(cfg.Callback, nil, logger),
+		eventURL: cfg.Callback.EventURL,
+
+		msgRouter: NewMessageRouter(cfg.MsgCallback, logger),
+
+		textSplit: cfg.TextSplit,
+
+		sendRetry: cfg.SendQueue.Retry,
+
+		auditStoreContent: cfg.Audit.StoreContent,
+
+		msgSearchFulltext: cfg.MsgSearch.Fulltext,
+	}
+
+	if cfg.BillParser.Enable {
+		svc.billParser = NewRegexBillParser(cfg.BillParser.Rules, logger)
+	}
+	svc.msgRouter.Register(MsgTypeText, svc.processBillMessage)
+	if cfg.AutoReply.Enable {
+		svc.autoReplier = NewAutoReplier(cfg.AutoReply, logger)
+		svc.autoReplyStrategy = NewRandomMessageSendStrategy()
+		svc.msgRouter.Register(MsgTypeText, svc.processAutoReply)
+	}
+
+	return svc, nil
+}
+
+// 生成授权码，生成的所有授权码都记录下来便于复用未分配的码，记录失败不影响返回
+func (s *wxRobotService) GenAuthKey(ctx context.Context, robot *WxRobotConfig, count, days int) (*GenAuthKeyResponse, error) {
+	return s.genAuthKey(ctx, robot, robot.AdminKey, count, days, AuthKeySourceManual)
+}
+
+// genAuthKey 使用指定管理密钥生成授权码并按来源记录
+func (s *wxRobotService) genAuthKey(ctx context.Context, robot *WxRobotConfig, adminKey string, count, days int, source string) (*GenAuthKeyResponse, error) {
+	resp, err := s.apiClient.GenAuthKey(withRobotTimeout(ctx, robot), robot.Address, adminKey, count, days)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Data) > 0 {
+		expireTime := time.Now().AddDate(0, 0, days)
+		keys := make([]WxAuthKey, 0, len(resp.Data))
+		for _, key := range resp.Data {
+			keys = append(keys, WxAuthKey{RobotID: robot.ID, AuthKey: key, Days: days, ExpireTime: expireTime, Source: source})
+		}
+		if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&keys).Error; err != nil {
+			s.logger.Error("记录授权码失败", zap.Uint("robot_id", robot.ID), zap.Int("count", len(keys)), zap.Error(err))
+		}
+	}
+	return resp, nil
+}
+
+// ListAuthKeys 查询机器人已生成的授权码及分配状态，有账号使用该token即视为已分配
+func (s *wxRobotService) ListAuthKeys(robotID uint, status string) (*AuthKeyListResponse, error) {
+	var rows []struct {
+		WxAuthKey
+		UserID   uint
+		WxID     string
+		NickName string
+	}
+	if err := s.db.Table("wx_auth_keys k").
+		Select("k.*, u.id AS user_id, u.wx_id, u.nick_name").
+		Joins("LEFT JOIN wx_user_logins u ON u.token = k.auth_key AND u.robot_id = k.robot_id").
+		Where("k.robot_id = ?", robotID).
+		Order("k.id DESC").
+		Scan(&rows).Error; err != nil {
+		s.logger.Error("查询授权码失败", zap.Uint("robot_id", robotID), zap.Error(err))
+		return nil, err
+	}
+
+	resp := &AuthKeyListResponse{RobotID: robotID, Keys: make([]AuthKeyInfo, 0, len(rows))}
+	for _, row := range rows {
+		info := AuthKeyInfo{
+			ID:         row.ID,
+			AuthKey:    row.AuthKey,
+			Days:       row.Days,
+			ExpireTime: FormatTime(row.ExpireTime),
+			CreateTime: FormatTime(row.CreateTime),
+			Status:     AuthKeyStatusUnassigned,
+			Source:     row.Source,
+		}
+		if row.UserID > 0 {
+			info.Status = AuthKeyStatusAssigned
+			info.UserID = row.UserID
+			info.WxID = row.WxID
+			info.NickName = row.NickName
+			resp.Assigned++
+		} else {
+			resp.Unassigned++
+		}
+		if status == "" || status == info.Status {
+			resp.Keys = append(resp.Keys, info)
+		}
+	}
+	resp.Total = len(rows)
+	return resp, nil
+}
+
+// 获取登录二维码
+func (s *wxRobotService) GetLoginQrCode(ctx context.Context, robotAddress, authKey string, check bool, proxy string) (*GetLoginQrCodeResponse, error) {
+	return s.apiClient.GetLoginQrCode(ctx, robotAddress, authKey, check, proxy)
+}
+
+// 检查是否有安全风险
+func (s *wxRobotService) CheckCanSetAlias(ctx context.Context, robotAddress, authKey string) (*CheckCanSetAliasResponse, error) {
+	resp, err := s.apiClient.CheckCanSetAlias(ctx, robotAddress, authKey)
+	s.handleTokenExpired(authKey, err)
+	return resp, err
+}
+
+// 检查登录状态
+func (s *wxRobotService) CheckLoginStatus(ctx context.Context, robotAddress, authKey string) (*CheckLoginStatusResponse, error) {
+	return s.apiClient.CheckLoginStatus(ctx, robotAddress, authKey)
+}
+
+// 获取登录状态
+func (s *wxRobotService) GetLoginStatus(ctx context.Context, robotAddress, authKey string) (*GetLoginStatusResponse, error) {
+	resp, err := s.apiClient.GetLoginStatus(ctx, robotAddress, authKey)
+	s.handleTokenExpired(authKey, err)
+	return resp, err
+}
+
+// 检查初始化状态
+func (s *wxRobotService) GetInitStatus(ctx context.Context, robotAddress, authKey string) (*GetInitStatusResponse, error) {
+	if resp, ok := s.initStatus.Get(authKey); ok {
+		s.logger.Debug("GetInitStatus命中缓存，跳过外部调用", zap.String("token", maskToken(authKey)))
+		return resp, nil
+	}
+
+	resp, err := s.apiClient.GetInitStatus(ctx, robotAddress, authKey)
+	if err != nil {
+		s.handleTokenExpired(authKey, err)
+		return nil, err
+	}
+	s.initStatus.Set(authKey, resp)
+	return resp, nil
+}
+
+// 授权码延期
+func (s *wxRobotService) DelayAuthKey(ctx context.Context, robotAddress, adminKey, authKey string, days int) (*DelayAuthKeyResponse, error) {
+	return s.apiClient.DelayAuthKey(ctx, robotAddress, adminKey, authKey, days)
+}
+
+// 获取群详情
+func (s *wxRobotService) GetChatRoomInfo(ctx context.Context, robotAddress, authKey string, chatRoomIds []string) (*GetChatRoomInfoResponse, error) {
+	resp, err := s.apiClient.GetChatRoomInfo(ctx, robotAddress, authKey, chatRoomIds)
+	s.handleTokenExpired(authKey, err)
+	return resp, err
+}
+
+// 获取群列表
+func (s *wxRobotService) GetGroupList(ctx context.Context, robotAddress, authKey string) (*GroupListResponse, error) {
+	resp, err := s.apiClient.GetGroupList(ctx, robotAddress, authKey)
+	s.handleTokenExpired(authKey, err)
+	return resp, err
+}
+
+// handleTokenExpired 外部接口返回token过期时，将对应用户标记为需要重新登录并发送事件通知
+func (s *wxRobotService) handleTokenExpired(authKey string, err error) {
+	if !errors.Is(err, ErrTokenExpired) {
+		return
+	}
+
+	var user WxUserLogin
+	if dbErr := s.db.Where("token = ?", authKey).First(&user).Error; dbErr != nil {
+		s.logger.Warn("token已过期，但未找到对应用户", zap.String("token", maskToken(authKey)), zap.Error(dbErr))
+		return
+	}
+
+	// 已标记过的不重复更新和通知
+	if user.Status == UserStatusRelogin {
+		return
+	}
+
+	if updateErr := s.UpdateUserStatus(user.ID, UserStatusRelogin, err.Error()); updateErr != nil {
+		s.logger.Error("token过期后更新用户状态失败", zap.Uint("user_id", user.ID), zap.Error(updateErr))
+		return
+	}
+
+	s.logger.Warn("用户token已过期，已标记为需要重新登录",
+		zap.Uint("user_id", user.ID),
+		zap.String("wx_id", user.WxID),
+		zap.Error(err))
+
+	s.notifier.Notify(s.eventURL, EventCallbackPayload{
+		Event:   EventTokenExpired,
+		Message: "用户token已过期，需要重新登录",
+		Data: map[string]interface{}{
+			"user_id":   user.ID,
+			"wx_id":     user.WxID,
+			"nick_name": user.NickName,
+			"robot_id":  user.RobotID,
+		},
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// 发送文本消息（简化版）
+// 超长文本按配置自动分段顺序发送，返回首段结果并在Segments中附带所有分段的结果
+func (s *wxRobotService) SendText(ctx context.Context, robotAddress, authKey string, req *SendTextRequest) (*SendTextResponse, error) {
+	req.AtWxIDList = s.checkAtAllPermission(ctx, robotAddress, authKey, req.ToUserName, req.AtWxIDList)
+	req.TextContent = s.applySignature(authKey, req.TextContent)
+	if !s.textSplit.Enable {
+		return s.sendTextSegment(ctx, robotAddress, authKey, req)
+	}
+
+	segments := splitText(req.TextContent, s.textSplit.MaxLength, s.textSplit.Strategy)
+	if len(segments) <= 1 {
+		return s.sendTextSegment(ctx, robotAddress, authKey, req)
+	}
+
+	s.logger.Info("文本超长，分段发送",
+		zap.String("to_user_name", req.ToUserName),
+		zap.Int("length", utf8.RuneCountInString(req.TextContent)),
+		zap.Int("segments", len(segments)))
+
+	var result *SendTextResponse
+	for i, segment := range segments {
+		if i > 0 && s.textSplit.Interval > 0 {
+			select {
+			case <-time.After(s.textSplit.Interval):
+			case <-ctx.Done():
+				return result, fmt.Errorf("第%d/%d段发送前已取消: %w", i+1, len(segments), ctx.Err())
+			}
+		}
+
+		segmentReq := &SendTextRequest{
+			TextContent: segment,
+			ToUserName:  req.ToUserName,
+			Priority:    req.Priority,
+		}
+		// 调用方指定的@成员只随第一段发送，未指定时每段按内容解析
+		if i == 0 {
+			segmentReq.AtWxIDList = req.AtWxIDList
+		}
+
+		resp, err := s.sendTextSegment(ctx, robotAddress, authKey, segmentReq)
+		if err != nil {
+			return result, fmt.Errorf("第%d/%d段发送失败: %w", i+1, len(segments), err)
+		}
+		if result == nil {
+			first := *resp
+			result = &first
+		}
+		result.Segments = append(result.Segments, *resp)
+	}
+	return result, nil
+}
+
+// checkAtAllPermission 带@所有人时校验发送账号在目标群是否为群主或管理员，无权限时降级为普通消息并告警，返回校验后的@列表；
+// 无法获取群详情时不做降级，由平台处理
+func (s *wxRobotService) checkAtAllPermission(ctx context.Context, robotAddress, authKey, toUserName string, atWxIDList []string) []string {
+	if !containsAtAll(atWxIDList) {
+		return atWxIDList
+	}
+
+	var user WxUserLogin
+	if err := s.db.Select("wx_id").Where("token = ?", authKey).First(&user).Error; err != nil || user.WxID == "" {
+		s.logger.Warn("查询发送账号失败，无法校验@所有人权限", zap.String("token", maskToken(authKey)), zap.Error(err))
+		return atWxIDList
+	}
+
+	info, err := s.GetChatRoomInfo(ctx, robotAddress, authKey, []string{toUserName})
+	if err != nil {
+		s.logger.Warn("获取群详情失败，无法校验@所有人权限",
+			zap.String("wx_id", user.WxID),
+			zap.String("to_user_name", toUserName),
+			zap.Error(err))
+		return atWxIDList
+	}
+
+	allowed, found := chatRoomAtAllAllowed(info, toUserName, user.WxID)
+	if !found {
+		s.logger.Warn("群详情中未找到目标群，无法校验@所有人权限",
+			zap.String("wx_id", user.WxID),
+			zap.String("to_user_name", toUserName))
+		return atWxIDList
+	}
+	if allowed {
+		return atWxIDList
+	}
+
+	s.logger.Warn("发送账号不是群主或管理员，@所有人已降级为普通消息",
+		zap.String("wx_id", user.WxID),
+		zap.String("to_user_name", toUserName))
+	s.notifier.Notify(s.eventURL, EventCallbackPayload{
+		Event:   EventAtAllDowngraded,
+		Message: "发送账号不是群主或管理员，@所有人已降级为普通消息",
+		Data: map[string]interface{}{
+			"wx_id":        user.WxID,
+			"to_user_name": toUserName,
+		},
+		Timestamp: time.Now().Unix(),
+	})
+	return removeAtAll(atWxIDList)
+}
+
+// sendTextSegment 发送单条文本消息
+func (s *wxRobotService) sendTextSegment(ctx context.Context, robotAddress, authKey string, req *SendTextRequest) (*SendTextResponse, error) {
+	if len(req.AtWxIDList) == 0 {
+		req.AtWxIDList = s.resolveAtWxIDList(req.ToUserName, req.TextContent)
+	}
+
+	var resp *SendTextResponse
+	err := s.send(ctx, authKey, req.Priority, WxSendAudit{
+		ToUserName: req.ToUserName,
+		MsgType:    SendAuditTypeText,
+		Content:    req.TextContent,
+	}, "", func() (err error) {
+		resp, err = s.apiClient.SendText(ctx, robotAddress, authKey, req)
+		return err
+	})
+	return resp, err
+}
+
+// waitSendSlot 发送前等待账号退避和限流（n为本次发送的消息条数），再按优先级排队执行fn；
+// 返回错误时消息未发出，fn未执行
+func (s *wxRobotService) waitSendSlot(ctx context.Context, authKey, priority string, n int, fn func()) error {
+	if err := s.sendBackoff.Wait(ctx, authKey); err != nil {
+		return err
+	}
+	if err := s.sendLimiter.WaitN(ctx, authKey, n); err != nil {
+		return err
+	}
+	return s.sendQueue.Do(ctx, priority, fn)
+}
+
+// send 发送单条消息的公共流程：等待发送时机后执行fn，按fn返回的发送结果记录账号退避、运行统计、
+// token过期处理和发送审计；被本地退避、限流或排队拒绝时消息未发出，直接返回原错误，不记录发送结果
+func (s *wxRobotService) send(ctx context.Context, authKey, priority string, audit WxSendAudit, image string, fn func() error) error {
+	var sendErr error
+	if err := s.waitSendSlot(ctx, authKey, priority, 1, func() { sendErr = fn() }); err != nil {
+		return err
+	}
+	s.sendBackoff.Record(authKey, sendErr)
+	runtimeStats.RecordSend(sendErr)
+	s.handleTokenExpired(authKey, sendErr)
+	s.auditSend(authKey, audit, image, sendErr)
+	return sendErr
+}
+
+// ErrLargeGroupNotConfirmed 目标群成员数超过阈值且未确认发送
+var ErrLargeGroupNotConfirmed = errors.New("目标群成员数超过阈值，需确认后发送")
+
+// CheckLargeGroup 发送前校验目标群成员数，超过阈值且未确认时拒绝发送
+// 成员数取入库的member_count，同一群被多个账号加入时取最大值
+func (s *wxRobotService) CheckLargeGroup(groupID string, confirmed bool) error {
+	if s.largeGroupThreshold <= 0 || confirmed {
+		return nil
+	}
+
+	var memberCount int
+	if err := s.db.Model(&WxGroup{}).
+		Select("COALESCE(MAX(member_count), 0)").
+		Where("group_id = ?", groupID).
+		Scan(&memberCount).Error; err != nil {
+		s.logger.Error("查询群成员数失败", zap.String("group_id", groupID), zap.Error(err))
+		return err
+	}
+
+	if memberCount > s.largeGroupThreshold {
+		s.logger.Warn("目标群成员数超过阈值，未确认发送",
+			zap.String("group_id", groupID),
+			zap.Int("member_count", memberCount),
+			zap.Int("threshold", s.largeGroupThreshold))
+		return fmt.Errorf("%w: 群成员数 %d，阈值 %d", ErrLargeGroupNotConfirmed, memberCount, s.largeGroupThreshold)
+	}
+	return nil
+}
+
+// ErrGroupBlacklisted 目标群在黑名单中
+var ErrGroupBlacklisted = errors.New("目标群在黑名单中，禁止发送")
+
+// ErrGroupAlreadyBlacklisted 群已在黑名单中
+var ErrGroupAlreadyBlacklisted = errors.New("群已在黑名单中")
+
+// CheckGroupBlacklist 发送前校验目标群是否在黑名单中
+func (s *wxRobotService) CheckGroupBlacklist(groupID string) error {
+	var count int64
+	if err := s.db.Model(&WxGroupBlacklist{}).Where("group_id = ?", groupID).Count(&count).Error; err != nil {
+		s.logger.Error("查询群黑名单失败", zap.String("group_id", groupID), zap.Error(err))
+		return err
+	}
+	if count > 0 {
+		s.logger.Warn("目标群在黑名单中，拒绝发送", zap.String("group_id", groupID))
+		return ErrGroupBlacklisted
+	}
+	return nil
+}
+
+// CheckContentURLs 发送前检查内容中的链接，命中黑名单或安全检测未通过时返回ErrURLBlocked
+func (s *wxRobotService) CheckContentURLs(text string) error {
+	return s.urlGuard.Check(text)
+}
+
+// 发送去重命中时的处理方式
+const (
+	SendDedupActionReject = "reject" // 拒绝发送
+	SendDedupActionWarn   = "warn"   // 照常发送并告警
+)
+
+// ErrDuplicateSend 去重窗口内已向该群发送过相同内容
+var ErrDuplicateSend = errors.New("短时间内已向该群发送过相同内容")
+
+// contentHash 发送内容的SHA256哈希
+func contentHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// sendDedupKey 去重key：群ID加内容哈希
+func sendDedupKey(groupID, content string) string {
+	return groupID + ":" + contentHash(content)
+}
+
+// CheckDuplicateSend 发送前检查去重窗口内是否已向该群发送过相同内容，未命中时记录本次发送
+// 命中时按配置拒绝（返回ErrDuplicateSend）或只告警；发送失败后应调用ReleaseDuplicateSend撤销记录
+func (s *wxRobotService) CheckDuplicateSend(groupID, content string) error {
+	allowed, retryAfter := s.sendDedup.Allow(sendDedupKey(groupID, content))
+	if allowed {
+		return nil
+	}
+
+	if s.sendDedupAction != SendDedupActionWarn {
+		s.logger.Warn("去重窗口内重复发送相同内容，拒绝发送",
+			zap.String("group_id", groupID),
+			zap.Duration("retry_after", retryAfter))
+		return fmt.Errorf("%w，请%s后再试", ErrDuplicateSend, retryAfter.Round(time.Second))
+	}
+
+	s.logger.Warn("去重窗口内重复发送相同内容", zap.String("group_id", groupID))
+	s.notifier.Notify(s.eventURL, EventCallbackPayload{
+		Event:   EventDuplicateSend,
+		Message: ErrDuplicateSend.Error(),
+		Data: map[string]interface{}{
+			"group_id":     groupID,
+			"content_hash": contentHash(content),
+		},
+		Timestamp: time.Now().Unix(),
+	})
+	return nil
+}
+
+// ReleaseDuplicateSend 发送失败时撤销去重记录，允许立即重试
+func (s *wxRobotService) ReleaseDuplicateSend(groupID, content string) {
+	s.sendDedup.Undo(sendDedupKey(groupID, content))
+}
+
+// ListGroupBlacklist 查询群黑名单，ownerID不为0时只返回该公司账号所在的群
+func (s *wxRobotService) ListGroupBlacklist(ownerID uint) ([]WxGroupBlacklist, error) {
+	var list []WxGroupBlacklist
+	query := s.db.Order("id DESC")
+	if ownerID > 0 {
+		query = query.Where("group_id IN (?)", s.ownerGroupIDs(ownerID))
+	}
+	if err := query.Find(&list).Error; err != nil {
+		s.logger.Error("查询群黑名单失败", zap.Error(err))
+		return nil, err
+	}
+	return list, nil
+}
+
+// AddGroupBlacklist 将群加入黑名单，已存在时返回ErrGroupAlreadyBlacklisted
+func (s *wxRobotService) AddGroupBlacklist(req *AddGroupBlacklistRequest) (*WxGroupBlacklist, error) {
+	var count int64
+	if err := s.db.Model(&WxGroupBlacklist{}).Where("group_id = ?", req.GroupID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrGroupAlreadyBlacklisted
+	}
+
+	item := &WxGroupBlacklist{GroupID: req.GroupID, Reason: req.Reason}
+	if err := s.db.Create(item).Error; err != nil {
+		s.logger.Error("添加群黑名单失败", zap.String("group_id", req.GroupID), zap.Error(err))
+		return nil, err
+	}
+	s.logger.Info("群已加入黑名单", zap.String("group_id", req.GroupID), zap.String("reason", req.Reason))
+	return item, nil
+}
+
+// RemoveGroupBlacklist 将群移出黑名单，不在黑名单中时返回gorm.ErrRecordNotFound
+func (s *wxRobotService) RemoveGroupBlacklist(groupID string) error {
+	result := s.db.Where("group_id = ?", groupID).Delete(&WxGroupBlacklist{})
+	if result.Error != nil {
+		s.logger.Error("移除群黑名单失败", zap.String("group_id", groupID), zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	s.logger.Info("群已移出黑名单", zap.String("group_id", groupID))
+	return nil
+}
+
+// ownerWxIDs 指定公司机器人下登录的微信账号子查询
+func (s *wxRobotService) ownerWxIDs(ownerID uint) *gorm.DB {
+	return s.db.Table("wx_user_logins u").
+		Select("u.wx_id").
+		Joins("JOIN wx_robot_configs r ON r.id = u.robot_id").
+		Where("r.owner_id = ?", ownerID)
+}
+
+// ownerGroupIDs 指定公司账号所在群的子查询
+func (s *wxRobotService) ownerGroupIDs(ownerID uint) *gorm.DB {
+	return s.db.Model(&WxGroup{}).Select("group_id").Where("wx_id IN (?)", s.ownerWxIDs(ownerID))
+}
+
+// OwnerHasWxID 微信账号是否登录在指定公司的机器人上
+func (s *wxRobotService) OwnerHasWxID(ownerID uint, wxID string) (bool, error) {
+	var count int64
+	if err := s.ownerWxIDs(ownerID).Where("u.wx_id = ?", wxID).Count(&count).Error; err != nil {
+		s.logger.Error("校验账号所属公司失败", zap.String("wx_id", wxID), zap.Error(err))
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// OwnerHasGroup 群内是否有指定公司的账号
+func (s *wxRobotService) OwnerHasGroup(ownerID uint, groupID string) (bool, error) {
+	var count int64
+	if err := s.ownerGroupIDs(ownerID).Where("group_id = ?", groupID).Count(&count).Error; err != nil {
+		s.logger.Error("校验群所属公司失败", zap.String("group_id", groupID), zap.Error(err))
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SendTextToGroups 向多个群发送同一条文本，同一消息机器人负责的群合并为一次批量请求，返回每个群的结果
+func (s *wxRobotService) SendTextToGroups(ctx context.Context, req *SendTextMultiRequest, strategy MessageSendStrategy) []SendTextResult {
+	type botBatch struct {
+		bot  *MessageBotInfo
+		reqs []*SendTextRequest
+	}
+
+	resultMap := make(map[string]SendTextResult, len(req.ToUserNames))
+	claimed := make(map[string]bool, len(req.ToUserNames)) // 已记录去重的群，发送失败时撤销
+	batches := make(map[uint]*botBatch)
+	var batchOrder []uint
+
+	for _, toUserName := range req.ToUserNames {
+		if err := s.CheckGroupBlacklist(toUserName); err != nil {
+			resultMap[toUserName] = SendTextResult{ToUserName: toUserName, Error: err.Error()}
+			continue
+		}
+		if err := s.CheckLargeGroup(toUserName, req.ConfirmLargeGroup); err != nil {
+			resultMap[toUserName] = SendTextResult{ToUserName: toUserName, Error: err.Error()}
+			continue
+		}
+
+		botInfo, err := s.GetMessageBotByStrategy(toUserName, strategy)
+		if err != nil {
+			resultMap[toUserName] = SendTextResult{ToUserName: toUserName, Error: "未找到对应的消息机器人"}
+			continue
+		}
+		if err := s.CheckDuplicateSend(toUserName, req.TextContent); err != nil {
+			resultMap[toUserName] = SendTextResult{ToUserName: toUserName, Error: err.Error()}
+			continue
+		}
+		claimed[toUserName] = true
+
+		batch, ok := batches[botInfo.User.ID]
+		if !ok {
+			batch = &botBatch{bot: botInfo}
+			batches[botInfo.User.ID] = batch
+			batchOrder = append(batchOrder, botInfo.User.ID)
+		}
+		atWxIDList := s.resolveAtWxIDList(toUserName, req.TextContent)
+		batch.reqs = append(batch.reqs, &SendTextRequest{
+			TextContent: req.TextContent,
+			ToUserName:  toUserName,
+			AtWxIDList:  s.checkAtAllPermission(withRobotTimeout(ctx, botInfo.Robot), botInfo.Robot.Address, botInfo.User.Token, toUserName, atWxIDList),
+		})
+	}
+
+	for _, userID := range batchOrder {
+		batch := batches[userID]
+
+		// 同一消息机器人的签名相同，按批次拼接
+		textContent := s.applySignature(batch.bot.User.Token, req.TextContent)
+		for _, r := range batch.reqs {
+			r.TextContent = textContent
+		}
+
+		var results []SendTextResult
+		var err error
+		if slotErr := s.waitSendSlot(ctx, batch.bot.User.Token, req.Priority, len(batch.reqs), func() {
+			results, err = s.apiClient.SendTextBatch(withRobotTimeout(ctx, batch.bot.Robot), batch.bot.Robot.Address, batch.bot.User.Token, batch.reqs)
+		}); slotErr != nil {
+			// 被本地退避、限流或排队拒绝，消息未发出，不记录发送结果
+			for _, r := range batch.reqs {
+				resultMap[r.ToUserName] = SendTextResult{ToUserName: r.ToUserName, Error: slotErr.Error(), ErrorType: classifySendError(slotErr)}
+			}
+			continue
+		}
+		s.handleTokenExpired(batch.bot.User.Token, err)
+
+		if err != nil {
+			for _, r := range batch.reqs {
+				resultMap[r.ToUserName] = SendTextResult{ToUserName: r.ToUserName, Error: err.Error(), ErrorType: classifySendError(err)}
+			}
+			runtimeStats.AddErrors(len(batch.reqs))
+		} else {
+			for _, result := range results {
+				resultMap[result.ToUserName] = result
+				if result.Success {
+					runtimeStats.AddSent(1)
+				} else {
+					runtimeStats.AddErrors(1)
+				}
+			}
+		}
+
+		for _, r := range batch.reqs {
+			// 整批请求失败时保留原错误，便于按错误类型退避；否则按各群的发送结果记录
+			auditErr := err
+			if auditErr == nil {
+				if result, ok := resultMap[r.ToUserName]; !ok {
+					auditErr = errors.New("无发送结果数据")
+				} else if !result.Success {
+					auditErr = &SendError{Type: result.ErrorType, Err: errors.New(result.Error)}
+				}
+			}
+			s.sendBackoff.Record(batch.bot.User.Token, auditErr)
+			s.auditSend(batch.bot.User.Token, WxSendAudit{
+				SenderWxID: batch.bot.User.WxID,
+				ToUserName: r.ToUserName,
+				MsgType:    SendAuditTypeText,
+				Content:    r.TextContent,
+			}, "", auditErr)
+		}
+	}
+
+	// 按请求顺序返回，接口未返回结果的群视为失败
+	ordered := make([]SendTextResult, 0, len(req.ToUserNames))
+	for _, toUserName := range req.ToUserNames {
+		result, ok := resultMap[toUserName]
+		if !ok {
+			result = SendTextResult{ToUserName: toUserName, Error: "无发送结果数据"}
+		}
+		if !result.Success && claimed[toUserName] {
+			s.ReleaseDuplicateSend(toUserName, req.TextContent)
+		}
+		ordered = append(ordered, result)
+	}
+	return ordered
+}
+
+// 发送图片消息（简化版）
+func (s *wxRobotService) SendImage(ctx context.Context, robotAddress, authKey string, req *SendImageRequest) (*SendImageResponse, error) {
+	var resp *SendImageResponse
+	err := s.send(ctx, authKey, req.Priority, WxSendAudit{
+		ToUserName: req.ToUserName,
+		MsgType:    SendAuditTypeImage,
+	}, req.ImageContent, func() (err error) {
+		resp, err = s.apiClient.SendImage(ctx, robotAddress, authKey, req)
+		return err
+	})
+	return resp, err
+}
+
+// 发送语音消息（简化版）
+func (s *wxRobotService) SendVoice(ctx context.Context, robotAddress, authKey string, req *SendVoiceRequest) (*SendVoiceResponse, error) {
+	var resp *SendVoiceResponse
+	err := s.send(ctx, authKey, req.Priority, WxSendAudit{
+		ToUserName: req.ToUserName,
+		MsgType:    SendAuditTypeVoice,
+	}, req.VoiceContent, func() (err error) {
+		resp, err = s.apiClient.SendVoice(ctx, robotAddress, authKey, req)
+		return err
+	})
+	return resp, err
+}
+
+// 发送视频消息（简化版）
+func (s *wxRobotService) SendVideo(ctx context.Context, robotAddress, authKey string, req *SendVideoRequest) (*SendVideoResponse, error) {
+	var resp *SendVideoResponse
+	err := s.send(ctx, authKey, req.Priority, WxSendAudit{
+		ToUserName: req.ToUserName,
+		MsgType:    SendAuditTypeVideo,
+	}, req.VideoContent, func() (err error) {
+		resp, err = s.apiClient.SendVideo(ctx, robotAddress, authKey, req)
+		return err
+	})
+	return resp, err
+}
+
+// 发送链接卡片消息（简化版），审计内容记录标题和链接
+func (s *wxRobotService) SendAppMsg(ctx context.Context, robotAddress, authKey string, req *SendAppMsgRequest) (*SendAppMsgResponse, error) {
+	var resp *SendAppMsgResponse
+	err := s.send(ctx, authKey, req.Priority, WxSendAudit{
+		ToUserName: req.ToUserName,
+		MsgType:    SendAuditTypeLink,
+		Content:    req.Title + "\n" + req.Url,
+	}, "", func() (err error) {
+		resp, err = s.apiClient.SendAppMsg(ctx, robotAddress, authKey, req)
+		return err
+	})
+	return resp, err
+}
+
+// 同时发送文字和图片
+func (s *wxRobotService) SendTextAndImage(ctx context.Context, robotAddress, authKey string, req *SendTextAndImageRequest) (*SendTextAndImageResponse, error) {
+	req.TextContent = s.applySignature(authKey, req.TextContent)
+	if len(req.AtWxIDList) == 0 {
+		req.AtWxIDList = s.resolveAtWxIDList(req.ToUserName, req.TextContent)
+	}
+	req.AtWxIDList = s.checkAtAllPermission(ctx, robotAddress, authKey, req.ToUserName, req.AtWxIDList)
+
+	var resp *SendTextAndImageResponse
+	var callErr error
+	err := s.send(ctx, authKey, req.Priority, WxSendAudit{
+		ToUserName: req.ToUserName,
+		MsgType:    SendAuditTypeTextImage,
+		Content:    req.TextContent,
+	}, req.ImageContent, func() error {
+		resp, callErr = s.apiClient.SendTextAndImage(ctx, robotAddress, authKey, req)
+		if callErr == nil && !resp.Success {
+			// 接口调用成功但发送失败，同样计入退避、统计和审计
+			return newSendError(classifySendFailure(resp.Message), "%s", resp.Message)
+		}
+		return callErr
+	})
+	if callErr == nil && resp != nil {
+		// 发送失败由resp.Success表示
+		return resp, nil
+	}
+	return resp, err
+}
+
+// auditSend 记录发送内容审计，image为图片内容，只参与哈希不保存；
+// audit未指定发送账号时按token查询；写入失败只记录日志，不影响发送结果
+func (s *wxRobotService) auditSend(authKey string, audit WxSendAudit, image string, sendErr error) {
+	audit.ContentHash = contentHash(audit.Content + image)
+	if !s.auditStoreContent {
+		audit.Content = ""
+	}
+
+	audit.Success = sendErr == nil
+	if sendErr != nil {
+		audit.Error = truncateSendError(sendErr)
+	}
+
+	// 按token查询发送账号及其所属公司，便于按公司查看审计记录
+	var sender struct {
+		WxID    string
+		OwnerID uint
+	}
+	if err := s.db.Table("wx_user_logins u").
+		Select("u.wx_id, r.owner_id").
+		Joins("JOIN wx_robot_configs r ON r.id = u.robot_id").
+		Where("u.token = ?", authKey).
+		Limit(1).Scan(&sender).Error; err != nil || sender.WxID == "" {
+		s.logger.Warn("查询发送账号失败，审计记录不含发送账号和所属公司", zap.String("token", maskToken(authKey)), zap.Error(err))
+	}
+	if audit.SenderWxID == "" {
+		audit.SenderWxID = sender.WxID
+	}
+	audit.OwnerID = sender.OwnerID
+
+	if err := s.db.Create(&audit).Error; err != nil {
+		s.logger.Error("保存发送审计记录失败",
+			zap.String("sender_wx_id", audit.SenderWxID),
+			zap.String("to_user_name", audit.ToUserName),
+			zap.String("content_hash", audit.ContentHash),
+			zap.Error(err))
+	}
+}
+
+// EnqueueSendRetry 发送因临时故障失败时写入重试队列，由后台按退避重发，返回重试记录ID
+// 未开启重试或失败分类不可重试时不入队，返回0；ownerID不为0时重发只使用该公司的消息机器人
+func (s *wxRobotService) EnqueueSendRetry(ownerID uint, msgType, toUserName string, payload interface{}, sendErr error) (uint, error) {
+	if !s.sendRetry.Enable || !classifySendError(sendErr).Retryable() {
+		return 0, nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("序列化重试消息失败: %w", err)
+	}
+	retry := WxSendRetry{
+		OwnerID:       ownerID,
+		MsgType:       msgType,
+		ToUserName:    toUserName,
+		Payload:       string(data),
+		Status:        SendRetryStatusPending,
+		LastError:     truncateSendError(sendErr),
+		NextRetryTime: time.Now().Add(retryBackoff(s.sendRetry.InitialBackoff, s.sendRetry.MaxBackoff, 1)),
+	}
+	if err := s.db.Create(&retry).Error; err != nil {
+		s.logger.Error("失败消息写入重试队列失败",
+			zap.String("msg_type", msgType),
+			zap.String("to_user_name", toUserName),
+			zap.Error(err))
+		return 0, err
+	}
+
+	s.logger.Info("发送失败的消息已进入重试队列",
+		zap.Uint("retry_id", retry.ID),
+		zap.String("msg_type", msgType),
+		zap.String("to_user_name", toUserName),
+		zap.Time("next_retry_time", retry.NextRetryTime))
+	return retry.ID, nil
+}
+
+// ProcessSendRetries 重发到期的失败消息：每次重新按策略选择消息机器人，成功则标记成功；
+// 仍因临时故障失败时按退避安排下次重试，重试次数用尽或遇到不可重试的失败时标记为最终失败并告警
+func (s *wxRobotService) ProcessSendRetries(ctx context.Context, strategy MessageSendStrategy) (*SendRetryRunSummary, error) {
+	var retries []WxSendRetry
+	if err := s.db.Where("status = ? AND next_retry_time <= ?", SendRetryStatusPending, time.Now()).
+		Order("next_retry_time ASC").Limit(s.sendRetry.BatchSize).
+		Find(&retries).Error; err != nil {
+		s.logger.Error("查询待重试消息失败", zap.Error(err))
+		return nil, err
+	}
+
+	summary := &SendRetryRunSummary{Total: len(retries)}
+	for i := range retries {
+		if ctx.Err() != nil {
+			s.logger.Warn("失败消息重试超时，剩余消息下次处理", zap.Error(ctx.Err()))
+			break
+		}
+
+		retry := &retries[i]
+		sendErr := s.resendRetry(ctx, retry, strategy)
+		retry.Attempts++
+		switch {
+		case sendErr == nil:
+			retry.Status = SendRetryStatusSuccess
+			retry.LastError = ""
+			summary.Success++
+		case classifySendError(sendErr).Retryable() && retry.Attempts < s.sendRetry.MaxAttempts:
+			retry.LastError = truncateSendError(sendErr)
+			retry.NextRetryTime = time.Now().Add(retryBackoff(s.sendRetry.InitialBackoff, s.sendRetry.MaxBackoff, retry.Attempts+1))
+			summary.Pending++
+		default:
+			retry.Status = SendRetryStatusFailed
+			retry.LastError = truncateSendError(sendErr)
+			summary.Failed++
+		}
+
+		if err := s.db.Model(retry).Select("status", "attempts", "last_error", "next_retry_time").Updates(retry).Error; err != nil {
+			s.logger.Error("更新重试消息状态失败", zap.Uint("retry_id", retry.ID), zap.Error(err))
+			continue
+		}
+
+		switch retry.Status {
+		case SendRetryStatusSuccess:
+			s.logger.Info("失败消息重试发送成功",
+				zap.Uint("retry_id", retry.ID),
+				zap.String("to_user_name", retry.ToUserName),
+				zap.Int("attempts", retry.Attempts))
+		case SendRetryStatusFailed:
+			s.notifySendRetryFailed(retry)
+		default:
+			s.logger.Warn("失败消息重试仍失败，稍后再试",
+				zap.Uint("retry_id", retry.ID),
+				zap.String("to_user_name", retry.ToUserName),
+				zap.Int("attempts", retry.Attempts),
+				zap.Time("next_retry_time", retry.NextRetryTime),
+				zap.Error(sendErr))
+		}
+	}
+	return summary, nil
+}
+
+// resendRetry 按消息类型还原原始请求并重新选择消息机器人发送
+func (s *wxRobotService) resendRetry(ctx context.Context, retry *WxSendRetry, strategy MessageSendStrategy) error {
+	if err := s.CheckGroupBlacklist(retry.ToUserName); err != nil {
+		return err
+	}
+	botInfo, err := s.GetMessageBotByStrategy(retry.ToUserName, withOwnerScope(strategy, retry.OwnerID))
+	if err != nil {
+		// 暂时没有可用的消息机器人（如账号均掉线），按临时故障稍后再试
+		return newSendError(SendErrorNetwork, "未找到对应的消息机器人: %w", err)
+	}
+	address, token := botInfo.Robot.Address, botInfo.User.Token
+	ctx = withRobotTimeout(ctx, botInfo.Robot)
+
+	switch retry.MsgType {
+	case SendAuditTypeText:
+		var req SendTextRequest
+		if err := json.Unmarshal([]byte(retry.Payload), &req); err != nil {
+			return fmt.Errorf("解析重试消息失败: %w", err)
+		}
+		_, err = s.SendText(ctx, address, token, &req)
+	case SendAuditTypeImage:
+		var req SendImageRequest
+		if err := json.Unmarshal([]byte(retry.Payload), &req); err != nil {
+			return fmt.Errorf("解析重试消息失败: %w", err)
+		}
+		_, err = s.SendImage(ctx, address, token, &req)
+	case SendAuditTypeVoice:
+		var req SendVoiceRequest
+		if err := json.Unmarshal([]byte(retry.Payload), &req); err != nil {
+			return fmt.Errorf("解析重试消息失败: %w", err)
+		}
+		_, err = s.SendVoice(ctx, address, token, &req)
+	case SendAuditTypeVideo:
+		var req SendVideoRequest
+		if err := json.Unmarshal([]byte(retry.Payload), &req); err != nil {
+			return fmt.Errorf("解析重试消息失败: %w", err)
+		}
+		_, err = s.SendVideo(ctx, address, token, &req)
+	case SendAuditTypeLink:
+		var req SendAppMsgRequest
+		if err := json.Unmarshal([]byte(retry.Payload), &req); err != nil {
+			return fmt.Errorf("解析重试消息失败: %w", err)
+		}
+		_, err = s.SendAppMsg(ctx, address, token, &req)
+	default:
+		return fmt.Errorf("不支持重试的消息类型: %s", retry.MsgType)
+	}
+	return err
+}
+
+// notifySendRetryFailed 失败消息最终失败时告警
+func (s *wxRobotService) notifySendRetryFailed(retry *WxSendRetry) {
+	s.logger.Error("失败消息重试最终失败",
+		zap.Uint("retry_id", retry.ID),
+		zap.String("msg_type", retry.MsgType),
+		zap.String("to_user_name", retry.ToUserName),
+		zap.Int("attempts", retry.Attempts),
+		zap.String("error", retry.LastError))
+
+	s.notifier.Notify(s.eventURL, EventCallbackPayload{
+		Event:   EventSendRetryFailed,
+		Message: "失败消息重试最终失败",
+		Data: map[string]interface{}{
+			"retry_id":     retry.ID,
+			"msg_type":     retry.MsgType,
+			"to_user_name": retry.ToUserName,
+			"attempts":     retry.Attempts,
+			"error":        retry.LastError,
+		},
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// sendAuditQuery 按条件构建审计记录查询，时间格式已由调用方校验
+func (s *wxRobotService) sendAuditQuery(filter SendAuditFilter) *gorm.DB {
+	query := s.db.Model(&WxSendAudit{})
+	if filter.StartTime != "" {
+		if startTime, err := ParseTime(filter.StartTime); err == nil {
+			query = query.Where("create_time >= ?", startTime)
+		}
+	}
+	if filter.EndTime != "" {
+		if endTime, err := ParseTime(filter.EndTime); err == nil {
+			query = query.Where("create_time <= ?", endTime)
+		}
+	}
+	if filter.ToUserName != "" {
+		query = query.Where("to_user_name = ?", filter.ToUserName)
+	}
+	if filter.SenderWxID != "" {
+		query = query.Where("sender_wx_id = ?", filter.SenderWxID)
+	}
+	if filter.OwnerID > 0 {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	return query
+}
+
+// ListSendAudits 按时间/群/发送账号分页查询发送审计记录
+func (s *wxRobotService) ListSendAudits(req SendAuditQueryRequest) (*SendAuditPaginatedResponse, error) {
+	var totalCount int64
+	if err := s.sendAuditQuery(req.SendAuditFilter).Count(&totalCount).Error; err != nil {
+		s.logger.Error("获取审计记录总数失败", zap.Error(err))
+		return nil, err
+	}
+
+	totalPages := int((totalCount + int64(req.PageSize) - 1) / int64(req.PageSize))
+	offset := (req.PageNo - 1) * req.PageSize
+
+	audits := make([]WxSendAudit, 0, req.PageSize)
+	if err := s.sendAuditQuery(req.SendAuditFilter).
+		Order("id DESC").Offset(offset).Limit(req.PageSize).
+		Find(&audits).Error; err != nil {
+		s.logger.Error("查询审计记录失败", zap.Error(err))
+		return nil, err
+	}
+
+	return &SendAuditPaginatedResponse{
+		List: audits,
+		Pagination: PaginationInfo{
+			PageNo:     req.PageNo,
+			PageSize:   req.PageSize,
+			TotalCount: totalCount,
+			TotalPages: totalPages,
+			HasNext:    req.PageNo < totalPages,
+			HasPrev:    req.PageNo > 1,
+		},
+	}, nil
+}
+
+// GetRobotTagSendStats 按机器人标签汇总发送审计记录的发送量与成功率，用于对比不同分组机器人
+// 发送账号登录过多个机器人时计入最近登录的机器人
+func (s *wxRobotService) GetRobotTagSendStats(req RobotTagStatsRequest) ([]RobotTagSendStats, error) {
+	var robots []WxRobotConfig
+	robotQuery := s.db.Select("id", "tags")
+	if req.OwnerID > 0 {
+		robotQuery = robotQuery.Where("owner_id = ?", req.OwnerID)
+	}
+	if err := robotQuery.Find(&robots).Error; err != nil {
+		s.logger.Error("查询机器人标签失败", zap.Error(err))
+		return nil, err
+	}
+	robotTags := make(map[uint][]string, len(robots))
+	for _, robot := range robots {
+		robotTags[robot.ID] = splitRobotTags(robot.Tags)
+	}
+
+	var senderCounts []struct {
+		SenderWxID   string
+		Total        int64
+		SuccessCount int64
+	}
+	filter := SendAuditFilter{StartTime: req.StartTime, EndTime: req.EndTime, OwnerID: req.OwnerID}
+	if err := s.sendAuditQuery(filter).
+		Select("sender_wx_id, COUNT(*) AS total, SUM(CASE WHEN success THEN 1 ELSE 0 END) AS success_count").
+		Where("sender_wx_id <> ''").
+		Group("sender_wx_id").
+		Scan(&senderCounts).Error; err != nil {
+		s.logger.Error("按发送账号统计审计记录失败", zap.Error(err))
+		return nil, err
+	}
+
+	sends := make(map[uint]robotSendCount)
+	if len(senderCounts) > 0 {
+		wxIDs := make([]string, 0, len(senderCounts))
+		for _, item := range senderCounts {
+			wxIDs = append(wxIDs, item.SenderWxID)
+		}
+		var users []WxUserLogin
+		if err := s.db.Select("robot_id", "wx_id").Where("wx_id IN ?", wxIDs).
+			Order("id").Find(&users).Error; err != nil {
+			s.logger.Error("查询发送账号所属机器人失败", zap.Error(err))
+			return nil, err
+		}
+		// 按id升序覆盖，保留最近登录的机器人
+		senderRobot := make(map[string]uint, len(users))
+		for _, user := range users {
+			senderRobot[user.WxID] = user.RobotID
+		}
+		for _, item := range senderCounts {
+			robotID, ok := senderRobot[item.SenderWxID]
+			if !ok {
+				continue
+			}
+			count := sends[robotID]
+			count.Total += item.Total
+			count.Success += item.SuccessCount
+			sends[robotID] = count
+		}
+	}
+
+	return aggregateRobotTagStats(robotTags, sends, splitRobotTags(req.Tags)), nil
+}
+
+// ExportSendAudits 逐条读取符合条件的审计记录并交给fn处理，用于流式导出
+func (s *wxRobotService) ExportSendAudits(filter SendAuditFilter, fn func(audit *WxSendAudit) error) error {
+	rows, err := s.sendAuditQuery(filter).Order("id").Rows()
+	if err != nil {
+		s.logger.Error("查询导出审计记录失败", zap.Error(err))
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var audit WxSendAudit
+		if err := s.db.ScanRows(rows, &audit); err != nil {
+			s.logger.Error("读取导出审计记录失败", zap.Error(err))
+			return err
+		}
+		if err := fn(&audit); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// resolveAtWxIDList 将文本中的 @昵称 解析为群成员wxid，匹配不到的保留原文并告警
+func (s *wxRobotService) resolveAtWxIDList(groupID, content string) []string {
+	if !strings.Contains(content, "@") {
+		return nil
+	}
+
+	members, err := s.GetGroupMembers(groupID)
+	if err != nil {
+		return nil
+	}
+
+	wxIDs, unresolved := resolveMentions(content, members)
+	if len(unresolved) > 0 {
+		s.logger.Warn("@昵称未匹配到群成员，保留原文",
+			zap.String("group_id", groupID),
+			zap.Strings("nick_names", unresolved))
+	}
+	return wxIDs
+}
+
+// BroadcastMessage 群发消息：逐个群通过策略选择消息机器人发送，onResult在每个群发送完成后回调（可为nil）
+// 每个群的结果以traceID写入发送记录，可按traceID查询整个批次
+func (s *wxRobotService) BroadcastMessage(ctx context.Context, req *BroadcastRequest, traceID string, strategy MessageSendStrategy, onResult func(BroadcastGroupResult)) []BroadcastGroupResult {
+	results := make([]BroadcastGroupResult, 0, len(req.ToUserNames))
+
+	for _, toUserName := range req.ToUserNames {
+		result := s.broadcastToGroup(ctx, req, toUserName, strategy)
+		if result.Success {
+			s.logger.Info("群发消息发送成功",
+				zap.String("trace_id", traceID),
+				zap.String("to_user_name", toUserName),
+				zap.String("wx_id", result.WxID))
+		} else {
+			s.logger.Warn("群发消息发送失败",
+				zap.String("trace_id", traceID),
+				zap.String("to_user_name", toUserName),
+				zap.String("wx_id", result.WxID),
+				zap.String("error", result.Error))
+		}
+		s.saveSendRecord(traceID, req.OwnerID, result)
+
+		results = append(results, result)
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+
+	return results
+}
+
+// saveSendRecord 保存单个群的发送记录，保存失败只记录日志
+func (s *wxRobotService) saveSendRecord(traceID string, ownerID uint, result BroadcastGroupResult) {
+	errMsg := result.Error
+	if utf8.RuneCountInString(errMsg) > 500 {
+		errMsg = string([]rune(errMsg)[:500])
+	}
+
+	record := &WxSendRecord{
+		TraceID:    traceID,
+		OwnerID:    ownerID,
+		ToUserName: result.ToUserName,
+		WxID:       result.WxID,
+		Success:    result.Success,
+		Error:      errMsg,
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		s.logger.Error("保存发送记录失败",
+			zap.String("trace_id", traceID),
+			zap.String("to_user_name", result.ToUserName),
+			zap.Error(err))
+	}
 }
 
-// NewWxRobotService 创建微信机器人服务
-func NewWxRobotService(db *gorm.DB, logger *zap.Logger) WxRobotService {
-	return &wxRobotService{
-		apiClient: NewWxAPIClient(logger),
-		db:        db,
-		logger:    logger,
+// GetSendRecordsByTrace 按trace_id查询同一发送批次的所有消息结果，ownerID不为0时只返回该公司发起的记录
+func (s *wxRobotService) GetSendRecordsByTrace(traceID string, ownerID uint) (*SendTraceResponse, error) {
+	var records []WxSendRecord
+	query := s.db.Where("trace_id = ?", traceID)
+	if ownerID > 0 {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+	if err := query.Order("id").Find(&records).Error; err != nil {
+		s.logger.Error("查询发送记录失败", zap.String("trace_id", traceID), zap.Error(err))
+		return nil, err
 	}
-}
 
-// 生成授权码
-func (s *wxRobotService) GenAuthKey(robotAddress, adminKey string, count, days int) (*GenAuthKeyResponse, error) {
-	return s.apiClient.GenAuthKey(robotAddress, adminKey, count, days)
+	resp := &SendTraceResponse{
+		TraceID: traceID,
+		Total:   len(records),
+		Records: records,
+	}
+	for _, record := range records {
+		if record.Success {
+			resp.Success++
+		} else {
+			resp.Failed++
+		}
+	}
+	return resp, nil
 }
 
-// 获取登录二维码
-func (s *wxRobotService) GetLoginQrCode(robotAddress, authKey string, check bool, proxy string) (*GetLoginQrCodeResponse, error) {
-	return s.apiClient.GetLoginQrCode(robotAddress, authKey, check, proxy)
-}
+// BroadcastToGroups 同步群发：剔除无效群后逐个群发送，汇总每个群的结果，被剔除的群作为失败结果返回，结果按请求顺序排列
+func (s *wxRobotService) BroadcastToGroups(ctx context.Context, req *BroadcastRequest, traceID string, strategy MessageSendStrategy) *GroupBroadcastResponse {
+	validGroups, skipped := s.FilterBroadcastGroups(req.ToUserNames, req.OwnerID)
 
-// 检查是否有安全风险
-func (s *wxRobotService) CheckCanSetAlias(robotAddress, authKey string) (*CheckCanSetAliasResponse, error) {
-	return s.apiClient.CheckCanSetAlias(robotAddress, authKey)
-}
+	resultMap := make(map[string]BroadcastGroupResult, len(req.ToUserNames))
+	for _, item := range skipped {
+		resultMap[item.ToUserName] = BroadcastGroupResult{ToUserName: item.ToUserName, Error: item.Reason}
+	}
+	if len(validGroups) > 0 {
+		sendReq := *req
+		sendReq.ToUserNames = validGroups
+		for _, result := range s.BroadcastMessage(ctx, &sendReq, traceID, strategy, nil) {
+			resultMap[result.ToUserName] = result
+		}
+	}
 
-// 检查登录状态
-func (s *wxRobotService) CheckLoginStatus(robotAddress, authKey string) (*CheckLoginStatusResponse, error) {
-	return s.apiClient.CheckLoginStatus(robotAddress, authKey)
-}
+	resp := &GroupBroadcastResponse{
+		TraceID: traceID,
+		Results: make([]BroadcastGroupResult, 0, len(resultMap)),
+	}
+	seen := make(map[string]bool, len(req.ToUserNames))
+	for _, toUserName := range req.ToUserNames {
+		if seen[toUserName] {
+			continue
+		}
+		seen[toUserName] = true
 
-// 获取登录状态
-func (s *wxRobotService) GetLoginStatus(robotAddress, authKey string) (*GetLoginStatusResponse, error) {
-	return s.apiClient.GetLoginStatus(robotAddress, authKey)
+		result, ok := resultMap[toUserName]
+		if !ok {
+			continue
+		}
+		if result.Success {
+			resp.Success++
+		} else {
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	resp.Total = len(resp.Results)
+	return resp
 }
 
-// 检查初始化状态
-func (s *wxRobotService) GetInitStatus(robotAddress, authKey string) (*GetInitStatusResponse, error) {
-	return s.apiClient.GetInitStatus(robotAddress, authKey)
-}
+// FilterBroadcastGroups 群发前去重并剔除系统中不存在、在黑名单中或没有可用消息机器人的群，返回有效群（保持原顺序）和被剔除的群
+// ownerID不为0时只认该公司的消息机器人，没有该公司账号的群视为没有可用消息机器人
+func (s *wxRobotService) FilterBroadcastGroups(toUserNames []string, ownerID uint) ([]string, []BroadcastSkippedGroup) {
+	valid := make([]string, 0, len(toUserNames))
+	skipped := make([]BroadcastSkippedGroup, 0)
 
-// 授权码延期
-func (s *wxRobotService) DelayAuthKey(robotAddress, adminKey, authKey string, days int) (*DelayAuthKeyResponse, error) {
-	return s.apiClient.DelayAuthKey(robotAddress, adminKey, authKey, days)
-}
+	var existing []string
+	if err := s.db.Model(&WxGroup{}).Where("group_id IN ?", toUserNames).
+		Distinct().Pluck("group_id", &existing).Error; err != nil {
+		// 查询失败时不剔除，交由发送时报错
+		s.logger.Error("查询群发目标群失败", zap.Error(err))
+		return toUserNames, skipped
+	}
+	existingSet := make(map[string]bool, len(existing))
+	for _, groupID := range existing {
+		existingSet[groupID] = true
+	}
 
-// 获取群详情
-func (s *wxRobotService) GetChatRoomInfo(robotAddress, authKey string, chatRoomIds []string) (*GetChatRoomInfoResponse, error) {
-	return s.apiClient.GetChatRoomInfo(robotAddress, authKey, chatRoomIds)
-}
+	seen := make(map[string]bool, len(toUserNames))
+	for _, toUserName := range toUserNames {
+		switch {
+		case seen[toUserName]:
+			skipped = append(skipped, BroadcastSkippedGroup{ToUserName: toUserName, Reason: "重复的群"})
+			continue
+		case !existingSet[toUserName]:
+			skipped = append(skipped, BroadcastSkippedGroup{ToUserName: toUserName, Reason: "群不存在"})
+		case errors.Is(s.CheckGroupBlacklist(toUserName), ErrGroupBlacklisted):
+			skipped = append(skipped, BroadcastSkippedGroup{ToUserName: toUserName, Reason: "群在黑名单中"})
+		default:
+			if _, err := queryMessageBots(scopeBotOwner(s.db, ownerID), toUserName, s.logger); errors.Is(err, ErrNoMessageBot) {
+				skipped = append(skipped, BroadcastSkippedGroup{ToUserName: toUserName, Reason: "没有可用的消息机器人"})
+			} else {
+				valid = append(valid, toUserName)
+			}
+		}
+		seen[toUserName] = true
+	}
 
-// 获取群列表
-func (s *wxRobotService) GetGroupList(robotAddress, authKey string) (*GroupListResponse, error) {
-	return s.apiClient.GetGroupList(robotAddress, authKey)
+	if len(skipped) > 0 {
+		s.logger.Info("群发已剔除重复或无效的群",
+			zap.Int("total", len(toUserNames)),
+			zap.Int("valid", len(valid)),
+			zap.Int("skipped", len(skipped)))
+	}
+	return valid, skipped
 }
 
-// 发送文本消息（简化版）
-func (s *wxRobotService) SendText(robotAddress, authKey string, req *SendTextRequest) (*SendTextResponse, error) {
-	return s.apiClient.SendText(robotAddress, authKey, req)
-}
+// broadcastToGroup 向单个群发送群发内容
+func (s *wxRobotService) broadcastToGroup(ctx context.Context, req *BroadcastRequest, toUserName string, strategy MessageSendStrategy) BroadcastGroupResult {
+	result := BroadcastGroupResult{ToUserName: toUserName}
 
-// 发送图片消息（简化版）
-func (s *wxRobotService) SendImage(robotAddress, authKey string, req *SendImageRequest) (*SendImageResponse, error) {
-	return s.apiClient.SendImage(robotAddress, authKey, req)
-}
+	if err := s.CheckGroupBlacklist(toUserName); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err := s.CheckLargeGroup(toUserName, req.ConfirmLargeGroup); err != nil {
+		result.Error = err.Error()
+		return result
+	}
 
-// 同时发送文字和图片
-func (s *wxRobotService) SendTextAndImage(robotAddress, authKey string, req *SendTextAndImageRequest) (*SendTextAndImageResponse, error) {
-	return s.apiClient.SendTextAndImage(robotAddress, authKey, req)
+	botInfo, err := s.GetMessageBotByStrategy(toUserName, strategy)
+	if err != nil {
+		result.Error = "未找到对应的消息机器人"
+		return result
+	}
+	result.WxID = botInfo.User.WxID
+
+	dedupContent := req.TextContent + req.ImageContent
+	if err := s.CheckDuplicateSend(toUserName, dedupContent); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer func() {
+		if !result.Success {
+			s.ReleaseDuplicateSend(toUserName, dedupContent)
+		}
+	}()
+
+	resp, err := s.SendTextAndImage(withRobotTimeout(ctx, botInfo.Robot), botInfo.Robot.Address, botInfo.User.Token, &SendTextAndImageRequest{
+		TextContent:  req.TextContent,
+		ImageContent: req.ImageContent,
+		ToUserName:   toUserName,
+		Priority:     req.Priority,
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !resp.Success {
+		result.Error = resp.Message
+		return result
+	}
+
+	result.Success = true
+	return result
 }
 
 // 数据库操作方法
 
-// GetRobotList 获取机器人列表
-func (s *wxRobotService) GetRobotList() ([]WxRobotConfig, error) {
+// GetRobotList 获取机器人列表，支持按创建时间范围过滤和排序
+func (s *wxRobotService) GetRobotList(req RobotListRequest) ([]WxRobotConfig, error) {
+	query := s.db.Preload("UserLogins")
+	if req.CreateTimeStart != "" {
+		if start, err := ParseTime(req.CreateTimeStart); err == nil {
+			query = query.Where("create_time >= ?", start)
+		}
+	}
+	if req.CreateTimeEnd != "" {
+		if end, err := ParseTime(req.CreateTimeEnd); err == nil {
+			query = query.Where("create_time <= ?", end)
+		}
+	}
+	if req.OwnerID > 0 {
+		query = query.Where("owner_id = ?", req.OwnerID)
+	}
+
+	// 排序字段已在请求绑定时限定取值
+	sortBy := req.SortBy
+	if sortBy == "" {
+		sortBy = "id"
+	}
+	order := req.Order
+	if order == "" {
+		order = "asc"
+	}
+
 	var robots []WxRobotConfig
-	if err := s.db.Preload("UserLogins").Find(&robots).Error; err != nil {
+	if err := query.Order(sortBy + " " + order).Find(&robots).Error; err != nil {
 		s.logger.Error("查询机器人列表失败", zap.Error(err))
 		return nil, err
 	}
 	return robots, nil
 }
 
+// BatchSetRobotStatus 按所属公司或ID列表批量启用/禁用机器人，返回状态实际变化的数量，每个变化的机器人都记录配置变更
+func (s *wxRobotService) BatchSetRobotStatus(req *BatchRobotStatusRequest, operator RobotChangeOperator) (*BatchRobotStatusResponse, error) {
+	enabled := *req.Enabled
+	var affected int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("enabled <> ?", enabled)
+		if req.OwnerID > 0 {
+			query = query.Where("owner_id = ?", req.OwnerID)
+		}
+		if len(req.IDs) > 0 {
+			query = query.Where("id IN ?", req.IDs)
+		}
+
+		var robots []WxRobotConfig
+		if err := query.Find(&robots).Error; err != nil {
+			return err
+		}
+		if len(robots) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(robots))
+		var changes []WxRobotConfigChange
+		for i := range robots {
+			ids = append(ids, robots[i].ID)
+			after := robots[i]
+			after.Enabled = enabled
+			changes = append(changes, diffRobotConfig(&robots[i], &after, RobotChangeSourceBatchStatus, operator)...)
+		}
+		result := tx.Model(&WxRobotConfig{}).Where("id IN ?", ids).Update("enabled", enabled)
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		return s.recordRobotConfigChanges(tx, changes)
+	})
+	if err != nil {
+		s.logger.Error("批量更新机器人启用状态失败",
+			zap.Uint("owner_id", req.OwnerID),
+			zap.Uints("ids", req.IDs),
+			zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("批量更新机器人启用状态",
+		zap.Uint("owner_id", req.OwnerID),
+		zap.Uints("ids", req.IDs),
+		zap.Int("enabled", enabled),
+		zap.Int64("affected", affected))
+	return &BatchRobotStatusResponse{Enabled: enabled, Affected: affected}, nil
+}
+
+// GetIdleRobots 查询没有在线账号且超过days天无活动的机器人
+func (s *wxRobotService) GetIdleRobots(ownerID uint, days int) ([]IdleRobot, error) {
+	query := s.db.Preload("UserLogins")
+	if ownerID > 0 {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+
+	var robots []WxRobotConfig
+	if err := query.Find(&robots).Error; err != nil {
+		s.logger.Error("查询机器人列表失败", zap.Uint("owner_id", ownerID), zap.Error(err))
+		return nil, err
+	}
+
+	var wxIDs []string
+	for _, robot := range robots {
+		for _, user := range robot.UserLogins {
+			if user.WxID != "" {
+				wxIDs = append(wxIDs, user.WxID)
+			}
+		}
+	}
+
+	lastSend := make(map[string]time.Time)
+	if len(wxIDs) > 0 {
+		var rows []struct {
+			SenderWxID   string
+			LastSendTime dbTime
+		}
+		if err := s.db.Model(&WxSendAudit{}).
+			Select("sender_wx_id, MAX(create_time) AS last_send_time").
+			Where("sender_wx_id IN ?", wxIDs).
+			Group("sender_wx_id").
+			Scan(&rows).Error; err != nil {
+			s.logger.Error("查询账号最近发送时间失败", zap.Error(err))
+			return nil, err
+		}
+		for _, row := range rows {
+			lastSend[row.SenderWxID] = row.LastSendTime.Time
+		}
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	return filterIdleRobots(robots, lastSend, cutoff), nil
+}
+
+// CleanupIdleRobots 批量禁用或删除闲置机器人，执行前重新判断是否闲置，避免清理期间恢复使用的机器人被误删
+func (s *wxRobotService) CleanupIdleRobots(req *CleanupIdleRobotsRequest, operator RobotChangeOperator) (*CleanupIdleRobotsResponse, error) {
+	idle, err := s.GetIdleRobots(req.OwnerID, req.Days)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.IDs) > 0 {
+		wanted := make(map[uint]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			wanted[id] = true
+		}
+		selected := idle[:0]
+		for _, robot := range idle {
+			if wanted[robot.ID] {
+				selected = append(selected, robot)
+			}
+		}
+		idle = selected
+	}
+
+	resp := &CleanupIdleRobotsResponse{Action: req.Action, Confirmed: req.Confirm, Robots: idle}
+	if !req.Confirm || len(idle) == 0 {
+		return resp, nil
+	}
+
+	ids := make([]uint, 0, len(idle))
+	for _, robot := range idle {
+		ids = append(ids, robot.ID)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var robots []WxRobotConfig
+		if err := tx.Where("id IN ?", ids).Find(&robots).Error; err != nil {
+			return err
+		}
+
+		var changes []WxRobotConfigChange
+		switch req.Action {
+		case IdleRobotActionDisable:
+			result := tx.Model(&WxRobotConfig{}).Where("id IN ? AND enabled <> ?", ids, 0).Update("enabled", 0)
+			if result.Error != nil {
+				return result.Error
+			}
+			resp.Affected = result.RowsAffected
+			for i := range robots {
+				after := robots[i]
+				after.Enabled = 0
+				changes = append(changes, diffRobotConfig(&robots[i], &after, RobotChangeSourceIdleDisable, operator)...)
+			}
+		case IdleRobotActionDelete:
+			// 与DeleteUser一致，账号的状态变更日志随账号一起删除；机器人生成的授权码记录也一并删除
+			userIDs := tx.Model(&WxUserLogin{}).Select("id").Where("robot_id IN ?", ids)
+			if err := tx.Where("user_id IN (?)", userIDs).Delete(&WxUserStatusLog{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("robot_id IN ?", ids).Delete(&WxUserLogin{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("robot_id IN ?", ids).Delete(&WxAuthKey{}).Error; err != nil {
+				return err
+			}
+			result := tx.Where("id IN ?", ids).Delete(&WxRobotConfig{})
+			if result.Error != nil {
+				return result.Error
+			}
+			resp.Affected = result.RowsAffected
+			// 删除记录为各字段被清空，保留被删除机器人的最后配置
+			for i := range robots {
+				changes = append(changes, diffRobotConfig(&robots[i], &WxRobotConfig{}, RobotChangeSourceIdleDelete, operator)...)
+			}
+		}
+		return s.recordRobotConfigChanges(tx, changes)
+	})
+	if err != nil {
+		s.logger.Error("清理闲置机器人失败", zap.String("action", req.Action), zap.Uints("ids", ids), zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("清理闲置机器人",
+		zap.String("action", req.Action),
+		zap.Uint("owner_id", req.OwnerID),
+		zap.Int("days", req.Days),
+		zap.Uints("ids", ids),
+		zap.Int64("affected", resp.Affected))
+	return resp, nil
+}
+
 // CreateRobot 创建机器人配置
 func (s *wxRobotService) CreateRobot(robot *WxRobotConfig) error {
 	if err := s.db.Create(robot).Error; err != nil {
@@ -158,15 +1742,175 @@ func (s *wxRobotService) CreateRobot(robot *WxRobotConfig) error {
 	return nil
 }
 
-// UpdateRobot 更新机器人配置
-func (s *wxRobotService) UpdateRobot(robot *WxRobotConfig) error {
-	if err := s.db.Save(robot).Error; err != nil {
+// UpdateRobot 更新机器人配置，与修改前对比记录变更字段
+func (s *wxRobotService) UpdateRobot(robot *WxRobotConfig, operator RobotChangeOperator) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var before WxRobotConfig
+		if err := tx.First(&before, robot.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(robot).Error; err != nil {
+			return err
+		}
+		return s.recordRobotConfigChanges(tx, diffRobotConfig(&before, robot, RobotChangeSourceUpdate, operator))
+	})
+	if err != nil {
 		s.logger.Error("更新机器人配置失败", zap.Error(err))
 		return err
 	}
 	return nil
 }
 
+// recordRobotConfigChanges 写入机器人配置变更记录，与配置修改在同一事务中
+func (s *wxRobotService) recordRobotConfigChanges(tx *gorm.DB, changes []WxRobotConfigChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := tx.Create(&changes).Error; err != nil {
+		return fmt.Errorf("记录机器人配置变更失败: %w", err)
+	}
+
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+	s.logger.Info("机器人配置已变更",
+		zap.Uint("robot_id", changes[0].RobotID),
+		zap.String("source", changes[0].Source),
+		zap.Strings("fields", fields),
+		zap.Uint("operator_owner_id", changes[0].OperatorOwnerID),
+		zap.String("client_ip", changes[0].ClientIP))
+	return nil
+}
+
+// GetRobotConfigChanges 获取机器人配置变更历史，按时间倒序
+func (s *wxRobotService) GetRobotConfigChanges(robotID uint) ([]WxRobotConfigChange, error) {
+	var changes []WxRobotConfigChange
+	if err := s.db.Where("robot_id = ?", robotID).Order("id DESC").Find(&changes).Error; err != nil {
+		s.logger.Error("查询机器人配置变更历史失败", zap.Uint("robot_id", robotID), zap.Error(err))
+		return nil, err
+	}
+	return changes, nil
+}
+
+// PatchRobot 部分更新机器人配置，只更新请求中传入的字段，与修改前对比记录变更字段
+func (s *wxRobotService) PatchRobot(id uint, req *PatchRobotRequest, operator RobotChangeOperator) (*WxRobotConfig, error) {
+	var robot WxRobotConfig
+	if err := s.db.First(&robot, id).Error; err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Address != nil {
+		updates["address"] = *req.Address
+	}
+	if req.AdminKey != nil {
+		updates["admin_key"] = *req.AdminKey
+	}
+	if req.OwnerID != nil {
+		updates["owner_id"] = *req.OwnerID
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.AdminUsers != nil {
+		updates["admin_users"] = strings.Join(*req.AdminUsers, ",")
+	}
+	if req.TimeoutSeconds != nil {
+		updates["timeout_seconds"] = *req.TimeoutSeconds
+	}
+	if req.HealthPath != nil {
+		updates["health_path"] = normalizeHealthPath(*req.HealthPath)
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if req.Tags != nil {
+		updates["tags"] = joinRobotTags(*req.Tags)
+	}
+
+	before := robot
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&robot).Updates(updates).Error; err != nil {
+			return err
+		}
+		// 重新读取，返回更新后的完整配置
+		if err := tx.First(&robot, id).Error; err != nil {
+			return err
+		}
+		return s.recordRobotConfigChanges(tx, diffRobotConfig(&before, &robot, RobotChangeSourcePatch, operator))
+	})
+	if err != nil {
+		s.logger.Error("部分更新机器人配置失败", zap.Uint("robot_id", id), zap.Error(err))
+		return nil, err
+	}
+	return &robot, nil
+}
+
+// ErrAdminKeyUnchanged 新管理密钥与当前密钥相同
+var ErrAdminKeyUnchanged = errors.New("新管理密钥与当前密钥相同")
+
+// ErrAdminKeyVerifyFailed 新管理密钥验证失败
+var ErrAdminKeyVerifyFailed = errors.New("新管理密钥验证失败")
+
+// RotateAdminKey 轮换机器人管理密钥：先用新密钥调用底层生成授权码接口验证可用，
+// 验证通过后才写入数据库，验证失败时保留原密钥不变
+// 底层不提供生成管理密钥的接口，新密钥需先在底层服务配置好；
+// 底层也没有无副作用的管理密钥校验接口，验证生成的授权码是真实可用的，按rotate_probe来源记录到授权码表
+func (s *wxRobotService) RotateAdminKey(ctx context.Context, id uint, newAdminKey string, operator RobotChangeOperator) (*RotateAdminKeyResponse, error) {
+	robot, err := s.GetRobotByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if robot.AdminKey == newAdminKey {
+		return nil, ErrAdminKeyUnchanged
+	}
+
+	// 生成一个1天有效期的授权码验证新密钥，与手动生成的授权码一样记录，可在授权码列表中查看
+	if _, err := s.genAuthKey(ctx, robot, newAdminKey, 1, 1, AuthKeySourceRotateProbe); err != nil {
+		s.logger.Warn("新管理密钥验证失败，保留原密钥",
+			zap.Uint("robot_id", id),
+			zap.String("admin_key", maskToken(newAdminKey)),
+			zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrAdminKeyVerifyFailed, err)
+	}
+
+	errKeyChanged := errors.New("管理密钥已被修改，请重新获取后再轮换")
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&WxRobotConfig{}).
+			Where("id = ? AND admin_key = ?", id, robot.AdminKey).
+			Update("admin_key", newAdminKey)
+		if result.Error != nil {
+			return result.Error
+		}
+		// 验证期间密钥已被其他请求修改，放弃本次轮换
+		if result.RowsAffected == 0 {
+			return errKeyChanged
+		}
+		after := *robot
+		after.AdminKey = newAdminKey
+		return s.recordRobotConfigChanges(tx, diffRobotConfig(robot, &after, RobotChangeSourceRotateKey, operator))
+	})
+	if errors.Is(err, errKeyChanged) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Error("保存新管理密钥失败", zap.Uint("robot_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("机器人管理密钥已轮换",
+		zap.Uint("robot_id", id),
+		zap.String("old_admin_key", maskToken(robot.AdminKey)),
+		zap.String("new_admin_key", maskToken(newAdminKey)))
+
+	return &RotateAdminKeyResponse{
+		RobotID:   id,
+		AdminKey:  maskToken(newAdminKey),
+		RotatedAt: FormatTime(time.Now()),
+	}, nil
+}
+
 // GetUsersByRobot 获取指定机器人的用户列表
 func (s *wxRobotService) GetUsersByRobot(robotId string) ([]WxUserLogin, error) {
 	var users []WxUserLogin
@@ -186,46 +1930,239 @@ func (s *wxRobotService) GetRobotByID(id uint) (*WxRobotConfig, error) {
 	return &robot, nil
 }
 
+// GetRobotSummary 汇总机器人下挂载用户的状态分布与消息机器人情况
+func (s *wxRobotService) GetRobotSummary(id uint) (*RobotSummaryResponse, error) {
+	robot, err := s.GetRobotByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []WxUserLogin
+	if err := s.db.Select("status", "has_security_risk", "is_initialized", "is_message_bot").
+		Where("robot_id = ?", id).Find(&users).Error; err != nil {
+		s.logger.Error("查询机器人用户失败", zap.Uint("robot_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	summary := &RobotSummaryResponse{
+		RobotID:     robot.ID,
+		Address:     robot.Address,
+		Description: robot.Description,
+		TotalUsers:  int64(len(users)),
+	}
+	for _, user := range users {
+		switch user.Status {
+		case UserStatusNormal:
+			summary.NormalUsers++
+		case UserStatusRisk:
+			summary.RiskUsers++
+		case UserStatusRelogin:
+			summary.ReloginUsers++
+		}
+		if user.HasSecurityRisk == 1 {
+			summary.SecurityRisk++
+		}
+		if user.IsInitialized == 1 {
+			summary.Initialized++
+		}
+		if user.IsMessageBot == 1 {
+			summary.MessageBots++
+			if user.Status == UserStatusNormal {
+				summary.HasMessageBot = true
+			}
+		}
+	}
+
+	return summary, nil
+}
+
 // GetUserByID 根据ID获取用户信息
 func (s *wxRobotService) GetUserByID(id uint) (*WxUserLogin, error) {
 	var user WxUserLogin
 	if err := s.db.First(&user, id).Error; err != nil {
 		return nil, err
 	}
-	return &user, nil
+	return &user, nil
+}
+
+// GetUserByToken 按token反查用户及所属机器人，未找到时返回gorm.ErrRecordNotFound
+func (s *wxRobotService) GetUserByToken(token string) (*UserByTokenResponse, error) {
+	var user WxUserLogin
+	if err := s.db.Where("token = ?", token).First(&user).Error; err != nil {
+		s.logger.Info("按token反查用户未找到", zap.String("token", maskToken(token)), zap.Error(err))
+		return nil, err
+	}
+
+	resp := &UserByTokenResponse{
+		UserID:         user.ID,
+		WxID:           user.WxID,
+		NickName:       user.NickName,
+		Token:          maskToken(user.Token),
+		Status:         user.Status,
+		IsMessageBot:   user.IsMessageBot,
+		ExpirationTime: FormatTime(user.ExpirationTime),
+		RobotID:        user.RobotID,
+	}
+
+	// 机器人可能已被删除，只返回用户信息
+	var robot WxRobotConfig
+	if err := s.db.First(&robot, user.RobotID).Error; err == nil {
+		resp.RobotAddress = robot.Address
+		resp.RobotDescription = robot.Description
+		resp.OwnerID = robot.OwnerID
+	} else {
+		s.logger.Warn("用户关联的机器人不存在", zap.Uint("user_id", user.ID), zap.Uint("robot_id", user.RobotID))
+	}
+
+	return resp, nil
+}
+
+// GetUserAuthInfo 获取用户授权概览（数据库字段 + 实时登录状态）
+func (s *wxRobotService) GetUserAuthInfo(ctx context.Context, id uint) (*UserAuthInfoResponse, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &UserAuthInfoResponse{
+		UserID:          user.ID,
+		RobotID:         user.RobotID,
+		WxID:            user.WxID,
+		NickName:        user.NickName,
+		Remark:          user.Remark,
+		Token:           maskToken(user.Token),
+		AuthCreateTime:  FormatTime(user.CreateTime),
+		ExtensionTime:   FormatTime(user.ExtensionTime),
+		ExpirationTime:  FormatTime(user.ExpirationTime),
+		RemainingDays:   int(time.Until(user.ExpirationTime).Hours() / 24),
+		Status:          user.Status,
+		HasSecurityRisk: user.HasSecurityRisk,
+		IsInitialized:   user.IsInitialized,
+	}
+
+	extensions, err := s.GetUserExtensions(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	info.Extensions = extensions
+
+	// 实时登录状态查询失败不影响数据库部分的返回
+	robot, err := s.GetRobotByID(user.RobotID)
+	if err != nil {
+		info.LoginStatusError = "关联的机器人不存在"
+		return info, nil
+	}
+
+	// 与登录状态接口走同一路径，token过期时同样标记用户需要重新登录
+	statusResp, err := s.GetLoginStatus(withRobotTimeout(ctx, robot), robot.Address, user.Token)
+	if err != nil {
+		s.logger.Warn("查询实时登录状态失败", zap.Uint("user_id", user.ID), zap.Error(err))
+		info.LoginStatusError = err.Error()
+		return info, nil
+	}
+
+	info.LoginStatus = &UserLoginStatusInfo{
+		LoginState:  statusResp.Data.LoginState,
+		LoginTime:   statusResp.Data.LoginTime,
+		OnlineTime:  statusResp.Data.OnlineTime,
+		OnlineDays:  statusResp.Data.OnlineDays,
+		ExpiryTime:  statusResp.Data.ExpiryTime,
+		LoginErrMsg: statusResp.Data.LoginErrMsg,
+	}
+
+	return info, nil
+}
+
+// ErrUserNotCallable 用户token为空或状态不正常，不能调用需要token的外部接口
+var ErrUserNotCallable = errors.New("用户不可调用外部接口")
+
+// checkUserCallable 调用需要token的外部接口前校验用户：token非空且状态正常
+func checkUserCallable(user *WxUserLogin) error {
+	if strings.TrimSpace(user.Token) == "" {
+		return fmt.Errorf("%w: token为空", ErrUserNotCallable)
+	}
+	if user.Status != UserStatusNormal {
+		return fmt.Errorf("%w: 用户状态为%d", ErrUserNotCallable, user.Status)
+	}
+	return nil
+}
+
+// maskToken token脱敏，仅保留首尾各4位
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return strings.Repeat("*", len(token))
+	}
+	return token[:4] + strings.Repeat("*", len(token)-8) + token[len(token)-4:]
 }
 
-// SaveUser 保存用户登录信息（saveOrUpdate逻辑：先更新，不存在则创建）
+// SaveUser 保存用户登录信息（upsert：不存在则创建，已存在则更新）
 func (s *wxRobotService) SaveUser(user *WxUserLogin) error {
-	// 先尝试查找现有记录，基于robot_id和wx_id的组合
-	var existingUser WxUserLogin
-	err := s.db.Where("robot_id = ? AND wx_id = ?", user.RobotID, user.WxID).First(&existingUser).Error
+	// 登录后进入未初始化状态，记录开始时间用于检测初始化超时
+	if user.IsInitialized == 0 {
+		now := time.Now()
+		user.InitStartTime = &now
+	}
+
+	// (robot_id, wx_id)有唯一索引，扫码后前端可能重复提交：
+	// 先插入，已存在时不做修改；再对已有记录加行锁更新，并发提交按顺序执行，只保留一条记录
+	created := false
+	err := WithTransaction(s.db, func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(user)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			created = true
+			return nil
+		}
+
+		var existingUser WxUserLogin
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("robot_id = ? AND wx_id = ?", user.RobotID, user.WxID).
+			First(&existingUser).Error; err != nil {
+			return err
+		}
 
-	if err == nil {
-		// 记录存在，执行更新操作
 		// 保留原有的ID和创建时间
 		user.ID = existingUser.ID
 		user.CreateTime = existingUser.CreateTime
+		if user.Remark == "" {
+			user.Remark = existingUser.Remark // 重新登录时保留运营备注
+		}
+		if user.Signature == "" {
+			user.Signature = existingUser.Signature // 重新登录时保留签名
+			user.SignaturePos = existingUser.SignaturePos
+		}
+		user.AutoRenew = existingUser.AutoRenew // 重新登录时保留自动续期设置
 		user.UpdateTime = time.Now()
+		if user.IsInitialized == 1 {
+			user.InitStartTime = existingUser.InitStartTime
+		}
 
-		if err := s.db.Save(user).Error; err != nil {
-			s.logger.Error("更新用户登录信息失败", zap.Error(err))
+		// 重新登录等场景会改变用户状态，与用户信息在同一事务中记录变更日志
+		if err := tx.Save(user).Error; err != nil {
 			return err
 		}
-		s.logger.Info("用户登录信息已更新", zap.String("wxid", user.WxID), zap.String("nickname", user.NickName))
-	} else if errors.Is(err, gorm.ErrRecordNotFound) {
-		// 记录不存在，创建新记录
-		if err := s.db.Create(user).Error; err != nil {
-			s.logger.Error("创建用户登录信息失败", zap.Error(err))
-			return err
+		if existingUser.Status == user.Status {
+			return nil
 		}
-		s.logger.Info("用户登录成功", zap.String("wxid", user.WxID), zap.String("nickname", user.NickName))
-	} else {
-		// 其他数据库错误
-		s.logger.Error("查询用户记录失败", zap.Error(err))
+		return tx.Create(&WxUserStatusLog{
+			UserID:    user.ID,
+			OldStatus: existingUser.Status,
+			NewStatus: user.Status,
+			Reason:    "保存用户登录信息",
+		}).Error
+	})
+	if err != nil {
+		s.logger.Error("保存用户登录信息失败", zap.String("wxid", user.WxID), zap.Error(err))
 		return err
 	}
 
+	if created {
+		s.logger.Info("用户登录成功", zap.String("wxid", user.WxID), zap.String("nickname", user.NickName))
+	} else {
+		s.logger.Info("用户登录信息已更新", zap.String("wxid", user.WxID), zap.String("nickname", user.NickName))
+	}
 	return nil
 }
 
@@ -238,8 +2175,14 @@ func (s *wxRobotService) DeleteUser(id string) error {
 		return err
 	}
 
-	// 删除用户记录（不删除群组信息，因为群组可能被其他用户使用）
-	if err := s.db.Delete(&WxUserLogin{}, id).Error; err != nil {
+	// 删除用户记录及其状态变更日志（不删除群组信息，因为群组可能被其他用户使用）
+	err := WithTransaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&WxUserStatusLog{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&WxUserLogin{}, user.ID).Error
+	})
+	if err != nil {
 		s.logger.Error("删除用户失败", zap.Error(err))
 		return err
 	}
@@ -248,13 +2191,193 @@ func (s *wxRobotService) DeleteUser(id string) error {
 	return nil
 }
 
-// UpdateUserExtension 更新用户延期时间
-func (s *wxRobotService) UpdateUserExtension(robotId uint, token string, newExpiry time.Time) error {
+// UpdateUserExtension 更新用户延期时间，并在同一事务中记录延期记录
+func (s *wxRobotService) UpdateUserExtension(robotId uint, token string, days int, newExpiry time.Time, source string) error {
 	var user WxUserLogin
-	if err := s.db.Where("robot_id = ? AND token = ?", robotId, token).First(&user).Error; err == nil {
-		user.ExtensionTime = newExpiry
-		user.ExpirationTime = newExpiry
-		return s.db.Save(&user).Error
+	if err := s.db.Where("robot_id = ? AND token = ?", robotId, token).First(&user).Error; err != nil {
+		return nil
+	}
+
+	extension := &WxUserExtensionLog{
+		UserID:            user.ID,
+		RobotID:           robotId,
+		Days:              days,
+		OldExpirationTime: user.ExpirationTime,
+		NewExpirationTime: newExpiry,
+		Source:            source,
+	}
+	user.ExtensionTime = newExpiry
+	user.ExpirationTime = newExpiry
+
+	err := WithTransaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(extension).Error
+	})
+	if err != nil {
+		s.logger.Error("更新用户延期时间失败", zap.Uint("user_id", user.ID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetUserExtensions 查询用户的延期记录，按时间倒序
+func (s *wxRobotService) GetUserExtensions(userID uint) ([]WxUserExtensionLog, error) {
+	var extensions []WxUserExtensionLog
+	if err := s.db.Where("user_id = ?", userID).Order("create_time DESC, id DESC").Find(&extensions).Error; err != nil {
+		s.logger.Error("查询用户延期记录失败", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return extensions, nil
+}
+
+// BatchExtendAuth 批量延期授权，按 owner_id / robot_id 筛选用户，单个用户失败不影响其他用户
+func (s *wxRobotService) BatchExtendAuth(ctx context.Context, req *BatchExtendAuthRequest) (*BatchExtendAuthResponse, error) {
+	query := s.db.Model(&WxUserLogin{}).
+		Joins("JOIN wx_robot_configs r ON r.id = wx_user_logins.robot_id").
+		Where("wx_user_logins.token <> ''")
+	if req.RobotID > 0 {
+		query = query.Where("wx_user_logins.robot_id = ?", req.RobotID)
+	}
+	if req.OwnerID > 0 {
+		query = query.Where("r.owner_id = ?", req.OwnerID)
+	}
+	if req.WithinDays > 0 {
+		query = query.Where("wx_user_logins.expiration_time <= ?", time.Now().AddDate(0, 0, req.WithinDays))
+	}
+
+	var users []WxUserLogin
+	if err := query.Select("wx_user_logins.*").Find(&users).Error; err != nil {
+		s.logger.Error("查询待延期用户失败", zap.Error(err))
+		return nil, err
+	}
+
+	resp := s.extendUsersAuth(ctx, users, req.Days, ExtensionSourceBatch)
+	s.logger.Info("批量延期授权完成",
+		zap.Uint("owner_id", req.OwnerID),
+		zap.Uint("robot_id", req.RobotID),
+		zap.Int("total", resp.Total),
+		zap.Int("success", resp.Success),
+		zap.Int("failed", resp.Failed))
+	return resp, nil
+}
+
+// extendUsersAuth 逐个延期用户授权，单个用户失败不影响其他用户
+func (s *wxRobotService) extendUsersAuth(ctx context.Context, users []WxUserLogin, days int, source string) *BatchExtendAuthResponse {
+	resp := &BatchExtendAuthResponse{
+		Total:   len(users),
+		Results: make([]BatchExtendAuthResult, 0, len(users)),
+	}
+
+	robots := make(map[uint]*WxRobotConfig)
+	for _, user := range users {
+		result := BatchExtendAuthResult{
+			UserID:   user.ID,
+			RobotID:  user.RobotID,
+			WxID:     user.WxID,
+			NickName: user.NickName,
+		}
+
+		robot, ok := robots[user.RobotID]
+		if !ok {
+			robot, _ = s.GetRobotByID(user.RobotID)
+			robots[user.RobotID] = robot
+		}
+
+		if robot == nil {
+			result.Error = "关联的机器人不存在"
+		} else if err := s.extendUserAuth(ctx, robot, &user, days, source, &result); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+		}
+
+		if result.Success {
+			resp.Success++
+		} else {
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp
+}
+
+// GetExpiringUsers 查询withinDays天内到期（含已过期）且token不为空的用户，autoRenew筛选是否开启自动续期
+func (s *wxRobotService) GetExpiringUsers(withinDays int, autoRenew int) ([]WxUserLogin, error) {
+	var users []WxUserLogin
+	if err := s.db.Where("token <> '' AND auto_renew = ? AND expiration_time <= ?",
+		autoRenew, time.Now().AddDate(0, 0, withinDays)).
+		Find(&users).Error; err != nil {
+		s.logger.Error("查询即将到期用户失败", zap.Error(err))
+		return nil, err
+	}
+	return users, nil
+}
+
+// RenewExpiringUsers 对开启自动续期且withinDays天内到期的用户延期days天，并逐个发送续期结果通知
+func (s *wxRobotService) RenewExpiringUsers(ctx context.Context, withinDays, days int) (*BatchExtendAuthResponse, error) {
+	users, err := s.GetExpiringUsers(withinDays, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := s.extendUsersAuth(ctx, users, days, ExtensionSourceAutoRenew)
+	for _, result := range resp.Results {
+		event := EventAuthRenewed
+		message := fmt.Sprintf("用户授权已自动续期%d天", days)
+		if !result.Success {
+			event = EventAuthRenewFailed
+			message = "用户授权自动续期失败: " + result.Error
+		}
+		s.notifier.Notify(s.eventURL, EventCallbackPayload{
+			Event:     event,
+			Message:   message,
+			Data:      result,
+			Timestamp: time.Now().Unix(),
+		})
+	}
+	return resp, nil
+}
+
+// NotifyAuthExpiring 发送用户授权即将到期通知
+func (s *wxRobotService) NotifyAuthExpiring(user WxUserLogin) {
+	remainingDays := int(time.Until(user.ExpirationTime).Hours() / 24)
+	message := fmt.Sprintf("用户授权剩余%d天到期", remainingDays)
+	if time.Now().After(user.ExpirationTime) {
+		message = "用户授权已过期"
+	}
+	s.notifier.Notify(s.eventURL, EventCallbackPayload{
+		Event:   EventAuthExpiring,
+		Message: message,
+		Data: map[string]interface{}{
+			"user_id":         user.ID,
+			"wx_id":           user.WxID,
+			"nick_name":       user.NickName,
+			"robot_id":        user.RobotID,
+			"expiration_time": FormatTime(user.ExpirationTime),
+			"remaining_days":  remainingDays,
+		},
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// extendUserAuth 调用外部接口延期单个用户并更新数据库中的到期时间
+func (s *wxRobotService) extendUserAuth(ctx context.Context, robot *WxRobotConfig, user *WxUserLogin, days int, source string, result *BatchExtendAuthResult) error {
+	extendResp, err := s.apiClient.DelayAuthKey(withRobotTimeout(ctx, robot), robot.Address, robot.AdminKey, user.Token, days)
+	if err != nil {
+		s.logger.Warn("用户延期授权失败", zap.Uint("user_id", user.ID), zap.Error(err))
+		return err
+	}
+
+	result.ExpiryDate = extendResp.Data.ExpiryDate
+	newExpiry, err := time.Parse("2006-01-02", extendResp.Data.ExpiryDate)
+	if err != nil {
+		return fmt.Errorf("延期成功但到期日期解析失败: %s", extendResp.Data.ExpiryDate)
+	}
+
+	if err := s.UpdateUserExtension(robot.ID, user.Token, days, newExpiry, source); err != nil {
+		return fmt.Errorf("延期成功但更新到期时间失败: %w", err)
 	}
 	return nil
 }
@@ -306,9 +2429,12 @@ func (s *wxRobotService) SaveOrUpdateGroup(group *WxGroup) error {
 			zap.String("group_id", group.GroupID),
 			zap.String("group_nick_name", group.GroupNickName))
 	} else {
-		// 群已存在，更新昵称（如果有变化）
-		if existing.GroupNickName != group.GroupNickName {
+		// 群已存在，更新昵称、成员数和群主（如果有变化）
+		if existing.GroupNickName != group.GroupNickName || existing.MemberCount != group.MemberCount ||
+			existing.ChatRoomOwner != group.ChatRoomOwner {
 			existing.GroupNickName = group.GroupNickName
+			existing.MemberCount = group.MemberCount
+			existing.ChatRoomOwner = group.ChatRoomOwner
 			if err := s.db.Save(&existing).Error; err != nil {
 				s.logger.Error("更新群记录失败", zap.Error(err))
 				return err
@@ -316,7 +2442,8 @@ func (s *wxRobotService) SaveOrUpdateGroup(group *WxGroup) error {
 			s.logger.Debug("成功更新群记录",
 				zap.String("wx_id", group.WxID),
 				zap.String("group_id", group.GroupID),
-				zap.String("group_nick_name", group.GroupNickName))
+				zap.String("group_nick_name", group.GroupNickName),
+				zap.Int("member_count", group.MemberCount))
 		}
 	}
 	return nil
@@ -324,34 +2451,110 @@ func (s *wxRobotService) SaveOrUpdateGroup(group *WxGroup) error {
 
 // DeleteGroupsByWxIDNotInList 删除数据库中存在但群列表中没有的群
 func (s *wxRobotService) DeleteGroupsByWxIDNotInList(wxID string, groupIDs []string) error {
-	if len(groupIDs) == 0 {
-		// 如果群列表为空，删除该用户的所有群
-		result := s.db.Where("wx_id = ?", wxID).Delete(&WxGroup{})
-		if result.Error != nil {
-			s.logger.Error("删除用户所有群记录失败", zap.String("wx_id", wxID), zap.Error(result.Error))
-			return result.Error
-		}
-		if result.RowsAffected > 0 {
-			s.logger.Info("删除用户所有群记录", zap.String("wx_id", wxID), zap.Int64("count", result.RowsAffected))
-		}
+	query := s.db.Where("wx_id = ?", wxID)
+	if len(groupIDs) > 0 {
+		query = query.Where("group_id NOT IN ?", groupIDs)
+	}
+
+	var staleGroups []WxGroup
+	if err := query.Find(&staleGroups).Error; err != nil {
+		s.logger.Error("查询待删除群记录失败", zap.String("wx_id", wxID), zap.Error(err))
+		return err
+	}
+	if len(staleGroups) == 0 {
 		return nil
 	}
 
-	result := s.db.Where("wx_id = ? AND group_id NOT IN ?", wxID, groupIDs).Delete(&WxGroup{})
-	if result.Error != nil {
-		s.logger.Error("删除群记录失败", zap.String("wx_id", wxID), zap.Error(result.Error))
-		return result.Error
+	// 删除群记录的同时写入退群记录，用于流失群统计
+	now := time.Now()
+	leaveLogs := make([]WxGroupLeaveLog, 0, len(staleGroups))
+	staleIDs := make([]uint, 0, len(staleGroups))
+	for _, g := range staleGroups {
+		staleIDs = append(staleIDs, g.ID)
+		leaveLogs = append(leaveLogs, WxGroupLeaveLog{
+			WxID:          g.WxID,
+			GroupID:       g.GroupID,
+			GroupNickName: g.GroupNickName,
+			JoinTime:      g.CreateTime,
+			LeaveTime:     now,
+		})
+	}
+
+	err := WithTransaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Create(&leaveLogs).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&WxGroup{}, staleIDs).Error
+	})
+	if err != nil {
+		s.logger.Error("删除群记录失败", zap.String("wx_id", wxID), zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("删除过期群记录",
+		zap.String("wx_id", wxID),
+		zap.Int("count", len(staleGroups)))
+
+	return nil
+}
+
+// GetGroupChanges 查询时间段内新增和流失的群，ownerID不为0时只查询该公司的账号
+func (s *wxRobotService) GetGroupChanges(wxID string, ownerID uint, start, end time.Time) (*GroupChangesResponse, error) {
+	resp := &GroupChangesResponse{
+		Added: []WxGroup{},
+		Lost:  []WxGroupLeaveLog{},
+	}
+
+	addedQuery := s.db.Where("create_time >= ? AND create_time <= ?", start, end)
+	lostQuery := s.db.Where("leave_time >= ? AND leave_time <= ?", start, end)
+	if wxID != "" {
+		addedQuery = addedQuery.Where("wx_id = ?", wxID)
+		lostQuery = lostQuery.Where("wx_id = ?", wxID)
+	}
+	if ownerID > 0 {
+		addedQuery = addedQuery.Where("wx_id IN (?)", s.ownerWxIDs(ownerID))
+		lostQuery = lostQuery.Where("wx_id IN (?)", s.ownerWxIDs(ownerID))
 	}
 
-	if result.RowsAffected > 0 {
-		s.logger.Info("删除过期群记录",
-			zap.String("wx_id", wxID),
-			zap.Int64("count", result.RowsAffected))
+	if err := addedQuery.Order("create_time DESC").Find(&resp.Added).Error; err != nil {
+		s.logger.Error("查询新增群失败", zap.Error(err))
+		return nil, err
+	}
+	if err := lostQuery.Order("leave_time DESC").Find(&resp.Lost).Error; err != nil {
+		s.logger.Error("查询流失群失败", zap.Error(err))
+		return nil, err
 	}
+	return resp, nil
+}
 
+// SaveGroupMembers 全量替换群成员列表
+func (s *wxRobotService) SaveGroupMembers(groupID string, members []WxGroupMember) error {
+	err := WithTransaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", groupID).Delete(&WxGroupMember{}).Error; err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(members, 200).Error
+	})
+	if err != nil {
+		s.logger.Error("保存群成员失败", zap.String("group_id", groupID), zap.Error(err))
+		return err
+	}
 	return nil
 }
 
+// GetGroupMembers 获取群成员列表
+func (s *wxRobotService) GetGroupMembers(groupID string) ([]WxGroupMember, error) {
+	var members []WxGroupMember
+	if err := s.db.Where("group_id = ?", groupID).Find(&members).Error; err != nil {
+		s.logger.Error("查询群成员失败", zap.String("group_id", groupID), zap.Error(err))
+		return nil, err
+	}
+	return members, nil
+}
+
 // GetGroupsByWxID 获取用户的群列表
 func (s *wxRobotService) GetGroupsByWxID(wxID string) ([]WxGroup, error) {
 	var groups []WxGroup
@@ -359,40 +2562,358 @@ func (s *wxRobotService) GetGroupsByWxID(wxID string) ([]WxGroup, error) {
 		s.logger.Error("查询用户群列表失败", zap.String("wx_id", wxID), zap.Error(err))
 		return nil, err
 	}
-	return groups, nil
+	return groups, nil
+}
+
+// SearchGroupsByName 按群名称模糊搜索群组，ownerID不为0时只返回该公司账号所在的群
+func (s *wxRobotService) SearchGroupsByName(groupNickName string, ownerID uint) ([]WxGroup, error) {
+	var groups []WxGroup
+	query := s.db.Where("group_nick_name LIKE ?", "%"+groupNickName+"%")
+	if ownerID > 0 {
+		query = query.Where("wx_id IN (?)", s.ownerWxIDs(ownerID))
+	}
+	if err := query.Find(&groups).Error; err != nil {
+		s.logger.Error("按群名称搜索群组失败", zap.String("group_nick_name", groupNickName), zap.Error(err))
+		return nil, err
+	}
+	return groups, nil
+}
+
+// ExportGroups 按条件逐行读取群组并交给fn处理，用于流式导出，不一次性加载全部数据
+func (s *wxRobotService) ExportGroups(req GroupExportRequest, fn func(group *WxGroup) error) error {
+	query := s.db.Model(&WxGroup{})
+	if req.WxID != "" {
+		query = query.Where("wx_id = ?", req.WxID)
+	}
+	if req.OwnerID > 0 {
+		query = query.Where("wx_id IN (?)", s.db.Table("wx_user_logins u").
+			Select("u.wx_id").
+			Joins("JOIN wx_robot_configs r ON r.id = u.robot_id").
+			Where("r.owner_id = ?", req.OwnerID))
+	}
+
+	rows, err := query.Order("wx_id, id").Rows()
+	if err != nil {
+		s.logger.Error("查询导出群组失败", zap.Error(err))
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var group WxGroup
+		if err := s.db.ScanRows(rows, &group); err != nil {
+			s.logger.Error("读取导出群组失败", zap.Error(err))
+			return err
+		}
+		if err := fn(&group); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ListGroups 分页查询全部群组，同一群按group_id合并并返回覆盖该群的账号
+func (s *wxRobotService) ListGroups(req GroupListRequest) (*GroupListPaginatedResponse, error) {
+	// 统计与分页查询的条件相同，但select不同，分别构建避免互相影响
+	newQuery := func() *gorm.DB {
+		query := s.db.Table("wx_groups g")
+		if req.GroupNickName != "" {
+			query = query.Where("g.group_nick_name LIKE ?", "%"+req.GroupNickName+"%")
+		}
+		if req.OwnerID > 0 {
+			query = query.Joins("JOIN wx_user_logins u ON u.wx_id = g.wx_id").
+				Joins("JOIN wx_robot_configs r ON r.id = u.robot_id").
+				Where("r.owner_id = ?", req.OwnerID)
+		}
+		return query
+	}
+
+	var totalCount int64
+	if err := newQuery().Distinct("g.group_id").Count(&totalCount).Error; err != nil {
+		s.logger.Error("统计群组数量失败", zap.Error(err))
+		return nil, err
+	}
+
+	var rows []struct {
+		GroupID       string
+		GroupNickName string
+		WxIDs         string
+		CreateTime    dbTime
+	}
+	offset := (req.PageNo - 1) * req.PageSize
+	err := newQuery().Select(`g.group_id, MAX(g.group_nick_name) AS group_nick_name,
+			GROUP_CONCAT(DISTINCT g.wx_id ORDER BY g.wx_id) AS wx_ids, MIN(g.create_time) AS create_time`).
+		Group("g.group_id").
+		Order("create_time DESC").
+		Offset(offset).Limit(req.PageSize).
+		Scan(&rows).Error
+	if err != nil {
+		s.logger.Error("分页查询群组失败", zap.Error(err))
+		return nil, err
+	}
+
+	list := make([]GroupOverview, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, GroupOverview{
+			GroupID:       row.GroupID,
+			GroupNickName: row.GroupNickName,
+			WxIDs:         strings.Split(row.WxIDs, ","),
+			CreateTime:    FormatTime(row.CreateTime.Time),
+		})
+	}
+
+	totalPages := int((totalCount + int64(req.PageSize) - 1) / int64(req.PageSize))
+	return &GroupListPaginatedResponse{
+		List: list,
+		Pagination: PaginationInfo{
+			PageNo:     req.PageNo,
+			PageSize:   req.PageSize,
+			TotalCount: totalCount,
+			TotalPages: totalPages,
+			HasNext:    req.PageNo < totalPages,
+			HasPrev:    req.PageNo > 1,
+		},
+	}, nil
+}
+
+// GetActiveUsers 获取状态为1的用户列表
+func (s *wxRobotService) GetActiveUsers() ([]WxUserLogin, error) {
+	var users []WxUserLogin
+	if err := s.db.Where("status = ?", 1).Find(&users).Error; err != nil {
+		s.logger.Error("查询活跃用户失败", zap.Error(err))
+		return nil, err
+	}
+	return users, nil
+}
+
+// UpdateUserStatus 更新用户状态，状态发生变化时在同一事务中记录变更日志
+func (s *wxRobotService) UpdateUserStatus(userID uint, status int, reason string) error {
+	err := WithTransaction(s.db, func(tx *gorm.DB) error {
+		var user WxUserLogin
+		if err := tx.Select("id", "status").First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.Status == status {
+			return nil
+		}
+
+		if err := tx.Model(&WxUserLogin{}).Where("id = ?", userID).Update("status", status).Error; err != nil {
+			return err
+		}
+		return tx.Create(&WxUserStatusLog{
+			UserID:    userID,
+			OldStatus: user.Status,
+			NewStatus: status,
+			Reason:    reason,
+		}).Error
+	})
+	if err != nil {
+		s.logger.Error("更新用户状态失败", zap.Uint("user_id", userID), zap.Int("status", status), zap.Error(err))
+		return err
+	}
+	s.logger.Info("用户状态更新完成", zap.Uint("user_id", userID), zap.Int("status", status), zap.String("reason", reason))
+	return nil
+}
+
+// HandleInitTimeout 用户初始化超时：发送事件通知，relogin为true时标记为需要重新登录
+func (s *wxRobotService) HandleInitTimeout(user WxUserLogin, elapsed time.Duration, relogin bool) error {
+	s.logger.Warn("用户初始化超时",
+		zap.Uint("user_id", user.ID),
+		zap.String("wx_id", user.WxID),
+		zap.Duration("elapsed", elapsed),
+		zap.Bool("relogin", relogin))
+
+	message := fmt.Sprintf("用户初始化超时，已等待%s", elapsed.Truncate(time.Second))
+	if relogin {
+		if err := s.UpdateUserStatus(user.ID, UserStatusRelogin, message); err != nil {
+			return err
+		}
+		message += "，已标记为需要重新登录"
+	}
+
+	s.notifier.Notify(s.eventURL, EventCallbackPayload{
+		Event:   EventInitTimeout,
+		Message: message,
+		Data: map[string]interface{}{
+			"user_id":         user.ID,
+			"wx_id":           user.WxID,
+			"nick_name":       user.NickName,
+			"robot_id":        user.RobotID,
+			"init_start_time": FormatTime(*user.InitStartTime),
+			"relogin":         relogin,
+		},
+		Timestamp: time.Now().Unix(),
+	})
+	return nil
+}
+
+// SearchUsers 按 wx_id 或昵称模糊搜索用户，支持所属公司、状态过滤和分页
+func (s *wxRobotService) SearchUsers(req UserSearchRequest) (*UserSearchPaginatedResponse, error) {
+	keyword := "%" + req.Keyword + "%"
+	query := s.db.Model(&WxUserLogin{}).
+		Where("(wx_user_logins.wx_id LIKE ? OR wx_user_logins.nick_name LIKE ?)", keyword, keyword)
+	if req.OwnerID > 0 {
+		query = query.Joins("JOIN wx_robot_configs r ON r.id = wx_user_logins.robot_id").
+			Where("r.owner_id = ?", req.OwnerID)
+	}
+	if req.Status > 0 {
+		query = query.Where("wx_user_logins.status = ?", req.Status)
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		s.logger.Error("统计用户搜索结果失败", zap.Error(err))
+		return nil, err
+	}
+
+	users := []WxUserLogin{}
+	offset := (req.PageNo - 1) * req.PageSize
+	if err := query.Select("wx_user_logins.*").Order("wx_user_logins.id DESC").Offset(offset).Limit(req.PageSize).Find(&users).Error; err != nil {
+		s.logger.Error("搜索用户失败", zap.String("keyword", req.Keyword), zap.Error(err))
+		return nil, err
+	}
+
+	totalPages := int((totalCount + int64(req.PageSize) - 1) / int64(req.PageSize))
+	return &UserSearchPaginatedResponse{
+		List: users,
+		Pagination: PaginationInfo{
+			PageNo:     req.PageNo,
+			PageSize:   req.PageSize,
+			TotalCount: totalCount,
+			TotalPages: totalPages,
+			HasNext:    req.PageNo < totalPages,
+			HasPrev:    req.PageNo > 1,
+		},
+	}, nil
+}
+
+// ListNeedReloginUsers 分页查询所有状态为需要重新登录的用户，可按所属公司、机器人过滤
+func (s *wxRobotService) ListNeedReloginUsers(req NeedReloginRequest) (*NeedReloginPaginatedResponse, error) {
+	query := s.db.Table("wx_user_logins u").
+		Joins("JOIN wx_robot_configs r ON r.id = u.robot_id").
+		Where("u.status = ?", UserStatusRelogin)
+	if req.OwnerID > 0 {
+		query = query.Where("r.owner_id = ?", req.OwnerID)
+	}
+	if req.RobotID > 0 {
+		query = query.Where("u.robot_id = ?", req.RobotID)
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		s.logger.Error("统计需重新登录用户失败", zap.Error(err))
+		return nil, err
+	}
+
+	users := []NeedReloginUser{}
+	offset := (req.PageNo - 1) * req.PageSize
+	if err := query.Select("u.id AS user_id, u.robot_id, r.owner_id, u.wx_id, u.nick_name, u.remark, u.token, u.update_time").
+		Order("u.update_time DESC, u.id DESC").Offset(offset).Limit(req.PageSize).
+		Scan(&users).Error; err != nil {
+		s.logger.Error("查询需重新登录用户失败", zap.Error(err))
+		return nil, err
+	}
+	// 不返回原始token，二维码统一按用户ID通过批量接口获取
+	for i := range users {
+		users[i].Token = maskToken(users[i].Token)
+		users[i].QRCodeURL = reloginQRCodeURL
+	}
+
+	totalPages := int((totalCount + int64(req.PageSize) - 1) / int64(req.PageSize))
+	return &NeedReloginPaginatedResponse{
+		List: users,
+		Pagination: PaginationInfo{
+			PageNo:     req.PageNo,
+			PageSize:   req.PageSize,
+			TotalCount: totalCount,
+			TotalPages: totalPages,
+			HasNext:    req.PageNo < totalPages,
+			HasPrev:    req.PageNo > 1,
+		},
+	}, nil
+}
+
+// UpdateUserSignature 更新用户签名，发送文本时自动拼接
+func (s *wxRobotService) UpdateUserSignature(userID uint, signature, position string) error {
+	var user WxUserLogin
+	if err := s.db.Select("id").First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if position == "" {
+		position = SignaturePositionSuffix
+	}
+	if err := s.db.Model(&user).Updates(map[string]interface{}{
+		"signature":          signature,
+		"signature_position": position,
+	}).Error; err != nil {
+		s.logger.Error("更新用户签名失败", zap.Uint("user_id", userID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// applySignature 按发送账号的签名配置拼接文本，未配置签名时原样返回
+func (s *wxRobotService) applySignature(authKey, text string) string {
+	if text == "" {
+		return text
+	}
+
+	var user WxUserLogin
+	if err := s.db.Select("signature", "signature_position").Where("token = ?", authKey).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("查询用户签名失败，不拼接签名", zap.String("token", maskToken(authKey)), zap.Error(err))
+		}
+		return text
+	}
+
+	if user.Signature == "" {
+		return text
+	}
+	if user.SignaturePos == SignaturePositionPrefix {
+		return user.Signature + text
+	}
+	return text + user.Signature
 }
 
-// SearchGroupsByName 按群名称模糊搜索群组
-func (s *wxRobotService) SearchGroupsByName(groupNickName string) ([]WxGroup, error) {
-	var groups []WxGroup
-	if err := s.db.Where("group_nick_name LIKE ?", "%"+groupNickName+"%").Find(&groups).Error; err != nil {
-		s.logger.Error("按群名称搜索群组失败", zap.String("group_nick_name", groupNickName), zap.Error(err))
-		return nil, err
+// UpdateUserRemark 更新用户备注
+func (s *wxRobotService) UpdateUserRemark(userID uint, remark string) error {
+	var user WxUserLogin
+	if err := s.db.Select("id").First(&user, userID).Error; err != nil {
+		return err
 	}
-	return groups, nil
-}
 
-// GetActiveUsers 获取状态为1的用户列表
-func (s *wxRobotService) GetActiveUsers() ([]WxUserLogin, error) {
-	var users []WxUserLogin
-	if err := s.db.Where("status = ?", 1).Find(&users).Error; err != nil {
-		s.logger.Error("查询活跃用户失败", zap.Error(err))
-		return nil, err
+	if err := s.db.Model(&user).Update("remark", remark).Error; err != nil {
+		s.logger.Error("更新用户备注失败", zap.Uint("user_id", userID), zap.Error(err))
+		return err
 	}
-	return users, nil
+	return nil
 }
 
-// UpdateUserStatus 更新用户状态
-func (s *wxRobotService) UpdateUserStatus(userID uint, status int) error {
-	result := s.db.Model(&WxUserLogin{}).Where("id = ?", userID).Update("status", status)
-	if result.Error != nil {
-		s.logger.Error("更新用户状态失败", zap.Uint("user_id", userID), zap.Int("status", status), zap.Error(result.Error))
-		return result.Error
+// UpdateUserAutoRenew 设置用户是否到期前自动续期
+func (s *wxRobotService) UpdateUserAutoRenew(userID uint, autoRenew int) error {
+	var user WxUserLogin
+	if err := s.db.Select("id").First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Model(&user).Update("auto_renew", autoRenew).Error; err != nil {
+		s.logger.Error("更新用户自动续期失败", zap.Uint("user_id", userID), zap.Error(err))
+		return err
 	}
-	s.logger.Info("用户状态更新完成", zap.Uint("user_id", userID), zap.Int("status", status))
 	return nil
 }
 
+// GetUserStatusHistory 获取用户状态变更历史，按时间倒序
+func (s *wxRobotService) GetUserStatusHistory(userID uint) ([]WxUserStatusLog, error) {
+	var logs []WxUserStatusLog
+	if err := s.db.Where("user_id = ?", userID).Order("id DESC").Find(&logs).Error; err != nil {
+		s.logger.Error("查询用户状态变更历史失败", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return logs, nil
+}
+
 // UpdateMessageBotStatus 更新消息机器人状态
 func (s *wxRobotService) UpdateMessageBotStatus(userID uint, isMessageBot int) error {
 	// 首先检查用户是否存在
@@ -417,6 +2938,183 @@ func (s *wxRobotService) GetMessageBotByStrategy(groupId string, strategy Messag
 	return strategy.GetMessageBot(s.db, groupId, s.logger)
 }
 
+// GetGroupMessageBots 获取可服务该群的所有候选消息机器人（与发送策略使用相同的筛选条件），ownerID不为0时只返回该公司的
+func (s *wxRobotService) GetGroupMessageBots(groupID string, ownerID uint) ([]GroupBotCandidate, error) {
+	results, err := queryMessageBots(scopeBotOwner(s.db, ownerID), groupID, s.logger)
+	if err != nil {
+		if errors.Is(err, ErrNoMessageBot) {
+			return []GroupBotCandidate{}, nil
+		}
+		return nil, err
+	}
+
+	candidates := make([]GroupBotCandidate, 0, len(results))
+	for _, r := range results {
+		candidates = append(candidates, GroupBotCandidate{
+			UserID:       r.UserID,
+			WxID:         r.UserWxID,
+			NickName:     r.UserNickName,
+			RobotID:      r.RobotID,
+			RobotAddress: r.RobotAddress,
+		})
+	}
+	return candidates, nil
+}
+
+// GetAvailableMessageBots 查询当前所有可用于发送的消息机器人及其覆盖群数
+// 筛选条件与发送策略一致：状态正常、已设为消息机器人且无风控；ownerID为0时查询全部
+func (s *wxRobotService) GetAvailableMessageBots(ownerID uint) (*AvailableMessageBotsResponse, error) {
+	query := s.db.Table("wx_user_logins u").
+		Select(`u.id as user_id, u.wx_id, u.nick_name, u.robot_id,
+			r.address as robot_address, r.owner_id, COUNT(g.id) as group_count`).
+		Joins("JOIN wx_robot_configs r ON u.robot_id = r.id").
+		Joins("LEFT JOIN wx_groups g ON g.wx_id = u.wx_id").
+		Where("u.status = 1 AND u.is_message_bot = 1 AND u.has_security_risk = 0 AND r.enabled = 1")
+	if ownerID > 0 {
+		query = query.Where("r.owner_id = ?", ownerID)
+	}
+
+	bots := make([]AvailableMessageBot, 0)
+	if err := query.Group("u.id, u.wx_id, u.nick_name, u.robot_id, r.address, r.owner_id").
+		Order("u.id").
+		Scan(&bots).Error; err != nil {
+		s.logger.Error("查询可用消息机器人失败", zap.Uint("owner_id", ownerID), zap.Error(err))
+		return nil, err
+	}
+
+	return &AvailableMessageBotsResponse{
+		Total: len(bots),
+		Bots:  bots,
+	}, nil
+}
+
+// GetBotCoverageGaps 分析缺少消息机器人覆盖的群并给出分配建议
+// 群内已有状态正常且无风控的账号时，建议将其设为消息机器人；否则建议同公司覆盖群数最少的消息机器人加群
+func (s *wxRobotService) GetBotCoverageGaps(req BotCoverageRequest) (*BotCoverageResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 3
+	}
+
+	// 1. 群内没有可用消息机器人的群
+	var uncovered []struct {
+		GroupID       string
+		GroupNickName string
+	}
+	query := s.db.Table("wx_groups g").
+		Select("g.group_id, MAX(g.group_nick_name) as group_nick_name").
+		Where(`NOT EXISTS (SELECT 1 FROM wx_groups g2 JOIN wx_user_logins u2 ON g2.wx_id = u2.wx_id
+			JOIN wx_robot_configs r2 ON r2.id = u2.robot_id
+			WHERE g2.group_id = g.group_id AND u2.status = 1 AND u2.is_message_bot = 1 AND u2.has_security_risk = 0 AND r2.enabled = 1)`)
+	if req.OwnerID > 0 {
+		query = query.
+			Joins("JOIN wx_user_logins u ON g.wx_id = u.wx_id").
+			Joins("JOIN wx_robot_configs r ON u.robot_id = r.id").
+			Where("r.owner_id = ?", req.OwnerID)
+	}
+	if err := query.Group("g.group_id").Order("g.group_id").Scan(&uncovered).Error; err != nil {
+		s.logger.Error("查询缺少消息机器人覆盖的群失败", zap.Error(err))
+		return nil, err
+	}
+
+	resp := &BotCoverageResponse{
+		UncoveredGroups: len(uncovered),
+		Gaps:            make([]BotCoverageGap, 0, len(uncovered)),
+	}
+	if len(uncovered) == 0 {
+		return resp, nil
+	}
+
+	groupIDs := make([]string, 0, len(uncovered))
+	for _, g := range uncovered {
+		groupIDs = append(groupIDs, g.GroupID)
+	}
+
+	// 2. 这些群内的账号，用于确定群所属公司和可直接设为消息机器人的账号
+	var members []struct {
+		GroupID         string
+		UserID          uint
+		WxID            string
+		NickName        string
+		RobotID         uint
+		Status          int
+		HasSecurityRisk int
+		OwnerID         uint
+		RobotEnabled    int
+	}
+	if err := s.db.Table("wx_groups g").
+		Select("g.group_id, u.id as user_id, u.wx_id, u.nick_name, u.robot_id, u.status, u.has_security_risk, r.owner_id, r.enabled as robot_enabled").
+		Joins("JOIN wx_user_logins u ON g.wx_id = u.wx_id").
+		Joins("JOIN wx_robot_configs r ON u.robot_id = r.id").
+		Where("g.group_id IN ?", groupIDs).
+		Order("u.id").
+		Scan(&members).Error; err != nil {
+		s.logger.Error("查询群内账号失败", zap.Error(err))
+		return nil, err
+	}
+
+	groupOwners := make(map[string]map[uint]bool)
+	enableCandidates := make(map[string][]BotCoverageCandidate)
+	for _, m := range members {
+		if req.OwnerID > 0 && m.OwnerID != req.OwnerID {
+			continue
+		}
+		if groupOwners[m.GroupID] == nil {
+			groupOwners[m.GroupID] = make(map[uint]bool)
+		}
+		groupOwners[m.GroupID][m.OwnerID] = true
+
+		// 已禁用机器人下的账号不会作为消息机器人发送，不建议启用
+		if m.Status == UserStatusNormal && m.HasSecurityRisk == 0 && m.RobotEnabled == 1 {
+			enableCandidates[m.GroupID] = append(enableCandidates[m.GroupID], BotCoverageCandidate{
+				UserID:   m.UserID,
+				WxID:     m.WxID,
+				NickName: m.NickName,
+				RobotID:  m.RobotID,
+				Action:   CoverageActionEnableBot,
+			})
+		}
+	}
+
+	// 3. 现有消息机器人按覆盖群数从少到多排序，作为加群候选
+	bots, err := s.GetAvailableMessageBots(req.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(bots.Bots, func(i, j int) bool {
+		return bots.Bots[i].GroupCount < bots.Bots[j].GroupCount
+	})
+
+	for _, g := range uncovered {
+		gap := BotCoverageGap{
+			GroupID:       g.GroupID,
+			GroupNickName: g.GroupNickName,
+			Suggestions:   enableCandidates[g.GroupID],
+		}
+		if len(gap.Suggestions) == 0 {
+			gap.Suggestions = make([]BotCoverageCandidate, 0, limit)
+			for _, bot := range bots.Bots {
+				if len(gap.Suggestions) >= limit {
+					break
+				}
+				if !groupOwners[g.GroupID][bot.OwnerID] {
+					continue
+				}
+				gap.Suggestions = append(gap.Suggestions, BotCoverageCandidate{
+					UserID:     bot.UserID,
+					WxID:       bot.WxID,
+					NickName:   bot.NickName,
+					RobotID:    bot.RobotID,
+					GroupCount: bot.GroupCount,
+					Action:     CoverageActionJoinGroup,
+				})
+			}
+		}
+		resp.Gaps = append(resp.Gaps, gap)
+	}
+	return resp, nil
+}
+
 // CheckDatabaseHealth 检查数据库健康状态
 func (s *wxRobotService) CheckDatabaseHealth() error {
 	sqlDB, err := s.db.DB()
@@ -426,9 +3124,110 @@ func (s *wxRobotService) CheckDatabaseHealth() error {
 	return sqlDB.Ping()
 }
 
-// CheckRobotHealth 检查机器人健康状态
-func (s *wxRobotService) CheckRobotHealth(robotAddress string) (bool, error) {
-	return s.apiClient.CheckRobotHealth(robotAddress)
+// GetMigrationStatus 查询数据库迁移版本状态
+func (s *wxRobotService) GetMigrationStatus() (*MigrationStatus, error) {
+	return loadMigrationStatus(s.db, schemaMigrations)
+}
+
+// CheckRobotHealth 检查机器人健康状态，healthPath为空时检查根路径
+func (s *wxRobotService) CheckRobotHealth(ctx context.Context, robotAddress, healthPath string) (bool, error) {
+	return s.apiClient.CheckRobotHealth(ctx, robotAddress, healthPath)
+}
+
+// NotifyRobotHealth 发送机器人健康告警或恢复通知，failures为告警时的连续失败次数
+func (s *wxRobotService) NotifyRobotHealth(result RobotHealthResult, failures int, recovered bool) {
+	event := EventRobotUnhealthy
+	message := fmt.Sprintf("机器人连续%d次健康检查失败", failures)
+	if recovered {
+		event = EventRobotRecovered
+		message = "机器人已恢复健康"
+	}
+
+	s.notifier.Notify(s.eventURL, EventCallbackPayload{
+		Event:   event,
+		Message: message,
+		Data: map[string]interface{}{
+			"robot_id":         result.RobotID,
+			"address":          result.Address,
+			"failures":         failures,
+			"response_time_ms": result.ResponseTimeMs,
+			"error":            result.Error,
+		},
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// robotHealthConcurrency 批量健康检查的最大并发数
+const robotHealthConcurrency = 5
+
+// CheckRobotsHealth 并发检查多个机器人的健康状态，robotIDs为空时检查全部机器人，ownerID不为0时只检查该公司的机器人
+func (s *wxRobotService) CheckRobotsHealth(ctx context.Context, robotIDs []uint, ownerID uint) (*RobotHealthSummary, error) {
+	var robots []WxRobotConfig
+	query := s.db.Select("id", "address", "health_path")
+	if len(robotIDs) > 0 {
+		query = query.Where("id IN ?", robotIDs)
+	}
+	if ownerID > 0 {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+	if err := query.Order("id").Find(&robots).Error; err != nil {
+		s.logger.Error("查询机器人列表失败", zap.Error(err))
+		return nil, err
+	}
+
+	results := make([]RobotHealthResult, len(robots))
+	sem := make(chan struct{}, robotHealthConcurrency)
+	var wg sync.WaitGroup
+
+	for i, robot := range robots {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, robot WxRobotConfig) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			startTime := time.Now()
+			isHealthy, err := s.CheckRobotHealth(withRobotTimeout(ctx, &robot), robot.Address, robot.HealthPath)
+			responseTime := time.Since(startTime)
+
+			result := RobotHealthResult{
+				RobotID:        robot.ID,
+				Address:        robot.Address,
+				Status:         "unhealthy",
+				ResponseTime:   responseTime.String(),
+				ResponseTimeMs: responseTime.Milliseconds(),
+			}
+			if err != nil {
+				result.Error = err.Error()
+			} else if isHealthy {
+				result.Status = "healthy"
+			}
+			results[i] = result
+		}(i, robot)
+	}
+	wg.Wait()
+
+	summary := &RobotHealthSummary{
+		Total:   len(results),
+		Results: results,
+	}
+	for _, result := range results {
+		if result.Status == "healthy" {
+			summary.Healthy++
+		} else {
+			summary.Unhealthy++
+		}
+	}
+	return summary, nil
+}
+
+// ValidateRobotAddress 校验机器人地址是否指向受限地址（SSRF防护）
+func (s *wxRobotService) ValidateRobotAddress(robotAddress string) error {
+	if err := s.addressGuard.Check(robotAddress); err != nil {
+		s.logger.Warn("机器人地址校验未通过", zap.String("address", robotAddress), zap.Error(err))
+		return err
+	}
+	return nil
 }
 
 // GetMaxMsgTimeFromMessages 获取wx_group_messages表中最大的msg_time
@@ -453,8 +3252,74 @@ func (s *wxRobotService) GetGroupByGroupID(groupID string) (*WxGroup, error) {
 	return &group, nil
 }
 
-// CreateBill 创建账单
+// checkBillOwner 校验账单owner_id与群所属公司一致，避免统计串租户
+// 群的所属公司取加入该群的账号所在机器人的owner_id，同一群可能被多个公司的账号加入，命中其一即可；
+// 群尚未同步入库时无法校验，仅告警放行
+func (s *wxRobotService) checkBillOwner(bill *WxBillInfo) error {
+	var ownerIDs []uint
+	if err := s.db.Table("wx_groups g").
+		Joins("JOIN wx_user_logins u ON g.wx_id = u.wx_id").
+		Joins("JOIN wx_robot_configs r ON u.robot_id = r.id").
+		Where("g.group_id = ?", bill.GroupID).
+		Distinct().
+		Pluck("r.owner_id", &ownerIDs).Error; err != nil {
+		s.logger.Error("查询群所属公司失败", zap.String("group_id", bill.GroupID), zap.Error(err))
+		return err
+	}
+
+	if len(ownerIDs) == 0 {
+		s.logger.Warn("群未入库，无法校验账单所属公司",
+			zap.String("group_id", bill.GroupID),
+			zap.Uint("owner_id", bill.OwnerID))
+		return nil
+	}
+	for _, ownerID := range ownerIDs {
+		if ownerID == bill.OwnerID {
+			return nil
+		}
+	}
+
+	s.logger.Warn("账单所属公司与群所属公司不一致，拒绝入库",
+		zap.String("group_id", bill.GroupID),
+		zap.Uint("owner_id", bill.OwnerID),
+		zap.Uints("group_owner_ids", ownerIDs))
+	return fmt.Errorf("%w: 账单owner_id %d，群所属owner_id %v", ErrBillOwnerMismatch, bill.OwnerID, ownerIDs)
+}
+
+// CreateBill 创建账单，金额等数值不合法或所属公司与群不一致时拒绝入库，重复账单直接返回已有记录
 func (s *wxRobotService) CreateBill(bill *WxBillInfo) error {
+	if err := validateBill(bill); err != nil {
+		s.logger.Warn("账单校验失败，拒绝入库",
+			zap.String("group_id", bill.GroupID),
+			zap.String("amount", bill.Amount),
+			zap.String("dollar", bill.Dollar),
+			zap.String("rate", bill.Rate),
+			zap.Error(err))
+		return err
+	}
+
+	if err := s.checkBillOwner(bill); err != nil {
+		return err
+	}
+
+	// 同一条群消息可能因回调重试被重复解析，按 群+消息时间+操作人+金额 去重
+	var existing WxBillInfo
+	err := s.db.Select("id").
+		Where("group_id = ? AND msg_time = ? AND operator = ? AND amount = ?", bill.GroupID, bill.MsgTime, bill.Operator, bill.Amount).
+		Limit(1).Find(&existing).Error
+	if err != nil {
+		s.logger.Error("账单去重检查失败", zap.Error(err))
+		return err
+	}
+	if existing.ID > 0 {
+		bill.ID = existing.ID
+		s.logger.Info("账单已存在，跳过重复入库",
+			zap.Uint("bill_id", existing.ID),
+			zap.String("group_id", bill.GroupID),
+			zap.Int64("msg_time", bill.MsgTime))
+		return nil
+	}
+
 	if err := s.db.Create(bill).Error; err != nil {
 		s.logger.Error("创建账单失败", zap.Error(err))
 		return err
@@ -463,31 +3328,205 @@ func (s *wxRobotService) CreateBill(bill *WxBillInfo) error {
 	return nil
 }
 
+// SearchGroupMessages 按内容关键词分页搜索群消息，支持群、所属公司和消息时间过滤
+// 开启全文索引时使用MATCH AGAINST短语匹配，否则使用LIKE模糊匹配
+func (s *wxRobotService) SearchGroupMessages(req GroupMessageSearchRequest) (*GroupMessageSearchResponse, error) {
+	newQuery := func() *gorm.DB {
+		query := s.db.Model(&WxGroupMessage{})
+		if s.msgSearchFulltext {
+			// 双引号包裹按短语匹配，去掉关键词中的双引号避免破坏布尔模式语法
+			phrase := `"` + strings.ReplaceAll(req.Keyword, `"`, " ") + `"`
+			query = query.Where("MATCH(content) AGAINST(? IN BOOLEAN MODE)", phrase)
+		} else {
+			query = query.Where("content LIKE ?", "%"+escapeLike(req.Keyword)+"%")
+		}
+		if req.GroupID != "" {
+			query = query.Where("group_id = ?", req.GroupID)
+		}
+		if req.OwnerID > 0 {
+			query = query.Where("owner_id = ?", req.OwnerID)
+		}
+		if req.StartTime != "" {
+			if startTime, err := ParseTime(req.StartTime); err == nil {
+				query = query.Where("msg_time >= ?", startTime.Unix())
+			}
+		}
+		if req.EndTime != "" {
+			if endTime, err := ParseTime(req.EndTime); err == nil {
+				query = query.Where("msg_time <= ?", endTime.Unix())
+			}
+		}
+		return query
+	}
+
+	var totalCount int64
+	if err := newQuery().Count(&totalCount).Error; err != nil {
+		s.logger.Error("统计群消息搜索结果失败", zap.String("keyword", req.Keyword), zap.Error(err))
+		return nil, err
+	}
+
+	totalPages := int((totalCount + int64(req.PageSize) - 1) / int64(req.PageSize))
+	offset := (req.PageNo - 1) * req.PageSize
+
+	messages := make([]WxGroupMessage, 0, req.PageSize)
+	if err := newQuery().Order("msg_time DESC, id DESC").Offset(offset).Limit(req.PageSize).Find(&messages).Error; err != nil {
+		s.logger.Error("搜索群消息失败", zap.String("keyword", req.Keyword), zap.Error(err))
+		return nil, err
+	}
+
+	return &GroupMessageSearchResponse{
+		List: messages,
+		Pagination: PaginationInfo{
+			PageNo:     req.PageNo,
+			PageSize:   req.PageSize,
+			TotalCount: totalCount,
+			TotalPages: totalPages,
+			HasNext:    req.PageNo < totalPages,
+			HasPrev:    req.PageNo > 1,
+		},
+	}, nil
+}
+
+// escapeLike 转义LIKE中的通配符，关键词按字面匹配
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// HandleGroupMessage 处理群消息回调：保存消息并按消息类型路由到对应处理器（如文本消息识别账单）
+// 配置为不处理且不记录的消息类型直接丢弃，返回nil
+func (s *wxRobotService) HandleGroupMessage(req *MessageCallbackRequest) (*WxGroupMessage, error) {
+	// 不处理且不记录的消息类型直接丢弃
+	if !s.msgRouter.ShouldSave(req.MsgType) {
+		s.logger.Debug("忽略不处理的消息类型",
+			zap.String("group_id", req.GroupID),
+			zap.Int("msg_type", req.MsgType))
+		return nil, nil
+	}
+
+	// 通过接收账号确定所属公司
+	var robot WxRobotConfig
+	err := s.db.Table("wx_robot_configs r").
+		Select("r.*").
+		Joins("JOIN wx_user_logins u ON u.robot_id = r.id").
+		Where("u.wx_id = ?", req.WxID).
+		First(&robot).Error
+	if err != nil {
+		s.logger.Error("查询接收账号所属机器人失败", zap.String("wx_id", req.WxID), zap.Error(err))
+		return nil, err
+	}
+
+	msgTime := req.MsgTime
+	if msgTime == 0 {
+		msgTime = time.Now().Unix()
+	}
+
+	msg := &WxGroupMessage{
+		GroupID:    req.GroupID,
+		WxNickName: req.WxNickName,
+		Content:    req.Content,
+		MsgType:    req.MsgType,
+		MsgTime:    msgTime,
+		OwnerID:    robot.OwnerID,
+	}
+
+	if err := s.db.Create(msg).Error; err != nil {
+		s.logger.Error("保存群消息失败", zap.String("group_id", req.GroupID), zap.Error(err))
+		return nil, err
+	}
+
+	s.msgRouter.Dispatch(msg)
+	return msg, nil
+}
+
+// processBillMessage 识别账单消息并入库，解析失败的消息忽略
+func (s *wxRobotService) processBillMessage(msg *WxGroupMessage) {
+	if s.billParser == nil {
+		return
+	}
+
+	bill, ok := s.billParser.Parse(msg)
+	if !ok {
+		return
+	}
+
+	bill.GroupName = msg.GroupID
+	if group, err := s.GetGroupByGroupID(msg.GroupID); err == nil && group.GroupNickName != "" {
+		bill.GroupName = group.GroupNickName
+	}
+
+	if err := s.CreateBill(bill); err != nil && !errors.Is(err, ErrInvalidBill) {
+		s.logger.Error("自动识别账单入库失败", zap.String("group_id", msg.GroupID), zap.Error(err))
+	}
+}
+
+// autoReplyTimeout 自动回复脱离消息回调请求异步发送，包含排队等待在内的最长耗时
+const autoReplyTimeout = 2 * time.Minute
+
+// processAutoReply 消息命中自动回复规则时，由该群的消息机器人异步回复，不阻塞消息回调
+func (s *wxRobotService) processAutoReply(msg *WxGroupMessage) {
+	reply, ok := s.autoReplier.Match(msg)
+	if !ok {
+		return
+	}
+	if err := s.CheckGroupBlacklist(msg.GroupID); err != nil {
+		s.logger.Info("群在黑名单中，不自动回复", zap.String("group_id", msg.GroupID))
+		return
+	}
+
+	botInfo, err := s.GetMessageBotByStrategy(msg.GroupID, s.autoReplyStrategy)
+	if err != nil {
+		s.logger.Warn("自动回复未找到消息机器人", zap.String("group_id", msg.GroupID), zap.Error(err))
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), autoReplyTimeout)
+		defer cancel()
+
+		_, err := s.SendText(withRobotTimeout(ctx, botInfo.Robot), botInfo.Robot.Address, botInfo.User.Token, &SendTextRequest{
+			TextContent: reply,
+			ToUserName:  msg.GroupID,
+		})
+		if err != nil {
+			s.logger.Error("自动回复发送失败",
+				zap.String("group_id", msg.GroupID),
+				zap.String("wx_id", botInfo.User.WxID),
+				zap.Error(err))
+		}
+	}()
+}
 
 // GetBillStatistics 获取账单统计信息（分页）
 func (s *wxRobotService) GetBillStatistics(req BillStatsRequest) (*BillStatsPaginatedResponse, error) {
-	// 构建基础查询
-	baseQuery := s.db.Model(&WxBillInfo{}).
-		Select("group_id, group_name as group_nick, SUM(CAST(amount AS DECIMAL(15,2))) as total_amount, COUNT(*) as count").
-		Where("owner_id = ?", req.OwnerID).
-		Group("group_id, group_name")
+	// 构建基础查询，按分组维度选择统计字段
+	byOperator := req.GroupBy == BillStatsGroupByOperator
+	baseQuery := s.db.Model(&WxBillInfo{}).Where("owner_id = ?", req.OwnerID)
+	if byOperator {
+		baseQuery = baseQuery.
+			Select("operator, SUM(CAST(amount AS DECIMAL(15,2))) as total_amount, COUNT(*) as count").
+			Group("operator")
+	} else {
+		baseQuery = baseQuery.
+			Select("group_id, group_name as group_nick, SUM(CAST(amount AS DECIMAL(15,2))) as total_amount, COUNT(*) as count").
+			Group("group_id, group_name")
+	}
 	
 	// 根据条件过滤
-	if req.GroupID != "" {
-		baseQuery = baseQuery.Where("group_id = ?", req.GroupID)
+	baseQuery = applyBillStatsFilter(baseQuery, req)
+	if req.CreateTimeStart != "" {
+		if start, err := ParseTime(req.CreateTimeStart); err == nil {
+			baseQuery = baseQuery.Where("msg_time >= ?", start.Unix())
+		}
 	}
-	if req.GroupNick != "" {
-		baseQuery = baseQuery.Where("group_name LIKE ?", "%"+req.GroupNick+"%")
+	if req.CreateTimeEnd != "" {
+		if end, err := ParseTime(req.CreateTimeEnd); err == nil {
+			baseQuery = baseQuery.Where("msg_time <= ?", end.Unix())
+		}
 	}
 	
 	// 获取总数量（从分组结果中计算）
 	var totalCount int64
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) as grouped_results", 
-		s.db.ToSQL(func(tx *gorm.DB) *gorm.DB {
-			return baseQuery.Session(&gorm.Session{DryRun: true})
-		}))
-	
-	if err := s.db.Raw(countQuery).Scan(&totalCount).Error; err != nil {
+	if err := s.db.Raw("SELECT COUNT(*) FROM (?) as grouped_results", baseQuery).Scan(&totalCount).Error; err != nil {
 		s.logger.Error("获取统计总数量失败", zap.Error(err))
 		return nil, err
 	}
@@ -511,7 +3550,14 @@ func (s *wxRobotService) GetBillStatistics(req BillStatsRequest) (*BillStatsPagi
 		var result BillStatsResponse
 		var totalAmount float64
 		
-		err := rows.Scan(&result.GroupID, &result.GroupNick, &totalAmount, &result.Count)
+		var err error
+		if byOperator {
+			var operator sql.NullString
+			err = rows.Scan(&operator, &totalAmount, &result.Count)
+			result.Operator = operator.String
+		} else {
+			err = rows.Scan(&result.GroupID, &result.GroupNick, &totalAmount, &result.Count)
+		}
 		if err != nil {
 			s.logger.Error("扫描统计结果失败", zap.Error(err))
 			continue
@@ -519,6 +3565,7 @@ func (s *wxRobotService) GetBillStatistics(req BillStatsRequest) (*BillStatsPagi
 		
 		// 格式化金额
 		result.TotalAmount = fmt.Sprintf("%.2f", totalAmount)
+		result.DetailFilter = billDetailFilter(req, result)
 		results = append(results, result)
 	}
 	
@@ -536,10 +3583,165 @@ func (s *wxRobotService) GetBillStatistics(req BillStatsRequest) (*BillStatsPagi
 		List:       results,
 		Pagination: pagination,
 	}
+
+	if req.Compare {
+		comparison, err := s.billStatsComparison(req)
+		if err != nil {
+			return nil, err
+		}
+		response.Comparison = comparison
+	}
 	
 	return response, nil
 }
 
+// applyBillStatsFilter 账单统计的群过滤条件
+func applyBillStatsFilter(query *gorm.DB, req BillStatsRequest) *gorm.DB {
+	if req.GroupID != "" {
+		query = query.Where("group_id = ?", req.GroupID)
+	}
+	if req.GroupNick != "" {
+		query = query.Where("group_name LIKE ?", "%"+req.GroupNick+"%")
+	}
+	return query
+}
+
+// billStatsRange 解析统计的开始和结束时间，任一未传、格式错误或开始晚于结束时返回false
+func billStatsRange(req BillStatsRequest) (time.Time, time.Time, bool) {
+	if req.CreateTimeStart == "" || req.CreateTimeEnd == "" {
+		return time.Time{}, time.Time{}, false
+	}
+	start, err := ParseTime(req.CreateTimeStart)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end, err := ParseTime(req.CreateTimeEnd)
+	if err != nil || start.After(end) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// previousPeriod 计算紧邻本期之前的等长时间段，时间段两端均包含（按秒）
+func previousPeriod(start, end time.Time) (time.Time, time.Time) {
+	prevEnd := start.Add(-time.Second)
+	return prevEnd.Add(-end.Sub(start)), prevEnd
+}
+
+// changeRate 计算环比增减百分比，保留两位小数，上期为0时无法计算返回nil
+func changeRate(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	rate := math.Round((current-previous)/previous*10000) / 100
+	return &rate
+}
+
+// billStatsComparison 统计本期与上一等长时间段的账单合计并计算环比
+func (s *wxRobotService) billStatsComparison(req BillStatsRequest) (*BillStatsComparison, error) {
+	start, end, ok := billStatsRange(req)
+	if !ok {
+		return nil, ErrBillCompareRange
+	}
+	prevStart, prevEnd := previousPeriod(start, end)
+
+	sum := func(from, to time.Time) (float64, int64, error) {
+		var total struct {
+			Amount float64
+			Count  int64
+		}
+		query := s.db.Model(&WxBillInfo{}).
+			Select("COALESCE(SUM(CAST(amount AS DECIMAL(15,2))), 0) as amount, COUNT(*) as count").
+			Where("owner_id = ?", req.OwnerID).
+			Where("msg_time BETWEEN ? AND ?", from.Unix(), to.Unix())
+		err := applyBillStatsFilter(query, req).Scan(&total).Error
+		return total.Amount, total.Count, err
+	}
+
+	currentAmount, currentCount, err := sum(start, end)
+	if err != nil {
+		s.logger.Error("统计本期账单合计失败", zap.Error(err))
+		return nil, err
+	}
+	previousAmount, previousCount, err := sum(prevStart, prevEnd)
+	if err != nil {
+		s.logger.Error("统计上期账单合计失败", zap.Error(err))
+		return nil, err
+	}
+
+	return &BillStatsComparison{
+		CurrentStart:     FormatTime(start),
+		CurrentEnd:       FormatTime(end),
+		PreviousStart:    FormatTime(prevStart),
+		PreviousEnd:      FormatTime(prevEnd),
+		CurrentAmount:    fmt.Sprintf("%.2f", currentAmount),
+		PreviousAmount:   fmt.Sprintf("%.2f", previousAmount),
+		CurrentCount:     currentCount,
+		PreviousCount:    previousCount,
+		AmountChangeRate: changeRate(currentAmount, previousAmount),
+		CountChangeRate:  changeRate(float64(currentCount), float64(previousCount)),
+	}, nil
+}
+
+// ErrBillCompareRange 环比统计缺少有效的开始或结束时间
+var ErrBillCompareRange = errors.New("环比统计需同时传入开始和结束时间，且开始时间不能晚于结束时间")
+
+// billDetailFilter 生成统计项对应的明细过滤条件：按群分组时定位到该群，
+// 按操作人分组时定位到该操作人，并带上统计时的群过滤条件
+func billDetailFilter(req BillStatsRequest, item BillStatsResponse) BillDetailFilter {
+	filter := BillDetailFilter{
+		OwnerID:         req.OwnerID,
+		CreateTimeStart: req.CreateTimeStart,
+		CreateTimeEnd:   req.CreateTimeEnd,
+	}
+	if req.GroupBy == BillStatsGroupByOperator {
+		filter.GroupID = req.GroupID
+		filter.GroupName = req.GroupNick
+		filter.Operator = item.Operator
+		return filter
+	}
+	filter.GroupID = item.GroupID
+	return filter
+}
+
+// toBillInfoResponse 账单转换为响应格式
+func toBillInfoResponse(bill WxBillInfo) BillInfoResponse {
+	return BillInfoResponse{
+		ID:          bill.ID,
+		GroupName:   bill.GroupName,
+		GroupID:     bill.GroupID,
+		Dollar:      bill.Dollar,
+		Rate:        bill.Rate,
+		Amount:      bill.Amount,
+		Remark:      bill.Remark,
+		Operator:    bill.Operator,
+		MsgTime:     bill.MsgTime,
+		MsgTimeText: FormatUnix(bill.MsgTime),
+		Status:      bill.Status,
+		OwnerID:     bill.OwnerID,
+		CreateTime:  FormatTime(bill.CreateTime),
+		UpdateTime:  FormatTime(bill.UpdateTime),
+	}
+}
+
+// GetRecentBillsByGroup 按账单时间倒序查询某群最近的账单，只返回属于该公司的账单
+func (s *wxRobotService) GetRecentBillsByGroup(groupID string, ownerID uint, limit int) ([]BillInfoResponse, error) {
+	var bills []WxBillInfo
+	if err := s.db.Where("owner_id = ? AND group_id = ?", ownerID, groupID).
+		Order("msg_time DESC, id DESC").
+		Limit(limit).
+		Find(&bills).Error; err != nil {
+		s.logger.Error("查询群最近账单失败", zap.String("group_id", groupID), zap.Error(err))
+		return nil, err
+	}
+
+	results := make([]BillInfoResponse, 0, len(bills))
+	for _, bill := range bills {
+		results = append(results, toBillInfoResponse(bill))
+	}
+	return results, nil
+}
+
 // GetBillList 查询账单列表（分页）
 func (s *wxRobotService) GetBillList(req BillQueryRequest) (*BillQueryPaginatedResponse, error) {
 	// 构建基础查询
@@ -547,7 +3749,7 @@ func (s *wxRobotService) GetBillList(req BillQueryRequest) (*BillQueryPaginatedR
 	
 	// 根据条件过滤
 	if req.CreateTimeStart != "" {
-		if startTime, err := time.Parse("2006-01-02 15:04:05", req.CreateTimeStart); err == nil {
+		if startTime, err := ParseTime(req.CreateTimeStart); err == nil {
 			// 将时间转换为时间戳进行比较
 			startTimestamp := startTime.Unix()
 			query = query.Where("msg_time >= ?", startTimestamp)
@@ -555,7 +3757,7 @@ func (s *wxRobotService) GetBillList(req BillQueryRequest) (*BillQueryPaginatedR
 	}
 	
 	if req.CreateTimeEnd != "" {
-		if endTime, err := time.Parse("2006-01-02 15:04:05", req.CreateTimeEnd); err == nil {
+		if endTime, err := ParseTime(req.CreateTimeEnd); err == nil {
 			// 将时间转换为时间戳进行比较
 			endTimestamp := endTime.Unix()
 			query = query.Where("msg_time <= ?", endTimestamp)
@@ -574,6 +3776,10 @@ func (s *wxRobotService) GetBillList(req BillQueryRequest) (*BillQueryPaginatedR
 		query = query.Where("status = ?", req.Status)
 	}
 	
+	if req.Operator != "" {
+		query = query.Where("operator = ?", req.Operator)
+	}
+	
 	// 获取总数量
 	var totalCount int64
 	if err := query.Count(&totalCount).Error; err != nil {
@@ -595,22 +3801,7 @@ func (s *wxRobotService) GetBillList(req BillQueryRequest) (*BillQueryPaginatedR
 	// 转换为响应格式
 	var results []BillInfoResponse
 	for _, bill := range bills {
-		result := BillInfoResponse{
-			ID:         bill.ID,
-			GroupName:  bill.GroupName,
-			GroupID:    bill.GroupID,
-			Dollar:     bill.Dollar,
-			Rate:       bill.Rate,
-			Amount:     bill.Amount,
-			Remark:     bill.Remark,
-			Operator:   bill.Operator,
-			MsgTime:    bill.MsgTime,
-			Status:     bill.Status,
-			OwnerID:    bill.OwnerID,
-			CreateTime: bill.CreateTime.Format("2006-01-02 15:04:05"),
-			UpdateTime: bill.UpdateTime.Format("2006-01-02 15:04:05"),
-		}
-		results = append(results, result)
+		results = append(results, toBillInfoResponse(bill))
 	}
 	
 	// 构建分页信息
@@ -0,0 +1,1596 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestGetUserAuthInfoExtensions(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		"/login/GetLoginStatus": jsonHandler(map[string]interface{}{
+			"Code": 200,
+			"Data": map[string]interface{}{"loginState": 1, "onlineDays": 3},
+		}),
+	})
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := &WxUserLogin{Token: "token-abcdefgh", WxID: "wxid_a", ExpirationTime: start}
+	createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, user)
+
+	extensions := []struct {
+		days   int
+		expiry time.Time
+		source string
+	}{
+		{days: 30, expiry: start.AddDate(0, 0, 30), source: ExtensionSourceManual},
+		{days: 60, expiry: start.AddDate(0, 0, 90), source: ExtensionSourceBatch},
+		{days: 30, expiry: start.AddDate(0, 0, 120), source: ExtensionSourceAutoRenew},
+	}
+	for _, ext := range extensions {
+		if err := svc.UpdateUserExtension(user.RobotID, user.Token, ext.days, ext.expiry, ext.source); err != nil {
+			t.Fatalf("UpdateUserExtension: %v", err)
+		}
+	}
+
+	info, err := svc.GetUserAuthInfo(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUserAuthInfo: %v", err)
+	}
+	if info.LoginStatus == nil || info.LoginStatus.LoginState != 1 {
+		t.Fatalf("login status = %+v, error = %q", info.LoginStatus, info.LoginStatusError)
+	}
+	if len(info.Extensions) != len(extensions) {
+		t.Fatalf("got %d extensions, want %d", len(info.Extensions), len(extensions))
+	}
+
+	// 按时间倒序：最近一次延期在最前，且前后过期时间首尾相接
+	for i, got := range info.Extensions {
+		want := extensions[len(extensions)-1-i]
+		if got.Days != want.days || got.Source != want.source || !got.NewExpirationTime.Equal(want.expiry) {
+			t.Errorf("extension[%d] = %+v, want days=%d source=%s expiry=%s", i, got, want.days, want.source, want.expiry)
+		}
+	}
+	if !info.Extensions[len(extensions)-1].OldExpirationTime.Equal(start) {
+		t.Errorf("first extension old expiry = %s, want %s", info.Extensions[len(extensions)-1].OldExpirationTime, start)
+	}
+	if info.ExpirationTime != FormatTime(start.AddDate(0, 0, 120)) {
+		t.Errorf("expiration_time = %s", info.ExpirationTime)
+	}
+}
+
+func TestGetUserAuthInfoTokenExpired(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		"/login/GetLoginStatus": jsonHandler(map[string]interface{}{"Code": wxCodeTokenExpired, "Text": "token expired"}),
+	})
+
+	user := &WxUserLogin{Token: "token-expired", WxID: "wxid_b", Status: UserStatusNormal}
+	createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, user)
+
+	info, err := svc.GetUserAuthInfo(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUserAuthInfo: %v", err)
+	}
+	if info.LoginStatus != nil || info.LoginStatusError == "" {
+		t.Fatalf("expected login status error, got %+v", info)
+	}
+
+	var reloaded WxUserLogin
+	db.First(&reloaded, user.ID)
+	if reloaded.Status != UserStatusRelogin {
+		t.Fatalf("user status = %d, want %d (token expiry must go through handleTokenExpired)", reloaded.Status, UserStatusRelogin)
+	}
+	var logs []WxUserStatusLog
+	db.Where("user_id = ?", user.ID).Find(&logs)
+	if len(logs) != 1 || logs[0].NewStatus != UserStatusRelogin {
+		t.Fatalf("status logs = %+v", logs)
+	}
+}
+
+func TestSendTextAtAllPermission(t *testing.T) {
+	const groupID = "123@chatroom"
+	const sender = "wxid_sender"
+
+	// chatRoomInfo 返回群详情，owner为群主，flag为发送账号的群成员标志
+	chatRoomInfo := func(owner string, flag int) http.HandlerFunc {
+		return jsonHandler(map[string]interface{}{
+			"Code": 200,
+			"Data": map[string]interface{}{
+				"contactList": []map[string]interface{}{{
+					"userName":      map[string]string{"str": groupID},
+					"chatRoomOwner": owner,
+					"newChatroomData": map[string]interface{}{
+						"chatroom_member_list": []map[string]interface{}{
+							{"user_name": sender, "chatroom_member_flag": flag},
+						},
+					},
+				}},
+			},
+		})
+	}
+
+	tests := []struct {
+		name     string
+		info     http.HandlerFunc
+		atWxIDs  []string
+		wantAtWx []string
+	}{
+		{name: "group owner keeps at all", info: chatRoomInfo(sender, 0), atWxIDs: []string{AtAllWxID}, wantAtWx: []string{AtAllWxID}},
+		{name: "group admin keeps at all", info: chatRoomInfo("wxid_owner", chatroomMemberFlagAdmin), atWxIDs: []string{AtAllWxID}, wantAtWx: []string{AtAllWxID}},
+		{name: "plain member downgraded", info: chatRoomInfo("wxid_owner", 0), atWxIDs: []string{AtAllWxID}, wantAtWx: []string{}},
+		{name: "downgrade keeps other mentions", info: chatRoomInfo("wxid_owner", 0), atWxIDs: []string{"wxid_c", AtAllWxID}, wantAtWx: []string{"wxid_c"}},
+		{name: "group info unavailable", info: jsonHandler(map[string]interface{}{"Code": 200}), atWxIDs: []string{AtAllWxID}, wantAtWx: []string{AtAllWxID}},
+	}
+
+	sends := []struct {
+		name string
+		send func(svc *wxRobotService, address string, atWxIDs []string)
+	}{
+		{name: "SendText", send: func(svc *wxRobotService, address string, atWxIDs []string) {
+			svc.SendText(context.Background(), address, "token-sender", &SendTextRequest{TextContent: "通知", ToUserName: groupID, AtWxIDList: atWxIDs})
+		}},
+		{name: "SendTextAndImage", send: func(svc *wxRobotService, address string, atWxIDs []string) {
+			svc.SendTextAndImage(context.Background(), address, "token-sender", &SendTextAndImageRequest{TextContent: "通知", ToUserName: groupID, AtWxIDList: atWxIDs})
+		}},
+	}
+
+	for _, tt := range tests {
+		for _, send := range sends {
+			t.Run(tt.name+"/"+send.name, func(t *testing.T) {
+				svc, db := newTestService(t, nil)
+				sent := make(chan []string, 1)
+				server := newTestRobotServer(t, map[string]http.HandlerFunc{
+					defaultWxAPIEndpoints.GetChatRoomInfo: tt.info,
+					defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+						var req SendTextMessageRequest
+						if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.MsgItem) != 1 {
+							t.Errorf("decode send request: %v, items = %d", err, len(req.MsgItem))
+						} else {
+							sent <- req.MsgItem[0].AtWxIDList
+						}
+						jsonHandler(map[string]interface{}{"Code": 200, "Data": []map[string]interface{}{{"isSendSuccess": true}}})(w, r)
+					},
+				})
+				createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, &WxUserLogin{WxID: sender, Token: "token-sender", Status: UserStatusNormal})
+
+				send.send(svc, server.URL, append([]string(nil), tt.atWxIDs...))
+
+				select {
+				case got := <-sent:
+					if !reflect.DeepEqual(got, tt.wantAtWx) {
+						t.Fatalf("sent AtWxIDList = %v, want %v", got, tt.wantAtWx)
+					}
+				default:
+					t.Fatal("text message not sent")
+				}
+			})
+		}
+	}
+}
+
+func TestBatchExtendAuth(t *testing.T) {
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.DelayAuthKey: jsonHandler(map[string]interface{}{
+			"Code": 200,
+			"Data": map[string]interface{}{"expiryDate": "2030-01-01"},
+		}),
+	})
+
+	tests := []struct {
+		name       string
+		ownerID    uint
+		byRobot    bool // 只处理第一个机器人
+		withinDays int
+		wantWx     []string
+	}{
+		{name: "all users with token", wantWx: []string{"wxid_a1", "wxid_a2", "wxid_b1"}},
+		{name: "by owner", ownerID: 2, wantWx: []string{"wxid_b1"}},
+		{name: "by robot", byRobot: true, wantWx: []string{"wxid_a1", "wxid_a2"}},
+		{name: "expiring within days", withinDays: 7, wantWx: []string{"wxid_a1", "wxid_b1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, db := newTestService(t, nil)
+			now := time.Now()
+			robots := [2]*WxRobotConfig{{Address: server.URL, OwnerID: 1}, {Address: server.URL, OwnerID: 2}}
+			createTestRobot(t, db, robots[0],
+				&WxUserLogin{WxID: "wxid_a1", Token: "token-a1", ExpirationTime: now.AddDate(0, 0, 3)},
+				&WxUserLogin{WxID: "wxid_a2", Token: "token-a2", ExpirationTime: now.AddDate(0, 0, 60)},
+				&WxUserLogin{WxID: "wxid_a3", ExpirationTime: now})
+			createTestRobot(t, db, robots[1],
+				&WxUserLogin{WxID: "wxid_b1", Token: "token-b1", ExpirationTime: now.AddDate(0, 0, -1)})
+
+			req := &BatchExtendAuthRequest{OwnerID: tt.ownerID, WithinDays: tt.withinDays, Days: 30}
+			if tt.byRobot {
+				req.RobotID = robots[0].ID
+			}
+			resp, err := svc.BatchExtendAuth(context.Background(), req)
+			if err != nil {
+				t.Fatalf("BatchExtendAuth: %v", err)
+			}
+			if resp.Total != len(tt.wantWx) || resp.Success != len(tt.wantWx) || resp.Failed != 0 {
+				t.Fatalf("resp = total %d success %d failed %d, want %d", resp.Total, resp.Success, resp.Failed, len(tt.wantWx))
+			}
+			var got []string
+			for _, r := range resp.Results {
+				got = append(got, r.WxID)
+				if r.ExpiryDate != "2030-01-01" {
+					t.Errorf("%s expiry_date = %q", r.WxID, r.ExpiryDate)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.wantWx) {
+				t.Fatalf("extended users = %v, want %v", got, tt.wantWx)
+			}
+
+			var extended WxUserLogin
+			db.Where("wx_id = ?", tt.wantWx[0]).First(&extended)
+			if extended.ExpirationTime.Format("2006-01-02") != "2030-01-01" {
+				t.Fatalf("expiration_time = %s, want 2030-01-01", extended.ExpirationTime)
+			}
+		})
+	}
+}
+
+func TestUpdateUserStatusRecordsTransitions(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	user := &WxUserLogin{WxID: "wxid_status", Token: "token-status", Status: UserStatusNormal}
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot.invalid", OwnerID: 1}, user)
+
+	transitions := []struct {
+		status  int
+		reason  string
+		wantLog bool
+	}{
+		{status: UserStatusRisk, reason: "发送被风控", wantLog: true},
+		{status: UserStatusRisk, reason: "重复设置"},
+		{status: UserStatusRelogin, reason: "token过期", wantLog: true},
+		{status: UserStatusNormal, reason: "重新登录", wantLog: true},
+	}
+
+	var want []WxUserStatusLog
+	previous := UserStatusNormal
+	for _, tr := range transitions {
+		if err := svc.UpdateUserStatus(user.ID, tr.status, tr.reason); err != nil {
+			t.Fatalf("UpdateUserStatus(%d): %v", tr.status, err)
+		}
+		if tr.wantLog {
+			want = append(want, WxUserStatusLog{OldStatus: previous, NewStatus: tr.status, Reason: tr.reason})
+		}
+		previous = tr.status
+	}
+
+	logs, err := svc.GetUserStatusHistory(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserStatusHistory: %v", err)
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("got %d status logs, want %d: %+v", len(logs), len(want), logs)
+	}
+	// 历史按时间倒序
+	for i, got := range logs {
+		w := want[len(want)-1-i]
+		if got.OldStatus != w.OldStatus || got.NewStatus != w.NewStatus || got.Reason != w.Reason {
+			t.Errorf("log[%d] = %d->%d %q, want %d->%d %q", i, got.OldStatus, got.NewStatus, got.Reason, w.OldStatus, w.NewStatus, w.Reason)
+		}
+	}
+
+	if err := svc.UpdateUserStatus(user.ID+100, UserStatusRisk, "不存在"); err == nil {
+		t.Fatal("UpdateUserStatus on missing user succeeded")
+	}
+}
+
+func TestGetGroupChanges(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	for _, g := range []WxGroup{
+		{WxID: "wxid_a", GroupID: "1@chatroom", GroupNickName: "群1"},
+		{WxID: "wxid_a", GroupID: "2@chatroom", GroupNickName: "群2"},
+		{WxID: "wxid_a", GroupID: "3@chatroom", GroupNickName: "群3"},
+		{WxID: "wxid_b", GroupID: "4@chatroom", GroupNickName: "群4"},
+	} {
+		if err := db.Create(&g).Error; err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+	// wxid_a 退出了群2和群3
+	if err := svc.DeleteGroupsByWxIDNotInList("wxid_a", []string{"1@chatroom"}); err != nil {
+		t.Fatalf("DeleteGroupsByWxIDNotInList: %v", err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name      string
+		wxID      string
+		start     time.Time
+		end       time.Time
+		wantAdded []string
+		wantLost  []string
+	}{
+		{name: "all accounts", start: now.Add(-time.Hour), end: now.Add(time.Hour), wantAdded: []string{"1@chatroom", "4@chatroom"}, wantLost: []string{"2@chatroom", "3@chatroom"}},
+		{name: "single account", wxID: "wxid_b", start: now.Add(-time.Hour), end: now.Add(time.Hour), wantAdded: []string{"4@chatroom"}},
+		{name: "window before changes", start: now.Add(-2 * time.Hour), end: now.Add(-time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.GetGroupChanges(tt.wxID, 0, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("GetGroupChanges: %v", err)
+			}
+			var added, lost []string
+			for _, g := range resp.Added {
+				added = append(added, g.GroupID)
+			}
+			for _, g := range resp.Lost {
+				lost = append(lost, g.GroupID)
+			}
+			sort.Strings(added)
+			sort.Strings(lost)
+			if !reflect.DeepEqual(added, tt.wantAdded) || !reflect.DeepEqual(lost, tt.wantLost) {
+				t.Fatalf("added = %v lost = %v, want %v %v", added, lost, tt.wantAdded, tt.wantLost)
+			}
+		})
+	}
+
+	var remaining int64
+	db.Model(&WxGroup{}).Where("wx_id = ?", "wxid_a").Count(&remaining)
+	if remaining != 1 {
+		t.Fatalf("wxid_a has %d groups after sync, want 1", remaining)
+	}
+}
+
+// createTestBills 创建测试账单
+func createTestBills(t *testing.T, db *gorm.DB, bills ...WxBillInfo) {
+	t.Helper()
+	for _, bill := range bills {
+		if err := db.Create(&bill).Error; err != nil {
+			t.Fatalf("create bill: %v", err)
+		}
+	}
+}
+
+func TestGetBillStatisticsGroupBy(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	createTestBills(t, db,
+		WxBillInfo{OwnerID: 1, GroupID: "1@chatroom", GroupName: "群1", Operator: "张三", Amount: "100.00", MsgTime: 1},
+		WxBillInfo{OwnerID: 1, GroupID: "1@chatroom", GroupName: "群1", Operator: "李四", Amount: "50.50", MsgTime: 2},
+		WxBillInfo{OwnerID: 1, GroupID: "2@chatroom", GroupName: "群2", Operator: "张三", Amount: "20.00", MsgTime: 3},
+		WxBillInfo{OwnerID: 2, GroupID: "3@chatroom", GroupName: "群3", Operator: "张三", Amount: "999.00", MsgTime: 4},
+	)
+
+	tests := []struct {
+		name    string
+		groupBy string
+		groupID string
+		want    map[string]string // 分组项 -> 合计金额
+	}{
+		{name: "by group", groupBy: BillStatsGroupByGroup, want: map[string]string{"1@chatroom": "150.50", "2@chatroom": "20.00"}},
+		{name: "default is by group", want: map[string]string{"1@chatroom": "150.50", "2@chatroom": "20.00"}},
+		{name: "by operator", groupBy: BillStatsGroupByOperator, want: map[string]string{"张三": "120.00", "李四": "50.50"}},
+		{name: "by operator within group", groupBy: BillStatsGroupByOperator, groupID: "1@chatroom", want: map[string]string{"张三": "100.00", "李四": "50.50"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.GetBillStatistics(BillStatsRequest{OwnerID: 1, GroupBy: tt.groupBy, GroupID: tt.groupID, PageNo: 1, PageSize: 10})
+			if err != nil {
+				t.Fatalf("GetBillStatistics: %v", err)
+			}
+			got := make(map[string]string, len(resp.List))
+			for _, item := range resp.List {
+				key := item.GroupID
+				if tt.groupBy == BillStatsGroupByOperator {
+					key = item.Operator
+				}
+				got[key] = item.TotalAmount
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("stats = %v, want %v", got, tt.want)
+			}
+			if resp.Pagination.TotalCount != int64(len(tt.want)) {
+				t.Fatalf("total_count = %d, want %d", resp.Pagination.TotalCount, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCheckRobotsHealth(t *testing.T) {
+	healthy := newTestRobotServer(t, map[string]http.HandlerFunc{"/": jsonHandler("ok")})
+	unhealthy := newTestRobotServer(t, map[string]http.HandlerFunc{
+		"/": func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) },
+	})
+	down := newTestRobotServer(t, nil)
+	down.Close()
+
+	svc, db := newTestService(t, nil)
+	robots := []*WxRobotConfig{
+		{Address: healthy.URL, OwnerID: 1},
+		{Address: unhealthy.URL, OwnerID: 1},
+		{Address: down.URL, OwnerID: 2},
+	}
+	for _, robot := range robots {
+		createTestRobot(t, db, robot)
+	}
+
+	tests := []struct {
+		name       string
+		robotIDs   []uint
+		ownerID    uint
+		wantStatus []string // 按机器人ID顺序
+		wantError  []bool
+	}{
+		{name: "all robots", wantStatus: []string{"healthy", "unhealthy", "unhealthy"}, wantError: []bool{false, false, true}},
+		{name: "selected robots", robotIDs: []uint{robots[0].ID, robots[2].ID}, wantStatus: []string{"healthy", "unhealthy"}, wantError: []bool{false, true}},
+		{name: "by owner", ownerID: 1, wantStatus: []string{"healthy", "unhealthy"}, wantError: []bool{false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := svc.CheckRobotsHealth(context.Background(), tt.robotIDs, tt.ownerID)
+			if err != nil {
+				t.Fatalf("CheckRobotsHealth: %v", err)
+			}
+			if summary.Total != len(tt.wantStatus) || len(summary.Results) != len(tt.wantStatus) {
+				t.Fatalf("total = %d, want %d", summary.Total, len(tt.wantStatus))
+			}
+			healthyCount := 0
+			for i, result := range summary.Results {
+				if result.Status != tt.wantStatus[i] || (result.Error != "") != tt.wantError[i] {
+					t.Errorf("result[%d] = %+v, want status %s error %v", i, result, tt.wantStatus[i], tt.wantError[i])
+				}
+				if result.Status == "healthy" {
+					healthyCount++
+				}
+			}
+			if summary.Healthy != healthyCount || summary.Unhealthy != summary.Total-healthyCount {
+				t.Errorf("healthy = %d unhealthy = %d", summary.Healthy, summary.Unhealthy)
+			}
+		})
+	}
+}
+
+func TestGetGroupMessageBots(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	const groupID = "bots@chatroom"
+
+	enabled := &WxRobotConfig{Address: "http://robot1.invalid", OwnerID: 1}
+	createTestRobot(t, db, enabled,
+		&WxUserLogin{WxID: "wxid_bot", Token: "t1", IsMessageBot: 1},
+		&WxUserLogin{WxID: "wxid_member", Token: "t2"},
+		&WxUserLogin{WxID: "wxid_risk", Token: "t3", IsMessageBot: 1, HasSecurityRisk: 1},
+		&WxUserLogin{WxID: "wxid_relogin", Token: "t4", IsMessageBot: 1, Status: UserStatusRelogin},
+		&WxUserLogin{WxID: "wxid_elsewhere", Token: "t5", IsMessageBot: 1})
+	disabled := &WxRobotConfig{Address: "http://robot2.invalid", OwnerID: 1}
+	createTestRobot(t, db, disabled, &WxUserLogin{WxID: "wxid_disabled", Token: "t6", IsMessageBot: 1})
+	// enabled 默认值为1，创建后再禁用
+	db.Model(disabled).Update("enabled", 0)
+	other := &WxRobotConfig{Address: "http://robot3.invalid", OwnerID: 2}
+	createTestRobot(t, db, other, &WxUserLogin{WxID: "wxid_other_owner", Token: "t7", IsMessageBot: 1})
+
+	for _, wxID := range []string{"wxid_bot", "wxid_member", "wxid_risk", "wxid_relogin", "wxid_disabled", "wxid_other_owner"} {
+		if err := db.Create(&WxGroup{WxID: wxID, GroupID: groupID}).Error; err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		groupID string
+		ownerID uint
+		want    []string
+	}{
+		{name: "only usable message bots", groupID: groupID, want: []string{"wxid_bot", "wxid_other_owner"}},
+		{name: "scoped to owner", groupID: groupID, ownerID: 1, want: []string{"wxid_bot"}},
+		{name: "group without bots", groupID: "none@chatroom", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, err := svc.GetGroupMessageBots(tt.groupID, tt.ownerID)
+			if err != nil {
+				t.Fatalf("GetGroupMessageBots: %v", err)
+			}
+			got := []string{}
+			for _, c := range candidates {
+				got = append(got, c.WxID)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("bots = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateBillDedup(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	first := &WxBillInfo{GroupID: "g@chatroom", MsgTime: 100, Operator: "张三", Amount: "10"}
+	if err := svc.CreateBill(first); err != nil {
+		t.Fatalf("CreateBill: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		bill      WxBillInfo
+		wantSame  bool
+		wantCount int64
+	}{
+		{name: "duplicate", bill: WxBillInfo{GroupID: "g@chatroom", MsgTime: 100, Operator: "张三", Amount: "10"}, wantSame: true, wantCount: 1},
+		{name: "different msg time", bill: WxBillInfo{GroupID: "g@chatroom", MsgTime: 101, Operator: "张三", Amount: "10"}, wantCount: 2},
+		{name: "different operator", bill: WxBillInfo{GroupID: "g@chatroom", MsgTime: 100, Operator: "李四", Amount: "10"}, wantCount: 3},
+		{name: "different amount", bill: WxBillInfo{GroupID: "g@chatroom", MsgTime: 100, Operator: "张三", Amount: "20"}, wantCount: 4},
+		{name: "different group", bill: WxBillInfo{GroupID: "h@chatroom", MsgTime: 100, Operator: "张三", Amount: "10"}, wantCount: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := svc.CreateBill(&tt.bill); err != nil {
+				t.Fatalf("CreateBill: %v", err)
+			}
+			if same := tt.bill.ID == first.ID; same != tt.wantSame {
+				t.Fatalf("bill id = %d, first id = %d, wantSame %v", tt.bill.ID, first.ID, tt.wantSame)
+			}
+			var count int64
+			db.Model(&WxBillInfo{}).Count(&count)
+			if count != tt.wantCount {
+				t.Fatalf("bill count = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot1.invalid", OwnerID: 1},
+		&WxUserLogin{WxID: "wxid_alpha", NickName: "客服小王", Token: "t1"},
+		&WxUserLogin{WxID: "wxid_beta", NickName: "客服小李", Token: "t2", Status: UserStatusRelogin},
+		&WxUserLogin{WxID: "wxid_gamma", NickName: "财务", Token: "t3"})
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot2.invalid", OwnerID: 2},
+		&WxUserLogin{WxID: "wxid_delta", NickName: "客服小张", Token: "t4"})
+
+	tests := []struct {
+		name      string
+		req       UserSearchRequest
+		want      []string
+		wantTotal int64
+	}{
+		{name: "by nickname", req: UserSearchRequest{Keyword: "客服"}, want: []string{"wxid_alpha", "wxid_beta", "wxid_delta"}, wantTotal: 3},
+		{name: "by wxid", req: UserSearchRequest{Keyword: "gam"}, want: []string{"wxid_gamma"}, wantTotal: 1},
+		{name: "owner filter", req: UserSearchRequest{Keyword: "客服", OwnerID: 2}, want: []string{"wxid_delta"}, wantTotal: 1},
+		{name: "status filter", req: UserSearchRequest{Keyword: "客服", Status: UserStatusRelogin}, want: []string{"wxid_beta"}, wantTotal: 1},
+		{name: "paged", req: UserSearchRequest{Keyword: "wxid", PageNo: 2, PageSize: 3}, want: []string{"wxid_alpha"}, wantTotal: 4},
+		{name: "no match", req: UserSearchRequest{Keyword: "不存在"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.req.PageNo == 0 {
+				tt.req.PageNo, tt.req.PageSize = 1, 10
+			}
+			resp, err := svc.SearchUsers(tt.req)
+			if err != nil {
+				t.Fatalf("SearchUsers: %v", err)
+			}
+			got := []string{}
+			for _, u := range resp.List {
+				got = append(got, u.WxID)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("users = %v, want %v", got, tt.want)
+			}
+			if resp.Pagination.TotalCount != tt.wantTotal {
+				t.Fatalf("total = %d, want %d", resp.Pagination.TotalCount, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestListGroups(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot1.invalid", OwnerID: 1},
+		&WxUserLogin{WxID: "wxid_a", Token: "t1"},
+		&WxUserLogin{WxID: "wxid_b", Token: "t2"})
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot2.invalid", OwnerID: 2},
+		&WxUserLogin{WxID: "wxid_c", Token: "t3"})
+
+	base := time.Now().Add(-time.Hour)
+	groups := []WxGroup{
+		{WxID: "wxid_a", GroupID: "1@chatroom", GroupNickName: "销售群", CreateTime: base},
+		{WxID: "wxid_b", GroupID: "1@chatroom", GroupNickName: "销售群", CreateTime: base.Add(time.Minute)},
+		{WxID: "wxid_a", GroupID: "2@chatroom", GroupNickName: "客服群", CreateTime: base.Add(2 * time.Minute)},
+		{WxID: "wxid_c", GroupID: "3@chatroom", GroupNickName: "销售二群", CreateTime: base.Add(3 * time.Minute)},
+	}
+	for i := range groups {
+		if err := db.Create(&groups[i]).Error; err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		req       GroupListRequest
+		want      []string
+		wantWxIDs map[string][]string
+		wantTotal int64
+	}{
+		{
+			name:      "all groups merged",
+			req:       GroupListRequest{PageNo: 1, PageSize: 10},
+			want:      []string{"3@chatroom", "2@chatroom", "1@chatroom"},
+			wantWxIDs: map[string][]string{"1@chatroom": {"wxid_a", "wxid_b"}},
+			wantTotal: 3,
+		},
+		{name: "paged", req: GroupListRequest{PageNo: 2, PageSize: 2}, want: []string{"1@chatroom"}, wantTotal: 3},
+		{name: "by name", req: GroupListRequest{GroupNickName: "销售", PageNo: 1, PageSize: 10}, want: []string{"3@chatroom", "1@chatroom"}, wantTotal: 2},
+		{name: "by owner", req: GroupListRequest{OwnerID: 2, PageNo: 1, PageSize: 10}, want: []string{"3@chatroom"}, wantTotal: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.ListGroups(tt.req)
+			if err != nil {
+				t.Fatalf("ListGroups: %v", err)
+			}
+			got := []string{}
+			for _, g := range resp.List {
+				got = append(got, g.GroupID)
+				if want, ok := tt.wantWxIDs[g.GroupID]; ok && !reflect.DeepEqual(g.WxIDs, want) {
+					t.Errorf("%s wx_ids = %v, want %v", g.GroupID, g.WxIDs, want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("groups = %v, want %v", got, tt.want)
+			}
+			if resp.Pagination.TotalCount != tt.wantTotal {
+				t.Fatalf("total = %d, want %d", resp.Pagination.TotalCount, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestCheckLargeGroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		groupID   string
+		confirmed bool
+		wantErr   bool
+	}{
+		{name: "normal group", threshold: 300, groupID: "small@chatroom"},
+		{name: "large group not confirmed", threshold: 300, groupID: "large@chatroom", wantErr: true},
+		{name: "large group confirmed", threshold: 300, groupID: "large@chatroom", confirmed: true},
+		{name: "max member count across accounts", threshold: 300, groupID: "mixed@chatroom", wantErr: true},
+		{name: "at threshold", threshold: 500, groupID: "large@chatroom"},
+		{name: "unknown group", threshold: 300, groupID: "unknown@chatroom"},
+		{name: "check disabled", threshold: 0, groupID: "large@chatroom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, db := newTestService(t, &Config{Security: SecurityConfig{LargeGroupThreshold: tt.threshold}})
+			for _, g := range []WxGroup{
+				{WxID: "wxid_a", GroupID: "small@chatroom", MemberCount: 50},
+				{WxID: "wxid_a", GroupID: "large@chatroom", MemberCount: 500},
+				{WxID: "wxid_a", GroupID: "mixed@chatroom", MemberCount: 10},
+				{WxID: "wxid_b", GroupID: "mixed@chatroom", MemberCount: 400},
+			} {
+				if err := db.Create(&g).Error; err != nil {
+					t.Fatalf("create group: %v", err)
+				}
+			}
+
+			err := svc.CheckLargeGroup(tt.groupID, tt.confirmed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckLargeGroup = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrLargeGroupNotConfirmed) {
+				t.Fatalf("err = %v, want ErrLargeGroupNotConfirmed", err)
+			}
+		})
+	}
+}
+
+func TestTokenExpiredMarksUserRelogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		endpoint   string
+		code       int
+		call       func(svc *wxRobotService, address, token string) error
+		wantStatus int
+	}{
+		{
+			name:     "login status expired",
+			endpoint: defaultWxAPIEndpoints.GetLoginStatus,
+			code:     wxCodeTokenExpired,
+			call: func(svc *wxRobotService, address, token string) error {
+				_, err := svc.GetLoginStatus(context.Background(), address, token)
+				return err
+			},
+			wantStatus: UserStatusRelogin,
+		},
+		{
+			name:     "security check expired",
+			endpoint: defaultWxAPIEndpoints.CheckCanSetAlias,
+			code:     wxCodeTokenExpired,
+			call: func(svc *wxRobotService, address, token string) error {
+				_, err := svc.CheckCanSetAlias(context.Background(), address, token)
+				return err
+			},
+			wantStatus: UserStatusRelogin,
+		},
+		{
+			name:     "other failure keeps status",
+			endpoint: defaultWxAPIEndpoints.GetLoginStatus,
+			code:     500,
+			call: func(svc *wxRobotService, address, token string) error {
+				_, err := svc.GetLoginStatus(context.Background(), address, token)
+				return err
+			},
+			wantStatus: UserStatusNormal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				tt.endpoint: jsonHandler(map[string]interface{}{"Code": tt.code, "Text": "该链接不存在"}),
+			})
+			svc, db := newTestService(t, nil)
+			user := &WxUserLogin{WxID: "wxid_expired", Token: "token-expired"}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, user)
+
+			err := tt.call(svc, server.URL, user.Token)
+			if expired := errors.Is(err, ErrTokenExpired); expired != (tt.wantStatus == UserStatusRelogin) {
+				t.Fatalf("err = %v, want ErrTokenExpired %v", err, tt.wantStatus == UserStatusRelogin)
+			}
+			var saved WxUserLogin
+			db.First(&saved, user.ID)
+			if saved.Status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", saved.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSendTextAppliesSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		position  string
+		want      string
+	}{
+		{name: "no signature", want: "通知内容"},
+		{name: "default suffix", signature: "——客服", want: "通知内容——客服"},
+		{name: "prefix", signature: "【客服】", position: SignaturePositionPrefix, want: "【客服】通知内容"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := make(chan string, 1)
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+					var req SendTextMessageRequest
+					if err := json.NewDecoder(r.Body).Decode(&req); err == nil && len(req.MsgItem) == 1 {
+						sent <- req.MsgItem[0].TextContent
+					}
+					jsonHandler(map[string]interface{}{"Code": 200})(w, r)
+				},
+			})
+			svc, db := newTestService(t, nil)
+			user := &WxUserLogin{WxID: "wxid_sign", Token: "token-sign"}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, user)
+			if tt.signature != "" {
+				if err := svc.UpdateUserSignature(user.ID, tt.signature, tt.position); err != nil {
+					t.Fatalf("UpdateUserSignature: %v", err)
+				}
+			}
+
+			svc.SendText(context.Background(), server.URL, user.Token, &SendTextRequest{TextContent: "通知内容", ToUserName: "wxid_friend"})
+			select {
+			case got := <-sent:
+				if got != tt.want {
+					t.Fatalf("text = %q, want %q", got, tt.want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("text not sent")
+			}
+		})
+	}
+}
+
+func TestBillStatisticsDrillDown(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	createTestBills(t, db,
+		WxBillInfo{OwnerID: 1, GroupID: "1@chatroom", GroupName: "群1", Operator: "张三", Amount: "100.00", MsgTime: 1},
+		WxBillInfo{OwnerID: 1, GroupID: "1@chatroom", GroupName: "群1", Operator: "李四", Amount: "50.50", MsgTime: 2},
+		WxBillInfo{OwnerID: 1, GroupID: "2@chatroom", GroupName: "群2", Operator: "张三", Amount: "20.00", MsgTime: 3},
+		WxBillInfo{OwnerID: 2, GroupID: "1@chatroom", GroupName: "群1", Operator: "张三", Amount: "999.00", MsgTime: 4},
+	)
+
+	tests := []struct {
+		name string
+		req  BillStatsRequest
+	}{
+		{name: "by group", req: BillStatsRequest{OwnerID: 1, GroupBy: BillStatsGroupByGroup}},
+		{name: "by operator", req: BillStatsRequest{OwnerID: 1, GroupBy: BillStatsGroupByOperator}},
+		{name: "by operator within group", req: BillStatsRequest{OwnerID: 1, GroupBy: BillStatsGroupByOperator, GroupID: "1@chatroom"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.PageNo, tt.req.PageSize = 1, 10
+			stats, err := svc.GetBillStatistics(tt.req)
+			if err != nil {
+				t.Fatalf("GetBillStatistics: %v", err)
+			}
+			if len(stats.List) == 0 {
+				t.Fatal("no statistics items")
+			}
+
+			for _, item := range stats.List {
+				f := item.DetailFilter
+				detail, err := svc.GetBillList(BillQueryRequest{
+					OwnerID: f.OwnerID, GroupID: f.GroupID, GroupName: f.GroupName, Operator: f.Operator,
+					CreateTimeStart: f.CreateTimeStart, CreateTimeEnd: f.CreateTimeEnd,
+					PageNum: 1, PageSize: 100,
+				})
+				if err != nil {
+					t.Fatalf("GetBillList(%+v): %v", f, err)
+				}
+				if detail.Pagination.TotalCount != item.Count {
+					t.Fatalf("item %+v: detail count = %d, want %d", item, detail.Pagination.TotalCount, item.Count)
+				}
+				for _, bill := range detail.List {
+					if bill.OwnerID != 1 || (item.GroupID != "" && bill.GroupID != item.GroupID) || (item.Operator != "" && bill.Operator != item.Operator) {
+						t.Fatalf("item %+v: unexpected bill %+v", item, bill)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSendTextSplitsLongText(t *testing.T) {
+	tests := []struct {
+		name    string
+		split   TextSplitConfig
+		text    string
+		atWxIDs []string
+		want    []string
+	}{
+		{name: "split disabled", split: TextSplitConfig{MaxLength: 3}, text: "一二三四五", want: []string{"一二三四五"}},
+		{name: "short text", split: TextSplitConfig{Enable: true, MaxLength: 10}, text: "一二三", want: []string{"一二三"}},
+		{
+			name:  "segments sent in order",
+			split: TextSplitConfig{Enable: true, MaxLength: 4, Strategy: TextSplitStrategyParagraph, Interval: time.Millisecond},
+			text:  "第一段\n第二段\n第三段", atWxIDs: []string{"wxid_c"},
+			want: []string{"第一段", "第二段", "第三段"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			var atLists [][]string
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+					var req SendTextMessageRequest
+					json.NewDecoder(r.Body).Decode(&req)
+					sent = append(sent, req.MsgItem[0].TextContent)
+					atLists = append(atLists, req.MsgItem[0].AtWxIDList)
+					jsonHandler(sendSuccessResponse(len(sent)))(w, r)
+				},
+			})
+			svc, _ := newTestService(t, &Config{TextSplit: tt.split})
+
+			resp, err := svc.SendText(context.Background(), server.URL, "token", &SendTextRequest{TextContent: tt.text, ToUserName: "wxid_friend", AtWxIDList: tt.atWxIDs})
+			if err != nil {
+				t.Fatalf("SendText: %v", err)
+			}
+			if !reflect.DeepEqual(sent, tt.want) {
+				t.Fatalf("sent = %q, want %q", sent, tt.want)
+			}
+			if len(tt.want) > 1 {
+				if len(resp.Segments) != len(tt.want) {
+					t.Fatalf("segments = %d, want %d", len(resp.Segments), len(tt.want))
+				}
+				// @成员只随第一段发送
+				if !reflect.DeepEqual(atLists[0], tt.atWxIDs) || len(atLists[1]) != 0 {
+					t.Fatalf("at lists = %v", atLists)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRobotSummary(t *testing.T) {
+	tests := []struct {
+		name  string
+		users []*WxUserLogin
+		want  RobotSummaryResponse
+	}{
+		{name: "no users"},
+		{
+			name: "status distribution",
+			users: []*WxUserLogin{
+				{WxID: "wxid_1", Token: "t1", IsInitialized: 1, IsMessageBot: 1},
+				{WxID: "wxid_2", Token: "t2", IsInitialized: 1},
+				{WxID: "wxid_3", Token: "t3", Status: UserStatusRisk, HasSecurityRisk: 1},
+				{WxID: "wxid_4", Token: "t4", Status: UserStatusRelogin},
+			},
+			want: RobotSummaryResponse{TotalUsers: 4, NormalUsers: 2, RiskUsers: 1, ReloginUsers: 1, SecurityRisk: 1, Initialized: 2, MessageBots: 1, HasMessageBot: true},
+		},
+		{
+			name: "message bot not usable",
+			users: []*WxUserLogin{
+				{WxID: "wxid_1", Token: "t1", IsMessageBot: 1, Status: UserStatusRelogin},
+				{WxID: "wxid_2", Token: "t2"},
+			},
+			want: RobotSummaryResponse{TotalUsers: 2, NormalUsers: 1, ReloginUsers: 1, MessageBots: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, db := newTestService(t, nil)
+			robot := &WxRobotConfig{Address: "http://robot.invalid", OwnerID: 1, Description: "一号机"}
+			createTestRobot(t, db, robot, tt.users...)
+			createTestRobot(t, db, &WxRobotConfig{Address: "http://other.invalid", OwnerID: 1},
+				&WxUserLogin{WxID: "wxid_other", Token: "t-other", IsMessageBot: 1})
+
+			got, err := svc.GetRobotSummary(robot.ID)
+			if err != nil {
+				t.Fatalf("GetRobotSummary: %v", err)
+			}
+			tt.want.RobotID, tt.want.Address, tt.want.Description = robot.ID, robot.Address, robot.Description
+			if *got != tt.want {
+				t.Fatalf("summary = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterBroadcastGroups(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot1.invalid", OwnerID: 1},
+		&WxUserLogin{WxID: "wxid_bot1", Token: "t1", IsMessageBot: 1},
+		&WxUserLogin{WxID: "wxid_member", Token: "t2"})
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot2.invalid", OwnerID: 2},
+		&WxUserLogin{WxID: "wxid_bot2", Token: "t3", IsMessageBot: 1})
+	for _, g := range []WxGroup{
+		{WxID: "wxid_bot1", GroupID: "a@chatroom"},
+		{WxID: "wxid_bot1", GroupID: "b@chatroom"},
+		{WxID: "wxid_member", GroupID: "nobot@chatroom"},
+		{WxID: "wxid_bot1", GroupID: "black@chatroom"},
+		{WxID: "wxid_bot2", GroupID: "other@chatroom"},
+	} {
+		if err := db.Create(&g).Error; err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+	if _, err := svc.AddGroupBlacklist(&AddGroupBlacklistRequest{GroupID: "black@chatroom"}); err != nil {
+		t.Fatalf("AddGroupBlacklist: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		toUserNames []string
+		ownerID     uint
+		wantValid   []string
+		wantSkipped []BroadcastSkippedGroup
+	}{
+		{
+			name:        "all valid",
+			toUserNames: []string{"b@chatroom", "a@chatroom"},
+			wantValid:   []string{"b@chatroom", "a@chatroom"},
+			wantSkipped: []BroadcastSkippedGroup{},
+		},
+		{
+			name:        "duplicates and invalid groups",
+			toUserNames: []string{"a@chatroom", "missing@chatroom", "a@chatroom", "nobot@chatroom", "black@chatroom", "b@chatroom"},
+			wantValid:   []string{"a@chatroom", "b@chatroom"},
+			wantSkipped: []BroadcastSkippedGroup{
+				{ToUserName: "missing@chatroom", Reason: "群不存在"},
+				{ToUserName: "a@chatroom", Reason: "重复的群"},
+				{ToUserName: "nobot@chatroom", Reason: "没有可用的消息机器人"},
+				{ToUserName: "black@chatroom", Reason: "群在黑名单中"},
+			},
+		},
+		{
+			name:        "other owner's bot not usable",
+			toUserNames: []string{"a@chatroom", "other@chatroom"},
+			ownerID:     1,
+			wantValid:   []string{"a@chatroom"},
+			wantSkipped: []BroadcastSkippedGroup{{ToUserName: "other@chatroom", Reason: "没有可用的消息机器人"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, skipped := svc.FilterBroadcastGroups(tt.toUserNames, tt.ownerID)
+			if !reflect.DeepEqual(valid, tt.wantValid) {
+				t.Fatalf("valid = %v, want %v", valid, tt.wantValid)
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Fatalf("skipped = %+v, want %+v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestGetRobotListCreateTime(t *testing.T) {
+	orig := displayLocation
+	displayLocation = time.UTC
+	defer func() { displayLocation = orig }()
+
+	svc, db := newTestService(t, nil)
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, address := range []string{"http://r1.invalid", "http://r2.invalid", "http://r3.invalid"} {
+		robot := &WxRobotConfig{Address: address, OwnerID: 1, CreateTime: base.AddDate(0, 0, []int{2, 0, 1}[i])}
+		createTestRobot(t, db, robot)
+	}
+
+	tests := []struct {
+		name string
+		req  RobotListRequest
+		want []string
+	}{
+		{name: "default by id", want: []string{"http://r1.invalid", "http://r2.invalid", "http://r3.invalid"}},
+		{name: "create time asc", req: RobotListRequest{SortBy: "create_time"}, want: []string{"http://r2.invalid", "http://r3.invalid", "http://r1.invalid"}},
+		{name: "create time desc", req: RobotListRequest{SortBy: "create_time", Order: "desc"}, want: []string{"http://r1.invalid", "http://r3.invalid", "http://r2.invalid"}},
+		{name: "since", req: RobotListRequest{CreateTimeStart: "2024-05-02 00:00:00", SortBy: "create_time"}, want: []string{"http://r3.invalid", "http://r1.invalid"}},
+		{name: "range", req: RobotListRequest{CreateTimeStart: "2024-05-01 12:00:00", CreateTimeEnd: "2024-05-02 12:00:00"}, want: []string{"http://r3.invalid"}},
+		{name: "invalid time ignored", req: RobotListRequest{CreateTimeStart: "yesterday"}, want: []string{"http://r1.invalid", "http://r2.invalid", "http://r3.invalid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			robots, err := svc.GetRobotList(tt.req)
+			if err != nil {
+				t.Fatalf("GetRobotList: %v", err)
+			}
+			got := make([]string, 0, len(robots))
+			for _, r := range robots {
+				got = append(got, r.Address)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("robots = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotateAdminKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		newKey      string
+		genCode     int
+		wantErr     error
+		wantKey     string
+		wantChanges int64
+		wantProbe   bool // 验证生成的授权码记录为rotate_probe来源
+	}{
+		{name: "verified and saved", newKey: "new-key", genCode: 200, wantKey: "new-key", wantChanges: 1, wantProbe: true},
+		{name: "verify failed keeps old key", newKey: "bad-key", genCode: 500, wantErr: ErrAdminKeyVerifyFailed, wantKey: "old-key"},
+		{name: "unchanged", newKey: "old-key", genCode: 200, wantErr: ErrAdminKeyUnchanged, wantKey: "old-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var usedKey string
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.GenAuthKey: func(w http.ResponseWriter, r *http.Request) {
+					usedKey = r.URL.Query().Get("key")
+					jsonHandler(map[string]interface{}{"Code": tt.genCode, "Data": []string{"auth"}})(w, r)
+				},
+			})
+			svc, db := newTestService(t, nil)
+			robot := &WxRobotConfig{Address: server.URL, AdminKey: "old-key"}
+			createTestRobot(t, db, robot)
+
+			resp, err := svc.RotateAdminKey(context.Background(), robot.ID, tt.newKey, RobotChangeOperator{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RotateAdminKey err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (resp.RobotID != robot.ID || resp.AdminKey == tt.newKey) {
+				t.Fatalf("resp = %+v, want masked key for robot %d", resp, robot.ID)
+			}
+			if tt.wantErr == nil || errors.Is(tt.wantErr, ErrAdminKeyVerifyFailed) {
+				if usedKey != tt.newKey {
+					t.Fatalf("verified with key %q, want %q", usedKey, tt.newKey)
+				}
+			}
+
+			saved, err := svc.GetRobotByID(robot.ID)
+			if err != nil {
+				t.Fatalf("GetRobotByID: %v", err)
+			}
+			if saved.AdminKey != tt.wantKey {
+				t.Fatalf("admin_key = %q, want %q", saved.AdminKey, tt.wantKey)
+			}
+			var changes int64
+			db.Model(&WxRobotConfigChange{}).Where("robot_id = ? AND source = ?", robot.ID, RobotChangeSourceRotateKey).Count(&changes)
+			if changes != tt.wantChanges {
+				t.Fatalf("recorded %d changes, want %d", changes, tt.wantChanges)
+			}
+
+			keys, err := svc.ListAuthKeys(robot.ID, "")
+			if err != nil {
+				t.Fatalf("ListAuthKeys: %v", err)
+			}
+			gotProbe := len(keys.Keys) == 1 && keys.Keys[0].AuthKey == "auth" && keys.Keys[0].Source == AuthKeySourceRotateProbe
+			if gotProbe != tt.wantProbe {
+				t.Fatalf("auth keys = %+v, want probe recorded %v", keys.Keys, tt.wantProbe)
+			}
+		})
+	}
+}
+
+func TestSaveUserUpsert(t *testing.T) {
+	tests := []struct {
+		name  string
+		saves int
+	}{
+		{name: "single submit", saves: 1},
+		{name: "concurrent duplicate submits", saves: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, db := newTestService(t, nil)
+			robot := &WxRobotConfig{Address: "http://robot.invalid"}
+			createTestRobot(t, db, robot)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, tt.saves)
+			for i := 0; i < tt.saves; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs <- svc.SaveUser(&WxUserLogin{RobotID: robot.ID, WxID: "wxid_dup", Token: fmt.Sprintf("token-%d", i)})
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatalf("SaveUser: %v", err)
+				}
+			}
+
+			var count int64
+			db.Model(&WxUserLogin{}).Where("robot_id = ? AND wx_id = ?", robot.ID, "wxid_dup").Count(&count)
+			if count != 1 {
+				t.Fatalf("got %d user records, want 1", count)
+			}
+		})
+	}
+}
+
+func TestSaveUserKeepsExistingSettings(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	robot := &WxRobotConfig{Address: "http://robot.invalid"}
+	createTestRobot(t, db, robot, &WxUserLogin{WxID: "wxid_a", Token: "old", Remark: "财务群机器人", Signature: "——客服"})
+
+	user := &WxUserLogin{RobotID: robot.ID, WxID: "wxid_a", Token: "new"}
+	if err := svc.SaveUser(user); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	var saved []WxUserLogin
+	db.Where("robot_id = ?", robot.ID).Find(&saved)
+	if len(saved) != 1 {
+		t.Fatalf("got %d user records, want 1", len(saved))
+	}
+	if saved[0].Token != "new" || saved[0].Remark != "财务群机器人" || saved[0].Signature != "——客服" {
+		t.Fatalf("saved user = %+v, want new token with remark and signature kept", saved[0])
+	}
+}
+
+func TestGetBotCoverageGaps(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	bot1 := &WxUserLogin{WxID: "wxid_bot1", Status: 1, IsMessageBot: 1}
+	bot2 := &WxUserLogin{WxID: "wxid_bot2", Status: 1, IsMessageBot: 1}
+	member := &WxUserLogin{WxID: "wxid_member", Status: 1}
+	risky := &WxUserLogin{WxID: "wxid_risky", Status: 1, HasSecurityRisk: 1}
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot1.invalid", OwnerID: 1}, bot1, bot2, member, risky)
+	bot3 := &WxUserLogin{WxID: "wxid_bot3", Status: 1, IsMessageBot: 1}
+	other := &WxUserLogin{WxID: "wxid_other", Status: 1}
+	createTestRobot(t, db, &WxRobotConfig{Address: "http://robot2.invalid", OwnerID: 2}, bot3, other)
+	disabled := &WxRobotConfig{Address: "http://robot3.invalid", OwnerID: 1}
+	createTestRobot(t, db, disabled,
+		&WxUserLogin{WxID: "wxid_offbot", Status: 1, IsMessageBot: 1},
+		&WxUserLogin{WxID: "wxid_offmember", Status: 1})
+	db.Model(disabled).Update("enabled", 0)
+
+	groups := []WxGroup{
+		{WxID: "wxid_bot1", GroupID: "covered@chatroom"},
+		{WxID: "wxid_member", GroupID: "covered@chatroom"},
+		{WxID: "wxid_bot1", GroupID: "busy1@chatroom"},
+		{WxID: "wxid_bot1", GroupID: "busy2@chatroom"},
+		{WxID: "wxid_bot2", GroupID: "quiet@chatroom"},
+		{WxID: "wxid_member", GroupID: "enable@chatroom"},
+		{WxID: "wxid_risky", GroupID: "join@chatroom"},
+		{WxID: "wxid_other", GroupID: "owner2@chatroom"},
+		{WxID: "wxid_offbot", GroupID: "offline@chatroom"},
+		{WxID: "wxid_offmember", GroupID: "offline@chatroom"},
+	}
+	if err := db.Create(&groups).Error; err != nil {
+		t.Fatalf("create groups: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  BotCoverageRequest
+		want map[string][]string // 群ID -> 建议（动作:wx_id）
+	}{
+		{
+			name: "all owners",
+			req:  BotCoverageRequest{},
+			want: map[string][]string{
+				"enable@chatroom":  {"enable_message_bot:wxid_member"},
+				"join@chatroom":    {"join_group:wxid_bot2", "join_group:wxid_bot1"},
+				"offline@chatroom": {"join_group:wxid_bot2", "join_group:wxid_bot1"},
+				"owner2@chatroom":  {"enable_message_bot:wxid_other"},
+			},
+		},
+		{
+			name: "owner filter with limit",
+			req:  BotCoverageRequest{OwnerID: 1, Limit: 1},
+			want: map[string][]string{
+				"enable@chatroom":  {"enable_message_bot:wxid_member"},
+				"join@chatroom":    {"join_group:wxid_bot2"},
+				"offline@chatroom": {"join_group:wxid_bot2"},
+			},
+		},
+		{
+			name: "other owner",
+			req:  BotCoverageRequest{OwnerID: 2},
+			want: map[string][]string{
+				"owner2@chatroom": {"enable_message_bot:wxid_other"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.GetBotCoverageGaps(tt.req)
+			if err != nil {
+				t.Fatalf("GetBotCoverageGaps: %v", err)
+			}
+			got := make(map[string][]string, len(resp.Gaps))
+			for _, gap := range resp.Gaps {
+				suggestions := make([]string, 0, len(gap.Suggestions))
+				for _, s := range gap.Suggestions {
+					suggestions = append(suggestions, s.Action+":"+s.WxID)
+				}
+				got[gap.GroupID] = suggestions
+			}
+			if resp.UncoveredGroups != len(tt.want) || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("gaps = %v (uncovered %d), want %v", got, resp.UncoveredGroups, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchGroupMessages(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	msgTime := func(value string) int64 {
+		tm, err := ParseTime(value)
+		if err != nil {
+			t.Fatalf("ParseTime: %v", err)
+		}
+		return tm.Unix()
+	}
+	messages := []WxGroupMessage{
+		{GroupID: "a@chatroom", OwnerID: 1, Content: "今天的账单已确认", MsgTime: msgTime("2026-01-01 09:00:00")},
+		{GroupID: "a@chatroom", OwnerID: 1, Content: "收到，账单明天核对", MsgTime: msgTime("2026-01-02 09:00:00")},
+		{GroupID: "b@chatroom", OwnerID: 1, Content: "请发一下账单", MsgTime: msgTime("2026-01-03 09:00:00")},
+		{GroupID: "a@chatroom", OwnerID: 1, Content: "大家早上好", MsgTime: msgTime("2026-01-04 09:00:00")},
+		{GroupID: "c@chatroom", OwnerID: 2, Content: "其他公司的账单", MsgTime: msgTime("2026-01-05 09:00:00")},
+	}
+	if err := db.Create(&messages).Error; err != nil {
+		t.Fatalf("create messages: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		req       GroupMessageSearchRequest
+		want      []string
+		wantTotal int64
+	}{
+		{name: "keyword latest first", req: GroupMessageSearchRequest{Keyword: "账单", OwnerID: 1}, want: []string{"请发一下账单", "收到，账单明天核对", "今天的账单已确认"}, wantTotal: 3},
+		{name: "group filter", req: GroupMessageSearchRequest{Keyword: "账单", GroupID: "a@chatroom"}, want: []string{"收到，账单明天核对", "今天的账单已确认"}, wantTotal: 2},
+		{name: "time range", req: GroupMessageSearchRequest{Keyword: "账单", OwnerID: 1, StartTime: "2026-01-02 00:00:00", EndTime: "2026-01-02 23:59:59"}, want: []string{"收到，账单明天核对"}, wantTotal: 1},
+		{name: "paged", req: GroupMessageSearchRequest{Keyword: "账单", PageNo: 2, PageSize: 3}, want: []string{"今天的账单已确认"}, wantTotal: 4},
+		{name: "no match", req: GroupMessageSearchRequest{Keyword: "退款"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.req.PageNo == 0 {
+				tt.req.PageNo, tt.req.PageSize = 1, 10
+			}
+			resp, err := svc.SearchGroupMessages(tt.req)
+			if err != nil {
+				t.Fatalf("SearchGroupMessages: %v", err)
+			}
+			got := make([]string, 0, len(resp.List))
+			for _, msg := range resp.List {
+				got = append(got, msg.Content)
+			}
+			if !reflect.DeepEqual(got, tt.want) || resp.Pagination.TotalCount != tt.wantTotal {
+				t.Fatalf("messages = %v (total %d), want %v (total %d)", got, resp.Pagination.TotalCount, tt.want, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestListAuthKeys(t *testing.T) {
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.GenAuthKey: jsonHandler(map[string]interface{}{"Code": 200, "Data": []string{"auth-1", "auth-2", "auth-3"}}),
+	})
+	svc, db := newTestService(t, nil)
+	robot := &WxRobotConfig{Address: server.URL}
+	createTestRobot(t, db, robot)
+	other := &WxRobotConfig{Address: "http://other.invalid"}
+	createTestRobot(t, db, other)
+
+	// 重复生成同样的码只记录一次
+	for i := 0; i < 2; i++ {
+		if _, err := svc.GenAuthKey(context.Background(), robot, 3, 30); err != nil {
+			t.Fatalf("GenAuthKey: %v", err)
+		}
+	}
+	// auth-2 已被账号使用；其他机器人下同token的账号不算分配
+	if err := db.Create(&WxUserLogin{RobotID: robot.ID, WxID: "wxid_a", NickName: "甲", Token: "auth-2"}).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := db.Create(&WxUserLogin{RobotID: other.ID, WxID: "wxid_b", Token: "auth-3"}).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		robot  uint
+		status string
+		want   []string // 授权码:状态
+	}{
+		{name: "all", robot: robot.ID, want: []string{"auth-3:unassigned", "auth-2:assigned", "auth-1:unassigned"}},
+		{name: "assigned", robot: robot.ID, status: AuthKeyStatusAssigned, want: []string{"auth-2:assigned"}},
+		{name: "unassigned", robot: robot.ID, status: AuthKeyStatusUnassigned, want: []string{"auth-3:unassigned", "auth-1:unassigned"}},
+		{name: "other robot", robot: other.ID, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.ListAuthKeys(tt.robot, tt.status)
+			if err != nil {
+				t.Fatalf("ListAuthKeys: %v", err)
+			}
+			got := make([]string, 0, len(resp.Keys))
+			for _, key := range resp.Keys {
+				got = append(got, key.AuthKey+":"+key.Status)
+				if key.Days != 30 || key.ExpireTime == "" || key.Source != AuthKeySourceManual {
+					t.Fatalf("key = %+v, want manual key of 30 days with expire time", key)
+				}
+				if key.Status == AuthKeyStatusAssigned && (key.WxID != "wxid_a" || key.NickName != "甲") {
+					t.Fatalf("assigned key = %+v, want user wxid_a", key)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("keys = %v, want %v", got, tt.want)
+			}
+			if tt.robot == robot.ID && (resp.Total != 3 || resp.Assigned != 1 || resp.Unassigned != 2) {
+				t.Fatalf("counts total=%d assigned=%d unassigned=%d", resp.Total, resp.Assigned, resp.Unassigned)
+			}
+		})
+	}
+}
+
+func TestPreviousPeriod(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		wantStart string
+		wantEnd   string
+	}{
+		{name: "one day", start: "2024-03-02 00:00:00", end: "2024-03-02 23:59:59", wantStart: "2024-03-01 00:00:00", wantEnd: "2024-03-01 23:59:59"},
+		{name: "one week", start: "2024-03-08 00:00:00", end: "2024-03-14 23:59:59", wantStart: "2024-03-01 00:00:00", wantEnd: "2024-03-07 23:59:59"},
+		{name: "across month", start: "2024-03-01 00:00:00", end: "2024-03-10 23:59:59", wantStart: "2024-02-20 00:00:00", wantEnd: "2024-02-29 23:59:59"},
+		{name: "single second", start: "2024-03-01 12:00:00", end: "2024-03-01 12:00:00", wantStart: "2024-03-01 11:59:59", wantEnd: "2024-03-01 11:59:59"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, _ := ParseTime(tt.start)
+			end, _ := ParseTime(tt.end)
+			prevStart, prevEnd := previousPeriod(start, end)
+			if got := FormatTime(prevStart); got != tt.wantStart {
+				t.Fatalf("previous start = %s, want %s", got, tt.wantStart)
+			}
+			if got := FormatTime(prevEnd); got != tt.wantEnd {
+				t.Fatalf("previous end = %s, want %s", got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestChangeRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  float64
+		previous float64
+		want     *float64
+	}{
+		{name: "increase", current: 150, previous: 100, want: floatPtr(50)},
+		{name: "decrease", current: 75, previous: 100, want: floatPtr(-25)},
+		{name: "unchanged", current: 100, previous: 100, want: floatPtr(0)},
+		{name: "rounded", current: 2, previous: 3, want: floatPtr(-33.33)},
+		{name: "from zero current", current: 0, previous: 80, want: floatPtr(-100)},
+		{name: "previous zero", current: 100, previous: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changeRate(tt.current, tt.previous)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("changeRate(%v, %v) = %v, want %v", tt.current, tt.previous, got, tt.want)
+			}
+		})
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestGetBillStatisticsComparison(t *testing.T) {
+	unix := func(s string) int64 {
+		ts, err := ParseTime(s)
+		if err != nil {
+			t.Fatalf("parse %q: %v", s, err)
+		}
+		return ts.Unix()
+	}
+	svc, db := newTestService(t, nil)
+	createTestBills(t, db,
+		// 上期 2024-03-01
+		WxBillInfo{OwnerID: 1, GroupID: "1@chatroom", GroupName: "群1", Amount: "60.00", MsgTime: unix("2024-03-01 09:00:00")},
+		WxBillInfo{OwnerID: 1, GroupID: "2@chatroom", GroupName: "群2", Amount: "40.00", MsgTime: unix("2024-03-01 23:59:59")},
+		// 本期 2024-03-02
+		WxBillInfo{OwnerID: 1, GroupID: "1@chatroom", GroupName: "群1", Amount: "100.00", MsgTime: unix("2024-03-02 00:00:00")},
+		WxBillInfo{OwnerID: 1, GroupID: "1@chatroom", GroupName: "群1", Amount: "20.50", MsgTime: unix("2024-03-02 18:00:00")},
+		WxBillInfo{OwnerID: 1, GroupID: "2@chatroom", GroupName: "群2", Amount: "29.50", MsgTime: unix("2024-03-02 23:59:59")},
+		// 范围外与其他公司
+		WxBillInfo{OwnerID: 1, GroupID: "1@chatroom", GroupName: "群1", Amount: "500.00", MsgTime: unix("2024-02-29 23:59:59")},
+		WxBillInfo{OwnerID: 2, GroupID: "3@chatroom", GroupName: "群3", Amount: "999.00", MsgTime: unix("2024-03-02 12:00:00")},
+	)
+
+	tests := []struct {
+		name    string
+		req     BillStatsRequest
+		want    BillStatsComparison
+		wantErr error
+	}{
+		{
+			name: "all groups",
+			req:  BillStatsRequest{CreateTimeStart: "2024-03-02 00:00:00", CreateTimeEnd: "2024-03-02 23:59:59"},
+			want: BillStatsComparison{
+				CurrentStart: "2024-03-02 00:00:00", CurrentEnd: "2024-03-02 23:59:59",
+				PreviousStart: "2024-03-01 00:00:00", PreviousEnd: "2024-03-01 23:59:59",
+				CurrentAmount: "150.00", PreviousAmount: "100.00", CurrentCount: 3, PreviousCount: 2,
+				AmountChangeRate: floatPtr(50), CountChangeRate: floatPtr(50),
+			},
+		},
+		{
+			name: "single group",
+			req:  BillStatsRequest{GroupID: "2@chatroom", CreateTimeStart: "2024-03-02 00:00:00", CreateTimeEnd: "2024-03-02 23:59:59"},
+			want: BillStatsComparison{
+				CurrentStart: "2024-03-02 00:00:00", CurrentEnd: "2024-03-02 23:59:59",
+				PreviousStart: "2024-03-01 00:00:00", PreviousEnd: "2024-03-01 23:59:59",
+				CurrentAmount: "29.50", PreviousAmount: "40.00", CurrentCount: 1, PreviousCount: 1,
+				AmountChangeRate: floatPtr(-26.25), CountChangeRate: floatPtr(0),
+			},
+		},
+		{
+			name: "previous period empty",
+			req:  BillStatsRequest{CreateTimeStart: "2024-03-03 00:00:00", CreateTimeEnd: "2024-03-03 23:59:59"},
+			want: BillStatsComparison{
+				CurrentStart: "2024-03-03 00:00:00", CurrentEnd: "2024-03-03 23:59:59",
+				PreviousStart: "2024-03-02 00:00:00", PreviousEnd: "2024-03-02 23:59:59",
+				CurrentAmount: "0.00", PreviousAmount: "150.00", CurrentCount: 0, PreviousCount: 3,
+				AmountChangeRate: floatPtr(-100), CountChangeRate: floatPtr(-100),
+			},
+		},
+		{name: "missing end", req: BillStatsRequest{CreateTimeStart: "2024-03-02 00:00:00"}, wantErr: ErrBillCompareRange},
+		{name: "start after end", req: BillStatsRequest{CreateTimeStart: "2024-03-03 00:00:00", CreateTimeEnd: "2024-03-02 00:00:00"}, wantErr: ErrBillCompareRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.OwnerID, req.PageNo, req.PageSize, req.Compare = 1, 1, 10, true
+			resp, err := svc.GetBillStatistics(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetBillStatistics err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if resp.Comparison == nil || !reflect.DeepEqual(*resp.Comparison, tt.want) {
+				t.Fatalf("comparison = %+v, want %+v", resp.Comparison, tt.want)
+			}
+		})
+	}
+}
+
+func TestListNeedReloginUsers(t *testing.T) {
+	svc, db := newTestService(t, nil)
+	robots := []*WxRobotConfig{{Address: "http://r1.invalid", OwnerID: 1}, {Address: "http://r2.invalid", OwnerID: 1}, {Address: "http://r3.invalid", OwnerID: 2}}
+	createTestRobot(t, db, robots[0],
+		&WxUserLogin{WxID: "wxid_a1", Token: "token+a1", Status: UserStatusRelogin},
+		&WxUserLogin{WxID: "wxid_a2", Token: "token-a2", Status: UserStatusNormal})
+	createTestRobot(t, db, robots[1],
+		&WxUserLogin{WxID: "wxid_b1", Token: "token-b1", Status: UserStatusRelogin},
+		&WxUserLogin{WxID: "wxid_b2", Token: "token-b2", Status: UserStatusRisk})
+	createTestRobot(t, db, robots[2],
+		&WxUserLogin{WxID: "wxid_c1", Token: "token-c1", Status: UserStatusRelogin})
+	rawTokens := map[string]string{"wxid_a1": "token+a1", "wxid_b1": "token-b1", "wxid_c1": "token-c1"}
+
+	tests := []struct {
+		name      string
+		req       NeedReloginRequest
+		wantWx    []string
+		wantTotal int64
+	}{
+		{name: "all owners", req: NeedReloginRequest{}, wantWx: []string{"wxid_c1", "wxid_b1", "wxid_a1"}, wantTotal: 3},
+		{name: "by owner", req: NeedReloginRequest{OwnerID: 1}, wantWx: []string{"wxid_b1", "wxid_a1"}, wantTotal: 2},
+		{name: "by robot", req: NeedReloginRequest{RobotID: robots[0].ID}, wantWx: []string{"wxid_a1"}, wantTotal: 1},
+		{name: "second page", req: NeedReloginRequest{OwnerID: 1, PageNo: 2, PageSize: 1}, wantWx: []string{"wxid_a1"}, wantTotal: 2},
+		{name: "none", req: NeedReloginRequest{OwnerID: 3}, wantWx: []string{}, wantTotal: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req.PageNo == 0 {
+				req.PageNo, req.PageSize = 1, 10
+			}
+			resp, err := svc.ListNeedReloginUsers(req)
+			if err != nil {
+				t.Fatalf("ListNeedReloginUsers: %v", err)
+			}
+			got := make([]string, 0, len(resp.List))
+			for _, user := range resp.List {
+				got = append(got, user.WxID)
+				if want := maskToken(rawTokens[user.WxID]); user.Token != want {
+					t.Errorf("%s token = %s, want masked %s", user.WxID, user.Token, want)
+				}
+				if user.QRCodeURL != "/api/wx/v1/users/need-relogin/qrcodes" {
+					t.Errorf("%s qrcode_url = %s, want batch qrcode endpoint", user.WxID, user.QRCodeURL)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantWx) {
+				t.Fatalf("users = %v, want %v", got, tt.wantWx)
+			}
+			if resp.Pagination.TotalCount != tt.wantTotal {
+				t.Fatalf("total_count = %d, want %d", resp.Pagination.TotalCount, tt.wantTotal)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+var testDBSeq int64
+
+// testModels 测试库需要建表的模型
+var testModels = []interface{}{
+	&WxRobotConfig{}, &WxUserLogin{}, &WxUserStatusLog{}, &WxUserExtensionLog{},
+	&WxGroup{}, &WxGroupLeaveLog{}, &WxGroupBlacklist{}, &WxGroupMember{},
+	&WxBillInfo{}, &WxGroupMessage{}, &WxSendRecord{}, &WxAuthKey{},
+	&WxSendAudit{}, &WxSendRetry{}, &WxRobotConfigChange{}, &SchemaMigration{},
+}
+
+// newTestDB 创建独立的内存数据库并按模型建表
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:testdb%d?mode=memory&cache=shared", atomic.AddInt64(&testDBSeq, 1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	if err := db.AutoMigrate(testModels...); err != nil {
+		t.Fatalf("migrate test db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// newTestService 创建使用内存数据库的服务，cfg为nil时使用空配置
+func newTestService(t *testing.T, cfg *Config) (*wxRobotService, *gorm.DB) {
+	t.Helper()
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	db := newTestDB(t)
+	svc, err := NewWxRobotService(cfg, db, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewWxRobotService: %v", err)
+	}
+	return svc.(*wxRobotService), db
+}
+
+// newTestRobotServer 模拟外部机器人API，按请求路径返回handlers中的响应，未登记的路径返回404
+func newTestRobotServer(t *testing.T, handlers map[string]http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// jsonHandler 返回固定JSON响应的handler
+func jsonHandler(v interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+// sendSuccessResponse 外部发送消息接口的成功响应
+func sendSuccessResponse(newMsgID int) map[string]interface{} {
+	return map[string]interface{}{
+		"Code": 200,
+		"Data": []map[string]interface{}{{
+			"isSendSuccess": true,
+			"resp": map[string]interface{}{
+				"base_response":      map[string]interface{}{"ret": 0},
+				"chat_send_ret_list": []map[string]interface{}{{"ret": 0, "newMsgId": newMsgID}},
+			},
+		}},
+	}
+}
+
+// sendRawResponse 外部发送语音、视频、卡片消息接口的响应，ret不为0时为发送失败
+func sendRawResponse(ret int, errMsg string, newMsgID int64) map[string]interface{} {
+	return map[string]interface{}{
+		"Code": 200,
+		"Data": []map[string]interface{}{{
+			"isSendSuccess": ret == 0,
+			"resp": map[string]interface{}{
+				"baseResponse": map[string]interface{}{"ret": ret, "errMsg": map[string]interface{}{"str": errMsg}},
+				"msgId":        newMsgID - 1,
+				"newMsgId":     newMsgID,
+			},
+		}},
+	}
+}
+
+// createTestRobot 创建机器人及其下的用户
+func createTestRobot(t *testing.T, db *gorm.DB, robot *WxRobotConfig, users ...*WxUserLogin) {
+	t.Helper()
+	if robot.AdminKey == "" {
+		robot.AdminKey = "admin-key"
+	}
+	if err := db.Create(robot).Error; err != nil {
+		t.Fatalf("create robot: %v", err)
+	}
+	for _, user := range users {
+		user.RobotID = robot.ID
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+}
+
+// newTestRouter 创建使用内存数据库的完整路由，cfg为nil时使用空配置（不鉴权）
+func newTestRouter(t *testing.T, cfg *Config) (*gin.Engine, *wxRobotService, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	svc, db := newTestService(t, cfg)
+	rm, err := NewRouterManager(cfg, zap.NewNop(), svc, nil)
+	if err != nil {
+		t.Fatalf("NewRouterManager: %v", err)
+	}
+	return rm.InitRoutes(cfg), svc, db
+}
+
+// doRequest 向路由发送JSON请求，path不含 /api/wx/v1 前缀，headers为请求头键值对
+func doRequest(router http.Handler, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/wx/v1"+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
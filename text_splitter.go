@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// 长文本分段策略
+const (
+	TextSplitStrategyParagraph = "paragraph" // 按段落（换行）拆分，单段超长时再按固定长度拆分
+	TextSplitStrategyFixed     = "fixed"     // 按固定长度拆分
+)
+
+// splitText 将超过maxLen（按字符数）的文本拆成多段，未超长时原样返回一段
+func splitText(text string, maxLen int, strategy string) []string {
+	if maxLen <= 0 || utf8.RuneCountInString(text) <= maxLen {
+		return []string{text}
+	}
+	if strategy == TextSplitStrategyFixed {
+		return splitTextFixed(text, maxLen)
+	}
+
+	var segments []string
+	var current strings.Builder
+	currentLen := 0
+	flush := func() {
+		if segment := strings.TrimRight(current.String(), "\n"); segment != "" {
+			segments = append(segments, segment)
+		}
+		current.Reset()
+		currentLen = 0
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineLen := utf8.RuneCountInString(line)
+		if currentLen > 0 && currentLen+lineLen > maxLen {
+			flush()
+		}
+
+		if lineLen <= maxLen {
+			current.WriteString(line)
+			currentLen += lineLen
+			continue
+		}
+
+		// 单段超长，按固定长度拆分，最后一块留给后续段落拼接
+		chunks := splitTextFixed(line, maxLen)
+		for _, chunk := range chunks[:len(chunks)-1] {
+			current.WriteString(chunk)
+			flush()
+		}
+		last := chunks[len(chunks)-1]
+		current.WriteString(last)
+		currentLen = utf8.RuneCountInString(last)
+	}
+	flush()
+
+	return segments
+}
+
+// splitTextFixed 按固定字符数拆分文本
+func splitTextFixed(text string, maxLen int) []string {
+	runes := []rune(text)
+	segments := make([]string, 0, (len(runes)+maxLen-1)/maxLen)
+	for start := 0; start < len(runes); start += maxLen {
+		end := start + maxLen
+		if end > len(runes) {
+			end = len(runes)
+		}
+		segments = append(segments, string(runes[start:end]))
+	}
+	return segments
+}
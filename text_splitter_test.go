@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxLen   int
+		strategy string
+		want     []string
+	}{
+		{name: "short text", text: "你好", maxLen: 10, strategy: TextSplitStrategyParagraph, want: []string{"你好"}},
+		{name: "split disabled", text: "你好世界", maxLen: 0, strategy: TextSplitStrategyParagraph, want: []string{"你好世界"}},
+		{name: "fixed by runes", text: "一二三四五六七", maxLen: 3, strategy: TextSplitStrategyFixed, want: []string{"一二三", "四五六", "七"}},
+		{name: "paragraphs merged up to limit", text: "第一段\n第二段\n第三段", maxLen: 8, strategy: TextSplitStrategyParagraph, want: []string{"第一段\n第二段", "第三段"}},
+		{name: "one paragraph per segment", text: "第一段落\n第二段落", maxLen: 5, strategy: TextSplitStrategyParagraph, want: []string{"第一段落", "第二段落"}},
+		{name: "long paragraph split fixed", text: "短\n" + strings.Repeat("长", 7), maxLen: 3, strategy: TextSplitStrategyParagraph, want: []string{"短", "长长长", "长长长", "长"}},
+		{name: "blank lines dropped", text: "甲乙\n\n\n丙丁", maxLen: 3, strategy: TextSplitStrategyParagraph, want: []string{"甲乙", "丙丁"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitText(tt.text, tt.maxLen, tt.strategy)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("splitText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
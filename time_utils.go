@@ -0,0 +1,91 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// TimeLayout 接口中时间字符串的统一格式
+const TimeLayout = "2006-01-02 15:04:05"
+
+// displayLocation 响应展示和解析请求时间参数使用的时区，数据库统一按UTC存储
+var displayLocation = time.Local
+
+// SetDisplayTimezone 设置展示时区，name为IANA时区名（如Asia/Shanghai），为空或Local时使用服务器本地时区
+func SetDisplayTimezone(name string) error {
+	if name == "" {
+		displayLocation = time.Local
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("无效的时区 %s: %w", name, err)
+	}
+	displayLocation = loc
+	return nil
+}
+
+// FormatTime 按展示时区格式化时间
+func FormatTime(t time.Time) string {
+	return t.In(displayLocation).Format(TimeLayout)
+}
+
+// FormatUnix 按展示时区格式化Unix时间戳（秒），0返回空字符串
+func FormatUnix(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	return FormatTime(time.Unix(ts, 0))
+}
+
+// ParseTime 按展示时区解析请求中的时间字符串
+func ParseTime(s string) (time.Time, error) {
+	return time.ParseInLocation(TimeLayout, s, displayLocation)
+}
+
+// dbTimeLayouts SQLite以文本存储时间时可能的格式
+var dbTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	TimeLayout,
+}
+
+// dbTime 聚合查询（MIN/MAX）得到的时间；MySQL返回time.Time，SQLite聚合结果丢失列类型返回字符串
+type dbTime struct {
+	time.Time
+}
+
+// Scan 实现sql.Scanner
+func (t *dbTime) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("无法将 %T 转换为时间", value)
+	}
+
+	for _, layout := range dbTimeLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("无法解析时间: %s", s)
+}
+
+// Value 实现driver.Valuer
+func (t dbTime) Value() (driver.Value, error) {
+	return t.Time, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatTimeAcrossTimezones(t *testing.T) {
+	// 同一时刻以不同时区的time.Time表示
+	instant := time.Date(2024, 5, 1, 2, 30, 0, 0, time.UTC)
+	newYork := time.FixedZone("EDT", -4*3600)
+
+	tests := []struct {
+		name     string
+		timezone string
+		want     string
+	}{
+		{name: "shanghai", timezone: "Asia/Shanghai", want: "2024-05-01 10:30:00"},
+		{name: "utc", timezone: "UTC", want: "2024-05-01 02:30:00"},
+		{name: "tokyo", timezone: "Asia/Tokyo", want: "2024-05-01 11:30:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := displayLocation
+			defer func() { displayLocation = orig }()
+			if err := SetDisplayTimezone(tt.timezone); err != nil {
+				t.Fatalf("SetDisplayTimezone: %v", err)
+			}
+
+			for _, ts := range []time.Time{instant, instant.In(newYork), instant.Local()} {
+				if got := FormatTime(ts); got != tt.want {
+					t.Fatalf("FormatTime(%v) = %q, want %q", ts, got, tt.want)
+				}
+			}
+			if got := FormatUnix(instant.Unix()); got != tt.want {
+				t.Fatalf("FormatUnix = %q, want %q", got, tt.want)
+			}
+
+			parsed, err := ParseTime(tt.want)
+			if err != nil || !parsed.Equal(instant) {
+				t.Fatalf("ParseTime(%q) = %v, %v, want %v", tt.want, parsed, err, instant)
+			}
+		})
+	}
+}
+
+func TestTimeUtilsEdgeCases(t *testing.T) {
+	if got := FormatUnix(0); got != "" {
+		t.Errorf("FormatUnix(0) = %q, want empty", got)
+	}
+	if err := SetDisplayTimezone("Mars/Olympus"); err == nil {
+		t.Error("SetDisplayTimezone accepted invalid timezone")
+	}
+	if _, err := ParseTime("2024/05/01"); err == nil {
+		t.Error("ParseTime accepted invalid layout")
+	}
+}
+
+func TestDBTimeScan(t *testing.T) {
+	want := time.Date(2024, 5, 1, 2, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "time value", value: want, want: want},
+		{name: "sqlite text", value: "2024-05-01 02:30:00+00:00", want: want},
+		{name: "bytes", value: []byte("2024-05-01 02:30:00"), want: want},
+		{name: "null", value: nil},
+		{name: "invalid text", value: "yesterday", wantErr: true},
+		{name: "unsupported type", value: 42, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got dbTime
+			err := got.Scan(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Time.Equal(tt.want) {
+				t.Fatalf("Scan = %v, want %v", got.Time, tt.want)
+			}
+		})
+	}
+}
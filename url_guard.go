@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrURLBlocked 消息内容中的链接未通过安全检查
+var ErrURLBlocked = errors.New("消息内容包含禁止发送的链接")
+
+// contentURLPattern 匹配内容中以http(s)://或www.开头的链接，遇到空白或中文标点结束
+var contentURLPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)[^\s<>"'，。！？；、（）【】“”]+`)
+
+// URLGuard 发送内容链接安全检查：白名单域名直接放行，黑名单域名拒绝发送，其余可选交给外部安全检测接口
+type URLGuard struct {
+	enable     bool
+	allow      []string
+	block      []string
+	checkerURL string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+// URLCheckResult 外部安全检测接口的响应，请求体为 {"url": "..."}
+type URLCheckResult struct {
+	Safe   bool   `json:"safe"`
+	Reason string `json:"reason"`
+}
+
+// NewURLGuard 创建链接安全检查
+func NewURLGuard(cfg URLCheckConfig, logger *zap.Logger) *URLGuard {
+	timeout := cfg.CheckerTimeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	return &URLGuard{
+		enable:     cfg.Enable,
+		allow:      normalizeDomains(cfg.AllowDomains),
+		block:      normalizeDomains(cfg.BlockDomains),
+		checkerURL: strings.TrimSpace(cfg.CheckerURL),
+		httpClient: &http.Client{Timeout: timeout},
+		logger:     logger,
+	}
+}
+
+// normalizeDomains 域名统一小写并去掉前导的点
+func normalizeDomains(domains []string) []string {
+	result := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" {
+			result = append(result, domain)
+		}
+	}
+	return result
+}
+
+// matchDomain 判断host是否为列表中的域名或其子域名
+func matchDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractURLs 提取内容中的链接
+func extractURLs(text string) []string {
+	return contentURLPattern.FindAllString(text, -1)
+}
+
+// urlHost 解析链接的域名，www.开头的链接补全协议
+func urlHost(rawURL string) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// Check 检查内容中的所有链接，命中黑名单或外部检测判定不安全时返回ErrURLBlocked
+// 外部检测接口调用失败时放行，避免检测服务故障导致无法发送
+func (g *URLGuard) Check(text string) error {
+	if !g.enable {
+		return nil
+	}
+
+	for _, rawURL := range extractURLs(text) {
+		host := urlHost(rawURL)
+		if host == "" || matchDomain(host, g.allow) {
+			continue
+		}
+		if matchDomain(host, g.block) {
+			g.logger.Warn("消息内容包含黑名单链接，拒绝发送", zap.String("url", rawURL))
+			return fmt.Errorf("%w: %s", ErrURLBlocked, host)
+		}
+		if g.checkerURL == "" {
+			continue
+		}
+
+		result, err := g.checkRemote(rawURL)
+		if err != nil {
+			g.logger.Warn("链接安全检测失败，放行", zap.String("url", rawURL), zap.Error(err))
+			continue
+		}
+		if !result.Safe {
+			g.logger.Warn("链接安全检测未通过，拒绝发送",
+				zap.String("url", rawURL),
+				zap.String("reason", result.Reason))
+			return fmt.Errorf("%w: %s %s", ErrURLBlocked, host, result.Reason)
+		}
+	}
+	return nil
+}
+
+// checkRemote 调用外部安全检测接口检查单个链接
+func (g *URLGuard) checkRemote(rawURL string) (*URLCheckResult, error) {
+	body, err := json.Marshal(map[string]string{"url": rawURL})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.httpClient.Post(g.checkerURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("安全检测接口返回状态码 %d", resp.StatusCode)
+	}
+
+	var result URLCheckResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析安全检测结果失败: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestURLGuardCheck(t *testing.T) {
+	// 外部检测接口：包含 phish 的链接判定为不安全
+	checker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			URL string `json:"url"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(URLCheckResult{Safe: req.URL != "https://phish.example.net/login", Reason: "钓鱼"})
+	}))
+	defer checker.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	cfg := URLCheckConfig{Enable: true, AllowDomains: []string{"Example.com"}, BlockDomains: []string{".bad.cn", "evil.com"}}
+
+	tests := []struct {
+		name        string
+		disabled    bool
+		checkerURL  string
+		text        string
+		wantBlocked bool
+	}{
+		{name: "no link", text: "今晚八点开会"},
+		{name: "normal link", text: "详情见 https://news.qq.com/a/1"},
+		{name: "blocked domain", text: "点击 https://evil.com/x 领取", wantBlocked: true},
+		{name: "blocked subdomain", text: "访问www.shop.bad.cn，立即领取", wantBlocked: true},
+		{name: "blocked host uppercase", text: "HTTP://EVIL.COM/a", wantBlocked: true},
+		{name: "similar domain not blocked", text: "https://notevil.com/a"},
+		{name: "allowed subdomain", text: "https://docs.example.com/guide"},
+		{name: "second link blocked", text: "https://example.com 和 https://evil.com", wantBlocked: true},
+		{name: "disabled", disabled: true, text: "https://evil.com/x"},
+		{name: "checker rejects", checkerURL: checker.URL, text: "请登录 https://phish.example.net/login", wantBlocked: true},
+		{name: "checker accepts", checkerURL: checker.URL, text: "https://safe.example.net/"},
+		{name: "allowed skips checker", checkerURL: broken.URL, text: "https://example.com/a"},
+		{name: "checker failure passes", checkerURL: broken.URL, text: "https://phish.example.net/login"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			c.Enable = !tt.disabled
+			c.CheckerURL = tt.checkerURL
+			err := NewURLGuard(c, zap.NewNop()).Check(tt.text)
+			if blocked := errors.Is(err, ErrURLBlocked); blocked != tt.wantBlocked || (err != nil && !blocked) {
+				t.Fatalf("Check(%q) = %v, want blocked %v", tt.text, err, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestSendTextBlockedURL(t *testing.T) {
+	const groupID = "url@chatroom"
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantSent   bool
+	}{
+		{name: "send text allowed", path: "/messages/group/send-text", body: `{"text_content":"https://example.com","to_user_name":"` + groupID + `"}`, wantStatus: http.StatusOK, wantSent: true},
+		{name: "send text blocked", path: "/messages/group/send-text", body: `{"text_content":"https://evil.com","to_user_name":"` + groupID + `"}`, wantStatus: http.StatusForbidden},
+		{name: "send text multi blocked", path: "/messages/group/send-text-multi", body: `{"text_content":"https://evil.com","to_user_names":["` + groupID + `"]}`, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent bool
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendTextMessage: func(w http.ResponseWriter, r *http.Request) {
+					sent = true
+					jsonHandler(sendSuccessResponse(1))(w, r)
+				},
+			})
+			cfg := &Config{}
+			cfg.Security.URLCheck = URLCheckConfig{Enable: true, BlockDomains: []string{"evil.com"}}
+			router, _, db := newTestRouter(t, cfg)
+			bot := &WxUserLogin{WxID: "wxid_url", Token: "token-url", IsMessageBot: 1}
+			createTestRobot(t, db, &WxRobotConfig{Address: server.URL, OwnerID: 1}, bot)
+			db.Create(&WxGroup{WxID: bot.WxID, GroupID: groupID})
+
+			w := doRequest(router, http.MethodPost, tt.path, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if sent != tt.wantSent {
+				t.Fatalf("sent = %v, want %v", sent, tt.wantSent)
+			}
+		})
+	}
+}
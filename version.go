@@ -0,0 +1,36 @@
+package main
+
+import "runtime"
+
+// 构建信息，编译时通过 ldflags 注入，例如：
+// go build -ldflags "-X main.BuildTime=$(date '+%Y-%m-%d %H:%M:%S') -X main.GitCommit=$(git rev-parse --short HEAD)"
+var (
+	Version   = "" // 为空时使用配置文件中的 app.version
+	BuildTime = "unknown"
+	GitCommit = "unknown"
+)
+
+// VersionInfo 版本与构建信息
+type VersionInfo struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GitCommit string `json:"git_commit"`
+	GoVersion string `json:"go_version"`
+}
+
+// getVersionInfo 汇总版本与构建信息
+func getVersionInfo(app AppConfig) VersionInfo {
+	version := Version
+	if version == "" {
+		version = app.Version
+	}
+
+	return VersionInfo{
+		Name:      app.Name,
+		Version:   version,
+		BuildTime: BuildTime,
+		GitCommit: GitCommit,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestGetVersionInfo(t *testing.T) {
+	app := AppConfig{Name: "wx-msg-api", Version: "1.2.0"}
+
+	tests := []struct {
+		name        string
+		ldVersion   string
+		wantVersion string
+	}{
+		{name: "config version", wantVersion: "1.2.0"},
+		{name: "ldflags version wins", ldVersion: "1.3.0-rc1", wantVersion: "1.3.0-rc1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := Version
+			Version = tt.ldVersion
+			defer func() { Version = orig }()
+
+			info := getVersionInfo(app)
+			if info.Name != app.Name || info.Version != tt.wantVersion {
+				t.Fatalf("info = %+v, want name %q version %q", info, app.Name, tt.wantVersion)
+			}
+			if info.BuildTime != BuildTime || info.GitCommit != GitCommit || info.GoVersion != runtime.Version() {
+				t.Fatalf("build info = %+v", info)
+			}
+		})
+	}
+}
+
+func TestVersionRoute(t *testing.T) {
+	origCommit := GitCommit
+	GitCommit = "abc1234"
+	defer func() { GitCommit = origCommit }()
+
+	router, _, _ := newTestRouter(t, &Config{App: AppConfig{Name: "wx-msg-api", Version: "1.2.0"}})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data VersionInfo `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Data.Version != "1.2.0" || resp.Data.GitCommit != "abc1234" {
+		t.Fatalf("version info = %+v", resp.Data)
+	}
+}
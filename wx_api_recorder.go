@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 录制时超过该长度的字符串字段（如图片base64）只保留前缀
+const recordMaxStringLen = 256
+
+// recordEntry 单次外部调用的录制内容，每行一条JSON
+type recordEntry struct {
+	Time         string          `json:"time"`
+	Method       string          `json:"method"`
+	URL          string          `json:"url"`
+	RequestBody  json.RawMessage `json:"request_body,omitempty"`
+	Status       int             `json:"status,omitempty"`
+	ResponseBody json.RawMessage `json:"response_body,omitempty"`
+	DurationMs   int64           `json:"duration_ms"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// recordingTransport 录制模式，把外部调用的请求体和响应体脱敏后写入单独文件，便于联调回放
+type recordingTransport struct {
+	next      http.RoundTripper
+	endpoints []string // 只录制这些路径，为空时录制全部
+	keyPath   string   // 生成授权码接口路径，响应Data是授权码列表，需整体隐藏
+	writer    io.Writer
+	logger    *zap.Logger
+	mu        sync.Mutex
+}
+
+// newRecordingTransport 创建录制transport，包装原有transport
+func newRecordingTransport(cfg WxAPIRecordConfig, keyPath string, next http.RoundTripper, logger *zap.Logger) *recordingTransport {
+	filePath := cfg.FilePath
+	if filePath == "" {
+		filePath = "./logs/wx_api_record.log"
+	}
+
+	logger.Info("外部接口录制模式已开启",
+		zap.String("file_path", filePath),
+		zap.Strings("endpoints", cfg.Endpoints))
+
+	return &recordingTransport{
+		next:      next,
+		endpoints: cfg.Endpoints,
+		keyPath:   keyPath,
+		writer: &lumberjack.Logger{
+			Filename:   filePath,
+			MaxSize:    100, // MB
+			MaxBackups: 3,
+			LocalTime:  true,
+		},
+		logger: logger,
+	}
+}
+
+// RoundTrip 实现http.RoundTripper，录制失败不影响实际请求
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.shouldRecord(req.URL.Path) {
+		return t.next.RoundTrip(req)
+	}
+
+	entry := recordEntry{
+		Time:   time.Now().Format("2006-01-02 15:04:05.000"),
+		Method: req.Method,
+		URL:    sanitizeRecordURL(req),
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			entry.RequestBody = sanitizeRecordBody(data, false)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	entry.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		entry.Error = err.Error()
+		t.write(entry)
+		return nil, err
+	}
+
+	// 读取响应体后重新放回，调用方照常读取
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		entry.Status = resp.StatusCode
+		entry.Error = err.Error()
+		t.write(entry)
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	entry.Status = resp.StatusCode
+	entry.ResponseBody = sanitizeRecordBody(data, t.keyPath != "" && strings.HasSuffix(req.URL.Path, t.keyPath))
+	t.write(entry)
+	return resp, nil
+}
+
+// shouldRecord 判断请求路径是否在录制范围内
+func (t *recordingTransport) shouldRecord(path string) bool {
+	if len(t.endpoints) == 0 {
+		return true
+	}
+	for _, endpoint := range t.endpoints {
+		if strings.HasSuffix(path, endpoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// write 写入一条录制记录
+func (t *recordingTransport) write(entry recordEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		t.logger.Warn("序列化录制记录失败", zap.Error(err))
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.writer.Write(append(line, '\n')); err != nil {
+		t.logger.Warn("写入录制文件失败", zap.Error(err))
+	}
+}
+
+// sanitizeRecordURL 隐藏URL中的授权key
+func sanitizeRecordURL(req *http.Request) string {
+	u := *req.URL
+	query := u.Query()
+	if query.Has("key") {
+		query.Set("key", "***")
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// sanitizeRecordBody 对JSON内容脱敏：隐藏key/token/password类字段，截断超长字符串
+// maskData为true时Data整体隐藏；非JSON内容按字符串记录
+func sanitizeRecordBody(data []byte, maskData bool) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		raw, _ := json.Marshal(truncateRecordString(string(data)))
+		return raw
+	}
+
+	if obj, ok := v.(map[string]interface{}); ok && maskData {
+		if _, exists := obj["Data"]; exists {
+			obj["Data"] = "***"
+		}
+	}
+
+	raw, err := json.Marshal(sanitizeRecordValue("", v))
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// sanitizeRecordValue 递归脱敏JSON值
+func sanitizeRecordValue(field string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = sanitizeRecordValue(k, item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = sanitizeRecordValue(field, item)
+		}
+		return val
+	case string:
+		if isSensitiveRecordField(field) && val != "" {
+			return "***"
+		}
+		return truncateRecordString(val)
+	default:
+		return val
+	}
+}
+
+// isSensitiveRecordField 判断字段是否为敏感字段
+func isSensitiveRecordField(field string) bool {
+	lower := strings.ToLower(field)
+	return strings.Contains(lower, "key") || strings.Contains(lower, "token") || strings.Contains(lower, "password")
+}
+
+// truncateRecordString 截断超长字符串
+func truncateRecordString(s string) string {
+	if len(s) <= recordMaxStringLen {
+		return s
+	}
+	return fmt.Sprintf("%s...(共%d字节)", s[:recordMaxStringLen], len(s))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+func TestRecordingTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin/GenAuthKey1":
+			w.Write([]byte(`{"Code":200,"Data":["secret-key-1"]}`))
+		default:
+			w.Write([]byte(`{"Code":200,"Data":{"Token":"tok-123","Nick":"小王"}}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		endpoints    []string
+		path         string
+		body         string
+		wantRecorded bool
+		wantContains []string
+		wantHidden   []string
+	}{
+		{
+			name:         "record all",
+			path:         "/message/SendTextMessage",
+			body:         `{"TextContent":"你好","Password":"p@ss"}`,
+			wantRecorded: true,
+			wantContains: []string{"你好", "小王", "key=%2A%2A%2A"},
+			wantHidden:   []string{"p@ss", "tok-123", "secret-query-key"},
+		},
+		{name: "endpoint filtered out", endpoints: []string{"/login/GetLoginStatus"}, path: "/message/SendTextMessage", body: `{}`},
+		{name: "endpoint matched", endpoints: []string{"/message/SendTextMessage"}, path: "/message/SendTextMessage", body: `{}`, wantRecorded: true},
+		{
+			name:         "auth key list masked",
+			path:         "/admin/GenAuthKey1",
+			body:         `{"Count":1}`,
+			wantRecorded: true,
+			wantHidden:   []string{"secret-key-1"},
+		},
+		{
+			name:         "long string truncated",
+			path:         "/message/SendImageNewMessage",
+			body:         `{"ImageContent":"` + strings.Repeat("A", 1000) + `"}`,
+			wantRecorded: true,
+			wantContains: []string{"(共1000字节)"},
+			wantHidden:   []string{strings.Repeat("A", recordMaxStringLen+1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "record.log")
+			transport := newRecordingTransport(WxAPIRecordConfig{Enable: true, FilePath: filePath, Endpoints: tt.endpoints},
+				"/admin/GenAuthKey1", http.DefaultTransport, zap.NewNop())
+			defer transport.writer.(*lumberjack.Logger).Close()
+
+			client := &http.Client{Transport: transport}
+			resp, err := client.Post(server.URL+tt.path+"?key=secret-query-key", "application/json", bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			respBody, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if !bytes.Contains(respBody, []byte(`"Code":200`)) {
+				t.Fatalf("response body not passed through: %s", respBody)
+			}
+
+			data, _ := os.ReadFile(filePath)
+			if recorded := len(data) > 0; recorded != tt.wantRecorded {
+				t.Fatalf("recorded = %v, want %v: %s", recorded, tt.wantRecorded, data)
+			}
+			if !tt.wantRecorded {
+				return
+			}
+
+			var entry recordEntry
+			if err := json.Unmarshal(bytes.TrimSpace(data), &entry); err != nil {
+				t.Fatalf("record is not a JSON line: %s", data)
+			}
+			if entry.Status != http.StatusOK || entry.Method != http.MethodPost {
+				t.Fatalf("entry = %+v", entry)
+			}
+			plain := string(data)
+			for _, s := range tt.wantContains {
+				if !strings.Contains(plain, s) {
+					t.Errorf("record missing %q: %s", s, data)
+				}
+			}
+			for _, s := range tt.wantHidden {
+				if strings.Contains(plain, s) {
+					t.Errorf("record leaks %q: %s", s, data)
+				}
+			}
+		})
+	}
+}
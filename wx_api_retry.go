@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// retryBackoff 第attempt次失败后的等待时间：按初始间隔指数增长，不超过最大间隔，并在后一半区间内随机抖动，避免多个请求同时重试
+func retryBackoff(initial, max time.Duration, attempt int) time.Duration {
+	backoff := initial
+	if backoff <= 0 {
+		return 0
+	}
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if max > 0 && backoff >= max {
+			break
+		}
+	}
+	if max > 0 && backoff > max {
+		backoff = max
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// shouldRetryRequest 判断请求结果是否值得重试：只重试连接错误和5xx状态码，
+// 业务错误码（如token过期300）在响应体中解析，不在此重试；
+// 地址防护拦截、并发已达上限和调用方取消属于确定性失败，重试无意义
+func shouldRetryRequest(statusCode int, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrAddressBlocked) &&
+			!errors.Is(err, ErrWxAPIBusy) &&
+			!errors.Is(err, context.Canceled)
+	}
+	return statusCode >= http.StatusInternalServerError
+}
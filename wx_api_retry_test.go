@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestShouldRetryRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		want       bool
+	}{
+		{name: "ok or business error in body", statusCode: http.StatusOK, want: false},
+		{name: "bad request", statusCode: http.StatusBadRequest, want: false},
+		{name: "server error", statusCode: http.StatusInternalServerError, want: true},
+		{name: "bad gateway", statusCode: http.StatusBadGateway, want: true},
+		{name: "connection error", err: errors.New("connection refused"), want: true},
+		{name: "address blocked", err: fmt.Errorf("dial: %w", ErrAddressBlocked), want: false},
+		{name: "client busy", err: fmt.Errorf("do request: %w", ErrWxAPIBusy), want: false},
+		{name: "caller canceled", err: fmt.Errorf("do request: %w", context.Canceled), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRetryRequest(tt.statusCode, tt.err); got != tt.want {
+				t.Fatalf("shouldRetryRequest(%d, %v) = %v, want %v", tt.statusCode, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		initial  time.Duration
+		max      time.Duration
+		attempt  int
+		min, top time.Duration
+	}{
+		{name: "first retry", initial: 100 * time.Millisecond, max: time.Second, attempt: 1, min: 50 * time.Millisecond, top: 100 * time.Millisecond},
+		{name: "doubles", initial: 100 * time.Millisecond, max: time.Second, attempt: 3, min: 200 * time.Millisecond, top: 400 * time.Millisecond},
+		{name: "capped", initial: 100 * time.Millisecond, max: time.Second, attempt: 10, min: 500 * time.Millisecond, top: time.Second},
+		{name: "disabled", initial: 0, max: time.Second, attempt: 2, min: 0, top: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got := retryBackoff(tt.initial, tt.max, tt.attempt)
+				if got < tt.min || got > tt.top {
+					t.Fatalf("retryBackoff = %v, want in [%v, %v]", got, tt.min, tt.top)
+				}
+			}
+		})
+	}
+}
+
+func TestWxAPIClientRetriesOnlyIdempotentRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		call      func(ctx context.Context, client *WxAPIClient, address string) error
+		wantCalls int32
+	}{
+		{
+			name: "get request retried",
+			path: defaultWxAPIEndpoints.GetLoginStatus,
+			call: func(ctx context.Context, client *WxAPIClient, address string) error {
+				_, err := client.GetLoginStatus(ctx, address, "token")
+				return err
+			},
+			wantCalls: 3,
+		},
+		{
+			name: "read-only post retried",
+			path: defaultWxAPIEndpoints.GetChatRoomInfo,
+			call: func(ctx context.Context, client *WxAPIClient, address string) error {
+				_, err := client.GetChatRoomInfo(ctx, address, "token", []string{"123@chatroom"})
+				return err
+			},
+			wantCalls: 3,
+		},
+		{
+			name: "login qrcode not retried",
+			path: defaultWxAPIEndpoints.GetLoginQrCode,
+			call: func(ctx context.Context, client *WxAPIClient, address string) error {
+				_, err := client.GetLoginQrCode(ctx, address, "token", false, "")
+				return err
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "send text not retried",
+			path: defaultWxAPIEndpoints.SendTextMessage,
+			call: func(ctx context.Context, client *WxAPIClient, address string) error {
+				_, err := client.SendText(ctx, address, "token", &SendTextRequest{TextContent: "hi", ToUserName: "123@chatroom"})
+				return err
+			},
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				tt.path: func(w http.ResponseWriter, r *http.Request) {
+					atomic.AddInt32(&calls, 1)
+					w.WriteHeader(http.StatusBadGateway)
+				},
+			})
+
+			client := NewWxAPIClient(WxAPIConfig{
+				Timeout: time.Second,
+				Retry:   WxAPIRetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+			}, zap.NewNop(), newTestAddressGuard(t, false))
+
+			if err := tt.call(context.Background(), client, server.URL); err == nil {
+				t.Fatal("call succeeded, want error from 502 response")
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
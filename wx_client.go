@@ -2,12 +2,16 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 )
@@ -77,6 +81,14 @@ type CheckCanSetAliasResponse struct {
 	Text string `json:"Text"`
 }
 
+// 外部接口CheckLoginStatus返回的扫码状态（Data.state）
+const (
+	QrCodeStateWaitScan  = 0 // 等待扫码
+	QrCodeStateScanned   = 1 // 已扫码，等待手机确认
+	QrCodeStateConfirmed = 2 // 已确认，登录成功
+	QrCodeStateCancelled = 4 // 手机端取消登录
+)
+
 type CheckLoginStatusResponse struct {
 	Code int `json:"Code"`
 	Data struct {
@@ -200,57 +212,238 @@ type GroupListResponse struct {
 	Text string `json:"Text"`
 }
 
+// DedupeGroups 按群ID合并重复的群，返回被合并的条数
+// 底层接口偶尔对同一个群返回多条，保留首次出现的位置，内容取后出现（较新）的一条；较新一条昵称为空时沿用已有昵称
+func (r *GroupListResponse) DedupeGroups() int {
+	groups := r.Data.GroupList
+	index := make(map[string]int, len(groups))
+	deduped := groups[:0]
+	for _, group := range groups {
+		groupID := group.UserName.Str
+		pos, exists := index[groupID]
+		if !exists {
+			index[groupID] = len(deduped)
+			deduped = append(deduped, group)
+			continue
+		}
+
+		if group.NickName.Str == "" {
+			group.NickName = deduped[pos].NickName
+		}
+		deduped[pos] = group
+	}
+
+	removed := len(groups) - len(deduped)
+	r.Data.GroupList = deduped
+	return removed
+}
+
+// defaultWxAPIEndpoints 外部微信机器人API默认路径
+var defaultWxAPIEndpoints = WxAPIEndpoints{
+	GenAuthKey:          "/admin/GenAuthKey1",
+	DelayAuthKey:        "/admin/DelayAuthKey",
+	GetLoginQrCode:      "/login/GetLoginQrCodeNewX",
+	CheckCanSetAlias:    "/login/CheckCanSetAlias",
+	CheckLoginStatus:    "/login/CheckLoginStatus",
+	GetLoginStatus:      "/login/GetLoginStatus",
+	GetInitStatus:       "/login/GetInItStatus",
+	GetChatRoomInfo:     "/group/GetChatRoomInfo",
+	GroupList:           "/group/GroupList",
+	SendTextMessage:     "/message/SendTextMessage",
+	SendImageNewMessage: "/message/SendImageNewMessage",
+	SendVoiceMessage:    "/message/SendVoiceMessage",
+	SendVideoMessage:    "/message/SendVideoMessage",
+	SendAppMessage:      "/message/SendAppMessage",
+}
+
+// withDefaults 未配置的路径使用默认值
+func (e WxAPIEndpoints) withDefaults() WxAPIEndpoints {
+	fill := func(path *string, def string) {
+		if strings.TrimSpace(*path) == "" {
+			*path = def
+		}
+	}
+
+	fill(&e.GenAuthKey, defaultWxAPIEndpoints.GenAuthKey)
+	fill(&e.DelayAuthKey, defaultWxAPIEndpoints.DelayAuthKey)
+	fill(&e.GetLoginQrCode, defaultWxAPIEndpoints.GetLoginQrCode)
+	fill(&e.CheckCanSetAlias, defaultWxAPIEndpoints.CheckCanSetAlias)
+	fill(&e.CheckLoginStatus, defaultWxAPIEndpoints.CheckLoginStatus)
+	fill(&e.GetLoginStatus, defaultWxAPIEndpoints.GetLoginStatus)
+	fill(&e.GetInitStatus, defaultWxAPIEndpoints.GetInitStatus)
+	fill(&e.GetChatRoomInfo, defaultWxAPIEndpoints.GetChatRoomInfo)
+	fill(&e.GroupList, defaultWxAPIEndpoints.GroupList)
+	fill(&e.SendTextMessage, defaultWxAPIEndpoints.SendTextMessage)
+	fill(&e.SendImageNewMessage, defaultWxAPIEndpoints.SendImageNewMessage)
+	fill(&e.SendVoiceMessage, defaultWxAPIEndpoints.SendVoiceMessage)
+	fill(&e.SendVideoMessage, defaultWxAPIEndpoints.SendVideoMessage)
+	fill(&e.SendAppMessage, defaultWxAPIEndpoints.SendAppMessage)
+	return e
+}
+
 // 微信API客户端
 type WxAPIClient struct {
-	httpClient *http.Client
-	logger     *zap.Logger
+	httpClient     *http.Client
+	logger         *zap.Logger
+	endpoints      WxAPIEndpoints
+	defaultTimeout time.Duration
+	retry          WxAPIRetryConfig
 }
 
-// NewWxAPIClient 创建新的微信API客户端
-func NewWxAPIClient(logger *zap.Logger) *WxAPIClient {
+// NewWxAPIClient 创建新的微信API客户端，所有请求在拨号阶段经过地址防护校验
+// 超时由每个请求的context控制，未配置机器人独立超时时使用全局默认超时
+func NewWxAPIClient(cfg WxAPIConfig, logger *zap.Logger, guard *AddressGuard) *WxAPIClient {
+	defaultTimeout := cfg.Timeout
+	if defaultTimeout <= 0 {
+		defaultTimeout = 30 * time.Second
+	}
+
+	endpoints := cfg.Endpoints.withDefaults()
+
+	var transport http.RoundTripper = guard.Transport()
+	if cfg.Record.Enable {
+		transport = newRecordingTransport(cfg.Record, endpoints.GenAuthKey, transport, logger)
+	}
+	// 并发限制放在最外层，被拒绝的请求不会被录制
+	transport = newConcurrencyLimitTransport(cfg.Concurrency, transport, logger)
+
 	return &WxAPIClient{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: transport,
 		},
-		logger: logger,
+		logger:         logger,
+		endpoints:      endpoints,
+		defaultTimeout: defaultTimeout,
+		retry:          cfg.Retry,
 	}
 }
 
-// HTTP请求通用方法
-func (c *WxAPIClient) makeRequest(method, url string, body interface{}) ([]byte, error) {
-	var reqBody io.Reader
+// robotTimeoutKey context中机器人独立超时的键
+type robotTimeoutKey struct{}
+
+// withRobotTimeout 在context中携带机器人配置的独立超时，调用该机器人的接口前设置，
+// 未配置独立超时时请求使用全局默认超时
+func withRobotTimeout(ctx context.Context, robot *WxRobotConfig) context.Context {
+	if robot == nil || robot.TimeoutSeconds <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, robotTimeoutKey{}, time.Duration(robot.TimeoutSeconds)*time.Second)
+}
+
+// timeoutFor 获取单次请求的超时，context携带机器人独立超时时优先使用
+func (c *WxAPIClient) timeoutFor(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(robotTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return c.defaultTimeout
+}
+
+// marshalJSON 序列化请求数据，不对 <、>、& 做HTML转义，emoji等Unicode字符按UTF-8原样输出
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encode 会追加换行符，去掉以保持与 json.Marshal 一致
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// buildURL 拼接机器人地址、接口路径和key参数
+func (c *WxAPIClient) buildURL(robotAddress, path, key string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("%s%s?key=%s", strings.TrimRight(robotAddress, "/"), path, key)
+}
+
+// HTTP请求通用方法，GET请求在连接错误或5xx时按重试配置重试，POST请求不重试
+// 每次请求的超时取ctx剩余时间与机器人超时中较短的一个，ctx取消时立即返回
+func (c *WxAPIClient) makeRequest(ctx context.Context, method, robotAddress, url string, body interface{}) ([]byte, error) {
+	return c.makeRequestWithRetry(ctx, method, robotAddress, url, body, method == http.MethodGet)
+}
+
+// makeSafeRequest 用于只读查询、可安全重复调用的POST接口（如获取群详情），与GET一样失败时重试；
+// 会产生副作用的接口（生成二维码、发送消息、延期等）不能使用
+func (c *WxAPIClient) makeSafeRequest(ctx context.Context, method, robotAddress, url string, body interface{}) ([]byte, error) {
+	return c.makeRequestWithRetry(ctx, method, robotAddress, url, body, true)
+}
+
+// makeRequestWithRetry 发送请求，retry为true时连接错误或5xx按指数退避重试，
+// 重试用尽后5xx响应体照常返回，由调用方按业务错误处理
+func (c *WxAPIClient) makeRequestWithRetry(ctx context.Context, method, robotAddress, url string, body interface{}, retry bool) ([]byte, error) {
+	var jsonData []byte
 	if body != nil {
-		jsonData, err := json.Marshal(body)
+		var err error
+		jsonData, err = marshalJSON(body)
 		if err != nil {
 			return nil, fmt.Errorf("marshal request body: %w", err)
 		}
-		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
+	maxAttempts := c.retry.MaxAttempts
+	if !retry || maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		respBody, statusCode, err := c.doRequest(ctx, method, robotAddress, url, jsonData)
+		if attempt >= maxAttempts || !shouldRetryRequest(statusCode, err) {
+			return respBody, err
+		}
+
+		backoff := retryBackoff(c.retry.InitialBackoff, c.retry.MaxBackoff, attempt)
+		c.logger.Warn("外部接口请求失败，准备重试",
+			zap.String("method", method),
+			zap.String("url", url),
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", maxAttempts),
+			zap.Int("status", statusCode),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("do request: %w", ctx.Err())
+		}
+	}
+}
+
+// doRequest 发送一次HTTP请求，每次请求单独计算超时，返回响应体和状态码
+func (c *WxAPIClient) doRequest(ctx context.Context, method, robotAddress, url string, jsonData []byte) ([]byte, int, error) {
+	var reqBody io.Reader
+	if jsonData != nil {
+		reqBody = bytes.NewReader(jsonData)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeoutFor(ctx))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, 0, fmt.Errorf("create request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	req.Header.Set("Accept", "application/json")
 
 	c.logger.Debug("发送HTTP请求", zap.String("method", method), zap.String("url", url))
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, 0, fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
+		return nil, resp.StatusCode, fmt.Errorf("read response: %w", err)
 	}
 
 	c.logger.Debug("收到HTTP响应", zap.Int("status", resp.StatusCode), zap.Int("body_length", len(respBody)))
 
-	return respBody, nil
+	return respBody, resp.StatusCode, nil
 }
 
 // 判断响应是否成功
@@ -258,15 +451,30 @@ func (c *WxAPIClient) isSuccess(code int) bool {
 	return code == 200
 }
 
+// wxCodeTokenExpired 外部接口返回该Code表示token已过期，需要重新登录
+const wxCodeTokenExpired = 300
+
+// ErrTokenExpired token已过期，调用方可通过errors.Is识别
+var ErrTokenExpired = errors.New("token已过期，需要重新登录")
+
+// checkTokenExpired 统一拦截token过期响应
+func (c *WxAPIClient) checkTokenExpired(api string, code int, text string) error {
+	if code != wxCodeTokenExpired {
+		return nil
+	}
+	c.logger.Warn("外部接口返回token过期", zap.String("api", api), zap.String("text", text))
+	return fmt.Errorf("%s: %w", api, ErrTokenExpired)
+}
+
 // 生成授权码
-func (c *WxAPIClient) GenAuthKey(robotAddress, adminKey string, count, days int) (*GenAuthKeyResponse, error) {
-	url := fmt.Sprintf("%s/admin/GenAuthKey1?key=%s", robotAddress, adminKey)
+func (c *WxAPIClient) GenAuthKey(ctx context.Context, robotAddress, adminKey string, count, days int) (*GenAuthKeyResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.GenAuthKey, adminKey)
 	reqBody := GenAuthKeyRequest{
 		Count: count,
 		Days:  days,
 	}
 
-	respBody, err := c.makeRequest("POST", url, reqBody)
+	respBody, err := c.makeRequest(ctx, "POST", robotAddress, url, reqBody)
 	if err != nil {
 		c.logger.Error("调用GenAuthKey失败", zap.Error(err))
 		return nil, err
@@ -288,14 +496,15 @@ func (c *WxAPIClient) GenAuthKey(robotAddress, adminKey string, count, days int)
 }
 
 // 获取登录二维码
-func (c *WxAPIClient) GetLoginQrCode(robotAddress, authKey string, check bool, proxy string) (*GetLoginQrCodeResponse, error) {
-	url := fmt.Sprintf("%s/login/GetLoginQrCodeNewX?key=%s", robotAddress, authKey)
+func (c *WxAPIClient) GetLoginQrCode(ctx context.Context, robotAddress, authKey string, check bool, proxy string) (*GetLoginQrCodeResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.GetLoginQrCode, authKey)
 	reqBody := GetLoginQrCodeRequest{
 		Check: check,
 		Proxy: proxy,
 	}
 
-	respBody, err := c.makeRequest("POST", url, reqBody)
+	// 每次调用都会生成新的登录二维码，重复调用会使前一个二维码失效，不能重试
+	respBody, err := c.makeRequest(ctx, "POST", robotAddress, url, reqBody)
 	if err != nil {
 		c.logger.Error("调用GetLoginQrCode失败", zap.Error(err))
 		return nil, err
@@ -317,10 +526,10 @@ func (c *WxAPIClient) GetLoginQrCode(robotAddress, authKey string, check bool, p
 }
 
 // 检查是否有安全风险
-func (c *WxAPIClient) CheckCanSetAlias(robotAddress, authKey string) (*CheckCanSetAliasResponse, error) {
-	url := fmt.Sprintf("%s/login/CheckCanSetAlias?key=%s", robotAddress, authKey)
+func (c *WxAPIClient) CheckCanSetAlias(ctx context.Context, robotAddress, authKey string) (*CheckCanSetAliasResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.CheckCanSetAlias, authKey)
 
-	respBody, err := c.makeRequest("GET", url, nil)
+	respBody, err := c.makeRequest(ctx, "GET", robotAddress, url, nil)
 	if err != nil {
 		c.logger.Error("调用CheckCanSetAlias失败", zap.Error(err))
 		return nil, err
@@ -332,8 +541,12 @@ func (c *WxAPIClient) CheckCanSetAlias(robotAddress, authKey string) (*CheckCanS
 		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
 
-	// 对于CheckCanSetAlias，Code 200表示正常，Code 300表示需要重新登录，都是正常响应
-	if resp.Code != 200 && resp.Code != 300 {
+	// 对于CheckCanSetAlias，Code 300表示需要重新登录
+	if err := c.checkTokenExpired("CheckCanSetAlias", resp.Code, resp.Text); err != nil {
+		return &resp, err
+	}
+
+	if !c.isSuccess(resp.Code) {
 		c.logger.Warn("CheckCanSetAlias调用失败", zap.Int("code", resp.Code), zap.String("text", resp.Text))
 		return &resp, fmt.Errorf("API调用失败: %s", resp.Text)
 	}
@@ -343,10 +556,10 @@ func (c *WxAPIClient) CheckCanSetAlias(robotAddress, authKey string) (*CheckCanS
 }
 
 // 检查登录状态
-func (c *WxAPIClient) CheckLoginStatus(robotAddress, authKey string) (*CheckLoginStatusResponse, error) {
-	url := fmt.Sprintf("%s/login/CheckLoginStatus?key=%s", robotAddress, authKey)
+func (c *WxAPIClient) CheckLoginStatus(ctx context.Context, robotAddress, authKey string) (*CheckLoginStatusResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.CheckLoginStatus, authKey)
 
-	respBody, err := c.makeRequest("GET", url, nil)
+	respBody, err := c.makeRequest(ctx, "GET", robotAddress, url, nil)
 	if err != nil {
 		c.logger.Error("调用CheckLoginStatus失败", zap.Error(err))
 		return nil, err
@@ -370,10 +583,10 @@ func (c *WxAPIClient) CheckLoginStatus(robotAddress, authKey string) (*CheckLogi
 }
 
 // 获取登录状态
-func (c *WxAPIClient) GetLoginStatus(robotAddress, authKey string) (*GetLoginStatusResponse, error) {
-	url := fmt.Sprintf("%s/login/GetLoginStatus?key=%s", robotAddress, authKey)
+func (c *WxAPIClient) GetLoginStatus(ctx context.Context, robotAddress, authKey string) (*GetLoginStatusResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.GetLoginStatus, authKey)
 
-	respBody, err := c.makeRequest("GET", url, nil)
+	respBody, err := c.makeRequest(ctx, "GET", robotAddress, url, nil)
 	if err != nil {
 		c.logger.Error("调用GetLoginStatus失败", zap.Error(err))
 		return nil, err
@@ -385,6 +598,10 @@ func (c *WxAPIClient) GetLoginStatus(robotAddress, authKey string) (*GetLoginSta
 		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
 
+	if err := c.checkTokenExpired("GetLoginStatus", resp.Code, resp.Text); err != nil {
+		return &resp, err
+	}
+
 	if !c.isSuccess(resp.Code) {
 		c.logger.Warn("GetLoginStatus调用失败", zap.Int("code", resp.Code), zap.String("text", resp.Text))
 		return &resp, fmt.Errorf("API调用失败: %s", resp.Text)
@@ -395,10 +612,10 @@ func (c *WxAPIClient) GetLoginStatus(robotAddress, authKey string) (*GetLoginSta
 }
 
 // 检查初始化状态
-func (c *WxAPIClient) GetInitStatus(robotAddress, authKey string) (*GetInitStatusResponse, error) {
-	url := fmt.Sprintf("%s/login/GetInItStatus?key=%s", robotAddress, authKey)
+func (c *WxAPIClient) GetInitStatus(ctx context.Context, robotAddress, authKey string) (*GetInitStatusResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.GetInitStatus, authKey)
 
-	respBody, err := c.makeRequest("GET", url, nil)
+	respBody, err := c.makeRequest(ctx, "GET", robotAddress, url, nil)
 	if err != nil {
 		c.logger.Error("调用GetInitStatus失败", zap.Error(err))
 		return nil, err
@@ -410,6 +627,10 @@ func (c *WxAPIClient) GetInitStatus(robotAddress, authKey string) (*GetInitStatu
 		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
 
+	if err := c.checkTokenExpired("GetInitStatus", resp.Code, resp.Text); err != nil {
+		return &resp, err
+	}
+
 	if !c.isSuccess(resp.Code) {
 		c.logger.Warn("GetInitStatus调用失败", zap.Int("code", resp.Code), zap.String("text", resp.Text))
 		return &resp, fmt.Errorf("API调用失败: %s", resp.Text)
@@ -420,15 +641,15 @@ func (c *WxAPIClient) GetInitStatus(robotAddress, authKey string) (*GetInitStatu
 }
 
 // 授权码延期
-func (c *WxAPIClient) DelayAuthKey(robotAddress, adminKey, authKey string, days int) (*DelayAuthKeyResponse, error) {
-	url := fmt.Sprintf("%s/admin/DelayAuthKey?key=%s", robotAddress, adminKey)
+func (c *WxAPIClient) DelayAuthKey(ctx context.Context, robotAddress, adminKey, authKey string, days int) (*DelayAuthKeyResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.DelayAuthKey, adminKey)
 	reqBody := DelayAuthKeyRequest{
 		Days:       days,
 		ExpiryDate: "",
 		Key:        authKey,
 	}
 
-	respBody, err := c.makeRequest("POST", url, reqBody)
+	respBody, err := c.makeRequest(ctx, "POST", robotAddress, url, reqBody)
 	if err != nil {
 		c.logger.Error("调用DelayAuthKey失败", zap.Error(err))
 		return nil, err
@@ -450,13 +671,13 @@ func (c *WxAPIClient) DelayAuthKey(robotAddress, adminKey, authKey string, days
 }
 
 // 获取群详情
-func (c *WxAPIClient) GetChatRoomInfo(robotAddress, authKey string, chatRoomIds []string) (*GetChatRoomInfoResponse, error) {
-	url := fmt.Sprintf("%s/group/GetChatRoomInfo?key=%s", robotAddress, authKey)
+func (c *WxAPIClient) GetChatRoomInfo(ctx context.Context, robotAddress, authKey string, chatRoomIds []string) (*GetChatRoomInfoResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.GetChatRoomInfo, authKey)
 	reqBody := GetChatRoomInfoRequest{
 		ChatRoomWxIdList: chatRoomIds,
 	}
 
-	respBody, err := c.makeRequest("POST", url, reqBody)
+	respBody, err := c.makeSafeRequest(ctx, "POST", robotAddress, url, reqBody)
 	if err != nil {
 		c.logger.Error("调用GetChatRoomInfo失败", zap.Error(err))
 		return nil, err
@@ -468,6 +689,10 @@ func (c *WxAPIClient) GetChatRoomInfo(robotAddress, authKey string, chatRoomIds
 		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
 
+	if err := c.checkTokenExpired("GetChatRoomInfo", resp.Code, resp.Text); err != nil {
+		return &resp, err
+	}
+
 	if !c.isSuccess(resp.Code) {
 		c.logger.Warn("GetChatRoomInfo调用失败", zap.Int("code", resp.Code), zap.String("text", resp.Text))
 		return &resp, fmt.Errorf("API调用失败: %s", resp.Text)
@@ -478,10 +703,10 @@ func (c *WxAPIClient) GetChatRoomInfo(robotAddress, authKey string, chatRoomIds
 }
 
 // 获取群列表
-func (c *WxAPIClient) GetGroupList(robotAddress, authKey string) (*GroupListResponse, error) {
-	url := fmt.Sprintf("%s/group/GroupList?key=%s", robotAddress, authKey)
+func (c *WxAPIClient) GetGroupList(ctx context.Context, robotAddress, authKey string) (*GroupListResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.GroupList, authKey)
 
-	respBody, err := c.makeRequest("GET", url, nil)
+	respBody, err := c.makeRequest(ctx, "GET", robotAddress, url, nil)
 	if err != nil {
 		c.logger.Error("调用GetGroupList失败", zap.Error(err))
 		return nil, err
@@ -493,11 +718,19 @@ func (c *WxAPIClient) GetGroupList(robotAddress, authKey string) (*GroupListResp
 		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
 
+	if err := c.checkTokenExpired("GetGroupList", resp.Code, resp.Text); err != nil {
+		return &resp, err
+	}
+
 	if !c.isSuccess(resp.Code) {
 		c.logger.Warn("GetGroupList调用失败", zap.Int("code", resp.Code), zap.String("text", resp.Text))
 		return &resp, fmt.Errorf("API调用失败: %s", resp.Text)
 	}
 
+	if removed := resp.DedupeGroups(); removed > 0 {
+		c.logger.Warn("GetGroupList返回重复群ID，已合并", zap.Int("removed", removed))
+	}
+
 	c.logger.Info("GetGroupList调用成功", zap.Int("groupCount", len(resp.Data.GroupList)), zap.Bool("isInitFinished", resp.Data.IsInitFinished))
 	return &resp, nil
 }
@@ -506,8 +739,10 @@ func (c *WxAPIClient) GetGroupList(robotAddress, authKey string) (*GroupListResp
 
 // SendTextRequest 发送文本消息请求（简化版）
 type SendTextRequest struct {
-	TextContent string `json:"TextContent"` // 文本内容
-	ToUserName  string `json:"ToUserName"`  // 接收者用户名
+	TextContent string   `json:"TextContent"`          // 文本内容
+	ToUserName  string   `json:"ToUserName"`           // 接收者用户名
+	AtWxIDList  []string `json:"AtWxIDList,omitempty"` // 需要@的成员wxid，notify@all表示@所有人；由平台触发@提醒，不改写文本，为空时按文本中的@昵称解析
+	Priority    string   `json:"-"`                    // 发送优先级，仅用于本地发送队列
 }
 
 // SendTextResponse 发送文本消息响应（简化版）
@@ -516,12 +751,18 @@ type SendTextResponse struct {
 	ClientMsgId int64  `json:"ClientMsgId"`
 	CreateTime  int64  `json:"CreateTime"`
 	NewMsgId    int64  `json:"NewMsgId"`
+
+	// 长文本分段发送时每一段的结果，未分段时为空
+	Segments []SendTextResponse `json:"Segments,omitempty"`
 }
 
 // SendImageRequest 发送图片消息请求（简化版）
 type SendImageRequest struct {
-	ImageContent string `json:"ImageContent"` // 图片内容(base64)
-	ToUserName   string `json:"ToUserName"`   // 接收者用户名
+	ImageContent string `json:"ImageContent"`           // 图片内容(base64)
+	ThumbContent string `json:"ThumbContent,omitempty"` // 缩略图内容(base64)，可选
+	AutoThumb    bool   `json:"-"`                      // 未传缩略图时是否从原图自动生成
+	ToUserName   string `json:"ToUserName"`             // 接收者用户名
+	Priority     string `json:"-"`                      // 发送优先级，仅用于本地发送队列
 }
 
 // SendImageResponse 发送图片消息响应（简化版）
@@ -533,11 +774,74 @@ type SendImageResponse struct {
 	NewMsgId     int64  `json:"NewMsgId"`
 }
 
+// 语音格式（SendVoiceRequest.VoiceFormat），取值与外部接口一致
+const (
+	VoiceFormatAMR  = 0
+	VoiceFormatSilk = 4
+)
+
+// SendVoiceRequest 发送语音消息请求（简化版）
+type SendVoiceRequest struct {
+	VoiceContent  string `json:"VoiceContent"`  // 语音内容(base64)，silk或amr
+	VoiceFormat   int    `json:"VoiceFormat"`   // 语音格式：0 amr、4 silk
+	VoiceDuration int    `json:"VoiceDuration"` // 语音时长(秒)
+	ToUserName    string `json:"ToUserName"`    // 接收者用户名
+	Priority      string `json:"-"`             // 发送优先级，仅用于本地发送队列
+}
+
+// SendVoiceResponse 发送语音消息响应（简化版）
+type SendVoiceResponse struct {
+	MsgId       int64  `json:"MsgId"`
+	ToUserName  string `json:"ToUserName"`
+	ClientMsgId string `json:"ClientMsgId"`
+	CreateTime  int64  `json:"CreateTime"`
+	NewMsgId    int64  `json:"NewMsgId"`
+}
+
+// SendVideoRequest 发送视频消息请求（简化版）
+type SendVideoRequest struct {
+	VideoContent string `json:"VideoContent"`           // 视频内容(base64)
+	ThumbContent string `json:"ThumbContent,omitempty"` // 视频封面缩略图(base64)，可选
+	PlayLength   int    `json:"PlayLength"`             // 视频时长(秒)
+	ToUserName   string `json:"ToUserName"`             // 接收者用户名
+	Priority     string `json:"-"`                      // 发送优先级，仅用于本地发送队列
+}
+
+// SendVideoResponse 发送视频消息响应（简化版）
+type SendVideoResponse struct {
+	MsgId       int64  `json:"MsgId"`
+	ToUserName  string `json:"ToUserName"`
+	ClientMsgId string `json:"ClientMsgId"`
+	CreateTime  int64  `json:"CreateTime"`
+	NewMsgId    int64  `json:"NewMsgId"`
+}
+
+// SendAppMsgRequest 发送链接卡片消息请求（简化版）
+type SendAppMsgRequest struct {
+	Title       string `json:"Title"`       // 卡片标题
+	Description string `json:"Description"` // 卡片描述
+	Url         string `json:"Url"`         // 点击卡片打开的链接
+	ThumbUrl    string `json:"ThumbUrl"`    // 卡片缩略图地址
+	ToUserName  string `json:"ToUserName"`  // 接收者用户名
+	Priority    string `json:"-"`           // 发送优先级，仅用于本地发送队列
+}
+
+// SendAppMsgResponse 发送链接卡片消息响应（简化版）
+type SendAppMsgResponse struct {
+	MsgId       int64  `json:"MsgId"`
+	ToUserName  string `json:"ToUserName"`
+	ClientMsgId string `json:"ClientMsgId"`
+	CreateTime  int64  `json:"CreateTime"`
+	NewMsgId    int64  `json:"NewMsgId"`
+}
+
 // SendTextAndImageRequest 同时发送文字和图片请求
 type SendTextAndImageRequest struct {
-	TextContent  string `json:"TextContent"`  // 文本内容
-	ImageContent string `json:"ImageContent"` // 图片内容(base64)
-	ToUserName   string `json:"ToUserName"`   // 接收者用户名
+	TextContent  string   `json:"TextContent"`          // 文本内容
+	ImageContent string   `json:"ImageContent"`         // 图片内容(base64)
+	ToUserName   string   `json:"ToUserName"`           // 接收者用户名
+	AtWxIDList   []string `json:"AtWxIDList,omitempty"` // 需要@的成员wxid
+	Priority     string   `json:"-"`                    // 发送优先级，仅用于本地发送队列
 }
 
 // SendTextAndImageResponse 同时发送文字和图片响应
@@ -564,40 +868,55 @@ type SendTextMessageRequest struct {
 
 // SendTextMessageRawResponse 原始发送文本消息响应
 type SendTextMessageRawResponse struct {
-	Code int    `json:"Code"`
-	Text string `json:"Text"`
-	Data []struct {
-		IsSendSuccess bool   `json:"isSendSuccess"`
-		TextContent   string `json:"textContent"`
-		ToUserName    string `json:"toUSerName"`
-		Resp          *struct {
-			BaseResponse struct {
-				Ret    int `json:"ret"`
-				ErrMsg struct {
-					Str string `json:"str,omitempty"`
-				} `json:"errMsg"`
-			} `json:"base_response"`
-			Count           int `json:"count"`
-			ChatSendRetList []struct {
-				Ret        int `json:"ret"`
-				ToUserName struct {
-					Str string `json:"str"`
-				} `json:"toUserName"`
-				MsgId       int64 `json:"msgId"`
-				ClientMsgId int64 `json:"clientMsgId"`
-				CreateTime  int64 `json:"createTime"`
-				ServerTime  int64 `json:"serverTime"`
-				Type        int   `json:"type"`
-				NewMsgId    int64 `json:"newMsgId"`
-			} `json:"chat_send_ret_list"`
-		} `json:"resp,omitempty"`
-	} `json:"Data"`
+	Code int                      `json:"Code"`
+	Text string                   `json:"Text"`
+	Data []SendTextMessageRawItem `json:"Data"`
+}
+
+// SendTextMessageRawItem 单个消息项的原始发送结果
+type SendTextMessageRawItem struct {
+	IsSendSuccess bool   `json:"isSendSuccess"`
+	TextContent   string `json:"textContent"`
+	ToUserName    string `json:"toUSerName"`
+	Resp          *struct {
+		BaseResponse struct {
+			Ret    int `json:"ret"`
+			ErrMsg struct {
+				Str string `json:"str,omitempty"`
+			} `json:"errMsg"`
+		} `json:"base_response"`
+		Count           int `json:"count"`
+		ChatSendRetList []struct {
+			Ret        int `json:"ret"`
+			ToUserName struct {
+				Str string `json:"str"`
+			} `json:"toUserName"`
+			MsgId       int64 `json:"msgId"`
+			ClientMsgId int64 `json:"clientMsgId"`
+			CreateTime  int64 `json:"createTime"`
+			ServerTime  int64 `json:"serverTime"`
+			Type        int   `json:"type"`
+			NewMsgId    int64 `json:"newMsgId"`
+		} `json:"chat_send_ret_list"`
+	} `json:"resp,omitempty"`
+}
+
+// SendTextResult 单个群的文本发送结果
+type SendTextResult struct {
+	ToUserName  string        `json:"ToUserName"`
+	Success     bool          `json:"Success"`
+	Error       string        `json:"Error,omitempty"`
+	ErrorType   SendErrorType `json:"ErrorType,omitempty"` // 失败分类
+	ClientMsgId int64         `json:"ClientMsgId,omitempty"`
+	CreateTime  int64         `json:"CreateTime,omitempty"`
+	NewMsgId    int64         `json:"NewMsgId,omitempty"`
 }
 
 // SendImageMsgItem 图片消息项
 type SendImageMsgItem struct {
-	AtWxIDList   []string `json:"AtWxIDList"`   // @用户列表
-	ImageContent string   `json:"ImageContent"` // 图片内容(base64)
+	AtWxIDList   []string `json:"AtWxIDList"`             // @用户列表
+	ImageContent string   `json:"ImageContent"`           // 图片内容(base64)
+	ThumbContent string   `json:"ThumbContent,omitempty"` // 缩略图内容(base64)
 	MsgType      int      `json:"MsgType"`      // 消息类型
 	TextContent  string   `json:"TextContent"`  // 文本内容
 	ToUserName   string   `json:"ToUserName"`   // 接收者用户名
@@ -608,6 +927,94 @@ type SendImageNewMessageRequest struct {
 	MsgItem []SendImageMsgItem `json:"MsgItem"`
 }
 
+// SendVoiceMessageRequest 发送语音消息请求
+type SendVoiceMessageRequest struct {
+	ToUserName  string `json:"ToUserName"`
+	VoiceData   string `json:"VoiceData"`   // 语音内容(base64)
+	VoiceFormat int    `json:"VoiceFormat"` // 语音格式
+	VoiceSecond int    `json:"VoiceSecond"` // 语音时长(秒)
+}
+
+// SendMessageRawResponse 原始发送语音、视频、卡片消息响应
+type SendMessageRawResponse struct {
+	Code int    `json:"Code"`
+	Text string `json:"Text"`
+	Data []struct {
+		ErrMsg        string                `json:"errMsg,omitempty"`
+		IsSendSuccess bool                  `json:"isSendSuccess,omitempty"`
+		ToUserName    string                `json:"toUSerName"`
+		Resp          *SendMessageRawResult `json:"resp,omitempty"`
+	} `json:"Data"`
+}
+
+// SendMessageRawResult 单条消息的原始发送结果
+type SendMessageRawResult struct {
+	BaseResponse struct {
+		Ret    int `json:"ret"`
+		ErrMsg struct {
+			Str string `json:"str,omitempty"`
+		} `json:"errMsg"`
+	} `json:"baseResponse"`
+	MsgId       int64  `json:"msgId"`
+	ClientMsgId string `json:"clientMsgId"`
+	CreateTime  int64  `json:"createTime"`
+	NewMsgId    int64  `json:"newMsgId"`
+}
+
+// SendVideoMessageRequest 发送视频消息请求
+type SendVideoMessageRequest struct {
+	ToUserName string `json:"ToUserName"`
+	VideoData  string `json:"VideoData"`           // 视频内容(base64)
+	ThumbData  string `json:"ThumbData,omitempty"` // 封面缩略图(base64)
+	PlayLength int    `json:"PlayLength"`          // 视频时长(秒)
+}
+
+// 卡片消息类型：链接
+const appMsgTypeLink = 5
+
+// SendAppMessageRequest 发送卡片消息请求
+type SendAppMessageRequest struct {
+	AppList []AppMessageItem `json:"AppList"`
+}
+
+// AppMessageItem 卡片消息项
+type AppMessageItem struct {
+	ContentType int    `json:"ContentType"` // 卡片类型，5为链接
+	ContentXML  string `json:"ContentXML"`  // appmsg XML
+	ToUserName  string `json:"ToUserName"`
+}
+
+// linkAppMsg 链接卡片的appmsg XML结构
+type linkAppMsg struct {
+	XMLName  xml.Name `xml:"appmsg"`
+	AppID    string   `xml:"appid,attr"`
+	SDKVer   string   `xml:"sdkver,attr"`
+	Title    string   `xml:"title"`
+	Des      string   `xml:"des"`
+	Action   string   `xml:"action"`
+	Type     int      `xml:"type"`
+	ShowType int      `xml:"showtype"`
+	URL      string   `xml:"url"`
+	ThumbURL string   `xml:"thumburl"`
+}
+
+// buildLinkAppMsgXML 生成链接卡片的appmsg XML，标题、描述等内容会按XML转义
+func buildLinkAppMsgXML(req *SendAppMsgRequest) (string, error) {
+	data, err := xml.Marshal(linkAppMsg{
+		SDKVer:   "0",
+		Title:    req.Title,
+		Des:      req.Description,
+		Action:   "view",
+		Type:     appMsgTypeLink,
+		URL:      req.Url,
+		ThumbURL: req.ThumbUrl,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // SendImageNewMessageRawResponse 原始发送图片消息响应
 type SendImageNewMessageRawResponse struct {
 	Code int    `json:"Code"`
@@ -645,49 +1052,78 @@ type SendImageNewMessageRawResponse struct {
 }
 
 // SendText 发送文本消息（简化版）
-func (c *WxAPIClient) SendText(robotAddress, authKey string, req *SendTextRequest) (*SendTextResponse, error) {
-	url := fmt.Sprintf("%s/message/SendTextMessage?key=%s", robotAddress, authKey)
+func (c *WxAPIClient) SendText(ctx context.Context, robotAddress, authKey string, req *SendTextRequest) (*SendTextResponse, error) {
+	results, err := c.SendTextBatch(ctx, robotAddress, authKey, []*SendTextRequest{req})
+	if err != nil {
+		return nil, err
+	}
+
+	result := results[0]
+	if !result.Success {
+		return nil, newSendError(result.ErrorType, "SendText 发送文本消息失败: %s", result.Error)
+	}
+
+	return &SendTextResponse{
+		ToUserName:  result.ToUserName,
+		ClientMsgId: result.ClientMsgId,
+		CreateTime:  result.CreateTime,
+		NewMsgId:    result.NewMsgId,
+	}, nil
+}
+
+// SendTextBatch 一次请求向多个群发送文本消息，返回每个群的发送结果
+// 请求本身失败时返回错误；单个群发送失败记录在对应结果中
+func (c *WxAPIClient) SendTextBatch(ctx context.Context, robotAddress, authKey string, reqs []*SendTextRequest) ([]SendTextResult, error) {
+	url := c.buildURL(robotAddress, c.endpoints.SendTextMessage, authKey)
 
 	// 构建原始请求
 	originalReq := &SendTextMessageRequest{
-		MsgItem: []SendTextMsgItem{
-			{
-				AtWxIDList:   []string{},
-				ImageContent: "",
-				MsgType:      1, // 文本消息类型
-				TextContent:  req.TextContent,
-				ToUserName:   req.ToUserName,
-			},
-		},
+		MsgItem: make([]SendTextMsgItem, 0, len(reqs)),
+	}
+	for _, req := range reqs {
+		atWxIDList := req.AtWxIDList
+		if atWxIDList == nil {
+			atWxIDList = []string{}
+		}
+		originalReq.MsgItem = append(originalReq.MsgItem, SendTextMsgItem{
+			AtWxIDList:   atWxIDList,
+			ImageContent: "",
+			MsgType:      1, // 文本消息类型
+			TextContent:  req.TextContent,
+			ToUserName:   req.ToUserName,
+		})
+
+		c.logger.Info("发送文本消息请求",
+			zap.String("url", url),
+			zap.String("to_user", req.ToUserName),
+			zap.Int("text_length", utf8.RuneCountInString(req.TextContent)))
 	}
 
-	jsonData, err := json.Marshal(originalReq)
+	jsonData, err := marshalJSON(originalReq)
 	if err != nil {
 		return nil, fmt.Errorf("序列化请求数据失败: %w", err)
 	}
 
-	c.logger.Info("发送文本消息请求",
-		zap.String("url", url),
-		zap.String("to_user", req.ToUserName),
-		zap.Int("text_length", len(req.TextContent)))
+	ctx, cancel := context.WithTimeout(ctx, c.timeoutFor(ctx))
+	defer cancel()
 
-	reqBody, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	reqBody, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 
-	reqBody.Header.Set("Content-Type", "application/json")
+	reqBody.Header.Set("Content-Type", "application/json; charset=utf-8")
 	reqBody.Header.Set("Accept", "application/json")
 
 	resp, err := c.httpClient.Do(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("SendText 发送HTTP请求失败: %w", err)
+		return nil, newSendError(SendErrorNetwork, "SendText 发送HTTP请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("SendText 读取响应数据失败: %w", err)
+		return nil, newSendError(SendErrorNetwork, "SendText 读取响应数据失败: %w", err)
 	}
 
 	var rawResponse SendTextMessageRawResponse
@@ -695,6 +1131,10 @@ func (c *WxAPIClient) SendText(robotAddress, authKey string, req *SendTextReques
 		return nil, fmt.Errorf("SendText 解析响应数据失败: %w", err)
 	}
 
+	if err := c.checkTokenExpired("SendText", rawResponse.Code, rawResponse.Text); err != nil {
+		return nil, err
+	}
+
 	c.logger.Info("SendText 发送文本消息响应",
 		zap.Int("code", rawResponse.Code),
 		zap.Int("data_count", len(rawResponse.Data)))
@@ -704,68 +1144,109 @@ func (c *WxAPIClient) SendText(robotAddress, authKey string, req *SendTextReques
 		return nil, fmt.Errorf("SendText 发送文本消息失败: 无响应数据")
 	}
 
-	// 检查第一个结果的发送状态
-	firstResult := rawResponse.Data[0]
+	results := make([]SendTextResult, 0, len(rawResponse.Data))
+	for i, item := range rawResponse.Data {
+		result := parseSendTextItem(item)
+		if result.ToUserName == "" && i < len(reqs) {
+			result.ToUserName = reqs[i].ToUserName
+		}
+
+		if result.Success {
+			c.logger.Info("文本消息发送成功",
+				zap.String("to_user", result.ToUserName),
+				zap.Int64("client_msg_id", result.ClientMsgId),
+				zap.Int64("create_time", result.CreateTime),
+				zap.Int64("new_msg_id", result.NewMsgId))
+		} else {
+			result.ErrorType = classifySendFailure(result.Error)
+			c.logger.Warn("文本消息发送失败",
+				zap.String("to_user", result.ToUserName),
+				zap.String("error", result.Error),
+				zap.String("error_type", string(result.ErrorType)))
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// parseSendTextItem 解析单个消息项的发送结果
+func parseSendTextItem(item SendTextMessageRawItem) SendTextResult {
+	result := SendTextResult{ToUserName: item.ToUserName}
 
 	// 检查是否发送成功
-	if !firstResult.IsSendSuccess {
-		return nil, fmt.Errorf("SendText 发送文本消息失败: 发送状态为失败")
+	if !item.IsSendSuccess {
+		result.Error = "发送状态为失败"
+		return result
 	}
 
 	// 检查是否有Resp字段
-	if firstResult.Resp == nil {
-		return nil, fmt.Errorf("SendText 发送文本消息失败: 响应数据不完整")
+	if item.Resp == nil {
+		result.Error = "响应数据不完整"
+		return result
 	}
 
 	// 检查响应状态
-	if firstResult.Resp.BaseResponse.Ret != 0 {
-		errMsg := firstResult.Resp.BaseResponse.ErrMsg.Str
-		if errMsg == "" {
-			errMsg = "未知错误"
+	if item.Resp.BaseResponse.Ret != 0 {
+		result.Error = item.Resp.BaseResponse.ErrMsg.Str
+		if result.Error == "" {
+			result.Error = "未知错误"
 		}
-		return nil, fmt.Errorf("SendText 发送文本消息失败: %s", errMsg)
+		return result
 	}
 
 	// 检查chat_send_ret_list是否有数据
-	if len(firstResult.Resp.ChatSendRetList) == 0 {
-		return nil, fmt.Errorf("SendText 发送文本消息失败: 无发送结果数据")
+	if len(item.Resp.ChatSendRetList) == 0 {
+		result.Error = "无发送结果数据"
+		return result
 	}
 
-	// 获取第一个发送结果
-	sendRet := firstResult.Resp.ChatSendRetList[0]
+	// 获取发送结果
+	sendRet := item.Resp.ChatSendRetList[0]
+	if sendRet.ToUserName.Str != "" {
+		result.ToUserName = sendRet.ToUserName.Str
+	}
 
 	// 检查发送结果状态
 	if sendRet.Ret != 0 {
-		return nil, fmt.Errorf("SendText 发送文本消息失败: 发送结果状态码 %d", sendRet.Ret)
+		result.Error = fmt.Sprintf("发送结果状态码 %d", sendRet.Ret)
+		return result
 	}
 
-	// 构建成功响应
-	response := &SendTextResponse{
-		ToUserName:  sendRet.ToUserName.Str,
-		ClientMsgId: sendRet.ClientMsgId,
-		CreateTime:  sendRet.CreateTime,
-		NewMsgId:    sendRet.NewMsgId,
-	}
-
-	c.logger.Info("文本消息发送成功",
-		zap.String("to_user", response.ToUserName),
-		zap.Int64("client_msg_id", response.ClientMsgId),
-		zap.Int64("create_time", response.CreateTime),
-		zap.Int64("new_msg_id", response.NewMsgId))
-
-	return response, nil
+	result.Success = true
+	result.ClientMsgId = sendRet.ClientMsgId
+	result.CreateTime = sendRet.CreateTime
+	result.NewMsgId = sendRet.NewMsgId
+	return result
 }
 
 // SendImage 发送图片消息（简化版）
-func (c *WxAPIClient) SendImage(robotAddress, authKey string, req *SendImageRequest) (*SendImageResponse, error) {
-	url := fmt.Sprintf("%s/message/SendImageNewMessage?key=%s", robotAddress, authKey)
+func (c *WxAPIClient) SendImage(ctx context.Context, robotAddress, authKey string, req *SendImageRequest) (*SendImageResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.SendImageNewMessage, authKey)
+
+	// 兼容data URI前缀和带换行的base64
+	imageContent := normalizeBase64Image(req.ImageContent)
+
+	// 缩略图：优先使用传入的，否则按需从原图生成；生成失败只发原图
+	thumbContent := normalizeBase64Image(req.ThumbContent)
+	if thumbContent == "" && req.AutoThumb {
+		thumb, err := generateThumbnail(imageContent, thumbnailMaxSize)
+		if err != nil {
+			c.logger.Warn("生成缩略图失败，仅发送原图",
+				zap.String("to_user", req.ToUserName),
+				zap.Error(err))
+		} else {
+			thumbContent = thumb
+		}
+	}
 
 	// 构建原始请求
 	originalReq := &SendImageNewMessageRequest{
 		MsgItem: []SendImageMsgItem{
 			{
 				AtWxIDList:   []string{},
-				ImageContent: req.ImageContent,
+				ImageContent: imageContent,
+				ThumbContent: thumbContent,
 				MsgType:      3, // 图片消息类型
 				TextContent:  "",
 				ToUserName:   req.ToUserName,
@@ -773,7 +1254,7 @@ func (c *WxAPIClient) SendImage(robotAddress, authKey string, req *SendImageRequ
 		},
 	}
 
-	jsonData, err := json.Marshal(originalReq)
+	jsonData, err := marshalJSON(originalReq)
 	if err != nil {
 		return nil, fmt.Errorf("序列化请求数据失败: %w", err)
 	}
@@ -781,14 +1262,18 @@ func (c *WxAPIClient) SendImage(robotAddress, authKey string, req *SendImageRequ
 	c.logger.Info("发送图片消息请求",
 		zap.String("url", url),
 		zap.String("to_user", req.ToUserName),
-		zap.Int("image_size", len(req.ImageContent)))
+		zap.Int("image_size", len(imageContent)),
+		zap.Int("thumb_size", len(thumbContent)))
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	ctx, cancel := context.WithTimeout(ctx, c.timeoutFor(ctx))
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
 	httpReq.Header.Set("Accept", "application/json")
 
 	resp, err := c.httpClient.Do(httpReq)
@@ -807,6 +1292,10 @@ func (c *WxAPIClient) SendImage(robotAddress, authKey string, req *SendImageRequ
 		return nil, fmt.Errorf("解析响应数据失败: %w", err)
 	}
 
+	if err := c.checkTokenExpired("SendImage", rawResponse.Code, rawResponse.Text); err != nil {
+		return nil, err
+	}
+
 	c.logger.Info("发送图片消息响应",
 		zap.Int("code", rawResponse.Code),
 		zap.Int("data_count", len(rawResponse.Data)))
@@ -857,8 +1346,195 @@ func (c *WxAPIClient) SendImage(robotAddress, authKey string, req *SendImageRequ
 	return response, nil
 }
 
+// parseSendMessageResult 解析语音、视频、卡片消息的原始响应，按SendImage的方式逐层检查失败信息，返回第一条发送结果
+func (c *WxAPIClient) parseSendMessageResult(operation, label string, body []byte) (*SendMessageRawResult, error) {
+	var rawResponse SendMessageRawResponse
+	if err := json.Unmarshal(body, &rawResponse); err != nil {
+		return nil, fmt.Errorf("解析响应数据失败: %w", err)
+	}
+
+	if err := c.checkTokenExpired(operation, rawResponse.Code, rawResponse.Text); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("发送"+label+"消息响应",
+		zap.Int("code", rawResponse.Code),
+		zap.Int("data_count", len(rawResponse.Data)))
+
+	if len(rawResponse.Data) == 0 {
+		return nil, fmt.Errorf("发送%s消息失败: 无响应数据", label)
+	}
+
+	firstResult := rawResponse.Data[0]
+	if firstResult.ErrMsg != "" {
+		return nil, newSendError(classifySendFailure(firstResult.ErrMsg), "发送%s消息失败: %s", label, firstResult.ErrMsg)
+	}
+	if firstResult.Resp == nil {
+		return nil, fmt.Errorf("发送%s消息失败: 响应数据不完整", label)
+	}
+	if firstResult.Resp.BaseResponse.Ret != 0 {
+		errMsg := firstResult.Resp.BaseResponse.ErrMsg.Str
+		if errMsg == "" {
+			errMsg = "未知错误"
+		}
+		return nil, newSendError(classifySendFailure(errMsg), "发送%s消息失败: %s", label, errMsg)
+	}
+	return firstResult.Resp, nil
+}
+
+// SendVoice 发送语音消息（简化版）
+func (c *WxAPIClient) SendVoice(ctx context.Context, robotAddress, authKey string, req *SendVoiceRequest) (*SendVoiceResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.SendVoiceMessage, authKey)
+
+	// 兼容data URI前缀和带换行的base64
+	voiceContent := normalizeBase64Image(req.VoiceContent)
+
+	originalReq := &SendVoiceMessageRequest{
+		ToUserName:  req.ToUserName,
+		VoiceData:   voiceContent,
+		VoiceFormat: req.VoiceFormat,
+		VoiceSecond: req.VoiceDuration,
+	}
+
+	c.logger.Info("发送语音消息请求",
+		zap.String("url", url),
+		zap.String("to_user", req.ToUserName),
+		zap.Int("voice_size", len(voiceContent)),
+		zap.Int("voice_format", req.VoiceFormat),
+		zap.Int("voice_duration", req.VoiceDuration))
+
+	body, err := c.makeRequest(ctx, "POST", robotAddress, url, originalReq)
+	if err != nil {
+		return nil, newSendError(SendErrorNetwork, "SendVoice 发送HTTP请求失败: %w", err)
+	}
+
+	result, err := c.parseSendMessageResult("SendVoice", "语音", body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SendVoiceResponse{
+		MsgId:       result.MsgId,
+		ToUserName:  req.ToUserName,
+		ClientMsgId: result.ClientMsgId,
+		CreateTime:  result.CreateTime,
+		NewMsgId:    result.NewMsgId,
+	}
+
+	c.logger.Info("语音消息发送成功",
+		zap.Int64("msg_id", response.MsgId),
+		zap.String("to_user", response.ToUserName),
+		zap.Int64("new_msg_id", response.NewMsgId))
+
+	return response, nil
+}
+
+// SendVideo 发送视频消息（简化版）
+func (c *WxAPIClient) SendVideo(ctx context.Context, robotAddress, authKey string, req *SendVideoRequest) (*SendVideoResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.SendVideoMessage, authKey)
+
+	// 兼容data URI前缀和带换行的base64
+	videoContent := normalizeBase64Image(req.VideoContent)
+	thumbContent := normalizeBase64Image(req.ThumbContent)
+	if thumbContent != "" {
+		if _, err := decodeBase64Image(thumbContent); err != nil {
+			return nil, fmt.Errorf("视频缩略图无效: %w", err)
+		}
+	}
+
+	originalReq := &SendVideoMessageRequest{
+		ToUserName: req.ToUserName,
+		VideoData:  videoContent,
+		ThumbData:  thumbContent,
+		PlayLength: req.PlayLength,
+	}
+
+	c.logger.Info("发送视频消息请求",
+		zap.String("url", url),
+		zap.String("to_user", req.ToUserName),
+		zap.Int("video_size", len(videoContent)),
+		zap.Int("thumb_size", len(thumbContent)),
+		zap.Int("play_length", req.PlayLength))
+
+	body, err := c.makeRequest(ctx, "POST", robotAddress, url, originalReq)
+	if err != nil {
+		return nil, newSendError(SendErrorNetwork, "SendVideo 发送HTTP请求失败: %w", err)
+	}
+
+	result, err := c.parseSendMessageResult("SendVideo", "视频", body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SendVideoResponse{
+		MsgId:       result.MsgId,
+		ToUserName:  req.ToUserName,
+		ClientMsgId: result.ClientMsgId,
+		CreateTime:  result.CreateTime,
+		NewMsgId:    result.NewMsgId,
+	}
+
+	c.logger.Info("视频消息发送成功",
+		zap.Int64("msg_id", response.MsgId),
+		zap.String("to_user", response.ToUserName),
+		zap.Int64("new_msg_id", response.NewMsgId))
+
+	return response, nil
+}
+
+// SendAppMsg 发送链接卡片消息（简化版）
+func (c *WxAPIClient) SendAppMsg(ctx context.Context, robotAddress, authKey string, req *SendAppMsgRequest) (*SendAppMsgResponse, error) {
+	url := c.buildURL(robotAddress, c.endpoints.SendAppMessage, authKey)
+
+	contentXML, err := buildLinkAppMsgXML(req)
+	if err != nil {
+		return nil, fmt.Errorf("生成卡片消息XML失败: %w", err)
+	}
+
+	originalReq := &SendAppMessageRequest{
+		AppList: []AppMessageItem{
+			{
+				ContentType: appMsgTypeLink,
+				ContentXML:  contentXML,
+				ToUserName:  req.ToUserName,
+			},
+		},
+	}
+
+	c.logger.Info("发送卡片消息请求",
+		zap.String("url", url),
+		zap.String("to_user", req.ToUserName),
+		zap.String("title", req.Title))
+	c.logger.Debug("卡片消息XML", zap.String("xml", contentXML))
+
+	body, err := c.makeRequest(ctx, "POST", robotAddress, url, originalReq)
+	if err != nil {
+		return nil, newSendError(SendErrorNetwork, "SendAppMsg 发送HTTP请求失败: %w", err)
+	}
+
+	result, err := c.parseSendMessageResult("SendAppMsg", "卡片", body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SendAppMsgResponse{
+		MsgId:       result.MsgId,
+		ToUserName:  req.ToUserName,
+		ClientMsgId: result.ClientMsgId,
+		CreateTime:  result.CreateTime,
+		NewMsgId:    result.NewMsgId,
+	}
+
+	c.logger.Info("卡片消息发送成功",
+		zap.Int64("msg_id", response.MsgId),
+		zap.String("to_user", response.ToUserName),
+		zap.Int64("new_msg_id", response.NewMsgId))
+
+	return response, nil
+}
+
 // SendTextAndImage 同时发送文字和图片
-func (c *WxAPIClient) SendTextAndImage(robotAddress, authKey string, req *SendTextAndImageRequest) (*SendTextAndImageResponse, error) {
+func (c *WxAPIClient) SendTextAndImage(ctx context.Context, robotAddress, authKey string, req *SendTextAndImageRequest) (*SendTextAndImageResponse, error) {
 	// 检查输入参数
 	hasText := req.TextContent != ""
 	hasImage := req.ImageContent != ""
@@ -880,9 +1556,10 @@ func (c *WxAPIClient) SendTextAndImage(robotAddress, authKey string, req *SendTe
 		textReq := &SendTextRequest{
 			TextContent: req.TextContent,
 			ToUserName:  req.ToUserName,
+			AtWxIDList:  req.AtWxIDList,
 		}
 
-		textResp, textErr = c.SendText(robotAddress, authKey, textReq)
+		textResp, textErr = c.SendText(ctx, robotAddress, authKey, textReq)
 		if textErr != nil {
 			c.logger.Error("SendTextAndImage 发送文本消息失败",
 				zap.String("to_user", req.ToUserName),
@@ -897,7 +1574,7 @@ func (c *WxAPIClient) SendTextAndImage(robotAddress, authKey string, req *SendTe
 			ToUserName:   req.ToUserName,
 		}
 
-		imageResp, imageErr = c.SendImage(robotAddress, authKey, imageReq)
+		imageResp, imageErr = c.SendImage(ctx, robotAddress, authKey, imageReq)
 		if imageErr != nil {
 			c.logger.Error("SendTextAndImage 发送图片消息失败",
 				zap.String("to_user", req.ToUserName),
@@ -953,18 +1630,36 @@ func (c *WxAPIClient) SendTextAndImage(robotAddress, authKey string, req *SendTe
 			zap.Bool("image_failed", hasImage && imageErr != nil))
 	}
 
+	// token过期时文本和图片都无法发送，返回可识别的错误
+	if errors.Is(textErr, ErrTokenExpired) || errors.Is(imageErr, ErrTokenExpired) {
+		return response, fmt.Errorf("SendTextAndImage: %w", ErrTokenExpired)
+	}
+
 	return response, nil
 }
 
-// CheckRobotHealth 检查机器人健康状态
-func (c *WxAPIClient) CheckRobotHealth(robotAddress string) (bool, error) {
+// normalizeHealthPath 规范化健康检查路径，为空时使用根路径，缺少前导/时补上
+func normalizeHealthPath(healthPath string) string {
+	healthPath = strings.TrimSpace(healthPath)
+	if healthPath == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(healthPath, "/") {
+		healthPath = "/" + healthPath
+	}
+	return healthPath
+}
+
+// CheckRobotHealth 检查机器人健康状态，GET 机器人地址拼接健康检查路径，返回200视为健康
+func (c *WxAPIClient) CheckRobotHealth(ctx context.Context, robotAddress, healthPath string) (bool, error) {
 	// 确保地址以http://或https://开头
 	if !strings.HasPrefix(robotAddress, "http://") && !strings.HasPrefix(robotAddress, "https://") {
 		robotAddress = "http://" + robotAddress
 	}
+	robotAddress = strings.TrimRight(robotAddress, "/") + normalizeHealthPath(healthPath)
 
 	// 发送简单的GET请求检查机器人状态
-	req, err := http.NewRequest("GET", robotAddress, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", robotAddress, nil)
 	if err != nil {
 		c.logger.Error("创建健康检查请求失败",
 			zap.String("robot_address", robotAddress),
@@ -972,9 +1667,10 @@ func (c *WxAPIClient) CheckRobotHealth(robotAddress string) (bool, error) {
 		return false, fmt.Errorf("创建请求失败: %w", err)
 	}
 
-	// 设置超时时间
+	// 设置超时时间，复用带地址防护的Transport
 	client := &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout:   10 * time.Second,
+		Transport: c.httpClient.Transport,
 	}
 
 	resp, err := client.Do(req)
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{name: "emoji", v: map[string]string{"TextContent": "早上好😀🎉"}, want: `{"TextContent":"早上好😀🎉"}`},
+		{name: "html characters", v: map[string]string{"TextContent": "<a href=\"x\">A&B</a>"}, want: `{"TextContent":"<a href=\"x\">A&B</a>"}`},
+		{name: "control characters", v: map[string]string{"TextContent": "第一行\n第二行\t"}, want: `{"TextContent":"第一行\n第二行\t"}`},
+		{name: "no trailing newline", v: []int{1, 2}, want: `[1,2]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalJSON(tt.v)
+			if err != nil {
+				t.Fatalf("marshalJSON: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("marshalJSON = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWxAPIEndpointsWithDefaults(t *testing.T) {
+	got := WxAPIEndpoints{
+		GroupList:       "/v2/group/GroupList",
+		SendTextMessage: "  ",
+	}.withDefaults()
+
+	if got.GroupList != "/v2/group/GroupList" {
+		t.Errorf("configured GroupList = %q, want kept", got.GroupList)
+	}
+	if got.SendTextMessage != defaultWxAPIEndpoints.SendTextMessage {
+		t.Errorf("blank SendTextMessage = %q, want default", got.SendTextMessage)
+	}
+	if got.GetLoginStatus != defaultWxAPIEndpoints.GetLoginStatus {
+		t.Errorf("missing GetLoginStatus = %q, want default", got.GetLoginStatus)
+	}
+}
+
+func TestWxAPIClientBuildURL(t *testing.T) {
+	client := NewWxAPIClient(WxAPIConfig{}, zap.NewNop(), newTestAddressGuard(t, false))
+
+	tests := []struct {
+		name    string
+		address string
+		path    string
+		want    string
+	}{
+		{name: "plain", address: "http://robot:2531", path: "/group/GroupList", want: "http://robot:2531/group/GroupList?key=k"},
+		{name: "trailing slash", address: "http://robot:2531/", path: "/group/GroupList", want: "http://robot:2531/group/GroupList?key=k"},
+		{name: "path without slash", address: "http://robot:2531", path: "api/group/GroupList", want: "http://robot:2531/api/group/GroupList?key=k"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := client.buildURL(tt.address, tt.path, "k"); got != tt.want {
+				t.Fatalf("buildURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWxAPIClientUsesConfiguredEndpoints(t *testing.T) {
+	var called int32
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		"/v2/login/GetLoginStatus": func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&called, 1)
+			jsonHandler(map[string]interface{}{"Code": 200})(w, r)
+		},
+	})
+
+	client := NewWxAPIClient(WxAPIConfig{
+		Endpoints: WxAPIEndpoints{GetLoginStatus: "/v2/login/GetLoginStatus"},
+	}, zap.NewNop(), newTestAddressGuard(t, false))
+	if _, err := client.GetLoginStatus(context.Background(), server.URL, "token"); err != nil {
+		t.Fatalf("GetLoginStatus: %v", err)
+	}
+	if atomic.LoadInt32(&called) != 1 {
+		t.Fatal("configured endpoint path not used")
+	}
+}
+
+func TestWxAPIClientSendTextBatch(t *testing.T) {
+	sent := func(toUserName string, newMsgID int64) map[string]interface{} {
+		return map[string]interface{}{
+			"isSendSuccess": true,
+			"toUSerName":    toUserName,
+			"resp": map[string]interface{}{
+				"base_response":      map[string]interface{}{"ret": 0},
+				"chat_send_ret_list": []map[string]interface{}{{"ret": 0, "newMsgId": newMsgID}},
+			},
+		}
+	}
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		defaultWxAPIEndpoints.SendTextMessage: jsonHandler(map[string]interface{}{
+			"Code": 200,
+			"Data": []interface{}{
+				sent("g1@chatroom", 11),
+				map[string]interface{}{"isSendSuccess": false, "toUSerName": "g2@chatroom"},
+				sent("", 33),
+			},
+		}),
+	})
+
+	client := NewWxAPIClient(WxAPIConfig{}, zap.NewNop(), newTestAddressGuard(t, false))
+	results, err := client.SendTextBatch(context.Background(), server.URL, "token", []*SendTextRequest{
+		{ToUserName: "g1@chatroom", TextContent: "hi"},
+		{ToUserName: "g2@chatroom", TextContent: "hi"},
+		{ToUserName: "g3@chatroom", TextContent: "hi"},
+	})
+	if err != nil {
+		t.Fatalf("SendTextBatch: %v", err)
+	}
+
+	tests := []struct {
+		toUserName  string
+		wantSuccess bool
+		wantMsgID   int64
+	}{
+		{toUserName: "g1@chatroom", wantSuccess: true, wantMsgID: 11},
+		{toUserName: "g2@chatroom"},
+		{toUserName: "g3@chatroom", wantSuccess: true, wantMsgID: 33},
+	}
+	if len(results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(results), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.toUserName, func(t *testing.T) {
+			got := results[i]
+			if got.ToUserName != tt.toUserName || got.Success != tt.wantSuccess || got.NewMsgId != tt.wantMsgID {
+				t.Fatalf("result = %+v, want %s success=%v msgID=%d", got, tt.toUserName, tt.wantSuccess, tt.wantMsgID)
+			}
+			if !got.Success && (got.Error == "" || got.ErrorType == "") {
+				t.Fatalf("failed result missing error: %+v", got)
+			}
+		})
+	}
+}
+
+func TestWxAPIClientSendImageThumbnail(t *testing.T) {
+	original := testPNGBase64(t, 600, 300)
+
+	tests := []struct {
+		name      string
+		req       SendImageRequest
+		wantThumb string // given 原样使用，generated 自动生成，空表示不带缩略图
+	}{
+		{name: "auto generated", req: SendImageRequest{ImageContent: original, AutoThumb: true}, wantThumb: "generated"},
+		{name: "given thumb wins", req: SendImageRequest{ImageContent: original, ThumbContent: "dGh1bWI=", AutoThumb: true}, wantThumb: "given"},
+		{name: "auto thumb off", req: SendImageRequest{ImageContent: original}},
+		{name: "undecodable image sent without thumb", req: SendImageRequest{ImageContent: "aGVsbG8=", AutoThumb: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got SendImageNewMessageRequest
+			server := newTestRobotServer(t, map[string]http.HandlerFunc{
+				defaultWxAPIEndpoints.SendImageNewMessage: func(w http.ResponseWriter, r *http.Request) {
+					json.NewDecoder(r.Body).Decode(&got)
+					jsonHandler(map[string]interface{}{"Code": 200})(w, r)
+				},
+			})
+			client := NewWxAPIClient(WxAPIConfig{}, zap.NewNop(), newTestAddressGuard(t, false))
+			tt.req.ToUserName = "g@chatroom"
+			client.SendImage(context.Background(), server.URL, "token", &tt.req)
+
+			if len(got.MsgItem) != 1 {
+				t.Fatalf("image not sent: %+v", got)
+			}
+			thumb := got.MsgItem[0].ThumbContent
+			switch tt.wantThumb {
+			case "given":
+				if thumb != "dGh1bWI=" {
+					t.Fatalf("thumb = %q, want given thumb", thumb)
+				}
+			case "generated":
+				data, _ := base64.StdEncoding.DecodeString(thumb)
+				cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+				if err != nil || cfg.Width != thumbnailMaxSize {
+					t.Fatalf("generated thumb width = %d, err %v", cfg.Width, err)
+				}
+			default:
+				if thumb != "" {
+					t.Fatalf("thumb = %q, want none", thumb)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupListDedupeGroups(t *testing.T) {
+	tests := []struct {
+		name        string
+		groups      [][2]string
+		want        [][2]string
+		wantRemoved int
+	}{
+		{name: "no duplicates", groups: [][2]string{{"1@chatroom", "群1"}, {"2@chatroom", "群2"}}, want: [][2]string{{"1@chatroom", "群1"}, {"2@chatroom", "群2"}}},
+		{name: "latest nickname wins", groups: [][2]string{{"1@chatroom", "旧名"}, {"2@chatroom", "群2"}, {"1@chatroom", "新名"}}, want: [][2]string{{"1@chatroom", "新名"}, {"2@chatroom", "群2"}}, wantRemoved: 1},
+		{name: "empty nickname keeps previous", groups: [][2]string{{"1@chatroom", "群1"}, {"1@chatroom", ""}}, want: [][2]string{{"1@chatroom", "群1"}}, wantRemoved: 1},
+		{name: "many duplicates", groups: [][2]string{{"1@chatroom", "a"}, {"1@chatroom", "b"}, {"1@chatroom", "c"}}, want: [][2]string{{"1@chatroom", "c"}}, wantRemoved: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := newTestGroupList(t, tt.groups)
+			if removed := resp.DedupeGroups(); removed != tt.wantRemoved {
+				t.Fatalf("removed = %d, want %d", removed, tt.wantRemoved)
+			}
+			got := make([][2]string, 0, len(resp.Data.GroupList))
+			for _, g := range resp.Data.GroupList {
+				got = append(got, [2]string{g.UserName.Str, g.NickName.Str})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHealthPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "", want: "/"},
+		{path: "  ", want: "/"},
+		{path: "/", want: "/"},
+		{path: "/healthz", want: "/healthz"},
+		{path: "api/health", want: "/api/health"},
+		{path: " /ping ", want: "/ping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := normalizeHealthPath(tt.path); got != tt.want {
+				t.Fatalf("normalizeHealthPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRobotsHealthPath(t *testing.T) {
+	// 根路径未登记返回404，只有/healthz返回200
+	server := newTestRobotServer(t, map[string]http.HandlerFunc{
+		"/healthz": func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) },
+	})
+	svc, db := newTestService(t, nil)
+
+	tests := []struct {
+		name       string
+		address    string
+		healthPath string
+		want       string
+	}{
+		{name: "default root path", address: server.URL, want: "unhealthy"},
+		{name: "custom path", address: server.URL, healthPath: "/healthz", want: "healthy"},
+		{name: "address with trailing slash", address: server.URL + "/", healthPath: "/healthz", want: "healthy"},
+		{name: "unknown custom path", address: server.URL, healthPath: "/status", want: "unhealthy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			robot := &WxRobotConfig{Address: tt.address, HealthPath: tt.healthPath}
+			createTestRobot(t, db, robot)
+
+			summary, err := svc.CheckRobotsHealth(context.Background(), []uint{robot.ID}, 0)
+			if err != nil {
+				t.Fatalf("CheckRobotsHealth: %v", err)
+			}
+			if len(summary.Results) != 1 || summary.Results[0].Status != tt.want {
+				t.Fatalf("results = %+v, want %s", summary.Results, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildLinkAppMsgXML(t *testing.T) {
+	tests := []struct {
+		name string
+		req  SendAppMsgRequest
+		want string
+	}{
+		{
+			name: "link card",
+			req:  SendAppMsgRequest{Title: "周报", Description: "本周进展", Url: "https://example.com/a", ThumbUrl: "https://example.com/t.jpg"},
+			want: `<appmsg appid="" sdkver="0"><title>周报</title><des>本周进展</des><action>view</action><type>5</type><showtype>0</showtype><url>https://example.com/a</url><thumburl>https://example.com/t.jpg</thumburl></appmsg>`,
+		},
+		{
+			name: "escaped",
+			req:  SendAppMsgRequest{Title: `A&B <新品>`, Description: `"限时"`, Url: "https://example.com/?a=1&b=2"},
+			want: `<appmsg appid="" sdkver="0"><title>A&amp;B &lt;新品&gt;</title><des>&#34;限时&#34;</des><action>view</action><type>5</type><showtype>0</showtype><url>https://example.com/?a=1&amp;b=2</url><thumburl></thumburl></appmsg>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildLinkAppMsgXML(&tt.req)
+			if err != nil {
+				t.Fatalf("buildLinkAppMsgXML: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("xml = %s\nwant  %s", got, tt.want)
+			}
+		})
+	}
+}